@@ -5,14 +5,16 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
-	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/example/shineyshot/internal/appstate"
 	"github.com/example/shineyshot/internal/capture"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/project"
 	"github.com/example/shineyshot/internal/render"
 )
 
@@ -29,6 +31,12 @@ type annotateCmd struct {
 	shadowOffset  string
 	shadowPoint   image.Point
 	shadowOpacity float64
+	frame         frameFlags
+	frameOpts     render.FrameOptions
+	delay         time.Duration
+	primary       bool
+	splitMonitors bool
+	restore       bool
 
 	commonFlags  *flag.FlagSet
 	captureFlags *flag.FlagSet
@@ -44,6 +52,10 @@ type annotateCaptureConfig struct {
 	rect               string
 	includeDecorations bool
 	includeCursor      bool
+	backend            string
+	units              string
+	pick               bool
+	pngMetadata        bool
 }
 
 type annotateOpenConfig struct {
@@ -78,10 +90,23 @@ func parseAnnotateCmd(args []string, r *root) (*annotateCmd, error) {
 	intFlag(fs, &a.shadowRadius, "shadow-radius", defaults.Radius, "drop shadow blur radius in pixels", a.commonFlags)
 	stringFlag(fs, &a.shadowOffset, "shadow-offset", formatShadowOffset(defaults.Offset), "drop shadow offset as dx,dy", a.commonFlags)
 	floatFlag(fs, &a.shadowOpacity, "shadow-opacity", defaults.Opacity, "drop shadow opacity between 0 and 1", a.commonFlags)
+	stringFlag(fs, &a.frame.preset, "frame", "", "frame saved and copied images with a preset ("+framePresetNames()+"); Ctrl+F toggles it in the editor", a.commonFlags)
+	stringFlag(fs, &a.frame.padding, "padding", "", "pad saved and copied images by N, V,H or T,R,B,L pixels", a.commonFlags)
+	stringFlag(fs, &a.frame.background, "background", "", "backdrop color behind the padding, or two comma separated colors for a gradient", a.commonFlags)
+	stringFlag(fs, &a.frame.title, "frame-title", "", "window title shown by the macos and gnome frames (defaults to the captured window's title)", a.commonFlags)
+	stringFlag(fs, &a.frame.url, "frame-url", "", "address shown by the browser frame; Ctrl+L edits it in the editor", a.commonFlags)
+	durationFlag(fs, &a.delay, "delay", 0, "wait this long before capturing, here and for Ctrl+N in the editor", a.commonFlags)
 	boolFlag(fs, &a.open.fromClipboard, "from-clipboard", false, "load the input image from the clipboard", a.openFlags)
 	boolFlag(fs, &a.open.fromClipboard, "from-clip", false, "load the input image from the clipboard (alias)", a.openFlags)
+	boolFlag(fs, &a.splitMonitors, "split-monitors", false, "open a screen capture spanning several monitors as a tab per monitor, here and for Ctrl+N", a.commonFlags)
+	boolFlag(fs, &a.primary, "primary", false, "paste from the primary selection and also copy to it, here and in the editor", a.commonFlags)
+	boolFlag(fs, &a.restore, "restore", false, "reopen the tabs of the last annotate session instead of capturing or opening an image", a.commonFlags)
 	boolFlag(fs, &a.capture.includeDecorations, "include-decorations", false, "request window decorations when capturing windows", a.captureFlags)
 	boolFlag(fs, &a.capture.includeCursor, "include-cursor", false, "embed the cursor in captures when supported", a.captureFlags)
+	stringFlag(fs, &a.capture.backend, "backend", capture.BackendAuto, "screenshot backend: auto, wlr, kwin, gnome, portal, x11, external, grim, spectacle, maim, or scrot", a.captureFlags)
+	stringFlag(fs, &a.capture.units, "units", capture.UnitsPixel, "region coordinate units: pixel, or logical to scale by the monitor's HiDPI factor", a.captureFlags)
+	boolFlag(fs, &a.capture.pick, "pick", false, "click the window to capture with a crosshair pointer", a.captureFlags)
+	boolFlag(fs, &a.capture.pngMetadata, "png-metadata", false, "store the capture time, window and monitor as PNG text chunks when saving", a.captureFlags)
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
@@ -90,7 +115,23 @@ func parseAnnotateCmd(args []string, r *root) (*annotateCmd, error) {
 		return nil, err
 	}
 	a.shadowPoint = pt
+	if a.frameOpts, err = a.frame.options(); err != nil {
+		return nil, err
+	}
+	if err := capture.CheckBackend(a.capture.backend); err != nil {
+		return nil, err
+	}
+	if err := capture.CheckUnits(a.capture.units); err != nil {
+		return nil, err
+	}
 	operands := fs.Args()
+	if a.restore {
+		if len(operands) > 0 {
+			return nil, fmt.Errorf("-restore cannot be combined with capture or open")
+		}
+		a.action = "restore"
+		return a, nil
+	}
 	if len(operands) == 0 {
 		return nil, &UsageError{of: a}
 	}
@@ -125,6 +166,15 @@ func parseAnnotateCmd(args []string, r *root) (*annotateCmd, error) {
 		if a.capture.target != "region" && strings.TrimSpace(a.capture.rect) != "" {
 			return nil, &UsageError{of: a}
 		}
+		if a.capture.pick {
+			if a.capture.target != "window" {
+				return nil, fmt.Errorf("-pick only applies to window captures")
+			}
+			if a.capture.selector != "" {
+				return nil, fmt.Errorf("-pick cannot be combined with a window selector")
+			}
+			a.capture.selector = capture.PickWindowSelector
+		}
 	case "open":
 		if a.open.file == "" && len(operands) > 1 {
 			a.open.file = strings.TrimSpace(strings.Join(operands[1:], " "))
@@ -135,6 +185,11 @@ func parseAnnotateCmd(args []string, r *root) (*annotateCmd, error) {
 			}
 		}
 		a.output = a.open.file
+		if project.IsProject(a.open.file) {
+			// Ctrl+S exports the tab beside the project; Ctrl+Shift+S
+			// saves the project itself.
+			a.output = strings.TrimSuffix(a.open.file, filepath.Ext(a.open.file)) + ".png"
+		}
 	default:
 		return nil, &UsageError{of: a}
 	}
@@ -142,59 +197,89 @@ func parseAnnotateCmd(args []string, r *root) (*annotateCmd, error) {
 }
 
 func (a *annotateCmd) Run() error {
-	var img *image.RGBA
+	var (
+		img      *image.RGBA
+		captured *capture.CaptureResult
+		proj     *project.Project
+		// more are the monitors after the first when a capture is split.
+		more []capture.CaptureResult
+	)
+	// startup is set when the window opens at once and captures in the
+	// background, which needs it to be hidden from the capture.
+	var startup func(capture.CaptureOptions) (capture.CaptureResult, error)
+	// The editor keeps its tabs in the session directory, unless another
+	// editor still does or a crashed one's wait there to be restored.
+	sessDir, err := sessionDir()
+	keepSession := err == nil
+	if keepSession {
+		switch checkSession(sessDir) {
+		case sessionBusy:
+			keepSession = false
+		case sessionCrashed:
+			if a.action == "restore" {
+				break
+			}
+			if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+				if askRestore(os.Stdin, os.Stderr) {
+					a.action = "restore"
+				}
+				break
+			}
+			fmt.Fprintln(os.Stderr, "the last annotate session did not close cleanly; annotate -restore reopens its tabs")
+			keepSession = false
+		}
+	}
 	switch a.action {
-	case "capture":
-		var err error
-		opts := capture.CaptureOptions{
-			IncludeDecorations: a.capture.includeDecorations,
-			IncludeCursor:      a.capture.includeCursor,
+	case "restore":
+		if sessDir == "" {
+			return fmt.Errorf("locate session: %w", err)
 		}
-		switch a.capture.target {
-		case "screen":
-			img, err = captureScreenshotFn(a.capture.selector, opts)
-		case "window":
-			img, err = captureWindowFn(a.capture.selector, opts)
-		case "region":
-			rectSpec := a.capture.rect
-			if rectSpec == "" {
-				rectSpec = a.capture.selector
-			}
-			if strings.TrimSpace(rectSpec) == "" {
-				img, err = captureRegionFn(opts)
-			} else {
-				var rect image.Rectangle
-				rect, err = parseRect(rectSpec)
-				if err == nil {
-					img, err = captureRegionRectFn(rect, opts)
+		p, err := openSession(sessDir)
+		if err != nil {
+			return err
+		}
+		proj = p
+	case "capture":
+		if canHideOwnWindowsFn() {
+			startup = func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+				res, err := a.captureImage(opts)
+				if err != nil {
+					return res, fmt.Errorf("failed to capture %s: %w", a.capture.target, err)
 				}
+				a.root.notifyCapture(withCaptureSummary(a.captureDetail(), res), res.Image)
+				return res, nil
 			}
+			break
 		}
+		res, err := a.captureImage(capture.CaptureOptions{Delay: a.delay, Progress: countdown(os.Stderr)})
 		if err != nil {
 			return fmt.Errorf("failed to capture %s: %w", a.capture.target, err)
 		}
+		img, captured = res.Image, &res
+		if a.splitMonitors && a.capture.target == "screen" {
+			parts := capture.SplitMonitors(res)
+			img, captured, more = parts[0].Image, &parts[0], parts[1:]
+		}
 	case "open":
 		if a.open.fromClipboard {
-			src, err := clipboard.ReadImage()
+			src, err := clipboard.ReadImageFrom(pasteSelection(a.primary))
 			if err != nil {
 				return fmt.Errorf("read clipboard image: %w", err)
 			}
 			img = image.NewRGBA(src.Bounds())
 			draw.Draw(img, img.Bounds(), src, image.Point{}, draw.Src)
-		} else {
-			f, err := os.Open(a.open.file)
+		} else if project.IsProject(a.open.file) {
+			p, err := project.Open(a.open.file)
 			if err != nil {
-				return fmt.Errorf("open %q: %w", a.open.file, err)
-			}
-			dec, err := png.Decode(f)
-			if cerr := f.Close(); cerr != nil && err == nil {
-				err = cerr
+				return err
 			}
+			proj = p
+		} else {
+			dec, err := imagefile.Open(a.open.file)
 			if err != nil {
-				return fmt.Errorf("decode %q: %w", a.open.file, err)
+				return err
 			}
-			img = image.NewRGBA(dec.Bounds())
-			draw.Draw(img, img.Bounds(), dec, image.Point{}, draw.Src)
+			img = dec
 		}
 	}
 	shadowOpts := a.shadowOptions()
@@ -204,8 +289,11 @@ func (a *annotateCmd) Run() error {
 		initialShadowOffset = image.Pt(-res.Offset.X, -res.Offset.Y)
 		img = res.Image
 	}
-	if a.action == "capture" && a.root != nil {
-		a.root.notifyCapture(a.captureDetail(), img)
+	if a.frameOpts.Title == "" && captured != nil && captured.Window != nil {
+		a.frameOpts.Title = captured.Window.Title
+	}
+	if captured != nil && a.root != nil {
+		a.root.notifyCapture(withCaptureSummary(a.captureDetail(), *captured), img)
 	}
 	detail := ""
 	fileName := ""
@@ -230,16 +318,77 @@ func (a *annotateCmd) Run() error {
 		appstate.WithShadowDefaults(shadowOpts),
 		appstate.WithInitialShadowApplied(a.shadow),
 		appstate.WithInitialShadowOffset(initialShadowOffset),
+		appstate.WithFrameDefaults(a.frameOpts),
+		appstate.WithFrameExport(a.frame.requested()),
 		appstate.WithTheme(a.root.activeTheme),
+		appstate.WithTabStorage(a.root.tabStorage),
+		appstate.WithShortcuts(a.root.shortcuts),
+		appstate.WithUIScale(a.root.uiScale),
+		appstate.WithSnapDistance(a.root.snapDistance),
+		appstate.WithWebP(a.root.webp),
+		appstate.WithAVIF(a.root.avif),
+		appstate.WithJPEG(a.root.jpeg),
+		appstate.WithCaptureDelay(a.delay),
+		appstate.WithSplitMonitors(a.splitMonitors),
+		appstate.WithPrimarySelection(a.primary),
 	}
 	if strings.TrimSpace(a.output) != "" {
 		opts = append(opts, appstate.WithOutput(a.output))
 	}
+	if captured != nil {
+		opts = append(opts, appstate.WithCapture(*captured), appstate.WithPNGMetadata(a.capture.pngMetadata))
+	}
+	if proj != nil {
+		// A restored session is not tied to the session file: Ctrl+Shift+S
+		// saves its project where a capture's would go.
+		opts = append(opts, appstate.WithProject(a.open.file, proj))
+	}
+	if keepSession {
+		if err := claimSession(sessDir); err != nil {
+			fmt.Fprintf(os.Stderr, "not keeping a session: %v\n", err)
+		} else {
+			defer os.Remove(pidfilePath(sessDir, sessionName))
+			opts = append(opts, appstate.WithSession(sessionPath(sessDir)))
+		}
+	}
+	if len(more) > 0 {
+		opts = append(opts, appstate.WithMoreCaptures(more))
+	}
+	if startup != nil {
+		opts = append(opts, appstate.WithStartupCapture(a.capture.target, startup), appstate.WithPNGMetadata(a.capture.pngMetadata))
+	}
 	st := appstate.New(opts...)
 	st.Run()
 	return nil
 }
 
+// captureImage captures the target given on the command line. opts carries
+// the delay and its progress; the capture flags are filled in here.
+func (a *annotateCmd) captureImage(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+	opts.IncludeDecorations = a.capture.includeDecorations
+	opts.IncludeCursor = a.capture.includeCursor
+	opts.Backend = a.capture.backend
+	opts.Units = a.capture.units
+	switch a.capture.target {
+	case "screen":
+		return captureScreenshotFn(a.capture.selector, opts)
+	case "window":
+		return captureWindowFn(a.capture.selector, opts)
+	}
+	rectSpec := a.capture.rect
+	if rectSpec == "" {
+		rectSpec = a.capture.selector
+	}
+	if strings.TrimSpace(rectSpec) == "" {
+		return captureRegionFn(opts)
+	}
+	rect, err := parseRect(rectSpec)
+	if err != nil {
+		return capture.CaptureResult{}, err
+	}
+	return captureRegionRectFn(rect, opts)
+}
+
 func (a *annotateCmd) shadowOptions() render.ShadowOptions {
 	opts := render.DefaultShadowOptions()
 	if a.shadowRadius >= 0 {
@@ -330,6 +479,15 @@ func floatFlag(fs *flag.FlagSet, target *float64, name string, value float64, us
 	}
 }
 
+func durationFlag(fs *flag.FlagSet, target *time.Duration, name string, value time.Duration, usage string, groups ...*flag.FlagSet) {
+	fs.DurationVar(target, name, value, usage)
+	for _, group := range groups {
+		if group != nil {
+			group.DurationVar(new(time.Duration), name, value, usage)
+		}
+	}
+}
+
 func hasDefinedFlags(fs *flag.FlagSet) bool {
 	if fs == nil {
 		return false
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,13 +12,16 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/example/shineyshot/internal/config"
 )
 
 type commandList []string
@@ -62,7 +67,14 @@ type backgroundCmd struct {
 	dir           string
 	helpRequested bool
 
+	defaults sessionDefaults
+
 	runArgs []string
+	jobID   string
+	stdout  bool
+
+	http httpOptions
+	grpc grpcOptions
 }
 
 func parseBackgroundCmd(args []string, r *root) (*backgroundCmd, error) {
@@ -77,13 +89,39 @@ func parseBackgroundCmd(args []string, r *root) (*backgroundCmd, error) {
 	cmd.fs.Usage = usageFunc(cmd)
 
 	switch cmd.op {
-	case "start", "stop", "attach", "run", "serve":
+	case "start", "stop", "attach", "run", "serve", "subscribe", "jobs", "cancel", "ensure", "http", "grpc":
 		cmd.fs.StringVar(&cmd.name, "name", "", "socket session name")
 	}
 	switch cmd.op {
-	case "start", "stop", "attach", "list", "clean", "run", "serve":
+	case "start", "stop", "attach", "list", "clean", "run", "serve", "subscribe", "status", "jobs", "cancel", "ensure", "http", "grpc":
 		cmd.fs.StringVar(&cmd.dir, "dir", "", "directory that stores shineyshot sockets")
 	}
+	switch cmd.op {
+	case "start", "serve", "http", "grpc":
+		cmd.fs.DurationVar(&cmd.defaults.IdleTimeout, "idle-timeout", 0, "shut the session down after this long without requests (e.g. 30m; 0 disables)")
+		cmd.fs.IntVar(&cmd.defaults.MaxImageMB, "max-image-mb", 0, "refuse captures whose RGBA buffer exceeds this many megabytes (0 disables)")
+		cmd.fs.BoolVar(&cmd.defaults.DBus, "dbus", false, "register the session on the D-Bus session bus as "+dbusServiceName)
+	}
+	if cmd.op == "http" {
+		cmd.fs.StringVar(&cmd.http.listen, "listen", defaultHTTPListen, "address the REST API listens on")
+		cmd.fs.StringVar(&cmd.http.token, "token", os.Getenv(httpTokenEnv), "bearer token clients must present (defaults to $"+httpTokenEnv+")")
+	}
+	if cmd.op == "grpc" {
+		cmd.fs.StringVar(&cmd.grpc.listen, "listen", defaultGRPCListen, "address the gRPC API listens on")
+		cmd.fs.StringVar(&cmd.grpc.token, "token", os.Getenv(grpcTokenEnv), "bearer token clients must send as authorization metadata (defaults to $"+grpcTokenEnv+")")
+	}
+	if cmd.op == "run" {
+		cmd.fs.BoolVar(&cmd.stdout, "stdout", false, "write the session image as PNG to stdout after the command; command output goes to stderr")
+	}
+	if cmd.op == "start" {
+		cmd.fs.StringVar(&cmd.defaults.OutDir, "outdir", "", "directory used by save without a FILE and for relative save paths")
+		cmd.fs.StringVar(&cmd.defaults.Pattern, "pattern", "", "filename pattern for automatic saves; supports {timestamp}, {date} and {time}")
+		cmd.fs.StringVar(&cmd.defaults.Color, "color", "", "initial stroke color (palette index, name or hex)")
+		cmd.fs.IntVar(&cmd.defaults.Width, "width", 0, "initial stroke width in pixels")
+		cmd.fs.StringVar(&cmd.defaults.Profile, "profile", "", "config profile providing defaults for unset options")
+		cmd.fs.BoolVar(&cmd.defaults.Sound, "sound", false, "play a shutter sound after each capture")
+		cmd.fs.BoolVar(&cmd.defaults.Flash, "flash", false, "briefly flash the captured area after each capture")
+	}
 	cmd.fs.BoolVar(&cmd.helpRequested, "help", false, "show this help message and exit")
 
 	if err := cmd.fs.Parse(args[1:]); err != nil {
@@ -109,7 +147,7 @@ func parseBackgroundCmd(args []string, r *root) (*backgroundCmd, error) {
 			cmd.dir = rest[0]
 			rest = rest[1:]
 		}
-	case "stop", "attach":
+	case "stop", "attach", "subscribe", "jobs", "ensure", "http", "grpc":
 		if cmd.name == "" && len(rest) > 0 {
 			cmd.name = rest[0]
 			rest = rest[1:]
@@ -119,8 +157,31 @@ func parseBackgroundCmd(args []string, r *root) (*backgroundCmd, error) {
 			rest = rest[1:]
 		}
 	case "run":
+		// --stdout may also trail the command, as in "run NAME capture screen --stdout".
+		for len(rest) > 0 && (rest[len(rest)-1] == "--stdout" || rest[len(rest)-1] == "-stdout") {
+			cmd.stdout = true
+			rest = rest[:len(rest)-1]
+		}
 		cmd.runArgs = append(cmd.runArgs, rest...)
 		rest = nil
+	case "cancel":
+		if cmd.name == "" && len(rest) > 1 {
+			cmd.name = rest[0]
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			cmd.jobID = rest[0]
+			rest = rest[1:]
+		}
+	case "status":
+		if len(rest) > 0 {
+			cmd.name = rest[0]
+			rest = rest[1:]
+		}
+		if cmd.dir == "" && len(rest) > 0 {
+			cmd.dir = rest[0]
+			rest = rest[1:]
+		}
 	case "list", "clean":
 		if cmd.dir == "" && len(rest) > 0 {
 			cmd.dir = rest[0]
@@ -152,6 +213,28 @@ func parseBackgroundCmd(args []string, r *root) (*backgroundCmd, error) {
 		if cmd.name == "" {
 			return nil, errors.New("serve requires a session name")
 		}
+	case "cancel":
+		if cmd.jobID == "" {
+			return nil, errors.New("background cancel requires a job id")
+		}
+	case "ensure":
+		if cmd.name == "" {
+			return nil, errors.New("background ensure requires a session name")
+		}
+	case "http":
+		if cmd.name == "" {
+			cmd.name = defaultHTTPName
+		}
+		if err := cmd.http.validate(); err != nil {
+			return nil, err
+		}
+	case "grpc":
+		if cmd.name == "" {
+			cmd.name = defaultGRPCName
+		}
+		if err := cmd.grpc.validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	return cmd, nil
@@ -183,12 +266,18 @@ func (b *backgroundCmd) Run() error {
 			return err
 		}
 		return cleanSocketDir(dir, os.Stdout)
+	case "status":
+		dir, err := resolveSocketDir(b.dir)
+		if err != nil {
+			return err
+		}
+		return printSessionStatuses(dir, b.name, os.Stdout)
 	case "start":
 		dir, err := resolveSocketDir(b.dir)
 		if err != nil {
 			return err
 		}
-		name, err := startBackgroundServer(dir, b.name, b.root)
+		name, err := startBackgroundServer(dir, b.name, b.defaults, b.root)
 		if err != nil {
 			return err
 		}
@@ -196,6 +285,12 @@ func (b *backgroundCmd) Run() error {
 			return err
 		}
 		return nil
+	case "ensure":
+		dir, err := resolveSocketDir(b.dir)
+		if err != nil {
+			return err
+		}
+		return ensureBackgroundServer(dir, b.name, b.root, os.Stdout)
 	case "stop":
 		dir, err := resolveSocketDir(b.dir)
 		if err != nil {
@@ -222,12 +317,38 @@ func (b *backgroundCmd) Run() error {
 			return err
 		}
 		return attachSocket(dir, name, os.Stdin, os.Stdout, os.Stderr)
+	case "subscribe":
+		dir, err := resolveSocketDir(b.dir)
+		if err != nil {
+			return err
+		}
+		name, err := selectRunningSocket(dir, b.name)
+		if err != nil {
+			return err
+		}
+		return subscribeSocket(dir, name, os.Stdout)
 	case "run":
 		dir, err := resolveSocketDir(b.dir)
 		if err != nil {
 			return err
 		}
 		return b.runCommand(dir)
+	case "jobs", "cancel":
+		dir, err := resolveSocketDir(b.dir)
+		if err != nil {
+			return err
+		}
+		name, err := selectRunningSocket(dir, b.name)
+		if err != nil {
+			return err
+		}
+		if b.op == "jobs" {
+			return printSocketJobs(dir, name, os.Stdout)
+		}
+		if err := cancelSocketJob(dir, name, b.jobID); err != nil {
+			return err
+		}
+		return writef(os.Stdout, "cancelled job %s in %s\n", b.jobID, name)
 	case "serve":
 		dir := b.dir
 		if dir == "" {
@@ -237,7 +358,20 @@ func (b *backgroundCmd) Run() error {
 				return err
 			}
 		}
-		return runSocketServer(dir, b.name, b.root)
+		return runSocketServer(dir, b.name, b.defaults, b.root)
+	case "http", "grpc":
+		dir, err := resolveSocketDir(b.dir)
+		if err != nil {
+			return err
+		}
+		if err := pingSocket(socketPath(dir, b.name)); err == nil {
+			return fmt.Errorf("session %s already running; choose another --name", b.name)
+		}
+		service := b.http.service(os.Stderr)
+		if b.op == "grpc" {
+			service = b.grpc.service(os.Stderr)
+		}
+		return runSocketServer(dir, b.name, b.defaults, b.root, service)
 	default:
 		return &UsageError{of: b}
 	}
@@ -345,6 +479,8 @@ func cleanSocketDir(dir string, out io.Writer) error {
 			}
 			continue
 		}
+		removeWithLog(sessionStatePath(dir, st.name))
+		removeWithLog(pidfilePath(dir, st.name))
 		removed = append(removed, st.name)
 	}
 	if len(removed) == 0 {
@@ -371,7 +507,15 @@ func ensureSocketDir(dir string) error {
 	return os.MkdirAll(dir, 0o755)
 }
 
-func startBackgroundServer(dir, desiredName string, r *root) (string, error) {
+func startBackgroundServer(dir, desiredName string, defaults sessionDefaults, r *root) (string, error) {
+	var cfg *config.Config
+	if r != nil {
+		cfg = r.config
+	}
+	defaults, err := defaults.withProfile(cfg)
+	if err != nil {
+		return "", err
+	}
 	if err := ensureSocketDir(dir); err != nil {
 		return "", err
 	}
@@ -400,6 +544,9 @@ func startBackgroundServer(dir, desiredName string, r *root) (string, error) {
 		}
 		break
 	}
+	if err := saveSessionDefaults(dir, name, defaults); err != nil {
+		return "", err
+	}
 	exe, err := os.Executable()
 	if err != nil {
 		return "", err
@@ -410,13 +557,22 @@ func startBackgroundServer(dir, desiredName string, r *root) (string, error) {
 	if err := cmd.Start(); err != nil {
 		return "", err
 	}
-	if err := cmd.Process.Release(); err != nil {
-		return "", err
-	}
+	// Reap the child in the background so a crash during startup can be told
+	// apart from a slow start.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
 	socket := socketPath(dir, name)
 	deadline := time.Now().Add(3 * time.Second)
 	var lastErr error
 	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited without error")
+			}
+			return "", fmt.Errorf("session %s exited during startup: %v", name, err)
+		default:
+		}
 		if err := pingSocket(socket); err != nil {
 			lastErr = normalizeSocketError(err)
 			time.Sleep(50 * time.Millisecond)
@@ -430,6 +586,34 @@ func startBackgroundServer(dir, desiredName string, r *root) (string, error) {
 	return "", fmt.Errorf("session %s did not become ready: %v", name, lastErr)
 }
 
+// ensureBackgroundServer starts the named session unless it already answers
+// pings. A crashed session is restarted with the defaults it was started with.
+func ensureBackgroundServer(dir, name string, r *root, out io.Writer) error {
+	socket := socketPath(dir, name)
+	if info, err := pingSocketInfo(socket); err == nil {
+		return writef(out, "session %s already running (pid %d, up %s)\n", name, info.pid, info.uptime)
+	}
+	pid, err := readPidfile(dir, name)
+	if err != nil {
+		return err
+	}
+	if processAlive(pid) {
+		return fmt.Errorf("session %s (pid %d) is not responding; stop it before restarting", name, pid)
+	}
+	removeWithLog(pidfilePath(dir, name))
+	defaults, err := loadSessionDefaults(dir, name)
+	if err != nil {
+		return err
+	}
+	if _, err := startBackgroundServer(dir, name, defaults, r); err != nil {
+		return err
+	}
+	if pid > 0 {
+		return writef(out, "restarted background session %s at %s (previous pid %d)\n", name, socket, pid)
+	}
+	return writef(out, "started background session %s at %s\n", name, socket)
+}
+
 func selectRunningSocket(dir, preferred string) (string, error) {
 	statuses, err := collectSocketStatuses(dir)
 	if err != nil {
@@ -538,6 +722,9 @@ func (b *backgroundCmd) runCommand(dir string) error {
 		return err
 	}
 	command := strings.Join(commandArgs, " ")
+	if b.stdout {
+		return runSocketImageCommand(dir, name, command, os.Stdout, os.Stderr)
+	}
 	return runSocketCommands(dir, name, []string{command}, os.Stdout, os.Stderr)
 }
 
@@ -572,37 +759,54 @@ func nextSocketName(dir string) (string, error) {
 }
 
 func pingSocket(path string) error {
+	_, err := pingSocketInfo(path)
+	return err
+}
+
+// pongInfo is the liveness detail a server includes in its PONG reply.
+type pongInfo struct {
+	pid    int
+	uptime string
+}
+
+func pingSocketInfo(path string) (pongInfo, error) {
+	var info pongInfo
 	conn, err := net.DialTimeout("unix", path, time.Second)
 	if err != nil {
-		return err
+		return info, err
 	}
 	defer closeWithLog("ping socket", conn)
 	if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
-		return err
+		return info, err
 	}
 	scanner := bufio.NewScanner(conn)
 	if !scanner.Scan() {
 		if err := scanner.Err(); err != nil {
-			return err
+			return info, err
 		}
-		return errors.New("socket closed")
+		return info, errors.New("socket closed")
 	}
 	if scanner.Text() != "READY" {
-		return fmt.Errorf("unexpected greeting: %s", scanner.Text())
+		return info, fmt.Errorf("unexpected greeting: %s", scanner.Text())
 	}
 	if _, err := fmt.Fprintln(conn, "PING"); err != nil {
-		return err
+		return info, err
 	}
 	if !scanner.Scan() {
 		if err := scanner.Err(); err != nil {
-			return err
+			return info, err
 		}
-		return errors.New("no pong received")
+		return info, errors.New("no pong received")
 	}
-	if scanner.Text() != "PONG" {
-		return fmt.Errorf("unexpected response: %s", scanner.Text())
+	fields := strings.Fields(scanner.Text())
+	if len(fields) == 0 || fields[0] != "PONG" {
+		return info, fmt.Errorf("unexpected response: %s", scanner.Text())
 	}
-	return nil
+	if len(fields) >= 3 {
+		info.pid, _ = strconv.Atoi(fields[1])
+		info.uptime = fields[2]
+	}
+	return info, nil
 }
 
 func normalizeSocketError(err error) error {
@@ -618,12 +822,16 @@ func normalizeSocketError(err error) error {
 type taggedWriter struct {
 	w   io.Writer
 	tag string
+	// wrote records whether anything was written, which for the ERR stream
+	// means the command reported a failure.
+	wrote atomic.Bool
 }
 
 func (t *taggedWriter) Write(p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
+	t.wrote.Store(true)
 	buf := make([]byte, len(t.tag)+len(p))
 	copy(buf, t.tag)
 	copy(buf[len(t.tag):], p)
@@ -638,10 +846,71 @@ type interactiveSocketServer struct {
 	path     string
 	stopCh   chan struct{}
 	listener net.Listener
-	execMu   sync.Mutex
+	jobs     *jobQueue
+
+	started time.Time
+	clients atomic.Int32
+
+	idleTimeout  time.Duration
+	lastActivity atomic.Int64
+	onIdle       func()
+}
+
+func newInteractiveSocketServer(session *interactiveCmd, path string) *interactiveSocketServer {
+	return &interactiveSocketServer{
+		session: session,
+		path:    path,
+		stopCh:  make(chan struct{}),
+		jobs:    newJobQueue(session),
+		started: time.Now(),
+	}
+}
+
+func (s *interactiveSocketServer) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// watchIdle shuts the server down once no request has arrived, no job is
+// pending and no annotation window has been open for idleTimeout.
+func (s *interactiveSocketServer) watchIdle() {
+	interval := s.idleTimeout / 4
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+		if len(s.jobs.list()) > 0 || s.session.status().windowOpen {
+			s.touch()
+			continue
+		}
+		last := time.Unix(0, s.lastActivity.Load())
+		if time.Since(last) < s.idleTimeout {
+			continue
+		}
+		log.Printf("session idle for %s; shutting down", s.idleTimeout)
+		if s.onIdle != nil {
+			s.onIdle()
+		}
+		s.shutdown()
+		return
+	}
 }
 
-func runSocketServer(dir, name string, r *root) error {
+// sessionService is an extra front end, such as D-Bus or HTTP, attached to a
+// session for as long as its socket server runs. It returns a function that
+// detaches it again.
+type sessionService func(*interactiveSocketServer) (func(), error)
+
+func runSocketServer(dir, name string, overrides sessionDefaults, r *root, services ...sessionService) error {
 	if err := ensureSocketDir(dir); err != nil {
 		return err
 	}
@@ -649,10 +918,39 @@ func runSocketServer(dir, name string, r *root) error {
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
-	server := &interactiveSocketServer{
-		session: newInteractiveCmd(r),
-		path:    path,
-		stopCh:  make(chan struct{}),
+	session := newInteractiveCmd(r)
+	defaults, err := loadSessionDefaults(dir, name)
+	if err != nil {
+		return err
+	}
+	if overrides.IdleTimeout > 0 {
+		defaults.IdleTimeout = overrides.IdleTimeout
+	}
+	if overrides.MaxImageMB > 0 {
+		defaults.MaxImageMB = overrides.MaxImageMB
+	}
+	if overrides.DBus {
+		defaults.DBus = true
+	}
+	if err := session.applySessionDefaults(defaults); err != nil {
+		return fmt.Errorf("session %s defaults: %w", name, err)
+	}
+	if err := writePidfile(dir, name); err != nil {
+		return err
+	}
+	defer removeWithLog(pidfilePath(dir, name))
+	server := newInteractiveSocketServer(session, path)
+	server.idleTimeout = defaults.IdleTimeout
+	server.onIdle = func() { removeWithLog(sessionStatePath(dir, name)) }
+	if defaults.DBus {
+		services = append(services, startDBusService)
+	}
+	for _, start := range services {
+		stop, err := start(server)
+		if err != nil {
+			return fmt.Errorf("session %s: %w", name, err)
+		}
+		defer stop()
 	}
 	return server.run()
 }
@@ -665,6 +963,12 @@ func (s *interactiveSocketServer) run() error {
 	s.listener = ln
 	defer closeWithLog("socket listener", ln)
 	defer removeWithLog(s.path)
+	defer s.session.finishRecording()
+	go s.jobs.run(s.stopCh)
+	s.touch()
+	if s.idleTimeout > 0 {
+		go s.watchIdle()
+	}
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
@@ -683,8 +987,42 @@ func (s *interactiveSocketServer) run() error {
 	}
 }
 
+// execCommand runs command on the job queue for front ends that report a
+// single result rather than streaming output, returning the first error line
+// the command printed.
+func (s *interactiveSocketServer) execCommand(command string) error {
+	return s.execJob(func(stderr io.Writer) *socketJob { return s.jobs.submit(command, io.Discard, stderr) })
+}
+
+// execCall runs call on the session's job queue, listed as command, and
+// reports the first error it writes, like execCommand.
+func (s *interactiveSocketServer) execCall(command string, call func()) error {
+	return s.execJob(func(stderr io.Writer) *socketJob { return s.jobs.submitCall(command, call, io.Discard, stderr) })
+}
+
+// execJob queues the job submit makes and waits for it, turning the first
+// error it writes into the result.
+func (s *interactiveSocketServer) execJob(submit func(stderr io.Writer) *socketJob) error {
+	var stderr bytes.Buffer
+	s.touch()
+	job := submit(&stderr)
+	res := s.jobs.wait(job)
+	s.touch()
+	if res.err != nil {
+		return res.err
+	}
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if msg := strings.TrimSpace(strings.TrimPrefix(line, "ERR ")); msg != "" {
+			return errors.New(msg)
+		}
+	}
+	return nil
+}
+
 func (s *interactiveSocketServer) handleConn(conn net.Conn) {
 	defer closeWithLog("socket connection", conn)
+	s.clients.Add(1)
+	defer s.clients.Add(-1)
 	if err := writeln(conn, "READY"); err != nil {
 		log.Printf("socket write READY: %v", err)
 		return
@@ -692,9 +1030,11 @@ func (s *interactiveSocketServer) handleConn(conn net.Conn) {
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		line := scanner.Text()
+		s.touch()
 		switch {
 		case line == "PING":
-			if err := writeln(conn, "PONG"); err != nil {
+			uptime := time.Since(s.started).Round(time.Second)
+			if err := writef(conn, "PONG %d %s\n", os.Getpid(), uptime); err != nil {
 				log.Printf("socket write PONG: %v", err)
 				return
 			}
@@ -704,29 +1044,64 @@ func (s *interactiveSocketServer) handleConn(conn net.Conn) {
 			}
 			s.shutdown()
 			return
-		case strings.HasPrefix(line, "EXEC "):
-			command := strings.TrimPrefix(line, "EXEC ")
-			s.execMu.Lock()
-			out := &taggedWriter{w: conn, tag: "OUT "}
-			errW := &taggedWriter{w: conn, tag: "ERR "}
-			restore := s.session.withIO(nil, out, errW)
-			done, execErr := s.session.executeLine(command)
-			restore()
-			s.execMu.Unlock()
-			if execErr != nil {
-				msg := strings.ReplaceAll(execErr.Error(), "\n", "\\n")
+		case line == "STATUS":
+			if err := s.writeStatus(conn); err != nil {
+				log.Printf("socket write STATUS: %v", err)
+				return
+			}
+		case line == "SUBSCRIBE":
+			s.streamEvents(conn, scanner)
+			return
+		case line == "JOBS":
+			if err := s.writeJobs(conn); err != nil {
+				log.Printf("socket write JOBS: %v", err)
+				return
+			}
+		case strings.HasPrefix(line, "CANCEL "):
+			reply := "DONE OK"
+			id, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "CANCEL ")))
+			if err != nil {
+				reply = "DONE ERR invalid job id"
+			} else if err := s.jobs.cancel(id); err != nil {
+				reply = "DONE ERR " + err.Error()
+			}
+			if err := writeln(conn, reply); err != nil {
+				log.Printf("socket write CANCEL reply: %v", err)
+				return
+			}
+		case strings.HasPrefix(line, "EXEC "), strings.HasPrefix(line, "EXECPNG "):
+			request, command, _ := strings.Cut(line, " ")
+			withImage := request == "EXECPNG"
+			// The job id goes out before the job can run, so the client
+			// has it to cancel with before any OUT line arrives.
+			job := s.jobs.prepare(command, withImage, conn, conn)
+			if err := writef(conn, "JOB %d\n", job.id); err != nil {
+				log.Printf("socket write JOB: %v", err)
+				_ = s.jobs.cancel(job.id)
+				return
+			}
+			s.jobs.start(job)
+			res := s.jobs.wait(job)
+			if res.err != nil {
+				msg := strings.ReplaceAll(res.err.Error(), "\n", "\\n")
 				if err := writef(conn, "DONE ERR %s\n", msg); err != nil {
 					log.Printf("socket write DONE ERR: %v", err)
 					return
 				}
 				continue
 			}
-			if done {
+			if res.close {
 				if err := writeln(conn, "DONE OK CLOSE"); err != nil {
 					log.Printf("socket write DONE OK CLOSE: %v", err)
 				}
 				return
 			}
+			if withImage {
+				if err := writePNGData(conn, res.png); err != nil {
+					log.Printf("socket write PNG: %v", err)
+					return
+				}
+			}
 			if err := writeln(conn, "DONE OK"); err != nil {
 				log.Printf("socket write DONE OK: %v", err)
 				return
@@ -740,6 +1115,103 @@ func (s *interactiveSocketServer) handleConn(conn net.Conn) {
 	}
 }
 
+// pngChunkBytes is the amount of PNG data carried by each DATA line; it
+// encodes to 4096 base64 characters, well inside the client's line limit.
+const pngChunkBytes = 3072
+
+// writePNGData sends an image inline as "PNG <bytes>" followed by
+// "DATA <base64>" lines, so it can share the connection with OUT and ERR text.
+func writePNGData(conn net.Conn, data []byte) error {
+	if err := writef(conn, "PNG %d\n", len(data)); err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n := min(len(data), pngChunkBytes)
+		if err := writef(conn, "DATA %s\n", base64.StdEncoding.EncodeToString(data[:n])); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// writeStatus reports the session state as "STATUS <key> <value>" lines
+// followed by DONE OK.
+func (s *interactiveSocketServer) writeStatus(conn net.Conn) error {
+	st := s.session.status()
+	image := "none"
+	if st.hasImage {
+		image = fmt.Sprintf("%dx%d", st.width, st.height)
+	}
+	window := "closed"
+	if st.windowOpen {
+		window = "open"
+	}
+	saved := st.lastSaved
+	if saved == "" {
+		saved = "-"
+	}
+	fields := [][2]string{
+		{"pid", strconv.Itoa(os.Getpid())},
+		{"uptime", time.Since(s.started).Round(time.Second).String()},
+		{"image", image},
+		{"window", window},
+		{"saved", saved},
+		// The connection asking for the status is not counted.
+		{"clients", strconv.Itoa(int(s.clients.Load()) - 1)},
+		{"pending", strconv.Itoa(s.jobs.queued())},
+	}
+	for _, f := range fields {
+		if err := writef(conn, "STATUS %s %s\n", f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return writeln(conn, "DONE OK")
+}
+
+// writeJobs lists queued and running commands as "JOB <id> <state> <command>"
+// lines followed by DONE OK.
+func (s *interactiveSocketServer) writeJobs(conn net.Conn) error {
+	for _, job := range s.jobs.list() {
+		if err := writef(conn, "JOB %d %s %s\n", job.id, job.state, job.command); err != nil {
+			return err
+		}
+	}
+	return writeln(conn, "DONE OK")
+}
+
+// streamEvents turns conn into an event stream. Each session event is written
+// as an "EVENT <kind> <detail>" line until the client disconnects or the
+// server shuts down.
+func (s *interactiveSocketServer) streamEvents(conn net.Conn, scanner *bufio.Scanner) {
+	events, cancel := s.session.events.subscribe()
+	defer cancel()
+	if err := writeln(conn, "SUBSCRIBED"); err != nil {
+		log.Printf("socket write SUBSCRIBED: %v", err)
+		return
+	}
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for scanner.Scan() {
+			// Requests are ignored once a connection is streaming events.
+		}
+	}()
+	for {
+		select {
+		case ev := <-events:
+			if err := writef(conn, "EVENT %s\n", ev); err != nil {
+				log.Printf("socket write EVENT: %v", err)
+				return
+			}
+		case <-gone:
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
 func (s *interactiveSocketServer) shutdown() {
 	select {
 	case <-s.stopCh:
@@ -753,22 +1225,33 @@ func (s *interactiveSocketServer) shutdown() {
 	removeWithLog(s.path)
 }
 
-func runSocketCommands(dir, name string, commands []string, stdout, stderr io.Writer) error {
+// dialSession connects to a session and consumes its READY greeting.
+func dialSession(dir, name string) (net.Conn, *bufio.Scanner, error) {
 	conn, err := net.Dial("unix", socketPath(dir, name))
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer closeWithLog("socket client", conn)
 	scanner := bufio.NewScanner(conn)
 	if !scanner.Scan() {
+		closeWithLog("socket client", conn)
 		if err := scanner.Err(); err != nil {
-			return err
+			return nil, nil, err
 		}
-		return errors.New("socket closed")
+		return nil, nil, errors.New("socket closed")
 	}
 	if scanner.Text() != "READY" {
-		return fmt.Errorf("unexpected greeting: %s", scanner.Text())
+		closeWithLog("socket client", conn)
+		return nil, nil, fmt.Errorf("unexpected greeting: %s", scanner.Text())
+	}
+	return conn, scanner, nil
+}
+
+func runSocketCommands(dir, name string, commands []string, stdout, stderr io.Writer) error {
+	conn, scanner, err := dialSession(dir, name)
+	if err != nil {
+		return err
 	}
+	defer closeWithLog("socket client", conn)
 	for _, cmd := range commands {
 		if err := executeOverSocket(conn, scanner, cmd, stdout, stderr); err != nil {
 			if errors.Is(err, errSocketClosed) {
@@ -781,12 +1264,28 @@ func runSocketCommands(dir, name string, commands []string, stdout, stderr io.Wr
 }
 
 func executeOverSocket(conn net.Conn, scanner *bufio.Scanner, cmd string, stdout, stderr io.Writer) error {
-	if _, err := fmt.Fprintf(conn, "EXEC %s\n", cmd); err != nil {
+	return executeRequest(conn, scanner, "EXEC "+cmd, stdout, stderr, nil)
+}
+
+// executeRequest sends an EXEC or EXECPNG request and relays its reply. PNG
+// data lines are decoded into image; it may be nil for plain EXEC.
+func executeRequest(conn net.Conn, scanner *bufio.Scanner, request string, stdout, stderr, image io.Writer) error {
+	if _, err := fmt.Fprintf(conn, "%s\n", request); err != nil {
 		return err
 	}
 	for scanner.Scan() {
 		line := scanner.Text()
 		switch {
+		case strings.HasPrefix(line, "JOB "), strings.HasPrefix(line, "PNG "):
+			continue
+		case strings.HasPrefix(line, "DATA ") && image != nil:
+			data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "DATA "))
+			if err != nil {
+				return fmt.Errorf("decode image data: %w", err)
+			}
+			if _, err := image.Write(data); err != nil {
+				return err
+			}
 		case strings.HasPrefix(line, "OUT "):
 			if err := writeln(stdout, strings.TrimPrefix(line, "OUT ")); err != nil {
 				return err
@@ -817,6 +1316,29 @@ func executeOverSocket(conn net.Conn, scanner *bufio.Scanner, cmd string, stdout
 
 var errSocketClosed = errors.New("socket closed by server")
 
+// runSocketImageCommand runs command and writes the resulting session image to
+// image as PNG. Command output goes to stderr so image receives only PNG
+// bytes, and nothing is written to image unless the command succeeds.
+func runSocketImageCommand(dir, name, command string, image, stderr io.Writer) error {
+	conn, scanner, err := dialSession(dir, name)
+	if err != nil {
+		return err
+	}
+	defer closeWithLog("socket client", conn)
+	var buf bytes.Buffer
+	if err := executeRequest(conn, scanner, "EXECPNG "+command, stderr, stderr, &buf); err != nil {
+		if errors.Is(err, errSocketClosed) {
+			return errors.New("session closed before returning an image")
+		}
+		return err
+	}
+	if buf.Len() == 0 {
+		return errors.New("session returned no image")
+	}
+	_, err = buf.WriteTo(image)
+	return err
+}
+
 func attachSocket(dir, name string, stdin io.Reader, stdout, stderr io.Writer) error {
 	conn, err := net.Dial("unix", socketPath(dir, name))
 	if err != nil {
@@ -833,31 +1355,122 @@ func attachSocket(dir, name string, stdin io.Reader, stdout, stderr io.Writer) e
 	if scanner.Text() != "READY" {
 		return fmt.Errorf("unexpected greeting: %s", scanner.Text())
 	}
-	input := bufio.NewScanner(stdin)
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	prompt := newLinePrompt(stdin, stdout, completeInteractive)
+	defer closeWithLog("prompt", prompt)
+	abandoned := 0
 	for {
-		if _, err := fmt.Fprint(stdout, "> "); err != nil {
+		line, err := prompt.ReadLine("> ")
+		if errors.Is(err, errPromptInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
 			return err
 		}
-		if !input.Scan() {
-			return input.Err()
+		select {
+		case <-interrupts:
+		default:
 		}
-		line := input.Text()
 		if _, err := fmt.Fprintf(conn, "EXEC %s\n", line); err != nil {
 			return err
 		}
-		if err := consumeSocketResponse(scanner, stdout, stderr); err != nil {
-			if errors.Is(err, errSocketClosed) {
+		cancel := func(id string) error { return cancelSocketJob(dir, name, id) }
+		err = awaitSocketResponse(lines, interrupts, abandoned, cancel, stdout, stderr)
+		abandoned = 0
+		var cmdErr *socketCommandError
+		switch {
+		case errors.Is(err, errCommandAbandoned):
+			// The server still finishes the command; its output is skipped
+			// when the next response is read.
+			abandoned++
+			if err := writeln(stderr, "command cancelled"); err != nil {
+				return err
+			}
+		case errors.As(err, &cmdErr):
+			if err := writeln(stderr, cmdErr.msg); err != nil {
+				return err
+			}
+		case errors.Is(err, errSocketClosed):
+			select {
+			case rerr := <-readErr:
+				return rerr
+			default:
 				return nil
 			}
+		case err != nil:
 			return err
 		}
 	}
 }
 
-func consumeSocketResponse(scanner *bufio.Scanner, stdout, stderr io.Writer) error {
-	for scanner.Scan() {
-		line := scanner.Text()
+var errCommandAbandoned = errors.New("command abandoned")
+
+// socketCommandError carries a DONE ERR message reported by the server.
+type socketCommandError struct {
+	msg string
+}
+
+func (e *socketCommandError) Error() string { return e.msg }
+
+// awaitSocketResponse relays one command's output. The first skip responses
+// belong to commands the user abandoned and are discarded. On an interrupt
+// the job is cancelled on the server via cancel when possible; otherwise
+// waiting stops and errCommandAbandoned is returned.
+func awaitSocketResponse(lines <-chan string, interrupts <-chan os.Signal, skip int, cancel func(id string) error, stdout, stderr io.Writer) error {
+	jobID := ""
+	for {
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				return errSocketClosed
+			}
+			line = l
+		case <-interrupts:
+			if skip > 0 {
+				continue
+			}
+			if jobID != "" && cancel != nil {
+				if err := cancel(jobID); err == nil {
+					continue
+				}
+			}
+			return errCommandAbandoned
+		}
+		isDone := strings.HasPrefix(line, "DONE ")
+		if isDone && strings.HasSuffix(line, "CLOSE") {
+			return errSocketClosed
+		}
+		if skip > 0 {
+			if isDone {
+				skip--
+			}
+			continue
+		}
 		switch {
+		case strings.HasPrefix(line, "JOB "):
+			jobID = strings.TrimPrefix(line, "JOB ")
 		case strings.HasPrefix(line, "OUT "):
 			if err := writeln(stdout, strings.TrimPrefix(line, "OUT ")); err != nil {
 				return err
@@ -867,32 +1480,211 @@ func consumeSocketResponse(scanner *bufio.Scanner, stdout, stderr io.Writer) err
 				return err
 			}
 		case strings.HasPrefix(line, "DONE OK"):
-			if strings.HasSuffix(line, "CLOSE") {
-				return errSocketClosed
-			}
 			return nil
 		case strings.HasPrefix(line, "DONE ERR "):
 			msg := strings.TrimPrefix(line, "DONE ERR ")
-			return errors.New(strings.ReplaceAll(msg, "\\n", "\n"))
+			return &socketCommandError{msg: strings.ReplaceAll(msg, "\\n", "\n")}
 		default:
 			if err := writeln(stdout, line); err != nil {
 				return err
 			}
 		}
 	}
+}
+
+// socketRequest sends a single request line and returns the lines up to and
+// including the terminating DONE line.
+func socketRequest(path, request string) ([]string, error) {
+	return socketRequestTimeout(path, request, 2*time.Second)
+}
+
+// socketRequestTimeout is socketRequest for requests, such as EXEC of a
+// capture, that may legitimately take longer than a status query.
+func socketRequestTimeout(path, request string, timeout time.Duration) ([]string, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer closeWithLog("socket client", conn)
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("socket closed")
+	}
+	if scanner.Text() != "READY" {
+		return nil, fmt.Errorf("unexpected greeting: %s", scanner.Text())
+	}
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines = append(lines, line)
+		if strings.HasPrefix(line, "DONE ") {
+			return lines, nil
+		}
+	}
 	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("socket closed")
+}
+
+func cancelSocketJob(dir, name, id string) error {
+	lines, err := socketRequest(socketPath(dir, name), "CANCEL "+id)
+	if err != nil {
+		return err
+	}
+	last := lines[len(lines)-1]
+	if msg, ok := strings.CutPrefix(last, "DONE ERR "); ok {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func printSocketJobs(dir, name string, out io.Writer) error {
+	lines, err := socketRequest(socketPath(dir, name), "JOBS")
+	if err != nil {
+		return err
+	}
+	if len(lines) == 1 {
+		return writeln(out, "no jobs")
+	}
+	for _, line := range lines[:len(lines)-1] {
+		fields := strings.SplitN(strings.TrimPrefix(line, "JOB "), " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		if err := writef(out, "%4s  %-9s %s\n", fields[0], fields[1], fields[2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// querySessionStatus sends STATUS to the socket at path and returns the
+// reported fields in order.
+func querySessionStatus(path string) ([][2]string, error) {
+	lines, err := socketRequest(path, "STATUS")
+	if err != nil {
+		return nil, err
+	}
+	var fields [][2]string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "STATUS "):
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, "STATUS "), " ")
+			fields = append(fields, [2]string{key, value})
+		case line == "DONE OK":
+			return fields, nil
+		default:
+			return nil, fmt.Errorf("unexpected response: %s", line)
+		}
+	}
+	return fields, nil
+}
+
+func printSessionStatuses(dir, name string, out io.Writer) error {
+	statuses, err := collectSocketStatuses(dir)
+	if err != nil {
 		return err
 	}
-	return errSocketClosed
+	if name != "" {
+		filtered := statuses[:0]
+		for _, st := range statuses {
+			if st.name == name {
+				filtered = append(filtered, st)
+			}
+		}
+		if len(filtered) == 0 {
+			return fmt.Errorf("session %s not found", name)
+		}
+		statuses = filtered
+	}
+	if len(statuses) == 0 {
+		return writeln(out, "no sockets found")
+	}
+	for _, st := range statuses {
+		if st.err != nil {
+			if err := writef(out, "%s: dead (%v)\n", st.name, st.err); err != nil {
+				return err
+			}
+			continue
+		}
+		fields, err := querySessionStatus(filepath.Join(dir, st.file))
+		if err != nil {
+			if err := writef(out, "%s: unavailable (%v)\n", st.name, normalizeSocketError(err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writef(out, "%s:\n", st.name); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := writef(out, "  %-8s %s\n", f[0]+":", f[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func subscribeSocket(dir, name string, stdout io.Writer) error {
+	conn, err := net.Dial("unix", socketPath(dir, name))
+	if err != nil {
+		return err
+	}
+	defer closeWithLog("socket client", conn)
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("socket closed")
+	}
+	if scanner.Text() != "READY" {
+		return fmt.Errorf("unexpected greeting: %s", scanner.Text())
+	}
+	if _, err := fmt.Fprintln(conn, "SUBSCRIBE"); err != nil {
+		return err
+	}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("socket closed")
+	}
+	if scanner.Text() != "SUBSCRIBED" {
+		return fmt.Errorf("unexpected response: %s", scanner.Text())
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "EVENT ") {
+			continue
+		}
+		if err := writeln(stdout, strings.TrimPrefix(line, "EVENT ")); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
 }
 
 func stopSocket(dir, name string) error {
+	defer removeWithLog(sessionStatePath(dir, name))
 	path := socketPath(dir, name)
 	conn, err := net.Dial("unix", path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
+		removeWithLog(pidfilePath(dir, name))
 		rmErr := os.Remove(path)
 		if rmErr == nil || errors.Is(rmErr, os.ErrNotExist) {
 			return nil
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startTestSocketServer(t *testing.T) *interactiveSocketServer {
+	t.Helper()
+	dir := t.TempDir()
+	server := newInteractiveSocketServer(newInteractiveCmd(nil), filepath.Join(dir, "test.sock"))
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.run() }()
+	t.Cleanup(func() {
+		server.shutdown()
+		if err := <-errCh; err != nil {
+			t.Errorf("server run: %v", err)
+		}
+	})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if err := pingSocket(server.path); err == nil {
+			return server
+		} else if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSocketSubscribeStreamsEvents(t *testing.T) {
+	server := startTestSocketServer(t)
+	conn, err := net.Dial("unix", server.path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer closeWithLog("test client", conn)
+	if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("deadline: %v", err)
+	}
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() || scanner.Text() != "READY" {
+		t.Fatalf("expected READY, got %q", scanner.Text())
+	}
+	if _, err := fmt.Fprintln(conn, "SUBSCRIBE"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !scanner.Scan() || scanner.Text() != "SUBSCRIBED" {
+		t.Fatalf("expected SUBSCRIBED, got %q", scanner.Text())
+	}
+
+	server.session.events.publish("save", "/tmp/shot.png")
+	server.session.events.publish("copy", "multi\nline")
+
+	want := []string{"EVENT save /tmp/shot.png", "EVENT copy multi\\nline"}
+	for _, expected := range want {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended early: %v", scanner.Err())
+		}
+		if got := scanner.Text(); got != expected {
+			t.Fatalf("got %q, want %q", got, expected)
+		}
+	}
+}
+
+func TestSessionDefaultsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	want := sessionDefaults{OutDir: "/tmp/shots", Pattern: "bug-{date}", Color: "red", Width: 6}
+	if err := saveSessionDefaults(dir, "demo", want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := loadSessionDefaults(dir, "demo")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	if name := expandSavePattern(got.Pattern, now, nil); name != "bug-20240305.png" {
+		t.Fatalf("unexpected pattern expansion %q", name)
+	}
+
+	if err := saveSessionDefaults(dir, "demo", sessionDefaults{}); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if got, err := loadSessionDefaults(dir, "demo"); err != nil || !got.empty() {
+		t.Fatalf("expected cleared defaults, got %+v (%v)", got, err)
+	}
+}
+
+func TestAwaitSocketResponseSkipsAbandoned(t *testing.T) {
+	lines := make(chan string, 8)
+	for _, l := range []string{"OUT stale", "DONE OK", "OUT fresh", "ERR warn", "DONE OK"} {
+		lines <- l
+	}
+	var stdout, stderr strings.Builder
+	if err := awaitSocketResponse(lines, nil, 1, nil, &stdout, &stderr); err != nil {
+		t.Fatalf("await: %v", err)
+	}
+	if stdout.String() != "fresh\n" || stderr.String() != "warn\n" {
+		t.Fatalf("unexpected output %q / %q", stdout.String(), stderr.String())
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	interrupts <- os.Interrupt
+	if err := awaitSocketResponse(make(chan string), interrupts, 0, nil, &stdout, &stderr); !errors.Is(err, errCommandAbandoned) {
+		t.Fatalf("expected errCommandAbandoned, got %v", err)
+	}
+}
+
+func TestSocketStatusReportsSession(t *testing.T) {
+	server := startTestSocketServer(t)
+	server.session.setImage(image.NewRGBA(image.Rect(0, 0, 64, 32)))
+
+	fields, err := querySessionStatus(server.path)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	got := make(map[string]string, len(fields))
+	for _, f := range fields {
+		got[f[0]] = f[1]
+	}
+	want := map[string]string{
+		"pid":     strconv.Itoa(os.Getpid()),
+		"image":   "64x32",
+		"window":  "closed",
+		"saved":   "-",
+		"clients": "0",
+		"pending": "0",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+	if _, ok := got["uptime"]; !ok {
+		t.Errorf("missing uptime in %v", got)
+	}
+}
+
+func TestPingReportsPidAndPidfileLiveness(t *testing.T) {
+	server := startTestSocketServer(t)
+	info, err := pingSocketInfo(server.path)
+	if err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if info.pid != os.Getpid() || info.uptime == "" {
+		t.Errorf("unexpected pong %+v", info)
+	}
+
+	dir := t.TempDir()
+	if pid, err := readPidfile(dir, "demo"); err != nil || pid != 0 {
+		t.Fatalf("missing pidfile: pid %d, err %v", pid, err)
+	}
+	if err := writePidfile(dir, "demo"); err != nil {
+		t.Fatalf("write pidfile: %v", err)
+	}
+	pid, err := readPidfile(dir, "demo")
+	if err != nil || pid != os.Getpid() {
+		t.Fatalf("read pidfile: pid %d, err %v", pid, err)
+	}
+	if !processAlive(pid) {
+		t.Errorf("own pid %d reported dead", pid)
+	}
+	if processAlive(0) {
+		t.Errorf("pid 0 reported alive")
+	}
+}
+
+func TestSocketCancelQueuedJob(t *testing.T) {
+	server := startTestSocketServer(t)
+
+	// Hold the session lock so the first command blocks while running and
+	// the second stays queued behind it.
+	server.session.mu.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			server.session.mu.Unlock()
+		}
+	}()
+
+	dial := func() (net.Conn, *bufio.Scanner) {
+		conn, err := net.Dial("unix", server.path)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { closeWithLog("test client", conn) })
+		if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			t.Fatalf("deadline: %v", err)
+		}
+		scanner := bufio.NewScanner(conn)
+		if !scanner.Scan() || scanner.Text() != "READY" {
+			t.Fatalf("expected READY, got %q", scanner.Text())
+		}
+		return conn, scanner
+	}
+	exec := func(conn net.Conn, scanner *bufio.Scanner, command string) string {
+		if _, err := fmt.Fprintf(conn, "EXEC %s\n", command); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "JOB ") {
+			t.Fatalf("expected JOB line, got %q", scanner.Text())
+		}
+		return strings.TrimPrefix(scanner.Text(), "JOB ")
+	}
+
+	runConn, runScanner := dial()
+	runID := exec(runConn, runScanner, "colors")
+	queuedConn, queuedScanner := dial()
+	queuedID := exec(queuedConn, queuedScanner, "widths")
+
+	lines, err := socketRequest(server.path, "JOBS")
+	if err != nil {
+		t.Fatalf("jobs: %v", err)
+	}
+	want := []string{"JOB " + runID + " running colors", "JOB " + queuedID + " queued widths", "DONE OK"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("got jobs %q, want %q", lines, want)
+	}
+
+	if err := cancelSocketJob(filepath.Dir(server.path), "test", queuedID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if !queuedScanner.Scan() || queuedScanner.Text() != "DONE ERR cancelled" {
+		t.Fatalf("expected cancellation, got %q", queuedScanner.Text())
+	}
+	if err := cancelSocketJob(filepath.Dir(server.path), "test", "999"); err == nil {
+		t.Fatalf("expected error cancelling unknown job")
+	}
+
+	server.session.mu.Unlock()
+	locked = false
+	for runScanner.Scan() {
+		if runScanner.Text() == "DONE OK" {
+			return
+		}
+	}
+	t.Fatalf("running job did not finish: %v", runScanner.Err())
+}
+
+func TestSocketServerIdleTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idle.sock")
+	server := newInteractiveSocketServer(newInteractiveCmd(nil), path)
+	server.idleTimeout = 50 * time.Millisecond
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.run() }()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		server.shutdown()
+		t.Fatalf("idle session did not shut down")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected socket to be removed, got %v", err)
+	}
+}
+
+func TestSetImageHonorsMemoryLimit(t *testing.T) {
+	session := newInteractiveCmd(nil)
+	if err := session.applySessionDefaults(sessionDefaults{MaxImageMB: 1}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if err := session.setImage(image.NewRGBA(image.Rect(0, 0, 1024, 512))); err == nil {
+		t.Fatalf("expected a 2MB image to exceed the 1MB limit")
+	}
+	if err := session.setImage(image.NewRGBA(image.Rect(0, 0, 256, 256))); err != nil {
+		t.Fatalf("small image rejected: %v", err)
+	}
+}
+
+func TestInteractiveOpenLoadsPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 5, 3))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	session := newInteractiveCmd(nil)
+	var stdout, stderr strings.Builder
+	restore := session.withIO(nil, &stdout, &stderr)
+	defer restore()
+	if _, err := session.executeLine("open " + path); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if stderr.Len() > 0 {
+		t.Fatalf("unexpected error output %q", stderr.String())
+	}
+	if st := session.status(); !st.hasImage || st.width != 5 || st.height != 3 {
+		t.Fatalf("unexpected session state %+v", st)
+	}
+}
+
+func TestRunImageCommandStreamsPNG(t *testing.T) {
+	server := startTestSocketServer(t)
+	src := image.NewNRGBA(image.Rect(0, 0, 120, 80))
+	seed := uint32(1)
+	for idx := range src.Pix {
+		seed = seed*1664525 + 1013904223
+		src.Pix[idx] = byte(seed >> 24)
+	}
+	path := filepath.Join(t.TempDir(), "in.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(f, src); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	dir, name := filepath.Dir(server.path), strings.TrimSuffix(filepath.Base(server.path), ".sock")
+	var out bytes.Buffer
+	var stderr strings.Builder
+	if err := runSocketImageCommand(dir, name, "open "+path, &out, &stderr); err != nil {
+		t.Fatalf("run: %v (stderr %q)", err, stderr.String())
+	}
+	if out.Len() <= pngChunkBytes {
+		t.Fatalf("expected an image spanning several DATA lines, got %d bytes", out.Len())
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatalf("decode streamed PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 120 || img.Bounds().Dy() != 80 {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+
+	out.Reset()
+	if err := runSocketImageCommand(dir, name, "open "+filepath.Join(dir, "missing.png"), &out, &stderr); err == nil {
+		t.Fatalf("expected error for failed command")
+	}
+	if out.Len() != 0 {
+		t.Fatalf("failed command wrote %d bytes to stdout", out.Len())
+	}
+}
+
+func TestExecCallKeepsPathWhole(t *testing.T) {
+	server := startTestSocketServer(t)
+	// Doubled spaces do not survive being split into command words.
+	path := filepath.Join(t.TempDir(), "my  shot.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 5, 3))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if err := server.execCall("open "+path, func() { server.session.openFile(path) }); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if st := server.session.status(); !st.hasImage || st.width != 5 || st.height != 3 {
+		t.Fatalf("unexpected session state %+v", st)
+	}
+	missing := path + ".missing"
+	if err := server.execCall("open "+missing, func() { server.session.openFile(missing) }); err == nil {
+		t.Fatal("opening a missing file reported no error")
+	}
+}
@@ -7,4 +7,7 @@ var (
 	captureWindowFn     = capture.CaptureWindow
 	captureRegionFn     = capture.CaptureRegion
 	captureRegionRectFn = capture.CaptureRegionRect
+	captureWorkspaceFn  = capture.CaptureWorkspace
+	recaptureFn         = capture.Recapture
+	canHideOwnWindowsFn = capture.CanHideOwnWindows
 )
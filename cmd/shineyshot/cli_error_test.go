@@ -1,18 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"image"
+	"image/png"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/config"
+	"github.com/example/shineyshot/internal/notify"
 )
 
 func TestSnapshotRunCaptureError(t *testing.T) {
 	original := captureScreenshotFn
 	sentinel := errors.New("boom")
-	captureScreenshotFn = func(string, capture.CaptureOptions) (*image.RGBA, error) { return nil, sentinel }
+	captureScreenshotFn = func(string, capture.CaptureOptions) (capture.CaptureResult, error) {
+		return capture.CaptureResult{}, sentinel
+	}
 	t.Cleanup(func() { captureScreenshotFn = original })
 
 	cmd := &snapshotCmd{mode: "screen", stdout: true}
@@ -28,12 +37,171 @@ func TestSnapshotRunCaptureError(t *testing.T) {
 	}
 }
 
-func TestAnnotateRunCaptureError(t *testing.T) {
+func TestSnapshotWorkspaceCapture(t *testing.T) {
+	original := captureWorkspaceFn
+	sentinel := errors.New("unmapped")
+	var got int
+	captureWorkspaceFn = func(desktop int, _ capture.CaptureOptions) (capture.CaptureResult, error) {
+		got = desktop
+		return capture.CaptureResult{}, sentinel
+	}
+	t.Cleanup(func() { captureWorkspaceFn = original })
+
+	cmd, err := parseSnapshotCmd([]string{"-stdout", "capture", "workspace", "2"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); !errors.Is(err, sentinel) || got != 2 {
+		t.Fatalf("expected desktop 2 capture error, got desktop %d, %v", got, err)
+	}
+	cmd, err = parseSnapshotCmd([]string{"-stdout", "workspace", "two"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "desktop number") {
+		t.Fatalf("expected desktop number error, got %v", err)
+	}
+}
+
+func TestSnapshotPassesDelayToCapture(t *testing.T) {
 	original := captureScreenshotFn
-	sentinel := errors.New("denied")
-	captureScreenshotFn = func(string, capture.CaptureOptions) (*image.RGBA, error) { return nil, sentinel }
+	var got capture.CaptureOptions
+	captureScreenshotFn = func(_ string, opts capture.CaptureOptions) (capture.CaptureResult, error) {
+		got = opts
+		return capture.CaptureResult{}, errors.New("stop")
+	}
 	t.Cleanup(func() { captureScreenshotFn = original })
 
+	cmd, err := parseSnapshotCmd([]string{"-stdout", "-delay", "3s", "screen"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	_ = cmd.Run()
+	if got.Delay != 3*time.Second || got.Progress == nil {
+		t.Fatalf("expected a 3s delay with progress, got %v", got.Delay)
+	}
+}
+
+func TestSnapshotWritesCaptureMetadata(t *testing.T) {
+	original := captureWindowFn
+	captureWindowFn = func(string, capture.CaptureOptions) (capture.CaptureResult, error) {
+		return capture.CaptureResult{
+			Image:  image.NewRGBA(image.Rect(0, 0, 4, 2)),
+			Time:   time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+			Window: &capture.WindowInfo{Title: "notes.txt", Class: "gedit"},
+			Scale:  1,
+		}, nil
+	}
+	t.Cleanup(func() { captureWindowFn = original })
+
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "{app}-{width}x{height}")
+	cmd, err := parseSnapshotCmd([]string{"-output", pattern, "-png-metadata", "window"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "gedit-4x2.png"))
+	if err != nil {
+		t.Fatalf("expected expanded output name: %v", err)
+	}
+	if !bytes.Contains(data, []byte("shineyshot:window-class\x00\x00\x00\x00\x00gedit")) {
+		t.Fatalf("expected window class text chunk in output")
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+}
+
+func TestSnapshotPickSelectsWindowMode(t *testing.T) {
+	cmd, err := parseSnapshotCmd([]string{"-pick"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cmd.mode != "window" || cmd.window != capture.PickWindowSelector {
+		t.Fatalf("expected a picked window capture, got mode %q window %q", cmd.mode, cmd.window)
+	}
+	if _, err := parseSnapshotCmd([]string{"-pick", "screen"}, &root{program: "shineyshot"}); err == nil {
+		t.Fatalf("expected -pick to be refused for screen captures")
+	}
+	if _, err := parseSnapshotCmd([]string{"-pick", "window", "firefox"}, &root{program: "shineyshot"}); err == nil {
+		t.Fatalf("expected -pick to be refused alongside a selector")
+	}
+}
+
+func TestSnapshotSameAsLastRepeatsCapture(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	originalScreen, originalRecapture := captureScreenshotFn, recaptureFn
+	t.Cleanup(func() { captureScreenshotFn, recaptureFn = originalScreen, originalRecapture })
+	captureScreenshotFn = func(string, capture.CaptureOptions) (capture.CaptureResult, error) {
+		return capture.CaptureResult{Image: image.NewRGBA(image.Rect(0, 0, 2, 2)), Token: "screen-token"}, nil
+	}
+	var repeated capture.RecaptureToken
+	recaptureFn = func(token capture.RecaptureToken, _ capture.CaptureOptions) (capture.CaptureResult, error) {
+		repeated = token
+		return capture.CaptureResult{Image: image.NewRGBA(image.Rect(0, 0, 2, 2)), Token: token}, nil
+	}
+	dir := t.TempDir()
+
+	cmd, err := parseSnapshotCmd([]string{"-same-as-last"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cmd.output = filepath.Join(dir, "none.png")
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "no previous snapshot") {
+		t.Fatalf("expected a missing previous capture to be reported, got %v", err)
+	}
+
+	cmd, err = parseSnapshotCmd([]string{"-output", filepath.Join(dir, "first.png"), "screen", "DP-1"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	cmd, err = parseSnapshotCmd([]string{"-output", filepath.Join(dir, "again.png"), "-same-as-last"}, &root{program: "shineyshot"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run again: %v", err)
+	}
+	if repeated != "screen-token" {
+		t.Fatalf("expected the stored token to be recaptured, got %q", repeated)
+	}
+	if _, err := parseSnapshotCmd([]string{"-same-as-last", "window"}, &root{program: "shineyshot"}); err == nil {
+		t.Fatalf("expected -same-as-last to be refused alongside a mode")
+	}
+}
+
+func TestParseWindowsMinSize(t *testing.T) {
+	for val, want := range map[string]image.Point{"64": image.Pt(64, 64), "200x50": image.Pt(200, 50), " 3X4 ": image.Pt(3, 4)} {
+		got, err := parseMinSize(val)
+		if err != nil || got != want {
+			t.Errorf("parseMinSize(%q) = %v, %v; want %v", val, got, err, want)
+		}
+	}
+	if _, err := parseWindowsCmd([]string{"-min-size", "wide"}, &root{program: "shineyshot"}); err == nil {
+		t.Fatalf("expected an invalid -min-size to be refused")
+	}
+}
+
+func TestAnnotateRunCaptureError(t *testing.T) {
+	original, originalHide := captureScreenshotFn, canHideOwnWindowsFn
+	sentinel := errors.New("denied")
+	captureScreenshotFn = func(string, capture.CaptureOptions) (capture.CaptureResult, error) {
+		return capture.CaptureResult{}, sentinel
+	}
+	// Without a way to hide the editor, annotate captures before opening
+	// it and reports failures as errors.
+	canHideOwnWindowsFn = func() bool { return false }
+	t.Cleanup(func() { captureScreenshotFn, canHideOwnWindowsFn = original, originalHide })
+	// The session directory is kept away from the real one.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
 	cmd := &annotateCmd{action: "capture", capture: annotateCaptureConfig{target: "screen"}}
 	if err := cmd.Run(); err == nil {
 		t.Fatalf("expected error")
@@ -89,3 +257,26 @@ func TestFileCaptureRejectsClipboard(t *testing.T) {
 		t.Fatalf("expected error to mention %q, got %v", want, err)
 	}
 }
+
+func TestSettingsKeepUnloadedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.rc")
+	if err := os.WriteFile(path, []byte("[broken\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	original := configPathOverride
+	configPathOverride = path
+	t.Cleanup(func() { configPathOverride = original })
+
+	r := &root{config: config.New(), configErr: errors.New("parse error")}
+	cmd := &notifyCmd{root: r, events: []notify.Event{notify.EventSave}}
+	if err := cmd.runSet(true); err == nil {
+		t.Fatalf("expected an error from notify")
+	}
+	shortcuts := &shortcutsCmd{root: r, action: "set", name: "save", keys: "ctrl+s"}
+	if err := shortcuts.Run(); err == nil {
+		t.Fatalf("expected an error from shortcuts")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "[broken\n" {
+		t.Fatalf("config file changed: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/clipboard"
+)
+
+// defaultClipboardPattern names images saved by clipboard watch.
+const defaultClipboardPattern = "clipboard-{timestamp}.png"
+
+type clipboardCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	action  string
+	output  string
+	primary bool
+	// entry is the history entry to copy again, counted from 1 for the
+	// newest, or 0 to list the history.
+	entry int
+}
+
+func parseClipboardCmd(args []string, r *root) (*clipboardCmd, error) {
+	fs := flag.NewFlagSet("clipboard", flag.ExitOnError)
+	cmd := &clipboardCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	fs.StringVar(&cmd.output, "output", defaultClipboardPattern, "file name pattern for saved images; expands {timestamp}, {date}, {time}, {width} and {height}")
+	fs.BoolVar(&cmd.primary, "primary", false, "watch the primary selection instead of the clipboard; with history copy, also copy to it")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.action = strings.ToLower(fs.Arg(0))
+	// Flags may also follow the action.
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	switch cmd.action {
+	case "watch":
+		if fs.NArg() > 0 {
+			return nil, &UsageError{of: cmd}
+		}
+	case "history":
+		switch {
+		case fs.NArg() == 0:
+		case fs.NArg() == 2 && strings.EqualFold(fs.Arg(0), "copy"):
+			n, err := strconv.Atoi(fs.Arg(1))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("history entry must be a positive number, got %q", fs.Arg(1))
+			}
+			cmd.entry = n
+		default:
+			return nil, &UsageError{of: cmd}
+		}
+	default:
+		return nil, &UsageError{of: cmd}
+	}
+	return cmd, nil
+}
+
+func (c *clipboardCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *clipboardCmd) Template() string {
+	return "clipboard.txt"
+}
+
+func (c *clipboardCmd) Run() error {
+	if c.action == "history" {
+		return c.runHistory()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sel := pasteSelection(c.primary)
+	copies, err := clipboard.Watch(ctx, sel)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "watching the %s for images; press Ctrl+C to stop\n", sel)
+	for copied := range copies {
+		if copied.Err != nil {
+			return fmt.Errorf("watch %s: %w", sel, copied.Err)
+		}
+		path, err := saveCopiedImage(c.output, copied)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "saved %s\n", path)
+		if c.root != nil {
+			c.root.notifySave(path)
+		}
+	}
+	return nil
+}
+
+// runHistory lists the clipboard history, or copies one entry again.
+func (c *clipboardCmd) runHistory() error {
+	entries, err := clipboard.History()
+	if err != nil {
+		return fmt.Errorf("read clipboard history: %w", err)
+	}
+	if c.entry == 0 {
+		if len(entries) == 0 {
+			fmt.Fprintln(os.Stderr, "clipboard history is empty")
+			return nil
+		}
+		for i, e := range entries {
+			fmt.Printf("%2d  %s  %s\n", i+1, e.Time.Format("2006-01-02 15:04:05"), e)
+		}
+		return nil
+	}
+	if c.entry > len(entries) {
+		return fmt.Errorf("clipboard history has %d entries, no entry %d", len(entries), c.entry)
+	}
+	e := entries[c.entry-1]
+	if err := clipboard.Recopy(e, copySelections(c.primary)...); err != nil {
+		return fmt.Errorf("copy history entry %d: %w", c.entry, err)
+	}
+	fmt.Fprintf(os.Stderr, "copied history entry %d to clipboard\n", c.entry)
+	if c.root != nil {
+		c.root.notifyCopy(fmt.Sprintf("history entry %d", c.entry))
+	}
+	return nil
+}
+
+// saveCopiedImage writes a watched image under pattern without replacing
+// an earlier one of the same name.
+func saveCopiedImage(pattern string, copied clipboard.Copied) (string, error) {
+	rgba := image.NewRGBA(copied.Image.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), copied.Image, copied.Image.Bounds().Min, draw.Src)
+	path, err := uniquePath(expandSavePattern(pattern, copied.Time, &capture.CaptureResult{Image: rgba}))
+	if err != nil {
+		return "", err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := png.Encode(f, rgba); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path, nil
+}
+
+// copySelections lists where copies go: the clipboard, and with -primary the
+// primary selection as well so a middle click pastes them too.
+func copySelections(primary bool) []clipboard.Selection {
+	if primary {
+		return []clipboard.Selection{clipboard.Clipboard, clipboard.Primary}
+	}
+	return []clipboard.Selection{clipboard.Clipboard}
+}
+
+// pasteSelection is where pastes read from: the primary selection with
+// -primary, as a middle click would, and otherwise the clipboard.
+func pasteSelection(primary bool) clipboard.Selection {
+	if primary {
+		return clipboard.Primary
+	}
+	return clipboard.Clipboard
+}
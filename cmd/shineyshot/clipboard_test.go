@@ -0,0 +1,30 @@
+package main
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/example/shineyshot/internal/clipboard"
+)
+
+func TestSaveCopiedImageKeepsEarlierFiles(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "clip-{width}x{height}.png")
+	copied := clipboard.Copied{Image: image.NewRGBA(image.Rect(0, 0, 3, 2)), Time: time.Now()}
+
+	first, err := saveCopiedImage(pattern, copied)
+	if err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	second, err := saveCopiedImage(pattern, copied)
+	if err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+	if filepath.Base(first) != "clip-3x2.png" {
+		t.Fatalf("first = %q, want clip-3x2.png", first)
+	}
+	if filepath.Base(second) != "clip-3x2-01.png" {
+		t.Fatalf("second = %q, want clip-3x2-01.png", second)
+	}
+}
@@ -57,20 +57,9 @@ func (c *configCmd) runSave(args []string) error {
 		return err
 	}
 
-	cfg := c.root.config
-	path := ""
-
-	// If loader found a config file, save there
-	// Otherwise determine a default path to save to
-	loader := config.NewLoader(version, configPathOverride)
-	path = loader.GetConfigPath()
-
-	if path == "" {
-		var err error
-		path, err = loader.GetDefaultPath()
-		if err != nil {
-			return fmt.Errorf("failed to determine default config path: %w", err)
-		}
+	path, err := configSavePath()
+	if err != nil {
+		return err
 	}
 
 	// Check if file exists
@@ -78,7 +67,43 @@ func (c *configCmd) runSave(args []string) error {
 		return fmt.Errorf("config file already exists at %s; use -force to overwrite", path)
 	}
 
-	// Ensure directory exists
+	if err := writeConfigFile(path, c.root.config); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Configuration saved to %s\n", path)
+	return nil
+}
+
+// configSavePath returns the configuration file that was loaded, or where a
+// new one belongs when none was found.
+func configSavePath() (string, error) {
+	loader := config.NewLoader(version, configPathOverride)
+	if path := loader.GetConfigPath(); path != "" {
+		return path, nil
+	}
+	path, err := loader.GetDefaultPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine default config path: %w", err)
+	}
+	return path, nil
+}
+
+// saveConfig writes the root's configuration back to the file it came from
+// after a command changed it, and returns that path. It refuses when the file
+// failed to load, as writing the defaults would lose everything else in it.
+func saveConfig(r *root) (string, error) {
+	if r.configErr != nil {
+		return "", fmt.Errorf("not saving the configuration, which failed to load: %w", r.configErr)
+	}
+	path, err := configSavePath()
+	if err != nil {
+		return "", err
+	}
+	return path, writeConfigFile(path, r.config)
+}
+
+// writeConfigFile writes cfg to path, creating its directory.
+func writeConfigFile(path string, cfg *config.Config) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
@@ -93,7 +118,5 @@ func (c *configCmd) runSave(args []string) error {
 	if _, err := f.WriteString(cfg.String()); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
-
-	fmt.Fprintf(os.Stderr, "Configuration saved to %s\n", path)
-	return nil
+	return f.Close()
 }
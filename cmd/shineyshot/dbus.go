@@ -0,0 +1,5 @@
+package main
+
+// dbusServiceName is the well-known name a session started with --dbus owns
+// on the session bus. It doubles as the interface name of the exported object.
+const dbusServiceName = "org.arran4.Shineyshot"
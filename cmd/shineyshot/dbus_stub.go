@@ -0,0 +1,9 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package main
+
+import "fmt"
+
+func startDBusService(*interactiveSocketServer) (func(), error) {
+	return nil, fmt.Errorf("dbus service is not supported on this platform")
+}
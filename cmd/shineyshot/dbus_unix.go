@@ -0,0 +1,169 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+
+	"github.com/example/shineyshot/internal/render"
+)
+
+const dbusObjectPath = dbus.ObjectPath("/org/arran4/Shineyshot")
+
+// dbusService exposes a background session on the session bus. Method calls
+// are queued as jobs like socket EXEC requests, so they never interleave with
+// commands from attached clients.
+type dbusService struct {
+	server *interactiveSocketServer
+	conn   *dbus.Conn
+}
+
+// startDBusService registers dbusServiceName for server and returns a function
+// that releases it.
+func startDBusService(server *interactiveSocketServer) (func(), error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("dbus connect: %w", err)
+	}
+	closeConn := func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("dbus close: %v", err)
+		}
+	}
+	svc := &dbusService{server: server, conn: conn}
+	if err := conn.Export(svc, dbusObjectPath, dbusServiceName); err != nil {
+		closeConn()
+		return nil, fmt.Errorf("dbus export: %w", err)
+	}
+	node := &introspect.Node{
+		Name: string(dbusObjectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name: dbusServiceName,
+				Methods: []introspect.Method{
+					{Name: "CaptureScreen", Args: []introspect.Arg{{Name: "display", Type: "s", Direction: "in"}}},
+					{Name: "CaptureWindow", Args: []introspect.Arg{{Name: "selector", Type: "s", Direction: "in"}}},
+					{Name: "Annotate", Args: []introspect.Arg{{Name: "path", Type: "s", Direction: "in"}}},
+					{Name: "OpenEditor", Args: []introspect.Arg{{Name: "path", Type: "s", Direction: "in"}}},
+					{Name: "Save", Args: []introspect.Arg{
+						{Name: "path", Type: "s", Direction: "in"},
+						{Name: "saved", Type: "s", Direction: "out"},
+					}},
+				},
+				Signals: []introspect.Signal{
+					{Name: "CaptureTaken", Args: []introspect.Arg{{Name: "detail", Type: "s"}}},
+					{Name: "Saved", Args: []introspect.Arg{{Name: "path", Type: "s"}}},
+				},
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), dbusObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		closeConn()
+		return nil, fmt.Errorf("dbus export introspection: %w", err)
+	}
+	reply, err := conn.RequestName(dbusServiceName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		closeConn()
+		return nil, fmt.Errorf("dbus request name: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		closeConn()
+		return nil, fmt.Errorf("dbus name %s is already owned by another session", dbusServiceName)
+	}
+	events, unsubscribe := server.session.events.subscribe()
+	done := make(chan struct{})
+	go svc.forwardEvents(events, done)
+	return func() {
+		unsubscribe()
+		close(done)
+		closeConn()
+	}, nil
+}
+
+func (d *dbusService) forwardEvents(events <-chan sessionEvent, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case ev := <-events:
+			var member string
+			switch ev.kind {
+			case "capture":
+				member = "CaptureTaken"
+			case "save":
+				member = "Saved"
+			default:
+				continue
+			}
+			if err := d.conn.Emit(dbusObjectPath, dbusServiceName+"."+member, ev.detail); err != nil {
+				log.Printf("dbus emit %s: %v", member, err)
+			}
+		}
+	}
+}
+
+func (d *dbusService) exec(command string) *dbus.Error {
+	if err := d.server.execCommand(command); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// CaptureScreen captures the named display, or the current one when empty.
+func (d *dbusService) CaptureScreen(display string) *dbus.Error {
+	return d.exec(strings.TrimSpace("capture screen " + display))
+}
+
+// CaptureWindow captures the window matching selector, or the active window
+// when empty.
+func (d *dbusService) CaptureWindow(selector string) *dbus.Error {
+	return d.exec(strings.TrimSpace("capture window " + selector))
+}
+
+// Annotate loads the image at path so later commands draw on it.
+func (d *dbusService) Annotate(path string) *dbus.Error {
+	if path == "" {
+		return dbus.MakeFailedError(errors.New("path must not be empty"))
+	}
+	return d.open(path)
+}
+
+// open loads the image at path, passing the path whole so that spaces in it
+// survive.
+func (d *dbusService) open(path string) *dbus.Error {
+	if err := d.server.execCall("open "+path, func() { d.server.session.openFile(path) }); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// OpenEditor loads the image at path, when given, and opens the annotation
+// window if it is not already showing.
+func (d *dbusService) OpenEditor(path string) *dbus.Error {
+	if path != "" {
+		if err := d.open(path); err != nil {
+			return err
+		}
+	}
+	if d.server.session.status().windowOpen {
+		return nil
+	}
+	return d.exec("show")
+}
+
+// Save writes the current image to path, or to the session outdir and pattern
+// when empty, and returns the absolute path written.
+func (d *dbusService) Save(path string) (string, *dbus.Error) {
+	save := func() { d.server.session.saveFile(path, render.ScaleOptions{}) }
+	if err := d.server.execCall(strings.TrimSpace("save "+path), save); err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+	return d.server.session.status().lastSaved, nil
+}
@@ -7,7 +7,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"log"
 	"math"
 	"os"
@@ -17,6 +16,8 @@ import (
 
 	"github.com/example/shineyshot/internal/appstate"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/render"
 	"golang.org/x/image/colornames"
 )
 
@@ -26,9 +27,12 @@ type drawCmd struct {
 	output        string
 	fromClipboard bool
 	toClipboard   bool
+	primary       bool
 	colorSpec     string
 	color         color.RGBA
 	width         int
+	styleSpec     string
+	style         appstate.LineStyle
 	shape         string
 	coords        []int
 	text          string
@@ -36,6 +40,14 @@ type drawCmd struct {
 	number        int
 	numberSize    int
 	maskOpacity   int
+	highlight     int
+	radius        int
+	fillSpec      string
+	fill          *color.NRGBA
+	frame         frameFlags
+	frameOpts     render.FrameOptions
+	scale         scaleFlags
+	scaleOpts     render.ScaleOptions
 	*root
 	fs *flag.FlagSet
 }
@@ -83,6 +95,26 @@ func parseColor(s string) (color.RGBA, error) {
 	return color.RGBA{}, fmt.Errorf("invalid color %q", s)
 }
 
+// parseFill reads a -fill value: a color as parseColor accepts, optionally
+// followed by a colon and an opacity from 0 to 255 that scales its alpha.
+func parseFill(s string) (color.NRGBA, error) {
+	spec, opacity := s, 255
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		spec = s[:i]
+		v, err := strconv.Atoi(strings.TrimSpace(s[i+1:]))
+		if err != nil || v < 0 || v > 255 {
+			return color.NRGBA{}, fmt.Errorf("fill opacity %q must be between 0 and 255", s[i+1:])
+		}
+		opacity = v
+	}
+	c, err := parseColor(spec)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("fill: %w", err)
+	}
+	// Hex colors give straight alpha, which the opacity scales.
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: uint8(int(c.A) * opacity / 255)}, nil
+}
+
 func parseDrawCmd(args []string, r *root) (*drawCmd, error) {
 	fs := flag.NewFlagSet("draw", flag.ExitOnError)
 	d := &drawCmd{root: r, fs: fs}
@@ -93,11 +125,23 @@ func parseDrawCmd(args []string, r *root) (*drawCmd, error) {
 	fs.BoolVar(&d.fromClipboard, "from-clip", false, "read the input image from the clipboard (alias)")
 	fs.BoolVar(&d.toClipboard, "to-clipboard", false, "copy the result to the clipboard")
 	fs.BoolVar(&d.toClipboard, "to-clip", false, "copy the result to the clipboard (alias)")
+	fs.BoolVar(&d.primary, "primary", false, "read from and also copy to the primary selection, pasted with a middle click")
 	fs.StringVar(&d.colorSpec, "color", "red", "stroke or fill color name or hex value")
 	fs.IntVar(&d.width, "width", 2, "stroke width in pixels")
+	fs.StringVar(&d.styleSpec, "style", "solid", "stroke style of line, arrow, rect and circle shapes: "+strings.Join(appstate.LineStyleNames(), ", "))
 	fs.Float64Var(&d.textSize, "text-size", appstate.DefaultTextSize(), "text size in points")
 	fs.IntVar(&d.numberSize, "number-size", 16, "radius of numbered markers in pixels")
 	fs.IntVar(&d.maskOpacity, "mask-opacity", 160, "mask opacity between 0 (transparent) and 255 (opaque)")
+	fs.IntVar(&d.highlight, "highlight-opacity", 102, "highlighter opacity between 0 (transparent) and 255 (opaque)")
+	fs.IntVar(&d.radius, "radius", 8, "corner radius of roundrect shapes in pixels")
+	fs.StringVar(&d.fillSpec, "fill", "", "fill rect, roundrect, circle and polygon shapes with COLOR[:opacity], opacity between 0 and 255")
+	fs.StringVar(&d.frame.preset, "frame", "", "finish by framing the image with a preset: "+framePresetNames())
+	fs.StringVar(&d.frame.title, "frame-title", "", "window title shown by the macos and gnome frames")
+	fs.StringVar(&d.frame.url, "frame-url", "", "address shown by the browser frame")
+	fs.StringVar(&d.frame.padding, "padding", "", "frame padding in pixels: N, V,H or T,R,B,L")
+	fs.StringVar(&d.frame.background, "background", "", "frame backdrop color, or two comma separated colors for a gradient")
+	fs.StringVar(&d.scale.scale, "scale", "", "scale the saved image by a percentage, such as 50%")
+	fs.IntVar(&d.scale.maxWidth, "max-width", 0, "shrink the saved image to at most this many pixels wide")
 
 	flagArgs, positionals, err := splitDrawArgs(args)
 	if err != nil {
@@ -106,14 +150,35 @@ func parseDrawCmd(args []string, r *root) (*drawCmd, error) {
 	if err := fs.Parse(flagArgs); err != nil {
 		return nil, err
 	}
-	if len(positionals) < 1 {
+	if d.primary && !d.fromClipboard && !d.toClipboard {
+		return nil, fmt.Errorf("-primary needs -from-clipboard or -to-clipboard")
+	}
+	if d.scaleOpts, err = d.scale.options(); err != nil {
+		return nil, err
+	}
+	if len(positionals) < 1 && !d.frame.requested() && !d.scaleOpts.Requested() {
 		return nil, &UsageError{of: d}
 	}
-	d.shape = strings.ToLower(positionals[0])
-	remaining := positionals[1:]
+	if d.frame.requested() {
+		if d.frameOpts, err = d.frame.options(); err != nil {
+			return nil, err
+		}
+	}
+	var remaining []string
+	if len(positionals) > 0 {
+		d.shape = strings.ToLower(positionals[0])
+		remaining = positionals[1:]
+	}
 	switch d.shape {
-	case "line", "arrow", "rect":
+	case "":
+		// Only the frame or scale is applied.
+	case "line", "arrow", "rect", "roundrect", "highlight":
 		d.coords, err = expectInts(remaining, 4, d.shape)
+	case "polygon":
+		if len(remaining) < 6 || len(remaining)%2 != 0 {
+			return nil, fmt.Errorf("polygon requires three or more x y pairs")
+		}
+		d.coords, err = expectInts(remaining, len(remaining), d.shape)
 	case "circle":
 		d.coords, err = expectInts(remaining, 3, d.shape)
 	case "number":
@@ -154,6 +219,16 @@ func parseDrawCmd(args []string, r *root) (*drawCmd, error) {
 		return nil, err
 	}
 	d.color = colorVal
+	if d.style, err = appstate.ParseLineStyle(d.styleSpec); err != nil {
+		return nil, err
+	}
+	if d.fillSpec != "" {
+		fill, err := parseFill(d.fillSpec)
+		if err != nil {
+			return nil, err
+		}
+		d.fill = &fill
+	}
 	if d.fromClipboard {
 		if d.output == "" {
 			if d.file != "" {
@@ -170,18 +245,31 @@ func parseDrawCmd(args []string, r *root) (*drawCmd, error) {
 			d.output = d.file
 		}
 	}
+	if d.shape == "highlight" {
+		widthSet := false
+		fs.Visit(func(f *flag.Flag) { widthSet = widthSet || f.Name == "width" })
+		if !widthSet {
+			d.width = defaultHighlightWidth
+		}
+	}
 	if d.width < 1 {
 		d.width = 1
 	}
 	if d.numberSize <= 0 {
 		d.numberSize = 16
 	}
+	if d.radius < 0 {
+		return nil, fmt.Errorf("radius cannot be negative")
+	}
 	if d.textSize <= 0 {
 		d.textSize = appstate.DefaultTextSize()
 	}
 	if d.maskOpacity < 0 || d.maskOpacity > 255 {
 		return nil, fmt.Errorf("mask-opacity must be between 0 and 255")
 	}
+	if d.highlight < 0 || d.highlight > 255 {
+		return nil, fmt.Errorf("highlight-opacity must be between 0 and 255")
+	}
 	return d, nil
 }
 
@@ -196,6 +284,10 @@ func (d *drawCmd) Run() error {
 	if err != nil {
 		return err
 	}
+	if d.frame.requested() {
+		rgba = render.ApplyFrame(rgba, d.frameOpts)
+	}
+	rgba = render.Scale(rgba, d.scaleOpts)
 	out, err := os.Create(d.output)
 	if err != nil {
 		return err
@@ -206,7 +298,7 @@ func (d *drawCmd) Run() error {
 			log.Printf("error closing %q: %v", out.Name(), err)
 		}
 	}(out)
-	if err := png.Encode(out, rgba); err != nil {
+	if err := imagefile.Encode(out, rgba, d.root.imageOptions(d.output)); err != nil {
 		return err
 	}
 	saved := d.output
@@ -218,7 +310,7 @@ func (d *drawCmd) Run() error {
 		d.root.notifySave(saved)
 	}
 	if d.toClipboard {
-		if err := clipboard.WriteImage(rgba); err != nil {
+		if err := clipboard.WriteContent(clipboard.Content{Image: rgba, Path: saved}, copySelections(d.primary)...); err != nil {
 			return fmt.Errorf("copy PNG to clipboard: %w", err)
 		}
 		detail := filepath.Base(d.output)
@@ -235,27 +327,13 @@ func (d *drawCmd) Run() error {
 
 func (d *drawCmd) loadSource() (image.Image, error) {
 	if d.fromClipboard {
-		img, err := clipboard.ReadImage()
+		img, err := clipboard.ReadImageFrom(pasteSelection(d.primary))
 		if err != nil {
 			return nil, fmt.Errorf("read clipboard image: %w", err)
 		}
 		return img, nil
 	}
-	f, err := os.Open(d.file)
-	if err != nil {
-		return nil, err
-	}
-	img, err := png.Decode(f)
-	if err != nil {
-		if cerr := f.Close(); cerr != nil {
-			log.Printf("error closing %q: %v", f.Name(), cerr)
-		}
-		return nil, err
-	}
-	if err := f.Close(); err != nil {
-		log.Printf("error closing %q: %v", f.Name(), err)
-	}
-	return img, nil
+	return imagefile.Open(d.file)
 }
 
 func expectInts(args []string, n int, shape string) ([]int, error) {
@@ -275,12 +353,18 @@ func expectInts(args []string, n int, shape string) ([]int, error) {
 
 func (d *drawCmd) applyShape(img *image.RGBA) (*image.RGBA, error) {
 	switch d.shape {
+	case "":
+		return img, nil
 	case "line":
 		return d.drawLine(img, false)
 	case "arrow":
 		return d.drawLine(img, true)
 	case "rect":
 		return d.drawRect(img)
+	case "roundrect":
+		return d.drawRoundRect(img)
+	case "polygon":
+		return d.drawPolygon(img)
 	case "circle":
 		return d.drawCircle(img)
 	case "number":
@@ -289,6 +373,8 @@ func (d *drawCmd) applyShape(img *image.RGBA) (*image.RGBA, error) {
 		return d.drawText(img)
 	case "mask":
 		return d.drawMask(img)
+	case "highlight":
+		return d.drawHighlight(img)
 	default:
 		return nil, errors.New("unhandled shape")
 	}
@@ -307,9 +393,9 @@ func (d *drawCmd) drawLine(img *image.RGBA, arrow bool) (*image.RGBA, error) {
 	d.coords[2] = x1 - shift.X
 	d.coords[3] = y1 - shift.Y
 	if arrow {
-		appstate.DrawArrow(img, d.coords[0], d.coords[1], d.coords[2], d.coords[3], d.color, d.width)
+		appstate.DrawArrow(img, d.coords[0], d.coords[1], d.coords[2], d.coords[3], d.color, d.width, d.style)
 	} else {
-		appstate.DrawLine(img, d.coords[0], d.coords[1], d.coords[2], d.coords[3], d.color, d.width)
+		appstate.DrawLine(img, d.coords[0], d.coords[1], d.coords[2], d.coords[3], d.color, d.width, d.style)
 	}
 	return img, nil
 }
@@ -323,7 +409,47 @@ func (d *drawCmd) drawRect(img *image.RGBA) (*image.RGBA, error) {
 	var shift image.Point
 	img, shift = appstate.ExpandCanvas(img, rect)
 	rect = rect.Sub(shift)
-	appstate.DrawRect(img, rect, d.color, d.width)
+	if d.fill != nil {
+		appstate.FillRect(img, rect.Inset(int(math.Ceil(float64(d.width)/2.0))), d.fill)
+	}
+	appstate.DrawRect(img, rect, d.color, d.width, d.style)
+	return img, nil
+}
+
+func (d *drawCmd) drawRoundRect(img *image.RGBA) (*image.RGBA, error) {
+	if len(d.coords) != 4 {
+		return nil, fmt.Errorf("expected 4 coordinates for roundrect")
+	}
+	rect := orderedRect(d.coords[0], d.coords[1], d.coords[2], d.coords[3])
+	var shift image.Point
+	img, shift = appstate.ExpandCanvas(img, inflateRect(rect, d.width))
+	rect = rect.Sub(shift)
+	if d.fill != nil {
+		appstate.FillRoundRect(img, rect, d.radius, d.fill)
+	}
+	appstate.DrawRoundRect(img, rect, d.radius, d.color, d.width)
+	return img, nil
+}
+
+func (d *drawCmd) drawPolygon(img *image.RGBA) (*image.RGBA, error) {
+	if len(d.coords) < 6 || len(d.coords)%2 != 0 {
+		return nil, fmt.Errorf("expected x y pairs for polygon")
+	}
+	pts := make([]image.Point, len(d.coords)/2)
+	bounds := image.Rectangle{Min: image.Pt(d.coords[0], d.coords[1]), Max: image.Pt(d.coords[0], d.coords[1])}
+	for i := range pts {
+		pts[i] = image.Pt(d.coords[2*i], d.coords[2*i+1])
+		bounds = bounds.Union(image.Rectangle{Min: pts[i], Max: pts[i].Add(image.Pt(1, 1))})
+	}
+	var shift image.Point
+	img, shift = appstate.ExpandCanvas(img, inflateRect(bounds, d.width))
+	for i := range pts {
+		pts[i] = pts[i].Sub(shift)
+	}
+	if d.fill != nil {
+		appstate.FillPolygon(img, pts, d.fill)
+	}
+	appstate.DrawPolygon(img, pts, d.color, d.width)
 	return img, nil
 }
 
@@ -341,7 +467,10 @@ func (d *drawCmd) drawCircle(img *image.RGBA) (*image.RGBA, error) {
 	img, shift = appstate.ExpandCanvas(img, rect)
 	cx -= shift.X
 	cy -= shift.Y
-	appstate.DrawCircle(img, cx, cy, radius, d.color, d.width)
+	if d.fill != nil {
+		appstate.FillCircle(img, cx, cy, radius, d.fill)
+	}
+	appstate.DrawCircle(img, cx, cy, radius, d.color, d.width, d.style)
 	return img, nil
 }
 
@@ -393,6 +522,21 @@ func (d *drawCmd) drawMask(img *image.RGBA) (*image.RGBA, error) {
 	return img, nil
 }
 
+// defaultHighlightWidth is the highlight stroke when -width is not given,
+// wide enough to cover a line of text.
+const defaultHighlightWidth = 16
+
+func (d *drawCmd) drawHighlight(img *image.RGBA) (*image.RGBA, error) {
+	if len(d.coords) != 4 {
+		return nil, fmt.Errorf("expected 4 coordinates for highlight")
+	}
+	x0, y0, x1, y1 := d.coords[0], d.coords[1], d.coords[2], d.coords[3]
+	var shift image.Point
+	img, shift = appstate.ExpandCanvas(img, boundsForLine(x0, y0, x1, y1, d.width))
+	appstate.DrawHighlight(img, x0-shift.X, y0-shift.Y, x1-shift.X, y1-shift.Y, d.color, d.width, uint8(d.highlight))
+	return img, nil
+}
+
 func boundsForLine(x0, y0, x1, y1, width int) image.Rectangle {
 	minX := minInt(x0, x1) - width
 	maxX := maxInt(x0, x1) + width
@@ -448,16 +592,32 @@ var drawFlagNames = map[string]struct{}{
 	"output":         {},
 	"from-clipboard": {},
 	"from-clip":      {},
+	"to-clipboard":   {},
+	"to-clip":        {},
+	"primary":        {},
 	"color":          {},
 	"width":          {},
+	"style":          {},
 	"text-size":      {},
 	"number-size":    {},
 	"mask-opacity":   {},
+	"fill":           {},
+	"radius":         {},
+	"frame":          {},
+	"frame-title":    {},
+	"frame-url":      {},
+	"padding":        {},
+	"background":     {},
+	"scale":          {},
+	"max-width":      {},
 }
 
 var drawBoolFlags = map[string]struct{}{
 	"from-clipboard": {},
 	"from-clip":      {},
+	"to-clipboard":   {},
+	"to-clip":        {},
+	"primary":        {},
 }
 
 func splitDrawArgs(args []string) ([]string, []string, error) {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestDrawFill(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(white, white.Rect, image.White, image.Point{}, draw.Src)
+	tests := []struct {
+		args   []string
+		inside color.RGBA
+	}{
+		{[]string{"-fill", "blue", "rect", "20", "20", "80", "80"}, color.RGBA{0, 0, 255, 255}},
+		{[]string{"-fill", "#0000ff:128", "rect", "20", "20", "80", "80"}, color.RGBA{127, 127, 255, 255}},
+		{[]string{"-fill=blue", "circle", "50", "50", "25"}, color.RGBA{0, 0, 255, 255}},
+		{[]string{"rect", "20", "20", "80", "80"}, color.RGBA{255, 255, 255, 255}},
+		{[]string{"-fill", "blue", "-radius", "20", "roundrect", "20", "20", "80", "80"}, color.RGBA{0, 0, 255, 255}},
+		{[]string{"-fill", "blue", "polygon", "50", "10", "90", "90", "10", "90"}, color.RGBA{0, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		d, err := parseDrawCmd(append([]string{"-file", "in.png", "-color", "red"}, tt.args...), nil)
+		if err != nil {
+			t.Fatalf("parse %v: %v", tt.args, err)
+		}
+		img := image.NewRGBA(white.Rect)
+		copy(img.Pix, white.Pix)
+		img, err = d.applyShape(img)
+		if err != nil {
+			t.Fatalf("draw %v: %v", tt.args, err)
+		}
+		if got := img.RGBAAt(50, 50); got != tt.inside {
+			t.Errorf("%v: inside = %v, want %v", tt.args, got, tt.inside)
+		}
+		if got := img.RGBAAt(5, 5); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("%v: outside = %v, want white", tt.args, got)
+		}
+	}
+
+	for _, args := range [][]string{{"polygon", "0", "0", "10", "10"}, {"polygon", "0", "0", "10", "10", "5"}, {"-radius", "-1", "roundrect", "0", "0", "9", "9"}} {
+		if _, err := parseDrawCmd(append([]string{"-file", "in.png"}, args...), nil); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+	for _, bad := range []string{"blue:300", "blue:x", "nocolor:10"} {
+		if _, err := parseDrawCmd([]string{"-file", "in.png", "-fill", bad, "rect", "0", "0", "1", "1"}, nil); err == nil {
+			t.Errorf("-fill %s: expected an error", bad)
+		}
+	}
+}
+
+func TestDrawStyle(t *testing.T) {
+	d, err := parseDrawCmd([]string{"-file", "in.png", "line", "0", "50", "99", "50", "-style", "dashed"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := d.applyShape(image.NewRGBA(image.Rect(0, 0, 100, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.RGBAAt(4, 50).A == 0 || img.RGBAAt(12, 50).A != 0 {
+		t.Errorf("dashed line: dash %v, gap %v", img.RGBAAt(4, 50), img.RGBAAt(12, 50))
+	}
+	if _, err := parseDrawCmd([]string{"-file", "in.png", "-style", "wavy", "line", "0", "0", "9", "9"}, nil); err == nil {
+		t.Error("-style wavy: expected an error")
+	}
+}
+
+func TestSplitScaleArgs(t *testing.T) {
+	rest, opts, err := splitScaleArgs([]string{"out.png", "--scale", "50%", "--max-width=1200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 1 || rest[0] != "out.png" || opts.Percent != 50 || opts.MaxWidth != 1200 {
+		t.Errorf("got %v %+v", rest, opts)
+	}
+	for _, args := range [][]string{{"out.png", "--scale"}, {"--scale", "half"}, {"--max-width", "-5"}} {
+		if _, _, err := splitScaleArgs(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+	if _, err := parseDrawCmd([]string{"-file", "in.png", "-scale", "50%"}, nil); err != nil {
+		t.Errorf("draw -scale without a shape: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// sessionEvent describes something that happened inside an interactive
+// session, such as a capture taken from the annotation window.
+type sessionEvent struct {
+	kind   string
+	detail string
+}
+
+// String renders the event as a single protocol line without the EVENT prefix.
+func (e sessionEvent) String() string {
+	detail := strings.ReplaceAll(e.detail, "\n", "\\n")
+	if detail == "" {
+		return e.kind
+	}
+	return e.kind + " " + detail
+}
+
+const eventBufferSize = 32
+
+// eventHub fans session events out to subscribers. Publishing never blocks;
+// subscribers that fall behind miss events rather than stalling the session.
+type eventHub struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]chan sessionEvent
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[int]chan sessionEvent)}
+}
+
+func (h *eventHub) subscribe() (<-chan sessionEvent, func()) {
+	ch := make(chan sessionEvent, eventBufferSize)
+	h.mu.Lock()
+	id := h.nextID
+	h.nextID++
+	h.subs[id] = ch
+	h.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, id)
+			h.mu.Unlock()
+		})
+	}
+}
+
+func (h *eventHub) publish(kind, detail string) {
+	if h == nil {
+		return
+	}
+	ev := sessionEvent{kind: kind, detail: detail}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+
+	"github.com/example/shineyshot/internal/capture"
+)
+
+// captureFeedback confirms a capture with the shutter sound and a flash over
+// the captured area, as enabled, so a capture fired from a hotkey or script
+// is noticeable. The returned function waits for the flash to end; commands
+// that exit right after capturing call it so the flash is not cut short.
+func captureFeedback(res capture.CaptureResult, sound, flash bool) (wait func()) {
+	if sound {
+		if err := capture.PlayShutter(); err != nil {
+			log.Printf("capture sound: %v", err)
+		}
+	}
+	if !flash {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := capture.Flash(res.Region); err != nil {
+			log.Printf("capture flash: %v", err)
+		}
+	}()
+	return func() { <-done }
+}
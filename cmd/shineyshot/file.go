@@ -3,7 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/example/shineyshot/internal/appstate"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/project"
 )
 
 type fileCmd struct {
@@ -11,6 +17,7 @@ type fileCmd struct {
 	op            string
 	args          []string
 	fromClipboard bool
+	primary       bool
 	*root
 	fs *flag.FlagSet
 }
@@ -30,6 +37,7 @@ func parseFileCmd(args []string, r *root) (*fileCmd, error) {
 	fs.StringVar(&cmd.path, "file", "", "path to the image file to read or write")
 	fs.BoolVar(&cmd.fromClipboard, "from-clipboard", false, "load the input image from the clipboard")
 	fs.BoolVar(&cmd.fromClipboard, "from-clip", false, "load the input image from the clipboard (alias)")
+	fs.BoolVar(&cmd.primary, "primary", false, "use the primary selection with -from-clipboard and in the editor")
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
@@ -51,6 +59,9 @@ func (f *fileCmd) Run() error {
 		if f.fromClipboard {
 			return fmt.Errorf("-from-clipboard cannot be used with file capture")
 		}
+		if f.primary {
+			return fmt.Errorf("-primary cannot be used with file capture")
+		}
 		args := append([]string{"-output", f.path}, f.args...)
 		cmd, err := parseSnapshotCmd(args, child)
 		if err != nil {
@@ -62,6 +73,9 @@ func (f *fileCmd) Run() error {
 		if f.fromClipboard {
 			base = append(base, "-from-clipboard")
 		}
+		if f.primary {
+			base = append(base, "-primary")
+		}
 		base = append(base, "-file", f.path, "-output", f.path)
 		args := append(base, f.args...)
 		cmd, err := parseDrawCmd(args, child)
@@ -98,6 +112,9 @@ func (f *fileCmd) Run() error {
 			}
 			flags = append([]string{"-from-clipboard"}, flags...)
 		}
+		if f.primary {
+			flags = append([]string{"-primary"}, flags...)
+		}
 		args := append([]string{"-file", f.path}, flags...)
 		if len(action) == 0 {
 			action = []string{"open"}
@@ -113,13 +130,63 @@ func (f *fileCmd) Run() error {
 		if f.fromClipboard {
 			base = append([]string{"-from-clipboard"}, base...)
 		}
+		if f.primary {
+			base = append([]string{"-primary"}, base...)
+		}
 		args := append(base, f.args...)
 		cmd, err := parsePreviewCmd(args, child)
 		if err != nil {
 			return err
 		}
 		return cmd.Run()
+	case "save-project":
+		if f.fromClipboard || f.primary {
+			return fmt.Errorf("-from-clipboard and -primary cannot be used with file save-project")
+		}
+		return f.saveProject()
+	case "open-project":
+		if !project.IsProject(f.path) {
+			return fmt.Errorf("%s is not a %s project", f.path, project.Ext)
+		}
+		args := append([]string{"-file", f.path}, f.args...)
+		args = append(args, "open")
+		cmd, err := parseAnnotateCmd(args, child)
+		if err != nil {
+			return err
+		}
+		return cmd.Run()
 	default:
 		return &UsageError{of: f}
 	}
 }
+
+// saveProject writes the images named after the operation to the project at
+// -file, one tab each, so they can be annotated together later.
+func (f *fileCmd) saveProject() error {
+	if !project.IsProject(f.path) {
+		return fmt.Errorf("%s does not end in %s", f.path, project.Ext)
+	}
+	if len(f.args) == 0 {
+		return fmt.Errorf("save-project needs at least one image")
+	}
+	p := &project.Project{}
+	for _, path := range f.args {
+		img, err := imagefile.Open(path)
+		if err != nil {
+			return err
+		}
+		p.Tabs = append(p.Tabs, project.Tab{
+			Title:      filepath.Base(path),
+			Image:      img,
+			Zoom:       1,
+			NextNumber: 1,
+			WidthIdx:   appstate.DefaultWidthIndex(),
+			SavedPath:  path,
+		})
+	}
+	if err := project.Save(f.path, p); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "saved %d tab(s) to %s\n", len(p.Tabs), f.path)
+	return nil
+}
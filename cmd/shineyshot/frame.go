@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/example/shineyshot/internal/render"
+)
+
+// frameFlags holds the -frame family of flags shared by draw and annotate.
+type frameFlags struct {
+	preset     string
+	title      string
+	url        string
+	padding    string
+	background string
+}
+
+// requested reports whether any flag asks for the image to be framed.
+func (f frameFlags) requested() bool {
+	return f.preset != "" || f.padding != "" || f.background != ""
+}
+
+// options resolves the flags. -padding and -background override the
+// preset's values; given without -frame they start from the plain preset,
+// so the image only gains a border. With no flags the default preset is
+// returned, for the editor to use once framing is toggled on.
+func (f frameFlags) options() (render.FrameOptions, error) {
+	preset := f.preset
+	switch {
+	case preset != "":
+	case f.requested():
+		preset = "plain"
+	default:
+		preset = render.DefaultFramePreset
+	}
+	opts, err := render.LookupFramePreset(preset)
+	if err != nil {
+		return opts, err
+	}
+	if f.padding != "" {
+		if opts.Padding, err = render.ParseInsets(f.padding); err != nil {
+			return opts, err
+		}
+	}
+	if f.background != "" {
+		if opts.Background, opts.GradientEnd, err = parseBackground(f.background); err != nil {
+			return opts, err
+		}
+	}
+	opts.Title, opts.URL = f.title, f.url
+	return opts, nil
+}
+
+// parseBackground reads a backdrop given as one color, or as two colors
+// separated by a comma for a gradient from the top left to the bottom right.
+func parseBackground(spec string) (color.RGBA, color.RGBA, error) {
+	from, to, gradient := strings.Cut(spec, ",")
+	start, err := parseColor(from)
+	if err != nil {
+		return color.RGBA{}, color.RGBA{}, fmt.Errorf("background: %w", err)
+	}
+	if !gradient {
+		return start, color.RGBA{}, nil
+	}
+	end, err := parseColor(to)
+	if err != nil {
+		return color.RGBA{}, color.RGBA{}, fmt.Errorf("background: %w", err)
+	}
+	return start, end, nil
+}
+
+// framePresetNames lists the frame presets for flag help.
+func framePresetNames() string {
+	var names []string
+	for _, p := range render.FramePresets() {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
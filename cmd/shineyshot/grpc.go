@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	shineyshotv1 "github.com/example/shineyshot/proto/shineyshot/v1"
+)
+
+const (
+	defaultGRPCListen = "127.0.0.1:8788"
+	defaultGRPCName   = "grpc"
+	grpcTokenEnv      = "SHINEYSHOT_GRPC_TOKEN"
+)
+
+// grpcOptions configures the gRPC front end started by `background grpc`.
+type grpcOptions struct {
+	listen string
+	token  string
+}
+
+func (o grpcOptions) validate() error {
+	return requireRemoteToken(o.listen, o.token, grpcTokenEnv)
+}
+
+// service returns a sessionService that serves the Shineyshot gRPC API for a
+// session and reports the address it listens on to out. Without a
+// configured token it makes one up and reports that too.
+func (o grpcOptions) service(out io.Writer) sessionService {
+	return func(server *interactiveSocketServer) (func(), error) {
+		token, generated := o.token, false
+		if token == "" {
+			var err error
+			if token, err = newRemoteToken(); err != nil {
+				return nil, err
+			}
+			generated = true
+		}
+		ln, err := net.Listen("tcp", o.listen)
+		if err != nil {
+			return nil, err
+		}
+		auth := grpcAuth{server: server, token: token}
+		srv := grpc.NewServer(
+			grpc.UnaryInterceptor(auth.unary),
+			grpc.StreamInterceptor(auth.stream),
+		)
+		shineyshotv1.RegisterShineyshotServer(srv, &grpcService{server: server})
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				log.Printf("grpc serve: %v", err)
+			}
+		}()
+		if err := writef(out, "listening on %s\n", ln.Addr()); err != nil {
+			log.Printf("grpc: %v", err)
+		}
+		if generated {
+			if err := writef(out, "token %s\n", token); err != nil {
+				log.Printf("grpc: %v", err)
+			}
+		}
+		return srv.Stop, nil
+	}
+}
+
+// grpcAuth checks the "authorization: Bearer TOKEN" metadata on every call.
+// An empty token lets no call through.
+type grpcAuth struct {
+	server *interactiveSocketServer
+	token  string
+}
+
+func (a grpcAuth) check(ctx context.Context) error {
+	a.server.touch()
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if bearer, ok := strings.CutPrefix(value, "Bearer "); ok && a.token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(a.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+func (a grpcAuth) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.check(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a grpcAuth) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// grpcService implements the Shineyshot service on top of a session's job
+// queue, reusing the request validation of the HTTP API.
+type grpcService struct {
+	shineyshotv1.UnimplementedShineyshotServer
+	server *interactiveSocketServer
+}
+
+var captureModes = map[shineyshotv1.CaptureRequest_Mode]string{
+	shineyshotv1.CaptureRequest_MODE_UNSPECIFIED: "screen",
+	shineyshotv1.CaptureRequest_MODE_SCREEN:      "screen",
+	shineyshotv1.CaptureRequest_MODE_WINDOW:      "window",
+	shineyshotv1.CaptureRequest_MODE_REGION:      "region",
+}
+
+func (g *grpcService) Capture(_ context.Context, req *shineyshotv1.CaptureRequest) (*shineyshotv1.Image, error) {
+	mode, ok := captureModes[req.GetMode()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown capture mode %v", req.GetMode())
+	}
+	capture := captureRequest{Mode: mode, Target: req.GetTarget()}
+	if r := req.GetRect(); r != nil {
+		capture.Rect = []int{int(r.GetX()), int(r.GetY()), int(r.GetWidth()), int(r.GetHeight())}
+	}
+	command, err := capture.command()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := g.server.execCommand(command); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return g.image(req.GetIncludePng())
+}
+
+func (g *grpcService) Draw(_ context.Context, req *shineyshotv1.DrawRequest) (*shineyshotv1.Image, error) {
+	draw := drawRequest{Color: req.GetColor(), Width: int(req.GetWidth())}
+	for _, shape := range req.GetShapes() {
+		kind := shape.GetKind()
+		if kind == shineyshotv1.Shape_KIND_UNSPECIFIED {
+			return nil, status.Error(codes.InvalidArgument, "shape kind is required")
+		}
+		points := make([]int, len(shape.GetPoints()))
+		for i, v := range shape.GetPoints() {
+			points[i] = int(v)
+		}
+		draw.Shapes = append(draw.Shapes, drawShape{
+			Type:   strings.ToLower(strings.TrimPrefix(kind.String(), "KIND_")),
+			Points: points,
+			Color:  shape.GetColor(),
+			Width:  int(shape.GetWidth()),
+		})
+	}
+	commands, err := draw.commands()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, command := range commands {
+		if err := g.server.execCommand(command); err != nil {
+			return nil, status.Errorf(codes.Internal, "%s: %v", command, err)
+		}
+	}
+	return g.image(req.GetIncludePng())
+}
+
+func (g *grpcService) Crop(_ context.Context, req *shineyshotv1.CropRequest) (*shineyshotv1.Image, error) {
+	r := req.GetRect()
+	if r.GetWidth() <= 0 || r.GetHeight() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "crop needs a rect with a positive width and height")
+	}
+	command := fmt.Sprintf("crop %d %d %d %d", r.GetX(), r.GetY(), r.GetX()+r.GetWidth(), r.GetY()+r.GetHeight())
+	if err := g.server.execCommand(command); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return g.image(req.GetIncludePng())
+}
+
+func (g *grpcService) Save(_ context.Context, req *shineyshotv1.SaveRequest) (*shineyshotv1.SaveResponse, error) {
+	if err := remoteSave(g.server, req.GetPath()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &shineyshotv1.SaveResponse{Path: g.server.session.status().lastSaved}, nil
+}
+
+func (g *grpcService) GetImage(context.Context, *shineyshotv1.GetImageRequest) (*shineyshotv1.Image, error) {
+	return g.image(true)
+}
+
+func (g *grpcService) StreamEvents(_ *shineyshotv1.StreamEventsRequest, stream grpc.ServerStreamingServer[shineyshotv1.Event]) error {
+	events, unsubscribe := g.server.session.events.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-g.server.stopCh:
+			return nil
+		case ev := <-events:
+			if err := stream.Send(&shineyshotv1.Event{Kind: ev.kind, Detail: ev.detail}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *grpcService) image(includePNG bool) (*shineyshotv1.Image, error) {
+	st := g.server.session.status()
+	img := &shineyshotv1.Image{
+		Width:    int32(st.width),
+		Height:   int32(st.height),
+		Revision: st.revision,
+	}
+	if includePNG {
+		data, err := g.server.session.encodePNG()
+		if err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		img.Png = data
+	}
+	return img, nil
+}
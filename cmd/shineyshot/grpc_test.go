@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	shineyshotv1 "github.com/example/shineyshot/proto/shineyshot/v1"
+)
+
+func startTestGRPC(t *testing.T, server *interactiveSocketServer, token string) shineyshotv1.ShineyshotClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	auth := grpcAuth{server: server, token: token}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(auth.unary), grpc.StreamInterceptor(auth.stream))
+	shineyshotv1.RegisterShineyshotServer(srv, &grpcService{server: server})
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { closeWithLog("grpc client", conn) })
+	return shineyshotv1.NewShineyshotClient(conn)
+}
+
+func TestGRPCRequiresToken(t *testing.T) {
+	server := startTestSocketServer(t)
+	client := startTestGRPC(t, server, "secret")
+
+	if _, err := client.GetImage(context.Background(), &shineyshotv1.GetImageRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without token: %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.GetImage(ctx, &shineyshotv1.GetImageRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("with token and no image: %v, want FailedPrecondition", err)
+	}
+}
+
+func TestGRPCMakesUpToken(t *testing.T) {
+	server := startTestSocketServer(t)
+	var out bytes.Buffer
+	stop, err := grpcOptions{listen: "127.0.0.1:0"}.service(&out)(server)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(stop)
+	var addr, token string
+	if _, err := fmt.Sscanf(out.String(), "listening on %s\ntoken %s\n", &addr, &token); err != nil || token == "" {
+		t.Fatalf("output %q does not report the address and a token: %v", out.String(), err)
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { closeWithLog("grpc client", conn) })
+	client := shineyshotv1.NewShineyshotClient(conn)
+
+	for _, bearer := range []string{"", "Bearer ", "Bearer wrong"} {
+		ctx := context.Background()
+		if bearer != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", bearer)
+		}
+		if _, err := client.GetImage(ctx, &shineyshotv1.GetImageRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("authorization %q: %v, want Unauthenticated", bearer, err)
+		}
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	if _, err := client.GetImage(ctx, &shineyshotv1.GetImageRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("with the printed token and no image: %v, want FailedPrecondition", err)
+	}
+}
+
+func TestGRPCDrawCropAndGetImage(t *testing.T) {
+	server := startTestSocketServer(t)
+	client := startTestGRPC(t, server, "secret")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if err := server.session.setImage(image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+
+	_, err := client.Draw(ctx, &shineyshotv1.DrawRequest{
+		Color: "red",
+		Shapes: []*shineyshotv1.Shape{
+			{Kind: shineyshotv1.Shape_KIND_RECT, Points: []int32{2, 2, 12, 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("draw: %v", err)
+	}
+	if _, err := client.Draw(ctx, &shineyshotv1.DrawRequest{
+		Shapes: []*shineyshotv1.Shape{{Kind: shineyshotv1.Shape_KIND_CIRCLE, Points: []int32{1}}},
+	}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad circle: %v, want InvalidArgument", err)
+	}
+
+	img, err := client.Crop(ctx, &shineyshotv1.CropRequest{
+		Rect:       &shineyshotv1.Rect{X: 0, Y: 0, Width: 16, Height: 16},
+		IncludePng: true,
+	})
+	if err != nil {
+		t.Fatalf("crop: %v", err)
+	}
+	if img.GetWidth() != 16 || img.GetHeight() != 16 {
+		t.Fatalf("cropped to %dx%d, want 16x16", img.GetWidth(), img.GetHeight())
+	}
+	decoded, err := png.Decode(bytes.NewReader(img.GetPng()))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.Bounds().Dx() != 16 {
+		t.Errorf("png width %d, want 16", decoded.Bounds().Dx())
+	}
+	path := filepath.Join(t.TempDir(), "my  shot.png")
+	saved, err := client.Save(ctx, &shineyshotv1.SaveRequest{Path: path})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if saved.GetPath() != path {
+		t.Errorf("saved to %q, want %q", saved.GetPath(), path)
+	}
+}
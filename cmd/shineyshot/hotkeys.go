@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/example/shineyshot/internal/hotkeys"
+)
+
+const defaultHotkeySession = "hotkeys"
+
+// hotkeyAction is a shortcut the hotkeys daemon can register and the session
+// commands it runs when pressed.
+type hotkeyAction struct {
+	id          string
+	description string
+	accelerator string
+	commands    []string
+}
+
+var hotkeyActions = []hotkeyAction{
+	{"screen", "Capture the current screen", "Print", []string{"capture screen"}},
+	{"monitor", "Capture the monitor under the pointer", "", []string{"capture screen current"}},
+	{"region", "Capture a selected region", "Shift+Print", []string{"capture region select"}},
+	{"window", "Capture the active window", "Alt+Print", []string{"capture window"}},
+	{"editor", "Open the annotation editor", "Ctrl+Print", []string{"show"}},
+	{"copy", "Copy the last capture to the clipboard", "Ctrl+Shift+Print", []string{"copy"}},
+}
+
+type hotkeysCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	name    string
+	dir     string
+	backend string
+	list    bool
+}
+
+func parseHotkeysCmd(args []string, r *root) (*hotkeysCmd, error) {
+	fs := flag.NewFlagSet("hotkeys", flag.ExitOnError)
+	cmd := &hotkeysCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	fs.StringVar(&cmd.name, "name", defaultHotkeySession, "background session that runs the captures")
+	fs.StringVar(&cmd.dir, "dir", "", "directory that stores shineyshot sockets")
+	fs.StringVar(&cmd.backend, "backend", "auto", "how to register shortcuts: auto, x11 or portal")
+	fs.BoolVar(&cmd.list, "list", false, "print the configured shortcuts and exit")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	if fs.NArg() == 1 {
+		cmd.name = fs.Arg(0)
+	}
+	if _, err := hotkeys.ParseBackend(cmd.backend); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (h *hotkeysCmd) FlagSet() *flag.FlagSet {
+	return h.fs
+}
+
+func (h *hotkeysCmd) Template() string {
+	return "hotkeys.txt"
+}
+
+// bindings returns the enabled shortcuts, with accelerators from the
+// [hotkeys] config section replacing the defaults.
+func (h *hotkeysCmd) bindings() ([]hotkeys.Binding, error) {
+	var configured map[string]string
+	if h.root != nil && h.root.config != nil {
+		configured = h.root.config.Hotkeys
+	}
+	for name := range configured {
+		if findHotkeyAction(name) == nil {
+			return nil, fmt.Errorf("unknown hotkey action %q in config", name)
+		}
+	}
+	var bindings []hotkeys.Binding
+	for _, action := range hotkeyActions {
+		accel := action.accelerator
+		if v, ok := configured[action.id]; ok {
+			accel = v
+		}
+		if strings.TrimSpace(accel) == "" {
+			continue
+		}
+		parsed, err := hotkeys.ParseAccelerator(accel)
+		if err != nil {
+			return nil, fmt.Errorf("hotkey %s: %w", action.id, err)
+		}
+		bindings = append(bindings, hotkeys.Binding{ID: action.id, Description: action.description, Accelerator: parsed})
+	}
+	return bindings, nil
+}
+
+func findHotkeyAction(id string) *hotkeyAction {
+	for idx := range hotkeyActions {
+		if hotkeyActions[idx].id == id {
+			return &hotkeyActions[idx]
+		}
+	}
+	return nil
+}
+
+func (h *hotkeysCmd) Run() error {
+	bindings, err := h.bindings()
+	if err != nil {
+		return err
+	}
+	if h.list {
+		for _, b := range bindings {
+			fmt.Printf("%-8s %-18s %s\n", b.ID, b.Accelerator, b.Description)
+		}
+		return nil
+	}
+	dir, err := resolveSocketDir(h.dir)
+	if err != nil {
+		return err
+	}
+	if err := h.ensureSession(dir); err != nil {
+		return err
+	}
+	backend, _ := hotkeys.ParseBackend(h.backend)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A shortcut pressed again while its previous run is still in progress is
+	// dropped rather than queued, so key repeat cannot stack up captures.
+	var busy sync.Map
+	fmt.Printf("listening for %d shortcuts; captures run in session %s\n", len(bindings), h.name)
+	return hotkeys.Listen(ctx, backend, bindings, func(id string) {
+		if _, running := busy.LoadOrStore(id, true); running {
+			return
+		}
+		go func() {
+			defer busy.Delete(id)
+			if err := h.dispatch(dir, id); err != nil {
+				log.Printf("hotkey %s: %v", id, err)
+			}
+		}()
+	})
+}
+
+// ensureSession starts the target session unless it already answers pings.
+func (h *hotkeysCmd) ensureSession(dir string) error {
+	if err := pingSocket(socketPath(dir, h.name)); err == nil {
+		return nil
+	}
+	defaults, err := loadSessionDefaults(dir, h.name)
+	if err != nil {
+		return err
+	}
+	if _, err := startBackgroundServer(dir, h.name, defaults, h.root); err != nil {
+		return err
+	}
+	fmt.Printf("started background session %s at %s\n", h.name, socketPath(dir, h.name))
+	return nil
+}
+
+// hotkeyCommandTimeout bounds a single command; region selection waits for the
+// user to finish dragging in the portal dialog.
+const hotkeyCommandTimeout = 2 * time.Minute
+
+func (h *hotkeysCmd) dispatch(dir, id string) error {
+	action := findHotkeyAction(id)
+	if action == nil {
+		return fmt.Errorf("unknown hotkey action")
+	}
+	if err := h.ensureSession(dir); err != nil {
+		return err
+	}
+	for _, command := range action.commands {
+		lines, err := socketRequestTimeout(socketPath(dir, h.name), "EXEC "+command, hotkeyCommandTimeout)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			switch {
+			case strings.HasPrefix(line, "OUT "):
+				fmt.Println(strings.TrimPrefix(line, "OUT "))
+			case strings.HasPrefix(line, "ERR "):
+				return errors.New(strings.TrimPrefix(line, "ERR "))
+			case strings.HasPrefix(line, "DONE ERR "):
+				return errors.New(strings.TrimPrefix(line, "DONE ERR "))
+			}
+		}
+	}
+	return nil
+}
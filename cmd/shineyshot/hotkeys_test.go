@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/example/shineyshot/internal/config"
+)
+
+func TestHotkeyBindingsFromConfig(t *testing.T) {
+	cfg := config.New()
+	cfg.Hotkeys["region"] = "Super+Shift+S"
+	cfg.Hotkeys["copy"] = ""
+	cmd := &hotkeysCmd{root: &root{config: cfg}}
+
+	bindings, err := cmd.bindings()
+	if err != nil {
+		t.Fatalf("bindings: %v", err)
+	}
+	got := make(map[string]string)
+	for _, b := range bindings {
+		got[b.ID] = b.Accelerator.String()
+	}
+	if _, ok := got["copy"]; ok {
+		t.Fatalf("copy should be disabled, got %v", got)
+	}
+	if got["region"] != "Shift+Super+s" {
+		t.Fatalf("region = %q, want Shift+Super+s", got["region"])
+	}
+	if got["screen"] != "Print" {
+		t.Fatalf("screen = %q, want default Print", got["screen"])
+	}
+
+	cfg.Hotkeys["bogus"] = "F1"
+	if _, err := cmd.bindings(); err == nil {
+		t.Fatalf("expected error for unknown action")
+	}
+}
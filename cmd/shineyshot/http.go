@@ -0,0 +1,488 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/shineyshot/internal/render"
+)
+
+const (
+	defaultHTTPListen = "127.0.0.1:8787"
+	defaultHTTPName   = "http"
+	httpTokenEnv      = "SHINEYSHOT_HTTP_TOKEN"
+
+	maxHTTPRequestBytes = 1 << 20
+	sseKeepAlive        = 30 * time.Second
+)
+
+//go:embed web/index.html
+var webIndex []byte
+
+// httpOptions configures the REST front end started by `background http`.
+type httpOptions struct {
+	listen string
+	token  string
+}
+
+func (o httpOptions) validate() error {
+	return requireRemoteToken(o.listen, o.token, httpTokenEnv)
+}
+
+// requireRemoteToken refuses to serve a session without a token on anything
+// but a loopback address.
+func requireRemoteToken(listen, token, env string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", listen, err)
+	}
+	if isLoopbackHost(host) {
+		return nil
+	}
+	return fmt.Errorf("--token (or %s) is required when listening on %s", env, listen)
+}
+
+// isLoopbackHost reports whether host, without a port, names this machine
+// only.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newRemoteToken makes the token for an HTTP or gRPC session started
+// without one, so that a web page the user happens to visit, or another
+// local user, cannot drive the session either.
+func newRemoteToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
+
+// service returns a sessionService that serves the REST API for a session and
+// reports the address it listens on to out. Without a configured token it
+// makes one up and reports that too.
+func (o httpOptions) service(out io.Writer) sessionService {
+	return func(server *interactiveSocketServer) (func(), error) {
+		token, generated := o.token, false
+		if token == "" {
+			var err error
+			if token, err = newRemoteToken(); err != nil {
+				return nil, err
+			}
+			generated = true
+		}
+		ln, err := net.Listen("tcp", o.listen)
+		if err != nil {
+			return nil, err
+		}
+		host, _, _ := net.SplitHostPort(o.listen)
+		srv := &http.Server{
+			Handler:           newHTTPHandler(server, token, isLoopbackHost(host)),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("http serve: %v", err)
+			}
+		}()
+		if err := writef(out, "listening on http://%s\n", ln.Addr()); err != nil {
+			log.Printf("http: %v", err)
+		}
+		if generated {
+			if err := writef(out, "token %s\npreview http://%s/?token=%s\n", token, ln.Addr(), token); err != nil {
+				log.Printf("http: %v", err)
+			}
+		}
+		return func() { closeWithLog("http server", srv) }, nil
+	}
+}
+
+// httpAPI translates REST requests into commands on a session's job queue.
+type httpAPI struct {
+	server *interactiveSocketServer
+	token  string
+	// loopback holds requests to loopback Host names, which stops a DNS
+	// rebinding page from reaching a session listening on 127.0.0.1.
+	loopback bool
+}
+
+func newHTTPHandler(server *interactiveSocketServer, token string, loopback bool) http.Handler {
+	api := &httpAPI{server: server, token: token, loopback: loopback}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /capture", api.handleCapture)
+	mux.HandleFunc("POST /draw", api.handleDraw)
+	mux.HandleFunc("POST /save", api.handleSave)
+	mux.HandleFunc("POST /copy", api.handleCopy)
+	mux.HandleFunc("GET /image.png", api.handleImage)
+	mux.HandleFunc("GET /status", api.handleStatus)
+	mux.HandleFunc("GET /events", api.handleEvents)
+	mux.HandleFunc("GET /{$}", api.handleIndex)
+	return api.authorize(mux)
+}
+
+// authorize requires the session token as a bearer token or, for clients such
+// as EventSource that cannot set headers, a token query parameter. It also
+// turns away requests from other origins and, on a loopback listener, for
+// other hosts.
+func (a *httpAPI) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.server.touch()
+		if err := a.checkOrigin(r); err != nil {
+			writeHTTPError(w, http.StatusForbidden, err)
+			return
+		}
+		got := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			got = bearer
+		}
+		if a.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+			writeHTTPError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin rejects a request whose Origin is not the host it was sent to,
+// or whose Host is not loopback when the session only listens there.
+func (a *httpAPI) checkOrigin(r *http.Request) error {
+	if a.loopback {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopbackHost(strings.Trim(host, "[]")) {
+			return fmt.Errorf("host %q is not loopback", r.Host)
+		}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return fmt.Errorf("cross-origin request from %q", origin)
+		}
+	}
+	return nil
+}
+
+type captureRequest struct {
+	// Mode is screen, window or region; screen when empty.
+	Mode string `json:"mode"`
+	// Target is the display, window selector or region screen.
+	Target string `json:"target"`
+	// Rect holds x, y, width and height for region captures.
+	Rect []int `json:"rect"`
+}
+
+func (c captureRequest) command() (string, error) {
+	mode := strings.ToLower(c.Mode)
+	if mode == "" {
+		mode = "screen"
+	}
+	parts := []string{"capture", mode}
+	switch mode {
+	case "screen", "window":
+		if len(c.Rect) > 0 {
+			return "", fmt.Errorf("rect is only valid for region captures")
+		}
+	case "region":
+		if len(c.Rect) != 4 {
+			return "", fmt.Errorf("region captures need rect [x, y, width, height]")
+		}
+	default:
+		return "", fmt.Errorf("unknown capture mode %q", c.Mode)
+	}
+	if target := strings.TrimSpace(c.Target); target != "" {
+		parts = append(parts, target)
+	}
+	for _, v := range c.Rect {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (a *httpAPI) handleCapture(w http.ResponseWriter, r *http.Request) {
+	var req captureRequest
+	if err := decodeHTTPBody(r, &req); err != nil {
+		writeHTTPError(w, httpBodyStatus(err), err)
+		return
+	}
+	command, err := req.command()
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := a.server.execCommand(command); err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, newHTTPStatus(a.server))
+}
+
+// drawRequest applies shapes in order using the current stroke, after
+// switching to Color and Width when given.
+type drawRequest struct {
+	Color  string      `json:"color"`
+	Width  int         `json:"width"`
+	Shapes []drawShape `json:"shapes"`
+}
+
+type drawShape struct {
+	// Type is arrow, line, rect, circle or crop.
+	Type string `json:"type"`
+	// Points holds x0 y0 x1 y1, or x y r for circles.
+	Points []int  `json:"points"`
+	Color  string `json:"color"`
+	Width  int    `json:"width"`
+}
+
+var drawShapePoints = map[string]int{"arrow": 4, "line": 4, "rect": 4, "circle": 3, "crop": 4}
+
+func (d drawRequest) commands() ([]string, error) {
+	if len(d.Shapes) == 0 {
+		return nil, errors.New("no shapes given")
+	}
+	var commands []string
+	stroke := func(color string, width int) {
+		if color != "" {
+			commands = append(commands, "color "+color)
+		}
+		if width != 0 {
+			commands = append(commands, "width "+strconv.Itoa(width))
+		}
+	}
+	stroke(d.Color, d.Width)
+	for idx, shape := range d.Shapes {
+		kind := strings.ToLower(shape.Type)
+		want, ok := drawShapePoints[kind]
+		if !ok {
+			return nil, fmt.Errorf("shape %d: unknown type %q", idx, shape.Type)
+		}
+		if len(shape.Points) != want {
+			return nil, fmt.Errorf("shape %d: %s needs %d points, got %d", idx, kind, want, len(shape.Points))
+		}
+		stroke(shape.Color, shape.Width)
+		parts := []string{kind}
+		for _, v := range shape.Points {
+			parts = append(parts, strconv.Itoa(v))
+		}
+		commands = append(commands, strings.Join(parts, " "))
+	}
+	return commands, nil
+}
+
+func (a *httpAPI) handleDraw(w http.ResponseWriter, r *http.Request) {
+	var req drawRequest
+	if err := decodeHTTPBody(r, &req); err != nil {
+		writeHTTPError(w, httpBodyStatus(err), err)
+		return
+	}
+	commands, err := req.commands()
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, command := range commands {
+		if err := a.server.execCommand(command); err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", command, err))
+			return
+		}
+	}
+	writeHTTPJSON(w, http.StatusOK, newHTTPStatus(a.server))
+}
+
+type saveRequest struct {
+	// Path is the file to write. When empty the session outdir and pattern
+	// are used, or the Pictures directory when the session has no outdir.
+	Path string `json:"path"`
+}
+
+func (a *httpAPI) handleSave(w http.ResponseWriter, r *http.Request) {
+	var req saveRequest
+	if err := decodeHTTPBody(r, &req); err != nil {
+		writeHTTPError(w, httpBodyStatus(err), err)
+		return
+	}
+	if err := remoteSave(a.server, req.Path); err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, newHTTPStatus(a.server))
+}
+
+// remoteSave saves the session image to path, passed whole so that spaces
+// in it survive, falling back to the session outdir or the Pictures
+// directory for remote clients that cannot pick a path on the session's
+// host.
+func remoteSave(server *interactiveSocketServer, path string) error {
+	if path == "" && server.session.currentDefaults().OutDir == "" {
+		return server.execCommand("savepictures")
+	}
+	save := func() { server.session.saveFile(path, render.ScaleOptions{}) }
+	return server.execCall(strings.TrimSpace("save "+path), save)
+}
+
+func (a *httpAPI) handleCopy(w http.ResponseWriter, _ *http.Request) {
+	if err := a.server.execCommand("copy"); err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, newHTTPStatus(a.server))
+}
+
+// handleIndex serves the web preview, which polls /status and reloads
+// /image.png whenever the revision changes.
+func (a *httpAPI) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write(webIndex); err != nil {
+		log.Printf("http write index: %v", err)
+	}
+}
+
+func (a *httpAPI) handleImage(w http.ResponseWriter, _ *http.Request) {
+	data, err := a.server.session.encodePNG()
+	if err != nil {
+		writeHTTPError(w, http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if _, err := w.Write(data); err != nil {
+		log.Printf("http write image: %v", err)
+	}
+}
+
+// httpStatus is the JSON view of a session returned by most endpoints.
+type httpStatus struct {
+	Image      *httpImageSize `json:"image"`
+	WindowOpen bool           `json:"window_open"`
+	LastSaved  string         `json:"last_saved,omitempty"`
+	Pending    int            `json:"pending"`
+	Revision   uint64         `json:"revision"`
+}
+
+type httpImageSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func newHTTPStatus(server *interactiveSocketServer) httpStatus {
+	st := server.session.status()
+	out := httpStatus{
+		WindowOpen: st.windowOpen,
+		LastSaved:  st.lastSaved,
+		Pending:    server.jobs.queued(),
+		Revision:   st.revision,
+	}
+	if st.hasImage {
+		out.Image = &httpImageSize{Width: st.width, Height: st.height}
+	}
+	return out
+}
+
+func (a *httpAPI) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeHTTPJSON(w, http.StatusOK, newHTTPStatus(a.server))
+}
+
+// handleEvents streams session events as server-sent events named after the
+// event kind, with the detail as data.
+func (a *httpAPI) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	events, unsubscribe := a.server.session.events.subscribe()
+	defer unsubscribe()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := io.WriteString(w, ": subscribed\n\n"); err != nil {
+		return
+	}
+	flusher.Flush()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.server.stopCh:
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case ev := <-events:
+			detail := strings.ReplaceAll(ev.detail, "\n", "\\n")
+			err = writef(w, "event: %s\ndata: %s\n\n", ev.kind, detail)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// decodeHTTPBody decodes a JSON request body into v. An empty body leaves v
+// unchanged so endpoints can fall back to their defaults. Bodies must be sent
+// as application/json, which a form or plain fetch from another site cannot
+// do without a preflight.
+func decodeHTTPBody(r *http.Request, v any) error {
+	if r.ContentLength != 0 {
+		mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mt != "application/json" {
+			return errUnsupportedBody
+		}
+	}
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxHTTPRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// errUnsupportedBody is returned by decodeHTTPBody for a body that is not
+// JSON.
+var errUnsupportedBody = errors.New("request body must be application/json")
+
+// httpBodyStatus is the status for a body decodeHTTPBody refused.
+func httpBodyStatus(err error) int {
+	if errors.Is(err, errUnsupportedBody) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+func writeHTTPJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("http write response: %v", err)
+	}
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	writeHTTPJSON(w, status, map[string]string{"error": err.Error()})
+}
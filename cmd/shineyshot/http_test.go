@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRequiresToken(t *testing.T) {
+	server := startTestSocketServer(t)
+	ts := httptest.NewServer(newHTTPHandler(server, "secret", true))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/status")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	closeWithLog("response", resp.Body)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status without token = %d, want 401", resp.StatusCode)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/status", nil)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	closeWithLog("response", resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status with token = %d, want 200", resp.StatusCode)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		body   string
+		want   int
+	}{
+		{"plain text body", "Content-Type", "text/plain", "{}", http.StatusUnsupportedMediaType},
+		{"foreign origin", "Origin", "http://example.com", "", http.StatusForbidden},
+		{"rebound host", "Host", "example.com:8787", "", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/save?token=secret", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if tc.header == "Host" {
+			req.Host = tc.value
+		} else {
+			req.Header.Set(tc.header, tc.value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		closeWithLog("response", resp.Body)
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s = %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestHTTPDrawAndFetchImage(t *testing.T) {
+	server := startTestSocketServer(t)
+	ts := httptest.NewServer(newHTTPHandler(server, "secret", true))
+	defer ts.Close()
+
+	body := `{"color":"red","shapes":[{"type":"line","points":[0,0,10,10]}]}`
+	resp, err := http.Post(ts.URL+"/draw?token=secret", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("draw: %v", err)
+	}
+	closeWithLog("response", resp.Body)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("draw without image = %d, want 500", resp.StatusCode)
+	}
+
+	if err := server.session.setImage(image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+	resp, err = http.Post(ts.URL+"/draw?token=secret", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("draw: %v", err)
+	}
+	var st httpStatus
+	err = json.NewDecoder(resp.Body).Decode(&st)
+	closeWithLog("response", resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("draw = %d, %v", resp.StatusCode, err)
+	}
+	if st.Image == nil || st.Image.Width != 20 || st.Image.Height != 10 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	resp, err = http.Get(ts.URL + "/image.png?token=secret")
+	if err != nil {
+		t.Fatalf("image: %v", err)
+	}
+	img, err := png.Decode(resp.Body)
+	closeWithLog("response", resp.Body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, _, _, a := img.At(5, 5).RGBA(); a == 0 {
+		t.Errorf("expected the line to be drawn at 5,5")
+	}
+}
+
+func TestHTTPSavePathWithSpaces(t *testing.T) {
+	server := startTestSocketServer(t)
+	ts := httptest.NewServer(newHTTPHandler(server, "secret", true))
+	defer ts.Close()
+	if err := server.session.setImage(image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "my  shot.png")
+	body, err := json.Marshal(saveRequest{Path: path})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	resp, err := http.Post(ts.URL+"/save?token=secret", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	closeWithLog("response", resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("save = %d, want 200", resp.StatusCode)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("saved file: %v", err)
+	}
+}
+
+func TestHTTPRejectsInvalidRequests(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		req  captureRequest
+	}{
+		{"unknown mode", captureRequest{Mode: "desk"}},
+		{"region without rect", captureRequest{Mode: "region"}},
+		{"rect on screen", captureRequest{Rect: []int{0, 0, 1, 1}}},
+	} {
+		if _, err := tc.req.command(); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+	if _, err := (drawRequest{Shapes: []drawShape{{Type: "circle", Points: []int{1, 2}}}}).commands(); err == nil {
+		t.Errorf("expected an error for a circle with two points")
+	}
+	if err := (httpOptions{listen: ":8787"}).validate(); err == nil {
+		t.Errorf("expected a token to be required on all interfaces")
+	}
+	if err := (httpOptions{listen: "127.0.0.1:8787"}).validate(); err != nil {
+		t.Errorf("loopback without token: %v", err)
+	}
+}
+
+func TestHTTPServesWebPreview(t *testing.T) {
+	server := startTestSocketServer(t)
+	ts := httptest.NewServer(newHTTPHandler(server, "secret", true))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/?token=secret")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	closeWithLog("response", resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("index = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	before := newHTTPStatus(server).Revision
+	if err := server.session.setImage(image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("set image: %v", err)
+	}
+	if after := newHTTPStatus(server).Revision; after <= before {
+		t.Errorf("revision did not advance: %d -> %d", before, after)
+	}
+}
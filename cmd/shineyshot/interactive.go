@@ -1,7 +1,8 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -19,6 +20,10 @@ import (
 	"github.com/example/shineyshot/internal/appstate"
 	"github.com/example/shineyshot/internal/capture"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/record"
+	"github.com/example/shineyshot/internal/render"
+	"github.com/example/shineyshot/internal/webp"
 )
 
 type interactiveCmd struct {
@@ -27,7 +32,9 @@ type interactiveCmd struct {
 	mu     sync.RWMutex
 	img    *image.RGBA
 	output string
-	state  *appstate.AppState
+	// captured describes how img was captured; nil for opened images.
+	captured *capture.CaptureResult
+	state    *appstate.AppState
 
 	stdin  io.Reader
 	stdout io.Writer
@@ -45,8 +52,28 @@ type interactiveCmd struct {
 	backgroundSession string
 	backgroundDir     string
 
+	// recording is the screen recording 'record start' began, nil when
+	// none is running.
+	recording *activeRecording
+
 	includeDecorations bool
 	includeCursor      bool
+	backend            string
+	units              string
+	delay              time.Duration
+	includeOwnWindows  bool
+	pngMetadata        bool
+	primary            bool
+
+	events  *eventHub
+	lastTab string
+
+	defaults      sessionDefaults
+	maxImageBytes int64
+
+	// revision increases whenever the image changes so remote viewers can
+	// tell when to fetch it again.
+	revision uint64
 }
 
 func (i *interactiveCmd) writeln(w io.Writer, args ...any) {
@@ -101,6 +128,7 @@ func newInteractiveCmd(r *root) *interactiveCmd {
 		stdin:             os.Stdin,
 		stdout:            os.Stdout,
 		stderr:            os.Stderr,
+		events:            newEventHub(),
 	}
 }
 
@@ -108,18 +136,31 @@ func (i *interactiveCmd) captureOptions() capture.CaptureOptions {
 	return capture.CaptureOptions{
 		IncludeDecorations: i.includeDecorations,
 		IncludeCursor:      i.includeCursor,
+		Backend:            i.backend,
+		Units:              i.units,
+		Delay:              i.delay,
+		Progress:           countdown(i.stdout),
+		IncludeOwnWindows:  i.includeOwnWindows,
 	}
 }
 
 func (i *interactiveCmd) Run() error {
 	i.writeln(i.stdout, "Interactive mode. Type 'help' for commands.")
-	scanner := bufio.NewScanner(i.stdin)
+	prompt := newLinePrompt(i.stdin, i.stdout, completeInteractive)
+	defer closeWithLog("prompt", prompt)
+	defer i.finishRecording()
 	for {
-		i.writef(i.stdout, "> ")
-		if !scanner.Scan() {
-			break
+		line, err := prompt.ReadLine("> ")
+		if errors.Is(err, errPromptInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
 		}
-		done, err := i.executeLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		done, err := i.executeLine(line)
 		if err != nil {
 			return err
 		}
@@ -127,7 +168,6 @@ func (i *interactiveCmd) Run() error {
 			return nil
 		}
 	}
-	return scanner.Err()
 }
 
 func (i *interactiveCmd) executeLine(line string) (bool, error) {
@@ -146,8 +186,10 @@ func (i *interactiveCmd) executeLine(line string) (bool, error) {
 		i.printHelp()
 	case "capture":
 		i.handleCapture(args)
+	case "open":
+		i.handleOpen(args)
 	case "windows":
-		i.printWindowList()
+		i.printWindows(len(args) > 0 && strings.EqualFold(args[0], "all"))
 	case "screens":
 		i.printScreenList()
 	case "arrow":
@@ -174,6 +216,8 @@ func (i *interactiveCmd) executeLine(line string) (bool, error) {
 		i.handleShow(true)
 	case "tabs":
 		i.handleTabs(args)
+	case "flatten":
+		i.handleFlatten()
 	case "save":
 		i.handleSave(args)
 	case "savetmp":
@@ -184,8 +228,16 @@ func (i *interactiveCmd) executeLine(line string) (bool, error) {
 		i.handleSaveHome()
 	case "copy":
 		i.handleCopy()
+	case "upload":
+		i.handleUpload(args)
+	case "record":
+		i.handleRecord(args)
 	case "copyname":
 		i.handleCopyName()
+	case "defaults":
+		i.handleDefaults()
+	case "delay":
+		i.handleDelay(args)
 	case "background":
 		i.handleBackground(args)
 	default:
@@ -196,9 +248,13 @@ func (i *interactiveCmd) executeLine(line string) (bool, error) {
 
 func (i *interactiveCmd) printHelp() {
 	i.writeln(i.stdout, "Commands:")
-	i.writeln(i.stdout, "  capture screen [DISPLAY]   capture full screen; use 'screens' to list displays")
+	i.writeln(i.stdout, "  capture screen [DISPLAY]   capture full screen, or DISPLAY ('current' follows the pointer, 'all' is every monitor); use 'screens' to list displays")
 	i.writeln(i.stdout, "  capture window [SELECTOR]   capture window by selector; defaults to active window; 'windows' lists options")
 	i.writeln(i.stdout, "  capture region [SCREEN] X Y WIDTH HEIGHT   capture region on a screen; 'screens' lists displays")
+	i.writeln(i.stdout, "  capture region select      pick a region interactively through the desktop portal")
+	i.writeln(i.stdout, "  capture workspace N        composite the windows on virtual desktop N")
+	i.writeln(i.stdout, "  delay [DURATION]           wait before each capture, e.g. 3 or 1.5s; 0 turns it off")
+	i.writeln(i.stdout, "  open FILE                  load an image file as the current image")
 	i.writeln(i.stdout, "  arrow x0 y0 x1 y1          draw arrow with current stroke")
 	i.writeln(i.stdout, "  line x0 y0 x1 y1           draw line with current stroke")
 	i.writeln(i.stdout, "  rect x0 y0 x1 y1           draw rectangle with current stroke")
@@ -210,8 +266,11 @@ func (i *interactiveCmd) printHelp() {
 	i.writeln(i.stdout, "  widths                     list stroke widths")
 	i.writeln(i.stdout, "  show                       open synced annotation window")
 	i.writeln(i.stdout, "  preview                    open copy in separate window")
-	i.writeln(i.stdout, "  tabs [list|switch|next|prev|close]   manage annotation tabs")
-	i.writeln(i.stdout, "  save FILE                  save image to FILE")
+	i.writeln(i.stdout, "  tabs [list|switch|next|prev|close|rename|move]   manage annotation tabs")
+	i.writeln(i.stdout, "    rename [INDEX] TITLE, move FROM TO   retitle a tab or move it along the bar")
+	i.writeln(i.stdout, "  flatten                    draw the current tab's texts, callouts and markers into its image")
+	i.writeln(i.stdout, "  save [FILE]                save image to FILE; without FILE uses the session (or configured) outdir and pattern")
+	i.writeln(i.stdout, "    --scale 50% --max-width 1200   shrink the saved image, keeping its aspect ratio")
 	i.writeln(i.stdout, "  savetmp                    save to /tmp with a unique filename")
 	picturesHelp := "save to your Pictures directory"
 	if dir, err := picturesDir(); err == nil {
@@ -220,9 +279,13 @@ func (i *interactiveCmd) printHelp() {
 	i.writeln(i.stdout, fmt.Sprintf("  savepictures               %s", picturesHelp))
 	i.writeln(i.stdout, "  savehome                   save to your home directory")
 	i.writeln(i.stdout, "  copy                       copy image to clipboard")
-	i.writeln(i.stdout, "  windows                    list available windows and selectors")
+	i.writeln(i.stdout, "  upload [NAME]              upload image to a configured destination and copy its link")
+	i.writeln(i.stdout, "  record start [screen|window|region] [SELECTOR|X0,Y0,X1,Y1] [FILE]   record to a .gif, .webp, .mp4 or .webm file")
+	i.writeln(i.stdout, "  record stop                save the recording and load its last frame")
+	i.writeln(i.stdout, "  windows [all]              list available windows and selectors; 'all' adds minimized ones")
 	i.writeln(i.stdout, "  screens                    list available screens/displays")
 	i.writeln(i.stdout, "  copyname                   copy last saved filename")
+	i.writeln(i.stdout, "  defaults                   show background session defaults")
 	i.writeln(i.stdout, "  background start [NAME] [DIR]   launch a background socket session")
 	i.writeln(i.stdout, "  background stop [NAME] [DIR]    stop a background socket session")
 	i.writeln(i.stdout, "  background list [DIR]           list background sessions")
@@ -239,18 +302,21 @@ func (i *interactiveCmd) printHelp() {
 	i.writeln(i.stdout, "  exec:<name>      executable name substring")
 	i.writeln(i.stdout, "  class:<name>     X11 WM_CLASS substring")
 	i.writeln(i.stdout, "  title:<text>     window title substring (useful for literal words like 'list')")
+	i.writeln(i.stdout, "  desktop:<n>      a window on virtual desktop n, preferring the active one")
+	i.writeln(i.stdout, "  pick             click the window with a crosshair pointer (portal dialog on Wayland)")
+	i.writeln(i.stdout, "  portal           pick the window in the desktop's screen-sharing dialog (Wayland)")
 	i.writeln(i.stdout, "  <text>           fallback substring match on title/executable/class")
 }
 
 func (i *interactiveCmd) handleCapture(args []string) {
 	if len(args) < 1 {
-		i.writeln(i.stderr, "usage: capture [screen|window|region] ...")
+		i.writeln(i.stderr, "usage: capture [screen|window|region|workspace] ...")
 		return
 	}
 	mode := strings.ToLower(args[0])
 	params := args[1:]
 	var (
-		img    *image.RGBA
+		res    capture.CaptureResult
 		err    error
 		target string
 	)
@@ -265,9 +331,10 @@ func (i *interactiveCmd) handleCapture(args []string) {
 		if len(params) >= 1 {
 			display = strings.Join(params, " ")
 		}
-		img, err = capture.CaptureScreenshot(display, opts)
+		res, err = capture.CaptureScreenshot(display, opts)
 		if err != nil && display == "" {
-			img, err = capture.CaptureScreenshot("0", opts)
+			opts.Delay = 0
+			res, err = capture.CaptureScreenshot("0", opts)
 			if err == nil {
 				target = "display 0"
 			}
@@ -295,19 +362,27 @@ func (i *interactiveCmd) handleCapture(args []string) {
 		if len(params) > 0 {
 			selector = strings.Join(params, " ")
 		}
-		var info capture.WindowInfo
-		img, info, err = capture.CaptureWindowDetailed(selector, opts)
+		res, err = capture.CaptureWindow(selector, opts)
 		if err != nil {
 			i.writeln(i.stderr, err)
 			i.printWindowList()
 			return
 		}
-		target = formatWindowLabel(info)
+		if res.Window != nil {
+			target = formatWindowLabel(*res.Window)
+		}
 	case "region":
 		if len(params) >= 1 && strings.EqualFold(params[0], "list") {
 			i.printScreenList()
 			return
 		}
+		if len(params) == 1 && strings.EqualFold(params[0], "select") {
+			res, err = capture.CaptureRegion(opts)
+			if err == nil {
+				target = "selection"
+			}
+			break
+		}
 		if len(params) < 4 {
 			i.writeln(i.stderr, "usage: capture region [SCREEN] X Y WIDTH HEIGHT")
 			i.printScreenList()
@@ -345,26 +420,47 @@ func (i *interactiveCmd) handleCapture(args []string) {
 			monitor.Rect.Min.X+coords[0]+coords[2],
 			monitor.Rect.Min.Y+coords[1]+coords[3],
 		)
-		img, err = capture.CaptureRegionRect(rect, opts)
+		res, err = capture.CaptureRegionRect(rect, opts)
 		if err == nil {
 			target = fmt.Sprintf("%s @ %dx%d+%d,%d", formatMonitorName(monitor), coords[2], coords[3], coords[0], coords[1])
 		}
+	case "workspace":
+		if len(params) != 1 {
+			i.writeln(i.stderr, "usage: capture workspace N")
+			return
+		}
+		desktop, convErr := strconv.Atoi(params[0])
+		if convErr != nil || desktop < 0 {
+			i.writef(i.stderr, "invalid desktop %q\n", params[0])
+			return
+		}
+		res, err = capture.CaptureWorkspace(desktop, opts)
+		if err == nil {
+			target = strconv.Itoa(desktop)
+		}
 	default:
-		i.writeln(i.stderr, "usage: capture [screen|window|region] ...")
+		i.writeln(i.stderr, "usage: capture [screen|window|region|workspace] ...")
 		return
 	}
 	if err != nil {
 		i.writeln(i.stderr, err)
 		return
 	}
-	i.setImage(img)
+	if err := i.setCapture(res); err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	defaults := i.currentDefaults()
+	captureFeedback(res, defaults.Sound, defaults.Flash)
+	detail := mode
+	if target != "" {
+		detail = fmt.Sprintf("%s %s", mode, target)
+	}
+	detail = strings.TrimSpace(detail)
 	if i.r != nil {
-		detail := mode
-		if target != "" {
-			detail = fmt.Sprintf("%s %s", mode, target)
-		}
-		i.r.notifyCapture(strings.TrimSpace(detail), img)
+		i.r.notifyCapture(withCaptureSummary(detail, res), res.Image)
 	}
+	i.events.publish("capture", detail)
 	if target != "" {
 		i.writef(i.stdout, "captured %s %s\n", mode, target)
 	} else {
@@ -372,6 +468,34 @@ func (i *interactiveCmd) handleCapture(args []string) {
 	}
 }
 
+func (i *interactiveCmd) handleOpen(args []string) {
+	if len(args) == 0 {
+		i.writeln(i.stderr, "usage: open FILE")
+		return
+	}
+	i.openFile(strings.Join(args, " "))
+}
+
+// openFile loads the image at path, which is taken whole rather than split
+// into words, as the current image.
+func (i *interactiveCmd) openFile(path string) {
+	path, err := expandUserPath(path)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	img, err := imagefile.Open(path)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	if err := i.setImage(img); err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	i.writef(i.stdout, "opened %s (%dx%d)\n", path, img.Bounds().Dx(), img.Bounds().Dy())
+}
+
 func (i *interactiveCmd) handleArrow(args []string) {
 	vals, err := parseInts(args, 4)
 	if err != nil {
@@ -380,7 +504,7 @@ func (i *interactiveCmd) handleArrow(args []string) {
 	}
 	if err := i.withImage(true, func(img *image.RGBA) error {
 		col, width := i.strokeLocked()
-		appstate.DrawArrow(img, vals[0], vals[1], vals[2], vals[3], col, width)
+		appstate.DrawArrow(img, vals[0], vals[1], vals[2], vals[3], col, width, appstate.LineSolid)
 		return nil
 	}); err != nil {
 		i.writeln(i.stderr, err)
@@ -397,7 +521,7 @@ func (i *interactiveCmd) handleLine(args []string) {
 	}
 	if err := i.withImage(true, func(img *image.RGBA) error {
 		col, width := i.strokeLocked()
-		appstate.DrawLine(img, vals[0], vals[1], vals[2], vals[3], col, width)
+		appstate.DrawLine(img, vals[0], vals[1], vals[2], vals[3], col, width, appstate.LineSolid)
 		return nil
 	}); err != nil {
 		i.writeln(i.stderr, err)
@@ -414,7 +538,7 @@ func (i *interactiveCmd) handleRect(args []string) {
 	}
 	if err := i.withImage(true, func(img *image.RGBA) error {
 		col, width := i.strokeLocked()
-		appstate.DrawRect(img, image.Rect(vals[0], vals[1], vals[2], vals[3]), col, width)
+		appstate.DrawRect(img, image.Rect(vals[0], vals[1], vals[2], vals[3]), col, width, appstate.LineSolid)
 		return nil
 	}); err != nil {
 		i.writeln(i.stderr, err)
@@ -431,7 +555,7 @@ func (i *interactiveCmd) handleCircle(args []string) {
 	}
 	if err := i.withImage(true, func(img *image.RGBA) error {
 		col, width := i.strokeLocked()
-		appstate.DrawCircle(img, vals[0], vals[1], vals[2], col, width)
+		appstate.DrawCircle(img, vals[0], vals[1], vals[2], col, width, appstate.LineSolid)
 		return nil
 	}); err != nil {
 		i.writeln(i.stderr, err)
@@ -463,29 +587,39 @@ func (i *interactiveCmd) handleColor(args []string) {
 		i.printColorList()
 		return
 	}
-	arg := args[0]
+	idx, err := i.resolveColor(args[0])
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	i.applyColorIndex(idx)
+}
+
+// resolveColor maps a palette index, palette name or hex value to a palette
+// index, adding hex colors to the palette when needed.
+func (i *interactiveCmd) resolveColor(arg string) (int, error) {
+	i.refreshPalette()
+	i.mu.RLock()
+	palette := i.palette
+	i.mu.RUnlock()
 	if idx, err := strconv.Atoi(arg); err == nil {
-		if idx < 0 || idx >= len(i.palette) {
-			i.writef(i.stderr, "color index must be between 0 and %d\n", len(i.palette)-1)
-			return
+		if idx < 0 || idx >= len(palette) {
+			return 0, fmt.Errorf("color index must be between 0 and %d", len(palette)-1)
 		}
-		i.applyColorIndex(idx)
-		return
+		return idx, nil
 	}
-	for idx, entry := range i.palette {
+	for idx, entry := range palette {
 		if entry.Name != "" && strings.EqualFold(entry.Name, arg) {
-			i.applyColorIndex(idx)
-			return
+			return idx, nil
 		}
 	}
 	col, err := parseHexColor(arg)
 	if err != nil {
-		i.writef(i.stderr, "invalid color %q\n", arg)
-		return
+		return 0, fmt.Errorf("invalid color %q", arg)
 	}
 	idx := appstate.EnsurePaletteColor(col, "")
 	i.refreshPalette()
-	i.applyColorIndex(idx)
+	return idx, nil
 }
 
 func (i *interactiveCmd) handleColorList() {
@@ -578,6 +712,36 @@ func (i *interactiveCmd) printWidthList() {
 	}
 }
 
+func (i *interactiveCmd) handleDelay(args []string) {
+	if len(args) == 0 {
+		i.writef(i.stdout, "capture delay %s\n", i.delay)
+		return
+	}
+	if len(args) != 1 {
+		i.writeln(i.stderr, "usage: delay [DURATION]")
+		return
+	}
+	delay, err := parseDelay(args[0])
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	i.delay = delay
+	i.writef(i.stdout, "capture delay %s\n", i.delay)
+}
+
+// parseDelay reads a capture delay as a Go duration or a number of seconds.
+func parseDelay(val string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(val, 64); err == nil && secs >= 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid delay %q", val)
+	}
+	return d, nil
+}
+
 func (i *interactiveCmd) printScreenList() {
 	monitors, err := capture.ListMonitors()
 	if err != nil {
@@ -595,18 +759,42 @@ func (i *interactiveCmd) printScreenList() {
 			primary = " [primary]"
 		}
 		rect := mon.Rect
-		i.writef(i.stdout, "  %s -> %dx%d+%d,%d%s\n", formatMonitorName(mon), rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y, primary)
+		i.writef(i.stdout, "  %s -> %dx%d+%d,%d%s%s\n", formatMonitorName(mon), rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y, formatMonitorTransform(mon), primary)
 	}
 }
 
+func formatMonitorTransform(mon capture.MonitorInfo) string {
+	var b strings.Builder
+	if scale := mon.ScaleFactor(); scale != 1 {
+		fmt.Fprintf(&b, " scale %g", scale)
+	}
+	if mon.Rotation != 0 {
+		fmt.Fprintf(&b, " rotated %d", mon.Rotation)
+	}
+	if mon.Flipped {
+		b.WriteString(" flipped")
+	}
+	return b.String()
+}
+
 func formatMonitorName(mon capture.MonitorInfo) string {
+	name := fmt.Sprintf("#%d", mon.Index)
 	if mon.Name != "" {
-		return fmt.Sprintf("#%d %s", mon.Index, mon.Name)
+		name += " " + mon.Name
+	}
+	if mon.Model != "" {
+		name += fmt.Sprintf(" (%s)", mon.Model)
 	}
-	return fmt.Sprintf("#%d", mon.Index)
+	return name
 }
 
 func (i *interactiveCmd) printWindowList() {
+	i.printWindows(false)
+}
+
+// printWindows lists the windows, including minimized and hidden ones when
+// all is set.
+func (i *interactiveCmd) printWindows(all bool) {
 	windows, err := capture.ListWindows()
 	if err != nil {
 		i.writeln(i.stderr, err)
@@ -617,14 +805,18 @@ func (i *interactiveCmd) printWindowList() {
 		return
 	}
 	i.writeln(i.stdout, "available windows (* marks the active window):")
-	for _, win := range windows {
+	shown, hidden := viewableWindows(windows, all)
+	for _, win := range shown {
 		marker := " "
 		if win.Active {
 			marker = "*"
 		}
 		i.writef(i.stdout, "%s %s\n", marker, formatWindowLabel(win))
 	}
-	i.writeln(i.stdout, "selectors: index:<n>, id:<hex>, pid:<pid>, exec:<name>, class:<name>, title:<text>, substring match")
+	if hidden > 0 {
+		i.writef(i.stdout, "%d minimized or hidden windows not shown; run 'windows all' to list them\n", hidden)
+	}
+	i.writeln(i.stdout, "selectors: index:<n>, id:<hex>, pid:<pid>, exec:<name>, class:<name>, title:<text>, desktop:<n>, substring match")
 }
 
 func formatWindowLabel(info capture.WindowInfo) string {
@@ -639,6 +831,18 @@ func formatWindowLabel(info capture.WindowInfo) string {
 	if info.Class != "" {
 		meta = append(meta, fmt.Sprintf("class:%s", info.Class))
 	}
+	switch {
+	case info.Desktop == capture.AllDesktops:
+		meta = append(meta, "desktop:all")
+	case info.Desktop >= 0:
+		meta = append(meta, fmt.Sprintf("desktop:%d", info.Desktop))
+	}
+	if info.Type != "" && info.Type != "normal" {
+		meta = append(meta, fmt.Sprintf("type:%s", info.Type))
+	}
+	if info.State != 0 {
+		meta = append(meta, fmt.Sprintf("state:%s", info.State))
+	}
 	extra := ""
 	if len(meta) > 0 {
 		extra = " (" + strings.Join(meta, ", ") + ")"
@@ -710,6 +914,13 @@ func (i *interactiveCmd) handleShow(copyImage bool) {
 			})),
 			appstate.WithVersion(version),
 			appstate.WithTheme(i.r.activeTheme),
+			appstate.WithTabStorage(i.r.tabStorage),
+			appstate.WithShortcuts(i.r.shortcuts),
+			appstate.WithUIScale(i.r.uiScale),
+			appstate.WithSnapDistance(i.r.snapDistance),
+			appstate.WithWebP(i.r.webp),
+			appstate.WithAVIF(i.r.avif),
+			appstate.WithJPEG(i.r.jpeg),
 		)
 		go st.Run()
 		i.writeln(i.stdout, "preview window opened")
@@ -724,6 +935,7 @@ func (i *interactiveCmd) handleShow(copyImage bool) {
 	output := i.output
 	colorIdx := i.colorIdx
 	widthIdx := i.widthIdx
+	captured := i.captured
 	var st *appstate.AppState
 	onClose := func() {
 		i.mu.Lock()
@@ -737,7 +949,7 @@ func (i *interactiveCmd) handleShow(copyImage bool) {
 	if output != "" {
 		detail = filepath.Base(output)
 	}
-	st = appstate.New(
+	opts := []appstate.Option{
 		appstate.WithImage(img),
 		appstate.WithOutput(output),
 		appstate.WithColorIndex(colorIdx),
@@ -751,14 +963,31 @@ func (i *interactiveCmd) handleShow(copyImage bool) {
 		})),
 		appstate.WithVersion(version),
 		appstate.WithTheme(i.r.activeTheme),
+		appstate.WithTabStorage(i.r.tabStorage),
+		appstate.WithShortcuts(i.r.shortcuts),
+		appstate.WithUIScale(i.r.uiScale),
+		appstate.WithSnapDistance(i.r.snapDistance),
+		appstate.WithWebP(i.r.webp),
+		appstate.WithAVIF(i.r.avif),
+		appstate.WithJPEG(i.r.jpeg),
 		appstate.WithSettingsListener(func(cIdx, wIdx int) {
 			i.mu.Lock()
 			i.colorIdx = cIdx
 			i.widthIdx = wIdx
 			i.mu.Unlock()
 		}),
+		appstate.WithEventListener(func(kind appstate.EventKind, detail string) {
+			i.events.publish(string(kind), detail)
+		}),
 		appstate.WithOnClose(onClose),
-	)
+		appstate.WithCaptureDelay(i.delay),
+		appstate.WithPNGMetadata(i.pngMetadata),
+		appstate.WithPrimarySelection(i.primary),
+	}
+	if captured != nil {
+		opts = append(opts, appstate.WithCapture(*captured))
+	}
+	st = appstate.New(opts...)
 	i.state = st
 	i.r.state = st
 	i.mu.Unlock()
@@ -771,10 +1000,38 @@ func (i *interactiveCmd) onTabChange(change appstate.TabChange) {
 	if change.Image == nil {
 		return
 	}
+	summary := fmt.Sprintf("%d/%d", change.Current+1, len(change.Tabs))
+	if change.Current >= 0 && change.Current < len(change.Tabs) {
+		summary += " " + tabDisplayTitle(change.Tabs[change.Current])
+	}
 	i.mu.Lock()
 	i.img = change.Image
+	i.captured = change.Capture
+	i.revision++
 	i.widthIdx = clampIndex(change.WidthIdx, len(i.widths))
+	changed := summary != i.lastTab
+	i.lastTab = summary
 	i.mu.Unlock()
+	if changed {
+		i.events.publish("tab", summary)
+	}
+}
+
+// handleFlatten draws the items on the annotation window's current tab
+// into its image, as an edit the window can undo.
+func (i *interactiveCmd) handleFlatten() {
+	i.mu.RLock()
+	st := i.state
+	i.mu.RUnlock()
+	if st == nil {
+		i.writeln(i.stderr, "annotation window not open; run 'show' first")
+		return
+	}
+	if err := st.Flatten(); err != nil {
+		i.writeln(i.stderr, err.Error())
+		return
+	}
+	i.writeln(i.stdout, "flattened the current tab")
 }
 
 func (i *interactiveCmd) handleTabs(args []string) {
@@ -866,8 +1123,58 @@ func (i *interactiveCmd) handleTabs(args []string) {
 			return
 		}
 		i.writef(i.stdout, "closed tab %d (%s)\n", idx+1, title)
+	case "rename":
+		// A leading number naming an open tab picks the tab to rename;
+		// otherwise the whole rest of the line titles the current tab.
+		idx, words := snapshot.Current, args[1:]
+		if len(words) > 1 {
+			if parsed, err := parseTabNumber(words[0]); err == nil && parsed >= 0 && parsed < len(snapshot.Tabs) {
+				idx, words = parsed, words[1:]
+			}
+		}
+		title := strings.Join(words, " ")
+		if strings.TrimSpace(title) == "" {
+			i.writeln(i.stderr, "usage: tabs rename [INDEX] TITLE")
+			return
+		}
+		if idx < 0 || idx >= len(snapshot.Tabs) {
+			i.writef(i.stderr, "tab %d does not exist\n", idx+1)
+			return
+		}
+		if err := st.RenameTab(idx, title); err != nil {
+			i.writeln(i.stderr, err.Error())
+			return
+		}
+		i.writef(i.stdout, "renamed tab %d to %s\n", idx+1, strings.TrimSpace(title))
+	case "move":
+		if len(args) < 3 {
+			i.writeln(i.stderr, "usage: tabs move FROM TO")
+			return
+		}
+		from, err := parseTabNumber(args[1])
+		if err != nil {
+			i.writeln(i.stderr, err.Error())
+			return
+		}
+		to, err := parseTabNumber(args[2])
+		if err != nil {
+			i.writeln(i.stderr, err.Error())
+			return
+		}
+		for _, idx := range []int{from, to} {
+			if idx < 0 || idx >= len(snapshot.Tabs) {
+				i.writef(i.stderr, "tab %d does not exist\n", idx+1)
+				return
+			}
+		}
+		title := tabDisplayTitle(snapshot.Tabs[from])
+		if err := st.MoveTab(from, to); err != nil {
+			i.writeln(i.stderr, err.Error())
+			return
+		}
+		i.writef(i.stdout, "moved tab %d (%s) to %d\n", from+1, title, to+1)
 	default:
-		i.writeln(i.stderr, "usage: tabs [list|switch INDEX|next|prev|close [INDEX]]")
+		i.writeln(i.stderr, "usage: tabs [list|switch INDEX|next|prev|close [INDEX]|rename [INDEX] TITLE|move FROM TO]")
 	}
 }
 
@@ -883,16 +1190,58 @@ func (i *interactiveCmd) printTabList(state appstate.TabsState) {
 }
 
 func (i *interactiveCmd) handleSave(args []string) {
-	if len(args) != 1 {
-		i.writeln(i.stderr, "usage: save FILE")
+	args, scale, err := splitScaleArgs(args)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	if len(args) > 1 {
+		i.writeln(i.stderr, "usage: save FILE [--scale PERCENT] [--max-width PIXELS]")
+		return
+	}
+	path := ""
+	if len(args) == 1 {
+		if args[0] == "" {
+			i.writeln(i.stderr, "path must not be empty")
+			return
+		}
+		path = args[0]
+	}
+	i.saveFile(path, scale)
+}
+
+// saveFile saves the image to path, which is taken whole rather than split
+// into words, or with an empty path to the session outdir and pattern.
+func (i *interactiveCmd) saveFile(path string, scale render.ScaleOptions) {
+	i.mu.RLock()
+	outDir := i.defaults.OutDir
+	i.mu.RUnlock()
+	autoDir := outDir
+	if autoDir == "" && i.r != nil && i.r.config != nil && i.r.config.SaveDir != "" {
+		dir, err := expandUserPath(i.r.config.SaveDir)
+		if err != nil {
+			i.writeln(i.stderr, err)
+			return
+		}
+		autoDir = dir
+	}
+	if path == "" && autoDir != "" {
+		path, err := i.saveAuto(autoDir, i.savePattern(), scale)
+		if err != nil {
+			i.writeln(i.stderr, err)
+			return
+		}
+		i.finalizeSave(path)
 		return
 	}
-	path := args[0]
 	if path == "" {
-		i.writeln(i.stderr, "path must not be empty")
+		i.writeln(i.stderr, "usage: save FILE [--scale PERCENT] [--max-width PIXELS]")
 		return
 	}
-	if err := i.saveToPath(path); err != nil {
+	if outDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(outDir, path)
+	}
+	if err := i.saveToPath(path, scale); err != nil {
 		i.writeln(i.stderr, err)
 		return
 	}
@@ -914,7 +1263,7 @@ func (i *interactiveCmd) handleSavePictures() {
 		i.writeln(i.stderr, err)
 		return
 	}
-	path, err := i.saveAuto(dir, "shineyshot")
+	path, err := i.saveAuto(dir, i.savePattern(), render.ScaleOptions{})
 	if err != nil {
 		i.writeln(i.stderr, err)
 		return
@@ -928,7 +1277,7 @@ func (i *interactiveCmd) handleSaveHome() {
 		i.writeln(i.stderr, err)
 		return
 	}
-	path, err := i.saveAuto(home, "shineyshot")
+	path, err := i.saveAuto(home, i.savePattern(), render.ScaleOptions{})
 	if err != nil {
 		i.writeln(i.stderr, err)
 		return
@@ -937,8 +1286,11 @@ func (i *interactiveCmd) handleSaveHome() {
 }
 
 func (i *interactiveCmd) handleCopy() {
+	i.mu.RLock()
+	output := i.output
+	i.mu.RUnlock()
 	if err := i.withImage(false, func(img *image.RGBA) error {
-		return clipboard.WriteImage(img)
+		return clipboard.WriteContent(clipboard.Content{Image: img, Path: output}, copySelections(i.primary)...)
 	}); err != nil {
 		i.writeln(i.stderr, err)
 		return
@@ -947,6 +1299,162 @@ func (i *interactiveCmd) handleCopy() {
 	if i.r != nil {
 		i.r.notifyCopy("image")
 	}
+	i.events.publish("copy", "image")
+}
+
+// handleUpload uploads the current image as a PNG to the destination named
+// by args, or the default one.
+func (i *interactiveCmd) handleUpload(args []string) {
+	if len(args) > 1 {
+		i.writeln(i.stderr, "usage: upload [NAME]")
+		return
+	}
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	}
+	dest, err := i.r.uploadDestination(name)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	data, err := i.encodePNG()
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	i.mu.RLock()
+	output := i.output
+	i.mu.RUnlock()
+	file := time.Now().Format("shineyshot-20060102-150405.png")
+	if output != "" {
+		file = strings.TrimSuffix(filepath.Base(output), filepath.Ext(output)) + ".png"
+	}
+	link, err := i.r.upload(dest, uploadImage(file, data), true, i.primary, i.stderr)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	i.writeln(i.stdout, link)
+	i.events.publish("upload", link)
+}
+
+// handleRecord starts a screen recording in the background or stops the
+// running one, loading its last frame as the current image.
+func (i *interactiveCmd) handleRecord(args []string) {
+	if len(args) == 0 {
+		i.writeln(i.stderr, "usage: record [start|stop] ...")
+		return
+	}
+	switch strings.ToLower(args[0]) {
+	case "start":
+		i.startRecording(args[1:])
+	case "stop":
+		i.mu.RLock()
+		running := i.recording != nil
+		i.mu.RUnlock()
+		if !running {
+			i.writeln(i.stderr, "not recording")
+			return
+		}
+		rec := i.stopRecording()
+		if rec == nil {
+			return
+		}
+		if err := i.setImage(rec.w.Last()); err != nil {
+			i.writeln(i.stderr, err)
+		}
+		i.finalizeSave(rec.path)
+		i.writef(i.stdout, "%d frames, %s\n", rec.w.Frames(), rec.length.Round(time.Second/10))
+	default:
+		i.writeln(i.stderr, "usage: record [start|stop] ...")
+	}
+}
+
+// stopRecording stops the running recording and waits for its file,
+// returning nil when none was running or it failed.
+func (i *interactiveCmd) stopRecording() *activeRecording {
+	i.mu.Lock()
+	rec := i.recording
+	i.recording = nil
+	i.mu.Unlock()
+	if rec == nil {
+		return nil
+	}
+	if err := rec.stop(); err != nil {
+		i.writef(i.stderr, "recording failed: %v\n", err)
+		return nil
+	}
+	return rec
+}
+
+// finishRecording saves a recording still running when the session ends.
+func (i *interactiveCmd) finishRecording() {
+	if rec := i.stopRecording(); rec != nil {
+		i.finalizeSave(rec.path)
+	}
+}
+
+// startRecording parses [screen|window|region] [SELECTOR] [FILE], where
+// FILE is recognised by its recording extension.
+func (i *interactiveCmd) startRecording(args []string) {
+	i.mu.RLock()
+	running := i.recording
+	outDir := i.defaults.OutDir
+	i.mu.RUnlock()
+	if running != nil {
+		i.writef(i.stderr, "already recording to %s\n", running.path)
+		return
+	}
+	kind := "screen"
+	if len(args) > 0 {
+		kind = strings.ToLower(args[0])
+		args = args[1:]
+	}
+	path := ""
+	if n := len(args); n > 0 {
+		switch strings.ToLower(filepath.Ext(args[n-1])) {
+		case ".gif", ".webp", ".mp4", ".webm":
+			path, args = args[n-1], args[:n-1]
+		}
+	}
+	if outDir == "" && i.r != nil && i.r.config != nil && i.r.config.SaveDir != "" {
+		dir, err := expandUserPath(i.r.config.SaveDir)
+		if err != nil {
+			i.writeln(i.stderr, err)
+			return
+		}
+		outDir = dir
+	}
+	if path == "" {
+		unique, err := uniquePath(filepath.Join(outDir, time.Now().Format("shineyshot-20060102-150405.gif")))
+		if err != nil {
+			i.writeln(i.stderr, err)
+			return
+		}
+		path = unique
+	} else if outDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(outDir, path)
+	}
+	target, err := recordTarget(kind, strings.Join(args, " "), i.includeCursor)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	var webpOpts *webp.Options
+	if i.r != nil {
+		webpOpts = &i.r.webp
+	}
+	w, err := record.Create(path, record.FormatOf(path), defaultRecordFPS, webpOpts)
+	if err != nil {
+		i.writeln(i.stderr, err)
+		return
+	}
+	rec := startRecording(target, path, w)
+	i.mu.Lock()
+	i.recording = rec
+	i.mu.Unlock()
+	i.writef(i.stdout, "recording %s to %s; 'record stop' ends it\n", kind, path)
 }
 
 func (i *interactiveCmd) handleCopyName() {
@@ -957,7 +1465,7 @@ func (i *interactiveCmd) handleCopyName() {
 		i.writeln(i.stderr, "no saved file")
 		return
 	}
-	if err := clipboard.WriteText(output); err != nil {
+	if err := clipboard.WriteText(output, copySelections(i.primary)...); err != nil {
 		i.writeln(i.stderr, err)
 		return
 	}
@@ -965,6 +1473,7 @@ func (i *interactiveCmd) handleCopyName() {
 	if i.r != nil {
 		i.r.notifyCopy(output)
 	}
+	i.events.publish("copy", output)
 }
 
 func (i *interactiveCmd) handleBackground(args []string) {
@@ -1103,7 +1612,7 @@ func (i *interactiveCmd) startBackgroundSession(name, dirArg string) (string, st
 	if err != nil {
 		return "", "", err
 	}
-	session, err := startBackgroundServer(dir, name, i.r)
+	session, err := startBackgroundServer(dir, name, sessionDefaults{}, i.r)
 	if err != nil {
 		return "", "", err
 	}
@@ -1126,6 +1635,28 @@ func (i *interactiveCmd) listBackgroundSessions(dirArg string) error {
 	return printSocketList(dir, i.stdout)
 }
 
+// sessionState summarizes what a session currently holds.
+type sessionState struct {
+	hasImage   bool
+	width      int
+	height     int
+	windowOpen bool
+	lastSaved  string
+	revision   uint64
+}
+
+func (i *interactiveCmd) status() sessionState {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	st := sessionState{windowOpen: i.state != nil, lastSaved: i.output, revision: i.revision}
+	if i.img != nil {
+		st.hasImage = true
+		st.width = i.img.Bounds().Dx()
+		st.height = i.img.Bounds().Dy()
+	}
+	return st
+}
+
 func (i *interactiveCmd) withImage(write bool, fn func(img *image.RGBA) error) error {
 	if write {
 		i.mu.Lock()
@@ -1147,22 +1678,38 @@ func (i *interactiveCmd) withImage(write bool, fn func(img *image.RGBA) error) e
 	return fn(i.img)
 }
 
-func (i *interactiveCmd) setImage(img *image.RGBA) {
+func (i *interactiveCmd) setImage(img *image.RGBA) error {
+	return i.storeImage(img, nil)
+}
+
+// setCapture loads a fresh capture, keeping its metadata for file name
+// patterns and PNG text chunks.
+func (i *interactiveCmd) setCapture(res capture.CaptureResult) error {
+	return i.storeImage(res.Image, &res)
+}
+
+func (i *interactiveCmd) storeImage(img *image.RGBA, captured *capture.CaptureResult) error {
 	if img == nil {
-		return
+		return nil
 	}
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if limit := i.maxImageBytes; limit > 0 && int64(len(img.Pix)) > limit {
+		return fmt.Errorf("image needs %dMB, above the session limit of %dMB", (len(img.Pix)+(1<<20)-1)>>20, limit>>20)
+	}
 	if i.img == nil || i.state == nil {
 		i.img = img
 	} else {
 		*i.img = *img
 	}
 	i.output = ""
+	i.captured = captured
 	i.notifyLocked()
+	return nil
 }
 
 func (i *interactiveCmd) notifyLocked() {
+	i.revision++
 	if i.state != nil {
 		i.state.NotifyImageChanged()
 	}
@@ -1187,7 +1734,8 @@ func parseHexColor(s string) (color.RGBA, error) {
 	return color.RGBA{R: uint8(v >> 16), G: uint8((v >> 8) & 0xFF), B: uint8(v & 0xFF), A: 255}, nil
 }
 
-func (i *interactiveCmd) saveToPath(path string) error {
+// saveToPath writes the image to path, resized by scale.
+func (i *interactiveCmd) saveToPath(path string, scale render.ScaleOptions) error {
 	return i.withImage(false, func(img *image.RGBA) error {
 		dir := filepath.Dir(path)
 		if dir != "" && dir != "." {
@@ -1199,7 +1747,11 @@ func (i *interactiveCmd) saveToPath(path string) error {
 		if err != nil {
 			return err
 		}
-		if err := png.Encode(f, img); err != nil {
+		o := i.r.imageOptions(path)
+		if i.pngMetadata && i.captured != nil {
+			o.Text = i.captured.Text()
+		}
+		if err := imagefile.Encode(f, render.Scale(img, scale), o); err != nil {
 			if cerr := f.Close(); cerr != nil {
 				return fmt.Errorf("encode image: %w (close error: %v)", err, cerr)
 			}
@@ -1209,6 +1761,17 @@ func (i *interactiveCmd) saveToPath(path string) error {
 	})
 }
 
+// encodePNG returns the current image encoded as PNG.
+func (i *interactiveCmd) encodePNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := i.withImage(false, func(img *image.RGBA) error {
+		return png.Encode(&buf, img)
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func (i *interactiveCmd) saveToTmp() (string, error) {
 	var path string
 	err := i.withImage(false, func(img *image.RGBA) error {
@@ -1228,22 +1791,24 @@ func (i *interactiveCmd) saveToTmp() (string, error) {
 	return path, err
 }
 
-func (i *interactiveCmd) saveAuto(dir, prefix string) (string, error) {
-	ts := time.Now().Format("20060102-150405")
-	base := fmt.Sprintf("%s-%s.png", prefix, ts)
-	path := filepath.Join(dir, base)
-	counter := 1
-	for {
-		if _, err := os.Stat(path); err == nil {
-			path = filepath.Join(dir, fmt.Sprintf("%s-%s-%02d.png", prefix, ts, counter))
-			counter++
-			continue
-		} else if !os.IsNotExist(err) {
-			return "", err
-		}
-		break
+func (i *interactiveCmd) savePattern() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.defaults.Pattern != "" {
+		return i.defaults.Pattern
+	}
+	return i.r.savePattern()
+}
+
+func (i *interactiveCmd) saveAuto(dir, pattern string, scale render.ScaleOptions) (string, error) {
+	i.mu.RLock()
+	captured := i.captured
+	i.mu.RUnlock()
+	path, err := uniquePath(filepath.Join(dir, expandSavePattern(pattern, time.Now(), captured)))
+	if err != nil {
+		return "", err
 	}
-	if err := i.saveToPath(path); err != nil {
+	if err := i.saveToPath(path, scale); err != nil {
 		return "", err
 	}
 	return path, nil
@@ -1298,6 +1863,7 @@ func (i *interactiveCmd) finalizeSave(path string) {
 	if i.r != nil {
 		i.r.notifySave(display)
 	}
+	i.events.publish("save", display)
 }
 
 func parseInts(args []string, count int) ([]int, error) {
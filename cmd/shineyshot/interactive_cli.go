@@ -1,6 +1,10 @@
 package main
 
-import "flag"
+import (
+	"flag"
+
+	"github.com/example/shineyshot/internal/capture"
+)
 
 type interactiveCLI struct {
 	*interactiveCmd
@@ -24,9 +28,21 @@ func parseInteractiveCmd(args []string, r *root) (*interactiveCLI, error) {
 	fs.StringVar(&cli.socketDir, "socket-dir", "", "directory that stores shineyshot sockets (deprecated)")
 	fs.BoolVar(&cli.includeDecorations, "include-decorations", false, "request window decorations when capturing windows")
 	fs.BoolVar(&cli.includeCursor, "include-cursor", false, "embed the cursor in captures when supported")
+	fs.BoolVar(&cli.includeOwnWindows, "include-own-windows", false, "leave shineyshot's own windows visible in captures")
+	fs.StringVar(&cli.backend, "backend", capture.BackendAuto, "screenshot backend: auto, wlr, kwin, gnome, portal, x11, external, grim, spectacle, maim, or scrot")
+	fs.DurationVar(&cli.delay, "delay", 0, "wait this long before each capture, e.g. 3s")
+	fs.StringVar(&cli.units, "units", capture.UnitsPixel, "region coordinate units: pixel, or logical to scale by the monitor's HiDPI factor")
+	fs.BoolVar(&cli.pngMetadata, "png-metadata", false, "store the capture time, window and monitor as PNG text chunks when saving")
+	fs.BoolVar(&cli.primary, "primary", false, "also copy images and file names to the primary selection, pasted with a middle click")
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	if err := capture.CheckBackend(cli.backend); err != nil {
+		return nil, err
+	}
+	if err := capture.CheckUnits(cli.units); err != nil {
+		return nil, err
+	}
 	return cli, nil
 }
 
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+type jobState string
+
+const (
+	// jobPending is a job whose id is being sent to its client; it is
+	// queued once the client has it, and listed as queued meanwhile.
+	jobPending   jobState = "pending"
+	jobQueued    jobState = "queued"
+	jobRunning   jobState = "running"
+	jobCancelled jobState = "cancelled"
+)
+
+var errJobCancelled = errors.New("cancelled")
+
+// socketJob is one EXEC request waiting for or running on the session.
+type socketJob struct {
+	id      int
+	command string
+	// call, when set, runs in place of command, which then only names the
+	// job in JOBS listings. It serves callers that already hold their
+	// arguments, such as file paths, which the command line would split.
+	call func()
+	// image asks for the session image, PNG encoded, once the command
+	// succeeds.
+	image bool
+
+	stdout *jobWriter
+	stderr *jobWriter
+
+	// cancelled is closed when the job is cancelled; done receives the
+	// result once the command returns.
+	cancelled chan struct{}
+	done      chan jobResult
+}
+
+type jobResult struct {
+	close bool
+	err   error
+	png   []byte
+}
+
+// jobWriter forwards a job's output to its client until sealed. Commands can
+// leave goroutines behind, so anything written after the job finished or was
+// cancelled is dropped instead of leaking into another client's stream.
+type jobWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	sealed bool
+}
+
+func (j *jobWriter) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.sealed {
+		return len(p), nil
+	}
+	return j.w.Write(p)
+}
+
+func (j *jobWriter) seal() {
+	j.mu.Lock()
+	j.sealed = true
+	j.mu.Unlock()
+}
+
+// jobQueue runs socket commands one at a time against a single interactive
+// session while keeping every queued command visible and cancellable.
+type jobQueue struct {
+	session *interactiveCmd
+
+	mu     sync.Mutex
+	nextID int
+	jobs   map[int]*socketJob
+	states map[int]jobState
+	wake   chan struct{}
+}
+
+func newJobQueue(session *interactiveCmd) *jobQueue {
+	return &jobQueue{
+		session: session,
+		nextID:  1,
+		jobs:    make(map[int]*socketJob),
+		states:  make(map[int]jobState),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (q *jobQueue) submit(command string, stdout, stderr io.Writer) *socketJob {
+	return q.enqueue(command, false, stdout, stderr)
+}
+
+// submitCall queues call to run on the session in turn with the commands,
+// listed as command.
+func (q *jobQueue) submitCall(command string, call func(), stdout, stderr io.Writer) *socketJob {
+	job := q.prepare(command, false, stdout, stderr)
+	job.call = call
+	q.start(job)
+	return job
+}
+
+func (q *jobQueue) enqueue(command string, image bool, stdout, stderr io.Writer) *socketJob {
+	job := q.prepare(command, image, stdout, stderr)
+	q.start(job)
+	return job
+}
+
+// prepare registers a job for command without letting it run yet, so its id
+// can reach the client before any of its output. start queues it.
+func (q *jobQueue) prepare(command string, image bool, stdout, stderr io.Writer) *socketJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &socketJob{
+		id:        q.nextID,
+		command:   command,
+		image:     image,
+		stdout:    &jobWriter{w: stdout},
+		stderr:    &jobWriter{w: stderr},
+		cancelled: make(chan struct{}),
+		done:      make(chan jobResult, 1),
+	}
+	q.nextID++
+	q.jobs[job.id] = job
+	q.states[job.id] = jobPending
+	return job
+}
+
+// start queues a prepared job, unless it was cancelled meanwhile.
+func (q *jobQueue) start(job *socketJob) {
+	q.mu.Lock()
+	if q.states[job.id] != jobPending {
+		q.mu.Unlock()
+		return
+	}
+	q.states[job.id] = jobQueued
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the oldest queued job and marks it running.
+func (q *jobQueue) next() *socketJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var ids []int
+	for id, state := range q.states {
+		if state == jobQueued {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Ints(ids)
+	q.states[ids[0]] = jobRunning
+	return q.jobs[ids[0]]
+}
+
+func (q *jobQueue) finish(job *socketJob) {
+	job.stdout.seal()
+	job.stderr.seal()
+	q.mu.Lock()
+	delete(q.jobs, job.id)
+	delete(q.states, job.id)
+	q.mu.Unlock()
+}
+
+// run executes queued jobs until stop is closed.
+func (q *jobQueue) run(stop <-chan struct{}) {
+	for {
+		job := q.next()
+		if job == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-stop:
+				return
+			}
+		}
+		out := &taggedWriter{w: job.stdout, tag: "OUT "}
+		errW := &taggedWriter{w: job.stderr, tag: "ERR "}
+		restore := q.session.withIO(nil, out, errW)
+		var done bool
+		var err error
+		if job.call != nil {
+			job.call()
+		} else {
+			done, err = q.session.executeLine(job.command)
+		}
+		restore()
+		res := jobResult{close: done, err: err}
+		switch {
+		case err != nil || !job.image:
+		case errW.wrote.Load():
+			// Commands report most failures as ERR output; returning the
+			// previous image would hide that from pipelines.
+			res.err = errors.New("command failed; no image returned")
+		default:
+			res.png, res.err = q.session.encodePNG()
+		}
+		q.finish(job)
+		job.done <- res
+	}
+}
+
+// cancel removes a queued job or abandons a running one. A running command
+// cannot be interrupted, so it completes in the background with its output
+// discarded while the waiting client is released immediately.
+func (q *jobQueue) cancel(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf("no such job %d", id)
+	}
+	switch q.states[id] {
+	case jobPending, jobQueued:
+		delete(q.jobs, id)
+		delete(q.states, id)
+	case jobRunning:
+		q.states[id] = jobCancelled
+	default:
+		return fmt.Errorf("job %d already cancelled", id)
+	}
+	job.stdout.seal()
+	job.stderr.seal()
+	close(job.cancelled)
+	return nil
+}
+
+// wait blocks until the job completes or is cancelled.
+func (q *jobQueue) wait(job *socketJob) jobResult {
+	select {
+	case res := <-job.done:
+		return res
+	case <-job.cancelled:
+		return jobResult{err: errJobCancelled}
+	}
+}
+
+type jobSummary struct {
+	id      int
+	state   jobState
+	command string
+}
+
+func (q *jobQueue) list() []jobSummary {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	summaries := make([]jobSummary, 0, len(q.jobs))
+	for id, job := range q.jobs {
+		state := q.states[id]
+		if state == jobPending {
+			state = jobQueued
+		}
+		summaries = append(summaries, jobSummary{id: id, state: state, command: job.command})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].id < summaries[j].id })
+	return summaries
+}
+
+func (q *jobQueue) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, state := range q.states {
+		if state == jobQueued || state == jobPending {
+			n++
+		}
+	}
+	return n
+}
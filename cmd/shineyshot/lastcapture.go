@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/shineyshot/internal/capture"
+)
+
+// lastCapturePath is where snapshot keeps the recapture token of its most
+// recent capture for -same-as-last.
+func lastCapturePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shineyshot", "last-capture"), nil
+}
+
+func readLastCapture() (capture.RecaptureToken, error) {
+	path, err := lastCapturePath()
+	if err != nil {
+		return "", fmt.Errorf("locate last capture: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("no previous snapshot to repeat")
+	}
+	if err != nil {
+		return "", fmt.Errorf("read last capture: %w", err)
+	}
+	return capture.RecaptureToken(strings.TrimSpace(string(data))), nil
+}
+
+// saveLastCapture records token for the next -same-as-last. Captures that
+// cannot be repeated leave the previous token in place.
+func saveLastCapture(token capture.RecaptureToken) error {
+	if token == "" {
+		return nil
+	}
+	path, err := lastCapturePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(token+"\n"), 0o600)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// errPromptInterrupted is returned by ReadLine when Ctrl+C discards the line.
+var errPromptInterrupted = errors.New("interrupted")
+
+// linePrompt reads command lines from the user.
+type linePrompt interface {
+	ReadLine(prompt string) (string, error)
+	Close() error
+}
+
+// completionFunc returns candidates for word given the words before it.
+type completionFunc func(previous []string, word string) []string
+
+// newLinePrompt returns a line editor with history and completion when in is a
+// terminal, and a plain line reader otherwise.
+func newLinePrompt(in io.Reader, out io.Writer, complete completionFunc) linePrompt {
+	if f, ok := in.(*os.File); ok {
+		if term, err := openTerminal(f); err == nil {
+			return &lineEditor{
+				term:     term,
+				in:       bufio.NewReader(f),
+				out:      out,
+				complete: complete,
+			}
+		}
+	}
+	return &scannerPrompt{scanner: bufio.NewScanner(in), out: out}
+}
+
+type scannerPrompt struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (p *scannerPrompt) ReadLine(prompt string) (string, error) {
+	if _, err := fmt.Fprint(p.out, prompt); err != nil {
+		return "", err
+	}
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.scanner.Text(), nil
+}
+
+func (p *scannerPrompt) Close() error { return nil }
+
+// lineEditor is a small readline replacement supporting cursor movement,
+// history navigation, Tab completion and the usual Emacs-style control keys.
+type lineEditor struct {
+	term     *terminal
+	in       *bufio.Reader
+	out      io.Writer
+	complete completionFunc
+
+	history []string
+
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+const maxPromptHistory = 500
+
+func (e *lineEditor) Close() error { return e.term.restore() }
+
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	if err := e.term.makeRaw(); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := e.term.restore(); err != nil {
+			log.Printf("restore terminal: %v", err)
+		}
+	}()
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+	histIdx := len(e.history)
+	draft := ""
+	if err := e.redraw(); err != nil {
+		return "", err
+	}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			line := string(e.buf)
+			if _, err := io.WriteString(e.out, "\r\n"); err != nil {
+				return "", err
+			}
+			e.remember(line)
+			return line, nil
+		case 0x03: // Ctrl+C
+			if _, err := io.WriteString(e.out, "^C\r\n"); err != nil {
+				return "", err
+			}
+			return "", errPromptInterrupted
+		case 0x04: // Ctrl+D
+			if len(e.buf) == 0 {
+				if _, err := io.WriteString(e.out, "\r\n"); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case 0x7f, 0x08: // Backspace
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case 0x01: // Ctrl+A
+			e.pos = 0
+		case 0x05: // Ctrl+E
+			e.pos = len(e.buf)
+		case 0x02: // Ctrl+B
+			if e.pos > 0 {
+				e.pos--
+			}
+		case 0x06: // Ctrl+F
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case 0x0b: // Ctrl+K
+			e.buf = e.buf[:e.pos]
+		case 0x15: // Ctrl+U
+			e.buf = append(e.buf[:0], e.buf[e.pos:]...)
+			e.pos = 0
+		case 0x17: // Ctrl+W
+			start := e.pos
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.buf[start-1] != ' ' {
+				start--
+			}
+			e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+			e.pos = start
+		case 0x0c: // Ctrl+L
+			if _, err := io.WriteString(e.out, "\x1b[H\x1b[2J"); err != nil {
+				return "", err
+			}
+		case '\t':
+			if err := e.completeWord(); err != nil {
+				return "", err
+			}
+		case 0x10, 0x0e: // Ctrl+P, Ctrl+N
+			histIdx, draft = e.browseHistory(histIdx, draft, r == 0x10)
+		case 0x1b:
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "A":
+				histIdx, draft = e.browseHistory(histIdx, draft, true)
+			case "B":
+				histIdx, draft = e.browseHistory(histIdx, draft, false)
+			case "C":
+				if e.pos < len(e.buf) {
+					e.pos++
+				}
+			case "D":
+				if e.pos > 0 {
+					e.pos--
+				}
+			case "H", "1~", "7~":
+				e.pos = 0
+			case "F", "4~", "8~":
+				e.pos = len(e.buf)
+			case "3~":
+				e.deleteAt(e.pos)
+			}
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				continue
+			}
+			e.insert([]rune{r})
+		}
+		if err := e.redraw(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// readEscape consumes the remainder of a CSI or SS3 sequence and returns its
+// final key, e.g. "A" for the up arrow or "3~" for delete.
+func (e *lineEditor) readEscape() (string, error) {
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != '[' && b != 'O' {
+		return "", nil
+	}
+	var seq []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, c)
+		if (c >= 'A' && c <= 'Z') || c == '~' || len(seq) > 8 {
+			return string(seq), nil
+		}
+	}
+}
+
+func (e *lineEditor) insert(rs []rune) {
+	tail := append([]rune(nil), e.buf[e.pos:]...)
+	e.buf = append(append(e.buf[:e.pos], rs...), tail...)
+	e.pos += len(rs)
+}
+
+func (e *lineEditor) deleteAt(idx int) {
+	if idx < 0 || idx >= len(e.buf) {
+		return
+	}
+	e.buf = append(e.buf[:idx], e.buf[idx+1:]...)
+}
+
+func (e *lineEditor) browseHistory(idx int, draft string, older bool) (int, string) {
+	if idx == len(e.history) {
+		draft = string(e.buf)
+	}
+	if older {
+		if idx == 0 {
+			return idx, draft
+		}
+		idx--
+	} else {
+		if idx >= len(e.history) {
+			return idx, draft
+		}
+		idx++
+	}
+	line := draft
+	if idx < len(e.history) {
+		line = e.history[idx]
+	}
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+	return idx, draft
+}
+
+func (e *lineEditor) remember(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxPromptHistory {
+		e.history = e.history[len(e.history)-maxPromptHistory:]
+	}
+}
+
+func (e *lineEditor) completeWord() error {
+	if e.complete == nil {
+		return nil
+	}
+	start := e.pos
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	word := string(e.buf[start:e.pos])
+	previous := strings.Fields(string(e.buf[:start]))
+	candidates := e.complete(previous, word)
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		e.insert([]rune(strings.TrimPrefix(candidates[0], word) + " "))
+		return nil
+	}
+	if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+		e.insert([]rune(strings.TrimPrefix(prefix, word)))
+		return nil
+	}
+	_, err := fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	return err
+}
+
+func (e *lineEditor) redraw() error {
+	tail := len(e.buf) - e.pos
+	seq := "\r" + e.prompt + string(e.buf) + "\x1b[K"
+	if tail > 0 {
+		seq += fmt.Sprintf("\x1b[%dD", tail)
+	}
+	_, err := io.WriteString(e.out, seq)
+	return err
+}
+
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// interactiveCompletions lists the words offered by Tab completion, keyed by
+// the command they follow. The empty key holds the top-level commands.
+var interactiveCompletions = map[string][]string{
+	"": {
+		"arrow", "background", "capture", "circle", "color", "colors", "copy", "copyname", "crop",
+		"defaults", "delay", "exit", "flatten", "help", "line", "open", "preview", "quit", "record", "rect", "save", "savehome",
+		"savepictures", "savetmp", "screens", "show", "tabs", "upload", "width", "widths", "windows",
+	},
+	"background": {"clean", "list", "run", "start", "stop"},
+	"capture":    {"region", "screen", "window", "workspace"},
+	"color":      {"list"},
+	"record":     {"start", "stop"},
+	"tabs":       {"close", "list", "move", "next", "prev", "rename", "switch"},
+	"width":      {"list"},
+}
+
+func completeInteractive(previous []string, word string) []string {
+	key := ""
+	if len(previous) > 0 {
+		if len(previous) > 1 {
+			return nil
+		}
+		key = strings.ToLower(previous[0])
+	}
+	var matches []string
+	for _, candidate := range interactiveCompletions[key] {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
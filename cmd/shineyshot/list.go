@@ -3,7 +3,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"image"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/example/shineyshot/internal/appstate"
 	"github.com/example/shineyshot/internal/capture"
@@ -11,24 +14,48 @@ import (
 
 type windowsCmd struct {
 	*root
-	fs *flag.FlagSet
+	fs        *flag.FlagSet
+	minimized bool
+	monitor   string
+	noPanels  bool
+	minSize   string
 }
 
 func parseWindowsCmd(args []string, r *root) (*windowsCmd, error) {
 	fs := flag.NewFlagSet("windows", flag.ExitOnError)
 	cmd := &windowsCmd{root: r, fs: fs}
 	fs.Usage = usageFunc(cmd)
+	fs.BoolVar(&cmd.minimized, "minimized", false, "also list minimized and hidden windows")
+	fs.StringVar(&cmd.monitor, "monitor", "", "only list windows on this monitor (index, name or current)")
+	fs.BoolVar(&cmd.noPanels, "no-panels", false, "leave out desktops, docks, menus, tooltips and other non-application windows")
+	fs.StringVar(&cmd.minSize, "min-size", "", "only list windows at least WxH pixels (or N for NxN)")
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
 	if fs.NArg() != 0 {
 		return nil, &UsageError{of: cmd}
 	}
+	if cmd.minSize != "" {
+		if _, err := parseMinSize(cmd.minSize); err != nil {
+			return nil, err
+		}
+	}
 	return cmd, nil
 }
 
 func (c *windowsCmd) Run() error {
-	windows, err := capture.ListWindows()
+	filter := capture.WindowFilter{Monitor: c.monitor}
+	if c.noPanels {
+		filter.ExcludeTypes = capture.PanelWindowTypes
+	}
+	if c.minSize != "" {
+		size, err := parseMinSize(c.minSize)
+		if err != nil {
+			return err
+		}
+		filter.MinWidth, filter.MinHeight = size.X, size.Y
+	}
+	windows, err := capture.ListWindowsFiltered(filter)
 	if err != nil {
 		return err
 	}
@@ -37,17 +64,50 @@ func (c *windowsCmd) Run() error {
 		return nil
 	}
 	fmt.Fprintln(os.Stdout, "available windows (* marks the active window):")
-	for _, win := range windows {
+	shown, hidden := viewableWindows(windows, c.minimized)
+	for _, win := range shown {
 		marker := " "
 		if win.Active {
 			marker = "*"
 		}
 		fmt.Fprintf(os.Stdout, "%s %s\n", marker, formatWindowLabel(win))
 	}
-	fmt.Fprintln(os.Stdout, "selectors: index:<n>, id:<hex>, pid:<pid>, exec:<name>, class:<name>, title:<text>, substring match")
+	if hidden > 0 {
+		fmt.Fprintf(os.Stdout, "%d minimized or hidden windows not shown; pass -minimized to list them\n", hidden)
+	}
+	fmt.Fprintln(os.Stdout, "selectors: index:<n>, id:<hex>, pid:<pid>, exec:<name>, class:<name>, title:<text>, desktop:<n>, substring match")
 	return nil
 }
 
+// parseMinSize reads a WxH size, or N for a square.
+func parseMinSize(val string) (image.Point, error) {
+	w, h, found := strings.Cut(strings.ToLower(strings.TrimSpace(val)), "x")
+	if !found {
+		h = w
+	}
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if errW != nil || errH != nil || width < 0 || height < 0 {
+		return image.Point{}, fmt.Errorf("invalid minimum size %q", val)
+	}
+	return image.Pt(width, height), nil
+}
+
+// viewableWindows drops minimized and hidden windows unless all is set and
+// reports how many it dropped. Kept windows retain their list index.
+func viewableWindows(windows []capture.WindowInfo, all bool) ([]capture.WindowInfo, int) {
+	if all {
+		return windows, 0
+	}
+	shown := make([]capture.WindowInfo, 0, len(windows))
+	for _, win := range windows {
+		if !win.Unviewable() {
+			shown = append(shown, win)
+		}
+	}
+	return shown, len(windows) - len(shown)
+}
+
 func (c *windowsCmd) FlagSet() *flag.FlagSet {
 	return c.fs
 }
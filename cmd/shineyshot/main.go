@@ -5,13 +5,19 @@ import (
 	"flag"
 	"fmt"
 	"image"
+	"image/jpeg"
 	"os"
 	"strings"
 
 	"github.com/example/shineyshot/internal/appstate"
+	"github.com/example/shineyshot/internal/avif"
+	"github.com/example/shineyshot/internal/capture"
 	"github.com/example/shineyshot/internal/config"
+	"github.com/example/shineyshot/internal/fonts"
+	"github.com/example/shineyshot/internal/imagefile"
 	"github.com/example/shineyshot/internal/notify"
 	"github.com/example/shineyshot/internal/theme"
+	"github.com/example/shineyshot/internal/webp"
 )
 
 var (
@@ -29,11 +35,39 @@ type root struct {
 	state         *appstate.AppState
 	notifier      *notify.Notifier
 	config        *config.Config
+	configErr     error // why config failed to load, leaving it the defaults
 	captureAlerts bool
 	saveAlerts    bool
 	copyAlerts    bool
+	uploadAlerts  bool
 	themeName     string
 	activeTheme   *theme.Theme
+	// tabStorageName is the -tab-storage flag and tabStorage the mode
+	// it resolves to with the environment and config.
+	tabStorageName string
+	tabStorage     appstate.TabStorage
+	// webpQualityName is the -webp-quality flag and webp the encoding it
+	// resolves to with the environment and config.
+	webpQualityName string
+	webp            webp.Options
+	// avifQualityName and avifSpeedName are the -avif-quality and
+	// -avif-speed flags and avif the encoding they resolve to.
+	avifQualityName string
+	avifSpeedName   string
+	avif            avif.Options
+	// jpegQualityName is the -jpeg-quality flag and jpeg the encoding it
+	// resolves to.
+	jpegQualityName string
+	jpeg            jpeg.Options
+	// shortcuts are the editor keys the configuration replaces.
+	shortcuts map[string][]appstate.KeyShortcut
+	// pprofAddr is where -pprof serves profiles, if anywhere.
+	pprofAddr string
+	// uiScale is the -ui-scale flag; zero follows the display.
+	uiScale float64
+	// snapDistance is the -snap-distance flag; zero keeps the editor's
+	// default.
+	snapDistance int
 }
 
 func (r *root) Program() string {
@@ -50,11 +84,35 @@ func (r *root) subcommand(name string) *root {
 		captureAlerts: r.captureAlerts,
 		saveAlerts:    r.saveAlerts,
 		copyAlerts:    r.copyAlerts,
+		uploadAlerts:  r.uploadAlerts,
 		themeName:     r.themeName,
 		activeTheme:   r.activeTheme,
+		tabStorage:    r.tabStorage,
+		webp:          r.webp,
+		avif:          r.avif,
+		jpeg:          r.jpeg,
+		shortcuts:     r.shortcuts,
+		uiScale:       r.uiScale,
+		snapDistance:  r.snapDistance,
 	}
 }
 
+// imageOptions returns how an image saved to path is encoded: the format
+// its extension names, with the configured WebP, AVIF and JPEG settings. A nil
+// r uses the defaults.
+func (r *root) imageOptions(path string) imagefile.Options {
+	o := imagefile.Options{
+		Format: imagefile.FormatOf(path),
+		WebP:   webp.Options{Quality: webp.DefaultQuality},
+		AVIF:   avif.Options{Quality: avif.DefaultQuality, Speed: avif.DefaultSpeed},
+		JPEG:   jpeg.Options{Quality: imagefile.DefaultJPEGQuality},
+	}
+	if r != nil {
+		o.WebP, o.AVIF, o.JPEG = r.webp, r.avif, r.jpeg
+	}
+	return o
+}
+
 func (r *root) FlagSet() *flag.FlagSet {
 	return r.fs
 }
@@ -69,18 +127,28 @@ func newRoot() *root {
 	}
 
 	r := &root{
-		fs:       flag.NewFlagSet("shineyshot", flag.ExitOnError),
-		program:  "shineyshot",
-		notifier: notify.New(prefs),
-		config:   cfg,
+		fs:        flag.NewFlagSet("shineyshot", flag.ExitOnError),
+		program:   "shineyshot",
+		notifier:  notify.New(prefs),
+		config:    cfg,
+		configErr: err,
 	}
 	r.fs.BoolVar(&r.captureAlerts, "notify-capture", cfg.Notify.Capture, "show a desktop notification after capturing a screenshot")
 	r.fs.BoolVar(&r.saveAlerts, "notify-save", cfg.Notify.Save, "show a desktop notification after saving an image")
 	r.fs.BoolVar(&r.copyAlerts, "notify-copy", cfg.Notify.Copy, "show a desktop notification after copying to the clipboard")
+	r.fs.BoolVar(&r.uploadAlerts, "notify-upload", cfg.Notify.Upload, "show a desktop notification after uploading an image")
 
 	// Precedence: CLI > Env > Config > Default
 	// We set the default value for the flag to "", and handle fallback logic in Run if it remains empty.
 	r.fs.StringVar(&r.themeName, "theme", "", "color theme to use (default, dark, high_contrast, hotdog)")
+	r.fs.StringVar(&r.tabStorageName, "tab-storage", "", "how the editor keeps inactive tabs: memory, compress or disk")
+	r.fs.StringVar(&r.webpQualityName, "webp-quality", "", "quality of saved .webp files: 0 to 100, or lossless (default 90)")
+	r.fs.StringVar(&r.avifQualityName, "avif-quality", "", "quality of saved .avif files: 0 to 100, where 100 is lossless (default 60)")
+	r.fs.StringVar(&r.avifSpeedName, "avif-speed", "", "AVIF encoder speed: 0 (smallest files) to 10 (fastest) (default 6)")
+	r.fs.StringVar(&r.jpegQualityName, "jpeg-quality", "", "quality of saved .jpg files: 1 to 100 (default 90)")
+	r.fs.StringVar(&r.pprofAddr, "pprof", "", "serve net/http/pprof profiles on this address, such as localhost:6060")
+	r.fs.Float64Var(&r.uiScale, "ui-scale", 0, "scale the editor's toolbar, tabs and status bar, such as 2 on a 4K display; 0 follows the display's resolution")
+	r.fs.IntVar(&r.snapDistance, "snap-distance", 0, "how near, in unscaled pixels, edges dragged in the editor snap to the grid and other items once snapping is on (default 8)")
 	r.fs.Usage = usageFunc(r)
 	return r
 }
@@ -92,10 +160,22 @@ func (r *root) Run(args []string) error {
 	if r.fs.NArg() < 1 {
 		return &UsageError{of: r}
 	}
+	if r.uiScale < 0 || r.uiScale > 8 {
+		return fmt.Errorf("ui-scale must be between 0 and 8")
+	}
+	if r.snapDistance < 0 || r.snapDistance > 100 {
+		return fmt.Errorf("snap-distance must be between 0 and 100")
+	}
+	if r.pprofAddr != "" {
+		if err := startPprof(r.pprofAddr); err != nil {
+			return err
+		}
+	}
 	if r.notifier != nil {
 		r.notifier.Enable(notify.EventCapture, r.captureAlerts)
 		r.notifier.Enable(notify.EventSave, r.saveAlerts)
 		r.notifier.Enable(notify.EventCopy, r.copyAlerts)
+		r.notifier.Enable(notify.EventUpload, r.uploadAlerts)
 	}
 
 	// Load theme if specified via CLI, Env, or Config
@@ -136,6 +216,83 @@ func (r *root) Run(args []string) error {
 	// We can store the theme in `root` and have subcommands use it.
 	r.activeTheme = t
 
+	// Fallback fonts for text the embedded font cannot draw: Env > Config.
+	if env := os.Getenv("SHINEYSHOT_FONTS"); env != "" {
+		fonts.SetFallbacks(fonts.ParseList(env))
+	} else if len(r.config.Fonts) > 0 {
+		fonts.SetFallbacks(r.config.Fonts)
+	}
+
+	if err := r.applyDrawingConfig(); err != nil {
+		return err
+	}
+
+	// How the editor keeps inactive tabs: CLI > Env > Config.
+	storage := r.tabStorageName
+	if storage == "" {
+		storage = os.Getenv("SHINEYSHOT_TAB_STORAGE")
+	}
+	if storage == "" {
+		storage = r.config.TabStorage
+	}
+	mode, storageErr := appstate.ParseTabStorage(storage)
+	if storageErr != nil {
+		return storageErr
+	}
+	r.tabStorage = mode
+
+	// WebP encoding for .webp files: CLI > Env > Config.
+	quality := r.webpQualityName
+	if quality == "" {
+		quality = os.Getenv("SHINEYSHOT_WEBP_QUALITY")
+	}
+	if quality == "" {
+		quality = r.config.WebPQuality
+	}
+	webpOpts, qualityErr := webp.ParseQuality(quality)
+	if qualityErr != nil {
+		return qualityErr
+	}
+	r.webp = webpOpts
+
+	// AVIF encoding for .avif files: CLI > Env > Config.
+	avifQuality, avifSpeed := r.avifQualityName, r.avifSpeedName
+	if avifQuality == "" {
+		avifQuality = os.Getenv("SHINEYSHOT_AVIF_QUALITY")
+	}
+	if avifQuality == "" {
+		avifQuality = r.config.AVIFQuality
+	}
+	if avifSpeed == "" {
+		avifSpeed = os.Getenv("SHINEYSHOT_AVIF_SPEED")
+	}
+	if avifSpeed == "" {
+		avifSpeed = r.config.AVIFSpeed
+	}
+	avifQ, avifErr := avif.ParseQuality(avifQuality)
+	if avifErr != nil {
+		return avifErr
+	}
+	avifS, avifErr := avif.ParseSpeed(avifSpeed)
+	if avifErr != nil {
+		return avifErr
+	}
+	r.avif = avif.Options{Quality: avifQ, Speed: avifS}
+
+	// JPEG encoding for .jpg files: CLI > Env > Config.
+	jpegQuality := r.jpegQualityName
+	if jpegQuality == "" {
+		jpegQuality = os.Getenv("SHINEYSHOT_JPEG_QUALITY")
+	}
+	if jpegQuality == "" {
+		jpegQuality = r.config.JPEGQuality
+	}
+	jpegQ, jpegErr := imagefile.ParseJPEGQuality(jpegQuality)
+	if jpegErr != nil {
+		return jpegErr
+	}
+	r.jpeg = jpeg.Options{Quality: jpegQ}
+
 	cmdName := r.fs.Arg(0)
 	subArgs := r.fs.Args()[1:]
 
@@ -158,8 +315,22 @@ func (r *root) Run(args []string) error {
 		cmd, err = parseInteractiveCmd(subArgs, r)
 	case "background":
 		cmd, err = parseBackgroundCmd(subArgs, r)
+	case "remote":
+		cmd, err = parseRemoteCmd(subArgs, r)
+	case "hotkeys":
+		cmd, err = parseHotkeysCmd(subArgs, r)
 	case "windows":
 		cmd, err = parseWindowsCmd(subArgs, r)
+	case "clipboard":
+		cmd, err = parseClipboardCmd(subArgs, r)
+	case "upload":
+		cmd, err = parseUploadCmd(subArgs, r)
+	case "record":
+		cmd, err = parseRecordCmd(subArgs, r)
+	case "shortcuts":
+		cmd, err = parseShortcutsCmd(subArgs, r)
+	case "notify":
+		cmd, err = parseNotifyCmd(subArgs, r)
 	case "colors":
 		cmd, err = parseColorsCmd(subArgs, r)
 	case "widths":
@@ -195,6 +366,66 @@ func main() {
 	}
 }
 
+// applyDrawingConfig sets the palette, stroke widths and starting stroke
+// from the configuration, and reads its editor shortcuts.
+func (r *root) applyDrawingConfig() error {
+	cfg := r.config
+	var colors []appstate.PaletteColor
+	for _, p := range cfg.Palette {
+		c, err := parseColor(p.Color)
+		if err != nil {
+			return fmt.Errorf("config palette color %s: %w", p.Name, err)
+		}
+		colors = append(colors, appstate.PaletteColor{Name: p.Name, Color: c})
+	}
+	appstate.SetPalette(colors)
+	appstate.SetWidths(cfg.Widths)
+	if cfg.Color != "" {
+		c, err := parseColor(cfg.Color)
+		if err != nil {
+			return fmt.Errorf("config color: %w", err)
+		}
+		appstate.SetDefaultColorIndex(appstate.EnsurePaletteColor(c, ""))
+	}
+	if cfg.Width > 0 {
+		appstate.SetDefaultWidthIndex(appstate.EnsureWidth(cfg.Width))
+	}
+	r.shortcuts = make(map[string][]appstate.KeyShortcut, len(cfg.Shortcuts))
+	// A bad shortcut only warns, so shortcuts set can still fix it.
+	for action, keys := range cfg.Shortcuts {
+		if !appstate.IsShortcutAction(action) {
+			fmt.Fprintf(os.Stderr, "warning: config shortcut %s: unknown editor action; shineyshot shortcuts list shows them\n", action)
+			continue
+		}
+		sc, err := appstate.ParseShortcuts(keys)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: config shortcut %s: %v\n", action, err)
+			continue
+		}
+		r.shortcuts[action] = sc
+	}
+	return nil
+}
+
+// savePattern names automatic saves: the configured pattern, or the
+// default one.
+func (r *root) savePattern() string {
+	if r != nil && r.config != nil && r.config.Pattern != "" {
+		return r.config.Pattern
+	}
+	return defaultSavePattern
+}
+
+// withCaptureSummary appends the capture's size, monitor and scale to
+// detail for notifications.
+func withCaptureSummary(detail string, res capture.CaptureResult) string {
+	summary := res.Summary()
+	if summary == "" {
+		return detail
+	}
+	return fmt.Sprintf("%s (%s)", detail, summary)
+}
+
 func (r *root) notifyCapture(detail string, img image.Image) {
 	if r == nil || r.notifier == nil {
 		return
@@ -202,6 +433,15 @@ func (r *root) notifyCapture(detail string, img image.Image) {
 	r.notifier.Capture(detail, img)
 }
 
+// startProgress shows a progress notification for an operation ending in
+// event; it is nil, and ignores updates, when the event is not enabled.
+func (r *root) startProgress(event notify.Event, body string, cancel func()) *notify.Progress {
+	if r == nil || r.notifier == nil {
+		return nil
+	}
+	return r.notifier.StartProgress(event, body, cancel)
+}
+
 func (r *root) notifySave(path string) {
 	if r == nil || r.notifier == nil {
 		return
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/example/shineyshot/internal/config"
+	"github.com/example/shineyshot/internal/notify"
+)
+
+type notifyCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	action string
+	events []notify.Event
+}
+
+func parseNotifyCmd(args []string, r *root) (*notifyCmd, error) {
+	fs := flag.NewFlagSet("notify", flag.ExitOnError)
+	cmd := &notifyCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.action = strings.ToLower(fs.Arg(0))
+	rest := fs.Args()[1:]
+	switch cmd.action {
+	case "enable", "disable":
+		if len(rest) == 0 {
+			return nil, &UsageError{of: cmd}
+		}
+		for _, name := range rest {
+			event, err := parseNotifyEvent(name)
+			if err != nil {
+				return nil, err
+			}
+			cmd.events = append(cmd.events, event)
+		}
+	case "status", "test":
+		if len(rest) > 0 {
+			return nil, &UsageError{of: cmd}
+		}
+	default:
+		return nil, &UsageError{of: cmd}
+	}
+	return cmd, nil
+}
+
+func parseNotifyEvent(name string) (notify.Event, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, event := range notify.Events {
+		if string(event) == name {
+			return event, nil
+		}
+	}
+	names := make([]string, len(notify.Events))
+	for i, event := range notify.Events {
+		names[i] = string(event)
+	}
+	return "", fmt.Errorf("unknown notification %q; expected one of %s", name, strings.Join(names, ", "))
+}
+
+func (c *notifyCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *notifyCmd) Template() string {
+	return "notify.txt"
+}
+
+func (c *notifyCmd) Run() error {
+	switch c.action {
+	case "enable", "disable":
+		return c.runSet(c.action == "enable")
+	case "status":
+		return c.runStatus()
+	default:
+		if err := c.root.notifier.Test(); err != nil {
+			return fmt.Errorf("send test notification: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "test notification sent")
+		return nil
+	}
+}
+
+// runSet stores the events in the [notify] section of the configuration
+// file, which sets the defaults of the -notify-* flags.
+func (c *notifyCmd) runSet(enabled bool) error {
+	for _, event := range c.events {
+		*notifySetting(&c.root.config.Notify, event) = enabled
+	}
+	path, err := saveConfig(c.root)
+	if err != nil {
+		return err
+	}
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	for _, event := range c.events {
+		fmt.Fprintf(os.Stderr, "%s notifications %s\n", event, state)
+	}
+	fmt.Fprintf(os.Stderr, "Configuration saved to %s\n", path)
+	return nil
+}
+
+func (c *notifyCmd) runStatus() error {
+	for _, event := range notify.Events {
+		state := "off"
+		if *notifySetting(&c.root.config.Notify, event) {
+			state = "on"
+		}
+		fmt.Printf("%-8s %-3s  %s\n", event, state, c.root.notifier.Template(event))
+	}
+	return nil
+}
+
+// notifySetting returns the configuration field that enables event.
+func notifySetting(n *config.Notify, event notify.Event) *bool {
+	switch event {
+	case notify.EventCapture:
+		return &n.Capture
+	case notify.EventSave:
+		return &n.Save
+	case notify.EventCopy:
+		return &n.Copy
+	default:
+		return &n.Upload
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func pidfilePath(dir, name string) string {
+	return filepath.Join(dir, strings.TrimSuffix(name, ".sock")+".pid")
+}
+
+func writePidfile(dir, name string) error {
+	return os.WriteFile(pidfilePath(dir, name), []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600)
+}
+
+// readPidfile returns the pid recorded for a session, or 0 when none exists.
+func readPidfile(dir, name string) (int, error) {
+	data, err := os.ReadFile(pidfilePath(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("pidfile %s: %w", pidfilePath(dir, name), err)
+	}
+	return pid, nil
+}
+
+// processAlive reports whether pid refers to a running process. Platforms
+// without signal 0 support report false.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if proc.Signal(syscall.Signal(0)) != nil {
+		return false
+	}
+	return !processZombie(pid)
+}
+
+// processZombie reports whether pid has exited but not been reaped. A crashed
+// session whose parent never waits on it still accepts signals, so /proc is
+// consulted where available.
+func processZombie(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesised command name, which may itself
+	// contain spaces or parentheses.
+	idx := strings.LastIndexByte(string(data), ')')
+	if idx < 0 || idx+2 >= len(data) {
+		return false
+	}
+	return data[idx+2] == 'Z'
+}
@@ -5,17 +5,17 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
-	"image/png"
-	"os"
 	"path/filepath"
 
 	"github.com/example/shineyshot/internal/appstate"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
 )
 
 type previewCmd struct {
 	file          string
 	fromClipboard bool
+	primary       bool
 	*root
 	fs *flag.FlagSet
 }
@@ -31,9 +31,13 @@ func parsePreviewCmd(args []string, r *root) (*previewCmd, error) {
 	fs.StringVar(&c.file, "file", "", "image file to open")
 	fs.BoolVar(&c.fromClipboard, "from-clipboard", false, "load the input image from the clipboard")
 	fs.BoolVar(&c.fromClipboard, "from-clip", false, "load the input image from the clipboard (alias)")
+	fs.BoolVar(&c.primary, "primary", false, "load from the primary selection instead, as a middle click pastes")
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
+	if c.primary && !c.fromClipboard {
+		return nil, fmt.Errorf("-primary needs -from-clipboard")
+	}
 	if !c.fromClipboard && c.file == "" {
 		return nil, &UsageError{of: c}
 	}
@@ -46,23 +50,15 @@ func (p *previewCmd) Run() error {
 		err error
 	)
 	if p.fromClipboard {
-		src, err = clipboard.ReadImage()
+		src, err = clipboard.ReadImageFrom(pasteSelection(p.primary))
 		if err != nil {
 			return fmt.Errorf("read clipboard image: %w", err)
 		}
 	} else {
-		f, err := os.Open(p.file)
+		src, err = imagefile.Open(p.file)
 		if err != nil {
 			return err
 		}
-		src, err = png.Decode(f)
-		closeErr := f.Close()
-		if err != nil {
-			return err
-		}
-		if closeErr != nil {
-			return closeErr
-		}
 	}
 	rgba := image.NewRGBA(src.Bounds())
 	draw.Draw(rgba, rgba.Bounds(), src, image.Point{}, draw.Src)
@@ -80,6 +76,13 @@ func (p *previewCmd) Run() error {
 		})),
 		appstate.WithVersion(version),
 		appstate.WithTheme(p.root.activeTheme),
+		appstate.WithTabStorage(p.root.tabStorage),
+		appstate.WithShortcuts(p.root.shortcuts),
+		appstate.WithUIScale(p.root.uiScale),
+		appstate.WithSnapDistance(p.root.snapDistance),
+		appstate.WithWebP(p.root.webp),
+		appstate.WithAVIF(p.root.avif),
+		appstate.WithJPEG(p.root.jpeg),
 	)
 	st.Run()
 	return nil
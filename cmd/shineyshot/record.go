@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/shineyshot/internal/appstate"
+	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/hotkeys"
+	"github.com/example/shineyshot/internal/notify"
+	"github.com/example/shineyshot/internal/record"
+)
+
+var streamFn = capture.Stream
+
+// defaultRecordFPS is the frame rate of recordings that do not set one.
+const defaultRecordFPS = 10
+
+type recordCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	target        string
+	selector      string
+	output        string
+	formatName    string
+	format        record.Format
+	fps           int
+	duration      time.Duration
+	delay         time.Duration
+	stopKey       string
+	hotkeyBackend string
+	includeCursor bool
+	noAnnotate    bool
+}
+
+func parseRecordCmd(args []string, r *root) (*recordCmd, error) {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	cmd := &recordCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	defaultOutput := "recording.gif"
+	if r.config != nil && r.config.SaveDir != "" {
+		defaultOutput = filepath.Join(r.config.SaveDir, defaultOutput)
+	}
+	fs.StringVar(&cmd.output, "output", defaultOutput, "write the recording to this file path; {timestamp} is expanded")
+	fs.StringVar(&cmd.formatName, "format", "", "recording format: gif, webp, mp4 or webm; defaults to the -output extension")
+	fs.IntVar(&cmd.fps, "fps", defaultRecordFPS, "frames captured per second")
+	fs.DurationVar(&cmd.duration, "duration", 10*time.Second, "stop recording after this long; 0 records until stopped")
+	fs.DurationVar(&cmd.delay, "delay", 0, "wait this long before recording, e.g. 3s")
+	fs.StringVar(&cmd.stopKey, "stop-key", "Ctrl+Alt+R", "global shortcut that stops the recording; empty disables it")
+	fs.StringVar(&cmd.hotkeyBackend, "hotkey-backend", "auto", "how to register -stop-key: auto, x11 or portal")
+	fs.BoolVar(&cmd.includeCursor, "include-cursor", false, "draw the pointer into the frames when supported")
+	fs.BoolVar(&cmd.noAnnotate, "no-annotate", false, "do not open the last frame in the editor when done")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	operands := fs.Args()
+	// record video is a screen recording to MP4 unless told otherwise.
+	video := len(operands) > 0 && strings.EqualFold(operands[0], "video")
+	if video {
+		operands = operands[1:]
+		if len(operands) == 0 {
+			operands = []string{"screen"}
+		}
+		outputSet := false
+		fs.Visit(func(f *flag.Flag) { outputSet = outputSet || f.Name == "output" })
+		if !outputSet {
+			cmd.output = strings.TrimSuffix(cmd.output, filepath.Ext(cmd.output)) + ".mp4"
+		}
+	}
+	if len(operands) < 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.target = strings.ToLower(strings.TrimSpace(operands[0]))
+	switch cmd.target {
+	case "screen", "window", "region":
+	default:
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.selector = strings.TrimSpace(strings.Join(operands[1:], " "))
+	if cmd.target == "region" && cmd.selector != "" {
+		if _, err := parseRect(cmd.selector); err != nil {
+			return nil, err
+		}
+	}
+	if cmd.fps <= 0 || cmd.fps > 60 {
+		return nil, fmt.Errorf("-fps must be between 1 and 60")
+	}
+	if cmd.duration < 0 {
+		return nil, fmt.Errorf("-duration cannot be negative")
+	}
+	cmd.format = record.FormatOf(cmd.output)
+	if cmd.formatName != "" {
+		f, err := record.ParseFormat(cmd.formatName)
+		if err != nil {
+			return nil, err
+		}
+		cmd.format = f
+	}
+	if video && !cmd.format.IsVideo() {
+		return nil, fmt.Errorf("record video writes mp4 or webm, not %s", cmd.format)
+	}
+	if _, err := hotkeys.ParseBackend(cmd.hotkeyBackend); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cmd.stopKey) != "" {
+		if _, err := hotkeys.ParseAccelerator(cmd.stopKey); err != nil {
+			return nil, fmt.Errorf("-stop-key: %w", err)
+		}
+	}
+	return cmd, nil
+}
+
+func (c *recordCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *recordCmd) Template() string {
+	return "record.txt"
+}
+
+// recordTarget resolves what to record: a screen, window or region and its
+// selector. A region without coordinates is selected the way region
+// captures are, and recorded wherever it was drawn.
+func recordTarget(kind, selector string, includeCursor bool) (capture.StreamTarget, error) {
+	target := capture.StreamTarget{IncludeCursor: includeCursor}
+	switch kind {
+	case "screen":
+		target.Display = selector
+	case "window":
+		target.Window = selector
+		if target.Window == "" {
+			target.Window = "active"
+		}
+	case "region":
+		if selector != "" {
+			rect, err := parseRect(selector)
+			if err != nil {
+				return target, err
+			}
+			target.Region = rect
+			break
+		}
+		res, err := captureRegionFn(capture.CaptureOptions{})
+		if err != nil {
+			return target, fmt.Errorf("select region: %w", err)
+		}
+		if res.Region.Empty() {
+			return target, fmt.Errorf("the selected region's position is unknown; give it as x0,y0,x1,y1")
+		}
+		target.Region = res.Region
+	default:
+		return target, fmt.Errorf("cannot record %q; want screen, window or region", kind)
+	}
+	return target, nil
+}
+
+func (c *recordCmd) Run() error {
+	target, err := recordTarget(c.target, c.selector, c.includeCursor)
+	if err != nil {
+		return err
+	}
+	output := c.output
+	if strings.Contains(output, "{") {
+		output = expandSavePattern(output, time.Now(), nil)
+	}
+	w, err := record.Create(output, c.format, c.fps, &c.root.webp)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if c.delay > 0 {
+		fmt.Fprintf(os.Stderr, "recording in %s\n", c.delay.Round(time.Second))
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if strings.TrimSpace(c.stopKey) != "" {
+		acc, _ := hotkeys.ParseAccelerator(c.stopKey)
+		backend, _ := hotkeys.ParseBackend(c.hotkeyBackend)
+		binding := hotkeys.Binding{ID: "stop", Description: "Stop recording", Accelerator: acc}
+		go func() {
+			err := hotkeys.Listen(ctx, backend, []hotkeys.Binding{binding}, func(string) { cancel() })
+			if err != nil && ctx.Err() == nil {
+				log.Printf("stop key %s unavailable: %v", acc, err)
+			}
+		}()
+	}
+	if c.duration > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, c.duration)
+		defer cancelTimeout()
+	}
+
+	stopHint := "Ctrl+C"
+	if strings.TrimSpace(c.stopKey) != "" {
+		stopHint = c.stopKey + " or " + stopHint
+	}
+	fmt.Fprintf(os.Stderr, "recording %s at %d fps; press %s to stop\n", c.target, c.fps, stopHint)
+	progress := c.root.startProgress(notify.EventSave, fmt.Sprintf("Recording %s", c.target), cancel)
+	recorded := make(chan struct{})
+	go c.showProgress(progress, recorded)
+	length, err := recordFrames(ctx, target, c.fps, w)
+	close(recorded)
+	if err != nil {
+		err = fmt.Errorf("record %s: %w", c.target, err)
+		progress.Fail(err)
+		return err
+	}
+	saved := output
+	if abs, err := filepath.Abs(output); err == nil {
+		saved = abs
+	}
+	fmt.Fprintf(os.Stderr, "saved %s (%d frames, %s)\n", saved, w.Frames(), length.Round(time.Second/10))
+	if progress != nil {
+		progress.Done(saved, w.Last())
+	} else {
+		c.root.notifySave(saved)
+	}
+	if c.noAnnotate {
+		return nil
+	}
+	return c.annotate(w.Last(), strings.TrimSuffix(output, filepath.Ext(output))+".png")
+}
+
+// showProgress updates progress every second with the time recorded, and
+// how much of -duration that is, until recorded is closed.
+func (c *recordCmd) showProgress(progress *notify.Progress, recorded <-chan struct{}) {
+	if progress == nil {
+		return
+	}
+	start := time.Now()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-recorded:
+			return
+		case <-tick.C:
+		}
+		body := fmt.Sprintf("Recording %s", c.target)
+		if c.duration <= 0 {
+			progress.Update(body, notify.ProgressUnknown)
+			continue
+		}
+		elapsed := time.Since(start)
+		percent := int(min(100, 100*elapsed/c.duration))
+		progress.Update(fmt.Sprintf("%s, %s of %s", body, elapsed.Round(time.Second), c.duration), percent)
+	}
+}
+
+// recordFrames streams target into w until ctx ends or the stream fails,
+// then closes w. It returns the time from the first frame to the last.
+func recordFrames(ctx context.Context, target capture.StreamTarget, fps int, w record.Writer) (time.Duration, error) {
+	// Cancelling stops the stream if w fails first.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	frames, err := streamFn(ctx, target, fps)
+	if err != nil {
+		return 0, err
+	}
+	var started, ended time.Time
+	for frame := range frames {
+		if frame.Err != nil {
+			if w.Frames() == 0 {
+				return 0, frame.Err
+			}
+			log.Printf("recording stopped early: %v", frame.Err)
+			break
+		}
+		if started.IsZero() {
+			started = frame.Time
+		}
+		ended = frame.Time
+		if err := w.Add(frame.Image, frame.Time); err != nil {
+			return 0, err
+		}
+	}
+	if w.Frames() == 0 {
+		return 0, fmt.Errorf("no frames captured")
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	return ended.Sub(started), nil
+}
+
+// annotate opens img in the editor, saving to output.
+func (c *recordCmd) annotate(img *image.RGBA, output string) error {
+	st := appstate.New(
+		appstate.WithImage(img),
+		appstate.WithOutput(output),
+		appstate.WithTitle(windowTitle(titleOptions{
+			File: filepath.Base(output),
+			Mode: "Annotate",
+			Tab:  "Tab 1",
+		})),
+		appstate.WithVersion(version),
+		appstate.WithTheme(c.root.activeTheme),
+		appstate.WithTabStorage(c.root.tabStorage),
+		appstate.WithShortcuts(c.root.shortcuts),
+		appstate.WithUIScale(c.root.uiScale),
+		appstate.WithSnapDistance(c.root.snapDistance),
+		appstate.WithWebP(c.root.webp),
+		appstate.WithAVIF(c.root.avif),
+		appstate.WithJPEG(c.root.jpeg),
+	)
+	st.Run()
+	return nil
+}
+
+// activeRecording is a recording running in the background of an
+// interactive session until it is stopped.
+type activeRecording struct {
+	path   string
+	w      record.Writer
+	cancel context.CancelFunc
+	done   chan struct{}
+	// length and err are set once done is closed.
+	length time.Duration
+	err    error
+}
+
+// startRecording streams target into w until the recording is stopped.
+func startRecording(target capture.StreamTarget, path string, w record.Writer) *activeRecording {
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := &activeRecording{path: path, w: w, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(rec.done)
+		rec.length, rec.err = recordFrames(ctx, target, defaultRecordFPS, w)
+	}()
+	return rec
+}
+
+// stop ends the recording and waits for its file to be written.
+func (r *activeRecording) stop() error {
+	r.cancel()
+	<-r.done
+	return r.err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/config"
+	"github.com/example/shineyshot/internal/record"
+)
+
+func TestRecordWritesGIF(t *testing.T) {
+	var got capture.StreamTarget
+	oldStream := streamFn
+	streamFn = func(ctx context.Context, target capture.StreamTarget, fps int) (<-chan capture.Frame, error) {
+		got = target
+		frames := make(chan capture.Frame, 3)
+		start := time.Now()
+		for i := range 3 {
+			img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+			img.Set(i, 0, color.White)
+			frames <- capture.Frame{Image: img, Time: start.Add(time.Duration(i) * time.Second / time.Duration(fps))}
+		}
+		close(frames)
+		return frames, nil
+	}
+	defer func() { streamFn = oldStream }()
+
+	out := filepath.Join(t.TempDir(), "clip.gif")
+	cmd, err := parseRecordCmd([]string{"-output", out, "-stop-key", "", "-no-annotate", "region", "10,20,50,60"}, &root{config: config.New()})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.Region != image.Rect(10, 20, 50, 60) {
+		t.Errorf("streamed region %v, want 10,20,50,60", got.Region)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(g.Image) != 3 {
+		t.Errorf("got %d frames, want 3", len(g.Image))
+	}
+}
+
+func TestParseRecordVideo(t *testing.T) {
+	cmd, err := parseRecordCmd([]string{"video"}, &root{config: config.New()})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cmd.target != "screen" || cmd.output != "recording.mp4" || cmd.format != record.FormatMP4 {
+		t.Errorf("record video = %s to %s as %s, want screen to recording.mp4 as mp4", cmd.target, cmd.output, cmd.format)
+	}
+	cmd, err = parseRecordCmd([]string{"--output", "clip.webm", "--duration", "10s", "video", "window"}, &root{config: config.New()})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cmd.target != "window" || cmd.format != record.FormatWebM {
+		t.Errorf("record video window = %s as %s, want window as webm", cmd.target, cmd.format)
+	}
+}
+
+func TestParseRecordCmdErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"everything"},
+		{"-fps", "0", "screen"},
+		{"-format", "avi", "screen"},
+		{"-format", "gif", "video"},
+		{"-stop-key", "Ctrl+Bogus", "screen"},
+		{"region", "1,2"},
+	} {
+		if _, err := parseRecordCmd(args, &root{config: config.New()}); err == nil {
+			t.Errorf("parseRecordCmd(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestInteractiveRecordStartStop(t *testing.T) {
+	oldStream := streamFn
+	streamFn = func(ctx context.Context, target capture.StreamTarget, fps int) (<-chan capture.Frame, error) {
+		frames := make(chan capture.Frame)
+		go func() {
+			defer close(frames)
+			for i := 0; ; i++ {
+				img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+				img.Set(i%6, 0, color.White)
+				select {
+				case frames <- capture.Frame{Image: img, Time: time.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return frames, nil
+	}
+	defer func() { streamFn = oldStream }()
+
+	session := newInteractiveCmd(nil)
+	var stdout, stderr strings.Builder
+	restore := session.withIO(nil, &stdout, &stderr)
+	defer restore()
+	out := filepath.Join(t.TempDir(), "clip.webp")
+	if _, err := session.executeLine("record start window title:Editor " + out); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	session.executeLine("record start")
+	if !strings.Contains(stderr.String(), "already recording") {
+		t.Errorf("second start: stderr %q, want already recording", stderr.String())
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := session.executeLine("record stop"); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "saved "+out) {
+		t.Errorf("stdout %q, want saved %s", stdout.String(), out)
+	}
+	if st := session.status(); !st.hasImage || st.width != 6 || st.lastSaved != out {
+		t.Errorf("session state %+v, want the last frame loaded and %s saved", st, out)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data[8:12]) != "WEBP" {
+		t.Errorf("recording %s not written as WebP: %v", out, err)
+	}
+	session.executeLine("record stop")
+	if !strings.Contains(stderr.String(), "not recording") {
+		t.Errorf("stderr %q, want not recording", stderr.String())
+	}
+}
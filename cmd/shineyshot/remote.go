@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/notify"
+)
+
+// remoteCacheBinary is where an uploaded binary is kept, relative to the
+// remote user's home directory.
+const remoteCacheBinary = ".cache/shineyshot/shineyshot"
+
+// remoteMissingStatus is the exit status the remote script uses when no
+// shineyshot binary can be found; sh reports unknown commands the same way.
+const remoteMissingStatus = 127
+
+type sshOptions []string
+
+func (o *sshOptions) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *sshOptions) Set(v string) error {
+	*o = append(*o, v)
+	return nil
+}
+
+type remoteCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	ssh                string
+	sshOptions         sshOptions
+	remoteBin          string
+	upload             bool
+	output             string
+	x11Display         string
+	includeDecorations bool
+	includeCursor      bool
+	backend            string
+	units              string
+	delay              time.Duration
+
+	destination string
+	mode        string
+	selector    string
+
+	// ctx is cancelled from the progress notification's Cancel button,
+	// which stops ssh.
+	ctx      context.Context
+	progress *notify.Progress
+}
+
+func (c *remoteCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *remoteCmd) Template() string {
+	return "remote.txt"
+}
+
+func parseRemoteCmd(args []string, r *root) (*remoteCmd, error) {
+	fs := flag.NewFlagSet("remote", flag.ExitOnError)
+	c := &remoteCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(c)
+
+	defaultOutput := "screenshot.png"
+	if r != nil && r.config != nil && r.config.SaveDir != "" {
+		defaultOutput = filepath.Join(r.config.SaveDir, "screenshot.png")
+	}
+	fs.StringVar(&c.ssh, "ssh", "ssh", "ssh client to run")
+	fs.Var(&c.sshOptions, "ssh-option", "extra argument passed to ssh before the destination (repeatable)")
+	fs.StringVar(&c.remoteBin, "remote-bin", "shineyshot", "shineyshot executable on the remote host")
+	fs.BoolVar(&c.upload, "upload", true, "copy this binary to the remote host when it has none")
+	fs.StringVar(&c.output, "output", defaultOutput, "local file for the capture, or - for stdout")
+	fs.StringVar(&c.output, "o", defaultOutput, "local file for the capture (alias)")
+	fs.StringVar(&c.x11Display, "x-display", ":0", "DISPLAY to use on the remote host when the ssh session has none")
+	fs.BoolVar(&c.includeDecorations, "include-decorations", false, "request window decorations when capturing windows")
+	fs.BoolVar(&c.includeCursor, "include-cursor", false, "embed the cursor in captures when supported")
+	fs.StringVar(&c.backend, "backend", "", "screenshot backend on the remote host: auto, wlr, kwin, gnome, portal, x11, external, grim, spectacle, maim, or scrot")
+	fs.DurationVar(&c.delay, "delay", 0, "wait this long on the remote host before capturing")
+	fs.StringVar(&c.units, "units", "", "region coordinate units on the remote host: pixel or logical")
+
+	// Flags may follow the destination and capture operands, as in
+	// "remote host capture screen -o shot.png".
+	var operands []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		operands = append(operands, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if err := capture.CheckBackend(c.backend); err != nil {
+		return nil, err
+	}
+	if err := capture.CheckUnits(c.units); err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(c.x11Display, "\"$`\\") {
+		return nil, fmt.Errorf("invalid -x-display %q", c.x11Display)
+	}
+	if len(operands) < 2 {
+		return nil, &UsageError{of: c}
+	}
+	c.destination = operands[0]
+	operands = operands[1:]
+	if strings.EqualFold(operands[0], "capture") {
+		operands = operands[1:]
+	}
+	if len(operands) == 0 {
+		return nil, &UsageError{of: c}
+	}
+	c.mode = strings.ToLower(operands[0])
+	switch c.mode {
+	case "screen", "window", "region", "workspace":
+	default:
+		return nil, &UsageError{of: c}
+	}
+	c.selector = strings.Join(operands[1:], " ")
+	return c, nil
+}
+
+func (c *remoteCmd) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.ctx = ctx
+	c.progress = c.root.startProgress(notify.EventCapture, fmt.Sprintf("Capturing %s on %s", c.mode, c.destination), cancel)
+	data, err := c.run()
+	if err != nil {
+		c.progress.Fail(err)
+		return err
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		err = fmt.Errorf("remote capture did not return a PNG: %w", err)
+		c.progress.Fail(err)
+		return err
+	}
+
+	if c.output == "-" {
+		if _, err := os.Stdout.Write(data); err != nil {
+			return fmt.Errorf("write PNG to stdout: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "wrote PNG data to stdout")
+		c.progress.Done(fmt.Sprintf("%s on %s", c.mode, c.destination), img)
+		return nil
+	}
+	if o := c.root.imageOptions(c.output); o.Format != imagefile.PNG {
+		var buf bytes.Buffer
+		if err := imagefile.Encode(&buf, img, o); err != nil {
+			err = fmt.Errorf("encode %s: %w", o.Format, err)
+			c.progress.Fail(err)
+			return err
+		}
+		data = buf.Bytes()
+	}
+	if err := os.WriteFile(c.output, data, 0o644); err != nil {
+		err = fmt.Errorf("write image to %q: %w", c.output, err)
+		c.progress.Fail(err)
+		return err
+	}
+	saved := c.output
+	if abs, err := filepath.Abs(c.output); err == nil {
+		saved = abs
+	}
+	fmt.Fprintf(os.Stderr, "saved %s from %s\n", saved, c.destination)
+	if c.progress != nil {
+		c.progress.Done(fmt.Sprintf("%s on %s", c.mode, c.destination), img)
+	} else if c.root != nil {
+		c.root.notifyCapture(fmt.Sprintf("%s on %s", c.mode, c.destination), img)
+	}
+	if c.root != nil {
+		c.root.notifySave(saved)
+	}
+	return nil
+}
+
+// run fetches the capture, uploading this binary first when the remote host
+// has none.
+func (c *remoteCmd) run() ([]byte, error) {
+	data, err := c.fetch()
+	if errors.Is(err, errRemoteMissing) && c.upload {
+		if uerr := c.uploadSelf(); uerr != nil {
+			return nil, fmt.Errorf("shineyshot is not installed on %s and upload failed: %w", c.destination, uerr)
+		}
+		fmt.Fprintf(os.Stderr, "uploaded shineyshot to %s:~/%s\n", c.destination, remoteCacheBinary)
+		data, err = c.fetch()
+	}
+	if errors.Is(err, errRemoteMissing) {
+		return nil, fmt.Errorf("shineyshot is not installed on %s; install it or pass -remote-bin", c.destination)
+	}
+	return data, err
+}
+
+var errRemoteMissing = errors.New("remote shineyshot missing")
+
+// fetch runs the capture on the remote host and returns the PNG it wrote to
+// stdout. Remote diagnostics go straight to the local stderr.
+func (c *remoteCmd) fetch() ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := c.sshCommand(c.captureScript())
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	stop := c.reportElapsed(fmt.Sprintf("Capturing %s on %s", c.mode, c.destination))
+	err := cmd.Run()
+	stop()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == remoteMissingStatus {
+		return nil, errRemoteMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remote capture on %s: %w", c.destination, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// sshCommand runs script under sh on the remote host, whatever the login
+// shell there is.
+func (c *remoteCmd) sshCommand(script string) *exec.Cmd {
+	args := append([]string{"-T"}, c.sshOptions...)
+	args = append(args, c.destination, "sh -c "+shellQuote(script))
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return exec.CommandContext(ctx, c.ssh, args...)
+}
+
+// reportElapsed updates the progress notification with the time spent on
+// step once a second until the returned function is called.
+func (c *remoteCmd) reportElapsed(step string) func() {
+	if c.progress == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			c.progress.Update(step, notify.ProgressUnknown)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// captureScript builds the sh script run on the remote host. Sessions opened
+// over ssh usually lack the desktop environment, so the X display, runtime
+// directory and session bus of the logged-in user are filled in when unset.
+func (c *remoteCmd) captureScript() string {
+	args := []string{"snapshot", "-stdout"}
+	if c.includeDecorations {
+		args = append(args, "-include-decorations")
+	}
+	if c.includeCursor {
+		args = append(args, "-include-cursor")
+	}
+	if c.backend != "" {
+		args = append(args, "-backend", c.backend)
+	}
+	if c.units != "" {
+		args = append(args, "-units", c.units)
+	}
+	if c.delay > 0 {
+		args = append(args, "-delay", c.delay.String())
+	}
+	args = append(args, "capture", c.mode)
+	if c.selector != "" {
+		args = append(args, c.selector)
+	}
+	quoted := make([]string, len(args))
+	for idx, arg := range args {
+		quoted[idx] = shellQuote(arg)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "bin=%s\n", shellQuote(c.remoteBin))
+	b.WriteString("command -v \"$bin\" >/dev/null 2>&1 || bin=\"$HOME/" + remoteCacheBinary + "\"\n")
+	fmt.Fprintf(&b, "[ -x \"$bin\" ] || command -v \"$bin\" >/dev/null 2>&1 || exit %d\n", remoteMissingStatus)
+	fmt.Fprintf(&b, ": \"${DISPLAY:=%s}\"\n", c.x11Display)
+	b.WriteString(": \"${XDG_RUNTIME_DIR:=/run/user/$(id -u)}\"\n")
+	b.WriteString(": \"${DBUS_SESSION_BUS_ADDRESS:=unix:path=$XDG_RUNTIME_DIR/bus}\"\n")
+	b.WriteString("export DISPLAY XDG_RUNTIME_DIR DBUS_SESSION_BUS_ADDRESS\n")
+	b.WriteString("exec \"$bin\" " + strings.Join(quoted, " ") + "\n")
+	return b.String()
+}
+
+// uploadSelf copies the running executable to the remote cache path after
+// checking the remote platform can run it.
+func (c *remoteCmd) uploadSelf() error {
+	var platform bytes.Buffer
+	probe := c.sshCommand("uname -sm")
+	probe.Stdout = &platform
+	probe.Stderr = os.Stderr
+	if err := probe.Run(); err != nil {
+		return fmt.Errorf("detect remote platform: %w", err)
+	}
+	if goos, goarch := remotePlatform(platform.String()); goos != runtime.GOOS || goarch != runtime.GOARCH {
+		return fmt.Errorf("remote host is %s/%s but this binary is %s/%s", goos, goarch, runtime.GOOS, runtime.GOARCH)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	f, err := os.Open(self)
+	if err != nil {
+		return err
+	}
+	defer closeWithLog(self, f)
+	var stdin io.Reader = f
+	if info, err := f.Stat(); err == nil && c.progress != nil && info.Size() > 0 {
+		stdin = &progressReader{r: f, total: info.Size(), report: func(percent int) {
+			c.progress.Update(fmt.Sprintf("Uploading shineyshot to %s", c.destination), percent)
+		}}
+	}
+
+	target := "\"$HOME/" + remoteCacheBinary + "\""
+	script := "mkdir -p \"$HOME/" + filepath.Dir(remoteCacheBinary) + "\" && " +
+		"cat > " + target + ".tmp && chmod 755 " + target + ".tmp && mv " + target + ".tmp " + target
+	cmd := c.sshCommand(script)
+	cmd.Stdin = stdin
+	cmd.Stdout = io.Discard
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("copy binary: %w", err)
+	}
+	return nil
+}
+
+// remotePlatform maps `uname -sm` output to Go's GOOS and GOARCH names.
+func remotePlatform(uname string) (goos, goarch string) {
+	fields := strings.Fields(uname)
+	if len(fields) < 2 {
+		return "unknown", "unknown"
+	}
+	goos = strings.ToLower(fields[0])
+	switch fields[1] {
+	case "x86_64", "amd64":
+		goarch = "amd64"
+	case "aarch64", "arm64":
+		goarch = "arm64"
+	case "i386", "i686":
+		goarch = "386"
+	case "riscv64":
+		goarch = "riscv64"
+	default:
+		goarch = strings.ToLower(fields[1])
+		if strings.HasPrefix(goarch, "armv") {
+			goarch = "arm"
+		}
+	}
+	return goos, goarch
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./,:=+@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// progressReader reports how much of total has been read in steps of five
+// percent, so a fast upload does not flood the notification server.
+type progressReader struct {
+	r      io.Reader
+	total  int64
+	read   int64
+	last   int
+	report func(percent int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if percent := int(p.read*100/p.total) / 5 * 5; percent != p.last {
+		p.last = percent
+		p.report(percent)
+	}
+	return n, err
+}
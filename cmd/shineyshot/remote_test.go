@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFakeSSH installs an ssh stand-in that runs the remote command locally.
+func writeFakeSSH(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "ssh")
+	script := "#!/bin/sh\nfor a; do last=$a; done\nexec sh -c \"$last\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ssh: %v", err)
+	}
+	return path
+}
+
+func TestRemoteCaptureStreamsPNG(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	pngPath := filepath.Join(dir, "remote.png")
+	if err := os.WriteFile(pngPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write png: %v", err)
+	}
+	argsPath := filepath.Join(dir, "args")
+	bin := filepath.Join(dir, "fake shineyshot")
+	binScript := "#!/bin/sh\nprintf '%s\\n' \"$@\" > '" + argsPath + "'\ncat '" + pngPath + "'\n"
+	if err := os.WriteFile(bin, []byte(binScript), 0o755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+
+	out := filepath.Join(dir, "local.png")
+	cmd, err := parseRemoteCmd([]string{
+		"-ssh", writeFakeSSH(t, dir), "-remote-bin", bin,
+		"lab1", "capture", "window", "it's", "firefox", "-o", out,
+	}, &root{})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("open output: %v", err)
+	}
+	defer closeWithLog(out, f)
+	cfg, err := png.DecodeConfig(f)
+	if err != nil || cfg.Width != 3 || cfg.Height != 2 {
+		t.Fatalf("unexpected output %+v: %v", cfg, err)
+	}
+	args, err := os.ReadFile(argsPath)
+	if err != nil {
+		t.Fatalf("read args: %v", err)
+	}
+	want := "snapshot\n-stdout\ncapture\nwindow\nit's firefox\n"
+	if string(args) != want {
+		t.Fatalf("remote args = %q, want %q", args, want)
+	}
+}
+
+func TestRemoteCaptureReportsMissingBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	cmd, err := parseRemoteCmd([]string{
+		"-ssh", writeFakeSSH(t, dir), "-remote-bin", "shineyshot-not-installed", "-upload=false",
+		"lab1", "screen",
+	}, &root{})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	err = cmd.Run()
+	if err == nil || !strings.Contains(err.Error(), "not installed on lab1") {
+		t.Fatalf("expected missing binary error, got %v", err)
+	}
+}
+
+func TestRemotePlatform(t *testing.T) {
+	cases := map[string][2]string{
+		"Linux x86_64\n": {"linux", "amd64"},
+		"Linux aarch64":  {"linux", "arm64"},
+		"FreeBSD amd64":  {"freebsd", "amd64"},
+		"Linux armv7l":   {"linux", "arm"},
+		"Darwin":         {"unknown", "unknown"},
+	}
+	for in, want := range cases {
+		goos, goarch := remotePlatform(in)
+		if goos != want[0] || goarch != want[1] {
+			t.Errorf("remotePlatform(%q) = %s/%s, want %s/%s", in, goos, goarch, want[0], want[1])
+		}
+	}
+}
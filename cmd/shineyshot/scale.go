@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/example/shineyshot/internal/render"
+)
+
+// scaleFlags holds the -scale and -max-width flags that shrink an image as
+// it is saved.
+type scaleFlags struct {
+	scale    string
+	maxWidth int
+}
+
+// options resolves the flags.
+func (f scaleFlags) options() (render.ScaleOptions, error) {
+	var opts render.ScaleOptions
+	if f.scale != "" {
+		pct, err := render.ParseScalePercent(f.scale)
+		if err != nil {
+			return opts, err
+		}
+		opts.Percent = pct
+	}
+	if f.maxWidth < 0 {
+		return opts, fmt.Errorf("max-width cannot be negative")
+	}
+	opts.MaxWidth = f.maxWidth
+	return opts, nil
+}
+
+// splitScaleArgs takes --scale and --max-width, with one or two dashes and
+// their value either joined by = or following, out of args wherever they
+// appear, as the interactive save commands accept them after the file name.
+func splitScaleArgs(args []string) ([]string, render.ScaleOptions, error) {
+	var rest []string
+	var f scaleFlags
+	for i := 0; i < len(args); i++ {
+		name, value, joined := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "scale" && name != "max-width") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !joined {
+			if i+1 >= len(args) {
+				return nil, render.ScaleOptions{}, fmt.Errorf("--%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "scale" {
+			f.scale = value
+			continue
+		}
+		w, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, render.ScaleOptions{}, fmt.Errorf("invalid max-width %q", value)
+		}
+		f.maxWidth = w
+	}
+	opts, err := f.options()
+	return rest, opts, err
+}
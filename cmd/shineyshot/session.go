@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/shineyshot/internal/project"
+)
+
+// sessionName names the pidfile of the annotate editor keeping the session.
+const sessionName = "annotate"
+
+// sessionDir is where annotate keeps its open tabs for -restore, and the
+// pidfile that tells a crashed editor from a closed one.
+func sessionDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shineyshot", "session"), nil
+}
+
+// sessionPath is the project file holding the last session's tabs.
+func sessionPath(dir string) string {
+	return filepath.Join(dir, "last"+project.Ext)
+}
+
+// openSession reads the last session's tabs.
+func openSession(dir string) (*project.Project, error) {
+	p, err := project.Open(sessionPath(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no previous session to restore")
+	}
+	return p, err
+}
+
+// claimSession records this process as the editor keeping the session in
+// dir.
+func claimSession(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return writePidfile(dir, sessionName)
+}
+
+// sessionOutcome is what annotate finds of the last session on starting.
+type sessionOutcome int
+
+const (
+	// sessionClosed means no editor holds the session, which this one takes.
+	sessionClosed sessionOutcome = iota
+	// sessionBusy means another editor is still running and keeps it.
+	sessionBusy
+	// sessionCrashed means the last editor never closed and left its tabs.
+	sessionCrashed
+)
+
+// checkSession reports what became of the editor that last kept the session
+// in dir.
+func checkSession(dir string) sessionOutcome {
+	pid, err := readPidfile(dir, sessionName)
+	if err != nil || pid == 0 {
+		return sessionClosed
+	}
+	if processAlive(pid) {
+		return sessionBusy
+	}
+	if _, err := os.Stat(sessionPath(dir)); err != nil {
+		return sessionClosed
+	}
+	return sessionCrashed
+}
+
+// askRestore asks on in whether to restore a crashed session, defaulting to
+// yes.
+func askRestore(in io.Reader, out io.Writer) bool {
+	fmt.Fprint(out, "The last annotate session did not close cleanly. Restore its tabs instead? [Y/n] ")
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "", "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/example/shineyshot/internal/appstate"
+	"github.com/example/shineyshot/internal/capture"
+	"github.com/example/shineyshot/internal/config"
+)
+
+const defaultSavePattern = "shineyshot-{timestamp}.png"
+
+// sessionDefaults holds the settings a background session starts with. They
+// are stored beside the socket so every client of the session, and any
+// restart of it, sees the same behavior.
+type sessionDefaults struct {
+	OutDir  string `json:"outdir,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	// Sound and Flash confirm captures with a shutter sound and a flash.
+	Sound bool `json:"sound,omitempty"`
+	Flash bool `json:"flash,omitempty"`
+
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+	MaxImageMB  int           `json:"max_image_mb,omitempty"`
+	DBus        bool          `json:"dbus,omitempty"`
+}
+
+func (d sessionDefaults) empty() bool {
+	return d == sessionDefaults{}
+}
+
+// withProfile fills unset fields from the named config profile.
+func (d sessionDefaults) withProfile(cfg *config.Config) (sessionDefaults, error) {
+	if d.Profile == "" {
+		return d, nil
+	}
+	var profile *config.Profile
+	if cfg != nil {
+		profile = cfg.Profiles[d.Profile]
+	}
+	if profile == nil {
+		return d, fmt.Errorf("unknown profile %q", d.Profile)
+	}
+	if d.OutDir == "" {
+		d.OutDir = profile.SaveDir
+	}
+	if d.Pattern == "" {
+		d.Pattern = profile.Pattern
+	}
+	if d.Color == "" {
+		d.Color = profile.Color
+	}
+	if d.Width == 0 {
+		d.Width = profile.Width
+	}
+	d.Sound = d.Sound || profile.Sound
+	d.Flash = d.Flash || profile.Flash
+	return d, nil
+}
+
+func sessionStatePath(dir, name string) string {
+	return filepath.Join(dir, strings.TrimSuffix(name, ".sock")+".json")
+}
+
+func loadSessionDefaults(dir, name string) (sessionDefaults, error) {
+	var d sessionDefaults
+	data, err := os.ReadFile(sessionStatePath(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return d, nil
+		}
+		return d, err
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, fmt.Errorf("session state %s: %w", sessionStatePath(dir, name), err)
+	}
+	return d, nil
+}
+
+func saveSessionDefaults(dir, name string, d sessionDefaults) error {
+	path := sessionStatePath(dir, name)
+	if d.empty() {
+		removeWithLog(path)
+		return nil
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// expandSavePattern substitutes the {timestamp}, {date} and {time}
+// placeholders in pattern and appends .png when no extension is present.
+// When res is set, {window}, {app}, {monitor}, {width} and {height} take
+// the capture's window title and class, monitor name and image size; they
+// are empty otherwise.
+func expandSavePattern(pattern string, now time.Time, res *capture.CaptureResult) string {
+	if pattern == "" {
+		pattern = defaultSavePattern
+	}
+	var window, app, monitor, width, height string
+	if res != nil {
+		if res.Window != nil {
+			window = sanitizeFilenamePart(res.Window.Title)
+			app = sanitizeFilenamePart(res.Window.Class)
+		}
+		if res.Monitor != nil {
+			monitor = sanitizeFilenamePart(res.Monitor.Name)
+		}
+		if res.Image != nil {
+			width = strconv.Itoa(res.Image.Bounds().Dx())
+			height = strconv.Itoa(res.Image.Bounds().Dy())
+		}
+	}
+	name := strings.NewReplacer(
+		"{timestamp}", now.Format("20060102-150405"),
+		"{date}", now.Format("20060102"),
+		"{time}", now.Format("150405"),
+		"{window}", window,
+		"{app}", app,
+		"{monitor}", monitor,
+		"{width}", width,
+		"{height}", height,
+	).Replace(pattern)
+	if filepath.Ext(name) == "" {
+		name += ".png"
+	}
+	return name
+}
+
+// uniquePath returns path, or when a file already exists there the first
+// free name with a -01, -02, ... suffix before the extension.
+func uniquePath(path string) (string, error) {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for counter := 1; ; counter++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		} else if err != nil {
+			return "", err
+		}
+		path = fmt.Sprintf("%s-%02d%s", stem, counter, ext)
+	}
+}
+
+// sanitizeFilenamePart makes s safe to use inside a file name by replacing
+// path separators and control characters.
+func sanitizeFilenamePart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':':
+			return '_'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// applySessionDefaults configures the session from d without printing the
+// color and width listings the interactive commands show.
+func (i *interactiveCmd) applySessionDefaults(d sessionDefaults) error {
+	if d.OutDir != "" {
+		dir, err := expandUserPath(d.OutDir)
+		if err != nil {
+			return err
+		}
+		d.OutDir = dir
+	}
+	if d.Color != "" {
+		idx, err := i.resolveColor(d.Color)
+		if err != nil {
+			return err
+		}
+		i.mu.Lock()
+		i.colorIdx = clampIndex(idx, len(i.palette))
+		i.mu.Unlock()
+	}
+	if d.MaxImageMB < 0 {
+		return fmt.Errorf("invalid image memory limit %dMB", d.MaxImageMB)
+	}
+	if d.Width != 0 {
+		if d.Width < 0 {
+			return fmt.Errorf("invalid width %d", d.Width)
+		}
+		idx := appstate.EnsureWidth(d.Width)
+		i.refreshWidths()
+		i.mu.Lock()
+		i.widthIdx = clampIndex(idx, len(i.widths))
+		i.mu.Unlock()
+	}
+	i.mu.Lock()
+	i.defaults = d
+	i.maxImageBytes = int64(d.MaxImageMB) << 20
+	i.mu.Unlock()
+	return nil
+}
+
+func (i *interactiveCmd) currentDefaults() sessionDefaults {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.defaults
+}
+
+func (i *interactiveCmd) handleDefaults() {
+	d := i.currentDefaults()
+	if d.empty() {
+		i.writeln(i.stdout, "no session defaults set")
+		return
+	}
+	if d.Profile != "" {
+		i.writef(i.stdout, "profile: %s\n", d.Profile)
+	}
+	if d.OutDir != "" {
+		i.writef(i.stdout, "outdir:  %s\n", d.OutDir)
+	}
+	if d.Pattern != "" {
+		i.writef(i.stdout, "pattern: %s\n", d.Pattern)
+	}
+	if d.Color != "" {
+		i.writef(i.stdout, "color:   %s\n", d.Color)
+	}
+	if d.Width != 0 {
+		i.writef(i.stdout, "width:   %dpx\n", d.Width)
+	}
+	if d.Sound {
+		i.writeln(i.stdout, "sound:   shutter after each capture")
+	}
+	if d.Flash {
+		i.writeln(i.stdout, "flash:   captured area after each capture")
+	}
+	if d.IdleTimeout > 0 {
+		i.writef(i.stdout, "idle:    %s\n", d.IdleTimeout)
+	}
+	if d.MaxImageMB > 0 {
+		i.writef(i.stdout, "memory:  %dMB per image\n", d.MaxImageMB)
+	}
+	if d.DBus {
+		i.writef(i.stdout, "dbus:    %s\n", dbusServiceName)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckSession(t *testing.T) {
+	dir := t.TempDir()
+	if got := checkSession(dir); got != sessionClosed {
+		t.Fatalf("without a pidfile got %v, want closed", got)
+	}
+	if err := writePidfile(dir, sessionName); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkSession(dir); got != sessionBusy {
+		t.Fatalf("with a running editor got %v, want busy", got)
+	}
+
+	// A process that has exited and been waited on stands in for a
+	// crashed editor.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pidfilePath(dir, sessionName), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkSession(dir); got != sessionClosed {
+		t.Fatalf("crashed with no tabs saved got %v, want closed", got)
+	}
+	if err := os.WriteFile(sessionPath(dir), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkSession(dir); got != sessionCrashed {
+		t.Fatalf("crashed with tabs saved got %v, want crashed", got)
+	}
+
+	var out strings.Builder
+	if !askRestore(strings.NewReader("\n"), &out) || askRestore(strings.NewReader("n\n"), &out) {
+		t.Fatal("restore should default to yes and accept no")
+	}
+	if _, err := parseAnnotateCmd([]string{"-restore", "open", "shot.png"}, &root{}); err == nil {
+		t.Fatal("-restore combined with open was accepted")
+	}
+}
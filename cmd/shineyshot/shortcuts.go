@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/example/shineyshot/internal/appstate"
+)
+
+type shortcutsCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	action string
+	// name and keys are the editor action and its new keys for set and
+	// reset.
+	name string
+	keys string
+}
+
+func parseShortcutsCmd(args []string, r *root) (*shortcutsCmd, error) {
+	fs := flag.NewFlagSet("shortcuts", flag.ExitOnError)
+	cmd := &shortcutsCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() < 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.action = strings.ToLower(fs.Arg(0))
+	rest := fs.Args()[1:]
+	switch cmd.action {
+	case "list":
+		if len(rest) > 0 {
+			return nil, &UsageError{of: cmd}
+		}
+	case "set", "reset":
+		if len(rest) < 1 || cmd.action == "reset" && len(rest) > 1 {
+			return nil, &UsageError{of: cmd}
+		}
+		cmd.name = strings.ToLower(rest[0])
+		if !appstate.IsShortcutAction(cmd.name) {
+			return nil, fmt.Errorf("unknown editor action %q; shineyshot shortcuts list shows them", rest[0])
+		}
+		// Keys may be given as separate arguments or one comma list;
+		// none leaves the action without a key.
+		cmd.keys = strings.Join(rest[1:], ", ")
+		if strings.EqualFold(cmd.keys, "none") {
+			cmd.keys = ""
+		}
+		if _, err := appstate.ParseShortcuts(cmd.keys); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &UsageError{of: cmd}
+	}
+	return cmd, nil
+}
+
+func (c *shortcutsCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *shortcutsCmd) Template() string {
+	return "shortcuts.txt"
+}
+
+func (c *shortcutsCmd) Run() error {
+	if c.action == "list" {
+		for _, sc := range appstate.ResolveShortcuts(c.root.shortcuts) {
+			keys := appstate.FormatShortcuts(sc.Keys)
+			if keys == "" {
+				keys = "-"
+			}
+			marker := " "
+			if _, ok := c.root.shortcuts[sc.Action]; ok {
+				marker = "*"
+			}
+			fmt.Printf("%s %-14s %-22s %s\n", marker, sc.Action, keys, sc.Description)
+		}
+		return nil
+	}
+	if c.action == "set" {
+		c.root.config.Shortcuts[c.name] = c.keys
+	} else {
+		delete(c.root.config.Shortcuts, c.name)
+	}
+	path, err := saveConfig(c.root)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.action == "reset":
+		fmt.Fprintf(os.Stderr, "%s uses its built-in keys\n", c.name)
+	case c.keys == "":
+		fmt.Fprintf(os.Stderr, "%s has no key\n", c.name)
+	default:
+		fmt.Fprintf(os.Stderr, "%s bound to %s\n", c.name, c.keys)
+	}
+	fmt.Fprintf(os.Stderr, "Configuration saved to %s\n", path)
+	return nil
+}
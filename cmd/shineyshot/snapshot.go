@@ -1,27 +1,33 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"image"
-	"image/png"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/example/shineyshot/internal/capture"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/notify"
 	"github.com/example/shineyshot/internal/render"
 )
 
 type snapshotCmd struct {
 	output             string
+	formatName         string
+	format             imagefile.Format
 	stdout             bool
 	toClipboard        bool
+	primary            bool
 	mode               string
 	display            string
 	window             string
@@ -30,11 +36,27 @@ type snapshotCmd struct {
 	rect               string
 	includeDecorations bool
 	includeCursor      bool
+	backend            string
+	units              string
+	delay              time.Duration
+	sound              bool
+	flash              bool
+	includeOwnWindows  bool
+	pick               bool
+	all                bool
+	sameAsLast         bool
+	pngMetadata        bool
 	shadow             bool
 	shadowRadius       int
 	shadowOffset       string
 	shadowPoint        image.Point
 	shadowOpacity      float64
+	scale              scaleFlags
+	scaleOpts          render.ScaleOptions
+	// ctx and progress follow a delayed capture: the countdown is shown
+	// as a progress notification whose Cancel button cancels ctx.
+	ctx      context.Context
+	progress *notify.Progress
 	*root
 	fs *flag.FlagSet
 }
@@ -50,26 +72,43 @@ func parseSnapshotCmd(args []string, r *root) (*snapshotCmd, error) {
 	defaults := render.DefaultShadowOptions()
 
 	defaultOutput := "screenshot.png"
+	if r.config != nil && r.config.Pattern != "" {
+		defaultOutput = r.config.Pattern
+	}
 	if r.config != nil && r.config.SaveDir != "" {
-		defaultOutput = filepath.Join(r.config.SaveDir, "screenshot.png")
+		defaultOutput = filepath.Join(r.config.SaveDir, defaultOutput)
 	}
 
-	fs.StringVar(&s.output, "output", defaultOutput, "write the capture to this file path")
-	fs.StringVar(&s.mode, "mode", "", "capture mode: screen, window, or region")
+	fs.StringVar(&s.output, "output", defaultOutput, "write the capture to this file path; {timestamp}, {window}, {app}, {monitor}, {width} and {height} are expanded")
+	fs.StringVar(&s.mode, "mode", "", "capture mode: screen, window, region, or workspace")
 	fs.StringVar(&s.display, "display", "", "target display selector for screen captures")
+	fs.BoolVar(&s.all, "all", false, "capture every monitor as one image, as the screen selector all does")
 	fs.StringVar(&s.window, "window", "", "target window selector for window captures")
+	fs.BoolVar(&s.pick, "pick", false, "click the window to capture with a crosshair pointer")
+	fs.BoolVar(&s.sameAsLast, "same-as-last", false, "repeat the previous snapshot's capture of the same monitor, region, window or workspace")
 	fs.StringVar(&s.region, "region", "", "capture rectangle x0,y0,x1,y1 when targeting a region")
-	fs.BoolVar(&s.stdout, "stdout", false, "write PNG data to stdout")
+	fs.BoolVar(&s.stdout, "stdout", false, "write the image data to stdout")
+	fs.StringVar(&s.formatName, "format", "", "image format: png, webp, avif, pdf, jpeg, bmp or tiff; defaults to the -output extension, or png with -stdout")
 	fs.BoolVar(&s.toClipboard, "to-clipboard", false, "copy the capture to the clipboard")
 	fs.BoolVar(&s.toClipboard, "to-clip", false, "copy the capture to the clipboard (alias)")
+	fs.BoolVar(&s.primary, "primary", false, "also copy to the primary selection, pasted with a middle click")
 	fs.StringVar(&s.selector, "select", "", "selector for screen or window capture")
 	fs.StringVar(&s.rect, "rect", "", "capture rectangle x0,y0,x1,y1 when targeting a region")
 	fs.BoolVar(&s.includeDecorations, "include-decorations", false, "request window decorations when capturing windows")
 	fs.BoolVar(&s.includeCursor, "include-cursor", false, "embed the cursor in captures when supported")
+	fs.BoolVar(&s.includeOwnWindows, "include-own-windows", false, "leave shineyshot's own windows visible in captures")
+	fs.StringVar(&s.backend, "backend", capture.BackendAuto, "screenshot backend: auto, wlr, kwin, gnome, portal, x11, external, grim, spectacle, maim, or scrot")
+	fs.StringVar(&s.units, "units", capture.UnitsPixel, "region coordinate units: pixel, or logical to scale by the monitor's HiDPI factor")
+	fs.DurationVar(&s.delay, "delay", 0, "wait this long before capturing, e.g. 3s")
+	fs.BoolVar(&s.sound, "sound", false, "play a shutter sound once the capture is taken")
+	fs.BoolVar(&s.flash, "flash", false, "briefly flash the captured area once the capture is taken")
+	fs.BoolVar(&s.pngMetadata, "png-metadata", false, "store the capture time, window and monitor as PNG text chunks")
 	fs.BoolVar(&s.shadow, "shadow", false, "apply a drop shadow to the captured image")
 	fs.IntVar(&s.shadowRadius, "shadow-radius", defaults.Radius, "drop shadow blur radius in pixels")
 	fs.StringVar(&s.shadowOffset, "shadow-offset", formatShadowOffset(defaults.Offset), "drop shadow offset as dx,dy")
 	fs.Float64Var(&s.shadowOpacity, "shadow-opacity", defaults.Opacity, "drop shadow opacity between 0 and 1")
+	fs.StringVar(&s.scale.scale, "scale", "", "scale the saved image by a percentage, such as 50%")
+	fs.IntVar(&s.scale.maxWidth, "max-width", 0, "shrink the saved image to at most this many pixels wide")
 	if err := fs.Parse(args); err != nil {
 		return nil, err
 	}
@@ -77,14 +116,44 @@ func parseSnapshotCmd(args []string, r *root) (*snapshotCmd, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.scaleOpts, err = s.scale.options(); err != nil {
+		return nil, err
+	}
 	s.shadowPoint = pt
+	if s.formatName != "" {
+		if s.format, err = imagefile.ParseFormat(s.formatName); err != nil {
+			return nil, err
+		}
+	}
+	if err := capture.CheckBackend(s.backend); err != nil {
+		return nil, err
+	}
+	if err := capture.CheckUnits(s.units); err != nil {
+		return nil, err
+	}
 	if s.toClipboard && s.stdout {
 		return nil, fmt.Errorf("-stdout cannot be used with -to-clipboard")
 	}
+	if s.primary && !s.toClipboard {
+		return nil, fmt.Errorf("-primary needs -to-clipboard")
+	}
 	operands := fs.Args()
 	if len(operands) > 0 && strings.EqualFold(operands[0], "capture") {
 		operands = operands[1:]
 	}
+	if s.sameAsLast {
+		if strings.TrimSpace(s.mode) != "" || len(operands) > 0 || s.pick {
+			return nil, fmt.Errorf("-same-as-last cannot be combined with a capture mode")
+		}
+		s.mode = "last"
+		return s, nil
+	}
+	if strings.TrimSpace(s.mode) == "" && len(operands) == 0 && s.pick {
+		s.mode = "window"
+	}
+	if strings.TrimSpace(s.mode) == "" && len(operands) == 0 && s.all {
+		s.mode = "screen"
+	}
 	if strings.TrimSpace(s.mode) == "" {
 		if len(operands) == 0 {
 			return nil, &UsageError{of: s}
@@ -95,10 +164,28 @@ func parseSnapshotCmd(args []string, r *root) (*snapshotCmd, error) {
 		s.mode = strings.ToLower(strings.TrimSpace(s.mode))
 	}
 	switch s.mode {
-	case "screen", "window", "region":
+	case "screen", "window", "region", "workspace":
 	default:
 		return nil, &UsageError{of: s}
 	}
+	if s.all {
+		if s.mode != "screen" {
+			return nil, fmt.Errorf("-all only applies to screen captures")
+		}
+		if len(operands) > 0 || s.display != "" || s.selector != "" {
+			return nil, fmt.Errorf("-all cannot be combined with a screen selector")
+		}
+		s.display = capture.AllMonitorsSelector
+	}
+	if s.pick {
+		if s.mode != "window" {
+			return nil, fmt.Errorf("-pick only applies to window captures")
+		}
+		if len(operands) > 0 || s.window != "" || s.selector != "" {
+			return nil, fmt.Errorf("-pick cannot be combined with a window selector")
+		}
+		s.window = capture.PickWindowSelector
+	}
 	if len(operands) > 0 {
 		arg := strings.TrimSpace(strings.Join(operands, " "))
 		switch s.mode {
@@ -114,26 +201,45 @@ func parseSnapshotCmd(args []string, r *root) (*snapshotCmd, error) {
 			if s.region == "" && s.rect == "" {
 				s.region = arg
 			}
+		case "workspace":
+			if s.selector == "" {
+				s.selector = arg
+			}
 		}
 	}
 	return s, nil
 }
 
 func (s *snapshotCmd) Run() error {
-	img, err := s.capture()
+	if s.delay > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.ctx = ctx
+		s.progress = s.root.startProgress(notify.EventCapture, fmt.Sprintf("Capturing %s", s.describeCapture()), cancel)
+	}
+	res, err := s.capture()
 	if err != nil {
+		s.progress.Fail(err)
 		return fmt.Errorf("failed to capture %s: %w", s.mode, err)
 	}
+	if err := saveLastCapture(res.Token); err != nil {
+		log.Printf("remember capture for -same-as-last: %v", err)
+	}
+	defer captureFeedback(res, s.sound, s.flash)()
+	img := res.Image
 	if s.shadow {
 		res := render.ApplyShadow(img, s.shadowOptions())
 		img = res.Image
 	}
-	if s.root != nil {
-		detail := s.describeCapture()
+	if s.progress != nil {
+		s.progress.Done(withCaptureSummary(s.describeCapture(), res), img)
+	} else if s.root != nil {
+		detail := withCaptureSummary(s.describeCapture(), res)
 		s.root.notifyCapture(detail, img)
 	}
+	img = render.Scale(img, s.scaleOpts)
 	if s.toClipboard {
-		if err := clipboard.WriteImage(img); err != nil {
+		if err := clipboard.WriteContent(clipboard.Content{Image: img}, copySelections(s.primary)...); err != nil {
 			return fmt.Errorf("copy PNG to clipboard: %w", err)
 		}
 		detail := s.describeCapture()
@@ -146,6 +252,16 @@ func (s *snapshotCmd) Run() error {
 		}
 		return nil
 	}
+	if strings.Contains(s.output, "{") {
+		s.output = expandSavePattern(s.output, res.Time, &res)
+	}
+	o := s.root.imageOptions(s.output)
+	if s.formatName != "" || s.stdout {
+		o.Format = s.format
+	}
+	if s.pngMetadata {
+		o.Text = res.Text()
+	}
 	var w io.Writer
 	if s.stdout {
 		w = os.Stdout
@@ -161,14 +277,14 @@ func (s *snapshotCmd) Run() error {
 		}()
 		w = f
 	}
-	if err := png.Encode(w, img); err != nil {
+	if err := imagefile.Encode(w, img, o); err != nil {
 		if s.stdout {
-			return fmt.Errorf("write PNG to stdout: %w", err)
+			return fmt.Errorf("write %s to stdout: %w", o.Format, err)
 		}
-		return fmt.Errorf("write PNG to %q: %w", s.output, err)
+		return fmt.Errorf("write %s to %q: %w", o.Format, s.output, err)
 	}
 	if s.stdout {
-		fmt.Fprintln(os.Stderr, "wrote PNG data to stdout")
+		fmt.Fprintf(os.Stderr, "wrote %s data to stdout\n", o.Format)
 		return nil
 	}
 	saved := s.output
@@ -182,7 +298,7 @@ func (s *snapshotCmd) Run() error {
 	return nil
 }
 
-func (s *snapshotCmd) capture() (*image.RGBA, error) {
+func (s *snapshotCmd) capture() (capture.CaptureResult, error) {
 	opts := s.captureOptions()
 	switch s.mode {
 	case "screen":
@@ -198,11 +314,23 @@ func (s *snapshotCmd) capture() (*image.RGBA, error) {
 		}
 		rect, err := parseRect(region)
 		if err != nil {
-			return nil, err
+			return capture.CaptureResult{}, err
 		}
 		return captureRegionRectFn(rect, opts)
+	case "workspace":
+		desktop, err := strconv.Atoi(strings.TrimSpace(s.selector))
+		if err != nil || desktop < 0 {
+			return capture.CaptureResult{}, fmt.Errorf("workspace capture needs a desktop number, got %q", s.selector)
+		}
+		return captureWorkspaceFn(desktop, opts)
+	case "last":
+		token, err := readLastCapture()
+		if err != nil {
+			return capture.CaptureResult{}, err
+		}
+		return recaptureFn(token, opts)
 	default:
-		return nil, errors.New("unsupported capture mode")
+		return capture.CaptureResult{}, errors.New("unsupported capture mode")
 	}
 }
 
@@ -224,10 +352,17 @@ func (s *snapshotCmd) describeCapture() string {
 		if region != "" {
 			return fmt.Sprintf("region %s", region)
 		}
+	case "workspace":
+		if target := strings.TrimSpace(s.selector); target != "" {
+			return fmt.Sprintf("workspace %s", target)
+		}
 	}
 	if mode == "" {
 		return "capture"
 	}
+	if mode == "last" {
+		return "previous target"
+	}
 	return mode
 }
 
@@ -235,6 +370,25 @@ func (s *snapshotCmd) captureOptions() capture.CaptureOptions {
 	return capture.CaptureOptions{
 		IncludeDecorations: s.includeDecorations,
 		IncludeCursor:      s.includeCursor,
+		Backend:            s.backend,
+		Units:              s.units,
+		Delay:              s.delay,
+		Progress:           s.countdown(),
+		IncludeOwnWindows:  s.includeOwnWindows,
+		Context:            s.ctx,
+	}
+}
+
+// countdown prints the delay each second and mirrors it in the progress
+// notification, filling its bar as the capture approaches.
+func (s *snapshotCmd) countdown() func(time.Duration) {
+	report := countdown(os.Stderr)
+	return func(left time.Duration) {
+		report(left)
+		if left > 0 {
+			percent := int(100 * (s.delay - left) / s.delay)
+			s.progress.Update(fmt.Sprintf("Capturing in %s", left.Round(time.Second)), percent)
+		}
 	}
 }
 
@@ -272,6 +426,15 @@ func parseShadowOffset(val string) (image.Point, error) {
 	return image.Pt(vals[0], vals[1]), nil
 }
 
+// countdown reports the time left before a delayed capture on w.
+func countdown(w io.Writer) func(time.Duration) {
+	return func(left time.Duration) {
+		if left > 0 {
+			fmt.Fprintf(w, "capturing in %s\n", left.Round(time.Second))
+		}
+	}
+}
+
 func formatShadowOffset(pt image.Point) string {
 	return fmt.Sprintf("%d,%d", pt.X, pt.Y)
 }
@@ -0,0 +1,60 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminal switches a tty between its original mode and the raw mode used by
+// the line editor.
+type terminal struct {
+	fd    uintptr
+	saved syscall.Termios
+	raw   bool
+}
+
+func openTerminal(f *os.File) (*terminal, error) {
+	t := &terminal{fd: f.Fd()}
+	if err := ioctlTermios(t.fd, syscall.TCGETS, &t.saved); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *terminal) makeRaw() error {
+	if t.raw {
+		return nil
+	}
+	raw := t.saved
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(t.fd, syscall.TCSETS, &raw); err != nil {
+		return err
+	}
+	t.raw = true
+	return nil
+}
+
+func (t *terminal) restore() error {
+	if !t.raw {
+		return nil
+	}
+	t.raw = false
+	return ioctlTermios(t.fd, syscall.TCSETS, &t.saved)
+}
+
+func ioctlTermios(fd, req uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(state)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// terminal is unavailable on this platform; prompts fall back to plain line
+// reading.
+type terminal struct{}
+
+func openTerminal(*os.File) (*terminal, error) {
+	return nil, errors.New("line editing not supported on this platform")
+}
+
+func (t *terminal) makeRaw() error { return nil }
+
+func (t *terminal) restore() error { return nil }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/config"
+	"github.com/example/shineyshot/internal/notify"
+	"github.com/example/shineyshot/internal/upload"
+)
+
+type uploadCmd struct {
+	*root
+	fs *flag.FlagSet
+
+	path    string
+	to      string
+	noCopy  bool
+	primary bool
+	// dest overrides fields of the named destination.
+	dest    upload.Destination
+	headers headerList
+}
+
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+func parseUploadCmd(args []string, r *root) (*uploadCmd, error) {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	cmd := &uploadCmd{root: r, fs: fs}
+	fs.Usage = usageFunc(cmd)
+	fs.StringVar(&cmd.to, "to", "", "upload destination: an [upload.NAME] section of the configuration")
+	fs.StringVar(&cmd.dest.Kind, "type", "", "destination type: http, imgur or s3")
+	fs.StringVar(&cmd.dest.URL, "url", "", "endpoint, or for s3 the presigned PUT URL; {name} expands to the file name")
+	fs.StringVar(&cmd.dest.Method, "method", "", "HTTP method (default POST, PUT for s3)")
+	fs.StringVar(&cmd.dest.Field, "field", "", "multipart form field for the image; without it the image is the request body")
+	fs.Var(&cmd.headers, "header", "extra request header as 'Name: value'; may be repeated")
+	fs.StringVar(&cmd.dest.Response, "response", "", "dotted path of the link in a JSON response, such as data.link; without it the body is the link")
+	fs.StringVar(&cmd.dest.ClientID, "client-id", "", "imgur application client id")
+	fs.StringVar(&cmd.dest.PublicURL, "public-url", "", "link to return for s3 uploads; {name} expands to the file name")
+	fs.BoolVar(&cmd.noCopy, "no-copy", false, "do not copy the link to the clipboard")
+	fs.BoolVar(&cmd.primary, "primary", false, "also copy the link to the primary selection")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.path = fs.Arg(0)
+	// Flags may also follow the file.
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, &UsageError{of: cmd}
+	}
+	cmd.dest.Headers = cmd.headers
+	return cmd, nil
+}
+
+func (c *uploadCmd) FlagSet() *flag.FlagSet {
+	return c.fs
+}
+
+func (c *uploadCmd) Template() string {
+	return "upload.txt"
+}
+
+func (c *uploadCmd) Run() error {
+	dest, err := c.root.uploadDestination(c.to)
+	// Flags alone may describe the destination when none is configured.
+	if err != nil && (!errors.Is(err, errNoUploadDestination) || c.dest.URL == "" && c.dest.Kind == "") {
+		return err
+	}
+	dest = overrideDestination(dest, c.dest)
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+	link, err := c.root.upload(dest, uploadImage(filepath.Base(c.path), data), !c.noCopy, c.primary, os.Stderr)
+	if err != nil {
+		return err
+	}
+	fmt.Println(link)
+	return nil
+}
+
+// errNoUploadDestination reports that no destination was named or
+// configured.
+var errNoUploadDestination = errors.New("no upload destination: add an [upload.NAME] section to the configuration, set upload = NAME, or pass -to or -url")
+
+// uploadDestination returns the configured destination called name, or with
+// no name the SHINEYSHOT_UPLOAD one, then the configured default, then the
+// only one configured.
+func (r *root) uploadDestination(name string) (upload.Destination, error) {
+	var uploads map[string]*config.Upload
+	var def string
+	if r != nil && r.config != nil {
+		uploads, def = r.config.Uploads, r.config.DefaultUpload
+	}
+	if name == "" {
+		name = os.Getenv("SHINEYSHOT_UPLOAD")
+	}
+	if name == "" {
+		name = def
+	}
+	if name == "" && len(uploads) == 1 {
+		for only := range uploads {
+			name = only
+		}
+	}
+	if name == "" {
+		return upload.Destination{}, errNoUploadDestination
+	}
+	u, ok := uploads[name]
+	if !ok {
+		var names []string
+		for n := range uploads {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return upload.Destination{}, fmt.Errorf("unknown upload destination %q: none are configured", name)
+		}
+		return upload.Destination{}, fmt.Errorf("unknown upload destination %q (have %s)", name, strings.Join(names, ", "))
+	}
+	return upload.Destination{
+		Kind:      u.Type,
+		URL:       u.URL,
+		Method:    u.Method,
+		Field:     u.Field,
+		Headers:   u.Headers,
+		Response:  u.Response,
+		ClientID:  u.ClientID,
+		PublicURL: u.PublicURL,
+	}, nil
+}
+
+// overrideDestination replaces the fields of d that o sets.
+func overrideDestination(d, o upload.Destination) upload.Destination {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&d.Kind, o.Kind)
+	set(&d.URL, o.URL)
+	set(&d.Method, o.Method)
+	set(&d.Field, o.Field)
+	set(&d.Response, o.Response)
+	set(&d.ClientID, o.ClientID)
+	set(&d.PublicURL, o.PublicURL)
+	d.Headers = append(append([]string(nil), d.Headers...), o.Headers...)
+	return d
+}
+
+// uploadImage wraps encoded image data for upload, typed by name's
+// extension or else by its content.
+func uploadImage(name string, data []byte) upload.Image {
+	ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if ct == "" {
+		ct = http.DetectContentType(data)
+	}
+	return upload.Image{Name: name, ContentType: ct, Data: data}
+}
+
+// upload sends img to dest, copies the link to the clipboard when asked and
+// sends an upload notification. Progress is reported to log, and in a
+// progress notification whose Cancel button abandons the upload.
+func (r *root) upload(dest upload.Destination, img upload.Image, copyLink, primary bool, log io.Writer) (string, error) {
+	fmt.Fprintf(log, "uploading %s (%d bytes)\n", img.Name, len(img.Data))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progress := r.startProgress(notify.EventUpload, fmt.Sprintf("Uploading %s", img.Name), cancel)
+	link, err := upload.Upload(ctx, nil, dest, img)
+	if err != nil {
+		progress.Fail(err)
+		return "", err
+	}
+	if copyLink {
+		if err := clipboard.WriteText(link, copySelections(primary)...); err != nil {
+			fmt.Fprintf(log, "warning: copy link: %v\n", err)
+		} else {
+			fmt.Fprintln(log, "link copied to clipboard")
+		}
+	}
+	if progress != nil {
+		progress.Done(link, nil)
+	} else {
+		r.notifyUpload(link)
+	}
+	return link, nil
+}
+
+func (r *root) notifyUpload(link string) {
+	if r == nil || r.notifier == nil {
+		return
+	}
+	r.notifier.Upload(link)
+}
@@ -3,17 +3,18 @@ package appstate
 import (
 	"context"
 	"fmt"
+	"github.com/example/shineyshot/internal/capture"
 	xdraw "golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/font/gofont/goregular"
-	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 	"image"
 	"image/color"
 	"image/draw"
 	"log"
 	"math"
+	"runtime"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -21,6 +22,8 @@ import (
 	"github.com/arran4/spacemap"
 	"github.com/arran4/spacemap/simplearray"
 	"github.com/example/shineyshot/assets"
+	"github.com/example/shineyshot/internal/fonts"
+	"github.com/example/shineyshot/internal/render"
 	"github.com/example/shineyshot/internal/theme"
 	"golang.org/x/exp/shiny/screen"
 	"golang.org/x/mobile/event/key"
@@ -46,7 +49,7 @@ func CalculateToolbarWidth(versionLabel string) int {
 			max = w
 		}
 	}
-	toolLabels := []string{"Move(M)", "Crop(R)", "Draw(B)", "Circle(O)", "Line(L)", "Arrow(A)", "Rect(X)", "Num(H)", "Text(T)", "Shadow($)"}
+	toolLabels := []string{"Move(M)", "Crop(R)", "Draw(B)", "Circle(O)", "Line(L)", "Arrow(A)", "Rect(X)", "Num(H)", "Marker(G)", "Text(T)", "Shadow($)"}
 	for _, lbl := range toolLabels {
 		w := d.MeasureString(lbl).Ceil() + 8
 		if w > max {
@@ -81,6 +84,11 @@ const (
 	ToolNumber
 	ToolText
 	ToolShadow
+	ToolHighlight
+	ToolRoundRect
+	ToolPolygon
+	ToolCallout
+	ToolSelect
 )
 
 // Mode controls the available interactions in the UI.
@@ -93,7 +101,9 @@ const (
 	ModePreview
 )
 
-const (
+// defaultColorIndex and defaultWidthIndex are the stroke new editors start
+// with; the configuration may change them.
+var (
 	defaultColorIndex = 2
 	defaultWidthIndex = 2
 )
@@ -107,7 +117,40 @@ type Tab struct {
 	NextNumber    int
 	WidthIdx      int
 	ShadowApplied bool
-}
+	// Capture is the metadata of the capture the tab started from, nil for
+	// opened or pasted images.
+	Capture *capture.CaptureResult
+	// SavedPath is the file the tab was last saved to, offered alongside the
+	// image when it is copied.
+	SavedPath string
+
+	// generation counts in-place edits of Image, so its cached scaled copy
+	// can tell when it is out of date.
+	generation uint64
+	// history holds the tab's undo and redo steps.
+	history *editHistory
+	// items are the texts and callouts placed on the tab, drawn over its
+	// image bottom first.
+	items []tabItem
+	// numbers are the numbered markers placed on the tab, in order.
+	numbers []placedNumber
+	// lastItemID is the id given to the latest text or callout placed, and
+	// selected the ids of those the Select tool has picked.
+	lastItemID uint64
+	selected   []uint64
+	// locked are the ids of the items the layers panel keeps from being
+	// picked.
+	locked []uint64
+	// While the tab is inactive a tabStore may release Image, keeping its
+	// bounds and deflated pixels in packed or in the file spill.
+	bounds     image.Rectangle
+	packed     []byte
+	spill      string
+	packedSize int
+}
+
+// markEdited records that the tab's image changed in place.
+func (t *Tab) markEdited() { t.generation++ }
 
 // TabSummary provides identifying information for an open annotation tab.
 type TabSummary struct {
@@ -126,6 +169,7 @@ type TabChange struct {
 	TabsState
 	Image    *image.RGBA
 	WidthIdx int
+	Capture  *capture.CaptureResult
 }
 
 const handleSize = 8
@@ -152,6 +196,8 @@ const (
 	actionMove
 	actionCrop
 	actionDraw
+	// actionSelect drags the Select tool's selection or rubber band.
+	actionSelect
 )
 
 type UIType int
@@ -164,6 +210,35 @@ const (
 	UITypeNumber
 	UITypeTextSize
 	UITypeShortcut
+	// UITypeHistory is a clipboard history row, or with Index -1 the rest
+	// of the history popup.
+	UITypeHistory
+	// UITypeOpacity is a highlighter opacity row.
+	UITypeOpacity
+	// UITypeFill is a fill row of the tools drawing closed shapes.
+	UITypeFill
+	// UITypeRadius is a RoundRect tool corner radius row.
+	UITypeRadius
+	// UITypeStroke is a Draw tool toggle row, indexing freehandToggles.
+	UITypeStroke
+	// UITypeLink is a Num tool connector row, indexing numberLinks.
+	UITypeLink
+	// UITypeAspect is a Crop tool aspect ratio row, indexing cropAspects.
+	UITypeAspect
+	// UITypeStyle is a line style row, indexing lineStyleNames.
+	UITypeStyle
+	// UITypeAlign is a Select tool alignment row, indexing alignLabels.
+	UITypeAlign
+	// UITypeLayer is a layers panel row, or with Index -1 the rest of the
+	// panel.
+	UITypeLayer
+	// UITypeLayerToggle is a layers panel row's visibility or lock box.
+	UITypeLayerToggle
+	// UITypeTabClose is a tab's close box.
+	UITypeTabClose
+	// UITypeTabScroll is one of the tab bar's scroll buttons: 0 scrolls
+	// left and 1 right.
+	UITypeTabScroll
 )
 
 type UIShape struct {
@@ -230,33 +305,62 @@ var (
 )
 
 var textSizes = []float64{12, 16, 20, 24, 32}
-var textFaces []font.Face
 var textSizeIdx int
-var messageFace font.Face
-var goregularFont *opentype.Font
 
-func init() {
-	var err error
-	goregularFont, err = opentype.Parse(goregular.TTF)
-	if err != nil {
-		log.Fatalf("parse font: %v", err)
-	}
-	for _, sz := range textSizes {
-		face, err := opentype.NewFace(goregularFont, &opentype.FaceOptions{Size: sz, DPI: 72, Hinting: font.HintingFull})
+var (
+	faceOnce          sync.Once
+	loadedTextFaces   []font.Face
+	loadedMessageFace font.Face
+)
+
+// loadFaces builds the text and message faces on first use rather than at
+// start up, so commands that never draw text do not parse the font.
+func loadFaces() {
+	faceOnce.Do(func() {
+		for _, sz := range textSizes {
+			face, err := fonts.NewFace(sz)
+			if err != nil {
+				log.Fatalf("font face: %v", err)
+			}
+			loadedTextFaces = append(loadedTextFaces, face)
+		}
+		var err error
+		loadedMessageFace, err = fonts.NewFace(48)
 		if err != nil {
 			log.Fatalf("font face: %v", err)
 		}
-		textFaces = append(textFaces, face)
-	}
-	messageFace, err = opentype.NewFace(goregularFont, &opentype.FaceOptions{Size: 48, DPI: 72, Hinting: font.HintingFull})
-	if err != nil {
-		log.Fatalf("font face: %v", err)
-	}
+	})
+}
+
+// textFaces returns the faces of textSizes.
+func textFaces() []font.Face {
+	loadFaces()
+	return loadedTextFaces
+}
+
+// messageFace returns the face toasts are drawn with.
+func messageFace() font.Face {
+	loadFaces()
+	return loadedMessageFace
+}
+
+// capturingPlaceholder returns the image a window shows while its startup
+// capture runs: transparent, so the backdrop shows, with "capturing…" in the
+// middle.
+func capturingPlaceholder(size image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	face := textFaces()[len(textSizes)-1]
+	const label = "capturing…"
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(color.RGBA{0x60, 0x60, 0x60, 0xff}), Face: face}
+	m := face.Metrics()
+	d.Dot = fixed.P((size.X-d.MeasureString(label).Ceil())/2, (size.Y+m.Ascent.Ceil()-m.Descent.Ceil())/2)
+	fonts.Draw(d, label)
+	return img
 }
 
 func fitZoom(img *image.RGBA, winW, winH int) float64 {
-	availW := winW - toolbarWidth
-	availH := winH - tabHeight - bottomHeight
+	availW := winW - ui(toolbarWidth)
+	availH := winH - ui(tabHeight+bottomHeight)
 	zx := float64(availW) / float64(img.Bounds().Dx())
 	zy := float64(availH) / float64(img.Bounds().Dy())
 	if zx < zy {
@@ -284,33 +388,160 @@ func toolbarIconImage() image.Image {
 func imageRect(img *image.RGBA, winW, winH int, zoom float64) image.Rectangle {
 	w := int(float64(img.Bounds().Dx()) * zoom)
 	h := int(float64(img.Bounds().Dy()) * zoom)
-	x0 := toolbarWidth
-	y0 := tabHeight
+	x0 := ui(toolbarWidth)
+	y0 := ui(tabHeight)
 	return image.Rect(x0, y0, x0+w, y0+h)
 }
 
+// imageScreenRect returns where the tab's image is drawn in a window of the
+// given size, allowing for its zoom and offset.
+func imageScreenRect(t Tab, winW, winH int) image.Rectangle {
+	base := imageRect(t.Image, winW, winH, t.Zoom)
+	return base.Add(image.Pt(int(float64(t.Offset.X)*t.Zoom), int(float64(t.Offset.Y)*t.Zoom)))
+}
+
+// toScreen maps r, in the coordinates of an image drawn at dst with the
+// given zoom, to window coordinates.
+func toScreen(r, dst image.Rectangle, zoom float64) image.Rectangle {
+	return image.Rect(
+		dst.Min.X+int(float64(r.Min.X)*zoom),
+		dst.Min.Y+int(float64(r.Min.Y)*zoom),
+		dst.Min.X+int(float64(r.Max.X)*zoom),
+		dst.Min.Y+int(float64(r.Max.Y)*zoom),
+	)
+}
+
+// imageDamage returns the window area a change to r of an image drawn at
+// dst repaints, widened for pixels that zooming spreads or rounds over.
+func imageDamage(r, dst image.Rectangle, zoom float64) image.Rectangle {
+	return toScreen(r, dst, zoom).Inset(-int(math.Ceil(zoom)) - 1)
+}
+
+// cropSelection returns the crop rectangle shown while the crop tool is
+// active, in image coordinates.
+func cropSelection(cropping bool, start image.Point, rect image.Rectangle) image.Rectangle {
+	if cropping {
+		return image.Rect(start.X, start.Y, start.X, start.Y).Union(rect)
+	}
+	return rect
+}
+
+// cropDamage returns the window area the crop overlay for sel covers,
+// including its outline, resize handles and size label.
+func cropDamage(sel, dst image.Rectangle, zoom float64) image.Rectangle {
+	r := toScreen(sel, dst, zoom)
+	_, _, label := cropLabel(sel, r)
+	return r.Inset(-handleSize/2 - 2).Union(label)
+}
+
+// selectDamage returns the window area of the outlines drawn round the
+// selected areas and the rubber band.
+func selectDamage(selected []image.Rectangle, band, dst image.Rectangle, zoom float64) image.Rectangle {
+	var r image.Rectangle
+	for _, sel := range append(selected, band) {
+		if !sel.Empty() {
+			r = r.Union(toScreen(sel, dst, zoom).Inset(-4))
+		}
+	}
+	return r
+}
+
+// toolbarDamage returns the window area of the toolbar down the left edge,
+// which is all that changing a tool setting redraws. The thickest width
+// samples reach a few pixels past its edge.
+func toolbarDamage(height int) image.Rectangle {
+	return image.Rect(0, 0, ui(toolbarWidth+8), height)
+}
+
+// textOrigin returns the window position of the baseline the text typed at
+// pos in the image starts from.
+func textOrigin(pos image.Point, dst image.Rectangle, zoom float64) image.Point {
+	return image.Pt(dst.Min.X+int(float64(pos.X)*zoom), dst.Min.Y+int(float64(pos.Y)*zoom))
+}
+
+// textDamage returns the window area the text being typed at pos covers
+// with its cursor. It allows a line's height of slack on the right for
+// glyphs drawn from fallback fonts.
+func textDamage(text string, pos image.Point, dst image.Rectangle, zoom float64) image.Rectangle {
+	face := textFaces()[textSizeIdx]
+	m := face.Metrics()
+	o := textOrigin(pos, dst, zoom)
+	w := font.MeasureString(face, text+"|").Ceil()
+	return image.Rect(o.X, o.Y-m.Ascent.Ceil(), o.X+w+m.Height.Ceil(), o.Y+m.Descent.Ceil()).Inset(-2)
+}
+
+// calloutDamage returns the window area the open callout c covers with
+// its outline and handles.
+func calloutDamage(c callout, dst image.Rectangle, zoom float64) image.Rectangle {
+	box := toScreen(c.box, dst, zoom)
+	tip := toScreen(image.Rectangle{Min: c.tip, Max: c.tip}, dst, zoom).Min
+	return imageDamage(c.bounds(), dst, zoom).Union(box.Inset(-handleSize/2 - 2)).Union(calloutTipHandle(tip).Inset(-1))
+}
+
+// polygonDamage returns the window area the vertices and edges of the
+// polygon being placed cover.
+func polygonDamage(pts []image.Point, dst image.Rectangle, zoom float64) image.Rectangle {
+	var r image.Rectangle
+	for _, p := range pts {
+		sp := toScreen(image.Rectangle{Min: p, Max: p}, dst, zoom).Min
+		r = r.Union(image.Rect(sp.X-3, sp.Y-3, sp.X+4, sp.Y+4))
+	}
+	return r
+}
+
+// toastRect returns the area, in UI pixels, of the message box showing msg
+// in the middle of a window width by height UI pixels.
+func toastRect(msg string, width, height int) image.Rectangle {
+	if msg == "" {
+		return image.Rectangle{}
+	}
+	wmsg := font.MeasureString(messageFace(), msg).Ceil()
+	ascent := messageFace().Metrics().Ascent.Ceil()
+	descent := messageFace().Metrics().Descent.Ceil()
+	px := (width - wmsg) / 2
+	py := (height-ascent-descent)/2 + ascent
+	return image.Rect(px-8, py-ascent-8, px+wmsg+8, py+descent+8)
+}
+
 // drawCheckerboard fills rect of dst with a checkerboard pattern of the given
 // colors. size controls the checker square size.
 func drawCheckerboard(dst *image.RGBA, rect image.Rectangle, size int, light, dark color.Color) {
+	rect = rect.Intersect(dst.Rect)
+	if rect.Empty() {
+		return
+	}
+	cols := [2]color.RGBA{rgbaOf(light), rgbaOf(dark)}
 	for y := rect.Min.Y; y < rect.Max.Y; y++ {
-		for x := rect.Min.X; x < rect.Max.X; x++ {
-			if ((x/size)+(y/size))%2 == 0 {
-				dst.Set(x, y, light)
-			} else {
-				dst.Set(x, y, dark)
-			}
+		// Rows in the same band of squares are identical, so copy the
+		// previous one.
+		if y > rect.Min.Y && y/size == (y-1)/size {
+			row := dst.PixOffset(rect.Min.X, y)
+			copy(dst.Pix[row:row+rect.Dx()*4], dst.Pix[row-dst.Stride:])
+			continue
+		}
+		for x := rect.Min.X; x < rect.Max.X; {
+			end := min((x/size+1)*size, rect.Max.X)
+			fillSpan(dst, x, end, y, cols[((x/size)+(y/size))%2])
+			x = end
 		}
 	}
 }
 
-// drawBackdrop fills dst with a cached checkerboard pattern.
-func drawBackdrop(dst *image.RGBA, t *theme.Theme) {
-	b := dst.Bounds()
-	if backdropCache == nil || backdropCache.Bounds() != b {
-		backdropCache = image.NewRGBA(b)
+// drawBackdrop fills dst, part of a window of the given size, with a
+// checkerboard pattern cached at the window size.
+func drawBackdrop(dst *image.RGBA, size image.Point, t *theme.Theme) {
+	full := image.Rectangle{Max: size}
+	colors := [2]color.RGBA{t.CheckerLight, t.CheckerDark}
+	if backdropCache == nil || backdropCache.Bounds() != full || backdropColors != colors {
+		backdropCache = image.NewRGBA(full)
+		render.Bands(backdropCache, full, func(band *image.RGBA) {
+			drawCheckerboard(band, band.Bounds(), 8, colors[0], colors[1])
+		})
+		backdropColors = colors
 	}
-	drawCheckerboard(backdropCache, backdropCache.Bounds(), 8, t.CheckerLight, t.CheckerDark)
-	draw.Draw(dst, b, backdropCache, image.Point{}, draw.Src)
+	render.Bands(dst, dst.Bounds(), func(band *image.RGBA) {
+		draw.Draw(band, band.Bounds(), backdropCache, band.Bounds().Min, draw.Src)
+	})
 }
 
 var (
@@ -365,6 +596,46 @@ func EnsurePaletteColor(col color.RGBA, name string) int {
 	return len(palette) - 1
 }
 
+// SetPalette replaces the drawing colors, keeping the built-in ones when
+// colors is empty.
+func SetPalette(colors []PaletteColor) {
+	if len(colors) == 0 {
+		return
+	}
+	paletteMu.Lock()
+	defer paletteMu.Unlock()
+	palette = palette[:0:0]
+	paletteNames = paletteNames[:0:0]
+	for _, c := range colors {
+		palette = append(palette, c.Color)
+		paletteNames = append(paletteNames, c.Name)
+	}
+}
+
+// SetWidths replaces the stroke widths, keeping the built-in ones when ws
+// has none of at least one pixel.
+func SetWidths(ws []int) {
+	var out []int
+	for _, w := range ws {
+		if w >= 1 && !slices.Contains(out, w) {
+			out = append(out, w)
+		}
+	}
+	if len(out) == 0 {
+		return
+	}
+	sort.Ints(out)
+	widthsMu.Lock()
+	defer widthsMu.Unlock()
+	widths = out
+}
+
+// SetDefaultColorIndex sets the palette index new editors draw with.
+func SetDefaultColorIndex(idx int) { defaultColorIndex = clampColorIndex(idx) }
+
+// SetDefaultWidthIndex sets the stroke width index new editors draw with.
+func SetDefaultWidthIndex(idx int) { defaultWidthIndex = clampWidthIndex(idx) }
+
 // WidthOptions returns a copy of the available stroke widths.
 func WidthOptions() []int {
 	widthsMu.RLock()
@@ -649,8 +920,10 @@ func actionOfTool(t Tool) actionType {
 		return actionMove
 	case ToolCrop:
 		return actionCrop
-	case ToolDraw, ToolCircle, ToolLine, ToolArrow, ToolRect, ToolNumber:
+	case ToolDraw, ToolCircle, ToolLine, ToolArrow, ToolRect, ToolNumber, ToolHighlight, ToolRoundRect, ToolPolygon:
 		return actionDraw
+	case ToolSelect:
+		return actionSelect
 	default:
 		return actionNone
 	}
@@ -664,9 +937,82 @@ var toolButtons []*CacheButton
 var paletteRects []image.Rectangle
 var widthRects []image.Rectangle
 var numberRects []image.Rectangle
+var opacityRects []image.Rectangle
+var fillRects []image.Rectangle
+var radiusRects []image.Rectangle
+var strokeRects []image.Rectangle
+var linkRects []image.Rectangle
+var aspectRects []image.Rectangle
+var styleRects []image.Rectangle
+var alignRects []image.Rectangle
+
+// scaledCache holds the current tab's image scaled to its zoom, so frames
+// that only pan or redraw the UI copy it instead of rescaling. It is keyed
+// by the image's generation and scaled size, and zoomed out sizes are
+// scaled from the nearest mip level.
+var scaledCache struct {
+	src        *image.RGBA
+	bounds     image.Rectangle
+	generation uint64
+	img        *image.RGBA
+}
+
+// maxScaledPixels caps the scaled cache. Zooming in further scales straight
+// into the frame, which only touches the visible part of the image.
+const maxScaledPixels = 16 << 20
+
+// scaledTabImage returns t's image scaled to size, or nil if the image is
+// too large to cache or the cache is stale and refresh is false.
+func scaledTabImage(t Tab, size image.Point, refresh bool) *image.RGBA {
+	if size.X <= 0 || size.Y <= 0 || size.X*size.Y > maxScaledPixels {
+		scaledCache.src, scaledCache.img = nil, nil
+		return nil
+	}
+	c := &scaledCache
+	if c.src == t.Image && c.bounds == t.Image.Bounds() && c.generation == t.generation && c.img.Bounds().Size() == size {
+		return c.img
+	}
+	if !refresh {
+		return nil
+	}
+	if c.img == nil || c.img.Bounds().Size() != size {
+		c.img = image.NewRGBA(image.Rectangle{Max: size})
+	}
+	src := mipSource(t, size)
+	render.Bands(c.img, c.img.Bounds(), func(band *image.RGBA) {
+		xdraw.NearestNeighbor.Scale(band, c.img.Bounds(), src, src.Bounds(), draw.Src, nil)
+	})
+	c.src, c.bounds, c.generation = t.Image, t.Image.Bounds(), t.generation
+	return c.img
+}
+
+// drawTabItems draws t's markers and items over its image, drawn at dst
+// with the given zoom, within the part of b being painted.
+func drawTabItems(b *image.RGBA, t *Tab, dst image.Rectangle, zoom float64) {
+	seen := b.Bounds().Intersect(dst)
+	if seen.Empty() {
+		return
+	}
+	// The painted area in image coordinates, rounded out.
+	shown := image.Rect(
+		int(math.Floor(float64(seen.Min.X-dst.Min.X)/zoom)),
+		int(math.Floor(float64(seen.Min.Y-dst.Min.Y)/zoom)),
+		int(math.Ceil(float64(seen.Max.X-dst.Min.X)/zoom)),
+		int(math.Ceil(float64(seen.Max.Y-dst.Min.Y)/zoom)),
+	)
+	r := t.itemsBounds().Intersect(t.Image.Rect).Intersect(shown)
+	if r.Empty() {
+		return
+	}
+	layer := image.NewRGBA(r)
+	t.drawItems(layer)
+	xdraw.NearestNeighbor.Scale(b, toScreen(r, dst, zoom), layer, r, draw.Over, nil)
+}
 
-// backdropCache holds a cached checkerboard backdrop.
+// backdropCache holds a cached checkerboard backdrop drawn in
+// backdropColors.
 var backdropCache *image.RGBA
+var backdropColors [2]color.RGBA
 
 // keyboardAction maps a keyboard shortcut to the action name.
 var keyboardAction = map[KeyShortcut]string{}
@@ -677,12 +1023,27 @@ var hoverPalette = -1
 var hoverWidth = -1
 var hoverNumber = -1
 var hoverTextSize = -1
+var hoverOpacity = -1
+var hoverFill = -1
+var hoverRadius = -1
+var hoverStroke = -1
+var hoverLink = -1
+var hoverAspect = -1
+var hoverStyle = -1
+var hoverAlign = -1
+var hoverLayer = -1
+var hoverTabClose = -1
+var hoverTabScroll = -1
+var hoverHistory = -1
 
 // TabButton draws a tab title in the header bar.
 type TabButton struct {
 	label    string
 	rect     image.Rectangle
 	onSelect func()
+	// closable draws the tab's close box, and closeHover highlights it.
+	closable   bool
+	closeHover bool
 }
 
 func (tb *TabButton) Draw(dst *image.RGBA, state ButtonState, t *theme.Theme) {
@@ -697,9 +1058,57 @@ func (tb *TabButton) Draw(dst *image.RGBA, state ButtonState, t *theme.Theme) {
 		textCol = t.TabTextActive
 	}
 	draw.Draw(dst, tb.rect, &image.Uniform{c}, image.Point{}, draw.Src)
-	d := &font.Drawer{Dst: dst, Src: image.NewUniform(textCol), Face: basicfont.Face7x13,
+	// Long titles are cut off at the tab's edge, or its close box, rather
+	// than running into the next one.
+	clip := tb.rect.Inset(2)
+	if tb.closable {
+		clip.Max.X = tabCloseRect(tb.rect).Min.X - 2
+	}
+	label := dst.SubImage(clip).(*image.RGBA)
+	d := &font.Drawer{Dst: label, Src: image.NewUniform(textCol), Face: basicfont.Face7x13,
 		Dot: fixed.P(tb.rect.Min.X+4, tb.rect.Min.Y+16)}
 	d.DrawString(tb.label)
+	if tb.closable {
+		cr := tabCloseRect(tb.rect)
+		if tb.closeHover {
+			draw.Draw(dst, cr, &image.Uniform{t.ButtonBackgroundHover}, image.Point{}, draw.Src)
+		}
+		drawLine(dst, cr.Min.X+3, cr.Min.Y+3, cr.Max.X-4, cr.Max.Y-4, textCol, 1)
+		drawLine(dst, cr.Max.X-4, cr.Min.Y+3, cr.Min.X+3, cr.Max.Y-4, textCol, 1)
+	}
+}
+
+// tabWidth is the width of a tab in the tab bar, and tabScrollWidth that of
+// each of the scroll buttons at its right end once the tabs overflow it.
+const (
+	tabWidth       = 80
+	tabScrollWidth = 20
+)
+
+// tabCloseRect returns the close box of the tab drawn at r.
+func tabCloseRect(r image.Rectangle) image.Rectangle {
+	y := r.Min.Y + (r.Dy()-12)/2
+	return image.Rect(r.Max.X-16, y, r.Max.X-4, y+12)
+}
+
+// tabsShown returns how many of n tabs fit in the tab bar of a window width
+// UI pixels wide, leaving room for the scroll buttons when not all do.
+func tabsShown(width, n int) int {
+	room := width - toolbarWidth
+	if n*tabWidth <= room {
+		return n
+	}
+	return max((room-2*tabScrollWidth)/tabWidth, 1)
+}
+
+// scrollTabs returns scroll, the first of n tabs shown when shown of them
+// fit, kept in range and, when reveal is not negative, moved just far
+// enough to bring tab reveal into view.
+func scrollTabs(scroll, reveal, n, shown int) int {
+	if reveal >= 0 {
+		scroll = max(min(scroll, reveal), reveal-shown+1)
+	}
+	return max(min(scroll, n-shown), 0)
 }
 
 func (tb *TabButton) Rect() image.Rectangle { return tb.rect }
@@ -724,7 +1133,9 @@ func numberBoxHeight(size int) int {
 	return h
 }
 
-func drawTabs(dst *image.RGBA, tabs []Tab, current int, t *theme.Theme, sm spacemap.Interface) {
+// drawTabs draws the tab bar of a window width UI pixels wide, starting
+// from tab scroll when the tabs do not all fit.
+func drawTabs(dst *image.RGBA, tabs []Tab, current, scroll, width int, t *theme.Theme, sm spacemap.Interface) {
 	// background for title area
 	draw.Draw(dst, image.Rect(0, 0, toolbarWidth, tabHeight),
 		&image.Uniform{t.ToolbarBackground}, image.Point{}, draw.Src)
@@ -748,12 +1159,18 @@ func drawTabs(dst *image.RGBA, tabs []Tab, current int, t *theme.Theme, sm space
 	d.DrawString(title)
 
 	tabButtons = tabButtons[:0]
+	shown := tabsShown(width, len(tabs))
+	scroll = scrollTabs(scroll, -1, len(tabs), shown)
 	x := toolbarWidth
-	for i, t2 := range tabs {
-		tb := TabButton{label: t2.Title, onSelect: nil}
-		tb.SetRect(image.Rect(x, 0, x+80, tabHeight))
+	for i := scroll; i < scroll+shown; i++ {
+		t2 := tabs[i]
+		tb := TabButton{label: t2.Title, onSelect: nil, closable: len(tabs) > 1, closeHover: i == hoverTabClose}
+		tb.SetRect(image.Rect(x, 0, x+tabWidth, tabHeight))
 		if sm != nil {
 			sm.Add(&UIShape{Rect: tb.Rect(), Type: UITypeTab, Index: i}, 0)
+			if tb.closable {
+				sm.Add(&UIShape{Rect: tabCloseRect(tb.Rect()), Type: UITypeTabClose, Index: i}, -1)
+			}
 		}
 		state := StateDefault
 		switch i {
@@ -764,11 +1181,34 @@ func drawTabs(dst *image.RGBA, tabs []Tab, current int, t *theme.Theme, sm space
 		}
 		tb.Draw(dst, state, t)
 		tabButtons = append(tabButtons, tb)
-		x += 80
+		x += tabWidth
 	}
 	// fill remainder of bar
-	draw.Draw(dst, image.Rect(x, 0, dst.Bounds().Dx(), tabHeight),
+	draw.Draw(dst, image.Rect(x, 0, dst.Bounds().Max.X, tabHeight),
 		&image.Uniform{t.ToolbarBackground}, image.Point{}, draw.Src)
+	if shown == len(tabs) {
+		return
+	}
+	// The scroll buttons are greyed out at either end.
+	for i, label := range []string{"<", ">"} {
+		r := image.Rect(width-(2-i)*tabScrollWidth, 0, width-(1-i)*tabScrollWidth, tabHeight)
+		if sm != nil {
+			sm.Add(&UIShape{Rect: r, Type: UITypeTabScroll, Index: i}, 0)
+		}
+		c := t.ButtonBackground
+		if i == hoverTabScroll {
+			c = t.ButtonBackgroundHover
+		}
+		draw.Draw(dst, r, &image.Uniform{c}, image.Point{}, draw.Src)
+		drawRect(dst, r, t.ButtonBorder, 1)
+		text := t.ButtonText
+		if (i == 0 && scroll == 0) || (i == 1 && scroll+shown == len(tabs)) {
+			text = color.RGBA{128, 128, 128, 255}
+		}
+		d := &font.Drawer{Dst: dst, Src: image.NewUniform(text), Face: basicfont.Face7x13,
+			Dot: fixed.P(r.Min.X+7, r.Min.Y+16)}
+		d.DrawString(label)
+	}
 }
 
 func drawShortcuts(dst *image.RGBA, width, height int, tool Tool, textMode bool, z float64, trigger func(string), annotationEnabled bool, versionLabel string, t *theme.Theme, sm spacemap.Interface) {
@@ -792,12 +1232,14 @@ func drawShortcuts(dst *image.RGBA, width, height int, tool Tool, textMode bool,
 				{label: "^D:delete", action: func() { trigger("delete") }},
 				{label: "^C:copy image", action: func() { trigger("copy") }},
 				{label: "^S:save", action: func() { trigger("save") }},
+				{label: "F7:layers", action: func() { trigger("layers") }},
 				{label: "Q:quit", action: func() { trigger("quit") }},
 			}
 			if tool == ToolCrop {
 				shortcuts = append(shortcuts,
 					Shortcut{label: "Enter:crop", action: func() { trigger("crop") }},
 					Shortcut{label: "Ctrl+Enter:new tab", action: func() { trigger("croptab") }},
+					Shortcut{label: "#:size", action: func() { trigger("cropsize") }},
 					Shortcut{label: "Esc:cancel", action: func() { trigger("cropcancel") }},
 				)
 			}
@@ -836,6 +1278,98 @@ func drawShortcuts(dst *image.RGBA, width, height int, tool Tool, textMode bool,
 	}
 }
 
+// drawMemoryLabel right-aligns label in the status bar when it fits after
+// the shortcuts.
+func drawMemoryLabel(dst *image.RGBA, width, height int, label string, t *theme.Theme) {
+	if label == "" {
+		return
+	}
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.Foreground), Face: basicfont.Face7x13}
+	x := width - d.MeasureString(label).Ceil() - 8
+	if n := len(shortcutRects); n > 0 && x < shortcutRects[n-1].Rect().Max.X+8 {
+		return
+	}
+	d.Dot = fixed.P(x, height-bottomHeight+16)
+	d.DrawString(label)
+}
+
+// debugLines reports the last frame's render time, the frames canceled
+// before they finished and the Go runtime's memory use.
+func debugLines(frame time.Duration, dropped int) []string {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return []string{
+		fmt.Sprintf("frame %.1f ms", float64(frame)/float64(time.Millisecond)),
+		fmt.Sprintf("dropped frames %d", dropped),
+		fmt.Sprintf("heap %s of %s", formatMiB(int(ms.HeapAlloc)), formatMiB(int(ms.Sys))),
+		fmt.Sprintf("gc %d, goroutines %d", ms.NumGC, runtime.NumGoroutine()),
+	}
+}
+
+// debugOverlayRect is the box drawDebugOverlay draws lines in, at the top
+// right of a window width pixels wide.
+func debugOverlayRect(width int, lines []string) image.Rectangle {
+	w := 0
+	for _, l := range lines {
+		w = max(w, font.MeasureString(basicfont.Face7x13, l).Ceil())
+	}
+	top := tabHeight + 8
+	return image.Rect(width-w-20, top, width-8, top+len(lines)*14+8)
+}
+
+// drawDebugOverlay draws lines in white on a translucent black box.
+func drawDebugOverlay(dst *image.RGBA, width int, lines []string) {
+	r := debugOverlayRect(width, lines)
+	draw.Draw(dst, r, &image.Uniform{color.RGBA{0, 0, 0, 0xc0}}, image.Point{}, draw.Over)
+	d := &font.Drawer{Dst: dst, Src: image.White, Face: basicfont.Face7x13}
+	for i, l := range lines {
+		d.Dot = fixed.P(r.Min.X+6, r.Min.Y+4+(i+1)*14-3)
+		d.DrawString(l)
+	}
+}
+
+// historyRowHeight is the height of one clipboard history row.
+const historyRowHeight = 20
+
+// drawHistory draws the clipboard history popup centred in the window. Its
+// shapes sit above the rest of the UI so clicks on it never reach the
+// toolbar or image below.
+func drawHistory(dst *image.RGBA, width, height int, rows []string, t *theme.Theme, sm spacemap.Interface) {
+	title := "Clipboard history: click or press 1-9 to copy, Esc to close"
+	if len(rows) == 0 {
+		title = "Clipboard history is empty; Esc to close"
+	}
+	meas := &font.Drawer{Face: basicfont.Face7x13}
+	w := meas.MeasureString(title).Ceil()
+	for _, row := range rows {
+		w = max(w, meas.MeasureString(row).Ceil())
+	}
+	w += 16
+	h := historyRowHeight*(len(rows)+1) + 8
+	box := image.Rect((width-w)/2, (height-h)/2, (width+w)/2, (height+h)/2)
+	if sm != nil {
+		sm.Add(&UIShape{Rect: box, Type: UITypeHistory, Index: -1}, -1)
+	}
+	draw.Draw(dst, box, &image.Uniform{t.ToolbarBackground}, image.Point{}, draw.Src)
+	drawRect(dst, box, t.ButtonBorder, 1)
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.Foreground), Face: basicfont.Face7x13,
+		Dot: fixed.P(box.Min.X+8, box.Min.Y+18)}
+	d.DrawString(title)
+	for i, row := range rows {
+		y := box.Min.Y + 4 + historyRowHeight*(i+1)
+		sc := Shortcut{label: row}
+		sc.SetRect(image.Rect(box.Min.X+4, y, box.Max.X-4, y+historyRowHeight-2))
+		if sm != nil {
+			sm.Add(&UIShape{Rect: sc.Rect(), Type: UITypeHistory, Index: i}, -2)
+		}
+		state := StateDefault
+		if i == hoverHistory {
+			state = StateHover
+		}
+		sc.Draw(dst, state, t)
+	}
+}
+
 func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, annotationEnabled bool, shadowUsed bool, buttons []Button, t *theme.Theme, sm spacemap.Interface) {
 	y := tabHeight
 	for i, cb := range buttons {
@@ -893,6 +1427,12 @@ func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, an
 			drawLine(dst, rect.Max.X-1, rect.Min.Y, rect.Max.X-1, rect.Max.Y-1, color.White, 1)
 			drawLine(dst, rect.Min.X, rect.Max.Y-1, rect.Max.X-1, rect.Max.Y-1, color.White, 1)
 		}
+		if i == fillColorIdx {
+			// A corner notch marks the fill colour.
+			corner := image.Rect(rect.Max.X-6, rect.Max.Y-6, rect.Max.X, rect.Max.Y)
+			draw.Draw(dst, corner, image.White, image.Point{}, draw.Src)
+			draw.Draw(dst, corner.Inset(1), image.Black, image.Point{}, draw.Src)
+		}
 		paletteRects = append(paletteRects, rect)
 		x += 18
 		if x+16 > toolbarWidth {
@@ -901,7 +1441,7 @@ func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, an
 		}
 	}
 
-	if tool == ToolDraw || tool == ToolCircle || tool == ToolLine || tool == ToolArrow || tool == ToolRect {
+	if tool == ToolDraw || tool == ToolCircle || tool == ToolLine || tool == ToolArrow || tool == ToolRect || tool == ToolHighlight || tool == ToolRoundRect || tool == ToolPolygon || tool == ToolCallout {
 		y += 4
 		col := palette[colIdx]
 		widthRects = widthRects[:0]
@@ -919,13 +1459,193 @@ func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, an
 			}
 			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
 			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
-			d.DrawString(fmt.Sprintf("%d", w))
 			lineY := y + 8
-			drawLine(dst, 30, lineY, toolbarWidth-4, lineY, col, w)
+			if tool == ToolHighlight {
+				d.DrawString(fmt.Sprintf("%d", w*highlightScale))
+				// Drawn on the row alone, so the stroke only tracks its pixels.
+				var h highlighter
+				h.line(dst.SubImage(rect).(*image.RGBA), 30, lineY, toolbarWidth-4, lineY, col, min(w*highlightScale, 14), highlightAlpha())
+			} else {
+				d.DrawString(fmt.Sprintf("%d", w))
+				drawLine(dst, 30, lineY, toolbarWidth-4, lineY, col, w)
+			}
 			widthRects = append(widthRects, rect)
 			y += 16
 		}
 	}
+	if tool == ToolHighlight {
+		y += 4
+		col := palette[colIdx]
+		opacityRects = opacityRects[:0]
+		for i, o := range highlightOpacities {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeOpacity, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case highlightOpacityIdx:
+				c = t.ButtonBackgroundPress
+			case hoverOpacity:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(fmt.Sprintf("%d%%", o))
+			swatch := image.Rect(34, y+3, toolbarWidth-4, y+13)
+			draw.Draw(dst, swatch, image.White, image.Point{}, draw.Src)
+			var h highlighter
+			h.line(dst.SubImage(swatch).(*image.RGBA), swatch.Min.X+2, y+8, swatch.Max.X-3, y+8, col, 7, uint8(o*255/100))
+			opacityRects = append(opacityRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolLine || tool == ToolArrow || tool == ToolRect || tool == ToolCircle {
+		y += 4
+		col := palette[colIdx]
+		styleRects = styleRects[:0]
+		for i := range lineStyleNames {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeStyle, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case int(lineStyleIdx):
+				c = t.ButtonBackgroundPress
+			case hoverStyle:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(LineStyle(i).label())
+			dashLine(dst, 52, y+8, toolbarWidth-5, y+8, col, 2, newDasher(LineStyle(i), 2))
+			styleRects = append(styleRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolCrop {
+		y += 4
+		aspectRects = aspectRects[:0]
+		for i := range cropAspects {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeAspect, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case cropAspectIdx:
+				c = t.ButtonBackgroundPress
+			case hoverAspect:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(cropAspectLabel(i))
+			aspectRects = append(aspectRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolSelect {
+		y += 4
+		alignRects = alignRects[:0]
+		for i, label := range alignLabels {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeAlign, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			if i == hoverAlign {
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(label)
+			alignRects = append(alignRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolRoundRect {
+		y += 4
+		col := palette[colIdx]
+		radiusRects = radiusRects[:0]
+		for i, r := range roundRadii {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeRadius, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case roundRadiusIdx:
+				c = t.ButtonBackgroundPress
+			case hoverRadius:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(fmt.Sprintf("r%d", r))
+			// The swatch shows a corner at a quarter of the radius, which
+			// is as much as a row has room for.
+			drawRoundRect(dst, image.Rect(34, y+3, toolbarWidth-4, y+14), max(r/4, 1), col, 1)
+			radiusRects = append(radiusRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolRect || tool == ToolCircle || tool == ToolRoundRect || tool == ToolPolygon || tool == ToolCallout {
+		y += 4
+		col := palette[colIdx]
+		if fillColorIdx >= 0 && fillColorIdx < len(palette) {
+			col = palette[fillColorIdx]
+		}
+		fillRects = fillRects[:0]
+		for i, o := range fillOpacities {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeFill, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case fillIdx:
+				c = t.ButtonBackgroundPress
+			case hoverFill:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			if o == 0 {
+				d.DrawString("No fill")
+			} else {
+				d.DrawString(fmt.Sprintf("%d%%", o))
+				swatch := image.Rect(34, y+3, toolbarWidth-4, y+13)
+				draw.Draw(dst, swatch, image.White, image.Point{}, draw.Src)
+				fillRect(dst, swatch, fillColor(col, uint8(o*255/100)))
+			}
+			fillRects = append(fillRects, rect)
+			y += 16
+		}
+	}
+	if tool == ToolDraw {
+		y += 4
+		strokeRects = strokeRects[:0]
+		for i, o := range freehandToggles {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeStroke, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch {
+			case *o.on:
+				c = t.ButtonBackgroundPress
+			case i == hoverStroke:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(o.label)
+			strokeRects = append(strokeRects, rect)
+			y += 16
+		}
+	}
 	if tool == ToolNumber {
 		y += 4
 		col := palette[colIdx]
@@ -950,12 +1670,32 @@ func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, an
 			numberRects = append(numberRects, rect)
 			y += h
 		}
+		y += 4
+		linkRects = linkRects[:0]
+		for i, label := range numberLinks {
+			rect := image.Rect(0, y, toolbarWidth, y+16)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: rect, Type: UITypeLink, Index: i}, 0)
+			}
+			c := t.ButtonBackground
+			switch i {
+			case int(numberLinkIdx):
+				c = t.ButtonBackgroundPress
+			case hoverLink:
+				c = t.ButtonBackgroundHover
+			}
+			draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+			d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(4, y+12)}
+			d.DrawString(label)
+			linkRects = append(linkRects, rect)
+			y += 16
+		}
 	}
-	if tool == ToolText {
+	if tool == ToolText || tool == ToolCallout {
 		y += 4
 		col := palette[colIdx]
 		textSizeRects = textSizeRects[:0]
-		for i, face := range textFaces {
+		for i, face := range textFaces() {
 			rect := image.Rect(0, y, toolbarWidth, y+24)
 			if sm != nil {
 				sm.Add(&UIShape{Rect: rect, Type: UITypeTextSize, Index: i}, 0)
@@ -971,67 +1711,67 @@ func drawToolbar(dst *image.RGBA, tool Tool, colIdx, widthIdx, numberIdx int, an
 			d := &font.Drawer{Dst: dst, Src: image.NewUniform(col), Face: face}
 			baseline := y + face.Metrics().Ascent.Ceil()
 			d.Dot = fixed.P(4, baseline)
-			d.DrawString("Ab3")
+			fonts.Draw(d, "Ab3")
 			textSizeRects = append(textSizeRects, rect)
 			y += 24
 		}
 	}
 }
 
+// rgbaOf converts col to the pixel value img.Set would store for it.
+func rgbaOf(col color.Color) color.RGBA {
+	return color.RGBAModel.Convert(col).(color.RGBA)
+}
+
+// setPixel sets the pixel at (x, y) to c if it lies within img.
+func setPixel(img *image.RGBA, x, y int, c color.RGBA) {
+	if !image.Pt(x, y).In(img.Rect) {
+		return
+	}
+	i := img.PixOffset(x, y)
+	img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+}
+
+// fillSpan sets pixels x0 to x1-1 of row y to c, clipped to img. The first
+// pixel is written directly and then copied over the rest of the span in
+// doubling runs.
+func fillSpan(img *image.RGBA, x0, x1, y int, c color.RGBA) {
+	if y < img.Rect.Min.Y || y >= img.Rect.Max.Y {
+		return
+	}
+	x0, x1 = max(x0, img.Rect.Min.X), min(x1, img.Rect.Max.X)
+	if x0 >= x1 {
+		return
+	}
+	i := img.PixOffset(x0, y)
+	span := img.Pix[i : i+(x1-x0)*4]
+	span[0], span[1], span[2], span[3] = c.R, c.G, c.B, c.A
+	for n := 4; n < len(span); n *= 2 {
+		copy(span[n:], span[:n])
+	}
+}
+
 func setThickPixel(img *image.RGBA, x, y, thick int, col color.Color) {
 	r := thick / 2
-	for dx := -r; dx <= r; dx++ {
-		for dy := -r; dy <= r; dy++ {
-			px := x + dx
-			py := y + dy
-			if image.Pt(px, py).In(img.Bounds()) {
-				img.Set(px, py, col)
-			}
-		}
+	c := rgbaOf(col)
+	for dy := -r; dy <= r; dy++ {
+		fillSpan(img, x-r, x+r+1, y+dy, c)
 	}
 }
 
 func drawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int) {
-	dx := math.Abs(float64(x1 - x0))
-	dy := math.Abs(float64(y1 - y0))
-	sx := -1
-	if x0 < x1 {
-		sx = 1
-	}
-	sy := -1
-	if y0 < y1 {
-		sy = 1
-	}
-	err := dx - dy
-	for {
-		setThickPixel(img, x0, y0, thick, col)
-		if x0 == x1 && y0 == y1 {
-			break
-		}
-		e2 := 2 * err
-		if e2 > -dy {
-			err -= dy
-			x0 += sx
-		}
-		if e2 < dx {
-			err += dx
-			y0 += sy
-		}
-	}
+	dashLine(img, x0, y0, x1, y1, col, thick, nil)
 }
 
 func drawCircleThin(img *image.RGBA, cx, cy, r int, col color.Color) {
+	c := rgbaOf(col)
 	x := r
 	y := 0
 	err := 1 - r
 	for x >= y {
-		pts := [][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}}
+		pts := [8][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}}
 		for _, p := range pts {
-			px := cx + p[0]
-			py := cy + p[1]
-			if image.Pt(px, py).In(img.Bounds()) {
-				img.Set(px, py, col)
-			}
+			setPixel(img, cx+p[0], cy+p[1], c)
 		}
 		y++
 		if err < 0 {
@@ -1058,49 +1798,26 @@ func drawCircle(img *image.RGBA, cx, cy, r int, col color.Color, thick int) {
 }
 
 func drawEllipse(img *image.RGBA, cx, cy, rx, ry int, col color.Color, thick int) {
-	steps := int(math.Ceil(2 * math.Pi * math.Sqrt(float64(rx*rx+ry*ry))))
-	if steps < 8 {
-		steps = 8
-	}
-	var prevX, prevY int
-	for i := 0; i <= steps; i++ {
-		angle := 2 * math.Pi * float64(i) / float64(steps)
-		x := cx + int(math.Cos(angle)*float64(rx))
-		y := cy + int(math.Sin(angle)*float64(ry))
-		if i > 0 {
-			drawLine(img, prevX, prevY, x, y, col, thick)
-		} else {
-			setThickPixel(img, x, y, thick, col)
-		}
-		prevX, prevY = x, y
-	}
+	dashEllipse(img, cx, cy, rx, ry, col, thick, nil)
 }
 
 func drawArrow(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int) {
-	drawLine(img, x0, y0, x1, y1, col, thick)
-	angle := math.Atan2(float64(y1-y0), float64(x1-x0))
-	size := float64(6 + thick*2)
-	a1 := angle + math.Pi/6
-	a2 := angle - math.Pi/6
-	x2 := x1 - int(math.Cos(a1)*size)
-	y2 := y1 - int(math.Sin(a1)*size)
-	x3 := x1 - int(math.Cos(a2)*size)
-	y3 := y1 - int(math.Sin(a2)*size)
-	drawLine(img, x1, y1, x2, y2, col, thick)
-	drawLine(img, x1, y1, x3, y3, col, thick)
+	dashArrow(img, x0, y0, x1, y1, col, thick, nil)
 }
 
 func drawFilledCircle(img *image.RGBA, cx, cy, r int, col color.Color) {
+	c := rgbaOf(col)
 	for dy := -r; dy <= r; dy++ {
-		for dx := -r; dx <= r; dx++ {
-			if dx*dx+dy*dy <= r*r {
-				px := cx + dx
-				py := cy + dy
-				if image.Pt(px, py).In(img.Bounds()) {
-					img.Set(px, py, col)
-				}
-			}
+		// w is the widest dx with dx*dx+dy*dy <= r*r.
+		rem := r*r - dy*dy
+		w := int(math.Sqrt(float64(rem)))
+		for w*w > rem {
+			w--
 		}
+		for (w+1)*(w+1) <= rem {
+			w++
+		}
+		fillSpan(img, cx-w, cx+w+1, cy+dy, c)
 	}
 }
 
@@ -1118,14 +1835,19 @@ func drawNumberBox(img *image.RGBA, cx, cy, num int, col color.Color, size int)
 	}
 
 	text := fmt.Sprintf("%d", num)
+	face, err := faceForSize(float64(max(r, 10)))
+	if err != nil {
+		face = basicfont.Face7x13
+	}
 	d := &font.Drawer{
 		Dst:  img,
 		Src:  image.NewUniform(textCol),
-		Face: basicfont.Face7x13,
+		Face: face,
 	}
 	w := d.MeasureString(text).Ceil()
-	d.Dot = fixed.P(cx-w/2, cy+4)
-	d.DrawString(text)
+	m := face.Metrics()
+	d.Dot = fixed.P(cx-w/2, cy+(m.Ascent.Ceil()-m.Descent.Ceil())/2)
+	fonts.Draw(d, text)
 }
 
 // ensureCanvasContains expands the tab's image so that rect (in image coordinates)
@@ -1160,58 +1882,48 @@ func ensureCanvasContains(t *Tab, rect image.Rectangle) image.Point {
 	draw.Draw(newImg, b.Add(image.Pt(-minX, -minY)), t.Image, image.Point{}, draw.Src)
 	t.Image = newImg
 	t.Offset = t.Offset.Add(image.Pt(minX, minY))
+	t.shiftItems(image.Pt(minX, minY))
 	return image.Pt(minX, minY)
 }
 
+// drawDashedLine draws a line alternating between c1 and c2 every dash
+// pixels. The line is thickness pixels wide, extending below a horizontal
+// line, right of a vertical one and both right and below a slanted one.
 func drawDashedLine(img *image.RGBA, x0, y0, x1, y1, dash, thickness int, c1, c2 color.Color) {
+	dash = max(dash, 1)
+	cols := [2]color.RGBA{rgbaOf(c1), rgbaOf(c2)}
+	if x0 != x1 && y0 != y1 {
+		k := 0
+		walkLine(x0, y0, x1, y1, func(x, y int, _ float64) {
+			c := cols[(k/dash)%2]
+			k++
+			for t := 0; t < thickness; t++ {
+				fillSpan(img, x, x+thickness, y+t, c)
+			}
+		})
+		return
+	}
 	horiz := y0 == y1
-	length := x1 - x0
+	length, step := x1-x0, 1
 	if !horiz {
 		length = y1 - y0
 	}
 	if length < 0 {
-		length = -length
-	}
-	for i := 0; i <= length; i += dash * 2 {
-		for j := 0; j < dash && i+j <= length; j++ {
-			col := c1
-			if horiz {
-				for t := 0; t < thickness; t++ {
-					if x0 < x1 {
-						img.Set(x0+i+j, y0+t, col)
-					} else {
-						img.Set(x0-i-j, y0+t, col)
-					}
-				}
-			} else {
-				for t := 0; t < thickness; t++ {
-					if y0 < y1 {
-						img.Set(x0+t, y0+i+j, col)
-					} else {
-						img.Set(x0+t, y0-i-j, col)
-					}
-				}
+		length, step = -length, -1
+	}
+	for k := 0; k <= length; k += dash {
+		c := cols[(k/dash)%2]
+		end := min(k+dash, length+1)
+		if horiz {
+			// The dash covers x0+step*k to x0+step*(end-1).
+			a, b := x0+step*k, x0+step*(end-1)
+			for t := 0; t < thickness; t++ {
+				fillSpan(img, min(a, b), max(a, b)+1, y0+t, c)
 			}
+			continue
 		}
-		for j := 0; j < dash && i+dash+j <= length; j++ {
-			col := c2
-			if horiz {
-				for t := 0; t < thickness; t++ {
-					if x0 < x1 {
-						img.Set(x0+i+dash+j, y0+t, col)
-					} else {
-						img.Set(x0-i-dash-j, y0+t, col)
-					}
-				}
-			} else {
-				for t := 0; t < thickness; t++ {
-					if y0 < y1 {
-						img.Set(x0+t, y0+i+dash+j, col)
-					} else {
-						img.Set(x0+t, y0-i-dash-j, col)
-					}
-				}
-			}
+		for j := k; j < end; j++ {
+			fillSpan(img, x0, x0+thickness, y0+step*j, c)
 		}
 	}
 }
@@ -1224,10 +1936,7 @@ func drawDashedRect(img *image.RGBA, rect image.Rectangle, dash, thickness int,
 }
 
 func drawRect(img *image.RGBA, rect image.Rectangle, col color.Color, thick int) {
-	drawLine(img, rect.Min.X, rect.Min.Y, rect.Max.X-1, rect.Min.Y, col, thick)
-	drawLine(img, rect.Max.X-1, rect.Min.Y, rect.Max.X-1, rect.Max.Y-1, col, thick)
-	drawLine(img, rect.Max.X-1, rect.Max.Y-1, rect.Min.X, rect.Max.Y-1, col, thick)
-	drawLine(img, rect.Min.X, rect.Max.Y-1, rect.Min.X, rect.Min.Y, col, thick)
+	dashRect(img, rect, col, thick, nil)
 }
 
 func cropHandleRects(rect image.Rectangle) []image.Rectangle {
@@ -1261,15 +1970,23 @@ func cropImage(img *image.RGBA, rect image.Rectangle) *image.RGBA {
 }
 
 type PaintState struct {
-	Width, Height     int
-	Tabs              []Tab
-	Current           int
-	Tool              Tool
-	ColorIdx          int
-	NumberIdx         int
-	Cropping          bool
-	CropRect          image.Rectangle
-	CropStart         image.Point
+	Width, Height int
+	Tabs          []Tab
+	Current       int
+	Tool          Tool
+	ColorIdx      int
+	NumberIdx     int
+	Cropping      bool
+	CropRect      image.Rectangle
+	CropStart     image.Point
+	// Polygon holds the vertices of the polygon being placed.
+	Polygon []image.Point
+	// Callout is the callout being edited.
+	Callout *callout
+	// Selected holds the areas of the texts and callouts the Select tool
+	// has picked, and SelectBand the rubber band being dragged.
+	Selected          []image.Rectangle
+	SelectBand        image.Rectangle
 	TextInputActive   bool
 	TextInput         string
 	TextPos           image.Point
@@ -1281,6 +1998,26 @@ type PaintState struct {
 	Theme             *theme.Theme
 	ToolButtons       []Button
 	SetUIMap          func(spacemap.Interface)
+	// History lists the clipboard history popup's rows while it is open.
+	History     []string
+	HistoryOpen bool
+	// Dirty is the only window area that changed since the previous frame.
+	// It is empty when the whole frame must be drawn again.
+	Dirty image.Rectangle
+	// MemoryLabel reports the memory the tabs use in the status bar.
+	MemoryLabel string
+	// Debug holds the debug overlay's lines while it is shown.
+	Debug []string
+	// Layers lists the layers panel's rows while it is open, and LayerDrag
+	// is the row being dragged to restack it, or -1.
+	Layers     []layerRow
+	LayersOpen bool
+	LayerDrag  int
+	// Grid draws the grid overlay over the image.
+	Grid bool
+	// TabScroll is the first tab the tab bar shows when they do not all
+	// fit.
+	TabScroll int
 }
 
 func DefaultToolButtons(annotationEnabled bool) []Button {
@@ -1294,8 +2031,13 @@ func DefaultToolButtons(annotationEnabled bool) []Button {
 			&CacheButton{Button: &ToolButton{label: "Line(L)", tool: ToolLine, atype: actionDraw}},
 			&CacheButton{Button: &ToolButton{label: "Arrow(A)", tool: ToolArrow, atype: actionDraw}},
 			&CacheButton{Button: &ToolButton{label: "Rect(X)", tool: ToolRect, atype: actionDraw}},
+			&CacheButton{Button: &ToolButton{label: "Round(U)", tool: ToolRoundRect, atype: actionDraw}},
+			&CacheButton{Button: &ToolButton{label: "Poly(P)", tool: ToolPolygon, atype: actionDraw}},
 			&CacheButton{Button: &ToolButton{label: "Num(H)", tool: ToolNumber, atype: actionDraw}},
+			&CacheButton{Button: &ToolButton{label: "Marker(G)", tool: ToolHighlight, atype: actionDraw}},
 			&CacheButton{Button: &ToolButton{label: "Text(T)", tool: ToolText, atype: actionNone}},
+			&CacheButton{Button: &ToolButton{label: "Callout(K)", tool: ToolCallout, atype: actionNone}},
+			&CacheButton{Button: &ToolButton{label: "Select(S)", tool: ToolSelect, atype: actionSelect}},
 			&CacheButton{Button: &ToolButton{label: "Shadow($)", tool: ToolShadow, atype: actionNone}},
 		}
 	} else {
@@ -1317,32 +2059,36 @@ func DrawScene(ctx context.Context, b *image.RGBA, st PaintState) {
 	// Ensure toolbar width is correct for the current state
 	toolbarWidth = CalculateToolbarWidth(st.VersionLabel)
 
-	drawBackdrop(b, t)
+	drawBackdrop(b, image.Pt(st.Width, st.Height), t)
 	if ctx != nil && ctx.Err() != nil {
 		return
 	}
 
 	img := st.Tabs[st.Current].Image
 	zoom := st.Tabs[st.Current].Zoom
-	base := imageRect(img, st.Width, st.Height, zoom)
-	off := st.Tabs[st.Current].Offset
-	dst := base.Add(image.Pt(int(float64(off.X)*zoom), int(float64(off.Y)*zoom)))
-	xdraw.NearestNeighbor.Scale(b, dst, img, img.Bounds(), draw.Over, nil)
+	dst := imageScreenRect(st.Tabs[st.Current], st.Width, st.Height)
+	// Partial repaints scale just their area rather than refresh the cache.
+	full := b.Bounds() == image.Rect(0, 0, st.Width, st.Height)
+	if scaled := scaledTabImage(st.Tabs[st.Current], dst.Size(), full); scaled != nil {
+		render.Bands(b, dst, func(band *image.RGBA) {
+			draw.Draw(band, dst, scaled, image.Point{}, draw.Over)
+		})
+	} else {
+		render.Bands(b, dst, func(band *image.RGBA) {
+			xdraw.NearestNeighbor.Scale(band, dst, img, img.Bounds(), draw.Over, nil)
+		})
+	}
 	if ctx != nil && ctx.Err() != nil {
 		return
 	}
+	drawTabItems(b, &st.Tabs[st.Current], dst, zoom)
+
+	if st.Grid {
+		drawGrid(b, dst, zoom)
+	}
 
 	if st.Tool == ToolCrop && (st.Cropping || !st.CropRect.Empty()) {
-		sel := st.CropRect
-		if st.Cropping {
-			sel = image.Rect(st.CropStart.X, st.CropStart.Y, st.CropStart.X, st.CropStart.Y).Union(sel)
-		}
-		r := image.Rect(
-			dst.Min.X+int(float64(sel.Min.X)*zoom),
-			dst.Min.Y+int(float64(sel.Min.Y)*zoom),
-			dst.Min.X+int(float64(sel.Max.X)*zoom),
-			dst.Min.Y+int(float64(sel.Max.Y)*zoom),
-		)
+		r := toScreen(cropSelection(st.Cropping, st.CropStart, st.CropRect), dst, zoom)
 		drawDashedRect(b, r, 4, 2, color.White, color.Black)
 		for _, hr := range cropHandleRects(r) {
 			if ctx != nil && ctx.Err() != nil {
@@ -1352,15 +2098,68 @@ func DrawScene(ctx context.Context, b *image.RGBA, st PaintState) {
 			drawRect(b, hr, color.Black, 1)
 			drawDashedRect(b, hr, 2, 1, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255})
 		}
+		drawCropLabel(b, cropSelection(st.Cropping, st.CropStart, st.CropRect), r)
+	}
+
+	if st.Tool == ToolPolygon && len(st.Polygon) > 0 {
+		col := paletteColorAt(st.ColorIdx)
+		var prev image.Point
+		for i, p := range st.Polygon {
+			sp := toScreen(image.Rectangle{Min: p, Max: p}, dst, zoom).Min
+			if i > 0 {
+				drawLine(b, prev.X, prev.Y, sp.X, sp.Y, col, 1)
+			}
+			hr := image.Rect(sp.X-2, sp.Y-2, sp.X+3, sp.Y+3)
+			draw.Draw(b, hr, &image.Uniform{color.White}, image.Point{}, draw.Src)
+			drawRect(b, hr, color.Black, 1)
+			prev = sp
+		}
+	}
+
+	if st.Tool == ToolSelect {
+		for _, r := range st.Selected {
+			drawDashedRect(b, toScreen(r, dst, zoom).Inset(-2), 4, 1, color.White, color.Black)
+		}
+		if !st.SelectBand.Empty() {
+			drawDashedRect(b, toScreen(st.SelectBand, dst, zoom), 4, 1, color.White, color.Black)
+		}
+	}
+
+	if st.Tool == ToolCallout && st.Callout != nil {
+		c := *st.Callout
+		c.text += "|"
+		br := c.bounds()
+		layer := image.NewRGBA(br)
+		c.draw(layer)
+		xdraw.NearestNeighbor.Scale(b, toScreen(br, dst, zoom), layer, br, draw.Over, nil)
+		box := toScreen(c.box, dst, zoom)
+		drawDashedRect(b, box, 4, 2, color.White, color.Black)
+		tip := toScreen(image.Rectangle{Min: c.tip, Max: c.tip}, dst, zoom).Min
+		for _, hr := range append(cropHandleRects(box), calloutTipHandle(tip)) {
+			draw.Draw(b, hr, &image.Uniform{color.White}, image.Point{}, draw.Src)
+			drawRect(b, hr, color.Black, 1)
+		}
 	}
 
 	if ctx != nil && ctx.Err() != nil {
 		return
 	}
 
-	drawTabs(b, st.Tabs, st.Current, t, sm)
+	// The UI is drawn in UI pixels, which uiScale maps to the window's.
+	canvas := b
+	b, width, height := beginUI(canvas, st.Width, st.Height)
+	drawTabs(b, st.Tabs, st.Current, st.TabScroll, width, t, sm)
 	drawToolbar(b, st.Tool, st.ColorIdx, st.Tabs[st.Current].WidthIdx, st.NumberIdx, st.AnnotationEnabled, st.Tabs[st.Current].ShadowApplied, st.ToolButtons, t, sm)
-	drawShortcuts(b, st.Width, st.Height, st.Tool, st.TextInputActive, zoom, st.HandleShortcut, st.AnnotationEnabled, st.VersionLabel, t, sm)
+	drawShortcuts(b, width, height, st.Tool, st.TextInputActive, zoom, st.HandleShortcut, st.AnnotationEnabled, st.VersionLabel, t, sm)
+	drawMemoryLabel(b, width, height, st.MemoryLabel, t)
+
+	if st.LayersOpen {
+		drawLayers(b, width, height, st.Layers, st.LayerDrag, t, sm)
+	}
+
+	if st.HistoryOpen {
+		drawHistory(b, width, height, st.History, t, sm)
+	}
 
 	if st.SetUIMap != nil {
 		st.SetUIMap(sm)
@@ -1371,46 +2170,76 @@ func DrawScene(ctx context.Context, b *image.RGBA, st PaintState) {
 	}
 
 	if st.Message != "" && time.Now().Before(st.MessageUntil) {
-		d := &font.Drawer{Dst: b, Src: image.Black, Face: messageFace}
-		wmsg := d.MeasureString(st.Message).Ceil()
-		ascent := messageFace.Metrics().Ascent.Ceil()
-		descent := messageFace.Metrics().Descent.Ceil()
-		px := (st.Width - wmsg) / 2
-		py := (st.Height-ascent-descent)/2 + ascent
-		rect := image.Rect(px-8, py-ascent-8, px+wmsg+8, py+descent+8)
+		rect := toastRect(st.Message, width, height)
 		draw.Draw(b, rect, &image.Uniform{color.RGBA{255, 255, 255, 230}}, image.Point{}, draw.Over)
 		drawRect(b, rect, color.Black, 2)
-		d.Dot = fixed.P(px, py)
-		d.DrawString(st.Message)
+		d := &font.Drawer{Dst: b, Src: image.Black, Face: messageFace()}
+		d.Dot = fixed.P(rect.Min.X+8, rect.Min.Y+8+messageFace().Metrics().Ascent.Ceil())
+		fonts.Draw(d, st.Message)
 	}
 
 	if ctx != nil && ctx.Err() != nil {
 		return
 	}
 
+	if st.Debug != nil {
+		drawDebugOverlay(b, width, st.Debug)
+	}
+	endUI(canvas, b)
+	b = canvas
+
 	if st.TextInputActive {
-		d := &font.Drawer{Dst: b, Src: image.NewUniform(palette[st.ColorIdx]), Face: textFaces[textSizeIdx]}
-		px := dst.Min.X + int(float64(st.TextPos.X)*zoom)
-		py := dst.Min.Y + int(float64(st.TextPos.Y)*zoom)
-		d.Dot = fixed.P(px, py)
-		d.DrawString(st.TextInput + "|")
+		d := &font.Drawer{Dst: b, Src: image.NewUniform(palette[st.ColorIdx]), Face: textFaces()[textSizeIdx]}
+		o := textOrigin(st.TextPos, dst, zoom)
+		d.Dot = fixed.P(o.X, o.Y)
+		fonts.Draw(d, st.TextInput+"|")
 	}
 }
 
-func drawFrame(ctx context.Context, s screen.Screen, w screen.Window, st PaintState) {
-	b, err := s.NewBuffer(image.Point{st.Width, st.Height})
-	if err != nil {
-		log.Printf("new buffer: %v", err)
-		return
+// frameCache keeps the last composed frame so that a repaint naming a
+// dirty rectangle only recomposes that area over it.
+type frameCache struct {
+	buf screen.Buffer
+	// valid is set once buf holds a complete frame. stale is an area a
+	// canceled repaint left half drawn, added to the next repaint.
+	valid bool
+	stale image.Rectangle
+}
+
+func drawFrame(ctx context.Context, s screen.Screen, w screen.Window, st PaintState, fc *frameCache) {
+	size := image.Point{st.Width, st.Height}
+	if fc.buf == nil || fc.buf.Size() != size {
+		if fc.buf != nil {
+			fc.buf.Release()
+		}
+		b, err := s.NewBuffer(size)
+		if err != nil {
+			fc.buf = nil
+			log.Printf("new buffer: %v", err)
+			return
+		}
+		fc.buf, fc.valid, fc.stale = b, false, image.Rectangle{}
 	}
-	defer b.Release()
 
-	DrawScene(ctx, b.RGBA(), st)
+	frame := fc.buf.RGBA()
+	clip := frame.Bounds()
+	if fc.valid && !st.Dirty.Empty() {
+		clip = st.Dirty.Union(fc.stale).Intersect(clip)
+	}
+	DrawScene(ctx, frame.SubImage(clip).(*image.RGBA), st)
 
 	if ctx.Err() != nil {
+		if clip == frame.Bounds() {
+			fc.valid = false
+		} else {
+			fc.stale = fc.stale.Union(clip)
+		}
 		return
 	}
+	fc.valid, fc.stale = true, image.Rectangle{}
 
-	w.Upload(image.Point{}, b, b.Bounds())
+	// The whole frame is uploaded because drivers that swap buffers on
+	// Publish do not keep the previous frame in the window.
+	w.Upload(image.Point{}, fc.buf, fc.buf.Bounds())
 	w.Publish()
 }
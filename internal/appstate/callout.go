@@ -0,0 +1,208 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/example/shineyshot/internal/fonts"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Callouts are sized for a line of text inside calloutPadding, with corners
+// rounded to calloutRadius and a tail calloutTailWidth wide where it leaves
+// the box.
+const (
+	calloutPadding   = 6
+	calloutRadius    = 8
+	calloutTailWidth = 16
+)
+
+// callout is a speech bubble: a rounded box of text with a tail pointing at
+// tip, kept on a tab as a tabItem. The box grows to fit its text and the
+// tail leaves the side facing tip, so either can be dragged without the
+// other.
+type callout struct {
+	box      image.Rectangle
+	tip      image.Point
+	text     string
+	sizeIdx  int
+	colorIdx int
+	widthIdx int
+	// fill is the bubble's background.
+	fill color.NRGBA
+}
+
+// newCallout returns a callout in box using the toolbar's colour, width,
+// fill and text size.
+func newCallout(box image.Rectangle, colorIdx, widthIdx int) callout {
+	c := callout{
+		box:      box.Canon(),
+		sizeIdx:  textSizeIdx,
+		colorIdx: colorIdx,
+		widthIdx: widthIdx,
+		fill:     calloutFill(paletteColorAt(colorIdx)),
+	}
+	c.fit()
+	c.pointDown()
+	return c
+}
+
+// pointDown points the tail down and left of the box, where a new
+// callout's tail starts.
+func (c *callout) pointDown() {
+	c.tip = image.Pt(c.box.Min.X+c.box.Dx()/4, c.box.Max.Y+3*calloutTailWidth)
+}
+
+// calloutFill returns the background of callouts drawn in col: the fill
+// rows' colour, or white when they are off so the text stays readable over
+// busy screenshots.
+func calloutFill(col color.RGBA) color.NRGBA {
+	if fill, ok := shapeFill(col); ok {
+		return fill
+	}
+	return color.NRGBA{255, 255, 255, 255}
+}
+
+// minSize returns the smallest box the callout's text fits in.
+func (c callout) minSize() image.Point {
+	face := textFaces()[c.sizeIdx]
+	width := (&font.Drawer{Face: face}).MeasureString(c.text).Ceil()
+	m := face.Metrics()
+	thick := widthAt(c.widthIdx)
+	return image.Pt(width+2*calloutPadding+thick, (m.Ascent+m.Descent).Ceil()+2*calloutPadding+thick)
+}
+
+// fit grows the box to hold the text, keeping its top left corner.
+func (c *callout) fit() {
+	need := c.minSize()
+	c.box.Max.X = max(c.box.Max.X, c.box.Min.X+need.X)
+	c.box.Max.Y = max(c.box.Max.Y, c.box.Min.Y+need.Y)
+}
+
+// moved follows the image when its origin moves to d.
+func (c *callout) moved(d image.Point) {
+	c.box = c.box.Sub(d)
+	c.tip = c.tip.Sub(d)
+}
+
+// path returns the bubble's outline: the rounded box with the tail spliced
+// into the side facing the tip. A tip inside the box has no tail.
+func (c callout) path() []image.Point {
+	r := clampRadius(c.box, calloutRadius)
+	pts := roundRectPoints(c.box, r)
+	// The sides' distances from the tip, clockwise from the top as the
+	// outline runs.
+	far := []int{
+		c.box.Min.Y - c.tip.Y,
+		c.tip.X - (c.box.Max.X - 1),
+		c.tip.Y - (c.box.Max.Y - 1),
+		c.box.Min.X - c.tip.X,
+	}
+	side := 0
+	for i, d := range far {
+		if d > far[side] {
+			side = i
+		}
+	}
+	if far[side] <= 0 {
+		return pts
+	}
+	// Each corner's arc is len(pts)/4 points and each side runs from the
+	// end of one arc to the start of the next.
+	arc := len(pts) / 4
+	end := (side + 1) * arc
+	start, stop := pts[end-1], pts[end%len(pts)]
+	dx, dy := float64(stop.X-start.X), float64(stop.Y-start.Y)
+	length := math.Hypot(dx, dy)
+	if length < 2 {
+		return pts
+	}
+	dx, dy = dx/length, dy/length
+	half := math.Min(calloutTailWidth/2, length/2)
+	// The tail's base is centred where the tip lines up with the side.
+	along := float64(c.tip.X-start.X)*dx + float64(c.tip.Y-start.Y)*dy
+	along = math.Max(half, math.Min(length-half, along))
+	at := func(t float64) image.Point {
+		return image.Pt(start.X+int(math.Round(dx*t)), start.Y+int(math.Round(dy*t)))
+	}
+	out := make([]image.Point, 0, len(pts)+3)
+	out = append(out, pts[:end]...)
+	out = append(out, at(along-half), c.tip, at(along+half))
+	return append(out, pts[end:]...)
+}
+
+// bounds returns the area drawing the callout covers.
+func (c callout) bounds() image.Rectangle {
+	return polygonBounds(c.path()).Inset(-widthAt(c.widthIdx) - 2)
+}
+
+// draw paints the callout onto img.
+func (c callout) draw(img *image.RGBA) {
+	col := paletteColorAt(c.colorIdx)
+	thick := widthAt(c.widthIdx)
+	path := c.path()
+	fillPolygon(img, path, c.fill)
+	drawPolygon(img, path, col, thick)
+	face := textFaces()[c.sizeIdx]
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(col), Face: face}
+	d.Dot = fixed.P(c.box.Min.X+calloutPadding+thick/2, c.box.Min.Y+calloutPadding+thick/2+face.Metrics().Ascent.Ceil())
+	fonts.Draw(d, c.text)
+}
+
+// calloutTipHandle returns the handle that drags a callout's tail.
+func calloutTipHandle(tip image.Point) image.Rectangle {
+	return image.Rectangle{Min: tip, Max: tip}.Inset(-handleSize / 2)
+}
+
+// resizeRect returns r with the edges that mode drags moved by d.
+func resizeRect(r image.Rectangle, mode cropAction, d image.Point) image.Rectangle {
+	return moveEdges(r, mode, d).Canon()
+}
+
+// moveEdges returns r with the edges that mode drags moved by d, inside out
+// if they were dragged past the edges opposite.
+func moveEdges(r image.Rectangle, mode cropAction, d image.Point) image.Rectangle {
+	switch mode {
+	case cropMove:
+		return r.Add(d)
+	case cropResizeTL:
+		r.Min = r.Min.Add(d)
+	case cropResizeT:
+		r.Min.Y += d.Y
+	case cropResizeTR:
+		r.Min.Y += d.Y
+		r.Max.X += d.X
+	case cropResizeR:
+		r.Max.X += d.X
+	case cropResizeBR:
+		r.Max = r.Max.Add(d)
+	case cropResizeB:
+		r.Max.Y += d.Y
+	case cropResizeBL:
+		r.Min.X += d.X
+		r.Max.Y += d.Y
+	case cropResizeL:
+		r.Min.X += d.X
+	}
+	return r
+}
+
+// placeCallout puts c on top of the tab's items, growing the canvas to
+// fit.
+func (t *Tab) placeCallout(c callout) {
+	t.putItem(len(t.items), tabItem{callout: c, isCallout: true})
+}
+
+// calloutAt returns the index of the topmost visible, unlocked callout
+// whose box holds p, or -1.
+func (t *Tab) calloutAt(p image.Point) int {
+	for i := len(t.items) - 1; i >= 0; i-- {
+		it := t.items[i]
+		if it.isCallout && !it.hidden && !t.isLocked(it.id) && p.In(it.callout.box) {
+			return i
+		}
+	}
+	return -1
+}
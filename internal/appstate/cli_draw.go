@@ -1,15 +1,14 @@
 package appstate
 
 import (
-	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"math"
 	"sync"
 
+	"github.com/example/shineyshot/internal/fonts"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -37,16 +36,13 @@ func faceForSize(size float64) (font.Face, error) {
 	// If the size matches one of the predefined faces use it directly.
 	for i, s := range textSizes {
 		if math.Abs(s-size) < 0.01 {
-			return textFaces[i], nil
+			return textFaces()[i], nil
 		}
 	}
-	if goregularFont == nil {
-		return nil, fmt.Errorf("text font not initialised")
-	}
 	if face, ok := extraTextFaces.Load(size); ok {
 		return face.(font.Face), nil
 	}
-	face, err := opentype.NewFace(goregularFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	face, err := fonts.NewFace(size)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +82,7 @@ func DrawText(img *image.RGBA, x, y int, text string, col color.Color, size floa
 		Face: face,
 		Dot:  fixed.P(x, baseline),
 	}
-	drawer.DrawString(text)
+	fonts.Draw(drawer, text)
 	return nil
 }
 
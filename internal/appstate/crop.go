@@ -0,0 +1,149 @@
+package appstate
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// cropAspects are the Crop tool's aspect ratio rows. A zero ratio leaves
+// the selection free, and the last row holds the ratio typed for it.
+var cropAspects = []struct {
+	label string
+	ratio image.Point
+}{
+	{"Free", image.Point{}},
+	{"16:9", image.Pt(16, 9)},
+	{"4:3", image.Pt(4, 3)},
+	{"1:1", image.Pt(1, 1)},
+	{"Custom", image.Point{}},
+}
+var cropAspectIdx = 0
+
+// cropCustom is the ratio typed for the Custom row.
+var cropCustom image.Point
+
+// cropCustomIdx is the index of the Custom row.
+var cropCustomIdx = len(cropAspects) - 1
+
+// cropRatio returns the ratio the selection is locked to, or a zero point
+// when it is free.
+func cropRatio() image.Point {
+	if cropAspectIdx == cropCustomIdx {
+		return cropCustom
+	}
+	return cropAspects[cropAspectIdx].ratio
+}
+
+// cropAspectLabel returns the text of aspect row i.
+func cropAspectLabel(i int) string {
+	if i == cropCustomIdx && cropCustom != (image.Point{}) {
+		return fmt.Sprintf("%d:%d", cropCustom.X, cropCustom.Y)
+	}
+	return cropAspects[i].label
+}
+
+// dragCrop returns the selection start becomes when the edges mode drags
+// are moved by d, keeping it to ratio unless that is zero.
+func dragCrop(start image.Rectangle, mode cropAction, d image.Point, ratio image.Point) image.Rectangle {
+	return lockAspect(moveEdges(start, mode, d), mode, ratio).Canon()
+}
+
+// lockAspect resizes r, whose dragged edges mode names, to ratio. Corner
+// drags keep the opposite corner and follow whichever side the pointer
+// stretched further; edge drags keep the dragged side and centre the other
+// on where it was. r may be inside out from a drag past its anchor.
+func lockAspect(r image.Rectangle, mode cropAction, ratio image.Point) image.Rectangle {
+	if ratio.X <= 0 || ratio.Y <= 0 || mode == cropMove || mode == cropNone {
+		return r
+	}
+	sign := func(v int) int {
+		if v < 0 {
+			return -1
+		}
+		return 1
+	}
+	w, h := r.Dx(), r.Dy()
+	sw, sh := sign(w), sign(h)
+	w, h = w*sw, h*sh
+	switch mode {
+	case cropResizeT, cropResizeB:
+		w = h * ratio.X / ratio.Y
+		cx := (r.Min.X + r.Max.X) / 2
+		r.Min.X, r.Max.X = cx-w/2, cx-w/2+w
+		return r
+	case cropResizeL, cropResizeR:
+		h = w * ratio.Y / ratio.X
+		cy := (r.Min.Y + r.Max.Y) / 2
+		r.Min.Y, r.Max.Y = cy-h/2, cy-h/2+h
+		return r
+	}
+	if w*ratio.Y >= h*ratio.X {
+		h = w * ratio.Y / ratio.X
+	} else {
+		w = h * ratio.X / ratio.Y
+	}
+	switch mode {
+	case cropResizeTL:
+		r.Min = image.Pt(r.Max.X-sw*w, r.Max.Y-sh*h)
+	case cropResizeTR:
+		r.Max.X, r.Min.Y = r.Min.X+sw*w, r.Max.Y-sh*h
+	case cropResizeBR:
+		r.Max = image.Pt(r.Min.X+sw*w, r.Min.Y+sh*h)
+	case cropResizeBL:
+		r.Min.X, r.Max.Y = r.Max.X-sw*w, r.Min.Y+sh*h
+	}
+	return r
+}
+
+// parseCropSize reads an exact crop size typed as WxH, such as 1200x630.
+func parseCropSize(s string) (image.Point, error) {
+	return parsePair(s, "x", "crop size")
+}
+
+// parseCropAspect reads a custom aspect ratio typed as W:H, such as 3:2.
+func parseCropAspect(s string) (image.Point, error) {
+	return parsePair(s, ":", "aspect ratio")
+}
+
+// parsePair reads two positive integers separated by sep.
+func parsePair(s, sep, what string) (image.Point, error) {
+	a, b, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), sep)
+	if !ok {
+		return image.Point{}, fmt.Errorf("%s %q: want W%sH", what, s, sep)
+	}
+	x, errX := strconv.Atoi(strings.TrimSpace(a))
+	y, errY := strconv.Atoi(strings.TrimSpace(b))
+	if errX != nil || errY != nil || x <= 0 || y <= 0 {
+		return image.Point{}, fmt.Errorf("%s %q: want two positive whole numbers", what, s)
+	}
+	return image.Pt(x, y), nil
+}
+
+// cropLabel returns the text of the size label shown beside the selection
+// sel, its size in UI pixels and the window area it covers when the
+// selection is drawn at screen.
+func cropLabel(sel, screen image.Rectangle) (string, image.Point, image.Rectangle) {
+	text := fmt.Sprintf("%dx%d", sel.Dx(), sel.Dy())
+	size := image.Pt(font.MeasureString(basicfont.Face7x13, text).Ceil()+6, 16)
+	min := image.Pt(screen.Min.X, screen.Max.Y+handleSize/2+2)
+	return text, size, image.Rectangle{Min: min, Max: min.Add(image.Pt(ui(size.X), ui(size.Y)))}
+}
+
+// drawCropLabel draws the selection's size below its bottom left corner,
+// scaled like the rest of the UI.
+func drawCropLabel(dst *image.RGBA, sel, screen image.Rectangle) {
+	text, size, r := cropLabel(sel, screen)
+	label := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(label, label.Rect, &image.Uniform{color.RGBA{0, 0, 0, 200}}, image.Point{}, draw.Src)
+	d := &font.Drawer{Dst: label, Src: image.White, Face: basicfont.Face7x13, Dot: fixed.P(3, 12)}
+	d.DrawString(text)
+	uiScaler().Scale(dst, r, label, label.Rect, draw.Over, nil)
+}
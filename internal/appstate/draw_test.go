@@ -0,0 +1,865 @@
+package appstate
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/example/shineyshot/internal/project"
+)
+
+// The Set based versions the Pix writing helpers replaced; their output is
+// what the helpers must reproduce.
+
+func setThickPixelSet(img *image.RGBA, x, y, thick int, col color.Color) {
+	r := thick / 2
+	for dx := -r; dx <= r; dx++ {
+		for dy := -r; dy <= r; dy++ {
+			if image.Pt(x+dx, y+dy).In(img.Bounds()) {
+				img.Set(x+dx, y+dy, col)
+			}
+		}
+	}
+}
+
+func drawFilledCircleSet(img *image.RGBA, cx, cy, r int, col color.Color) {
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if dx*dx+dy*dy <= r*r && image.Pt(cx+dx, cy+dy).In(img.Bounds()) {
+				img.Set(cx+dx, cy+dy, col)
+			}
+		}
+	}
+}
+
+func drawCheckerboardSet(dst *image.RGBA, rect image.Rectangle, size int, light, dark color.Color) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if ((x/size)+(y/size))%2 == 0 {
+				dst.Set(x, y, light)
+			} else {
+				dst.Set(x, y, dark)
+			}
+		}
+	}
+}
+
+func drawDashedLineSet(img *image.RGBA, x0, y0, x1, y1, dash, thickness int, c1, c2 color.Color) {
+	horiz := y0 == y1
+	length, step := x1-x0, 1
+	if !horiz {
+		length = y1 - y0
+	}
+	if length < 0 {
+		length, step = -length, -1
+	}
+	for k := 0; k <= length; k++ {
+		col := c1
+		if (k/dash)%2 == 1 {
+			col = c2
+		}
+		for t := 0; t < thickness; t++ {
+			if horiz {
+				img.Set(x0+step*k, y0+t, col)
+			} else {
+				img.Set(x0+t, y0+step*k, col)
+			}
+		}
+	}
+}
+
+// drawTestImage returns a sub-image so that offsets from a non-zero origin
+// and clipping at every edge are exercised.
+func drawTestImage() *image.RGBA {
+	return image.NewRGBA(image.Rect(-10, -10, 130, 110)).SubImage(image.Rect(3, 5, 117, 96)).(*image.RGBA)
+}
+
+func TestDirectWritesMatchSet(t *testing.T) {
+	red := color.RGBA{200, 10, 20, 255}
+	faint := color.NRGBA{10, 200, 30, 100}
+	cases := []struct {
+		name       string
+		fast, slow func(img *image.RGBA)
+	}{
+		{"thick pixel",
+			func(img *image.RGBA) {
+				for i := 0; i < 40; i++ {
+					setThickPixel(img, i*3, i*2+1, i%7, red)
+				}
+			},
+			func(img *image.RGBA) {
+				for i := 0; i < 40; i++ {
+					setThickPixelSet(img, i*3, i*2+1, i%7, red)
+				}
+			}},
+		{"filled circle",
+			func(img *image.RGBA) {
+				for r := 0; r < 30; r += 3 {
+					drawFilledCircle(img, r*4, 100-r*3, r, faint)
+				}
+			},
+			func(img *image.RGBA) {
+				for r := 0; r < 30; r += 3 {
+					drawFilledCircleSet(img, r*4, 100-r*3, r, faint)
+				}
+			}},
+		{"checkerboard",
+			func(img *image.RGBA) { drawCheckerboard(img, image.Rect(-4, 7, 125, 90), 8, red, faint) },
+			func(img *image.RGBA) { drawCheckerboardSet(img, image.Rect(-4, 7, 125, 90), 8, red, faint) }},
+		{"dashed lines",
+			func(img *image.RGBA) {
+				drawDashedLine(img, 0, 20, 120, 20, 4, 2, red, faint)
+				drawDashedLine(img, 110, 40, 1, 40, 5, 1, red, faint)
+				drawDashedLine(img, 30, 0, 30, 100, 3, 3, red, faint)
+				drawDashedLine(img, 60, 95, 60, 4, 6, 2, red, faint)
+				drawDashedLine(img, 70, 70, 70, 70, 2, 2, red, faint)
+			},
+			func(img *image.RGBA) {
+				drawDashedLineSet(img, 0, 20, 120, 20, 4, 2, red, faint)
+				drawDashedLineSet(img, 110, 40, 1, 40, 5, 1, red, faint)
+				drawDashedLineSet(img, 30, 0, 30, 100, 3, 3, red, faint)
+				drawDashedLineSet(img, 60, 95, 60, 4, 6, 2, red, faint)
+				drawDashedLineSet(img, 70, 70, 70, 70, 2, 2, red, faint)
+			}},
+	}
+	for _, c := range cases {
+		got, want := drawTestImage(), drawTestImage()
+		c.fast(got)
+		c.slow(want)
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%s: output differs from drawing with Set", c.name)
+		}
+	}
+}
+
+func BenchmarkSetThickPixel(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for i := 0; i < b.N; i++ {
+		for x := 0; x < 800; x += 4 {
+			setThickPixel(img, x, x*3/4, 9, color.Black)
+		}
+	}
+}
+
+func BenchmarkDrawFilledCircle(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for i := 0; i < b.N; i++ {
+		drawFilledCircle(img, 400, 300, 250, color.Black)
+	}
+}
+
+func BenchmarkDrawCheckerboard(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	light, dark := color.RGBA{0xee, 0xee, 0xee, 0xff}, color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	for i := 0; i < b.N; i++ {
+		drawCheckerboard(img, img.Rect, 8, light, dark)
+	}
+}
+
+func BenchmarkDrawDashedRect(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	for i := 0; i < b.N; i++ {
+		drawDashedRect(img, image.Rect(100, 100, 1800, 1000), 4, 2, color.Black, color.White)
+	}
+}
+
+func TestHighlightMultipliesOnce(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(20, 0, 40, 20), image.Black, image.Point{}, draw.Src)
+	yellow := color.RGBA{255, 255, 0, 255}
+
+	var h highlighter
+	h.line(img, 2, 10, 38, 10, yellow, 6, 128)
+	// Going back over the stroke must not darken it further.
+	h.line(img, 38, 10, 10, 10, yellow, 6, 128)
+
+	if got, want := img.RGBAAt(5, 10), (color.RGBA{255, 255, 127, 255}); got != want {
+		t.Errorf("highlight over white = %v, want %v", got, want)
+	}
+	if got := img.RGBAAt(30, 10); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("highlight over black = %v, want black", got)
+	}
+	if got := img.RGBAAt(5, 2); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("pixel outside the stroke changed to %v", got)
+	}
+
+	// After the canvas grows the stroke still knows what it covered.
+	grown := image.NewRGBA(image.Rect(0, 0, 50, 30))
+	draw.Draw(grown, grown.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(grown, img.Rect.Add(image.Pt(10, 10)), img, image.Point{}, draw.Src)
+	h.moved(image.Pt(-10, -10))
+	h.line(grown, 12, 20, 48, 20, yellow, 6, 128)
+	if got := grown.RGBAAt(15, 20); got != (color.RGBA{255, 255, 127, 255}) {
+		t.Errorf("highlight after growing = %v, want a single pass", got)
+	}
+}
+
+func TestFreehandStroke(t *testing.T) {
+	defer func(smooth, taper bool) { freehandSmooth, freehandTaper = smooth, taper }(freehandSmooth, freehandTaper)
+	black := color.RGBA{0, 0, 0, 255}
+	pts := []image.Point{{10, 50}, {30, 20}, {60, 15}, {90, 40}, {110, 70}}
+	stroke := func(step time.Duration) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 120, 90))
+		at := time.Unix(0, 0)
+		f := newFreehand(pts[0], 6, at)
+		for _, p := range pts[1:] {
+			at = at.Add(step)
+			f.add(img, p, at, black)
+		}
+		f.finish(img, black)
+		return img
+	}
+	inked := func(img *image.RGBA) (n int) {
+		for i := 3; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 0 {
+				n++
+			}
+		}
+		return n
+	}
+
+	freehandSmooth, freehandTaper = true, false
+	smooth := stroke(10 * time.Millisecond)
+	// The curve passes through every point, including the last, which is
+	// only drawn by finish.
+	for _, p := range pts {
+		if smooth.RGBAAt(p.X, p.Y) != black {
+			t.Errorf("smoothed stroke misses %v", p)
+		}
+	}
+
+	freehandTaper = true
+	slow := inked(stroke(time.Second))
+	fast := inked(stroke(time.Millisecond))
+	if slow != inked(smooth) {
+		t.Errorf("slow tapered stroke inked %d pixels, want the untapered %d", slow, inked(smooth))
+	}
+	if fast >= slow*3/4 {
+		t.Errorf("fast tapered stroke inked %d pixels, want well under the slow %d", fast, slow)
+	}
+}
+
+func TestClosedShapes(t *testing.T) {
+	blue := color.RGBA{0, 0, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+
+	rect := image.Rect(10, 10, 60, 50)
+	fillRoundRect(img, rect, 12, blue)
+	drawRoundRect(img, rect, 12, red, 1)
+	for _, c := range []struct {
+		p    image.Point
+		want color.RGBA
+	}{
+		{image.Pt(35, 30), blue},         // inside
+		{image.Pt(35, 10), red},          // straight top edge
+		{image.Pt(10, 30), red},          // straight left edge
+		{image.Pt(10, 10), color.RGBA{}}, // cut off corner
+		{image.Pt(59, 49), color.RGBA{}}, // cut off corner
+		{image.Pt(60, 30), color.RGBA{}}, // outside
+	} {
+		if got := img.RGBAAt(c.p.X, c.p.Y); got != c.want {
+			t.Errorf("round rect at %v = %v, want %v", c.p, got, c.want)
+		}
+	}
+
+	img = image.NewRGBA(image.Rect(0, 0, 100, 100))
+	// A bow tie crosses itself; by the even-odd rule both halves fill.
+	pts := []image.Point{{10, 10}, {90, 90}, {90, 10}, {10, 90}}
+	fillPolygon(img, pts, color.NRGBA{0, 0, 255, 128})
+	for _, p := range []image.Point{{20, 50}, {80, 50}} {
+		if got := img.RGBAAt(p.X, p.Y); got.A != 128 {
+			t.Errorf("polygon at %v = %v, want a single translucent layer", p, got)
+		}
+	}
+	for _, p := range []image.Point{{50, 20}, {50, 80}} {
+		if got := img.RGBAAt(p.X, p.Y); got.A != 0 {
+			t.Errorf("polygon at %v = %v, want it left clear", p, got)
+		}
+	}
+}
+
+func TestPlacedTextReopens(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 120, 40))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect)}
+
+	tab.beginEdit()
+	tab.placeText("Review", image.Pt(10, 25), 1, defaultColorIndex)
+	tab.commitEdit()
+	if !bytes.Equal(tab.Image.Pix, blank.Pix) || bytes.Equal(tab.flatten(tab.Image).Pix, blank.Pix) {
+		t.Fatal("placing text drew into the pixels or is not drawn over them")
+	}
+	i := tab.textAt(image.Pt(15, 20))
+	if i < 0 || tab.textAt(image.Pt(110, 5)) >= 0 {
+		t.Fatalf("textAt = %d, want the text under the point and nothing beside it", i)
+	}
+	tab.beginEdit()
+	it := tab.takeItem(i)
+	tab.commitEdit()
+	if it.text.text != "Review" || it.text.sizeIdx != 1 || len(tab.items) != 0 {
+		t.Fatalf("taking the text left %q at size %d and %d items", it.text.text, it.text.sizeIdx, len(tab.items))
+	}
+
+	// Undo brings the text back as an item, and redo takes it away again.
+	if !tab.undo() || tab.textAt(image.Pt(15, 20)) != 0 {
+		t.Fatal("undo did not bring the text back")
+	}
+	if !tab.redo() || len(tab.items) != 0 {
+		t.Fatal("redo did not take the text away")
+	}
+
+	// Text placed past the left edge grows the canvas, and earlier text
+	// moves with it.
+	tab.placeText("Review", image.Pt(10, 25), 1, defaultColorIndex)
+	tab.placeText("x", image.Pt(-20, 25), 1, defaultColorIndex)
+	if tab.Image.Rect.Min != (image.Point{}) || tab.Image.Rect.Dx() <= 120 {
+		t.Fatalf("canvas did not grow: %v", tab.Image.Rect)
+	}
+	shift := tab.Image.Rect.Dx() - 120
+	if i := tab.textAt(image.Pt(15+shift, 20)); i < 0 || tab.items[i].text.text != "Review" {
+		t.Fatalf("text was not found after the canvas grew")
+	}
+
+	// Drawing over text leaves it editable and on top.
+	drawLine(tab.Image, 10+shift, 20, 60+shift, 20, color.Black, 3)
+	if i := tab.textAt(image.Pt(15+shift, 20)); i < 0 {
+		t.Fatal("text drawn over is no longer editable")
+	}
+}
+
+func TestCalloutReopens(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 120))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect)}
+
+	c := newCallout(image.Rect(20, 10, 20, 10), defaultColorIndex, defaultWidthIndex)
+	c.text = "Click here"
+	c.fit()
+	if c.box.Dx() <= 2*calloutPadding || c.box.Min != image.Pt(20, 10) {
+		t.Fatalf("box %v does not fit its text", c.box)
+	}
+	c.tip = image.Pt(150, 100)
+	path := c.path()
+	tipAt := -1
+	for i, p := range path {
+		if p == c.tip {
+			tipAt = i
+		}
+	}
+	if tipAt < 0 {
+		t.Fatalf("outline %v has no tail to %v", path, c.tip)
+	}
+
+	tab.placeCallout(c)
+	if got := tab.flatten(tab.Image).RGBAAt(c.tip.X, c.tip.Y); got == (color.RGBA{255, 255, 255, 255}) {
+		t.Error("the tail does not reach its tip")
+	}
+	i := tab.calloutAt(c.box.Min.Add(image.Pt(5, 5)))
+	if i < 0 || tab.calloutAt(image.Pt(190, 5)) >= 0 {
+		t.Fatalf("calloutAt = %d, want the callout under the point and nothing beside it", i)
+	}
+	got := tab.takeItem(i).callout
+	if got.text != c.text || got.tip != c.tip || !bytes.Equal(tab.Image.Pix, blank.Pix) {
+		t.Fatalf("taking the callout left %q pointing at %v and changed pixels", got.text, got.tip)
+	}
+
+	// Moved past the top edge, the canvas grows and the callout moves with
+	// the image.
+	got.box = resizeRect(got.box, cropMove, image.Pt(0, -30))
+	tab.placeCallout(got)
+	if tab.Image.Rect.Dy() <= 120 {
+		t.Fatalf("canvas did not grow: %v", tab.Image.Rect)
+	}
+	shift := tab.Image.Rect.Dy() - 120
+	if i := tab.calloutAt(image.Pt(25, shift-15)); i < 0 || tab.items[i].callout.tip != c.tip.Add(image.Pt(0, shift)) {
+		t.Fatalf("callout was not found where it moved to")
+	}
+}
+
+func TestNumberConnectors(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect), NextNumber: 1}
+	white := color.RGBA{255, 255, 255, 255}
+
+	tab.placeNumber(image.Pt(20, 50), 8, defaultColorIndex, linkArrow)
+	tab.placeNumber(image.Pt(100, 50), 8, defaultColorIndex, linkArrow)
+	if got := tab.flatten(tab.Image).RGBAAt(60, 50); got == white {
+		t.Fatal("no connector between the first two markers")
+	}
+	tab.placeNumber(image.Pt(180, 50), 8, defaultColorIndex, linkLine)
+	if tab.NextNumber != 4 {
+		t.Fatalf("NextNumber = %d, want 4", tab.NextNumber)
+	}
+
+	i := tab.numberAt(image.Pt(101, 49))
+	if i != 1 || tab.numberAt(image.Pt(60, 20)) >= 0 {
+		t.Fatalf("numberAt = %d, want the second marker and nothing off them", i)
+	}
+	tab.removeNumber(i)
+	if tab.NextNumber != 3 || len(tab.numbers) != 2 || tab.numbers[1].value != 2 {
+		t.Fatalf("markers after removal: next %d, %+v", tab.NextNumber, tab.numbers)
+	}
+	flat := tab.flatten(tab.Image)
+	if got := flat.RGBAAt(100, 44); got != white {
+		t.Error("the removed marker is still drawn")
+	}
+	if got := flat.RGBAAt(100, 50); got == white {
+		t.Error("the renumbered marker is not joined to the first")
+	}
+
+	// Markers drawn over can still be removed, and removing every marker
+	// leaves the image as it was.
+	drawLine(tab.Image, 10, 50, 30, 50, color.Black, 3)
+	tab.removeNumber(1)
+	tab.removeNumber(0)
+	if tab.NextNumber != 1 || len(tab.numbers) != 0 || tab.flatten(tab.Image) != tab.Image {
+		t.Error("removing every marker left some drawn")
+	}
+}
+
+func TestCropAspect(t *testing.T) {
+	start := image.Rect(10, 10, 10, 10)
+	tests := []struct {
+		name  string
+		start image.Rectangle
+		mode  cropAction
+		d     image.Point
+		ratio image.Point
+		want  image.Rectangle
+	}{
+		{"free", start, cropResizeBR, image.Pt(50, 20), image.Point{}, image.Rect(10, 10, 60, 30)},
+		{"wide drag", start, cropResizeBR, image.Pt(160, 10), image.Pt(16, 9), image.Rect(10, 10, 170, 100)},
+		{"tall drag", start, cropResizeBR, image.Pt(10, 40), image.Pt(1, 1), image.Rect(10, 10, 50, 50)},
+		{"past the anchor", start, cropResizeBR, image.Pt(-40, -10), image.Pt(4, 3), image.Rect(-30, -20, 10, 10)},
+		{"top left", image.Rect(0, 0, 100, 100), cropResizeTL, image.Pt(20, 60), image.Pt(1, 1), image.Rect(20, 20, 100, 100)},
+		{"edge", image.Rect(0, 0, 100, 100), cropResizeB, image.Pt(0, -50), image.Pt(1, 1), image.Rect(25, 0, 75, 50)},
+		{"move", image.Rect(0, 0, 40, 30), cropMove, image.Pt(5, 5), image.Pt(1, 1), image.Rect(5, 5, 45, 35)},
+	}
+	for _, tt := range tests {
+		if got := dragCrop(tt.start, tt.mode, tt.d, tt.ratio); got != tt.want {
+			t.Errorf("%s: dragCrop = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got, err := parseCropSize(" 1200 x 630 "); err != nil || got != image.Pt(1200, 630) {
+		t.Errorf("parseCropSize = %v, %v", got, err)
+	}
+	if got, err := parseCropAspect("3:2"); err != nil || got != image.Pt(3, 2) {
+		t.Errorf("parseCropAspect = %v, %v", got, err)
+	}
+	for _, bad := range []string{"", "1200", "0x10", "ax5"} {
+		if _, err := parseCropSize(bad); err == nil {
+			t.Errorf("parseCropSize(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestUIScaleFor(t *testing.T) {
+	for _, tt := range []struct {
+		pixelsPerPt float32
+		want        float64
+	}{
+		{96.0 / 72, 1},
+		{0, 1},
+		{110.0 / 72, 1},
+		{163.0 / 72, 1.5},
+		{192.0 / 72, 2},
+		{290.0 / 72, 3},
+		{2000.0 / 72, 4},
+	} {
+		if got := uiScaleFor(tt.pixelsPerPt); got != tt.want {
+			t.Errorf("uiScaleFor(%v) = %v, want %v", tt.pixelsPerPt, got, tt.want)
+		}
+	}
+}
+
+// changedArea returns the bounds of the pixels that differ between a and b.
+func changedArea(a, b *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			if a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestOverlayDamage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	draw.Draw(img, img.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: img, Zoom: 1}
+	frame := func(st PaintState) *image.RGBA {
+		st.Width, st.Height, st.Tabs, st.AnnotationEnabled = 400, 300, []Tab{tab}, true
+		b := image.NewRGBA(image.Rect(0, 0, st.Width, st.Height))
+		DrawScene(nil, b, st)
+		return b
+	}
+	// The first frame sets the toolbar's width, which places the image.
+	frame(PaintState{})
+	view := imageScreenRect(tab, 400, 300)
+
+	typed := PaintState{TextInputActive: true, TextInput: "ab", TextPos: image.Pt(20, 40)}
+	more := typed
+	more.TextInput = "abc"
+	want := textDamage("ab", typed.TextPos, view, 1).Union(textDamage("abc", more.TextPos, view, 1))
+	if got := changedArea(frame(typed), frame(more)); !got.In(want) {
+		t.Errorf("typing changed %v, outside %v", got, want)
+	}
+
+	c := callout{box: image.Rect(20, 20, 90, 50), tip: image.Pt(60, 110), text: "hi"}
+	moved := c
+	moved.tip = image.Pt(150, 120)
+	want = calloutDamage(c, view, 1).Union(calloutDamage(moved, view, 1))
+	if got := changedArea(frame(PaintState{Tool: ToolCallout, Callout: &c}), frame(PaintState{Tool: ToolCallout, Callout: &moved})); got.Empty() || !got.In(want) {
+		t.Errorf("moving the tail changed %v, outside %v", got, want)
+	}
+
+	pts := []image.Point{{10, 10}, {80, 30}}
+	want = polygonDamage(append(pts, image.Pt(40, 100)), view, 1)
+	if got := changedArea(frame(PaintState{Tool: ToolPolygon, Polygon: pts}), frame(PaintState{Tool: ToolPolygon, Polygon: append(pts, image.Pt(40, 100))})); got.Empty() || !got.In(want) {
+		t.Errorf("adding a vertex changed %v, outside %v", got, want)
+	}
+
+	if got := changedArea(frame(PaintState{Tool: ToolRect}), frame(PaintState{Tool: ToolRect, ColorIdx: 3})); got.Empty() || !got.In(toolbarDamage(300)) {
+		t.Errorf("picking a colour changed %v, outside the toolbar", got)
+	}
+}
+
+func TestMipSource(t *testing.T) {
+	tab := Tab{Image: image.NewRGBA(image.Rect(0, 0, 400, 200))}
+	for _, tt := range []struct {
+		size image.Point
+		want image.Rectangle
+	}{
+		{image.Pt(400, 200), image.Rect(0, 0, 400, 200)},
+		{image.Pt(201, 100), image.Rect(0, 0, 400, 200)},
+		{image.Pt(200, 100), image.Rect(0, 0, 200, 100)},
+		{image.Pt(120, 60), image.Rect(0, 0, 200, 100)},
+		{image.Pt(40, 20), image.Rect(0, 0, 100, 50)},
+	} {
+		if got := mipSource(tab, tt.size).Bounds(); got != tt.want {
+			t.Errorf("mipSource(%v) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+
+	level := mipSource(tab, image.Pt(100, 50))
+	if mipSource(tab, image.Pt(100, 50)) != level {
+		t.Error("level rebuilt without an edit")
+	}
+	tab.Image.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+	tab.markEdited()
+	if got := mipSource(tab, image.Pt(100, 50)); got == level || got.RGBAAt(0, 0).A == 0 {
+		t.Error("level not rebuilt after an edit")
+	}
+}
+
+func TestLineStyles(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	dashLine(img, 0, 10, 60, 10, red, 2, newDasher(LineDashed, 2))
+	for x, want := range map[int]bool{4: true, 12: false, 16: true} {
+		if got := img.RGBAAt(x, 10) == red; got != want {
+			t.Errorf("dashed pixel %d inked = %v, want %v", x, got, want)
+		}
+	}
+
+	img = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	dashLine(img, 0, 20, 30, 50, red, 1, newDasher(LineDotted, 1))
+	dots, prev := 0, false
+	for i := 0; i <= 30; i++ {
+		on := img.RGBAAt(i, 20+i) == red
+		if on && prev {
+			t.Fatalf("dots run together at %d", i)
+		}
+		if on {
+			dots++
+		}
+		prev = on
+	}
+	if dots < 12 {
+		t.Errorf("%d dots along the diagonal, want at least 12", dots)
+	}
+
+	if s, err := ParseLineStyle(" Dashed "); err != nil || s != LineDashed {
+		t.Errorf("ParseLineStyle = %v, %v", s, err)
+	}
+	if _, err := ParseLineStyle("wavy"); err == nil {
+		t.Error("ParseLineStyle(wavy) succeeded")
+	}
+}
+
+func TestSelection(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 300, 120))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect)}
+
+	tab.placeText("One", image.Pt(10, 30), 1, defaultColorIndex)
+	tab.placeText("Two", image.Pt(80, 60), 1, defaultColorIndex)
+	c := newCallout(image.Rect(160, 10, 160, 10), defaultColorIndex, defaultWidthIndex)
+	c.text = "Three"
+	c.fit()
+	tab.placeCallout(c)
+
+	one := tab.itemAt(image.Pt(15, 25))
+	if one == 0 || tab.itemAt(image.Pt(290, 110)) != 0 {
+		t.Fatalf("itemAt = %d, want the text under the point and nothing beside it", one)
+	}
+	tab.selectIn(image.Rect(0, 0, 150, 120))
+	if len(tab.selected) != 2 || tab.selected[0] != one {
+		t.Fatalf("rubber band selected %v, want the two texts", tab.selected)
+	}
+	tab.selectIn(tab.Image.Rect)
+	if items := tab.selectedItems(); len(items) != 3 {
+		t.Fatalf("selected %d items, want 3", len(items))
+	}
+
+	// A group move keeps the items selected and editable where they land.
+	moves := map[uint64]image.Point{}
+	for _, it := range tab.selectedItems() {
+		moves[it.id] = image.Pt(5, 10)
+	}
+	tab.moveItems(moves)
+	if tab.itemAt(image.Pt(20, 35)) != one || tab.Image.Rect != blank.Rect {
+		t.Fatal("the moved text was not found where it moved to")
+	}
+	if items := tab.selectedItems(); len(items) != 3 {
+		t.Fatalf("%d items still selected after moving, want 3", len(items))
+	}
+
+	// Copies paste into another tab as new items drawn afresh there.
+	other := Tab{Image: copyRect(blank, blank.Rect)}
+	ids := other.placeItems(tab.copyItems(), false)
+	if len(ids) != 3 || other.itemAt(image.Pt(20, 35)) != ids[0] || bytes.Equal(other.flatten(other.Image).Pix, blank.Pix) {
+		t.Fatalf("pasting placed %v", ids)
+	}
+
+	tab.deleteItems(tab.selected)
+	if !bytes.Equal(tab.flatten(tab.Image).Pix, blank.Pix) || len(tab.selectedItems()) != 0 {
+		t.Fatal("deleting the selection left pixels or items behind")
+	}
+
+	rects := []image.Rectangle{image.Rect(60, 5, 70, 15), image.Rect(0, 20, 10, 30), image.Rect(15, 0, 25, 10)}
+	for _, tc := range []struct {
+		mode alignMode
+		want []image.Point
+	}{
+		{alignLeft, []image.Point{{-60, 0}, {0, 0}, {-15, 0}}},
+		{alignTop, []image.Point{{0, -5}, {0, -20}, {0, 0}}},
+		{alignDistribute, []image.Point{{0, 0}, {0, 0}, {15, 0}}},
+	} {
+		if got := alignOffsets(rects, tc.mode); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: offsets %v, want %v", alignLabels[tc.mode], got, tc.want)
+		}
+	}
+}
+
+func TestLayers(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 60))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect)}
+	tab.placeText("One", image.Pt(10, 30), 1, defaultColorIndex)
+	tab.placeText("Two", image.Pt(100, 30), 1, defaultColorIndex)
+	placed := tab.flatten(tab.Image)
+
+	rows := tab.layerRows()
+	if len(rows) != 2 || rows[0].label != "Text: Two" || rows[1].label != "Text: One" {
+		t.Fatalf("rows %+v, want the two texts latest first", rows)
+	}
+	one := rows[1].id
+
+	tab.beginEdit()
+	tab.setHidden(one, true)
+	tab.markEdited()
+	tab.commitEdit()
+	if rows := tab.layerRows(); len(rows) != 2 || !rows[1].hidden || tab.itemAt(image.Pt(15, 25)) != 0 {
+		t.Fatalf("hidden text is still on the image or gone from the panel: %+v", rows)
+	}
+	tab.beginEdit()
+	tab.setHidden(one, false)
+	tab.markEdited()
+	tab.commitEdit()
+	if !bytes.Equal(tab.flatten(tab.Image).Pix, placed.Pix) || tab.itemAt(image.Pt(15, 25)) != one {
+		t.Fatal("showing the text did not draw it back")
+	}
+
+	// Undoing the show hides the text again.
+	tab.undo()
+	if i := tab.itemIndex(one); i < 0 || !tab.items[i].hidden {
+		t.Fatal("undo did not hide the text again")
+	}
+	tab.redo()
+
+	tab.toggleLocked(one)
+	if tab.itemAt(image.Pt(15, 25)) != 0 || tab.textAt(image.Pt(15, 25)) >= 0 || !tab.layerRows()[1].locked {
+		t.Fatal("a locked text can still be picked")
+	}
+
+	// Dragging the bottom row onto the top one restacks the text, and
+	// undoing puts it back.
+	tab.beginEdit()
+	tab.restackItem(one, len(tab.items)-1)
+	tab.markEdited()
+	tab.commitEdit()
+	if rows := tab.layerRows(); rows[0].id != one || rows[1].label != "Text: Two" {
+		t.Fatalf("rows %+v, want One on top", rows)
+	}
+	tab.undo()
+	if rows := tab.layerRows(); rows[1].id != one {
+		t.Fatalf("rows %+v, want One back beneath Two", rows)
+	}
+}
+
+func TestFlattenItems(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 60))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect), NextNumber: 1}
+	tab.placeText("One", image.Pt(10, 30), 1, defaultColorIndex)
+	tab.placeText("Two", image.Pt(100, 30), 1, defaultColorIndex)
+	tab.placeNumber(image.Pt(180, 40), 8, defaultColorIndex, linkNone)
+	tab.setHidden(tab.items[1].id, true)
+	want := tab.flatten(tab.Image)
+	items, numbers := slices.Clone(tab.items), slices.Clone(tab.numbers)
+
+	if n := tab.flattenItems(); n != 2 {
+		t.Fatalf("flattened %d items, want the marker and the visible text", n)
+	}
+	if len(tab.items) != 1 || tab.items[0].text.text != "Two" || len(tab.numbers) != 0 {
+		t.Fatalf("items %+v and numbers %+v left, want only the hidden text", tab.items, tab.numbers)
+	}
+	if !bytes.Equal(tab.Image.Pix, want.Pix) || !bytes.Equal(tab.flatten(tab.Image).Pix, want.Pix) {
+		t.Error("the flattened image differs from the items drawn over it")
+	}
+	if tab.flattenItems() != 0 {
+		t.Error("flattened a tab with only a hidden item left")
+	}
+
+	if !tab.undo() {
+		t.Fatal("flattening left nothing to undo")
+	}
+	if !bytes.Equal(tab.Image.Pix, blank.Pix) || !slices.Equal(tab.items, items) || !slices.Equal(tab.numbers, numbers) {
+		t.Errorf("undo left items %+v and numbers %+v, want %+v and %+v over the blank image", tab.items, tab.numbers, items, numbers)
+	}
+}
+
+func TestProjectKeepsItems(t *testing.T) {
+	saved, names := slices.Clone(palette), slices.Clone(paletteNames)
+	defer func() { palette, paletteNames = saved, names }()
+
+	blank := image.NewRGBA(image.Rect(0, 0, 300, 120))
+	draw.Draw(blank, blank.Rect, image.White, image.Point{}, draw.Src)
+	tab := Tab{Image: copyRect(blank, blank.Rect), Zoom: 1, NextNumber: 1}
+	tab.placeText("One", image.Pt(10, 30), 1, 2)
+	c := newCallout(image.Rect(160, 10, 160, 10), 3, defaultWidthIndex)
+	c.text = "Two"
+	c.fit()
+	tab.placeCallout(c)
+	tab.placeText("Three", image.Pt(80, 90), 1, 1)
+	tab.placeNumber(image.Pt(20, 80), 8, 2, linkArrow)
+	tab.placeNumber(image.Pt(60, 100), 8, 2, linkArrow)
+	tab.setHidden(tab.items[2].id, true)
+	tab.toggleLocked(tab.items[0].id)
+	want := tab.flatten(tab.Image)
+
+	var buf bytes.Buffer
+	p, err := projectOf([]Tab{tab}, 0, func(t Tab) (*image.RGBA, error) { return t.Image, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := project.Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	// The colours are reopened as they were drawn, whatever palette the
+	// configuration gives the editor that opens the project.
+	SetPalette([]PaletteColor{{Name: "Teal", Color: color.RGBA{0, 0x80, 0x80, 0xff}}})
+	p, err = project.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tabsFromProject(p)[0]
+	if !bytes.Equal(got.Image.Pix, blank.Pix) {
+		t.Error("the items were drawn into the saved pixels")
+	}
+	if !bytes.Equal(got.flatten(got.Image).Pix, want.Pix) {
+		t.Error("the reopened items are drawn differently")
+	}
+	if len(got.items) != 3 || got.items[1].callout.text != "Two" || !got.items[2].hidden || !slices.Equal(got.locked, tab.locked) || len(got.numbers) != 2 {
+		t.Errorf("reopened items %+v, numbers %+v, locked %v", got.items, got.numbers, got.locked)
+	}
+	got.placeText("Four", image.Pt(10, 60), 1, 0)
+	if got.items[3].id != 4 {
+		t.Errorf("next item id %d, want 4", got.items[3].id)
+	}
+}
+
+func TestSnapGuides(t *testing.T) {
+	g := newSnapGuides(image.Rect(0, 0, 200, 100), []image.Rectangle{image.Rect(50, 50, 70, 60)}, 0)
+	if got := g.moveOffset(image.Rect(3, 20, 23, 30), 5); got != image.Pt(-3, 0) {
+		t.Errorf("move offset %v, want the left edge pulled onto the canvas edge", got)
+	}
+	if got := g.moveOffset(image.Rect(48, 80, 68, 90), 5); got != image.Pt(2, 0) {
+		t.Errorf("move offset %v, want the edges lined up with the other item's", got)
+	}
+	if got := g.resize(image.Rect(10, 10, 46, 40), cropResizeBR, 5); got != image.Rect(10, 10, 50, 40) {
+		t.Errorf("resized to %v, want only the right edge snapped", got)
+	}
+
+	g.grid = gridSize
+	if got := g.moveOffset(image.Rect(103, 22, 113, 28), 5); got != image.Pt(-3, -2) {
+		t.Errorf("move offset %v, want the nearest edges pulled onto the grid", got)
+	}
+	if got := g.moveOffset(image.Rect(109, 29, 111, 31), 0); got != (image.Point{}) {
+		t.Errorf("move offset %v with no reach, want none", got)
+	}
+}
+
+func TestMoveTab(t *testing.T) {
+	titles := func(tabs []Tab) string {
+		var s string
+		for _, tb := range tabs {
+			s += tb.Title
+		}
+		return s
+	}
+	tabs := []Tab{{Title: "a"}, {Title: "b"}, {Title: "c"}, {Title: "d"}}
+	if cur := moveTab(tabs, 0, 2, 1); titles(tabs) != "bcad" || cur != 0 {
+		t.Fatalf("moving right gave %s with current %d, want bcad and 0", titles(tabs), cur)
+	}
+	if cur := moveTab(tabs, 3, 0, 3); titles(tabs) != "dbca" || cur != 0 {
+		t.Fatalf("moving the current tab left gave %s with current %d, want dbca and 0", titles(tabs), cur)
+	}
+	if cur := moveTab(tabs, 2, 1, 0); titles(tabs) != "dcba" || cur != 0 {
+		t.Fatalf("moving past nothing current gave %s with current %d, want dcba and 0", titles(tabs), cur)
+	}
+}
+
+func TestScrollTabs(t *testing.T) {
+	toolbarWidth = 48
+	if got := tabsShown(48+3*tabWidth, 3); got != 3 {
+		t.Fatalf("%d tabs shown, want all 3 that fit", got)
+	}
+	shown := tabsShown(48+5*tabWidth, 10)
+	if shown != 4 {
+		t.Fatalf("%d of 10 tabs shown, want 4 beside the scroll buttons", shown)
+	}
+	if got := scrollTabs(0, 7, 10, shown); got != 4 {
+		t.Errorf("scrolled to %d to show tab 7, want 4", got)
+	}
+	if got := scrollTabs(5, 2, 10, shown); got != 2 {
+		t.Errorf("scrolled to %d to show tab 2, want 2", got)
+	}
+	if got := scrollTabs(9, -1, 10, shown); got != 6 {
+		t.Errorf("scrolled to %d past the end, want 6", got)
+	}
+	if got := scrollTabs(-1, -1, 3, 3); got != 0 {
+		t.Errorf("scrolled to %d with every tab shown, want 0", got)
+	}
+}
@@ -0,0 +1,58 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// fillOpacities are the Rect and Circle tools' fill rows, in percent; 0
+// leaves shapes as outlines.
+var fillOpacities = []int{0, 30, 100}
+var fillIdx = 0
+
+// fillColorIdx is the palette colour shapes are filled with, picked by
+// right clicking a swatch. Until then, -1, fills take the stroke colour.
+var fillColorIdx = -1
+
+// shapeFill returns the colour to fill shapes drawn in stroke with, and
+// false when the fill is off.
+func shapeFill(stroke color.RGBA) (color.NRGBA, bool) {
+	o := fillOpacities[fillIdx]
+	if o == 0 {
+		return color.NRGBA{}, false
+	}
+	c := stroke
+	if fillColorIdx >= 0 {
+		c = paletteColorAt(fillColorIdx)
+	}
+	return fillColor(c, uint8(o*255/100)), true
+}
+
+// fillColor returns c with its opacity scaled by opacity.
+func fillColor(c color.RGBA, opacity uint8) color.NRGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(int(n.A) * int(opacity) / 255)
+	return n
+}
+
+// fillRect lays fill over rect, blending when it is translucent.
+func fillRect(img *image.RGBA, rect image.Rectangle, fill color.Color) {
+	draw.Draw(img, rect, image.NewUniform(fill), image.Point{}, draw.Over)
+}
+
+// fillEllipse lays fill over the ellipse centred at (cx, cy), a row at a
+// time so each pixel is blended once.
+func fillEllipse(img *image.RGBA, cx, cy, rx, ry int, fill color.Color) {
+	if rx <= 0 || ry <= 0 {
+		return
+	}
+	src := image.NewUniform(fill)
+	for dy := -ry; dy <= ry; dy++ {
+		t := float64(dy) / float64(ry)
+		w := int(math.Round(float64(rx) * math.Sqrt(1-t*t)))
+		row := image.Rect(cx-w, cy+dy, cx+w+1, cy+dy+1)
+		draw.Draw(img, row, src, image.Point{}, draw.Over)
+	}
+}
@@ -0,0 +1,148 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+)
+
+// freehandSmooth and freehandTaper are the Draw tool's toolbar toggles.
+// Smoothing curves the stroke through the pointer's path instead of joining
+// the points with straight lines; tapering narrows the stroke where the
+// pointer moves quickly, so flicks trail off like a pen.
+var freehandSmooth = true
+var freehandTaper = false
+
+// freehandToggles are the toggles in toolbar order.
+var freehandToggles = []struct {
+	label string
+	on    *bool
+}{
+	{"Smooth", &freehandSmooth},
+	{"Taper", &freehandTaper},
+}
+
+// Tapering narrows the stroke by taperRate of its width for each pixel per
+// millisecond the pointer moves, down to taperMin of it. Widths ease
+// towards their target so a single fast event does not pinch the stroke.
+const (
+	taperRate = 0.25
+	taperMin  = 0.35
+	taperEase = 0.4
+)
+
+// freehandPoint is a point of a stroke with the width the stroke has there.
+type freehandPoint struct {
+	p     image.Point
+	width float64
+}
+
+// freehand draws one Draw tool stroke as the pointer moves. A smoothed
+// stroke follows a Catmull-Rom spline through the points. Each piece of the
+// spline needs the point after it, so it is drawn one event behind the
+// pointer and finish draws the last piece.
+type freehand struct {
+	smooth, taper bool
+	thick         int
+	// pts are the latest points, at most four.
+	pts  []freehandPoint
+	last time.Time
+}
+
+// newFreehand starts a stroke of width thick at p, using the toolbar's
+// toggles as they are now.
+func newFreehand(p image.Point, thick int, t time.Time) freehand {
+	return freehand{
+		smooth: freehandSmooth,
+		taper:  freehandTaper,
+		thick:  thick,
+		pts:    []freehandPoint{{p, float64(thick)}},
+		last:   t,
+	}
+}
+
+// moved follows the canvas when ensureCanvasContains grows it by shift.
+func (f *freehand) moved(shift image.Point) {
+	for i := range f.pts {
+		f.pts[i].p = f.pts[i].p.Sub(shift)
+	}
+}
+
+// add extends the stroke to p, reached at t, and returns the area it drew.
+func (f *freehand) add(img *image.RGBA, p image.Point, t time.Time, col color.Color) image.Rectangle {
+	if len(f.pts) == 0 {
+		return image.Rectangle{}
+	}
+	prev := f.pts[len(f.pts)-1]
+	if p == prev.p {
+		return image.Rectangle{}
+	}
+	width := float64(f.thick)
+	if f.taper {
+		ms := max(float64(t.Sub(f.last))/float64(time.Millisecond), 1)
+		dx, dy := float64(p.X-prev.p.X), float64(p.Y-prev.p.Y)
+		speed := math.Hypot(dx, dy) / ms
+		target := float64(f.thick) * math.Max(1-taperRate*speed, taperMin)
+		width = prev.width + (target-prev.width)*taperEase
+	}
+	f.last = t
+	f.pts = append(f.pts, freehandPoint{p, width})
+	if len(f.pts) > 4 {
+		f.pts = f.pts[1:]
+	}
+	n := len(f.pts)
+	if !f.smooth {
+		return f.segment(img, f.pts[n-2], f.pts[n-2], f.pts[n-1], f.pts[n-1], col)
+	}
+	if n < 3 {
+		return image.Rectangle{}
+	}
+	before := f.pts[max(n-4, 0)]
+	return f.segment(img, before, f.pts[n-3], f.pts[n-2], f.pts[n-1], col)
+}
+
+// finish draws the piece of a smoothed stroke still waiting for a point
+// after it and returns the area drawn.
+func (f *freehand) finish(img *image.RGBA, col color.Color) image.Rectangle {
+	n := len(f.pts)
+	if !f.smooth || n < 2 {
+		return image.Rectangle{}
+	}
+	before := f.pts[max(n-3, 0)]
+	return f.segment(img, before, f.pts[n-2], f.pts[n-1], f.pts[n-1], col)
+}
+
+// segment draws the stroke from b to c, curving along the Catmull-Rom
+// spline through a, b, c and d, and returns the area drawn. Straight
+// segments pass a == b and c == d.
+func (f *freehand) segment(img *image.RGBA, a, b, c, d freehandPoint, col color.Color) image.Rectangle {
+	steps := 1
+	if f.smooth {
+		steps = max(int(math.Hypot(float64(c.p.X-b.p.X), float64(c.p.Y-b.p.Y))/2), 1)
+	}
+	from := b.p
+	area := image.Rectangle{Min: from, Max: from}
+	for i := 1; i <= steps; i++ {
+		s := float64(i) / float64(steps)
+		to := c.p
+		if i < steps {
+			to = catmullRom(a.p, b.p, c.p, d.p, s)
+		}
+		width := max(int(math.Round(b.width+(c.width-b.width)*s)), 1)
+		drawLine(img, from.X, from.Y, to.X, to.Y, col, width)
+		area = area.Union(image.Rectangle{Min: to, Max: to}.Inset(-width - 1))
+		from = to
+	}
+	return area
+}
+
+// catmullRom returns the point at s, from 0 to 1, on the uniform
+// Catmull-Rom spline from p1 to p2.
+func catmullRom(p0, p1, p2, p3 image.Point, s float64) image.Point {
+	at := func(v0, v1, v2, v3 int) int {
+		a, b, c, d := float64(v0), float64(v1), float64(v2), float64(v3)
+		return int(math.Round(0.5 * (2*b + (c-a)*s + (2*a-5*b+4*c-d)*s*s + (3*b-a-3*c+d)*s*s*s)))
+	}
+	return image.Pt(at(p0.X, p1.X, p2.X, p3.X), at(p0.Y, p1.Y, p2.Y, p3.Y))
+}
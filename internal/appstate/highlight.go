@@ -0,0 +1,104 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// highlightScale widens the selected stroke width for the highlighter, so
+// the same width rows pick marker sized strokes.
+const highlightScale = 4
+
+// highlightOpacities are the highlighter strengths offered in the toolbar,
+// in percent.
+var highlightOpacities = []int{25, 40, 55, 70}
+var highlightOpacityIdx = 1
+
+// highlightWidth returns the highlighter stroke for a width index.
+func highlightWidth(widthIdx int) int { return widthAt(widthIdx) * highlightScale }
+
+// highlightAlpha returns the opacity selected in the toolbar.
+func highlightAlpha() uint8 {
+	return uint8(highlightOpacities[highlightOpacityIdx] * 255 / 100)
+}
+
+// highlighter lays one translucent stroke over an image. It remembers the
+// pixels the stroke already covered, so a stroke crossing itself does not
+// darken where it overlaps.
+type highlighter struct {
+	covered *image.Alpha
+}
+
+// moved follows the canvas when ensureCanvasContains grows it by shift.
+func (h *highlighter) moved(shift image.Point) {
+	if h.covered != nil && shift != (image.Point{}) {
+		h.covered.Rect = h.covered.Rect.Sub(shift)
+	}
+}
+
+// line multiplies col into img along the line at the given opacity, skipping
+// pixels this stroke already marked.
+func (h *highlighter) line(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, opacity uint8) {
+	if h.covered == nil || h.covered.Rect != img.Rect {
+		covered := image.NewAlpha(img.Rect)
+		if h.covered != nil {
+			draw.Draw(covered, h.covered.Rect, h.covered, h.covered.Rect.Min, draw.Src)
+		}
+		h.covered = covered
+	}
+	c := rgbaOf(col)
+	r := thick / 2
+	dx := math.Abs(float64(x1 - x0))
+	dy := math.Abs(float64(y1 - y0))
+	sx := -1
+	if x0 < x1 {
+		sx = 1
+	}
+	sy := -1
+	if y0 < y1 {
+		sy = 1
+	}
+	err := dx - dy
+	for {
+		for y := max(y0-r, img.Rect.Min.Y); y <= min(y0+r, img.Rect.Max.Y-1); y++ {
+			for x := max(x0-r, img.Rect.Min.X); x <= min(x0+r, img.Rect.Max.X-1); x++ {
+				m := h.covered.PixOffset(x, y)
+				if h.covered.Pix[m] != 0 {
+					continue
+				}
+				h.covered.Pix[m] = 0xff
+				multiplyPixel(img.Pix[img.PixOffset(x, y):], c, opacity)
+			}
+		}
+		if x0 == x1 && y0 == y1 {
+			break
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x0 += sx
+		}
+		if e2 < dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// multiplyPixel blends c over the premultiplied pixel p in multiply mode at
+// the given opacity: light backgrounds take the colour while dark text stays
+// legible, as with a marker pen. Over transparency it paints c translucently.
+func multiplyPixel(p []byte, c color.RGBA, opacity uint8) {
+	a := uint32(opacity)
+	ab := uint32(p[3])
+	src := [3]uint32{uint32(c.R), uint32(c.G), uint32(c.B)}
+	for i := range 3 {
+		cb := uint32(p[i])
+		cs := src[i] * a / 255
+		// co = cs(1-ab) + cb(1-as) + cs*cb, all premultiplied.
+		p[i] = uint8((cs*(255-ab) + cb*(255-a) + cs*cb) / 255)
+	}
+	p[3] = uint8(a + ab*(255-a)/255)
+}
@@ -0,0 +1,258 @@
+package appstate
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"slices"
+)
+
+// maxHistoryBytes bounds the pixels the undo and redo steps of all the
+// tabs hold together. The oldest steps are dropped first; each tab's
+// latest is always kept.
+const maxHistoryBytes = 256 << 20
+
+// editSeq numbers edits in the order they are made across all the tabs,
+// so trimHistories can find the oldest.
+var editSeq uint64
+
+// editHistory is a tab's undo and redo stacks. Shapes and strokes are drawn
+// straight into the tab's pixels, so a step records the pixels an edit
+// replaced: only the rectangle that changed when the canvas kept its size,
+// or the whole image when it grew, was cropped or gained a shadow. Texts,
+// callouts and markers are kept in its editState.
+type editHistory struct {
+	undo, redo []editStep
+	// before is a copy of the image taken when an edit began, nil between
+	// edits, and state the tab fields beside it. An edit of the items
+	// alone shares before with the tab instead, since items are never
+	// drawn into the pixels; growing the canvas replaces the tab's image
+	// and leaves before as it was.
+	before *image.RGBA
+	shared bool
+	state  editState
+	// size is the bytes of pixels the steps hold.
+	size int
+}
+
+// editState is what an edit changes besides pixels. Its items and numbers
+// are copies, never the tab's own lists.
+type editState struct {
+	offset        image.Point
+	nextNumber    int
+	shadowApplied bool
+	items         []tabItem
+	numbers       []placedNumber
+}
+
+// equal reports whether s and o are the same state.
+func (s editState) equal(o editState) bool {
+	return s.offset == o.offset && s.nextNumber == o.nextNumber && s.shadowApplied == o.shadowApplied &&
+		slices.Equal(s.items, o.items) && slices.Equal(s.numbers, o.numbers)
+}
+
+// editStep restores the tab as it was on one side of an edit.
+type editStep struct {
+	editState
+	// whole replaces the tab's image with pix; otherwise pix, when the
+	// edit changed any pixels, is written back at rect.
+	whole bool
+	rect  image.Rectangle
+	pix   *image.RGBA
+	// seq is the edit's place in editSeq.
+	seq uint64
+}
+
+// bytes returns the size of the pixels s holds.
+func (s editStep) bytes() int {
+	if s.pix == nil {
+		return 0
+	}
+	return len(s.pix.Pix)
+}
+
+func (t *Tab) editState() editState {
+	return editState{
+		offset:        t.Offset,
+		nextNumber:    t.NextNumber,
+		shadowApplied: t.ShadowApplied,
+		items:         slices.Clone(t.items),
+		numbers:       slices.Clone(t.numbers),
+	}
+}
+
+// beginEdit copies the tab before an edit so commitEdit can record what
+// the edit changed.
+func (t *Tab) beginEdit() {
+	t.startEdit(copyRect(t.Image, t.Image.Bounds()), false)
+}
+
+// beginItemEdit starts an edit that changes only the tab's texts, callouts
+// and markers, and so needs no copy of its pixels.
+func (t *Tab) beginItemEdit() {
+	t.startEdit(t.Image, true)
+}
+
+func (t *Tab) startEdit(before *image.RGBA, shared bool) {
+	if t.history == nil {
+		t.history = &editHistory{}
+	}
+	h := t.history
+	h.before, h.shared = before, shared
+	h.state = t.editState()
+}
+
+// commitEdit records the edit made since beginEdit as an undo step and
+// forgets the steps that could be redone. An edit that changed nothing is
+// not recorded.
+func (t *Tab) commitEdit() {
+	h := t.history
+	if h == nil || h.before == nil {
+		return
+	}
+	before, shared := h.before, h.shared
+	h.before, h.shared = nil, false
+	step := editStep{editState: h.state}
+	switch {
+	case shared && t.Image == before:
+		if step.editState.equal(t.editState()) {
+			return
+		}
+	case shared || t.Image.Bounds() != before.Bounds():
+		step.whole, step.pix = true, before
+	default:
+		step.rect = changedRect(before, t.Image)
+		if step.rect.Empty() && step.editState.equal(t.editState()) {
+			return
+		}
+		step.pix = copyRect(before, step.rect)
+	}
+	editSeq++
+	step.seq = editSeq
+	h.undo = append(h.undo, step)
+	h.redo = nil
+	h.resize()
+}
+
+// undo restores the tab as it was before its latest edit and reports
+// whether there was one.
+func (t *Tab) undo() bool {
+	h := t.history
+	if h == nil || len(h.undo) == 0 {
+		return false
+	}
+	h.before = nil
+	s := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, t.restore(s))
+	h.resize()
+	return true
+}
+
+// redo repeats the edit undo last took back and reports whether there was
+// one.
+func (t *Tab) redo() bool {
+	h := t.history
+	if h == nil || len(h.redo) == 0 {
+		return false
+	}
+	h.before = nil
+	s := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, t.restore(s))
+	h.resize()
+	return true
+}
+
+// restore applies s to the tab and returns the step that reverses it.
+func (t *Tab) restore(s editStep) editStep {
+	rev := editStep{editState: t.editState(), whole: s.whole, rect: s.rect, seq: s.seq}
+	switch {
+	case s.whole:
+		rev.pix = t.Image
+		t.Image = s.pix
+	case s.pix != nil:
+		rev.pix = copyRect(t.Image, s.rect)
+		draw.Draw(t.Image, s.rect, s.pix, s.rect.Min, draw.Src)
+	}
+	t.Offset, t.NextNumber, t.ShadowApplied = s.offset, s.nextNumber, s.shadowApplied
+	t.items, t.numbers = slices.Clone(s.items), slices.Clone(s.numbers)
+	t.markEdited()
+	return rev
+}
+
+// resize totals the pixels the history's steps hold.
+func (h *editHistory) resize() {
+	h.size = 0
+	for _, s := range h.undo {
+		h.size += s.bytes()
+	}
+	for _, s := range h.redo {
+		h.size += s.bytes()
+	}
+}
+
+// historyBytes returns the pixels the tabs' histories hold together.
+func historyBytes(tabs []Tab) int {
+	n := 0
+	for _, t := range tabs {
+		if t.history != nil {
+			n += t.history.size
+		}
+	}
+	return n
+}
+
+// trimHistories drops the oldest undo steps of any tab while the tabs'
+// histories together hold more than maxHistoryBytes, keeping at least each
+// tab's latest.
+func trimHistories(tabs []Tab) {
+	for size := historyBytes(tabs); size > maxHistoryBytes; {
+		var oldest *editHistory
+		for _, t := range tabs {
+			h := t.history
+			if h != nil && len(h.undo) > 1 && (oldest == nil || h.undo[0].seq < oldest.undo[0].seq) {
+				oldest = h
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		size -= oldest.undo[0].bytes()
+		oldest.size -= oldest.undo[0].bytes()
+		// Clearing the step lets its pixels go before the slice regrows.
+		oldest.undo[0] = editStep{}
+		oldest.undo = oldest.undo[1:]
+	}
+}
+
+// changedRect returns the smallest rectangle holding every pixel that
+// differs between two images of the same bounds.
+func changedRect(a, b *image.RGBA) image.Rectangle {
+	bounds := a.Bounds()
+	r := image.Rectangle{}
+	n := 4 * bounds.Dx()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ra := a.Pix[a.PixOffset(bounds.Min.X, y):][:n]
+		rb := b.Pix[b.PixOffset(bounds.Min.X, y):][:n]
+		if bytes.Equal(ra, rb) {
+			continue
+		}
+		x0, x1 := 0, n
+		for ra[x0] == rb[x0] {
+			x0++
+		}
+		for ra[x1-1] == rb[x1-1] {
+			x1--
+		}
+		r = r.Union(image.Rect(bounds.Min.X+x0/4, y, bounds.Min.X+(x1+3)/4, y+1))
+	}
+	return r
+}
+
+// copyRect copies the pixels of img within r, keeping their coordinates.
+func copyRect(img *image.RGBA, r image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(r)
+	draw.Draw(out, r, img, r.Min, draw.Src)
+	return out
+}
@@ -0,0 +1,149 @@
+package appstate
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTabUndoRedo(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	tab := Tab{Image: image.NewRGBA(image.Rect(0, 0, 40, 30)), NextNumber: 1}
+	start := cropImage(tab.Image, tab.Image.Bounds())
+
+	// A line inside the canvas records only the pixels it covered.
+	tab.beginEdit()
+	drawLine(tab.Image, 5, 5, 20, 5, red, 2)
+	tab.commitEdit()
+	step := tab.history.undo[0]
+	if step.whole || step.rect.Dx() >= 40 || step.rect.Dy() >= 30 {
+		t.Fatalf("in-place edit recorded %v, whole %v", step.rect, step.whole)
+	}
+	drawn := cropImage(tab.Image, tab.Image.Bounds())
+
+	// A number past the edge grows the canvas and moves the offset.
+	tab.beginEdit()
+	shift := ensureCanvasContains(&tab, image.Rect(-10, -10, 5, 5))
+	drawNumberBox(tab.Image, 0-shift.X, 0-shift.Y, tab.NextNumber, red, 8)
+	tab.NextNumber++
+	tab.commitEdit()
+	grown, offset := tab.Image, tab.Offset
+	if grown.Bounds() == start.Bounds() {
+		t.Fatal("canvas did not grow")
+	}
+
+	// An edit that changes nothing is not a step.
+	tab.beginEdit()
+	tab.commitEdit()
+	if n := len(tab.history.undo); n != 2 {
+		t.Fatalf("%d undo steps, want 2", n)
+	}
+
+	if !tab.undo() || tab.Image.Bounds() != start.Bounds() || tab.Offset != (image.Point{}) || tab.NextNumber != 1 {
+		t.Fatalf("undoing the number left %v, offset %v, number %d", tab.Image.Bounds(), tab.Offset, tab.NextNumber)
+	}
+	if !bytes.Equal(tab.Image.Pix, drawn.Pix) {
+		t.Error("undoing the number lost the line")
+	}
+	if !tab.undo() || !bytes.Equal(tab.Image.Pix, start.Pix) {
+		t.Error("undoing the line did not restore the blank canvas")
+	}
+	if tab.undo() {
+		t.Error("undo past the first edit succeeded")
+	}
+
+	if !tab.redo() || !bytes.Equal(tab.Image.Pix, drawn.Pix) {
+		t.Error("redo did not bring back the line")
+	}
+	if !tab.redo() || tab.Image != grown || tab.Offset != offset || tab.NextNumber != 2 {
+		t.Error("redo did not bring back the grown canvas")
+	}
+	if tab.redo() {
+		t.Error("redo past the last edit succeeded")
+	}
+
+	// A new edit after an undo drops the steps that could be redone.
+	tab.undo()
+	tab.beginEdit()
+	drawLine(tab.Image, 0, 20, 30, 20, red, 1)
+	tab.commitEdit()
+	if tab.redo() {
+		t.Error("redo survived a new edit")
+	}
+}
+
+func TestItemEditHoldsNoPixels(t *testing.T) {
+	tab := Tab{Image: image.NewRGBA(image.Rect(0, 0, 40, 30)), NextNumber: 1}
+	start := tab.Image
+
+	// A marker inside the canvas shares the pixels rather than copying them.
+	tab.beginItemEdit()
+	tab.placeNumber(image.Pt(20, 15), 8, defaultColorIndex, linkNone)
+	tab.commitEdit()
+	if step := tab.history.undo[0]; step.pix != nil || tab.history.size != 0 {
+		t.Fatalf("marker step holds %d bytes", step.bytes())
+	}
+
+	// One past the edge grows the canvas, and keeps the old image to undo to.
+	tab.beginItemEdit()
+	tab.placeNumber(image.Pt(0, 0), 8, defaultColorIndex, linkNone)
+	tab.commitEdit()
+	if tab.Image == start || tab.history.undo[1].pix != start {
+		t.Fatal("growing the canvas did not keep the old image")
+	}
+
+	// Changing nothing is not a step.
+	tab.beginItemEdit()
+	tab.commitEdit()
+	if n := len(tab.history.undo); n != 2 {
+		t.Fatalf("%d undo steps, want 2", n)
+	}
+
+	if !tab.undo() || tab.Image != start || len(tab.numbers) != 1 || tab.Offset != (image.Point{}) {
+		t.Fatalf("undoing the grown marker left %v with %d markers", tab.Image.Bounds(), len(tab.numbers))
+	}
+	if !tab.undo() || tab.Image != start || len(tab.numbers) != 0 || tab.NextNumber != 1 {
+		t.Fatalf("undoing the first marker left %d markers", len(tab.numbers))
+	}
+	if !tab.redo() || len(tab.numbers) != 1 {
+		t.Error("redo did not bring back the marker")
+	}
+}
+
+func TestTrimHistoriesAcrossTabs(t *testing.T) {
+	// Each edit repaints a whole 2048x2048 canvas, 16 MB, so 20 of them
+	// spread over two tabs pass the limit they share.
+	tabs := make([]Tab, 2)
+	for i := range tabs {
+		tabs[i].Image = image.NewRGBA(image.Rect(0, 0, 2048, 2048))
+	}
+	var first []uint64
+	for e := 0; e < 20; e++ {
+		tab := &tabs[e%2]
+		tab.beginEdit()
+		for i := range tab.Image.Pix {
+			tab.Image.Pix[i] = byte(e + 1)
+		}
+		tab.commitEdit()
+		if e < 2 {
+			first = append(first, tab.history.undo[0].seq)
+		}
+	}
+	if historyBytes(tabs) <= maxHistoryBytes {
+		t.Fatalf("histories hold %d bytes, want over the limit", historyBytes(tabs))
+	}
+	trimHistories(tabs)
+	if n := historyBytes(tabs); n > maxHistoryBytes {
+		t.Fatalf("histories hold %d bytes after trimming", n)
+	}
+	// The oldest steps go first, whichever tab they belong to.
+	for i, tab := range tabs {
+		if tab.history.undo[0].seq == first[i] {
+			t.Errorf("tab %d kept its oldest step", i)
+		}
+		if len(tab.history.undo) < 8 {
+			t.Errorf("tab %d kept %d steps", i, len(tab.history.undo))
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package appstate
+
+import (
+	"image"
+	"slices"
+
+	"github.com/example/shineyshot/internal/fonts"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// tabItem is a text or callout placed on a tab. Items are kept as what they
+// are rather than drawn into the tab's pixels, and are drawn over the image,
+// the last on top, wherever it is shown or leaves the editor, so they stay
+// editable whatever is drawn or undone around them. Its id stays the same
+// while it is moved or edited, so the selection can follow it.
+type tabItem struct {
+	id        uint64
+	text      placedText
+	callout   callout
+	isCallout bool
+	// hidden items are listed in the layers panel but not drawn.
+	hidden bool
+}
+
+// move shifts the item by off.
+func (it *tabItem) move(off image.Point) {
+	if it.isCallout {
+		it.callout.moved(image.Point{}.Sub(off))
+		return
+	}
+	it.text.pos = it.text.pos.Add(off)
+}
+
+// bounds returns the area the item covers once drawn.
+func (it tabItem) bounds() image.Rectangle {
+	if it.isCallout {
+		return it.callout.bounds()
+	}
+	// A little room is kept for glyphs that overhang their advance.
+	return textBounds(it.text.text, it.text.pos, it.text.sizeIdx).Inset(-2)
+}
+
+// label names the item in the layers panel.
+func (it tabItem) label() string {
+	if it.isCallout {
+		return "Callout: " + it.callout.text
+	}
+	return "Text: " + it.text.text
+}
+
+// draw paints the item onto dst.
+func (it tabItem) draw(dst *image.RGBA) {
+	if it.isCallout {
+		it.callout.draw(dst)
+		return
+	}
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(paletteColorAt(it.text.colorIdx)), Face: textFaces()[it.text.sizeIdx]}
+	d.Dot = fixed.P(it.text.pos.X, it.text.pos.Y)
+	fonts.Draw(d, it.text.text)
+}
+
+// render draws the item alone, on a transparent image covering its bounds.
+func (it tabItem) render() *image.RGBA {
+	img := image.NewRGBA(it.bounds())
+	it.draw(img)
+	return img
+}
+
+// newItemID returns the id for the next text or callout placed on the tab.
+func (t *Tab) newItemID() uint64 {
+	t.lastItemID++
+	return t.lastItemID
+}
+
+// itemIndex returns the index of the item id in the tab's stack, or -1.
+func (t *Tab) itemIndex(id uint64) int {
+	return slices.IndexFunc(t.items, func(it tabItem) bool { return it.id == id })
+}
+
+// putItem puts it into the tab's stack at index i, or on top when i is past
+// the end, growing the canvas to fit. It keeps its id, or gets a new one
+// when it has none.
+func (t *Tab) putItem(i int, it tabItem) uint64 {
+	it.move(image.Point{}.Sub(ensureCanvasContains(t, it.bounds())))
+	if it.id == 0 {
+		it.id = t.newItemID()
+	}
+	t.items = slices.Insert(t.items, min(max(i, 0), len(t.items)), it)
+	return it.id
+}
+
+// takeItem removes the item at index i from the tab's stack, for editing,
+// and returns it.
+func (t *Tab) takeItem(i int) tabItem {
+	it := t.items[i]
+	t.items = slices.Delete(t.items, i, i+1)
+	return it
+}
+
+// itemsBounds returns the area the tab's numbered markers and visible items
+// cover.
+func (t *Tab) itemsBounds() image.Rectangle {
+	var r image.Rectangle
+	for i := range t.numbers {
+		r = r.Union(numberArea(t.numbers[i], t.linkedNumber(i)))
+	}
+	for _, it := range t.items {
+		if !it.hidden {
+			r = r.Union(it.bounds())
+		}
+	}
+	return r
+}
+
+// drawItems draws the tab's numbered markers and then its visible items,
+// bottom first, onto dst, which shares the image's coordinates.
+func (t *Tab) drawItems(dst *image.RGBA) {
+	for i := range t.numbers {
+		drawNumber(dst, t.numbers[i], t.linkedNumber(i))
+	}
+	for _, it := range t.items {
+		if !it.hidden && it.bounds().Overlaps(dst.Rect) {
+			it.draw(dst)
+		}
+	}
+}
+
+// flatten returns img, the tab's pixels, with its markers and items drawn
+// over them: a copy, unless there is nothing to draw.
+func (t *Tab) flatten(img *image.RGBA) *image.RGBA {
+	if !t.itemsBounds().Overlaps(img.Rect) {
+		return img
+	}
+	out := copyRect(img, img.Rect)
+	t.drawItems(out)
+	return out
+}
+
+// flattenItems draws the tab's markers and visible items into its pixels
+// as one edit, so they can no longer be moved or reopened, and returns how
+// many it drew. Hidden items are not drawn, so they stay in the stack.
+func (t *Tab) flattenItems() int {
+	n := len(t.numbers)
+	for _, it := range t.items {
+		if !it.hidden {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	t.beginEdit()
+	t.drawItems(t.Image)
+	t.items = slices.DeleteFunc(t.items, func(it tabItem) bool { return !it.hidden })
+	t.numbers = nil
+	t.markEdited()
+	t.commitEdit()
+	return n
+}
+
+// shiftItems follows the image with the tab's items and numbered markers
+// when its origin moves to d, as when the canvas grows or is cropped.
+func (t *Tab) shiftItems(d image.Point) {
+	for i := range t.items {
+		t.items[i].move(image.Point{}.Sub(d))
+	}
+	for i := range t.numbers {
+		t.numbers[i].center = t.numbers[i].center.Sub(d)
+	}
+}
+
+// flatCache holds the current tab flattened for the tab listener, keyed
+// like scaledCache by the image and its generation.
+var flatCache struct {
+	src        *image.RGBA
+	generation uint64
+	img        *image.RGBA
+}
+
+// flatTabImage returns t's image with its items drawn over it, reusing the
+// last copy while the tab is unchanged.
+func flatTabImage(t *Tab) *image.RGBA {
+	c := &flatCache
+	if c.src != t.Image || c.generation != t.generation || c.img == nil {
+		c.src, c.generation, c.img = t.Image, t.generation, t.flatten(t.Image)
+	}
+	return c.img
+}
@@ -0,0 +1,193 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+
+	"github.com/arran4/spacemap"
+	"github.com/example/shineyshot/internal/theme"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// The layers panel lists the current tab's texts and callouts down the right
+// of the window, top of the stack first. Everything else is drawn beneath
+// them, so the panel shows only what can be hidden, locked, picked or
+// dragged to another place in the stack.
+const (
+	layersWidth    = 200
+	layerRowHeight = 22
+)
+
+// layerThumb is the size of a row's thumbnail.
+var layerThumb = image.Pt(36, 18)
+
+// layerRow is one row of the layers panel.
+type layerRow struct {
+	id     uint64
+	label  string
+	thumb  *image.RGBA
+	hidden bool
+	locked bool
+}
+
+// A row's toggles are its visibility and lock boxes. UITypeLayerToggle
+// shapes index them as the row times two plus the toggle.
+const (
+	layerToggleShow = iota
+	layerToggleLock
+)
+
+// isLocked reports whether the item id is locked against selecting and
+// reopening.
+func (t *Tab) isLocked(id uint64) bool {
+	return slices.Contains(t.locked, id)
+}
+
+// toggleLocked locks the item id, or unlocks it, dropping it from the
+// selection as it is locked.
+func (t *Tab) toggleLocked(id uint64) {
+	if i := slices.Index(t.locked, id); i >= 0 {
+		t.locked = slices.Delete(t.locked, i, i+1)
+		return
+	}
+	t.locked = append(t.locked, id)
+	t.selected = slices.DeleteFunc(t.selected, func(s uint64) bool { return s == id })
+}
+
+// setHidden hides the item id, or shows it again, dropping it from the
+// selection as it is hidden.
+func (t *Tab) setHidden(id uint64, hidden bool) {
+	i := t.itemIndex(id)
+	if i < 0 {
+		return
+	}
+	t.items[i].hidden = hidden
+	if hidden {
+		t.selected = slices.DeleteFunc(t.selected, func(s uint64) bool { return s == id })
+	}
+}
+
+// restackItem moves the item id to index to of the tab's stack, where 0 is
+// the bottom, shifting those between up or down a place.
+func (t *Tab) restackItem(id uint64, to int) {
+	i := t.itemIndex(id)
+	if i < 0 {
+		return
+	}
+	it := t.takeItem(i)
+	t.items = slices.Insert(t.items, min(max(to, 0), len(t.items)), it)
+}
+
+// layerRows lists the tab's texts and callouts, hidden or not, top of the
+// stack first.
+func (t *Tab) layerRows() []layerRow {
+	rows := make([]layerRow, 0, len(t.items))
+	for i := len(t.items) - 1; i >= 0; i-- {
+		it := t.items[i]
+		img := it.render()
+		rows = append(rows, layerRow{id: it.id, label: it.label(), thumb: layerThumbnail(img, img.Rect), hidden: it.hidden, locked: t.isLocked(it.id)})
+	}
+	return rows
+}
+
+// layerThumbnail scales the area r of src down to fit layerThumb, keeping
+// its shape, on white.
+func layerThumbnail(src image.Image, r image.Rectangle) *image.RGBA {
+	thumb := image.NewRGBA(image.Rectangle{Max: layerThumb})
+	draw.Draw(thumb, thumb.Rect, image.White, image.Point{}, draw.Src)
+	if r.Empty() {
+		return thumb
+	}
+	w, h := layerThumb.X, r.Dy()*layerThumb.X/r.Dx()
+	if h > layerThumb.Y {
+		w, h = r.Dx()*layerThumb.Y/r.Dy(), layerThumb.Y
+	}
+	at := image.Pt((layerThumb.X-w)/2, (layerThumb.Y-h)/2)
+	xdraw.ApproxBiLinear.Scale(thumb, image.Rectangle{Min: at, Max: at.Add(image.Pt(max(w, 1), max(h, 1)))}, src, r, draw.Over, nil)
+	return thumb
+}
+
+// layersRect returns the area of the layers panel in a window width by
+// height UI pixels.
+func layersRect(width, height int) image.Rectangle {
+	return image.Rect(width-layersWidth, tabHeight, width, height-bottomHeight)
+}
+
+// layerToggleRect returns the visibility or lock box of the row at y.
+func layerToggleRect(box image.Rectangle, y, toggle int) image.Rectangle {
+	x := box.Max.X - 40 + 20*toggle
+	return image.Rect(x, y+3, x+16, y+19)
+}
+
+// drawLayers draws the layers panel, with row drag, if any, shown held as it
+// is dragged to another place in the stack. Its shapes sit above the canvas so
+// clicks on it never reach the image below.
+func drawLayers(dst *image.RGBA, width, height int, rows []layerRow, drag int, t *theme.Theme, sm spacemap.Interface) {
+	box := layersRect(width, height)
+	if sm != nil {
+		sm.Add(&UIShape{Rect: box, Type: UITypeLayer, Index: -1}, -1)
+	}
+	draw.Draw(dst, box, &image.Uniform{t.ToolbarBackground}, image.Point{}, draw.Src)
+	drawRect(dst, box, t.ButtonBorder, 1)
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(t.Foreground), Face: basicfont.Face7x13}
+	title := "Layers"
+	if len(rows) == 0 {
+		title = "No editable layers"
+	}
+	d.Dot = fixed.P(box.Min.X+6, box.Min.Y+15)
+	d.DrawString(title)
+	d.Dot = fixed.P(box.Max.X-36, box.Min.Y+15)
+	d.DrawString("V   L")
+	for i, row := range rows {
+		y := box.Min.Y + layerRowHeight*(i+1)
+		if y+layerRowHeight > box.Max.Y {
+			break
+		}
+		rect := image.Rect(box.Min.X+1, y, box.Max.X-1, y+layerRowHeight)
+		if sm != nil {
+			sm.Add(&UIShape{Rect: rect, Type: UITypeLayer, Index: i}, -2)
+		}
+		c := t.ButtonBackground
+		switch {
+		case i == drag:
+			c = t.ButtonBackgroundPress
+		case i == hoverLayer:
+			c = t.ButtonBackgroundHover
+		}
+		draw.Draw(dst, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+		thumb := image.Rect(rect.Min.X+3, y+2, rect.Min.X+3+layerThumb.X, y+2+layerThumb.Y)
+		draw.Draw(dst, thumb, row.thumb, image.Point{}, draw.Src)
+		text := t.ButtonText
+		if row.hidden {
+			text = color.RGBA{128, 128, 128, 255}
+		}
+		label := dst.SubImage(image.Rect(thumb.Max.X+4, y, box.Max.X-44, y+layerRowHeight)).(*image.RGBA)
+		ld := &font.Drawer{Dst: label, Src: image.NewUniform(text), Face: basicfont.Face7x13, Dot: fixed.P(thumb.Max.X+4, y+15)}
+		ld.DrawString(row.label)
+		for toggle, on := range []bool{!row.hidden, row.locked} {
+			tr := layerToggleRect(box, y, toggle)
+			if sm != nil {
+				sm.Add(&UIShape{Rect: tr, Type: UITypeLayerToggle, Index: i*2 + toggle}, -3)
+			}
+			c := t.ButtonBackground
+			if on {
+				c = t.ButtonBackgroundPress
+			}
+			draw.Draw(dst, tr, &image.Uniform{c}, image.Point{}, draw.Src)
+			drawRect(dst, tr, t.ButtonBorder, 1)
+			if on {
+				mark := "V"
+				if toggle == layerToggleLock {
+					mark = "L"
+				}
+				md := &font.Drawer{Dst: dst, Src: image.NewUniform(t.ButtonText), Face: basicfont.Face7x13, Dot: fixed.P(tr.Min.X+5, tr.Min.Y+12)}
+				md.DrawString(mark)
+			}
+		}
+	}
+}
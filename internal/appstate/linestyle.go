@@ -0,0 +1,184 @@
+package appstate
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// LineStyle is how the outline of a line, arrow, rectangle or circle is
+// stroked.
+type LineStyle int
+
+const (
+	LineSolid LineStyle = iota
+	LineDashed
+	LineDotted
+)
+
+// lineStyleNames are the styles' names, as -style takes them and the
+// toolbar lists them.
+var lineStyleNames = []string{"solid", "dashed", "dotted"}
+
+// lineStyleIdx is the style the editor strokes shapes with.
+var lineStyleIdx = LineSolid
+
+func (s LineStyle) String() string {
+	if s >= 0 && int(s) < len(lineStyleNames) {
+		return lineStyleNames[s]
+	}
+	return fmt.Sprintf("LineStyle(%d)", int(s))
+}
+
+// label returns the style's name as the toolbar shows it.
+func (s LineStyle) label() string {
+	name := s.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// LineStyleNames lists the names ParseLineStyle accepts.
+func LineStyleNames() []string {
+	return append([]string(nil), lineStyleNames...)
+}
+
+// ParseLineStyle reads a line style by name.
+func ParseLineStyle(s string) (LineStyle, error) {
+	for i, name := range lineStyleNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return LineStyle(i), nil
+		}
+	}
+	return LineSolid, fmt.Errorf("unknown line style %q: want %s", s, strings.Join(lineStyleNames, ", "))
+}
+
+// dasher breaks a stroke into dashes or dots, carrying its place in the
+// pattern from one segment of an outline to the next. A nil dasher strokes
+// solid.
+type dasher struct {
+	on, period float64
+	at         float64
+}
+
+// newDasher returns the dasher for a stroke thick pixels wide in style, or
+// nil for a solid one. Dashes, dots and the gaps between them grow with the
+// width so that they stay apart.
+func newDasher(style LineStyle, thick int) *dasher {
+	thick = max(thick, 1)
+	switch style {
+	case LineDashed:
+		return &dasher{on: float64(3*thick + 3), period: float64(5*thick + 5)}
+	case LineDotted:
+		return &dasher{on: 1, period: float64(2*thick + 1)}
+	}
+	return nil
+}
+
+// ink moves d step pixels along the stroke and reports whether the pixel
+// reached is drawn. Every repeat of the pattern inks at least one pixel,
+// however the steps fall, so diagonal dots are not skipped.
+func (d *dasher) ink(step float64) bool {
+	if d == nil {
+		return true
+	}
+	d.at += step
+	if d.at >= d.period {
+		d.at = math.Mod(d.at, d.period)
+		return true
+	}
+	return d.at < d.on
+}
+
+// walkLine steps from (x0, y0) to (x1, y1) with Bresenham's algorithm,
+// calling plot with each pixel and how far it lies from the one before.
+func walkLine(x0, y0, x1, y1 int, plot func(x, y int, step float64)) {
+	dx := math.Abs(float64(x1 - x0))
+	dy := math.Abs(float64(y1 - y0))
+	sx := -1
+	if x0 < x1 {
+		sx = 1
+	}
+	sy := -1
+	if y0 < y1 {
+		sy = 1
+	}
+	err := dx - dy
+	step := 0.0
+	for {
+		plot(x0, y0, step)
+		if x0 == x1 && y0 == y1 {
+			break
+		}
+		e2 := 2 * err
+		step = 0
+		if e2 > -dy {
+			err -= dy
+			x0 += sx
+			step++
+		}
+		if e2 < dx {
+			err += dx
+			y0 += sy
+			step++
+		}
+		if step == 2 {
+			step = math.Sqrt2
+		}
+	}
+}
+
+// dashLine draws a line as drawLine does, leaving the gaps d's pattern
+// makes.
+func dashLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, d *dasher) {
+	walkLine(x0, y0, x1, y1, func(x, y int, step float64) {
+		if d.ink(step) {
+			setThickPixel(img, x, y, thick, col)
+		}
+	})
+}
+
+// dashRect draws a rectangle as drawRect does, its pattern running on
+// round the corners.
+func dashRect(img *image.RGBA, rect image.Rectangle, col color.Color, thick int, d *dasher) {
+	dashLine(img, rect.Min.X, rect.Min.Y, rect.Max.X-1, rect.Min.Y, col, thick, d)
+	dashLine(img, rect.Max.X-1, rect.Min.Y, rect.Max.X-1, rect.Max.Y-1, col, thick, d)
+	dashLine(img, rect.Max.X-1, rect.Max.Y-1, rect.Min.X, rect.Max.Y-1, col, thick, d)
+	dashLine(img, rect.Min.X, rect.Max.Y-1, rect.Min.X, rect.Min.Y, col, thick, d)
+}
+
+// dashEllipse draws an ellipse as drawEllipse does, with d's pattern.
+func dashEllipse(img *image.RGBA, cx, cy, rx, ry int, col color.Color, thick int, d *dasher) {
+	steps := int(math.Ceil(2 * math.Pi * math.Sqrt(float64(rx*rx+ry*ry))))
+	if steps < 8 {
+		steps = 8
+	}
+	var prevX, prevY int
+	for i := 0; i <= steps; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(steps)
+		x := cx + int(math.Cos(angle)*float64(rx))
+		y := cy + int(math.Sin(angle)*float64(ry))
+		if i > 0 {
+			dashLine(img, prevX, prevY, x, y, col, thick, d)
+		} else if d.ink(0) {
+			setThickPixel(img, x, y, thick, col)
+		}
+		prevX, prevY = x, y
+	}
+}
+
+// dashArrow draws an arrow as drawArrow does, with d's pattern along its
+// shaft; the head stays solid.
+func dashArrow(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, d *dasher) {
+	dashLine(img, x0, y0, x1, y1, col, thick, d)
+	angle := math.Atan2(float64(y1-y0), float64(x1-x0))
+	size := float64(6 + thick*2)
+	a1 := angle + math.Pi/6
+	a2 := angle - math.Pi/6
+	x2 := x1 - int(math.Cos(a1)*size)
+	y2 := y1 - int(math.Sin(a1)*size)
+	x3 := x1 - int(math.Cos(a2)*size)
+	y3 := y1 - int(math.Sin(a2)*size)
+	drawLine(img, x1, y1, x2, y2, col, thick)
+	drawLine(img, x1, y1, x3, y3, col, thick)
+}
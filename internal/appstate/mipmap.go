@@ -0,0 +1,42 @@
+package appstate
+
+import (
+	"image"
+
+	"github.com/example/shineyshot/internal/render"
+)
+
+// mipLevels is how many times the current tab's image is halved for zoomed
+// out frames: to a half and to a quarter of its size.
+const mipLevels = 2
+
+// mipCache holds the halved levels of the current tab's image, built as
+// zoomed out frames first need them after it changes.
+var mipCache struct {
+	src        *image.RGBA
+	bounds     image.Rectangle
+	generation uint64
+	levels     []*image.RGBA
+}
+
+// mipSource returns the smallest of t's image and its halved levels that is
+// still at least size, so that shrinking it to size averages the pixels
+// nearest neighbour scaling would otherwise skip, and a 4K capture fitted to
+// the window is scaled from a level a fraction of its size.
+func mipSource(t Tab, size image.Point) *image.RGBA {
+	c := &mipCache
+	if c.src != t.Image || c.bounds != t.Image.Bounds() || c.generation != t.generation {
+		c.src, c.bounds, c.generation, c.levels = t.Image, t.Image.Bounds(), t.generation, nil
+	}
+	src := t.Image
+	for i := 0; i < mipLevels; i++ {
+		if (src.Rect.Dx()+1)/2 < size.X || (src.Rect.Dy()+1)/2 < size.Y {
+			break
+		}
+		if i == len(c.levels) {
+			c.levels = append(c.levels, render.Halve(src))
+		}
+		src = c.levels[i]
+	}
+	return src
+}
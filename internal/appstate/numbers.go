@@ -0,0 +1,129 @@
+package appstate
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+// numberLink is how the Num tool joins a marker to the one before it.
+type numberLink int
+
+const (
+	linkNone numberLink = iota
+	linkLine
+	linkArrow
+)
+
+// numberLinks label the Num tool's connector rows, indexed by numberLink.
+var numberLinks = []string{"No link", "Line", "Arrow"}
+var numberLinkIdx = linkNone
+
+// connectorWidth is the width of the lines joining markers; they are kept
+// thin so the markers stand out.
+const connectorWidth = 2
+
+// placedNumber is a numbered marker the Num tool placed. Like a tabItem it
+// is drawn over the tab's pixels rather than into them, so removing one can
+// renumber the markers after it.
+type placedNumber struct {
+	center   image.Point
+	value    int
+	size     int
+	colorIdx int
+	link     numberLink
+}
+
+// markerRect returns the area a marker of size drawn at c covers.
+func markerRect(c image.Point, size int) image.Rectangle {
+	return image.Rect(c.X-size, c.Y-size, c.X+size+1, c.Y+size+1).Inset(-1)
+}
+
+// connector returns the ends of the line from the marker prev to n, which
+// runs between their edges, and false when they are too close for one.
+func connector(prev, n placedNumber) (from, to image.Point, ok bool) {
+	dx, dy := float64(n.center.X-prev.center.X), float64(n.center.Y-prev.center.Y)
+	dist := math.Hypot(dx, dy)
+	gapFrom, gapTo := float64(prev.size+2), float64(n.size+2)
+	if dist <= gapFrom+gapTo+2 {
+		return from, to, false
+	}
+	at := func(c image.Point, t float64) image.Point {
+		return image.Pt(c.X+int(math.Round(dx/dist*t)), c.Y+int(math.Round(dy/dist*t)))
+	}
+	return at(prev.center, gapFrom), at(n.center, -gapTo), true
+}
+
+// numberArea returns the area drawing n after prev covers; prev is nil
+// for a marker without a connector.
+func numberArea(n placedNumber, prev *placedNumber) image.Rectangle {
+	r := markerRect(n.center, n.size)
+	if prev != nil {
+		if from, to, ok := connector(*prev, n); ok {
+			// Arrow heads reach 6+2*width back from the tip.
+			line := image.Rectangle{Min: from, Max: to}.Canon().Inset(-8 - 2*connectorWidth)
+			r = r.Union(line)
+		}
+	}
+	return r
+}
+
+// drawNumber draws n and its connector from prev onto img.
+func drawNumber(img *image.RGBA, n placedNumber, prev *placedNumber) {
+	col := paletteColorAt(n.colorIdx)
+	if prev != nil {
+		if from, to, ok := connector(*prev, n); ok {
+			if n.link == linkArrow {
+				drawArrow(img, from.X, from.Y, to.X, to.Y, col, connectorWidth)
+			} else {
+				drawLine(img, from.X, from.Y, to.X, to.Y, col, connectorWidth)
+			}
+		}
+	}
+	drawNumberBox(img, n.center.X, n.center.Y, n.value, col, n.size)
+}
+
+// linkedNumber returns the marker the one at index i is joined to, or nil.
+func (t *Tab) linkedNumber(i int) *placedNumber {
+	if i == 0 || t.numbers[i].link == linkNone {
+		return nil
+	}
+	return &t.numbers[i-1]
+}
+
+// placeNumber places the tab's next numbered marker at p, growing the
+// canvas to fit, and joins it to the previous marker as link says.
+func (t *Tab) placeNumber(p image.Point, size, colorIdx int, link numberLink) {
+	n := placedNumber{center: p, value: t.NextNumber, size: size, colorIdx: colorIdx, link: link}
+	var prev *placedNumber
+	if k := len(t.numbers); link != linkNone && k > 0 && t.numbers[k-1].value == n.value-1 {
+		prev = &t.numbers[k-1]
+	} else {
+		n.link = linkNone
+	}
+	n.center = n.center.Sub(ensureCanvasContains(t, numberArea(n, prev)))
+	t.numbers = append(t.numbers, n)
+	t.NextNumber++
+}
+
+// numberAt returns the index of the latest marker at p, or -1.
+func (t *Tab) numberAt(p image.Point) int {
+	for i := len(t.numbers) - 1; i >= 0; i-- {
+		n := t.numbers[i]
+		d := p.Sub(n.center)
+		if d.X*d.X+d.Y*d.Y <= n.size*n.size {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeNumber removes the marker at index i and renumbers the ones after
+// it, whose connectors then skip it.
+func (t *Tab) removeNumber(i int) {
+	t.numbers = slices.Delete(t.numbers, i, i+1)
+	for k := i; k < len(t.numbers); k++ {
+		t.numbers[k].value--
+	}
+	t.NextNumber--
+}
@@ -0,0 +1,146 @@
+package appstate
+
+import (
+	"image"
+	"slices"
+
+	"github.com/example/shineyshot/internal/project"
+)
+
+// tabsFromProject turns a saved project's tabs back into editor tabs.
+func tabsFromProject(p *project.Project) []Tab {
+	tabs := make([]Tab, 0, len(p.Tabs))
+	for _, pt := range p.Tabs {
+		t := Tab{
+			Image:         pt.Image,
+			Title:         pt.Title,
+			Offset:        pt.Offset,
+			Zoom:          pt.Zoom,
+			NextNumber:    max(pt.NextNumber, 1),
+			WidthIdx:      clampWidthIndex(pt.WidthIdx),
+			ShadowApplied: pt.ShadowApplied,
+			SavedPath:     pt.SavedPath,
+		}
+		if t.Zoom <= 0 {
+			t.Zoom = 1
+		}
+		t.items, t.locked = itemsFromProject(pt)
+		t.lastItemID = uint64(len(t.items))
+		for _, n := range pt.Numbers {
+			t.numbers = append(t.numbers, placedNumber{
+				center:   n.Center,
+				value:    n.Value,
+				size:     max(n.Size, 1),
+				colorIdx: EnsurePaletteColor(n.Color, ""),
+				link:     numberLink(min(max(n.Link, int(linkNone)), int(linkArrow))),
+			})
+		}
+		tabs = append(tabs, t)
+	}
+	return tabs
+}
+
+// itemsFromProject stacks a saved tab's texts and callouts by their places
+// in the stack, numbering them from 1, and lists those that were locked.
+func itemsFromProject(pt project.Tab) ([]tabItem, []uint64) {
+	type stacked struct {
+		project.Item
+		item tabItem
+	}
+	var all []stacked
+	for _, x := range pt.Texts {
+		all = append(all, stacked{x.Item, tabItem{
+			text:   placedText{text: x.Text, pos: x.Pos, sizeIdx: clampTextSize(x.SizeIdx), colorIdx: EnsurePaletteColor(x.Color, "")},
+			hidden: x.Hidden,
+		}})
+	}
+	for _, c := range pt.Callouts {
+		all = append(all, stacked{c.Item, tabItem{
+			callout: callout{
+				box:      c.Box.Canon(),
+				tip:      c.Tip,
+				text:     c.Text,
+				sizeIdx:  clampTextSize(c.SizeIdx),
+				colorIdx: EnsurePaletteColor(c.Color, ""),
+				widthIdx: clampWidthIndex(c.WidthIdx),
+				fill:     c.Fill,
+			},
+			isCallout: true,
+			hidden:    c.Hidden,
+		}})
+	}
+	slices.SortStableFunc(all, func(a, b stacked) int { return a.Z - b.Z })
+	var items []tabItem
+	var locked []uint64
+	for i, s := range all {
+		s.item.id = uint64(i + 1)
+		items = append(items, s.item)
+		if s.Locked {
+			locked = append(locked, s.item.id)
+		}
+	}
+	return items, locked
+}
+
+// clampTextSize keeps a saved text size index within the text sizes.
+func clampTextSize(idx int) int {
+	return min(max(idx, 0), len(textFaces())-1)
+}
+
+// projectOf collects the editor's tabs for saving, each with its texts,
+// callouts and markers kept apart from its pixels. pixels reads a tab's
+// image, which a tabStore may hold packed.
+func projectOf(tabs []Tab, current int, pixels func(Tab) (*image.RGBA, error)) (*project.Project, error) {
+	p := &project.Project{Current: current}
+	for _, t := range tabs {
+		img, err := pixels(t)
+		if err != nil {
+			return nil, err
+		}
+		pt := project.Tab{
+			Title:         t.Title,
+			Image:         img,
+			Offset:        t.Offset,
+			Zoom:          t.Zoom,
+			NextNumber:    t.NextNumber,
+			WidthIdx:      t.WidthIdx,
+			ShadowApplied: t.ShadowApplied,
+			SavedPath:     t.SavedPath,
+		}
+		for z, it := range t.items {
+			item := project.Item{Z: z, Hidden: it.hidden, Locked: t.isLocked(it.id)}
+			if it.isCallout {
+				c := it.callout
+				pt.Callouts = append(pt.Callouts, project.Callout{
+					Box:      c.box,
+					Tip:      c.tip,
+					Text:     c.text,
+					SizeIdx:  c.sizeIdx,
+					Color:    paletteColorAt(c.colorIdx),
+					WidthIdx: c.widthIdx,
+					Fill:     c.fill,
+					Item:     item,
+				})
+				continue
+			}
+			pt.Texts = append(pt.Texts, project.Text{
+				Text:    it.text.text,
+				Pos:     it.text.pos,
+				SizeIdx: it.text.sizeIdx,
+				Color:   paletteColorAt(it.text.colorIdx),
+				Item:    item,
+			})
+		}
+		for _, n := range t.numbers {
+			pt.Numbers = append(pt.Numbers, project.Number{
+				Center: n.center,
+				Value:  n.value,
+				Size:   n.size,
+				Color:  paletteColorAt(n.colorIdx),
+				Link:   int(n.link),
+			})
+		}
+		p.Tabs = append(p.Tabs, pt)
+	}
+	return p, nil
+}
@@ -0,0 +1,197 @@
+package appstate
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+// placedItem is a placed text or callout as the Select tool picks it. Its id
+// stays the same while it is moved, so the selection can follow it.
+type placedItem struct {
+	id   uint64
+	rect image.Rectangle
+}
+
+// pasteStep is how far down and right of the items they copy pasted and
+// duplicated items land, in image pixels.
+const pasteStep = 10
+
+// alignMode is one of the Select tool's alignment commands.
+type alignMode int
+
+const (
+	alignLeft alignMode = iota
+	alignTop
+	alignDistribute
+)
+
+// alignLabels are the Select tool's alignment rows, indexed by alignMode.
+var alignLabels = []string{"Align left", "Align top", "Distribute"}
+
+// minItems is how many items the command needs selected to do anything.
+func (m alignMode) minItems() int {
+	if m == alignDistribute {
+		return 3
+	}
+	return 2
+}
+
+// selectable returns the tab's visible, unlocked texts and callouts,
+// bottom first.
+func (t *Tab) selectable() []placedItem {
+	items := make([]placedItem, 0, len(t.items))
+	for _, it := range t.items {
+		if !it.hidden && !t.isLocked(it.id) {
+			items = append(items, placedItem{id: it.id, rect: it.bounds()})
+		}
+	}
+	return items
+}
+
+// itemAt returns the id of the topmost selectable item at p, or 0.
+func (t *Tab) itemAt(p image.Point) uint64 {
+	items := t.selectable()
+	for i := len(items) - 1; i >= 0; i-- {
+		if p.In(items[i].rect) {
+			return items[i].id
+		}
+	}
+	return 0
+}
+
+// selectIn adds the selectable items lying wholly within r to the
+// selection.
+func (t *Tab) selectIn(r image.Rectangle) {
+	for _, it := range t.selectable() {
+		if it.rect.In(r) && !slices.Contains(t.selected, it.id) {
+			t.selected = append(t.selected, it.id)
+		}
+	}
+}
+
+// selectedItems returns the selected items that can still be picked,
+// dropping the others from the selection.
+func (t *Tab) selectedItems() []placedItem {
+	var items []placedItem
+	for _, it := range t.selectable() {
+		if slices.Contains(t.selected, it.id) {
+			items = append(items, it)
+		}
+	}
+	t.selected = t.selected[:0]
+	for _, it := range items {
+		t.selected = append(t.selected, it.id)
+	}
+	return items
+}
+
+// toggleSelected adds id to the selection, or takes it out if it is
+// already in it.
+func (t *Tab) toggleSelected(id uint64) {
+	if i := slices.Index(t.selected, id); i >= 0 {
+		t.selected = slices.Delete(t.selected, i, i+1)
+		return
+	}
+	t.selected = append(t.selected, id)
+}
+
+// copyItems returns copies of the selected texts and callouts, bottom
+// first.
+func (t *Tab) copyItems() []tabItem {
+	t.selectedItems()
+	var items []tabItem
+	for _, it := range t.items {
+		if slices.Contains(t.selected, it.id) {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// placeItems puts items on top of the tab's stack in order, growing the
+// canvas once to fit them all, and returns the ids they are placed under:
+// their own with keepIDs, or new ones.
+func (t *Tab) placeItems(items []tabItem, keepIDs bool) []uint64 {
+	var area image.Rectangle
+	for _, it := range items {
+		area = area.Union(it.bounds())
+	}
+	shift := ensureCanvasContains(t, area)
+	ids := make([]uint64, 0, len(items))
+	for _, it := range items {
+		it.move(image.Point{}.Sub(shift))
+		it.hidden = false
+		if !keepIDs {
+			it.id = 0
+		}
+		ids = append(ids, t.putItem(len(t.items), it))
+	}
+	return ids
+}
+
+// moveItems moves the texts and callouts moves names by the offsets it
+// gives them, growing the canvas to fit. They keep their place in the
+// stack and their ids.
+func (t *Tab) moveItems(moves map[uint64]image.Point) {
+	var area image.Rectangle
+	for i := range t.items {
+		if off, ok := moves[t.items[i].id]; ok {
+			t.items[i].move(off)
+			area = area.Union(t.items[i].bounds())
+		}
+	}
+	ensureCanvasContains(t, area)
+}
+
+// deleteItems removes the texts and callouts ids names.
+func (t *Tab) deleteItems(ids []uint64) {
+	t.items = slices.DeleteFunc(t.items, func(it tabItem) bool { return slices.Contains(ids, it.id) })
+}
+
+// alignOffsets returns how far to move each of rects to carry out mode:
+// lining up their left or top edges with the furthest left or top of them,
+// or spreading them across the span they cover so the gaps between them are
+// equal, leaving the leftmost and rightmost where they are.
+func alignOffsets(rects []image.Rectangle, mode alignMode) []image.Point {
+	offs := make([]image.Point, len(rects))
+	if len(rects) < mode.minItems() {
+		return offs
+	}
+	switch mode {
+	case alignLeft:
+		left := rects[0].Min.X
+		for _, r := range rects {
+			left = min(left, r.Min.X)
+		}
+		for i, r := range rects {
+			offs[i].X = left - r.Min.X
+		}
+	case alignTop:
+		top := rects[0].Min.Y
+		for _, r := range rects {
+			top = min(top, r.Min.Y)
+		}
+		for i, r := range rects {
+			offs[i].Y = top - r.Min.Y
+		}
+	case alignDistribute:
+		order := make([]int, len(rects))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int { return rects[a].Min.X - rects[b].Min.X })
+		first, last := rects[order[0]], rects[order[len(order)-1]]
+		widths := 0
+		for _, r := range rects {
+			widths += r.Dx()
+		}
+		gap := float64(last.Max.X-first.Min.X-widths) / float64(len(rects)-1)
+		x := first.Min.X
+		for k, i := range order {
+			offs[i].X = x + int(math.Round(gap*float64(k))) - rects[i].Min.X
+			x += rects[i].Dx()
+		}
+	}
+	return offs
+}
@@ -0,0 +1,148 @@
+package appstate
+
+import (
+	"image"
+	"image/draw"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/example/shineyshot/internal/project"
+)
+
+// sessionInterval is how often the editor writes its tabs to the session
+// file while they keep changing, so a crash loses at most that much work.
+const sessionInterval = 30 * time.Second
+
+// WithSession keeps the editor's tabs in the project file at path, written
+// every sessionInterval while they change and again on quitting, so that
+// annotate -restore can reopen them.
+func WithSession(path string) Option {
+	return func(a *AppState) { a.SessionPath = path }
+}
+
+// sessionMark is what tells whether a tab changed since the session file
+// was written.
+type sessionMark struct {
+	title      string
+	generation uint64
+	bounds     image.Rectangle
+	offset     image.Point
+}
+
+// sessionWriter keeps the editor's tabs in its session file. A nil
+// sessionWriter writes nothing.
+type sessionWriter struct {
+	path string
+	// writing is closed once the write going on in the background is done,
+	// and nil when there is none.
+	writing chan struct{}
+	// marks and current note the tabs the file last took. They are set once
+	// a write succeeds, so one that fails is tried again next time; a write
+	// in the background sets them before closing writing, and they are only
+	// read once it is closed.
+	marks   []sessionMark
+	current int
+}
+
+func newSessionWriter(path string) *sessionWriter {
+	if path == "" {
+		return nil
+	}
+	return &sessionWriter{path: path, current: -1}
+}
+
+// sessionMarks returns the marks of tabs, to tell later whether they
+// changed.
+func sessionMarks(tabs []Tab) []sessionMark {
+	marks := make([]sessionMark, len(tabs))
+	for i, t := range tabs {
+		b := t.bounds
+		if t.Image != nil {
+			b = t.Image.Bounds()
+		}
+		marks[i] = sessionMark{title: t.Title, generation: t.generation, bounds: b, offset: t.Offset}
+	}
+	return marks
+}
+
+// changed reports whether tabs with marks differ from those last written.
+func (s *sessionWriter) changed(marks []sessionMark, current int) bool {
+	return current != s.current || !slices.Equal(marks, s.marks)
+}
+
+// save writes tabs to the session file if they changed. Without wait it
+// writes in the background, and leaves it for next time while the last
+// write is still going; with wait it waits for that one first. The current
+// tab's pixels are copied, as it is the one edited in place while the file
+// is written; the others are only ever replaced. Every tab's items are
+// copied, as they are moved in place.
+func (s *sessionWriter) save(tabs []Tab, current int, store *tabStore, wait bool) {
+	if s == nil {
+		return
+	}
+	if s.writing != nil {
+		if !wait {
+			select {
+			case <-s.writing:
+			default:
+				return
+			}
+		}
+		<-s.writing
+		s.writing = nil
+	}
+	marks := sessionMarks(tabs)
+	if !s.changed(marks, current) {
+		return
+	}
+	tabs = slices.Clone(tabs)
+	for i := range tabs {
+		tabs[i].items, tabs[i].numbers = slices.Clone(tabs[i].items), slices.Clone(tabs[i].numbers)
+	}
+	if img := tabs[current].Image; img != nil {
+		cp := image.NewRGBA(img.Rect)
+		draw.Draw(cp, cp.Rect, img, img.Rect.Min, draw.Src)
+		tabs[current].Image = cp
+	}
+	if wait {
+		s.written(s.write(tabs, current, store), marks, current)
+		return
+	}
+	writing := make(chan struct{})
+	s.writing = writing
+	go func() {
+		defer close(writing)
+		s.written(s.write(tabs, current, store), marks, current)
+	}()
+}
+
+// written notes the tabs with marks as in the file once their write
+// succeeded, and logs why it did not otherwise.
+func (s *sessionWriter) written(err error, marks []sessionMark, current int) {
+	if err != nil {
+		log.Printf("session: %v", err)
+		return
+	}
+	s.marks, s.current = marks, current
+}
+
+// write saves the project of tabs beside the session file and then moves it
+// into place, so a crash part way through leaves the previous one whole.
+func (s *sessionWriter) write(tabs []Tab, current int, store *tabStore) error {
+	p, err := projectOf(tabs, current, store.image)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := project.Save(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
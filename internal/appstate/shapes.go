@@ -0,0 +1,143 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"time"
+)
+
+// roundRadii are the RoundRect tool's corner radius rows, in pixels.
+var roundRadii = []int{4, 8, 16, 32}
+var roundRadiusIdx = 1
+
+// polygonCloseDistance is how near, in UI pixels, a click must be to a
+// polygon's first vertex to close it, or to its last to count as the second
+// click of a double click.
+const polygonCloseDistance = 6
+
+// polygonDoubleClick is the longest gap between the two clicks of a double
+// click that closes a polygon.
+const polygonDoubleClick = 400 * time.Millisecond
+
+// clampRadius keeps a corner radius within half of rect's shorter side.
+func clampRadius(rect image.Rectangle, radius int) int {
+	return max(min(radius, rect.Dx()/2, rect.Dy()/2), 0)
+}
+
+// drawRoundRect outlines rect with corners rounded to radius. Like
+// drawRect the outline runs along the rectangle's outermost pixels.
+func drawRoundRect(img *image.RGBA, rect image.Rectangle, radius int, col color.Color, thick int) {
+	rect = rect.Canon()
+	r := clampRadius(rect, radius)
+	if r == 0 {
+		drawRect(img, rect, col, thick)
+		return
+	}
+	pts := roundRectPoints(rect, r)
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		drawLine(img, a.X, a.Y, b.X, b.Y, col, thick)
+	}
+}
+
+// roundRectPoints returns the outline of rect with corners of radius r as
+// a closed path, the arcs stepped finely enough to look round.
+func roundRectPoints(rect image.Rectangle, r int) []image.Point {
+	steps := max(int(math.Ceil(float64(r)*math.Pi/8)), 2)
+	x0, y0 := rect.Min.X+r, rect.Min.Y+r
+	x1, y1 := rect.Max.X-1-r, rect.Max.Y-1-r
+	// Corners clockwise from the top left, each with its arc's start angle.
+	corners := []struct {
+		cx, cy int
+		start  float64
+	}{
+		{x0, y0, math.Pi},
+		{x1, y0, 1.5 * math.Pi},
+		{x1, y1, 0},
+		{x0, y1, 0.5 * math.Pi},
+	}
+	pts := make([]image.Point, 0, 4*(steps+1))
+	for _, c := range corners {
+		for i := 0; i <= steps; i++ {
+			a := c.start + 0.5*math.Pi*float64(i)/float64(steps)
+			pts = append(pts, image.Pt(
+				c.cx+int(math.Round(math.Cos(a)*float64(r))),
+				c.cy+int(math.Round(math.Sin(a)*float64(r))),
+			))
+		}
+	}
+	return pts
+}
+
+// fillRoundRect lays fill over rect with corners rounded to radius, a row
+// at a time so each pixel is blended once.
+func fillRoundRect(img *image.RGBA, rect image.Rectangle, radius int, fill color.Color) {
+	rect = rect.Canon()
+	r := clampRadius(rect, radius)
+	src := image.NewUniform(fill)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		// dy is how far into a corner's arc the row is.
+		dy := 0
+		switch {
+		case y < rect.Min.Y+r:
+			dy = rect.Min.Y + r - y
+		case y > rect.Max.Y-1-r:
+			dy = y - (rect.Max.Y - 1 - r)
+		}
+		inset := r - int(math.Round(math.Sqrt(float64(r*r-dy*dy))))
+		row := image.Rect(rect.Min.X+inset, y, rect.Max.X-inset, y+1)
+		draw.Draw(img, row, src, image.Point{}, draw.Over)
+	}
+}
+
+// drawPolygon outlines the closed polygon through pts.
+func drawPolygon(img *image.RGBA, pts []image.Point, col color.Color, thick int) {
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		drawLine(img, a.X, a.Y, b.X, b.Y, col, thick)
+	}
+}
+
+// fillPolygon lays fill over the closed polygon through pts, whose points
+// are pixels as drawLine takes them. Pixels inside by the even-odd rule are
+// filled, a span at a time so each is blended once even where the outline
+// crosses itself.
+func fillPolygon(img *image.RGBA, pts []image.Point, fill color.Color) {
+	if len(pts) < 3 {
+		return
+	}
+	b := polygonBounds(pts).Intersect(img.Bounds())
+	src := image.NewUniform(fill)
+	var xs []float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		xs = xs[:0]
+		for i := range pts {
+			p, q := pts[i], pts[(i+1)%len(pts)]
+			if (p.Y <= y) == (q.Y <= y) {
+				continue
+			}
+			xs = append(xs, float64(p.X)+float64(y-p.Y)*float64(q.X-p.X)/float64(q.Y-p.Y))
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := int(math.Ceil(xs[i]))
+			x1 := int(math.Ceil(xs[i+1]))
+			draw.Draw(img, image.Rect(x0, y, x1, y+1), src, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// polygonBounds returns the smallest rectangle holding every point of pts.
+func polygonBounds(pts []image.Point) image.Rectangle {
+	if len(pts) == 0 {
+		return image.Rectangle{}
+	}
+	r := image.Rectangle{Min: pts[0], Max: pts[0].Add(image.Pt(1, 1))}
+	for _, p := range pts[1:] {
+		r = r.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	return r
+}
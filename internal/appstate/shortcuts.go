@@ -0,0 +1,290 @@
+package appstate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/mobile/event/key"
+)
+
+// ShortcutBinding is an editor action and the keys that trigger it.
+type ShortcutBinding struct {
+	Action      string
+	Description string
+	Keys        []KeyShortcut
+}
+
+const (
+	ctrl      = key.ModControl
+	ctrlShift = key.ModControl | key.ModShift
+)
+
+// defaultShortcuts is the registry of editor actions with their built-in
+// keys. The editor binds each action it registers to the keys listed here,
+// or to those WithShortcuts gives it.
+var defaultShortcuts = []ShortcutBinding{
+	{"copy", "copy the image to the clipboard", shortcutList{{Rune: 'c', Modifiers: ctrl}}},
+	{"save", "save the image", shortcutList{{Rune: 's', Modifiers: ctrl}}},
+	{"saveproject", "save every tab as a .shineyshot project", shortcutList{{Rune: 's', Modifiers: ctrlShift}}},
+	{"exportpdf", "export the tab as a PDF", shortcutList{{Rune: 'e', Modifiers: ctrl}}},
+	{"exportpdfall", "export every tab as one PDF", shortcutList{{Rune: 'e', Modifiers: ctrlShift}}},
+	{"history", "show the clipboard history", shortcutList{{Rune: 'h', Modifiers: ctrl}}},
+	{"frame", "frame saved and copied images", shortcutList{{Rune: 'f', Modifiers: ctrl}}},
+	{"framenext", "switch to the next frame style", shortcutList{{Rune: 'f', Modifiers: ctrlShift}}},
+	{"frameurl", "edit the browser frame's address", shortcutList{{Rune: 'l', Modifiers: ctrl}}},
+	{"debug", "show the debug overlay", shortcutList{{Rune: -1, Code: key.CodeF12}}},
+	{"layers", "show or hide the layers panel", shortcutList{{Rune: -1, Code: key.CodeF7}}},
+	{"flatten", "draw the tab's texts, callouts and markers into the image", shortcutList{{Rune: -1, Code: key.CodeF7, Modifiers: key.ModShift}}},
+	{"grid", "show or hide the grid", shortcutList{{Rune: 'g', Modifiers: ctrl}}},
+	{"snap", "snap dragged texts, callouts and selections to the grid, canvas and each other", shortcutList{{Rune: 'g', Modifiers: ctrlShift}}},
+	{"annotate", "start annotating a preview", shortcutList{{Rune: 'a'}}},
+	{"shadow", "add a drop shadow", shortcutList{{Rune: '$'}}},
+	{"undo", "undo the last edit", shortcutList{{Rune: 'z', Modifiers: ctrl}}},
+	{"redo", "redo the last undone edit", shortcutList{{Rune: 'z', Modifiers: ctrlShift}, {Rune: 'y', Modifiers: ctrl}}},
+	{"capture", "capture the screen into a new tab", shortcutList{{Rune: 'n', Modifiers: ctrl}}},
+	{"timedcapture", "capture the screen after a delay", shortcutList{{Rune: 'n', Modifiers: ctrl | key.ModAlt}}},
+	{"pickwindow", "capture a window picked by clicking it", shortcutList{{Rune: 'n', Modifiers: ctrlShift}}},
+	{"recapture", "capture the tab's source again", shortcutList{{Rune: 'r', Modifiers: ctrl}}},
+	{"dup", "duplicate the tab", shortcutList{{Rune: 'u', Modifiers: ctrl}}},
+	{"paste", "paste an image into a new tab", shortcutList{{Rune: 'v', Modifiers: ctrl}}},
+	{"clipwatch", "open copied images as they arrive", shortcutList{{Rune: 'v', Modifiers: ctrlShift}}},
+	{"delete", "close the tab", shortcutList{{Rune: 'd', Modifiers: ctrl}}},
+	{"textdone", "place the text being typed", shortcutList{{Rune: -1, Code: key.CodeReturnEnter}}},
+	{"textcancel", "discard the text being typed", shortcutList{{Rune: -1, Code: key.CodeEscape}}},
+	{"crop", "crop to the selection", shortcutList{{Rune: -1, Code: key.CodeReturnEnter}}},
+	{"croptab", "crop the selection into a new tab", shortcutList{{Rune: -1, Code: key.CodeReturnEnter, Modifiers: ctrl}}},
+	{"cropsize", "type an exact crop size", shortcutList{{Rune: '#'}}},
+	{"cropcancel", "cancel the crop", shortcutList{{Rune: -1, Code: key.CodeEscape}}},
+	{"toolmove", "select the Move tool", shortcutList{{Rune: 'm'}}},
+	{"toolcrop", "select the Crop tool", shortcutList{{Rune: 'r'}}},
+	{"tooldraw", "select the Draw tool", shortcutList{{Rune: 'b'}}},
+	{"toolcircle", "select the Circle tool", shortcutList{{Rune: 'o'}}},
+	{"toolline", "select the Line tool", shortcutList{{Rune: 'l'}}},
+	{"toolarrow", "select the Arrow tool", shortcutList{{Rune: 'a'}}},
+	{"toolrect", "select the Rect tool", shortcutList{{Rune: 'x'}}},
+	{"toolroundrect", "select the Round tool, for rounded rectangles", shortcutList{{Rune: 'u'}}},
+	{"toolpolygon", "select the Poly tool", shortcutList{{Rune: 'p'}}},
+	{"toolnumber", "select the Num tool", shortcutList{{Rune: 'h'}}},
+	{"toolhighlight", "select the Marker tool", shortcutList{{Rune: 'g'}}},
+	{"tooltext", "select the Text tool", shortcutList{{Rune: 't'}}},
+	{"toolcallout", "select the Callout tool", shortcutList{{Rune: 'k'}}},
+	{"toolselect", "select the Select tool, to move and align placed text and callouts", shortcutList{{Rune: 's'}}},
+	// The Select tool's keys take over from copy, paste and delete while
+	// it has a selection or copied items to work on.
+	{"copyitems", "copy the Select tool's selection", shortcutList{{Rune: 'c', Modifiers: ctrl}}},
+	{"pasteitems", "paste the texts and callouts the Select tool copied", shortcutList{{Rune: 'v', Modifiers: ctrl}}},
+	{"dupitems", "duplicate the Select tool's selection", shortcutList{{Rune: 'd', Modifiers: ctrl}}},
+	{"zoomin", "zoom in", shortcutList{{Rune: '+'}, {Rune: '='}}},
+	{"zoomout", "zoom out", shortcutList{{Rune: '-'}}},
+	{"quit", "close the editor", shortcutList{{Rune: 'q'}}},
+}
+
+// DefaultShortcuts returns the editor actions with their built-in keys.
+func DefaultShortcuts() []ShortcutBinding {
+	return ResolveShortcuts(nil)
+}
+
+// ResolveShortcuts returns the editor actions with the keys overrides
+// gives them, by action name, and the built-in keys otherwise.
+func ResolveShortcuts(overrides map[string][]KeyShortcut) []ShortcutBinding {
+	out := make([]ShortcutBinding, len(defaultShortcuts))
+	for i, sc := range defaultShortcuts {
+		keys := sc.Keys
+		if o, ok := overrides[sc.Action]; ok {
+			keys = o
+		}
+		out[i] = ShortcutBinding{Action: sc.Action, Description: sc.Description, Keys: append([]KeyShortcut(nil), keys...)}
+	}
+	return out
+}
+
+// IsShortcutAction reports whether name is an editor action.
+func IsShortcutAction(name string) bool {
+	for _, sc := range defaultShortcuts {
+		if sc.Action == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shortcutKeys returns the keys the action is bound to.
+func (a *AppState) shortcutKeys(action string) []KeyShortcut {
+	if keys, ok := a.Shortcuts[action]; ok {
+		return keys
+	}
+	for _, sc := range defaultShortcuts {
+		if sc.Action == action {
+			return sc.Keys
+		}
+	}
+	return nil
+}
+
+// boundTo reports whether k is one of the keys action is bound to.
+func (a *AppState) boundTo(action string, k KeyShortcut) bool {
+	for _, sc := range a.shortcutKeys(action) {
+		if sc.normalized() == k.normalized() {
+			return true
+		}
+	}
+	return false
+}
+
+// normalized returns the form key presses are looked up by: a printable
+// key by its lower case character alone, since layouts differ in the key
+// code behind it, and other keys by their code. Shift is dropped when it
+// only chose the character, as for "$".
+func (k KeyShortcut) normalized() KeyShortcut {
+	if k.Rune > 0 {
+		mods := k.Modifiers
+		if !unicode.IsLetter(k.Rune) {
+			mods &^= key.ModShift
+		}
+		return KeyShortcut{Rune: unicode.ToLower(k.Rune), Modifiers: mods}
+	}
+	return KeyShortcut{Rune: -1, Code: k.Code, Modifiers: k.Modifiers}
+}
+
+// String formats the shortcut as ParseShortcuts reads it.
+func (k KeyShortcut) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  key.Modifiers
+		name string
+	}{{key.ModControl, "Ctrl"}, {key.ModAlt, "Alt"}, {key.ModShift, "Shift"}, {key.ModMeta, "Super"}} {
+		if k.Modifiers&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	name := string(unicode.ToUpper(k.Rune))
+	if k.Rune <= 0 {
+		name = strings.TrimPrefix(k.Code.String(), "Code")
+		for _, nk := range namedKeys {
+			if nk.code == k.Code {
+				name = nk.name
+				break
+			}
+		}
+	}
+	return strings.Join(append(parts, name), "+")
+}
+
+// FormatShortcuts lists keys as ParseShortcuts reads them.
+func FormatShortcuts(keys []KeyShortcut) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// namedKeys are the non-printing keys a shortcut may use. Names match
+// case-insensitively; the first name of a key is the one shown.
+var namedKeys = []struct {
+	name string
+	code key.Code
+}{
+	{"Enter", key.CodeReturnEnter},
+	{"Return", key.CodeReturnEnter},
+	{"Escape", key.CodeEscape},
+	{"Esc", key.CodeEscape},
+	{"Tab", key.CodeTab},
+	{"Space", key.CodeSpacebar},
+	{"Backspace", key.CodeDeleteBackspace},
+	{"Delete", key.CodeDeleteForward},
+	{"Insert", key.CodeInsert},
+	{"Home", key.CodeHome},
+	{"End", key.CodeEnd},
+	{"PageUp", key.CodePageUp},
+	{"PageDown", key.CodePageDown},
+	{"Left", key.CodeLeftArrow},
+	{"Right", key.CodeRightArrow},
+	{"Up", key.CodeUpArrow},
+	{"Down", key.CodeDownArrow},
+	{"F1", key.CodeF1},
+	{"F2", key.CodeF2},
+	{"F3", key.CodeF3},
+	{"F4", key.CodeF4},
+	{"F5", key.CodeF5},
+	{"F6", key.CodeF6},
+	{"F7", key.CodeF7},
+	{"F8", key.CodeF8},
+	{"F9", key.CodeF9},
+	{"F10", key.CodeF10},
+	{"F11", key.CodeF11},
+	{"F12", key.CodeF12},
+}
+
+// namedKey returns the code of the non-printing key called name.
+func namedKey(name string) (key.Code, bool) {
+	for _, nk := range namedKeys {
+		if strings.EqualFold(nk.name, name) {
+			return nk.code, true
+		}
+	}
+	return 0, false
+}
+
+// ParseShortcuts reads comma separated key combinations such as
+// "Ctrl+Shift+C, F5" for WithShortcuts. An empty string gives no keys.
+func ParseShortcuts(s string) ([]KeyShortcut, error) {
+	var out []KeyShortcut
+	for _, combo := range strings.Split(s, ",") {
+		combo = strings.TrimSpace(combo)
+		if combo == "" {
+			continue
+		}
+		sc, err := parseShortcut(combo)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+func parseShortcut(s string) (KeyShortcut, error) {
+	var sc KeyShortcut
+	parts := strings.Split(s, "+")
+	// A trailing "+" is the plus key itself, as in "+" or "Ctrl++".
+	switch {
+	case s == "+":
+		parts = []string{"+"}
+	case strings.HasSuffix(s, "++"):
+		parts = append(strings.Split(strings.TrimSuffix(s, "++"), "+"), "+")
+	}
+	for idx, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return sc, fmt.Errorf("invalid shortcut %q", s)
+		}
+		if idx == len(parts)-1 {
+			if code, ok := namedKey(part); ok {
+				sc.Rune, sc.Code = -1, code
+				break
+			}
+			r, size := utf8.DecodeRuneInString(part)
+			if size != len(part) || !unicode.IsPrint(r) {
+				return sc, fmt.Errorf("unknown key %q in shortcut %q", part, s)
+			}
+			sc.Rune = unicode.ToLower(r)
+			break
+		}
+		switch strings.ToLower(part) {
+		case "shift":
+			sc.Modifiers |= key.ModShift
+		case "ctrl", "control":
+			sc.Modifiers |= key.ModControl
+		case "alt":
+			sc.Modifiers |= key.ModAlt
+		case "super", "meta", "win":
+			sc.Modifiers |= key.ModMeta
+		default:
+			return sc, fmt.Errorf("unknown modifier %q in shortcut %q", part, s)
+		}
+	}
+	return sc, nil
+}
@@ -0,0 +1,54 @@
+package appstate
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/mobile/event/key"
+)
+
+func TestParseShortcuts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []KeyShortcut
+	}{
+		{"", nil},
+		{"Ctrl+Shift+C", []KeyShortcut{{Rune: 'c', Modifiers: key.ModControl | key.ModShift}}},
+		{"F5, ctrl+alt+Enter", []KeyShortcut{
+			{Rune: -1, Code: key.CodeF5},
+			{Rune: -1, Code: key.CodeReturnEnter, Modifiers: key.ModControl | key.ModAlt},
+		}},
+		{"Ctrl++", []KeyShortcut{{Rune: '+', Modifiers: key.ModControl}}},
+		{"$", []KeyShortcut{{Rune: '$'}}},
+		{"+, =", []KeyShortcut{{Rune: '+'}, {Rune: '='}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseShortcuts(tt.in)
+		if err != nil {
+			t.Errorf("ParseShortcuts(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseShortcuts(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"Ctrl+", "Hyper+C", "Ctrl+PrintScreenNow"} {
+		if _, err := ParseShortcuts(bad); err == nil {
+			t.Errorf("ParseShortcuts(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestShortcutStringRoundTrip(t *testing.T) {
+	for _, sc := range DefaultShortcuts() {
+		s := FormatShortcuts(sc.Keys)
+		got, err := ParseShortcuts(s)
+		if err != nil {
+			t.Errorf("%s: ParseShortcuts(%q): %v", sc.Action, s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, sc.Keys) {
+			t.Errorf("%s: ParseShortcuts(%q) = %+v, want %+v", sc.Action, s, got, sc.Keys)
+		}
+	}
+}
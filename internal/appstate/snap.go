@@ -0,0 +1,125 @@
+package appstate
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// gridSize is the spacing of the grid overlay's lines, in image pixels.
+const gridSize = 20
+
+// defaultSnapDistance is how close, in UI pixels, an edge is pulled onto a
+// guide when WithSnapDistance does not say.
+const defaultSnapDistance = 8
+
+// snapGuides are the positions in the image the edges and centres of a
+// dragged text, callout or selection snap to: the canvas edges and centre,
+// the other items' edges and centres, and with grid set the lines of a grid
+// that far apart.
+type snapGuides struct {
+	xs, ys []int
+	grid   int
+}
+
+// newSnapGuides returns the guides of a canvas bounds and of the items
+// others covers.
+func newSnapGuides(bounds image.Rectangle, others []image.Rectangle, grid int) snapGuides {
+	g := snapGuides{grid: grid}
+	for _, r := range append([]image.Rectangle{bounds}, others...) {
+		g.xs = append(g.xs, r.Min.X, (r.Min.X+r.Max.X)/2, r.Max.X)
+		g.ys = append(g.ys, r.Min.Y, (r.Min.Y+r.Max.Y)/2, r.Max.Y)
+	}
+	return g
+}
+
+// nearest returns how far v is from the closest of guides, or the grid
+// line, and whether that is within reach.
+func (g snapGuides) nearest(v int, guides []int, reach int) (int, bool) {
+	best, found := 0, false
+	try := func(to int) {
+		if d := to - v; abs(d) <= reach && (!found || abs(d) < abs(best)) {
+			best, found = d, true
+		}
+	}
+	for _, to := range guides {
+		try(to)
+	}
+	if g.grid > 0 {
+		below := v - ((v%g.grid)+g.grid)%g.grid
+		try(below)
+		try(below + g.grid)
+	}
+	return best, found
+}
+
+// moveOffset returns how far to nudge r, being moved, so that its left
+// edge, centre or right edge, whichever is nearest a guide within reach,
+// lies on it, and likewise its top, middle or bottom.
+func (g snapGuides) moveOffset(r image.Rectangle, reach int) image.Point {
+	var off image.Point
+	off.X = g.closest([]int{r.Min.X, (r.Min.X + r.Max.X) / 2, r.Max.X}, g.xs, reach)
+	off.Y = g.closest([]int{r.Min.Y, (r.Min.Y + r.Max.Y) / 2, r.Max.Y}, g.ys, reach)
+	return off
+}
+
+// closest returns the shortest distance from any of vs to a guide within
+// reach, or zero.
+func (g snapGuides) closest(vs, guides []int, reach int) int {
+	best, found := 0, false
+	for _, v := range vs {
+		if d, ok := g.nearest(v, guides, reach); ok && (!found || abs(d) < abs(best)) {
+			best, found = d, true
+		}
+	}
+	return best
+}
+
+// resize snaps the edges of r that mode drags onto guides within reach.
+func (g snapGuides) resize(r image.Rectangle, mode cropAction, reach int) image.Rectangle {
+	snap := func(v *int, guides []int) {
+		if d, ok := g.nearest(*v, guides, reach); ok {
+			*v += d
+		}
+	}
+	switch mode {
+	case cropResizeTL, cropResizeL, cropResizeBL:
+		snap(&r.Min.X, g.xs)
+	case cropResizeTR, cropResizeR, cropResizeBR:
+		snap(&r.Max.X, g.xs)
+	}
+	switch mode {
+	case cropResizeTL, cropResizeT, cropResizeTR:
+		snap(&r.Min.Y, g.ys)
+	case cropResizeBL, cropResizeB, cropResizeBR:
+		snap(&r.Max.Y, g.ys)
+	}
+	return r
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// drawGrid draws the grid overlay over the image shown at dst. Lines closer
+// than a few pixels on screen are thinned out to every second, fourth and
+// so on.
+func drawGrid(b *image.RGBA, dst image.Rectangle, zoom float64) {
+	step := gridSize
+	for float64(step)*zoom < 8 {
+		step *= 2
+	}
+	line := &image.Uniform{color.RGBA{0, 0, 0, 48}}
+	clip := dst.Intersect(b.Rect)
+	for x := step; float64(x)*zoom < float64(dst.Dx()); x += step {
+		sx := dst.Min.X + int(float64(x)*zoom)
+		draw.Draw(b, image.Rect(sx, clip.Min.Y, sx+1, clip.Max.Y).Intersect(clip), line, image.Point{}, draw.Over)
+	}
+	for y := step; float64(y)*zoom < float64(dst.Dy()); y += step {
+		sy := dst.Min.Y + int(float64(y)*zoom)
+		draw.Draw(b, image.Rect(clip.Min.X, sy, clip.Max.X, sy+1).Intersect(clip), line, image.Point{}, draw.Over)
+	}
+}
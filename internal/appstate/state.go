@@ -5,22 +5,26 @@ import (
 	"fmt"
 
 	"github.com/arran4/spacemap"
+	"github.com/example/shineyshot/internal/avif"
 	"github.com/example/shineyshot/internal/capture"
 	"github.com/example/shineyshot/internal/clipboard"
+	"github.com/example/shineyshot/internal/imagefile"
+	"github.com/example/shineyshot/internal/pdf"
+	"github.com/example/shineyshot/internal/project"
 	"github.com/example/shineyshot/internal/render"
 	"github.com/example/shineyshot/internal/theme"
-	"golang.org/x/image/font"
-	"golang.org/x/image/math/fixed"
+	"github.com/example/shineyshot/internal/webp"
 	"image"
 	"image/draw"
-	"image/png"
+	"image/jpeg"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
-	"unicode"
 
 	"golang.org/x/exp/shiny/driver"
 	"golang.org/x/exp/shiny/screen"
@@ -43,6 +47,52 @@ type AppState struct {
 	ShadowDefaults       render.ShadowOptions
 	InitialShadowApplied bool
 	InitialShadowOffset  image.Point
+	// FrameDefaults is the framing applied to saved and copied images while
+	// FrameExport is on; Ctrl+F toggles it.
+	FrameDefaults render.FrameOptions
+	FrameExport   bool
+	CaptureDelay  time.Duration
+	// Capture describes how Image was captured, when it was.
+	Capture *capture.CaptureResult
+	// MoreCaptures open as tabs after Image's.
+	MoreCaptures []capture.CaptureResult
+	// SplitMonitors opens a screen capture spanning several monitors as a
+	// tab per monitor.
+	SplitMonitors bool
+	// Project, when set and Image is nil, is a saved session whose tabs the
+	// editor opens with. ProjectPath is where Ctrl+Shift+S saves the
+	// session; empty means next to Output with the project extension.
+	Project     *project.Project
+	ProjectPath string
+	// SessionPath, when set, is the project file the editor keeps its tabs
+	// in while it runs, for reopening after a restart or crash.
+	SessionPath string
+	// StartupCapture, when set and Image is nil, is run once the window is
+	// open; its result replaces the placeholder the window starts on.
+	StartupCapture     func(capture.CaptureOptions) (capture.CaptureResult, error)
+	StartupCaptureKind string
+	// PNGMetadata writes the capture metadata into saved PNGs as text
+	// chunks.
+	PNGMetadata bool
+	// WebP, AVIF and JPEG are how saves to a .webp, .avif or .jpg path
+	// are encoded.
+	WebP webp.Options
+	AVIF avif.Options
+	JPEG jpeg.Options
+	// PrimarySelection makes copies also fill the primary selection and
+	// pastes read from it, as a middle click does.
+	PrimarySelection bool
+	// TabStorage is how the images of inactive tabs are kept.
+	TabStorage TabStorage
+	// Shortcuts replaces the keys of the named editor actions; an action
+	// mapped to no keys has none.
+	Shortcuts map[string][]KeyShortcut
+	// UIScale fixes how much the toolbar, tabs and status bar are scaled
+	// up; zero follows the display's resolution.
+	UIScale float64
+	// SnapDistance is how close, in UI pixels, a dragged edge must come to
+	// a guide to snap to it; zero keeps the default.
+	SnapDistance int
 
 	CurrentTheme *theme.Theme
 
@@ -59,6 +109,8 @@ type AppState struct {
 	tabState TabChange
 	tabFn    func(TabChange)
 
+	eventFn func(EventKind, string)
+
 	onClose   func()
 	closeOnce sync.Once
 }
@@ -94,7 +146,8 @@ func WithShadowDefaults(opts render.ShadowOptions) Option {
 	return func(a *AppState) { a.ShadowDefaults = normalizeShadowOptions(opts) }
 }
 
-// WithInitialShadowApplied marks the starting tab as already having a shadow applied.
+// WithInitialShadowApplied marks the starting tab as already having a shadow
+// applied. A startup capture has the shadow added when it arrives.
 func WithInitialShadowApplied(applied bool) Option {
 	return func(a *AppState) { a.InitialShadowApplied = applied }
 }
@@ -117,11 +170,111 @@ func normalizeShadowOptions(opts render.ShadowOptions) render.ShadowOptions {
 	return opts
 }
 
+// WithFrameDefaults sets the framing Ctrl+F applies to exported images.
+func WithFrameDefaults(opts render.FrameOptions) Option {
+	return func(a *AppState) { a.FrameDefaults = opts }
+}
+
+// WithFrameExport starts the editor with framed exports on.
+func WithFrameExport(enabled bool) Option {
+	return func(a *AppState) { a.FrameExport = enabled }
+}
+
+// WithCaptureDelay sets how long Ctrl+N waits before capturing the screen.
+func WithCaptureDelay(d time.Duration) Option {
+	return func(a *AppState) { a.CaptureDelay = d }
+}
+
+// timedCaptureDelay is how long Ctrl+Alt+N waits when no capture delay is
+// set, long enough to open a menu or hover a tooltip.
+const timedCaptureDelay = 3 * time.Second
+
+// WithStartupCapture opens the window straight away on a placeholder and
+// captures in the background with fn, which kind names in EventCapture, so
+// the window does not wait on the capture backend. The window is hidden
+// from the capture like any of shineyshot's own windows.
+func WithStartupCapture(kind string, fn func(capture.CaptureOptions) (capture.CaptureResult, error)) Option {
+	return func(a *AppState) {
+		a.StartupCapture = fn
+		a.StartupCaptureKind = kind
+	}
+}
+
+// WithCapture records how the initial image was captured.
+func WithCapture(res capture.CaptureResult) Option {
+	return func(a *AppState) { a.Capture = &res }
+}
+
+// WithMoreCaptures opens each capture in a tab after the initial image's.
+func WithMoreCaptures(res []capture.CaptureResult) Option {
+	return func(a *AppState) { a.MoreCaptures = res }
+}
+
+// WithSplitMonitors opens screen captures, at startup and from Ctrl+N, as a
+// tab per monitor.
+func WithSplitMonitors(enabled bool) Option {
+	return func(a *AppState) { a.SplitMonitors = enabled }
+}
+
+// WithPNGMetadata controls whether saves embed the capture metadata.
+func WithPNGMetadata(enabled bool) Option {
+	return func(a *AppState) { a.PNGMetadata = enabled }
+}
+
+// WithWebP sets how saves to a .webp path are encoded.
+func WithWebP(o webp.Options) Option {
+	return func(a *AppState) { a.WebP = o }
+}
+
+// WithAVIF sets how saves to a .avif path are encoded.
+func WithAVIF(o avif.Options) Option {
+	return func(a *AppState) { a.AVIF = o }
+}
+
+// WithJPEG sets how saves to a .jpg or .jpeg path are encoded.
+func WithJPEG(o jpeg.Options) Option {
+	return func(a *AppState) { a.JPEG = o }
+}
+
+// WithProject opens the editor on a saved project's tabs, saving the
+// session back to path.
+func WithProject(path string, p *project.Project) Option {
+	return func(a *AppState) { a.Project, a.ProjectPath = p, path }
+}
+
+// WithPrimarySelection makes copy and paste use the primary selection too.
+func WithPrimarySelection(enabled bool) Option {
+	return func(a *AppState) { a.PrimarySelection = enabled }
+}
+
 // WithSettingsListener registers a callback for when drawing settings change.
 func WithSettingsListener(fn func(colorIdx, widthIdx int)) Option {
 	return func(a *AppState) { a.settingsFn = fn }
 }
 
+// WithEventListener registers a callback for captures, saves and copies
+// performed from within the UI.
+func WithEventListener(fn func(kind EventKind, detail string)) Option {
+	return func(a *AppState) { a.eventFn = fn }
+}
+
+// WithShortcuts replaces the keys of editor actions, by action name.
+func WithShortcuts(shortcuts map[string][]KeyShortcut) Option {
+	return func(a *AppState) { a.Shortcuts = shortcuts }
+}
+
+// WithUIScale scales the editor's toolbar, tabs and status bar by scale
+// instead of by the display's resolution; zero keeps following it.
+func WithUIScale(scale float64) Option {
+	return func(a *AppState) { a.UIScale = scale }
+}
+
+// WithSnapDistance sets how close, in UI pixels, dragged edges snap to
+// guides; zero keeps the default.
+func WithSnapDistance(distance int) Option {
+	return func(a *AppState) { a.SnapDistance = distance }
+}
+
 // WithOnClose registers a callback invoked when the window closes.
 func WithOnClose(fn func()) Option { return func(a *AppState) { a.onClose = fn } }
 
@@ -136,6 +289,9 @@ func New(opts ...Option) *AppState {
 		Mode:           ModeAnnotate,
 		updateCh:       make(chan struct{}, 1),
 		ShadowDefaults: render.DefaultShadowOptions(),
+		WebP:           webp.Options{Quality: webp.DefaultQuality},
+		AVIF:           avif.Options{Quality: avif.DefaultQuality, Speed: avif.DefaultSpeed},
+		JPEG:           jpeg.Options{Quality: imagefile.DefaultJPEGQuality},
 	}
 	for _, o := range opts {
 		o(a)
@@ -146,6 +302,9 @@ func New(opts ...Option) *AppState {
 	a.ColorIdx = clampColorIndex(a.ColorIdx)
 	a.WidthIdx = clampWidthIndex(a.WidthIdx)
 	a.ShadowDefaults = normalizeShadowOptions(a.ShadowDefaults)
+	if a.FrameDefaults == (render.FrameOptions{}) {
+		a.FrameDefaults, _ = render.LookupFramePreset(render.DefaultFramePreset)
+	}
 	if a.Title == "" {
 		a.Title = ProgramTitle
 	}
@@ -154,10 +313,74 @@ func New(opts ...Option) *AppState {
 	return a
 }
 
+// EventKind identifies a user-visible action reported to the event listener.
+type EventKind string
+
+const (
+	// EventCapture fires after a new screenshot is captured into a tab.
+	EventCapture EventKind = "capture"
+	// EventSave fires after the active tab is written to disk.
+	EventSave EventKind = "save"
+	// EventCopy fires after the active tab is copied to the clipboard.
+	EventCopy EventKind = "copy"
+)
+
+func (a *AppState) emitEvent(kind EventKind, detail string) {
+	if a.eventFn != nil {
+		a.eventFn(kind, detail)
+	}
+}
+
 type controlEvent struct {
 	ColorIdx *int
 	WidthIdx *int
 	Tab      *tabControl
+	Capture  *captureControl
+	Watch    *watchControl
+	// ImageChanged reports that the image was edited from outside the
+	// editor, through NotifyImageChanged.
+	ImageChanged bool
+	// Packed carries an inactive tab's image packed in the background.
+	Packed *packedTab
+	// SessionDue asks for the tabs to be written to the session file if
+	// they changed.
+	SessionDue bool
+	// Flatten asks for the current tab's texts, callouts and markers to be
+	// drawn into its pixels.
+	Flatten bool
+}
+
+// damageEvent asks for a repaint of rect, in window coordinates, when
+// nothing outside it changed since the previous frame.
+type damageEvent struct {
+	rect image.Rectangle
+}
+
+// watchControl carries an image the clipboard watch read, or its failure,
+// back to the event loop.
+type watchControl struct {
+	ctx    context.Context
+	copied clipboard.Copied
+}
+
+// captureControl carries a screen capture running off the event loop back
+// to it: the countdown while it waits, then the result.
+type captureControl struct {
+	remaining time.Duration
+	done      bool
+	kind      string
+	res       capture.CaptureResult
+	err       error
+}
+
+// historyRows labels the clipboard history popup's rows, numbered as the
+// keys that copy them.
+func historyRows(entries []clipboard.HistoryEntry) []string {
+	rows := make([]string, len(entries))
+	for i, e := range entries {
+		rows[i] = fmt.Sprintf("%2d  %s  %s", i+1, e.Time.Format("Jan 2 15:04"), e)
+	}
+	return rows
 }
 
 type tabAction int
@@ -165,11 +388,17 @@ type tabAction int
 const (
 	tabActionActivate tabAction = iota
 	tabActionClose
+	tabActionRename
+	tabActionMove
 )
 
 type tabControl struct {
 	action tabAction
 	index  int
+	// to is where tabActionMove moves the tab to.
+	to int
+	// title is the tab's new title for tabActionRename.
+	title string
 }
 
 // NotifyImageChanged requests a repaint of the UI when the image mutates.
@@ -287,6 +516,104 @@ func (a *AppState) CloseTab(index int) error {
 	return nil
 }
 
+// RenameTab requests that the UI retitles the specified tab. When index is
+// negative the currently active tab is renamed.
+func (a *AppState) RenameTab(index int, title string) error {
+	tabs := a.TabsState()
+	if len(tabs.Tabs) == 0 {
+		return fmt.Errorf("no tabs available")
+	}
+	if index < 0 {
+		index = tabs.Current
+	}
+	if index < 0 || index >= len(tabs.Tabs) {
+		return fmt.Errorf("tab %d does not exist", index+1)
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("tab title is empty")
+	}
+	a.settingsMu.Lock()
+	sender := a.sendControl
+	a.settingsMu.Unlock()
+	if sender == nil {
+		return fmt.Errorf("annotation window not open")
+	}
+	sender(controlEvent{Tab: &tabControl{action: tabActionRename, index: index, title: title}})
+	return nil
+}
+
+// MoveTab requests that the UI moves the tab at from to the position to,
+// shifting the tabs between them along by one.
+func (a *AppState) MoveTab(from, to int) error {
+	tabs := a.TabsState()
+	if len(tabs.Tabs) == 0 {
+		return fmt.Errorf("no tabs available")
+	}
+	for _, idx := range []int{from, to} {
+		if idx < 0 || idx >= len(tabs.Tabs) {
+			return fmt.Errorf("tab %d does not exist", idx+1)
+		}
+	}
+	a.settingsMu.Lock()
+	sender := a.sendControl
+	a.settingsMu.Unlock()
+	if sender == nil {
+		return fmt.Errorf("annotation window not open")
+	}
+	sender(controlEvent{Tab: &tabControl{action: tabActionMove, index: from, to: to}})
+	return nil
+}
+
+// Flatten requests that the UI draws the current tab's texts, callouts and
+// numbered markers into its pixels, as one edit that can be undone.
+func (a *AppState) Flatten() error {
+	a.settingsMu.Lock()
+	sender := a.sendControl
+	a.settingsMu.Unlock()
+	if sender == nil {
+		return fmt.Errorf("annotation window not open")
+	}
+	sender(controlEvent{Flatten: true})
+	return nil
+}
+
+// closeTab removes tabs[idx] and returns the tabs left and the index of the
+// tab that is current after it.
+func closeTab(tabs []Tab, idx, current int) ([]Tab, int) {
+	tabs = append(tabs[:idx], tabs[idx+1:]...)
+	if current >= len(tabs) {
+		current = len(tabs) - 1
+	} else if idx <= current && current > 0 {
+		current--
+	}
+	return tabs, current
+}
+
+// moveTab moves tabs[from] to position to, shifting the tabs between them
+// along by one, and returns where the tab at current ends up.
+func moveTab(tabs []Tab, from, to, current int) int {
+	if from == to {
+		return current
+	}
+	t := tabs[from]
+	if from < to {
+		copy(tabs[from:to], tabs[from+1:to+1])
+	} else {
+		copy(tabs[to+1:from+1], tabs[to:from])
+	}
+	tabs[to] = t
+	switch {
+	case current == from:
+		return to
+	case from < current && current <= to:
+		return current - 1
+	case to <= current && current < from:
+		return current + 1
+	}
+	return current
+}
+
 func copyTabsState(state TabsState) TabsState {
 	dup := state
 	dup.Tabs = append([]TabSummary(nil), state.Tabs...)
@@ -315,8 +642,9 @@ func (a *AppState) updateTabsState(tabs []Tab, current int) {
 	}
 	if current >= 0 && current < len(tabs) {
 		change.Current = current
-		change.Image = tabs[current].Image
+		change.Image = flatTabImage(&tabs[current])
 		change.WidthIdx = tabs[current].WidthIdx
+		change.Capture = tabs[current].Capture
 	}
 	stored := copyTabChange(change)
 	a.tabMu.Lock()
@@ -342,11 +670,32 @@ func (a *AppState) notifyClose() {
 	})
 }
 
+// tagOwnWindow marks the editor window so captures can hide it. The window
+// can take a moment to reach the X server after NewWindow returns.
+func tagOwnWindow(title string) {
+	for attempt := 0; attempt < 20; attempt++ {
+		if capture.TagOwnWindow(title) == nil {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
 // Run executes the UI loop using shiny's driver.
 func (a *AppState) Run() { driver.Main(a.Main) }
 
 func (a *AppState) Main(s screen.Screen) {
 	rgba := a.Image
+	if rgba == nil && a.Project != nil {
+		rgba = a.Project.Tabs[a.Project.Current].Image
+	}
+	// placeholder is set while the first tab only waits for the startup
+	// capture.
+	placeholder := false
+	if rgba == nil && a.StartupCapture != nil {
+		rgba = capturingPlaceholder(image.Pt(960, 540))
+		placeholder = true
+	}
 	output := a.Output
 	colorIdx := clampColorIndex(a.ColorIdx)
 	widthIdx := clampWidthIndex(a.WidthIdx)
@@ -366,13 +715,23 @@ func (a *AppState) Main(s screen.Screen) {
 		toolbarWidth = w
 	}
 
-	width := rgba.Bounds().Dx() + toolbarWidth
-	height := rgba.Bounds().Dy() + tabHeight + bottomHeight
+	if a.UIScale > 0 {
+		uiScale = a.UIScale
+	}
+	width := rgba.Bounds().Dx() + ui(toolbarWidth)
+	height := rgba.Bounds().Dy() + ui(tabHeight+bottomHeight)
 	w, err := s.NewWindow(&screen.NewWindowOptions{Width: width, Height: height, Title: windowTitle})
 	if err != nil {
 		log.Fatalf("new window: %v", err)
 	}
 	defer w.Release()
+	// tagged is closed once the window is tagged as shineyshot's, or
+	// tagging gave up, so the startup capture can hide it.
+	tagged := make(chan struct{})
+	go func() {
+		tagOwnWindow(windowTitle)
+		close(tagged)
+	}()
 
 	defer a.notifyClose()
 
@@ -382,7 +741,7 @@ func (a *AppState) Main(s screen.Screen) {
 			for {
 				select {
 				case <-a.updateCh:
-					w.Send(paint.Event{})
+					w.Send(controlEvent{ImageChanged: true})
 				case <-done:
 					return
 				}
@@ -392,6 +751,8 @@ func (a *AppState) Main(s screen.Screen) {
 	}
 
 	a.setControlSender(func(ev controlEvent) { w.Send(ev) })
+	store := newTabStore(a.TabStorage, func(ev controlEvent) { w.Send(ev) })
+	defer store.close()
 
 	tabs := []Tab{{
 		Image:         rgba,
@@ -401,14 +762,55 @@ func (a *AppState) Main(s screen.Screen) {
 		NextNumber:    1,
 		WidthIdx:      widthIdx,
 		ShadowApplied: a.InitialShadowApplied,
+		Capture:       a.Capture,
 	}}
 	current := 0
+	if a.Image == nil && a.Project != nil {
+		tabs, current = tabsFromProject(a.Project), a.Project.Current
+	}
+	projectPath := a.ProjectPath
+
+	// The session is written once more on quitting, before the tab store
+	// lets go of the inactive tabs' pixels. A window still waiting for its
+	// startup capture has nothing worth keeping.
+	session := newSessionWriter(a.SessionPath)
+	if session != nil {
+		defer func() {
+			if !placeholder {
+				session.save(tabs, current, store, true)
+			}
+		}()
+		tick := time.NewTicker(sessionInterval)
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-tick.C:
+					w.Send(controlEvent{SessionDue: true})
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			tick.Stop()
+			close(done)
+		}()
+	}
 
 	var active actionType
 	var cropMode cropAction
 	var moveStart image.Point
 	var moveOffset image.Point
 	var last image.Point
+	// marker is the highlighter stroke being drawn.
+	var marker highlighter
+	// stroke is the Draw tool stroke being drawn.
+	var stroke freehand
+	// polygon holds the Poly tool's vertices until the polygon is closed,
+	// and polygonClick when the last was placed, to spot double clicks.
+	var polygon []image.Point
+	var polygonClick time.Time
 	var cropStart image.Point
 	var cropStartRect image.Rectangle
 	var cropRect image.Rectangle
@@ -418,32 +820,144 @@ func (a *AppState) Main(s screen.Screen) {
 	var textInputActive bool
 	var textInput string
 	var textPos image.Point
+	// reopened is the placed text being edited again, taken from index
+	// reopenedAt of the tab's items in an edit that placing or cancelling
+	// finishes.
+	var reopened *tabItem
+	var reopenedAt int
+	placeText := func() {
+		if reopened == nil {
+			tabs[current].beginItemEdit()
+			tabs[current].placeText(textInput, textPos, textSizeIdx, colorIdx)
+		} else if textInput != "" {
+			it := *reopened
+			it.text = placedText{text: textInput, pos: textPos, sizeIdx: textSizeIdx, colorIdx: colorIdx}
+			tabs[current].putItem(reopenedAt, it)
+		}
+		tabs[current].markEdited()
+		tabs[current].commitEdit()
+		textInputActive, reopened = false, nil
+	}
+	// cancelText puts reopened text back as it was.
+	cancelText := func() {
+		if reopened != nil {
+			tabs[current].putItem(reopenedAt, *reopened)
+			tabs[current].markEdited()
+			tabs[current].commitEdit()
+		}
+		textInputActive, reopened = false, nil
+	}
+	// openCallout is the callout the Callout tool is editing, and
+	// calloutBefore the item it was when it was picked up again from index
+	// calloutAt of the tab's items, nil for a new callout.
+	var openCallout *callout
+	var calloutBefore *tabItem
+	var calloutAt int
+	// A drag of the open callout moves or resizes its box with calloutDrag,
+	// or with calloutTail moves its tail, from where it started.
+	var calloutDrag cropAction
+	var calloutTail, calloutNew bool
+	var calloutStart image.Point
+	var calloutStartBox image.Rectangle
+	var calloutStartTip image.Point
+	// A drag with the Select tool moves the selection, or with selectBand
+	// stretches a rubber band, from selectStart to selectAt.
+	var selectStart, selectAt image.Point
+	var selectBand bool
+	// copiedItems holds the texts and callouts the Select tool copied, for
+	// pasting into any tab.
+	var copiedItems []tabItem
+	// placeCallout places the open callout on the tab. One left without text
+	// is dropped.
+	placeCallout := func() {
+		if openCallout == nil {
+			return
+		}
+		c := *openCallout
+		openCallout, calloutDrag, calloutTail, calloutNew = nil, cropNone, false, false
+		if c.text == "" && calloutBefore == nil {
+			return
+		}
+		switch {
+		case calloutBefore == nil:
+			tabs[current].beginItemEdit()
+			tabs[current].placeCallout(c)
+		case c.text != "":
+			it := *calloutBefore
+			it.callout = c
+			tabs[current].putItem(calloutAt, it)
+		}
+		tabs[current].markEdited()
+		tabs[current].commitEdit()
+		calloutBefore = nil
+	}
+	// cancelCallout puts a callout picked up again back as it was.
+	cancelCallout := func() {
+		if calloutBefore != nil {
+			tabs[current].putItem(calloutAt, *calloutBefore)
+			tabs[current].markEdited()
+			tabs[current].commitEdit()
+		}
+		openCallout, calloutBefore, calloutDrag, calloutTail, calloutNew = nil, nil, cropNone, false, false
+	}
+	// restyleCallout gives the open callout the toolbar's colour, width and
+	// text size.
+	restyleCallout := func() {
+		if openCallout != nil {
+			openCallout.colorIdx, openCallout.widthIdx, openCallout.sizeIdx = colorIdx, tabs[current].WidthIdx, textSizeIdx
+			openCallout.fit()
+		}
+	}
 	tool := ToolMove
 	numberIdx := 0
 	var paintMu sync.Mutex
 	var paintCancel context.CancelFunc
 	var dropCount int
+	// droppedFrames counts every canceled frame and frameTime is how long
+	// the last finished one took, for the debug overlay.
+	var droppedFrames int
+	var frameTime time.Duration
 	var lastPaint PaintState
 	_ = lastPaint
 	paintCh := make(chan PaintState, 1)
 	go func() {
+		var cache frameCache
 		for st := range paintCh {
 			ctx, cancel := context.WithCancel(context.Background())
 			paintMu.Lock()
 			paintCancel = cancel
 			paintMu.Unlock()
-			drawFrame(ctx, s, w, st)
+			start := time.Now()
+			drawFrame(ctx, s, w, st, &cache)
 			paintMu.Lock()
 			paintCancel = nil
 			if ctx.Err() == nil {
 				lastPaint = st
 				dropCount = 0
+				frameTime = time.Since(start)
 			}
 			paintMu.Unlock()
 		}
 	}()
 
 	col := paletteColorAt(colorIdx)
+	for _, res := range a.MoreCaptures {
+		tab := Tab{
+			Image:         res.Image,
+			Title:         fmt.Sprintf("%d", len(tabs)+1),
+			NextNumber:    1,
+			WidthIdx:      widthIdx,
+			ShadowApplied: a.InitialShadowApplied,
+			Capture:       &res,
+		}
+		if a.InitialShadowApplied {
+			shadowed := render.ApplyShadow(tab.Image, a.ShadowDefaults)
+			tab.Image = shadowed.Image
+			tab.Offset = image.Pt(-shadowed.Offset.X, -shadowed.Offset.Y)
+		}
+		tab.Zoom = fitZoom(tab.Image, width, height)
+		tabs = append(tabs, tab)
+	}
 	tabs[current].Zoom = fitZoom(rgba, width, height)
 	a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
 	a.updateTabsState(tabs, current)
@@ -454,13 +968,118 @@ func (a *AppState) Main(s screen.Screen) {
 
 	actions := map[string]func(){}
 	var applyShadow func()
+	var flattenTab func()
+	var onCapture func(*captureControl)
+	var startCapture func(kind string, fn func(capture.CaptureOptions) (capture.CaptureResult, error))
+	capturing := false
+	// The clipboard watch outlives mode changes, so it is kept here rather
+	// than in configureMode.
+	var watchCtx context.Context
+	var stopWatch context.CancelFunc
+	defer func() {
+		if stopWatch != nil {
+			stopWatch()
+		}
+	}()
+	var onWatch func(*watchControl)
+	// history holds the clipboard history while its popup is open.
+	var history []clipboard.HistoryEntry
+	historyOpen := false
+	// debugOverlay shows frame and memory statistics; F12 toggles it.
+	debugOverlay := false
+	// layersOpen shows the layers panel; F7 toggles it.
+	layersOpen := false
+	// gridShown draws the grid overlay and snapOn snaps dragged texts,
+	// callouts and selections to guides within snapDistance.
+	gridShown, snapOn := false, false
+	snapDistance := a.SnapDistance
+	if snapDistance <= 0 {
+		snapDistance = defaultSnapDistance
+	}
+	// quitRequested is set by the quit action; the event loop then ends.
+	quitRequested := false
+	var copyHistory func(int)
+	// frameExport frames saved and copied images with frame. The canvas
+	// stays unframed so annotations still land on the screenshot.
+	frameExport := a.FrameExport
+	frame := a.FrameDefaults
+	exported := func(img *image.RGBA) *image.RGBA {
+		if frameExport {
+			return render.ApplyFrame(img, frame)
+		}
+		return img
+	}
+	// urlInput holds the browser frame's address while Ctrl+L edits it.
+	var urlInputActive bool
+	var urlInput string
+	// cropInput holds an exact crop size, or the Custom aspect ratio when
+	// cropInputAspect is set, while it is typed.
+	var cropInputActive, cropInputAspect bool
+	var cropInput string
+	// tabTitle holds the new title of tab tabTitleIdx while it is typed
+	// after double clicking the tab. tabClick and tabClickIdx spot that
+	// double click, and tabDrag is the tab being dragged along the bar to
+	// reorder it, or -1.
+	var tabTitleActive bool
+	var tabTitle string
+	var tabTitleIdx int
+	var tabClick time.Time
+	var tabClickIdx = -1
+	var tabDrag = -1
+	// layerDrag is the layers panel row being dragged to another place in
+	// the stack, or -1.
+	var layerDrag = -1
+	// tabScroll is the first tab the tab bar shows when they do not all
+	// fit, and tabScrollFor the current tab it was last scrolled to show.
+	var tabScroll int
+	var tabScrollFor = -1
+	// closeTabAt closes tab idx, as its close box and middle clicking it
+	// do, placing the open callout first when it is the current tab.
+	closeTabAt := func(idx int) {
+		if len(tabs) < 2 || idx < 0 || idx >= len(tabs) {
+			return
+		}
+		if idx == current {
+			placeCallout()
+			polygon = nil
+		}
+		if tabTitleActive {
+			tabTitleActive, messageUntil = false, time.Time{}
+		}
+		tabs, current = closeTab(tabs, idx, current)
+		tabClickIdx, tabDrag = -1, -1
+		a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
+		w.Send(paint.Event{})
+	}
+	// scrollTabBar scrolls the tab bar a tab towards the start for a wheel
+	// turned up or left, and towards the end otherwise.
+	scrollTabBar := func(b mouse.Button) {
+		step := 1
+		if b == mouse.ButtonWheelUp || b == mouse.ButtonWheelLeft {
+			step = -1
+		}
+		shown := tabsShown(fromUI(image.Rect(0, 0, width, height)).Dx(), len(tabs))
+		if scrolled := scrollTabs(tabScroll+step, -1, len(tabs), shown); scrolled != tabScroll {
+			tabScroll = scrolled
+			w.Send(paint.Event{})
+		}
+	}
+
+	// register binds an action to the keys the shortcut registry, or the
+	// configuration, gives it.
+	// toolLabel names a tool button with the key that selects it, when
+	// that is a single key.
+	toolLabel := func(name, action string) string {
+		if keys := a.shortcutKeys(action); len(keys) > 0 && keys[0].Modifiers == 0 && keys[0].Rune > 0 {
+			return fmt.Sprintf("%s(%s)", name, keys[0])
+		}
+		return name
+	}
 
-	register := func(name string, keys KeyboardShortcuts, fn func()) {
+	register := func(name string, fn func()) {
 		actions[name] = fn
-		if keys != nil {
-			for _, sc := range keys.KeyboardShortcuts() {
-				keyboardAction[sc] = name
-			}
+		for _, sc := range a.shortcutKeys(name) {
+			keyboardAction[sc.normalized()] = name
 		}
 	}
 
@@ -490,23 +1109,34 @@ func (a *AppState) Main(s screen.Screen) {
 		}
 
 		registerCopy := func() {
-			register("copy", shortcutList{{Rune: 'c', Modifiers: key.ModControl}}, func() {
-				if err := clipboard.WriteImage(tabs[current].Image); err != nil {
+			register("copy", func() {
+				tab := tabs[current]
+				selections := []clipboard.Selection{clipboard.Clipboard}
+				if a.PrimarySelection {
+					selections = append(selections, clipboard.Primary)
+				}
+				if err := clipboard.WriteContent(clipboard.Content{Image: exported(tab.flatten(tab.Image)), Path: tab.SavedPath}, selections...); err != nil {
 					errorToast("copy failed: %v", err)
 					return
 				}
 				infoToast("image copied to clipboard")
+				a.emitEvent(EventCopy, "image")
 			})
 		}
 
 		registerSave := func() {
-			register("save", shortcutList{{Rune: 's', Modifiers: key.ModControl}}, func() {
+			register("save", func() {
 				out, err := os.Create(output)
 				if err != nil {
 					errorToast("save failed: %v", err)
 					return
 				}
-				if err := png.Encode(out, tabs[current].Image); err != nil {
+				tab := tabs[current]
+				o := imagefile.Options{Format: imagefile.FormatOf(output), WebP: a.WebP, AVIF: a.AVIF, JPEG: a.JPEG}
+				if a.PNGMetadata && tab.Capture != nil {
+					o.Text = tab.Capture.Text()
+				}
+				if err = imagefile.Encode(out, exported(tab.flatten(tab.Image)), o); err != nil {
 					errorToast("save failed: %v", err)
 					if cerr := out.Close(); cerr != nil {
 						log.Printf("save: closing file: %v", cerr)
@@ -517,10 +1147,80 @@ func (a *AppState) Main(s screen.Screen) {
 					errorToast("save failed closing file: %v", err)
 					return
 				}
+				tabs[current].SavedPath = output
 				infoToast(fmt.Sprintf("saved %s", output))
+				a.emitEvent(EventSave, output)
+			})
+			// Ctrl+Shift+S keeps every tab, unflattened, in a project
+			// that annotate open reads back.
+			register("saveproject", func() {
+				path := projectPath
+				if path == "" {
+					path = strings.TrimSuffix(output, filepath.Ext(output)) + project.Ext
+				}
+				p, err := projectOf(tabs, current, store.image)
+				if err != nil {
+					errorToast("project save failed: %v", err)
+					return
+				}
+				if err := project.Save(path, p); err != nil {
+					errorToast("project save failed: %v", err)
+					return
+				}
+				projectPath = path
+				if len(tabs) == 1 {
+					infoToast(fmt.Sprintf("saved %s", path))
+				} else {
+					infoToast(fmt.Sprintf("saved %d tabs to %s", len(tabs), path))
+				}
+				a.emitEvent(EventSave, path)
 			})
 		}
 
+		// registerExportPDF exports to the save path with a .pdf extension:
+		// Ctrl+E the current tab, Ctrl+Shift+E every tab as a page.
+		registerExportPDF := func() {
+			exportPDF := func(all bool) {
+				path := strings.TrimSuffix(output, filepath.Ext(output)) + ".pdf"
+				var pages []image.Image
+				for i := range tabs {
+					if !all && i != current {
+						continue
+					}
+					img, err := store.image(tabs[i])
+					if err != nil {
+						errorToast("export failed: %v", err)
+						return
+					}
+					pages = append(pages, exported(tabs[i].flatten(img)))
+				}
+				out, err := os.Create(path)
+				if err != nil {
+					errorToast("export failed: %v", err)
+					return
+				}
+				if err := pdf.Encode(out, pages); err != nil {
+					errorToast("export failed: %v", err)
+					if cerr := out.Close(); cerr != nil {
+						log.Printf("export: closing file: %v", cerr)
+					}
+					return
+				}
+				if err := out.Close(); err != nil {
+					errorToast("export failed closing file: %v", err)
+					return
+				}
+				if len(pages) == 1 {
+					infoToast(fmt.Sprintf("exported %s", path))
+				} else {
+					infoToast(fmt.Sprintf("exported %d tabs to %s", len(pages), path))
+				}
+				a.emitEvent(EventSave, path)
+			}
+			register("exportpdf", func() { exportPDF(false) })
+			register("exportpdfall", func() { exportPDF(true) })
+		}
+
 		applyShadow = func() {
 			if !annotationEnabled {
 				return
@@ -540,17 +1240,114 @@ func (a *AppState) Main(s screen.Screen) {
 				infoToast("shadow already applied")
 				return
 			}
+			tab.beginEdit()
 			tab.Image = res.Image
 			tab.Offset = tab.Offset.Add(image.Pt(-res.Offset.X, -res.Offset.Y))
+			tab.shiftItems(image.Pt(-res.Offset.X, -res.Offset.Y))
 			tab.ShadowApplied = true
+			tab.commitEdit()
 			a.NotifyImageChanged()
 			w.Send(paint.Event{})
 			infoToast("shadow added")
 		}
 
+		flattenTab = func() {
+			if !annotationEnabled {
+				return
+			}
+			placeCallout()
+			n := tabs[current].flattenItems()
+			if n == 0 {
+				infoToast("nothing to flatten")
+				return
+			}
+			a.NotifyImageChanged()
+			w.Send(paint.Event{})
+			infoToast(fmt.Sprintf("flattened %d items", n))
+		}
+
+		registerHistory := func() {
+			register("history", func() {
+				if historyOpen {
+					historyOpen, history, hoverHistory = false, nil, -1
+					return
+				}
+				entries, err := clipboard.History()
+				if err != nil {
+					errorToast("clipboard history: %v", err)
+					return
+				}
+				history, historyOpen, hoverHistory = entries, true, -1
+			})
+			copyHistory = func(i int) {
+				if i < 0 || i >= len(history) {
+					return
+				}
+				selections := []clipboard.Selection{clipboard.Clipboard}
+				if a.PrimarySelection {
+					selections = append(selections, clipboard.Primary)
+				}
+				historyOpen, hoverHistory = false, -1
+				err := clipboard.Recopy(history[i], selections...)
+				history = nil
+				if err != nil {
+					errorToast("copy failed: %v", err)
+					return
+				}
+				infoToast(fmt.Sprintf("history entry %d copied to clipboard", i+1))
+				a.emitEvent(EventCopy, "history")
+			}
+		}
+
+		registerFrame := func() {
+			register("frame", func() {
+				frameExport = !frameExport
+				if frameExport {
+					infoToast("saves and copies are framed")
+				} else {
+					infoToast("saves and copies are unframed")
+				}
+			})
+			register("framenext", func() {
+				presets := render.FramePresets()
+				next := 0
+				for i, p := range presets {
+					p.Options.Title, p.Options.URL = frame.Title, frame.URL
+					if p.Options == frame {
+						next = (i + 1) % len(presets)
+					}
+				}
+				title, url := frame.Title, frame.URL
+				frame = presets[next].Options
+				frame.Title, frame.URL = title, url
+				frameExport = true
+				infoToast(fmt.Sprintf("saves and copies use the %s frame", presets[next].Name))
+			})
+			register("frameurl", func() {
+				urlInputActive, urlInput = true, frame.URL
+				message, messageUntil = "address: "+urlInput+"_", time.Now().Add(time.Hour)
+			})
+		}
+
 		registerCommonActions := func() {
 			registerCopy()
 			registerSave()
+			registerExportPDF()
+			registerHistory()
+			registerFrame()
+			register("debug", func() {
+				debugOverlay = !debugOverlay
+			})
+			register("toolmove", func() {
+				tool = ToolMove
+				active = actionNone
+			})
+			zoom := func(f float64) {
+				tabs[current].Zoom = max(tabs[current].Zoom*f, 0.1)
+			}
+			register("zoomin", func() { zoom(1.25) })
+			register("zoomout", func() { zoom(1 / 1.25) })
+			register("quit", func() { quitRequested = true })
 		}
 
 		if !annotationEnabled {
@@ -567,7 +1364,7 @@ func (a *AppState) Main(s screen.Screen) {
 				}}},
 			}
 			registerCommonActions()
-			register("annotate", shortcutList{{Rune: 'a'}}, func() {
+			register("annotate", func() {
 				if annotationEnabled {
 					return
 				}
@@ -581,16 +1378,21 @@ func (a *AppState) Main(s screen.Screen) {
 		}
 
 		toolButtons = []*CacheButton{
-			{Button: &ToolButton{label: "Move(M)", tool: ToolMove, atype: actionMove}},
-			{Button: &ToolButton{label: "Crop(R)", tool: ToolCrop, atype: actionCrop}},
-			{Button: &ToolButton{label: "Draw(B)", tool: ToolDraw, atype: actionDraw}},
-			{Button: &ToolButton{label: "Circle(O)", tool: ToolCircle, atype: actionDraw}},
-			{Button: &ToolButton{label: "Line(L)", tool: ToolLine, atype: actionDraw}},
-			{Button: &ToolButton{label: "Arrow(A)", tool: ToolArrow, atype: actionDraw}},
-			{Button: &ToolButton{label: "Rect(X)", tool: ToolRect, atype: actionDraw}},
-			{Button: &ToolButton{label: "Num(H)", tool: ToolNumber, atype: actionDraw}},
-			{Button: &ToolButton{label: "Text(T)", tool: ToolText, atype: actionNone}},
-			{Button: &ToolButton{label: "Shadow($)", tool: ToolShadow, atype: actionNone}},
+			{Button: &ToolButton{label: toolLabel("Move", "toolmove"), tool: ToolMove, atype: actionMove}},
+			{Button: &ToolButton{label: toolLabel("Crop", "toolcrop"), tool: ToolCrop, atype: actionCrop}},
+			{Button: &ToolButton{label: toolLabel("Draw", "tooldraw"), tool: ToolDraw, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Circle", "toolcircle"), tool: ToolCircle, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Line", "toolline"), tool: ToolLine, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Arrow", "toolarrow"), tool: ToolArrow, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Rect", "toolrect"), tool: ToolRect, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Round", "toolroundrect"), tool: ToolRoundRect, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Poly", "toolpolygon"), tool: ToolPolygon, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Num", "toolnumber"), tool: ToolNumber, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Marker", "toolhighlight"), tool: ToolHighlight, atype: actionDraw}},
+			{Button: &ToolButton{label: toolLabel("Text", "tooltext"), tool: ToolText, atype: actionNone}},
+			{Button: &ToolButton{label: toolLabel("Callout", "toolcallout"), tool: ToolCallout, atype: actionNone}},
+			{Button: &ToolButton{label: toolLabel("Select", "toolselect"), tool: ToolSelect, atype: actionSelect}},
+			{Button: &ToolButton{label: toolLabel("Shadow", "shadow"), tool: ToolShadow, atype: actionNone}},
 		}
 		for _, cb := range toolButtons {
 			tb, ok := cb.Button.(*ToolButton)
@@ -607,41 +1409,190 @@ func (a *AppState) Main(s screen.Screen) {
 				}
 				tool = t.tool
 				active = actionNone
+				polygon = nil
+				placeCallout()
 			}
 		}
+		for name, t := range map[string]Tool{
+			"toolcrop":      ToolCrop,
+			"tooldraw":      ToolDraw,
+			"toolcircle":    ToolCircle,
+			"toolline":      ToolLine,
+			"toolarrow":     ToolArrow,
+			"toolrect":      ToolRect,
+			"toolroundrect": ToolRoundRect,
+			"toolpolygon":   ToolPolygon,
+			"toolnumber":    ToolNumber,
+			"toolhighlight": ToolHighlight,
+			"tooltext":      ToolText,
+			"toolcallout":   ToolCallout,
+			"toolselect":    ToolSelect,
+		} {
+			register(name, func() {
+				tool = t
+				active = actionNone
+				polygon = nil
+				placeCallout()
+			})
+		}
 
 		registerCommonActions()
 
-		register("shadow", shortcutList{
-			{Rune: '$'},
-			{Rune: -1, Code: key.Code4, Modifiers: key.ModShift},
-		}, func() {
+		register("layers", func() {
+			layersOpen, hoverLayer = !layersOpen, -1
+		})
+		register("grid", func() {
+			gridShown = !gridShown
+		})
+		register("snap", func() {
+			snapOn = !snapOn
+			if snapOn {
+				infoToast("snapping on")
+			} else {
+				infoToast("snapping off")
+			}
+		})
+
+		register("shadow", func() {
 			if applyShadow != nil {
 				applyShadow()
 			}
 		})
+		register("flatten", func() {
+			if flattenTab != nil {
+				flattenTab()
+			}
+		})
 
-		register("capture", shortcutList{{Rune: 'n', Modifiers: key.ModControl}}, func() {
-			img, err := capture.CaptureScreenshot("", capture.CaptureOptions{})
-			if err != nil {
-				errorToast("capture failed: %v", err)
+		register("undo", func() {
+			if !tabs[current].undo() {
+				infoToast("nothing to undo")
 				return
 			}
-			tabs = append(tabs, Tab{
-				Image:         img,
-				Title:         fmt.Sprintf("%d", len(tabs)+1),
-				Offset:        image.Point{},
-				Zoom:          1,
-				NextNumber:    1,
-				WidthIdx:      a.WidthIdx,
-				ShadowApplied: a.InitialShadowApplied,
-			})
-			current = len(tabs) - 1
-			tabs[current].Zoom = fitZoom(tabs[current].Image, width, height)
-			infoToast("captured screenshot")
+			a.NotifyImageChanged()
+			w.Send(paint.Event{})
 		})
+		register("redo", func() {
+			if !tabs[current].redo() {
+				infoToast("nothing to redo")
+				return
+			}
+			a.NotifyImageChanged()
+			w.Send(paint.Event{})
+		})
+
+		// startCapture runs fn off the event loop so the countdown toasts and
+		// any picker keep the window responsive; onCapture opens the result.
+		startCapture = func(kind string, fn func(capture.CaptureOptions) (capture.CaptureResult, error)) {
+			if capturing {
+				infoToast("capture already in progress")
+				return
+			}
+			capturing = true
+			opts := capture.CaptureOptions{
+				Delay: a.CaptureDelay,
+				Progress: func(left time.Duration) {
+					if left > 0 {
+						w.Send(controlEvent{Capture: &captureControl{remaining: left}})
+					}
+				},
+			}
+			go func() {
+				res, err := fn(opts)
+				w.Send(controlEvent{Capture: &captureControl{done: true, kind: kind, res: res, err: err}})
+			}()
+		}
+		register("capture", func() {
+			startCapture("screen", func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+				return capture.CaptureScreenshot("", opts)
+			})
+		})
+		// Ctrl+Alt+N always counts down, for menus and tooltips that close
+		// as soon as the screen is grabbed straight away.
+		register("timedcapture", func() {
+			delay := a.CaptureDelay
+			if delay <= 0 {
+				delay = timedCaptureDelay
+			}
+			startCapture("screen", func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+				opts.Delay = delay
+				return capture.CaptureScreenshot("", opts)
+			})
+		})
+		register("pickwindow", func() {
+			infoToast("click the window to capture")
+			startCapture("window", func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+				return capture.CaptureWindow(capture.PickWindowSelector, opts)
+			})
+		})
+		register("recapture", func() {
+			src := tabs[current].Capture
+			if src == nil || src.Token == "" {
+				infoToast("this tab cannot be captured again")
+				return
+			}
+			token := src.Token
+			startCapture("recapture", func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+				return capture.Recapture(token, opts)
+			})
+		})
+		onCapture = func(c *captureControl) {
+			if !c.done {
+				infoToast(fmt.Sprintf("capturing in %s", c.remaining.Round(time.Second)))
+				return
+			}
+			capturing = false
+			if c.err != nil {
+				errorToast("capture failed: %v", c.err)
+				return
+			}
+			results := []capture.CaptureResult{c.res}
+			if a.SplitMonitors && c.kind == "screen" {
+				results = capture.SplitMonitors(c.res)
+			}
+			// The startup capture takes the placeholder's place, with the
+			// shadow and frame title the starting image would have had.
+			startup := placeholder
+			first := len(tabs)
+			for _, res := range results {
+				tab := Tab{
+					Image:         res.Image,
+					Title:         fmt.Sprintf("%d", len(tabs)+1),
+					Offset:        image.Point{},
+					Zoom:          1,
+					NextNumber:    1,
+					WidthIdx:      a.WidthIdx,
+					ShadowApplied: a.InitialShadowApplied,
+					Capture:       &res,
+				}
+				if startup && a.InitialShadowApplied {
+					shadowed := render.ApplyShadow(tab.Image, a.ShadowDefaults)
+					tab.Image = shadowed.Image
+					tab.Offset = image.Pt(-shadowed.Offset.X, -shadowed.Offset.Y)
+				}
+				if startup && frame.Title == "" && res.Window != nil {
+					frame.Title = res.Window.Title
+				}
+				tab.Zoom = fitZoom(tab.Image, width, height)
+				if placeholder {
+					placeholder = false
+					tab.Title = tabs[0].Title
+					tabs[0] = tab
+					first = 0
+				} else {
+					tabs = append(tabs, tab)
+				}
+			}
+			current = first
+			if len(results) > 1 {
+				infoToast(fmt.Sprintf("captured %d monitors", len(results)))
+			} else {
+				infoToast("captured " + results[0].Summary())
+			}
+			a.emitEvent(EventCapture, c.kind)
+		}
 
-		register("dup", shortcutList{{Rune: 'u', Modifiers: key.ModControl}}, func() {
+		register("dup", func() {
 			dup := image.NewRGBA(tabs[current].Image.Bounds())
 			draw.Draw(dup, dup.Bounds(), tabs[current].Image, image.Point{}, draw.Src)
 			tabs = append(tabs, Tab{
@@ -652,12 +1603,20 @@ func (a *AppState) Main(s screen.Screen) {
 				NextNumber:    tabs[current].NextNumber,
 				WidthIdx:      tabs[current].WidthIdx,
 				ShadowApplied: tabs[current].ShadowApplied,
+				Capture:       tabs[current].Capture,
+				items:         slices.Clone(tabs[current].items),
+				numbers:       slices.Clone(tabs[current].numbers),
+				lastItemID:    tabs[current].lastItemID,
 			})
 			current = len(tabs) - 1
 		})
 
-		register("paste", shortcutList{{Rune: 'v', Modifiers: key.ModControl}}, func() {
-			img, err := clipboard.ReadImage()
+		register("paste", func() {
+			sel := clipboard.Clipboard
+			if a.PrimarySelection {
+				sel = clipboard.Primary
+			}
+			img, err := clipboard.ReadImageFrom(sel)
 			if err != nil {
 				errorToast("paste failed: %v", err)
 				return
@@ -677,7 +1636,61 @@ func (a *AppState) Main(s screen.Screen) {
 			infoToast("pasted new tab")
 		})
 
-		register("delete", shortcutList{{Rune: 'd', Modifiers: key.ModControl}}, func() {
+		register("clipwatch", func() {
+			if stopWatch != nil {
+				stopWatch()
+				watchCtx, stopWatch = nil, nil
+				infoToast("stopped watching the clipboard")
+				return
+			}
+			sel := clipboard.Clipboard
+			if a.PrimarySelection {
+				sel = clipboard.Primary
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			copies, err := clipboard.Watch(ctx, sel)
+			if err != nil {
+				cancel()
+				errorToast("watch failed: %v", err)
+				return
+			}
+			watchCtx, stopWatch = ctx, cancel
+			go func() {
+				for copied := range copies {
+					w.Send(controlEvent{Watch: &watchControl{ctx: ctx, copied: copied}})
+				}
+			}()
+			infoToast(fmt.Sprintf("watching the %s; copied images open in new tabs", sel))
+		})
+		onWatch = func(c *watchControl) {
+			if c.ctx != watchCtx {
+				// Sent by a watch that has since been stopped.
+				return
+			}
+			if c.copied.Err != nil {
+				stopWatch()
+				watchCtx, stopWatch = nil, nil
+				errorToast("watch failed: %v", c.copied.Err)
+				return
+			}
+			img := c.copied.Image
+			rgba := image.NewRGBA(img.Bounds())
+			draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+			tabs = append(tabs, Tab{
+				Image:         rgba,
+				Title:         fmt.Sprintf("%d", len(tabs)+1),
+				Offset:        image.Point{},
+				Zoom:          1,
+				NextNumber:    1,
+				WidthIdx:      a.WidthIdx,
+				ShadowApplied: a.InitialShadowApplied,
+			})
+			current = len(tabs) - 1
+			tabs[current].Zoom = fitZoom(rgba, width, height)
+			infoToast("copied image opened in a new tab")
+		}
+
+		register("delete", func() {
 			if len(tabs) > 1 {
 				tabs = append(tabs[:current], tabs[current+1:]...)
 				if current >= len(tabs) {
@@ -686,30 +1699,25 @@ func (a *AppState) Main(s screen.Screen) {
 			}
 		})
 
-		register("textdone", shortcutList{{Code: key.CodeReturnEnter}}, func() {
-			d := &font.Drawer{Dst: tabs[current].Image, Src: image.NewUniform(paletteColorAt(colorIdx)), Face: textFaces[textSizeIdx]}
-			d.Dot = fixed.P(textPos.X, textPos.Y)
-			d.DrawString(textInput)
-			textInputActive = false
-		})
-
-		register("textcancel", shortcutList{{Code: key.CodeEscape}}, func() {
-			textInputActive = false
-		})
+		register("textdone", placeText)
+		register("textcancel", cancelText)
 
-		register("crop", shortcutList{{Code: key.CodeReturnEnter}}, func() {
+		register("crop", func() {
 			if tool == ToolCrop && !cropRect.Empty() {
 				cropped := cropImage(tabs[current].Image, cropRect)
+				tabs[current].beginEdit()
 				tabs[current].Image = cropped
 				tabs[current].Offset = tabs[current].Offset.Add(cropRect.Min)
+				tabs[current].shiftItems(cropRect.Min)
+				tabs[current].commitEdit()
 				active = actionNone
 				cropRect = image.Rectangle{}
 			}
 		})
 
-		register("croptab", shortcutList{{Code: key.CodeReturnEnter, Modifiers: key.ModControl}}, func() {
+		register("croptab", func() {
 			if tool == ToolCrop && !cropRect.Empty() {
-				cropped := cropImage(tabs[current].Image, cropRect)
+				cropped := cropImage(tabs[current].flatten(tabs[current].Image), cropRect)
 				off := tabs[current].Offset.Add(cropRect.Min)
 				tabs = append(tabs, Tab{Image: cropped, Title: fmt.Sprintf("%d", len(tabs)+1), Offset: off, Zoom: tabs[current].Zoom, NextNumber: 1, WidthIdx: tabs[current].WidthIdx})
 				current = len(tabs) - 1
@@ -718,7 +1726,14 @@ func (a *AppState) Main(s screen.Screen) {
 			}
 		})
 
-		register("cropcancel", shortcutList{{Code: key.CodeEscape}}, func() {
+		register("cropsize", func() {
+			if tool == ToolCrop {
+				cropInputActive, cropInputAspect, cropInput = true, false, ""
+				message, messageUntil = "crop size WxH: _", time.Now().Add(time.Hour)
+			}
+		})
+
+		register("cropcancel", func() {
 			if tool == ToolCrop {
 				cropRect = image.Rectangle{}
 				active = actionNone
@@ -727,6 +1742,173 @@ func (a *AppState) Main(s screen.Screen) {
 
 	}
 
+	// selection returns the areas of the current tab's selected texts and
+	// callouts, following a drag that is moving them, and the rubber band
+	// being dragged.
+	selection := func() ([]image.Rectangle, image.Rectangle) {
+		if tool != ToolSelect {
+			return nil, image.Rectangle{}
+		}
+		var d image.Point
+		var band image.Rectangle
+		if active == actionSelect && selectBand {
+			band = image.Rectangle{Min: selectStart, Max: selectAt}.Canon()
+		} else if active == actionSelect {
+			d = selectAt.Sub(selectStart)
+		}
+		var rects []image.Rectangle
+		for _, it := range tabs[current].selectedItems() {
+			rects = append(rects, it.rect.Add(d))
+		}
+		return rects, band
+	}
+	// moveSelection moves each selected text and callout by the offset
+	// offsets gives for its area, as one edit.
+	moveSelection := func(offsets func([]image.Rectangle) []image.Point) {
+		t := &tabs[current]
+		items := t.selectedItems()
+		rects := make([]image.Rectangle, len(items))
+		for i, it := range items {
+			rects[i] = it.rect
+		}
+		moves := map[uint64]image.Point{}
+		for i, off := range offsets(rects) {
+			if off != (image.Point{}) {
+				moves[items[i].id] = off
+			}
+		}
+		if len(moves) == 0 {
+			return
+		}
+		t.beginItemEdit()
+		t.moveItems(moves)
+		t.markEdited()
+		t.commitEdit()
+	}
+	// guidesFor returns the guides a drag on the current tab snaps to,
+	// leaving out the items skip names, and how near in image pixels an
+	// edge must come to one. The reach is zero with snapping off.
+	guidesFor := func(skip []uint64) (snapGuides, int) {
+		if !snapOn {
+			return snapGuides{}, 0
+		}
+		t := &tabs[current]
+		var others []image.Rectangle
+		for _, it := range t.selectable() {
+			if !slices.Contains(skip, it.id) {
+				others = append(others, it.rect)
+			}
+		}
+		grid := 0
+		if gridShown {
+			grid = gridSize
+		}
+		reach := int(math.Ceil(float64(ui(snapDistance)) / t.Zoom))
+		return newSnapGuides(t.Image.Bounds(), others, grid), reach
+	}
+	// selectDrag returns where a drag of the selection to p leaves the
+	// pointer once the selection snaps.
+	selectDrag := func(p image.Point) image.Point {
+		g, reach := guidesFor(tabs[current].selected)
+		if reach == 0 {
+			return p
+		}
+		var area image.Rectangle
+		for _, it := range tabs[current].selectedItems() {
+			area = area.Union(it.rect)
+		}
+		return p.Add(g.moveOffset(area.Add(p.Sub(selectStart)), reach))
+	}
+	// pasteItems places items a step down and right of where they are, as
+	// one edit, and selects them. They are moved in place, so pasting the
+	// same copy again lands a step further on.
+	pasteItems := func(items []tabItem) {
+		for i := range items {
+			items[i].move(image.Pt(pasteStep, pasteStep))
+		}
+		t := &tabs[current]
+		t.beginItemEdit()
+		t.selected = t.placeItems(items, false)
+		t.markEdited()
+		t.commitEdit()
+	}
+	// damage repaints only r, for changes that stay inside it.
+	damage := func(r image.Rectangle) {
+		if !r.Empty() {
+			w.Send(damageEvent{rect: r})
+		}
+	}
+	// overlayDamage returns the window area of the previews drawn over the
+	// canvas: the crop selection, the polygon, the open callout, the text
+	// being typed and the Select tool's selection.
+	overlayDamage := func() image.Rectangle {
+		view := imageScreenRect(tabs[current], width, height)
+		zoom := tabs[current].Zoom
+		var r image.Rectangle
+		if tool == ToolCrop && (active == actionCrop || !cropRect.Empty()) {
+			r = cropDamage(cropSelection(active == actionCrop, cropStart, cropRect), view, zoom)
+		}
+		if tool == ToolPolygon {
+			r = r.Union(polygonDamage(polygon, view, zoom))
+		}
+		if tool == ToolCallout && openCallout != nil {
+			r = r.Union(calloutDamage(*openCallout, view, zoom))
+		}
+		if textInputActive {
+			r = r.Union(textDamage(textInput, textPos, view, zoom))
+		}
+		if tool == ToolSelect {
+			rects, band := selection()
+			r = r.Union(selectDamage(rects, band, view, zoom))
+		}
+		return r
+	}
+	// changeSetting runs change, which picks a tool setting, and repaints
+	// the toolbar along with the previews it restyles.
+	changeSetting := func(change func()) {
+		before := overlayDamage()
+		change()
+		damage(toolbarDamage(height).Union(before).Union(overlayDamage()))
+	}
+	// toastDamage returns the window area of the message box showing msg.
+	toastDamage := func(msg string) image.Rectangle {
+		full := fromUI(image.Rect(0, 0, width, height))
+		return uiRect(toastRect(msg, full.Dx(), full.Dy()))
+	}
+	// closePolygon draws the Poly tool's polygon, if it has enough vertices
+	// to enclose anything, and starts the next.
+	closePolygon := func() {
+		pts := polygon
+		polygon = nil
+		if len(pts) < 3 {
+			return
+		}
+		thick := widthAt(tabs[current].WidthIdx)
+		tabs[current].beginEdit()
+		shift := ensureCanvasContains(&tabs[current], polygonBounds(pts).Inset(-thick-2))
+		for i := range pts {
+			pts[i] = pts[i].Sub(shift)
+		}
+		if fill, ok := shapeFill(col); ok {
+			fillPolygon(tabs[current].Image, pts, fill)
+		}
+		drawPolygon(tabs[current].Image, pts, col, thick)
+		tabs[current].markEdited()
+		tabs[current].commitEdit()
+	}
+	// hoverRect is the UI element under the pointer, and shownToast the
+	// message on screen in the last frame.
+	var hoverRect image.Rectangle
+	var shownToast string
+
+	quit := func() {
+		paintMu.Lock()
+		if paintCancel != nil {
+			paintCancel()
+		}
+		paintMu.Unlock()
+	}
+
 	handleShortcut := func(action string) {
 		if fn, ok := actions[action]; ok {
 			fn()
@@ -736,8 +1918,22 @@ func (a *AppState) Main(s screen.Screen) {
 
 	configureMode()
 
+	if placeholder && startCapture != nil {
+		fn := a.StartupCapture
+		startCapture(a.StartupCaptureKind, func(opts capture.CaptureOptions) (capture.CaptureResult, error) {
+			<-tagged
+			return fn(opts)
+		})
+	}
+
 	for {
 		e := w.NextEvent()
+		if err := store.settle(tabs, current); err != nil {
+			message = err.Error()
+			log.Print(message)
+			messageUntil = time.Now().Add(4 * time.Second)
+		}
+		trimHistories(tabs)
 		switch e := e.(type) {
 		case controlEvent:
 			repaint := false
@@ -763,16 +1959,47 @@ func (a *AppState) Main(s screen.Screen) {
 				case tabActionClose:
 					idx := e.Tab.index
 					if idx >= 0 && idx < len(tabs) && len(tabs) > 1 {
-						tabs = append(tabs[:idx], tabs[idx+1:]...)
-						if current >= len(tabs) {
-							current = len(tabs) - 1
-						} else if idx <= current && current > 0 {
-							current--
-						}
+						tabs, current = closeTab(tabs, idx, current)
+						repaint = true
+					}
+				case tabActionRename:
+					idx := e.Tab.index
+					if idx >= 0 && idx < len(tabs) {
+						tabs[idx].Title = e.Tab.title
+						repaint = true
+					}
+				case tabActionMove:
+					from, to := e.Tab.index, e.Tab.to
+					if from >= 0 && from < len(tabs) && to >= 0 && to < len(tabs) {
+						current = moveTab(tabs, from, to, current)
 						repaint = true
 					}
 				}
 			}
+			if e.Capture != nil && onCapture != nil {
+				onCapture(e.Capture)
+				repaint = true
+			}
+			if e.Watch != nil && onWatch != nil {
+				onWatch(e.Watch)
+				repaint = true
+			}
+			if e.ImageChanged {
+				for i := range tabs {
+					tabs[i].markEdited()
+				}
+				repaint = true
+			}
+			if e.Packed != nil {
+				store.packed(e.Packed, tabs, current)
+				repaint = true
+			}
+			if e.Flatten && flattenTab != nil {
+				flattenTab()
+			}
+			if e.SessionDue && !placeholder {
+				session.save(tabs, current, store, false)
+			}
 			if len(tabs) > 0 {
 				a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
 			}
@@ -791,27 +2018,73 @@ func (a *AppState) Main(s screen.Screen) {
 		case size.Event:
 			width = e.WidthPx
 			height = e.HeightPx
+			if a.UIScale <= 0 {
+				uiScale = uiScaleFor(e.PixelsPerPt)
+			}
 			w.Send(paint.Event{})
-		case paint.Event:
+		case paint.Event, damageEvent:
+			// A damage event repaints only its rectangle, along with the
+			// toast's old and new boxes when it appeared, changed or
+			// expired since the last frame.
+			var dirty image.Rectangle
+			if d, ok := e.(damageEvent); ok {
+				dirty = d.rect
+			}
+			toast := ""
+			if message != "" && time.Now().Before(messageUntil) {
+				toast = message
+			}
+			if toast != shownToast && !dirty.Empty() {
+				dirty = dirty.Union(toastDamage(shownToast)).Union(toastDamage(toast))
+			}
+			shownToast = toast
+
 			a.updateTabsState(tabs, current)
 			paintMu.Lock()
 			if paintCancel != nil {
 				if dropCount < frameDropThreshold {
 					paintCancel()
 					dropCount++
+					droppedFrames++
 				}
 			}
+			var debug []string
+			if debugOverlay {
+				debug = debugLines(frameTime, droppedFrames)
+			}
 			paintMu.Unlock()
+			// The overlay's figures change with every frame, so it is
+			// redrawn with any dirty rectangle.
+			if debug != nil && !dirty.Empty() {
+				dirty = dirty.Union(uiRect(debugOverlayRect(fromUI(image.Rect(0, 0, width, height)).Dx(), debug)))
+			}
 
 			currentButtons := make([]Button, len(toolButtons))
 			for i, tb := range toolButtons {
 				currentButtons[i] = tb
 			}
 
+			reveal := -1
+			if current != tabScrollFor {
+				reveal, tabScrollFor = current, current
+			}
+			tabScroll = scrollTabs(tabScroll, reveal, len(tabs), tabsShown(fromUI(image.Rect(0, 0, width, height)).Dx(), len(tabs)))
+
+			selected, band := selection()
+			var layers []layerRow
+			if layersOpen {
+				layers = tabs[current].layerRows()
+			}
+			// The tabs are copied so that a tab packed while the frame
+			// draws keeps its pixels in it, and the current tab's items
+			// and markers so the frame draws them as they are now.
+			painted := append([]Tab(nil), tabs...)
+			painted[current].items = slices.Clone(painted[current].items)
+			painted[current].numbers = slices.Clone(painted[current].numbers)
 			st := PaintState{
 				Width:             width,
 				Height:            height,
-				Tabs:              tabs,
+				Tabs:              painted,
 				Current:           current,
 				Tool:              tool,
 				ColorIdx:          colorIdx,
@@ -819,6 +2092,10 @@ func (a *AppState) Main(s screen.Screen) {
 				Cropping:          active == actionCrop,
 				CropRect:          cropRect,
 				CropStart:         cropStart,
+				Polygon:           polygon,
+				Callout:           openCallout,
+				Selected:          selected,
+				SelectBand:        band,
 				TextInputActive:   textInputActive,
 				TextInput:         textInput,
 				TextPos:           textPos,
@@ -828,17 +2105,35 @@ func (a *AppState) Main(s screen.Screen) {
 				AnnotationEnabled: annotationEnabled,
 				VersionLabel:      toolbarVersion,
 				ToolButtons:       currentButtons,
+				History:           historyRows(history),
+				HistoryOpen:       historyOpen,
+				Dirty:             dirty,
+				MemoryLabel:       store.usage(tabs),
+				Debug:             debug,
+				Layers:            layers,
+				LayersOpen:        layersOpen,
+				LayerDrag:         layerDrag,
+				Grid:              gridShown,
+				TabScroll:         tabScroll,
 				SetUIMap: func(sm spacemap.Interface) {
 					a.uiMapMu.Lock()
 					a.uiMap = sm
 					a.uiMapMu.Unlock()
 				},
 			}
-			select {
-			case paintCh <- st:
-			default:
-				<-paintCh
-				paintCh <- st
+			// A frame still waiting to be drawn is replaced, so the new one
+			// must also cover the area it would have repainted.
+			for queued := false; !queued; {
+				select {
+				case paintCh <- st:
+					queued = true
+				case prev := <-paintCh:
+					if prev.Dirty.Empty() {
+						st.Dirty = image.Rectangle{}
+					} else if !st.Dirty.Empty() {
+						st.Dirty = st.Dirty.Union(prev.Dirty)
+					}
+				}
 			}
 			lastPaint = st
 		case mouse.Event:
@@ -850,21 +2145,50 @@ func (a *AppState) Main(s screen.Screen) {
 			a.uiMapMu.RLock()
 			var hit *UIShape
 			if a.uiMap != nil {
-				s := a.uiMap.GetAt(int(e.X), int(e.Y))
+				p := uiPoint(e.X, e.Y)
+				s := a.uiMap.GetAt(p.X, p.Y)
 				if s != nil {
 					hit, _ = s.(*UIShape)
 				}
 			}
 			a.uiMapMu.RUnlock()
+			if e.Direction == mouse.DirRelease && (hit == nil || hit.Type != UITypeTab) {
+				tabDrag = -1
+			}
+			if e.Direction == mouse.DirRelease && layerDrag >= 0 && (hit == nil || hit.Type != UITypeLayer) {
+				layerDrag = -1
+				w.Send(paint.Event{})
+			}
+
+			if historyOpen && (hit == nil || hit.Type != UITypeHistory) {
+				// The popup is modal: a click outside it only closes it.
+				if e.Direction == mouse.DirPress {
+					historyOpen, history, hoverHistory = false, nil, -1
+					w.Send(paint.Event{})
+				}
+				continue
+			}
 
 			if hit != nil {
 				hoverTab = -1
 				hoverShortcut = -1
+				hoverHistory = -1
 				hoverTool = -1
 				hoverPalette = -1
 				hoverWidth = -1
 				hoverNumber = -1
 				hoverTextSize = -1
+				hoverOpacity = -1
+				hoverFill = -1
+				hoverRadius = -1
+				hoverStroke = -1
+				hoverLink = -1
+				hoverAspect = -1
+				hoverStyle = -1
+				hoverAlign = -1
+				hoverLayer = -1
+				hoverTabClose = -1
+				hoverTabScroll = -1
 
 				switch hit.Type {
 				case UITypeShortcut:
@@ -873,14 +2197,58 @@ func (a *AppState) Main(s screen.Screen) {
 						if hit.Index >= 0 && hit.Index < len(shortcutRects) {
 							shortcutRects[hit.Index].Activate()
 						}
+						if quitRequested {
+							quit()
+							return
+						}
 					}
 				case UITypeTab:
 					hoverTab = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						placeCallout()
 						current = hit.Index
+						polygon = nil
 						a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
+						now := time.Now()
+						if hit.Index == tabClickIdx && now.Sub(tabClick) < polygonDoubleClick {
+							tabTitleActive, tabTitleIdx, tabTitle = true, hit.Index, tabs[hit.Index].Title
+							message, messageUntil = "tab title: "+tabTitle+"_", time.Now().Add(time.Hour)
+							tabClickIdx, tabDrag = -1, -1
+						} else {
+							tabClick, tabClickIdx, tabDrag = now, hit.Index, hit.Index
+						}
+						w.Send(paint.Event{})
+					}
+					// Letting go of a tab dragged over another moves it
+					// there.
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirRelease && tabDrag >= 0 && tabDrag != hit.Index {
+						current = moveTab(tabs, tabDrag, hit.Index, current)
+						tabTitleActive, tabClickIdx = false, -1
 						w.Send(paint.Event{})
 					}
+					if e.Button == mouse.ButtonMiddle && e.Direction == mouse.DirPress {
+						closeTabAt(hit.Index)
+					}
+					if e.Button.IsWheel() {
+						scrollTabBar(e.Button)
+					}
+				case UITypeTabClose:
+					hoverTabClose = hit.Index
+					if (e.Button == mouse.ButtonLeft || e.Button == mouse.ButtonMiddle) && e.Direction == mouse.DirPress {
+						closeTabAt(hit.Index)
+					}
+				case UITypeTabScroll:
+					hoverTabScroll = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						if hit.Index == 0 {
+							scrollTabBar(mouse.ButtonWheelLeft)
+						} else {
+							scrollTabBar(mouse.ButtonWheelRight)
+						}
+					}
+					if e.Button.IsWheel() {
+						scrollTabBar(e.Button)
+					}
 				case UITypeTool:
 					hoverTool = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
@@ -892,38 +2260,170 @@ func (a *AppState) Main(s screen.Screen) {
 				case UITypePalette:
 					hoverPalette = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
-						colorIdx = hit.Index
-						col = paletteColorAt(colorIdx)
-						a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
-						w.Send(paint.Event{})
+						changeSetting(func() {
+							colorIdx = hit.Index
+							col = paletteColorAt(colorIdx)
+							a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
+							restyleCallout()
+						})
+					}
+					// Right clicking picks the fill colour, or with the
+					// fill colour picked again goes back to the stroke's.
+					if e.Button == mouse.ButtonRight && e.Direction == mouse.DirPress {
+						changeSetting(func() {
+							if fillColorIdx == hit.Index {
+								fillColorIdx = -1
+							} else {
+								fillColorIdx = hit.Index
+							}
+						})
 					}
 				case UITypeWidth:
 					hoverWidth = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
-						tabs[current].WidthIdx = hit.Index
-						a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
-						w.Send(paint.Event{})
+						changeSetting(func() {
+							tabs[current].WidthIdx = hit.Index
+							a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
+							restyleCallout()
+						})
 					}
 				case UITypeNumber:
 					hoverNumber = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
-						numberIdx = hit.Index
+						changeSetting(func() { numberIdx = hit.Index })
+					}
+				case UITypeAspect:
+					hoverAspect = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() {
+							if hit.Index == cropCustomIdx {
+								// The Custom row asks for its ratio.
+								cropInputActive, cropInputAspect, cropInput = true, true, ""
+								message, messageUntil = "aspect ratio W:H: _", time.Now().Add(time.Hour)
+							} else {
+								cropAspectIdx = hit.Index
+								cropRect = lockAspect(cropRect, cropResizeBR, cropRatio())
+							}
+						})
+					}
+				case UITypeStyle:
+					hoverStyle = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() { lineStyleIdx = LineStyle(hit.Index) })
+					}
+				case UITypeAlign:
+					hoverAlign = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						mode := alignMode(hit.Index)
+						if n := mode.minItems(); len(tabs[current].selectedItems()) < n {
+							message = fmt.Sprintf("select %d or more texts or callouts to %s", n, strings.ToLower(alignLabels[mode]))
+							messageUntil = time.Now().Add(2 * time.Second)
+						} else {
+							moveSelection(func(rects []image.Rectangle) []image.Point { return alignOffsets(rects, mode) })
+						}
 						w.Send(paint.Event{})
 					}
+				case UITypeLayer:
+					hoverLayer = hit.Index
+					// Clicking a row picks its item with the Select tool, and
+					// dragging it onto another row moves the item to that
+					// place in the stack.
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress && hit.Index >= 0 {
+						rows := tabs[current].layerRows()
+						if hit.Index < len(rows) {
+							layerDrag = hit.Index
+							if !rows[hit.Index].hidden && !rows[hit.Index].locked {
+								handleShortcut("toolselect")
+								tabs[current].selected = []uint64{rows[hit.Index].id}
+							}
+						}
+						w.Send(paint.Event{})
+					}
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirRelease && layerDrag >= 0 {
+						rows := tabs[current].layerRows()
+						if hit.Index >= 0 && hit.Index < len(rows) && hit.Index != layerDrag && layerDrag < len(rows) {
+							t := &tabs[current]
+							t.beginItemEdit()
+							t.restackItem(rows[layerDrag].id, len(rows)-1-hit.Index)
+							t.markEdited()
+							t.commitEdit()
+						}
+						layerDrag = -1
+						w.Send(paint.Event{})
+					}
+				case UITypeLayerToggle:
+					hoverLayer = hit.Index / 2
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						rows := tabs[current].layerRows()
+						if i := hit.Index / 2; i < len(rows) {
+							t := &tabs[current]
+							if hit.Index%2 == layerToggleLock {
+								t.toggleLocked(rows[i].id)
+							} else {
+								t.beginItemEdit()
+								t.setHidden(rows[i].id, !rows[i].hidden)
+								t.markEdited()
+								t.commitEdit()
+							}
+							w.Send(paint.Event{})
+						}
+					}
+				case UITypeLink:
+					hoverLink = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() { numberLinkIdx = numberLink(hit.Index) })
+					}
 				case UITypeTextSize:
 					hoverTextSize = hit.Index
 					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
-						textSizeIdx = hit.Index
+						changeSetting(func() {
+							textSizeIdx = hit.Index
+							restyleCallout()
+						})
+					}
+				case UITypeOpacity:
+					hoverOpacity = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() { highlightOpacityIdx = hit.Index })
+					}
+				case UITypeFill:
+					hoverFill = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() {
+							fillIdx = hit.Index
+							if openCallout != nil {
+								openCallout.fill = calloutFill(col)
+							}
+						})
+					}
+				case UITypeRadius:
+					hoverRadius = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() { roundRadiusIdx = hit.Index })
+					}
+				case UITypeStroke:
+					hoverStroke = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress {
+						changeSetting(func() {
+							on := freehandToggles[hit.Index].on
+							*on = !*on
+						})
+					}
+				case UITypeHistory:
+					hoverHistory = hit.Index
+					if e.Button == mouse.ButtonLeft && e.Direction == mouse.DirPress && copyHistory != nil {
+						copyHistory(hit.Index)
 						w.Send(paint.Event{})
 					}
 				}
 
-				if e.Direction == mouse.DirNone {
-					w.Send(paint.Event{})
+				if e.Direction == mouse.DirNone && hit.Rect != hoverRect {
+					damage(uiRect(hoverRect.Union(hit.Rect)))
+					hoverRect = hit.Rect
 				}
 				continue
 			} else {
-				if hoverTab != -1 || hoverShortcut != -1 || hoverTool != -1 || hoverPalette != -1 || hoverWidth != -1 || hoverNumber != -1 || hoverTextSize != -1 {
+				if hoverTab != -1 || hoverShortcut != -1 || hoverTool != -1 || hoverPalette != -1 || hoverWidth != -1 || hoverNumber != -1 || hoverTextSize != -1 || hoverOpacity != -1 || hoverFill != -1 || hoverRadius != -1 || hoverStroke != -1 || hoverLink != -1 || hoverAspect != -1 || hoverStyle != -1 || hoverAlign != -1 || hoverLayer != -1 || hoverTabClose != -1 || hoverTabScroll != -1 {
 					hoverTab = -1
 					hoverShortcut = -1
 					hoverTool = -1
@@ -931,7 +2431,19 @@ func (a *AppState) Main(s screen.Screen) {
 					hoverWidth = -1
 					hoverNumber = -1
 					hoverTextSize = -1
-					w.Send(paint.Event{})
+					hoverOpacity = -1
+					hoverFill = -1
+					hoverRadius = -1
+					hoverStroke = -1
+					hoverLink = -1
+					hoverAspect = -1
+					hoverStyle = -1
+					hoverAlign = -1
+					hoverLayer = -1
+					hoverTabClose = -1
+					hoverTabScroll = -1
+					damage(uiRect(hoverRect))
+					hoverRect = image.Rectangle{}
 				}
 			}
 
@@ -939,6 +2451,18 @@ func (a *AppState) Main(s screen.Screen) {
 
 			mx := int((float64(e.X)-float64(baseRect.Min.X))/tabs[current].Zoom) - tabs[current].Offset.X
 			my := int((float64(e.Y)-float64(baseRect.Min.Y))/tabs[current].Zoom) - tabs[current].Offset.Y
+			if e.Button == mouse.ButtonRight && e.Direction == mouse.DirPress && tool == ToolNumber && annotationEnabled {
+				// Right clicking a marker removes it and renumbers the
+				// markers after it.
+				if i := tabs[current].numberAt(image.Pt(mx, my)); i >= 0 {
+					tabs[current].beginItemEdit()
+					tabs[current].removeNumber(i)
+					tabs[current].markEdited()
+					tabs[current].commitEdit()
+					w.Send(paint.Event{})
+				}
+				continue
+			}
 			if e.Button == mouse.ButtonLeft {
 				if !annotationEnabled && tool != ToolMove {
 					continue
@@ -975,12 +2499,133 @@ func (a *AppState) Main(s screen.Screen) {
 					case ToolDraw:
 						active = act
 						last = image.Point{mx, my}
-					case ToolCircle, ToolLine, ToolArrow, ToolRect, ToolNumber:
+						stroke = newFreehand(last, widthAt(tabs[current].WidthIdx), time.Now())
+						tabs[current].beginEdit()
+					case ToolHighlight:
+						active = act
+						last = image.Point{mx, my}
+						marker = highlighter{}
+						tabs[current].beginEdit()
+					case ToolPolygon:
+						// Each click places a vertex. Clicking the first
+						// vertex again, or double clicking, closes it.
+						p := image.Point{mx, my}
+						near := func(q image.Point) bool {
+							d := p.Sub(q)
+							reach := int(math.Ceil(float64(ui(polygonCloseDistance)) / tabs[current].Zoom))
+							return d.X*d.X+d.Y*d.Y <= reach*reach
+						}
+						now := time.Now()
+						switch {
+						case len(polygon) >= 3 && near(polygon[0]):
+							closePolygon()
+							w.Send(paint.Event{})
+						case len(polygon) > 0 && near(polygon[len(polygon)-1]) && now.Sub(polygonClick) < polygonDoubleClick:
+							closePolygon()
+							w.Send(paint.Event{})
+						default:
+							polygon = append(polygon, p)
+							polygonClick = now
+							damage(overlayDamage())
+						}
+					case ToolCallout:
+						// Dragging the open callout's tail, handles or box
+						// changes it; clicking elsewhere places it, then
+						// picks up the callout there or starts a new one.
+						p := image.Point{mx, my}
+						calloutDrag, calloutTail, calloutNew = cropNone, false, false
+						if openCallout != nil {
+							if p.In(calloutTipHandle(openCallout.tip)) {
+								calloutTail = true
+							} else {
+								for i, hr := range cropHandleRects(openCallout.box) {
+									if p.In(hr) {
+										calloutDrag = cropAction(i + int(cropResizeTL))
+										break
+									}
+								}
+								if calloutDrag == cropNone && p.In(openCallout.box) {
+									calloutDrag = cropMove
+								}
+							}
+							if !calloutTail && calloutDrag == cropNone {
+								placeCallout()
+							}
+						}
+						if openCallout == nil {
+							if i := tabs[current].calloutAt(p); i >= 0 {
+								tabs[current].beginItemEdit()
+								before := tabs[current].takeItem(i)
+								tabs[current].markEdited()
+								c := before.callout
+								openCallout, calloutBefore, calloutAt = &c, &before, i
+								colorIdx, textSizeIdx, tabs[current].WidthIdx = c.colorIdx, c.sizeIdx, c.widthIdx
+								col = paletteColorAt(colorIdx)
+								a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
+								calloutDrag = cropMove
+							} else {
+								c := newCallout(image.Rectangle{Min: p, Max: p}, colorIdx, tabs[current].WidthIdx)
+								openCallout = &c
+								calloutDrag, calloutNew = cropResizeBR, true
+							}
+						}
+						calloutStart = p
+						calloutStartBox, calloutStartTip = openCallout.box, openCallout.tip
+						if calloutNew {
+							calloutStartBox = image.Rectangle{Min: p, Max: p}
+						}
+						w.Send(paint.Event{})
+					case ToolCircle, ToolLine, ToolArrow, ToolRect, ToolRoundRect:
+						active = act
+						last = image.Point{mx, my}
+						tabs[current].beginEdit()
+					case ToolNumber:
+						// Markers are kept beside the pixels, so placing
+						// one copies none of them.
 						active = act
 						last = image.Point{mx, my}
+						tabs[current].beginItemEdit()
+					case ToolSelect:
+						// Clicking an item selects it, ready to drag the
+						// selection; shift-clicking adds or removes it.
+						// Dragging from anywhere else stretches a rubber
+						// band, which without shift starts a new selection.
+						p := image.Point{mx, my}
+						t := &tabs[current]
+						shift := e.Modifiers&key.ModShift != 0
+						id := t.itemAt(p)
+						switch {
+						case id != 0 && shift:
+							t.toggleSelected(id)
+						case id != 0:
+							if !slices.Contains(t.selected, id) {
+								t.selected = []uint64{id}
+							}
+							active, selectBand = act, false
+						default:
+							if !shift {
+								t.selected = nil
+							}
+							active, selectBand = act, true
+						}
+						selectStart, selectAt = p, p
+						w.Send(paint.Event{})
 					case ToolText:
 						if textInputActive {
 							textPos = image.Point{mx, my}
+						} else if i := tabs[current].textAt(image.Pt(mx, my)); i >= 0 {
+							// Reopen placed text with the size and colour it
+							// was drawn in.
+							tabs[current].beginItemEdit()
+							it := tabs[current].takeItem(i)
+							tabs[current].markEdited()
+							pt := it.text
+							reopened, reopenedAt = &it, i
+							textInputActive = true
+							textInput, textPos, textSizeIdx = pt.text, pt.pos, pt.sizeIdx
+							colorIdx = pt.colorIdx
+							col = paletteColorAt(colorIdx)
+							a.applySettingsFromUI(colorIdx, tabs[current].WidthIdx)
 						} else {
 							textInputActive = true
 							textInput = ""
@@ -993,41 +2638,28 @@ func (a *AppState) Main(s screen.Screen) {
 						active = actionNone
 						continue
 					}
-					if active == actionCrop && tool == ToolCrop {
-						dx := mx - cropStart.X
-						dy := my - cropStart.Y
-						r := cropStartRect
-						switch cropMode {
-						case cropMove:
-							r = r.Add(image.Pt(dx, dy))
-						case cropResizeTL:
-							r.Min.X = cropStartRect.Min.X + dx
-							r.Min.Y = cropStartRect.Min.Y + dy
-						case cropResizeT:
-							r.Min.Y = cropStartRect.Min.Y + dy
-						case cropResizeTR:
-							r.Min.Y = cropStartRect.Min.Y + dy
-							r.Max.X = cropStartRect.Max.X + dx
-						case cropResizeR:
-							r.Max.X = cropStartRect.Max.X + dx
-						case cropResizeBR:
-							r.Max.X = cropStartRect.Max.X + dx
-							r.Max.Y = cropStartRect.Max.Y + dy
-						case cropResizeB:
-							r.Max.Y = cropStartRect.Max.Y + dy
-						case cropResizeBL:
-							r.Min.X = cropStartRect.Min.X + dx
-							r.Max.Y = cropStartRect.Max.Y + dy
-						case cropResizeL:
-							r.Min.X = cropStartRect.Min.X + dx
-						}
-						if r.Min.X > r.Max.X {
-							r.Min.X, r.Max.X = r.Max.X, r.Min.X
-						}
-						if r.Min.Y > r.Max.Y {
-							r.Min.Y, r.Max.Y = r.Max.Y, r.Min.Y
+					if tool == ToolCallout {
+						calloutDrag, calloutTail, calloutNew = cropNone, false, false
+					}
+					if active == actionSelect && tool == ToolSelect {
+						if selectBand {
+							tabs[current].selectIn(image.Rectangle{Min: selectStart, Max: image.Pt(mx, my)}.Canon())
+						} else if d := selectDrag(image.Pt(mx, my)).Sub(selectStart); d != (image.Point{}) {
+							moveSelection(func(rects []image.Rectangle) []image.Point {
+								offs := make([]image.Point, len(rects))
+								for i := range offs {
+									offs[i] = d
+								}
+								return offs
+							})
 						}
-						cropRect = r
+						// The moved items can grow the canvas, so the whole
+						// frame is drawn again.
+						active = actionNone
+						w.Send(paint.Event{})
+					}
+					if active == actionCrop && tool == ToolCrop {
+						cropRect = dragCrop(cropStartRect, cropMode, image.Pt(mx, my).Sub(cropStart), cropRatio())
 					}
 					if annotationEnabled && active == actionDraw && tool != ToolCrop {
 						switch tool {
@@ -1048,10 +2680,23 @@ func (a *AppState) Main(s screen.Screen) {
 							}
 							br := image.Rect(minX, minY, maxX, maxY).Inset(-widthAt(tabs[current].WidthIdx) - 2)
 							shift := ensureCanvasContains(&tabs[current], br)
+							stroke.moved(shift)
 							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawLine(tabs[current].Image, last.X, last.Y, mx, my, col, widthAt(tabs[current].WidthIdx))
+							stroke.add(tabs[current].Image, image.Point{mx, my}, time.Now(), col)
+							stroke.finish(tabs[current].Image, col)
+							stroke = freehand{}
+						case ToolHighlight:
+							thick := highlightWidth(tabs[current].WidthIdx)
+							br := image.Rect(min(last.X, mx), min(last.Y, my), max(last.X, mx), max(last.Y, my)).Inset(-thick - 2)
+							shift := ensureCanvasContains(&tabs[current], br)
+							marker.moved(shift)
+							last = last.Sub(shift)
+							mx -= shift.X
+							my -= shift.Y
+							marker.line(tabs[current].Image, last.X, last.Y, mx, my, col, thick, highlightAlpha())
+							marker = highlighter{}
 						case ToolCircle:
 							rx := int(math.Abs(float64(mx - last.X)))
 							ry := int(math.Abs(float64(my - last.Y)))
@@ -1060,7 +2705,10 @@ func (a *AppState) Main(s screen.Screen) {
 							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawEllipse(tabs[current].Image, last.X, last.Y, rx, ry, col, widthAt(tabs[current].WidthIdx))
+							if fill, ok := shapeFill(col); ok {
+								fillEllipse(tabs[current].Image, last.X, last.Y, rx, ry, fill)
+							}
+							dashEllipse(tabs[current].Image, last.X, last.Y, rx, ry, col, widthAt(tabs[current].WidthIdx), newDasher(lineStyleIdx, widthAt(tabs[current].WidthIdx)))
 						case ToolLine:
 							minX, minY := last.X, last.Y
 							maxX, maxY := mx, my
@@ -1081,7 +2729,7 @@ func (a *AppState) Main(s screen.Screen) {
 							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawLine(tabs[current].Image, last.X, last.Y, mx, my, col, widthAt(tabs[current].WidthIdx))
+							dashLine(tabs[current].Image, last.X, last.Y, mx, my, col, widthAt(tabs[current].WidthIdx), newDasher(lineStyleIdx, widthAt(tabs[current].WidthIdx)))
 						case ToolArrow:
 							minX, minY := last.X, last.Y
 							maxX, maxY := mx, my
@@ -1102,7 +2750,7 @@ func (a *AppState) Main(s screen.Screen) {
 							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawArrow(tabs[current].Image, last.X, last.Y, mx, my, col, widthAt(tabs[current].WidthIdx))
+							dashArrow(tabs[current].Image, last.X, last.Y, mx, my, col, widthAt(tabs[current].WidthIdx), newDasher(lineStyleIdx, widthAt(tabs[current].WidthIdx)))
 						case ToolRect:
 							minX, minY := last.X, last.Y
 							maxX, maxY := mx, my
@@ -1123,16 +2771,26 @@ func (a *AppState) Main(s screen.Screen) {
 							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawRect(tabs[current].Image, image.Rect(last.X, last.Y, mx, my), col, widthAt(tabs[current].WidthIdx))
-						case ToolNumber:
-							s := numberSizes[numberIdx]
-							br := image.Rect(mx-s, my-s, mx+s, my+s)
+							if fill, ok := shapeFill(col); ok {
+								fillRect(tabs[current].Image, image.Rect(last.X, last.Y, mx, my), fill)
+							}
+							dashRect(tabs[current].Image, image.Rect(last.X, last.Y, mx, my), col, widthAt(tabs[current].WidthIdx), newDasher(lineStyleIdx, widthAt(tabs[current].WidthIdx)))
+						case ToolRoundRect:
+							br := image.Rect(last.X, last.Y, mx, my).Inset(-widthAt(tabs[current].WidthIdx) - 2)
 							shift := ensureCanvasContains(&tabs[current], br)
+							last = last.Sub(shift)
 							mx -= shift.X
 							my -= shift.Y
-							drawNumberBox(tabs[current].Image, mx, my, tabs[current].NextNumber, col, s)
-							tabs[current].NextNumber++
+							rect := image.Rect(last.X, last.Y, mx, my)
+							if fill, ok := shapeFill(col); ok {
+								fillRoundRect(tabs[current].Image, rect, roundRadii[roundRadiusIdx], fill)
+							}
+							drawRoundRect(tabs[current].Image, rect, roundRadii[roundRadiusIdx], col, widthAt(tabs[current].WidthIdx))
+						case ToolNumber:
+							tabs[current].placeNumber(image.Pt(mx, my), numberSizes[numberIdx], colorIdx, numberLinkIdx)
 						}
+						tabs[current].markEdited()
+						tabs[current].commitEdit()
 						w.Send(paint.Event{})
 					}
 					if active == actionMove && tool == ToolMove {
@@ -1146,41 +2804,11 @@ func (a *AppState) Main(s screen.Screen) {
 			}
 
 			if active == actionCrop && tool == ToolCrop && e.Direction == mouse.DirNone {
-				dx := mx - cropStart.X
-				dy := my - cropStart.Y
-				r := cropStartRect
-				switch cropMode {
-				case cropMove:
-					r = r.Add(image.Pt(dx, dy))
-				case cropResizeTL:
-					r.Min.X = cropStartRect.Min.X + dx
-					r.Min.Y = cropStartRect.Min.Y + dy
-				case cropResizeT:
-					r.Min.Y = cropStartRect.Min.Y + dy
-				case cropResizeTR:
-					r.Min.Y = cropStartRect.Min.Y + dy
-					r.Max.X = cropStartRect.Max.X + dx
-				case cropResizeR:
-					r.Max.X = cropStartRect.Max.X + dx
-				case cropResizeBR:
-					r.Max.X = cropStartRect.Max.X + dx
-					r.Max.Y = cropStartRect.Max.Y + dy
-				case cropResizeB:
-					r.Max.Y = cropStartRect.Max.Y + dy
-				case cropResizeBL:
-					r.Min.X = cropStartRect.Min.X + dx
-					r.Max.Y = cropStartRect.Max.Y + dy
-				case cropResizeL:
-					r.Min.X = cropStartRect.Min.X + dx
-				}
-				if r.Min.X > r.Max.X {
-					r.Min.X, r.Max.X = r.Max.X, r.Min.X
-				}
-				if r.Min.Y > r.Max.Y {
-					r.Min.Y, r.Max.Y = r.Max.Y, r.Min.Y
-				}
+				r := dragCrop(cropStartRect, cropMode, image.Pt(mx, my).Sub(cropStart), cropRatio())
+				view := imageScreenRect(tabs[current], width, height)
+				old := cropDamage(cropSelection(true, cropStart, cropRect), view, tabs[current].Zoom)
 				cropRect = r
-				w.Send(paint.Event{})
+				damage(old.Union(cropDamage(cropSelection(true, cropStart, cropRect), view, tabs[current].Zoom)))
 			}
 
 			if annotationEnabled && active == actionDraw && tool == ToolDraw && e.Direction == mouse.DirNone {
@@ -1200,12 +2828,38 @@ func (a *AppState) Main(s screen.Screen) {
 					maxY = last.Y
 				}
 				br := image.Rect(minX, minY, maxX, maxY).Inset(-widthAt(tabs[current].WidthIdx) - 2)
+				canvas := tabs[current].Image.Bounds()
 				shift := ensureCanvasContains(&tabs[current], br)
+				stroke.moved(shift)
 				last = last.Sub(shift)
 				p = p.Sub(shift)
-				drawLine(tabs[current].Image, last.X, last.Y, p.X, p.Y, col, widthAt(tabs[current].WidthIdx))
+				// A smoothed stroke lags a point behind and can bow past br.
+				drawn := stroke.add(tabs[current].Image, p, time.Now(), col)
+				tabs[current].markEdited()
 				last = p
-				w.Send(paint.Event{})
+				if tabs[current].Image.Bounds() == canvas {
+					damage(imageDamage(br.Union(drawn), imageScreenRect(tabs[current], width, height), tabs[current].Zoom))
+				} else {
+					w.Send(paint.Event{})
+				}
+			}
+			if annotationEnabled && active == actionDraw && tool == ToolHighlight && e.Direction == mouse.DirNone {
+				p := image.Point{mx, my}
+				thick := highlightWidth(tabs[current].WidthIdx)
+				br := image.Rect(min(last.X, p.X), min(last.Y, p.Y), max(last.X, p.X), max(last.Y, p.Y)).Inset(-thick - 2)
+				canvas := tabs[current].Image.Bounds()
+				shift := ensureCanvasContains(&tabs[current], br)
+				marker.moved(shift)
+				last = last.Sub(shift)
+				p = p.Sub(shift)
+				marker.line(tabs[current].Image, last.X, last.Y, p.X, p.Y, col, thick, highlightAlpha())
+				tabs[current].markEdited()
+				last = p
+				if tabs[current].Image.Bounds() == canvas {
+					damage(imageDamage(br, imageScreenRect(tabs[current], width, height), tabs[current].Zoom))
+				} else {
+					w.Send(paint.Event{})
+				}
 			}
 			if active == actionMove && tool == ToolMove && e.Direction == mouse.DirNone {
 				dx := int(float64(int(e.X)-moveStart.X) / tabs[current].Zoom)
@@ -1213,46 +2867,250 @@ func (a *AppState) Main(s screen.Screen) {
 				tabs[current].Offset = moveOffset.Add(image.Pt(dx, dy))
 				w.Send(paint.Event{})
 			}
+			if active == actionSelect && tool == ToolSelect && e.Direction == mouse.DirNone {
+				before := overlayDamage()
+				selectAt = image.Pt(mx, my)
+				if !selectBand {
+					selectAt = selectDrag(selectAt)
+				}
+				damage(before.Union(overlayDamage()))
+			}
+			if tool == ToolCallout && openCallout != nil && (calloutTail || calloutDrag != cropNone) && e.Direction == mouse.DirNone {
+				d := image.Pt(mx, my).Sub(calloutStart)
+				before := overlayDamage()
+				if calloutTail {
+					openCallout.tip = calloutStartTip.Add(d)
+				} else {
+					openCallout.box = resizeRect(calloutStartBox, calloutDrag, d)
+					if g, reach := guidesFor(nil); reach > 0 && calloutDrag == cropMove {
+						openCallout.box = openCallout.box.Add(g.moveOffset(openCallout.box, reach))
+					} else if reach > 0 {
+						openCallout.box = g.resize(openCallout.box, calloutDrag, reach).Canon()
+					}
+					openCallout.fit()
+					if calloutNew {
+						openCallout.pointDown()
+					}
+				}
+				damage(before.Union(overlayDamage()))
+			}
 		case key.Event:
 			if e.Direction == key.DirPress {
+				if urlInputActive {
+					switch e.Code {
+					case key.CodeReturnEnter:
+						frame.URL = strings.TrimSpace(urlInput)
+						urlInputActive = false
+						message, messageUntil = "frame address set", time.Now().Add(2*time.Second)
+					case key.CodeEscape:
+						urlInputActive = false
+						messageUntil = time.Time{}
+					case key.CodeDeleteBackspace:
+						if r := []rune(urlInput); len(r) > 0 {
+							urlInput = string(r[:len(r)-1])
+						}
+					default:
+						if e.Rune > 0 {
+							urlInput += string(e.Rune)
+						}
+					}
+					if urlInputActive {
+						message, messageUntil = "address: "+urlInput+"_", time.Now().Add(time.Hour)
+					}
+					damage(toastDamage(message))
+					continue
+				}
+				if tabTitleActive {
+					switch e.Code {
+					case key.CodeReturnEnter:
+						tabTitleActive = false
+						messageUntil = time.Time{}
+						if title := strings.TrimSpace(tabTitle); title != "" && tabTitleIdx < len(tabs) {
+							tabs[tabTitleIdx].Title = title
+						}
+					case key.CodeEscape:
+						tabTitleActive = false
+						messageUntil = time.Time{}
+					case key.CodeDeleteBackspace:
+						if r := []rune(tabTitle); len(r) > 0 {
+							tabTitle = string(r[:len(r)-1])
+						}
+					default:
+						if e.Rune > 0 {
+							tabTitle += string(e.Rune)
+						}
+					}
+					if tabTitleActive {
+						message, messageUntil = "tab title: "+tabTitle+"_", time.Now().Add(time.Hour)
+					}
+					w.Send(paint.Event{})
+					continue
+				}
+				if cropInputActive {
+					before := overlayDamage()
+					prompt := "crop size WxH: "
+					if cropInputAspect {
+						prompt = "aspect ratio W:H: "
+					}
+					switch e.Code {
+					case key.CodeReturnEnter:
+						cropInputActive = false
+						messageUntil = time.Time{}
+						if cropInputAspect {
+							if ratio, err := parseCropAspect(cropInput); err != nil {
+								message, messageUntil = err.Error(), time.Now().Add(3*time.Second)
+							} else {
+								cropCustom, cropAspectIdx = ratio, cropCustomIdx
+								cropRect = lockAspect(cropRect, cropResizeBR, ratio)
+							}
+						} else if size, err := parseCropSize(cropInput); err != nil {
+							message, messageUntil = err.Error(), time.Now().Add(3*time.Second)
+						} else {
+							// The selection keeps its top left corner, or
+							// starts at the image's when there is none.
+							cropRect = image.Rectangle{Min: cropRect.Min, Max: cropRect.Min.Add(size)}
+						}
+					case key.CodeEscape:
+						cropInputActive = false
+						messageUntil = time.Time{}
+					case key.CodeDeleteBackspace:
+						if r := []rune(cropInput); len(r) > 0 {
+							cropInput = string(r[:len(r)-1])
+						}
+					default:
+						if e.Rune > 0 {
+							cropInput += string(e.Rune)
+						}
+					}
+					if cropInputActive {
+						message, messageUntil = prompt+cropInput+"_", time.Now().Add(time.Hour)
+					}
+					damage(before.Union(overlayDamage()).Union(toastDamage(message)))
+					continue
+				}
 				if textInputActive {
 					switch e.Code {
 					case key.CodeReturnEnter:
-						d := &font.Drawer{Face: textFaces[textSizeIdx]}
-						width := d.MeasureString(textInput).Ceil()
-						metrics := textFaces[textSizeIdx].Metrics()
-						br := image.Rect(textPos.X, textPos.Y-metrics.Ascent.Ceil(), textPos.X+width, textPos.Y+metrics.Descent.Ceil())
-						shift := ensureCanvasContains(&tabs[current], br)
-						textPos = textPos.Sub(shift)
-						d = &font.Drawer{Dst: tabs[current].Image, Src: image.NewUniform(paletteColorAt(colorIdx)), Face: textFaces[textSizeIdx]}
-						d.Dot = fixed.P(textPos.X, textPos.Y)
-						d.DrawString(textInput)
-						textInputActive = false
+						placeText()
 						w.Send(paint.Event{})
 						continue
 					case key.CodeEscape:
-						textInputActive = false
+						cancelText()
 						w.Send(paint.Event{})
 						continue
 					case key.CodeDeleteBackspace:
 						if len(textInput) > 0 {
+							before := overlayDamage()
 							textInput = textInput[:len(textInput)-1]
-							w.Send(paint.Event{})
+							damage(before)
 						}
 						continue
 					}
 					if e.Rune > 0 {
 						textInput += string(e.Rune)
+						damage(overlayDamage())
+					}
+					continue
+				}
+				if historyOpen {
+					switch {
+					case e.Code == key.CodeEscape:
+						historyOpen, history, hoverHistory = false, nil, -1
+						w.Send(paint.Event{})
+						continue
+					case e.Rune >= '1' && e.Rune <= '9' && copyHistory != nil:
+						copyHistory(int(e.Rune - '1'))
+						w.Send(paint.Event{})
+						continue
+					}
+				}
+				if tool == ToolCallout && openCallout != nil {
+					// Placing the callout can grow the canvas, so only
+					// typing repaints just the callout.
+					before := overlayDamage()
+					switch e.Code {
+					case key.CodeReturnEnter:
+						placeCallout()
 						w.Send(paint.Event{})
+						continue
+					case key.CodeEscape:
+						cancelCallout()
+						w.Send(paint.Event{})
+						continue
+					case key.CodeDeleteBackspace:
+						if r := []rune(openCallout.text); len(r) > 0 {
+							openCallout.text = string(r[:len(r)-1])
+						}
+					default:
+						if e.Rune > 0 {
+							openCallout.text += string(e.Rune)
+							openCallout.fit()
+						}
 					}
+					damage(before.Union(overlayDamage()))
 					continue
 				}
-				ks := KeyShortcut{Rune: unicode.ToLower(e.Rune), Code: e.Code, Modifiers: e.Modifiers}
-				if action, ok := keyboardAction[ks]; ok {
+				if tool == ToolPolygon && len(polygon) > 0 {
+					switch e.Code {
+					case key.CodeReturnEnter:
+						closePolygon()
+						w.Send(paint.Event{})
+						continue
+					case key.CodeEscape:
+						damage(overlayDamage())
+						polygon = nil
+						continue
+					case key.CodeDeleteBackspace:
+						damage(overlayDamage())
+						polygon = polygon[:len(polygon)-1]
+						continue
+					}
+				}
+				ks := KeyShortcut{Rune: e.Rune, Code: e.Code, Modifiers: e.Modifiers}.normalized()
+				if tool == ToolSelect {
+					t := &tabs[current]
+					selected := len(t.selectedItems()) > 0
+					switch {
+					case selected && (e.Code == key.CodeDeleteForward || e.Code == key.CodeDeleteBackspace):
+						t.beginItemEdit()
+						t.deleteItems(t.selected)
+						t.markEdited()
+						t.commitEdit()
+						t.selected = nil
+						w.Send(paint.Event{})
+						continue
+					case selected && e.Code == key.CodeEscape:
+						damage(overlayDamage())
+						t.selected = nil
+						continue
+					case selected && a.boundTo("copyitems", ks):
+						copiedItems = t.copyItems()
+						message, messageUntil = "copied 1 item", time.Now().Add(2*time.Second)
+						if n := len(copiedItems); n != 1 {
+							message = fmt.Sprintf("copied %d items", n)
+						}
+						damage(toastDamage(message))
+						continue
+					case len(copiedItems) > 0 && a.boundTo("pasteitems", ks):
+						pasteItems(copiedItems)
+						w.Send(paint.Event{})
+						continue
+					case selected && a.boundTo("dupitems", ks):
+						pasteItems(t.copyItems())
+						w.Send(paint.Event{})
+						continue
+					}
+				}
+				action, ok := keyboardAction[ks]
+				if !ok && ks.Modifiers == key.ModShift {
+					// Shift+M picks the Move tool as M does.
+					action, ok = keyboardAction[KeyShortcut{Rune: ks.Rune, Code: ks.Code}]
+				}
+				if ok {
 					if action == "delete" {
 						if !confirmDelete {
 							confirmDelete = true
-							message = "press D again to delete"
+							message = fmt.Sprintf("press %s again to delete", ks)
 							log.Print(message)
 							messageUntil = time.Now().Add(2 * time.Second)
 							w.Send(paint.Event{})
@@ -1264,74 +3122,14 @@ func (a *AppState) Main(s screen.Screen) {
 					}
 					confirmDelete = false
 					handleShortcut(action)
+					if quitRequested {
+						quit()
+						return
+					}
 					continue
 				}
 				confirmDelete = false
 				switch e.Rune {
-				case 'm', 'M':
-					tool = ToolMove
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'r', 'R':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolCrop
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'b', 'B':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolDraw
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'o', 'O':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolCircle
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'l', 'L':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolLine
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'a', 'A':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolArrow
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'x', 'X':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolRect
-					active = actionNone
-					w.Send(paint.Event{})
-				case 't', 'T':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolText
-					active = actionNone
-					w.Send(paint.Event{})
-				case 'h', 'H':
-					if !annotationEnabled {
-						continue
-					}
-					tool = ToolNumber
-					active = actionNone
-					w.Send(paint.Event{})
-				case '$':
-					if applyShadow != nil {
-						applyShadow()
-					}
 				case '1', '2', '3', '4', '5', '6', '7', '8', '9':
 					if e.Modifiers&key.ModControl != 0 {
 						idx := int(e.Rune - '1')
@@ -1340,25 +3138,6 @@ func (a *AppState) Main(s screen.Screen) {
 							w.Send(paint.Event{})
 						}
 					}
-				case 'q', 'Q':
-					paintMu.Lock()
-					if paintCancel != nil {
-						paintCancel()
-					}
-					paintMu.Unlock()
-					return
-				case '+', '=':
-					tabs[current].Zoom *= 1.25
-					if tabs[current].Zoom < 0.1 {
-						tabs[current].Zoom = 0.1
-					}
-					w.Send(paint.Event{})
-				case '-':
-					tabs[current].Zoom /= 1.25
-					if tabs[current].Zoom < 0.1 {
-						tabs[current].Zoom = 0.1
-					}
-					w.Send(paint.Event{})
 				case -1:
 					switch e.Code {
 					case key.CodeLeftArrow:
@@ -1381,10 +3160,6 @@ func (a *AppState) Main(s screen.Screen) {
 							tabs[current].Offset.Y += 10
 							w.Send(paint.Event{})
 						}
-					case key.Code4:
-						if e.Modifiers&key.ModShift != 0 && applyShadow != nil {
-							applyShadow()
-						}
 					}
 				}
 			}
@@ -0,0 +1,266 @@
+package appstate
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// TabStorage selects how the images of inactive tabs are kept.
+type TabStorage int
+
+const (
+	// TabStorageMemory keeps every tab's pixels in memory.
+	TabStorageMemory TabStorage = iota
+	// TabStorageCompress keeps inactive tabs' pixels deflated in memory.
+	// Screenshots usually shrink to a small fraction of their size.
+	TabStorageCompress
+	// TabStorageDisk writes inactive tabs' deflated pixels to a temporary
+	// directory, removed when the window closes.
+	TabStorageDisk
+)
+
+// ParseTabStorage reads a storage mode name: memory, compress or disk.
+func ParseTabStorage(s string) (TabStorage, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "memory":
+		return TabStorageMemory, nil
+	case "compress":
+		return TabStorageCompress, nil
+	case "disk":
+		return TabStorageDisk, nil
+	}
+	return TabStorageMemory, fmt.Errorf("unknown tab storage %q (want memory, compress or disk)", s)
+}
+
+func (m TabStorage) String() string {
+	switch m {
+	case TabStorageCompress:
+		return "compress"
+	case TabStorageDisk:
+		return "disk"
+	}
+	return "memory"
+}
+
+// WithTabStorage sets how the images of inactive tabs are kept.
+func WithTabStorage(mode TabStorage) Option {
+	return func(a *AppState) { a.TabStorage = mode }
+}
+
+// tabStore releases the pixels of inactive tabs, packing one at a time in
+// the background, and unpacks a tab again when it becomes active. Packing
+// is lossless: the RGBA pixels themselves are deflated, so premultiplied
+// values survive exactly.
+type tabStore struct {
+	mode TabStorage
+	send func(controlEvent)
+	// dir holds spilled tabs in disk mode.
+	dir  string
+	busy bool
+	// spills are the files written, so ones no tab holds any more can be
+	// removed.
+	spills map[string]bool
+}
+
+// newTabStore returns a store for mode that reports packed tabs through
+// send. Disk mode falls back to compressing in memory when no temporary
+// directory can be made.
+func newTabStore(mode TabStorage, send func(controlEvent)) *tabStore {
+	ts := &tabStore{mode: mode, send: send, spills: map[string]bool{}}
+	if mode == TabStorageDisk {
+		dir, err := os.MkdirTemp("", "shineyshot-tabs-")
+		if err != nil {
+			log.Printf("tab storage: compressing in memory instead: %v", err)
+			ts.mode = TabStorageCompress
+		}
+		ts.dir = dir
+	}
+	return ts
+}
+
+// packedTab carries a tab image packed in the background back to the
+// event loop.
+type packedTab struct {
+	src        *image.RGBA
+	generation uint64
+	data       []byte
+	path       string
+	size       int
+}
+
+// settle unpacks the current tab if it was packed and starts packing the
+// next inactive tab that still holds its pixels. It must be called from the
+// event loop before tabs[current].Image is used.
+func (ts *tabStore) settle(tabs []Tab, current int) error {
+	var err error
+	if t := &tabs[current]; t.Image == nil {
+		err = ts.unpack(t)
+	}
+	if len(ts.spills) > 0 {
+		held := map[string]bool{}
+		for _, t := range tabs {
+			held[t.spill] = true
+		}
+		for path := range ts.spills {
+			if !held[path] {
+				_ = os.Remove(path)
+				delete(ts.spills, path)
+			}
+		}
+	}
+	if ts.mode == TabStorageMemory || ts.busy {
+		return err
+	}
+	for i := range tabs {
+		if i == current || tabs[i].Image == nil {
+			continue
+		}
+		ts.busy = true
+		ts.pack(tabs[i])
+		break
+	}
+	return err
+}
+
+// pack deflates a copy of t's pixels in the background and sends the
+// result to the event loop, which keeps it if the tab is still inactive
+// and unedited.
+func (ts *tabStore) pack(t Tab) {
+	img := t.Image
+	b := img.Bounds()
+	pix := make([]byte, 0, b.Dx()*b.Dy()*4)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := img.PixOffset(b.Min.X, y)
+		pix = append(pix, img.Pix[i:i+b.Dx()*4]...)
+	}
+	p := &packedTab{src: img, generation: t.generation}
+	go func() {
+		var buf bytes.Buffer
+		zw, _ := flate.NewWriter(&buf, flate.BestSpeed)
+		_, _ = zw.Write(pix)
+		_ = zw.Close()
+		p.data, p.size = buf.Bytes(), buf.Len()
+		if ts.mode == TabStorageDisk {
+			if path, err := ts.spill(p.data); err != nil {
+				log.Printf("tab storage: keeping tab in memory: %v", err)
+			} else {
+				p.data, p.path = nil, path
+			}
+		}
+		ts.send(controlEvent{Packed: p})
+	}()
+}
+
+// spill writes data to a new file in the store's directory.
+func (ts *tabStore) spill(data []byte) (string, error) {
+	f, err := os.CreateTemp(ts.dir, "tab-*.deflate")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// packed releases the pixels of the tab p was packed from, unless it became
+// active or was edited or closed in the meantime.
+func (ts *tabStore) packed(p *packedTab, tabs []Tab, current int) {
+	ts.busy = false
+	if p.path != "" {
+		ts.spills[p.path] = true
+	}
+	for i := range tabs {
+		t := &tabs[i]
+		if t.Image != p.src {
+			continue
+		}
+		if i == current || t.generation != p.generation {
+			return
+		}
+		t.bounds = t.Image.Bounds()
+		t.Image = nil
+		t.packed, t.spill, t.packedSize = p.data, p.path, p.size
+		return
+	}
+}
+
+// unpack restores t's pixels. If they cannot be read back the tab is left
+// with a blank image of the same size and the error is returned.
+func (ts *tabStore) unpack(t *Tab) error {
+	img, err := ts.image(*t)
+	t.Image = img
+	t.packed, t.spill, t.packedSize = nil, "", 0
+	t.markEdited()
+	if err != nil {
+		return fmt.Errorf("restoring tab %s: %w", t.Title, err)
+	}
+	return nil
+}
+
+// image returns t's pixels, reading a packed tab into a new image without
+// unpacking the tab itself. On error the image is blank.
+func (ts *tabStore) image(t Tab) (*image.RGBA, error) {
+	if t.Image != nil {
+		return t.Image, nil
+	}
+	img := image.NewRGBA(t.bounds)
+	data, err := t.packed, error(nil)
+	if t.spill != "" {
+		data, err = os.ReadFile(t.spill)
+	}
+	if err == nil {
+		zr := flate.NewReader(bytes.NewReader(data))
+		_, err = io.ReadFull(zr, img.Pix)
+		_ = zr.Close()
+	}
+	return img, err
+}
+
+// usage describes the memory the tabs take for the status bar: resident
+// pixels and compressed tabs in memory, the pixels their undo histories
+// hold, and spilled tabs on disk.
+func (ts *tabStore) usage(tabs []Tab) string {
+	var mem, disk int
+	for _, t := range tabs {
+		switch {
+		case t.Image != nil:
+			mem += len(t.Image.Pix)
+		case t.spill != "":
+			disk += t.packedSize
+		default:
+			mem += t.packedSize
+		}
+	}
+	label := "tabs " + formatMiB(mem)
+	if h := historyBytes(tabs); h > 0 {
+		label += " + history " + formatMiB(h)
+	}
+	if disk > 0 {
+		label += " + " + formatMiB(disk) + " on disk"
+	}
+	return label
+}
+
+func formatMiB(n int) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// close removes the spilled tabs.
+func (ts *tabStore) close() {
+	if ts.dir != "" {
+		_ = os.RemoveAll(ts.dir)
+	}
+}
@@ -0,0 +1,128 @@
+package appstate
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/example/shineyshot/internal/project"
+)
+
+func TestTabStoreRoundTrip(t *testing.T) {
+	for _, mode := range []TabStorage{TabStorageCompress, TabStorageDisk} {
+		t.Run(mode.String(), func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			events := make(chan controlEvent, 1)
+			ts := newTabStore(mode, func(ev controlEvent) { events <- ev })
+			defer ts.close()
+
+			// A sub-image with semi-transparent pixels checks that the
+			// premultiplied values and the bounds come back exactly.
+			img := image.NewRGBA(image.Rect(-5, -5, 70, 50)).SubImage(image.Rect(2, 3, 64, 41)).(*image.RGBA)
+			for y := 3; y < 41; y++ {
+				for x := 2; x < 64; x++ {
+					img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), 1, uint8(x + y)})
+				}
+			}
+			want := image.NewRGBA(img.Bounds())
+			copy(want.Pix, cropImage(img, img.Bounds()).Pix)
+			tabs := []Tab{{Image: image.NewRGBA(image.Rect(0, 0, 4, 4)), Title: "1"}, {Image: img, Title: "2"}}
+
+			if err := ts.settle(tabs, 0); err != nil {
+				t.Fatal(err)
+			}
+			ts.packed((<-events).Packed, tabs, 0)
+			if tabs[1].Image != nil {
+				t.Fatal("inactive tab kept its pixels")
+			}
+			if mode == TabStorageDisk {
+				if _, err := os.Stat(tabs[1].spill); err != nil {
+					t.Fatalf("spilled tab: %v", err)
+				}
+			}
+			// Reading a packed tab, as exports do, leaves it packed.
+			if read, err := ts.image(tabs[1]); err != nil || !bytes.Equal(read.Pix, want.Pix) || tabs[1].Image != nil {
+				t.Fatalf("reading the packed tab: %v", err)
+			}
+
+			if err := ts.settle(tabs, 1); err != nil {
+				t.Fatal(err)
+			}
+			got := tabs[1].Image
+			if got == nil || got.Bounds() != want.Bounds() || !bytes.Equal(got.Pix, want.Pix) {
+				t.Fatal("restored tab differs from the original")
+			}
+			// Tab 1 is packed in turn, and the file tab 2 was restored from
+			// is removed.
+			ts.packed((<-events).Packed, tabs, 1)
+			if tabs[0].Image != nil || len(ts.spills) > 1 {
+				t.Fatalf("after switching: tab 1 image %v, %d spill files", tabs[0].Image != nil, len(ts.spills))
+			}
+		})
+	}
+}
+
+func TestTabStoreDropsEditedPack(t *testing.T) {
+	events := make(chan controlEvent, 1)
+	ts := newTabStore(TabStorageCompress, func(ev controlEvent) { events <- ev })
+	tabs := []Tab{{Image: image.NewRGBA(image.Rect(0, 0, 4, 4))}, {Image: image.NewRGBA(image.Rect(0, 0, 8, 8))}}
+	_ = ts.settle(tabs, 0)
+	tabs[1].markEdited()
+	ts.packed((<-events).Packed, tabs, 0)
+	if tabs[1].Image == nil {
+		t.Fatal("tab edited while packing lost its pixels")
+	}
+}
+
+func TestSessionWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session", "last"+project.Ext)
+	s := newSessionWriter(path)
+	store := newTabStore(TabStorageMemory, func(controlEvent) {})
+	defer store.close()
+	tabs := []Tab{{Image: image.NewRGBA(image.Rect(0, 0, 8, 6)), Title: "one", Zoom: 1}}
+
+	// A write in the background is waited for by the next, which finds
+	// nothing changed and leaves the file as it was.
+	s.save(tabs, 0, store, false)
+	s.save(tabs, 0, store, true)
+	p, err := project.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tabs) != 1 || p.Tabs[0].Title != "one" {
+		t.Fatalf("session holds %+v", p.Tabs)
+	}
+
+	tabs[0].Image.SetRGBA(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	tabs[0].markEdited()
+	tabs = append(tabs, Tab{Image: image.NewRGBA(image.Rect(0, 0, 4, 4)), Title: "two", Zoom: 1})
+	s.save(tabs, 1, store, true)
+	if p, err = project.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tabs) != 2 || p.Current != 1 || p.Tabs[0].Image.Pix[0] != 0xff {
+		t.Fatalf("session not rewritten with the edit and new tab: %d tabs, current %d", len(p.Tabs), p.Current)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+
+	// A write that fails is tried again the next time, though the tabs have
+	// not changed since.
+	failing := newSessionWriter(filepath.Join(t.TempDir(), "blocked", "last"+project.Ext))
+	if err := os.WriteFile(filepath.Dir(failing.path), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	failing.save(tabs, 1, store, false)
+	failing.save(tabs, 1, store, true)
+	if err := os.Remove(filepath.Dir(failing.path)); err != nil {
+		t.Fatal(err)
+	}
+	failing.save(tabs, 1, store, true)
+	if p, err = project.Open(failing.path); err != nil || len(p.Tabs) != 2 {
+		t.Fatalf("failed write not tried again: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package appstate
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+)
+
+// placedText is text the Text tool placed on a tab. It is kept as a
+// tabItem, so clicking it with the Text tool again reopens it for editing.
+type placedText struct {
+	text string
+	// pos is the start of the baseline.
+	pos      image.Point
+	sizeIdx  int
+	colorIdx int
+}
+
+// textBounds returns the area text drawn from the baseline start pos
+// covers.
+func textBounds(text string, pos image.Point, sizeIdx int) image.Rectangle {
+	face := textFaces()[sizeIdx]
+	width := (&font.Drawer{Face: face}).MeasureString(text).Ceil()
+	m := face.Metrics()
+	return image.Rect(pos.X, pos.Y-m.Ascent.Ceil(), pos.X+width, pos.Y+m.Descent.Ceil())
+}
+
+// placeText puts text on top of the tab's items, growing the canvas to fit.
+// Empty text places nothing.
+func (t *Tab) placeText(text string, pos image.Point, sizeIdx, colorIdx int) {
+	if text == "" {
+		return
+	}
+	t.putItem(len(t.items), tabItem{text: placedText{text: text, pos: pos, sizeIdx: sizeIdx, colorIdx: colorIdx}})
+}
+
+// textAt returns the index of the topmost visible, unlocked text at p, or
+// -1.
+func (t *Tab) textAt(p image.Point) int {
+	for i := len(t.items) - 1; i >= 0; i-- {
+		it := t.items[i]
+		if !it.isCallout && !it.hidden && !t.isLocked(it.id) && p.In(it.bounds()) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,94 @@
+package appstate
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// uiScale is how many window pixels each pixel of the editor's tabs,
+// toolbar, status bar and popups covers. They are laid out and hit tested in
+// those UI pixels and scaled up as the frame is drawn, so they stay legible
+// on HiDPI displays while the canvas keeps the window's full resolution.
+var uiScale = 1.0
+
+// uiLayer is reused between frames to draw the UI into before it is scaled.
+var uiLayer *image.RGBA
+
+// uiScaleFor returns the UI scale suiting a window whose resolution is
+// pixelsPerPt, as shiny reports it: 1 up to 1.5 times the 96 DPI the UI was
+// drawn for, and that ratio rounded to a half above it. Displays that
+// report a plausible DPI below that, or none, keep the UI at 1.
+func uiScaleFor(pixelsPerPt float32) float64 {
+	ratio := float64(pixelsPerPt) * 72 / 96
+	if ratio < 1.5 || math.IsInf(ratio, 0) || math.IsNaN(ratio) {
+		return 1
+	}
+	return math.Min(math.Round(ratio*2)/2, 4)
+}
+
+// ui converts a length in UI pixels to window pixels.
+func ui(n int) int {
+	return int(math.Round(float64(n) * uiScale))
+}
+
+// uiRect converts r from UI pixels to the window pixels it covers.
+func uiRect(r image.Rectangle) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(float64(r.Min.X)*uiScale)),
+		int(math.Floor(float64(r.Min.Y)*uiScale)),
+		int(math.Ceil(float64(r.Max.X)*uiScale)),
+		int(math.Ceil(float64(r.Max.Y)*uiScale)),
+	)
+}
+
+// fromUI converts r from window pixels to the UI pixels covering it.
+func fromUI(r image.Rectangle) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(float64(r.Min.X)/uiScale)),
+		int(math.Floor(float64(r.Min.Y)/uiScale)),
+		int(math.Ceil(float64(r.Max.X)/uiScale)),
+		int(math.Ceil(float64(r.Max.Y)/uiScale)),
+	)
+}
+
+// uiPoint converts a window position to UI pixels.
+func uiPoint(x, y float32) image.Point {
+	return image.Pt(int(float64(x)/uiScale), int(float64(y)/uiScale))
+}
+
+// beginUI returns the image the UI over b is drawn into, in UI pixels, and
+// the window's size in them. At a scale of 1 that is b itself.
+func beginUI(b *image.RGBA, width, height int) (*image.RGBA, int, int) {
+	if uiScale == 1 {
+		return b, width, height
+	}
+	full := image.Rect(0, 0, int(math.Ceil(float64(width)/uiScale)), int(math.Ceil(float64(height)/uiScale)))
+	if uiLayer == nil || uiLayer.Rect != full {
+		uiLayer = image.NewRGBA(full)
+	}
+	// Only the area b repaints is drawn, starting out clear so the canvas
+	// shows through wherever the UI does not cover it.
+	layer := uiLayer.SubImage(fromUI(b.Bounds())).(*image.RGBA)
+	draw.Draw(layer, layer.Rect, image.Transparent, image.Point{}, draw.Src)
+	return layer, full.Dx(), full.Dy()
+}
+
+// endUI scales the UI drawn into layer by beginUI over b.
+func endUI(b, layer *image.RGBA) {
+	if layer == b {
+		return
+	}
+	uiScaler().Scale(b, uiRect(layer.Rect), layer, layer.Rect, draw.Over, nil)
+}
+
+// uiScaler returns the scaler that draws UI pixels at uiScale: whole
+// multiples keep bitmap text sharp, and anything between is smoothed.
+func uiScaler() xdraw.Scaler {
+	if uiScale == math.Trunc(uiScale) {
+		return xdraw.NearestNeighbor
+	}
+	return xdraw.ApproxBiLinear
+}
@@ -35,24 +35,69 @@ func ExpandCanvas(img *image.RGBA, rect image.Rectangle) (*image.RGBA, image.Poi
 	return newImg, image.Pt(minX, minY)
 }
 
-// DrawLine draws a line between the two points with the given thickness and color.
-func DrawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int) {
-	drawLine(img, x0, y0, x1, y1, col, thick)
+// DrawLine draws a line between the two points with the given thickness,
+// color and style.
+func DrawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, style LineStyle) {
+	dashLine(img, x0, y0, x1, y1, col, thick, newDasher(style, thick))
 }
 
-// DrawArrow draws an arrow between the two points with the given thickness and color.
-func DrawArrow(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int) {
-	drawArrow(img, x0, y0, x1, y1, col, thick)
+// DrawArrow draws an arrow between the two points with the given thickness
+// and color, styling its shaft.
+func DrawArrow(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, style LineStyle) {
+	dashArrow(img, x0, y0, x1, y1, col, thick, newDasher(style, thick))
 }
 
-// DrawRect draws a rectangle on the image with the given thickness and color.
-func DrawRect(img *image.RGBA, rect image.Rectangle, col color.Color, thick int) {
-	drawRect(img, rect, col, thick)
+// DrawHighlight lays a translucent marker stroke between the two points,
+// multiplying col into the image at the given opacity.
+func DrawHighlight(img *image.RGBA, x0, y0, x1, y1 int, col color.Color, thick int, opacity uint8) {
+	var h highlighter
+	h.line(img, x0, y0, x1, y1, col, thick, opacity)
 }
 
-// DrawCircle draws a circle centred at (cx, cy) with radius r.
-func DrawCircle(img *image.RGBA, cx, cy, r int, col color.Color, thick int) {
-	drawCircle(img, cx, cy, r, col, thick)
+// DrawRect draws a rectangle on the image with the given thickness, color
+// and style.
+func DrawRect(img *image.RGBA, rect image.Rectangle, col color.Color, thick int, style LineStyle) {
+	dashRect(img, rect, col, thick, newDasher(style, thick))
+}
+
+// DrawCircle draws a circle centred at (cx, cy) with radius r. Dashed and
+// dotted circles are traced as an ellipse so their pattern runs in order.
+func DrawCircle(img *image.RGBA, cx, cy, r int, col color.Color, thick int, style LineStyle) {
+	if style == LineSolid {
+		drawCircle(img, cx, cy, r, col, thick)
+		return
+	}
+	dashEllipse(img, cx, cy, r, r, col, thick, newDasher(style, thick))
+}
+
+// FillRect lays fill over rect, blending when it is translucent.
+func FillRect(img *image.RGBA, rect image.Rectangle, fill color.Color) {
+	fillRect(img, rect, fill)
+}
+
+// FillCircle lays fill over the circle centred at (cx, cy) with radius r.
+func FillCircle(img *image.RGBA, cx, cy, r int, fill color.Color) {
+	fillEllipse(img, cx, cy, r, r, fill)
+}
+
+// DrawRoundRect draws a rectangle with corners rounded to radius.
+func DrawRoundRect(img *image.RGBA, rect image.Rectangle, radius int, col color.Color, thick int) {
+	drawRoundRect(img, rect, radius, col, thick)
+}
+
+// FillRoundRect lays fill over a rectangle with corners rounded to radius.
+func FillRoundRect(img *image.RGBA, rect image.Rectangle, radius int, fill color.Color) {
+	fillRoundRect(img, rect, radius, fill)
+}
+
+// DrawPolygon draws the closed polygon through pts.
+func DrawPolygon(img *image.RGBA, pts []image.Point, col color.Color, thick int) {
+	drawPolygon(img, pts, col, thick)
+}
+
+// FillPolygon lays fill over the closed polygon through pts.
+func FillPolygon(img *image.RGBA, pts []image.Point, fill color.Color) {
+	fillPolygon(img, pts, fill)
 }
 
 // CropImage returns a copy of the given rectangle from img.
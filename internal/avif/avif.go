@@ -0,0 +1,121 @@
+// Package avif encodes images as AVIF files with avifenc from libavif.
+// AV1 encoding is too large to carry in Go, so AVIF output is available
+// where avifenc is installed and reports an error elsewhere.
+package avif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// avifenc is libavif's command line encoder, packaged as libavif-bin,
+// libavif-tools or libavif on most distributions.
+const avifenc = "avifenc"
+
+const (
+	// DefaultQuality is the quality used when none is configured,
+	// avifenc's own default.
+	DefaultQuality = 60
+	// DefaultSpeed is the encoder speed used when none is configured,
+	// avifenc's own default.
+	DefaultSpeed = 6
+	// MaxSpeed is the fastest speed, which compresses least.
+	MaxSpeed = 10
+)
+
+// Options sets how an image is encoded.
+type Options struct {
+	// Quality, from 0 to 100, trades size for fidelity. 100 is lossless.
+	Quality int
+	// Speed, from 0 to MaxSpeed, trades encoding time for size.
+	Speed int
+}
+
+// ParseQuality reads a quality from 0 to 100. Empty means DefaultQuality.
+func ParseQuality(s string) (int, error) {
+	return parseRange(s, "quality", DefaultQuality, 100)
+}
+
+// ParseSpeed reads a speed from 0 to MaxSpeed. Empty means DefaultSpeed.
+func ParseSpeed(s string) (int, error) {
+	return parseRange(s, "speed", DefaultSpeed, MaxSpeed)
+}
+
+func parseRange(s, what string, def, hi int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > hi {
+		return 0, fmt.Errorf("invalid avif %s %q (want 0 to %d)", what, s, hi)
+	}
+	return v, nil
+}
+
+// Available reports whether avifenc can be found.
+func Available() bool {
+	_, err := exec.LookPath(avifenc)
+	return err == nil
+}
+
+// Encode writes img as an AVIF file. A nil o uses DefaultQuality and
+// DefaultSpeed.
+func Encode(w io.Writer, img image.Image, o *Options) error {
+	if o == nil {
+		o = &Options{Quality: DefaultQuality, Speed: DefaultSpeed}
+	}
+	if _, err := exec.LookPath(avifenc); err != nil {
+		return fmt.Errorf("saving AVIF needs %s (libavif): %w", avifenc, err)
+	}
+	// avifenc reads and writes files, not pipes.
+	dir, err := os.MkdirTemp("", "shineyshot-avif-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.avif")
+	f, err := os.Create(in)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"-q", strconv.Itoa(o.Quality), "-s", strconv.Itoa(o.Speed)}
+	if o.Quality == 100 {
+		args = append(args, "--lossless")
+	}
+	cmd := exec.Command(avifenc, append(args, in, out)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", avifenc, msg)
+		}
+		return fmt.Errorf("%s: %w", avifenc, err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return errors.New(avifenc + " wrote an empty file")
+	}
+	_, err = w.Write(data)
+	return err
+}
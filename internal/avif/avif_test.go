@@ -0,0 +1,42 @@
+package avif
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestParseQualityAndSpeed(t *testing.T) {
+	if q, err := ParseQuality(""); err != nil || q != DefaultQuality {
+		t.Errorf(`ParseQuality("") = %d, %v`, q, err)
+	}
+	if q, err := ParseQuality(" 35 "); err != nil || q != 35 {
+		t.Errorf(`ParseQuality(" 35 ") = %d, %v`, q, err)
+	}
+	if s, err := ParseSpeed(""); err != nil || s != DefaultSpeed {
+		t.Errorf(`ParseSpeed("") = %d, %v`, s, err)
+	}
+	for _, s := range []string{"101", "-1", "high"} {
+		if _, err := ParseQuality(s); err == nil {
+			t.Errorf("ParseQuality(%q) succeeded", s)
+		}
+	}
+	if _, err := ParseSpeed("11"); err == nil {
+		t.Error(`ParseSpeed("11") succeeded`)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	if !Available() {
+		t.Skip(avifenc + " is not installed")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, &Options{Quality: 50, Speed: MaxSpeed}); err != nil {
+		t.Fatal(err)
+	}
+	// An ISO BMFF ftyp box with the avif brand leads the file.
+	if data := buf.Bytes(); len(data) < 12 || string(data[4:12]) != "ftypavif" {
+		t.Errorf("output does not start with an avif ftyp box: %q", data[:min(12, len(data))])
+	}
+}
@@ -1,10 +1,15 @@
 package capture
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
 	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // CaptureOptions describes optional preferences when capturing screenshots.
@@ -15,118 +20,659 @@ type CaptureOptions struct {
 	// IncludeCursor requests that the cursor be embedded into the captured
 	// image. Support depends on the compositor and platform backend.
 	IncludeCursor bool
+	// IncludeOwnWindows leaves shineyshot's own windows on screen. By default
+	// they are hidden while the desktop is captured.
+	IncludeOwnWindows bool
+	// Backend forces a screenshot backend by name instead of probing them in
+	// turn. Empty or BackendAuto keeps the automatic order.
+	Backend string
+	// Units selects how CaptureRegionRect reads its rectangle. Empty or
+	// UnitsPixel addresses captured pixels; UnitsLogical addresses the
+	// compositor layout and is scaled by the monitor holding the region.
+	Units string
+	// Delay waits this long before capturing, so menus can be opened or the
+	// pointer moved into place first.
+	Delay time.Duration
+	// Progress, when set, is called with the time left at the start of each
+	// second of Delay and with zero just before the capture.
+	Progress func(remaining time.Duration)
+	// Context, when set, abandons a capture still waiting on the desktop
+	// portal once it is done. Portal waits also time out on their own.
+	Context context.Context
 }
 
+// Region coordinate units accepted by CaptureOptions.Units.
+const (
+	UnitsPixel   = "pixel"
+	UnitsLogical = "logical"
+)
+
+// CheckUnits reports whether name is a known region coordinate unit.
+func CheckUnits(name string) error {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", UnitsPixel, UnitsLogical:
+		return nil
+	}
+	return fmt.Errorf("unknown region units %q (want %s or %s)", name, UnitsLogical, UnitsPixel)
+}
+
+// Screenshot backend names accepted by CaptureOptions.Backend.
+const (
+	BackendAuto       = "auto"
+	BackendScreencopy = "wlr"
+	BackendKWin       = "kwin"
+	BackendGNOME      = "gnome"
+	BackendPortal     = "portal"
+	BackendX11        = "x11"
+	// BackendExternal runs the first installed screenshot program suited to
+	// the session; the names after it force one program.
+	BackendExternal  = "external"
+	BackendGrim      = "grim"
+	BackendSpectacle = "spectacle"
+	BackendMaim      = "maim"
+	BackendScrot     = "scrot"
+)
+
+// Backends lists the screenshot backend names in the order they are probed.
+var Backends = []string{
+	BackendAuto, BackendScreencopy, BackendKWin, BackendGNOME, BackendPortal, BackendX11,
+	BackendExternal, BackendGrim, BackendSpectacle, BackendMaim, BackendScrot,
+}
+
+// CheckBackend reports whether name is a known screenshot backend.
+func CheckBackend(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	for _, b := range Backends {
+		if name == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown capture backend %q (want one of %s)", name, strings.Join(Backends, ", "))
+}
+
+// errBackendUnavailable reports that a compositor-specific backend does not
+// apply to this session, so the next backend should be tried quietly.
+var errBackendUnavailable = errors.New("capture backend unavailable")
+
 var (
+	wlrScreencopyFn      = wlrScreencopy
+	kwinScreenshotFn     = kwinScreenshot
+	gnomeScreenshotFn    = gnomeScreenshot
 	portalCapture        = portalScreenshot
 	portalScreenshotFn   = portalCapture
 	pipewireCapture      = pipewireScreenshot
 	pipewireScreenshotFn = pipewireCapture
+	screencastWindowFn   = screencastWindow
+	cursorImageFn        = cursorImage
+	hideOwnWindowsFn     = hideOwnWindows
+	externalScreenshotFn = externalScreenshot
+	sleepFn              = time.Sleep
 )
 
+// waitForDelay counts down opts.Delay, reporting each second through
+// opts.Progress, and returns opts with the delay spent so captures built on
+// other captures wait only once. A done opts.Context cancels the capture
+// before the next second starts.
+func waitForDelay(opts CaptureOptions) (CaptureOptions, error) {
+	if opts.Delay <= 0 {
+		return opts, nil
+	}
+	for left := opts.Delay; left > 0; {
+		if opts.Context != nil && opts.Context.Err() != nil {
+			return opts, fmt.Errorf("capture cancelled: %w", opts.Context.Err())
+		}
+		if opts.Progress != nil {
+			opts.Progress(left)
+		}
+		step := min(left, time.Second)
+		sleepFn(step)
+		left -= step
+	}
+	if opts.Context != nil && opts.Context.Err() != nil {
+		return opts, fmt.Errorf("capture cancelled: %w", opts.Context.Err())
+	}
+	if opts.Progress != nil {
+		opts.Progress(0)
+	}
+	opts.Delay = 0
+	return opts, nil
+}
+
+// PortalWindowSelector asks the desktop's ScreenCast dialog for the window
+// instead of matching the window list.
+const PortalWindowSelector = "portal"
+
+// directBackends capture the desktop without a dialog. They are probed in
+// order before the portal, and report errBackendUnavailable when the session
+// does not offer them.
+var directBackends = []struct {
+	name, label string
+	fn          *func(CaptureOptions) (*image.RGBA, error)
+}{
+	{BackendScreencopy, "wlr-screencopy", &wlrScreencopyFn},
+	{BackendKWin, "kwin", &kwinScreenshotFn},
+	{BackendGNOME, "gnome-shell", &gnomeScreenshotFn},
+}
+
 func screenshot(interactive bool, opts CaptureOptions) (*image.RGBA, error) {
+	if err := CheckBackend(opts.Backend); err != nil {
+		return nil, err
+	}
+	if !opts.IncludeOwnWindows {
+		restore := hideOwnWindowsFn()
+		defer restore()
+	}
+	switch name := strings.ToLower(strings.TrimSpace(opts.Backend)); name {
+	case "", BackendAuto:
+	case BackendPortal:
+		return portalScreenshotFn(interactive, opts)
+	case BackendX11:
+		if interactive {
+			return nil, fmt.Errorf("the %s backend cannot select a region interactively", name)
+		}
+		return pipewireScreenshotFn(opts)
+	case BackendExternal, BackendGrim, BackendSpectacle, BackendMaim, BackendScrot:
+		return externalScreenshotFn(interactive, opts, name)
+	default:
+		if interactive {
+			return nil, fmt.Errorf("the %s backend cannot select a region interactively", name)
+		}
+		for _, b := range directBackends {
+			if b.name == name {
+				img, err := (*b.fn)(opts)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", b.label, err)
+				}
+				return img, nil
+			}
+		}
+	}
+
+	// Compositors with a direct capture API can be read without a portal
+	// dialog.
+	var directErrs []error
+	if !interactive {
+		for _, b := range directBackends {
+			img, err := (*b.fn)(opts)
+			if err == nil {
+				return img, nil
+			}
+			if !errors.Is(err, errBackendUnavailable) {
+				directErrs = append(directErrs, fmt.Errorf("%s: %w", b.label, err))
+			}
+		}
+	}
 	img, err := portalScreenshotFn(interactive, opts)
 	if err == nil {
 		return img, nil
 	}
-	if interactive || !isPortalUnsupportedError(err) {
+	if len(directErrs) > 0 {
+		err = errors.Join(append(directErrs, err)...)
+	}
+	if !isPortalUnsupportedError(err) {
 		return nil, err
 	}
-	fallback, fallbackErr := pipewireScreenshotFn(opts)
-	if fallbackErr != nil {
-		return nil, errors.Join(err, fmt.Errorf("pipewire fallback: %w", fallbackErr))
+	if !interactive {
+		fallback, fallbackErr := pipewireScreenshotFn(opts)
+		if fallbackErr == nil {
+			return fallback, nil
+		}
+		err = errors.Join(err, fmt.Errorf("pipewire fallback: %w", fallbackErr))
+	}
+	// As a last resort run a screenshot program installed on the system.
+	img, toolErr := externalScreenshotFn(interactive, opts, BackendExternal)
+	if toolErr == nil {
+		return img, nil
 	}
-	return fallback, nil
+	if !errors.Is(toolErr, errBackendUnavailable) {
+		err = errors.Join(err, fmt.Errorf("external tool: %w", toolErr))
+	}
+	return nil, err
 }
 
+// AllMonitorsSelector selects every monitor for a screen capture: the whole
+// desktop, as an empty selector does.
+const AllMonitorsSelector = "all"
+
 // CaptureScreenshot captures the desktop. When a display selector is provided it will
 // crop the result to the matching monitor.
-func CaptureScreenshot(display string, opts CaptureOptions) (*image.RGBA, error) {
+func CaptureScreenshot(display string, opts CaptureOptions) (CaptureResult, error) {
+	opts, err := waitForDelay(opts)
+	if err != nil {
+		return CaptureResult{}, err
+	}
 	img, err := screenshot(false, opts)
 	if err != nil {
-		return nil, fmt.Errorf("capture screenshot: %w", err)
+		return CaptureResult{}, fmt.Errorf("capture screenshot: %w", err)
 	}
-	if display == "" {
-		return img, nil
+	if display == "" || strings.EqualFold(strings.TrimSpace(display), AllMonitorsSelector) {
+		monitors, _ := ListMonitors()
+		res := newResult(img, desktopBounds(monitors, img.Bounds()), monitors)
+		res.Token = recaptureSpec{Kind: recaptureScreen}.token()
+		return res, nil
 	}
 	monitors, err := ListMonitors()
 	if err != nil {
-		return nil, fmt.Errorf("capture screenshot for display %q: %w", display, err)
+		return CaptureResult{}, fmt.Errorf("capture screenshot for display %q: %w", display, err)
 	}
 	monitor, err := FindMonitor(monitors, display)
 	if err != nil {
-		return nil, fmt.Errorf("capture screenshot for display %q: %w", display, err)
+		return CaptureResult{}, fmt.Errorf("capture screenshot for display %q: %w", display, err)
+	}
+	// The image starts at the desktop's top-left monitor, which can sit left
+	// of or above the origin.
+	inImage := monitor.Rect
+	for _, placed := range monitorsInImage(monitors, img.Bounds()) {
+		if placed.Index == monitor.Index {
+			inImage = placed.Rect
+		}
+	}
+	cropped, err := cropToRect(img, inImage)
+	if err != nil {
+		return CaptureResult{}, fmt.Errorf("capture screenshot for display %q: %w", display, err)
+	}
+	res := newResult(cropped, monitor.Rect, monitors)
+	name := monitor.Name
+	if name == "" {
+		name = strconv.Itoa(monitor.Index)
+	}
+	res.Token = recaptureSpec{Kind: recaptureScreen, Monitor: name}.token()
+	return res, nil
+}
+
+// VirtualScreen is a capture of the whole desktop and the monitors it spans.
+type VirtualScreen struct {
+	Image *image.RGBA
+	// Monitors lists each monitor with Rect in Image coordinates, so the
+	// capture can be split per monitor without another grab.
+	Monitors []MonitorInfo
+}
+
+// CaptureAllMonitors captures every monitor as one image. When the monitor
+// layout cannot be read the image is reported as a single monitor.
+func CaptureAllMonitors(opts CaptureOptions) (VirtualScreen, error) {
+	opts, err := waitForDelay(opts)
+	if err != nil {
+		return VirtualScreen{}, err
+	}
+	img, err := screenshot(false, opts)
+	if err != nil {
+		return VirtualScreen{}, fmt.Errorf("capture all monitors: %w", err)
+	}
+	monitors, err := ListMonitors()
+	if err != nil || len(monitors) == 0 {
+		whole := MonitorInfo{Name: "screen", Rect: img.Bounds(), Primary: true}
+		return VirtualScreen{Image: img, Monitors: []MonitorInfo{whole}}, nil
 	}
-	cropped, err := cropToRect(img, monitor.Rect)
+	return VirtualScreen{Image: img, Monitors: monitorsInImage(monitors, img.Bounds())}, nil
+}
+
+// SplitMonitors cuts a capture of the whole desktop into one result per
+// monitor, in the order ListMonitors reports them, each as if the monitor
+// had been captured on its own. Any other capture, or a desktop with one
+// monitor, is returned as the only result.
+func SplitMonitors(res CaptureResult) []CaptureResult {
+	if res.Image == nil || res.Window != nil {
+		return []CaptureResult{res}
+	}
+	monitors, err := ListMonitors()
 	if err != nil {
-		return nil, fmt.Errorf("capture screenshot for display %q: %w", display, err)
+		return []CaptureResult{res}
 	}
-	return cropped, nil
+	return splitMonitors(res, monitors)
 }
 
-// CaptureWindowDetailed captures the window that matches the selector and returns
-// both the image and the resolved window metadata. It prefers a direct X11 window
-// capture and falls back to cropping a desktop screenshot if the compositor
-// refuses to provide the pixels.
-func CaptureWindowDetailed(selector string, opts CaptureOptions) (*image.RGBA, WindowInfo, error) {
+func splitMonitors(res CaptureResult, monitors []MonitorInfo) []CaptureResult {
+	if len(monitors) < 2 || res.Region != desktopBounds(monitors, res.Image.Bounds()) {
+		return []CaptureResult{res}
+	}
+	desktop := make(map[int]MonitorInfo, len(monitors))
+	for _, mon := range monitors {
+		desktop[mon.Index] = mon
+	}
+	var parts []CaptureResult
+	for _, placed := range monitorsInImage(monitors, res.Image.Bounds()) {
+		img, err := cropToRect(res.Image, placed.Rect)
+		if err != nil {
+			continue
+		}
+		mon := desktop[placed.Index]
+		part := newResult(img, mon.Rect, monitors)
+		part.Time = res.Time
+		name := mon.Name
+		if name == "" {
+			name = strconv.Itoa(mon.Index)
+		}
+		part.Token = recaptureSpec{Kind: recaptureScreen, Monitor: name}.token()
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return []CaptureResult{res}
+	}
+	return parts
+}
+
+// monitorsInImage moves monitor rectangles from desktop coordinates, whose
+// top-left monitor may sit away from the origin, into the image's space.
+func monitorsInImage(monitors []MonitorInfo, bounds image.Rectangle) []MonitorInfo {
+	var desktop image.Rectangle
+	for _, mon := range monitors {
+		desktop = desktop.Union(mon.Rect)
+	}
+	shift := bounds.Min.Sub(desktop.Min)
+	out := make([]MonitorInfo, 0, len(monitors))
+	for _, mon := range monitors {
+		mon.Rect = mon.Rect.Add(shift).Intersect(bounds)
+		if mon.Rect.Empty() {
+			continue
+		}
+		out = append(out, mon)
+	}
+	return out
+}
+
+// CaptureWindow captures the window that matches the selector and reports it
+// in the result's Window. It prefers a direct X11 window capture and falls
+// back to cropping a desktop screenshot if the compositor refuses to provide
+// the pixels. With IncludeDecorations the window manager's frame is captured
+// instead and the reported Rect covers it. PickWindowSelector lets the user
+// click the window, through the portal's picker on Wayland.
+func CaptureWindow(selector string, opts CaptureOptions) (CaptureResult, error) {
+	opts, err := waitForDelay(opts)
+	if err != nil {
+		return CaptureResult{}, err
+	}
+	if strings.EqualFold(strings.TrimSpace(selector), PortalWindowSelector) {
+		return captureWindowViaPortal(selector, opts, "", nil)
+	}
+	if strings.EqualFold(strings.TrimSpace(selector), PickWindowSelector) {
+		picked, err := PickWindow()
+		if errors.Is(err, ErrPickUnsupported) {
+			return captureWindowViaPortal(selector, opts, "", err)
+		}
+		if err != nil {
+			return CaptureResult{}, fmt.Errorf("capture window: %w", err)
+		}
+		selector = fmt.Sprintf("id:%d", picked.ID)
+	}
 	windows, err := ListWindows()
 	if err != nil {
-		return nil, WindowInfo{}, fmt.Errorf("capture window %q: %w", selector, err)
+		if runningOnWayland() {
+			return captureWindowViaPortal(selector, opts, "", err)
+		}
+		return CaptureResult{}, fmt.Errorf("capture window %q: %w", selector, err)
 	}
 	info, err := SelectWindow(selector, windows)
 	if err != nil {
-		return nil, WindowInfo{}, fmt.Errorf("capture window %q: %w", selector, err)
+		if runningOnWayland() {
+			return captureWindowViaPortal(selector, opts, "", err)
+		}
+		return CaptureResult{}, fmt.Errorf("capture window %q: %w", selector, err)
+	}
+	if info.Unviewable() {
+		return CaptureResult{}, fmt.Errorf("capture window %q: %w", info.Title, ErrWindowMinimized)
 	}
 	if info.Rect.Empty() {
-		return nil, WindowInfo{}, fmt.Errorf("window has empty geometry")
+		return CaptureResult{}, fmt.Errorf("window has empty geometry")
 	}
-	img, err := captureWindowImage(info.ID)
-	if err == nil {
-		return img, info, nil
+	target := info.ID
+	if opts.IncludeDecorations {
+		// Keep the client area when the frame cannot be resolved.
+		if frameID, frame, err := backend.WindowFrame(info.ID); err == nil && !frame.Empty() {
+			target, info.Rect = frameID, frame
+		}
+	}
+	var directErr error
+	if target != 0 {
+		img, err := captureWindowImage(target)
+		if err == nil {
+			if opts.IncludeCursor {
+				drawCursor(img, info.Rect.Min)
+			}
+			return windowResult(img, info), nil
+		}
+		directErr = fmt.Errorf("direct window capture: %w", err)
 	}
-	directErr := fmt.Errorf("direct window capture: %w", err)
 	shot, err := screenshot(false, opts)
 	if err != nil {
 		fallbackErr := fmt.Errorf("fallback screenshot: %w", err)
-		return nil, WindowInfo{}, fmt.Errorf("window capture failed: %w", errors.Join(directErr, fallbackErr))
+		return CaptureResult{}, fmt.Errorf("window capture failed: %w", errors.Join(directErr, fallbackErr))
 	}
-	img, err = cropToRect(shot, info.Rect)
+	img, err := cropToRect(shot, info.Rect)
 	if err != nil {
 		fallbackErr := fmt.Errorf("fallback crop: %w", err)
-		return nil, WindowInfo{}, fmt.Errorf("window capture failed: %w", errors.Join(directErr, fallbackErr))
+		return CaptureResult{}, fmt.Errorf("window capture failed: %w", errors.Join(directErr, fallbackErr))
+	}
+	return windowResult(img, info), nil
+}
+
+func windowResult(img *image.RGBA, info WindowInfo) CaptureResult {
+	monitors, _ := ListMonitors()
+	res := newResult(img, info.Rect, monitors)
+	res.Window = &info
+	res.Token = recaptureSpec{Kind: recaptureWindow, Window: info.ID}.token()
+	return res
+}
+
+// captureWindowViaPortal lets the user pick the window in the ScreenCast
+// portal. Wayland sessions use it when the window list, which only covers X11
+// and XWayland clients outside sway and i3, has no match for the selector.
+// A restore token from an earlier pick shares the same window again without
+// the dialog where the portal allows it.
+func captureWindowViaPortal(selector string, opts CaptureOptions, restore string, listErr error) (CaptureResult, error) {
+	img, restore, err := screencastWindowFn(opts, restore)
+	if err != nil {
+		err = fmt.Errorf("screencast portal: %w", err)
+		if listErr != nil {
+			err = errors.Join(listErr, err)
+		}
+		return CaptureResult{}, fmt.Errorf("capture window %q: %w", selector, err)
+	}
+	info := WindowInfo{
+		Index:   -1,
+		Title:   "portal selection",
+		Rect:    img.Bounds(),
+		Monitor: -1,
+		Desktop: -1,
+	}
+	// The portal does not say where the window is.
+	res := newResult(img, image.Rectangle{}, nil)
+	res.Window = &info
+	if restore != "" {
+		res.Token = recaptureSpec{Kind: recaptureWindow, Restore: restore}.token()
 	}
-	return img, info, nil
+	return res, nil
 }
 
-// CaptureWindow captures a single window specified by the selector string.
-func CaptureWindow(selector string, opts CaptureOptions) (*image.RGBA, error) {
-	img, _, err := CaptureWindowDetailed(selector, opts)
-	return img, err
+// CaptureWorkspace composites the windows on a virtual desktop, including
+// sticky ones, onto a transparent canvas the size of the desktop. Windows are
+// drawn bottom-most first. Window managers usually unmap windows on hidden
+// desktops, so those are skipped unless a compositor keeps their contents;
+// an error is returned only when no window could be read.
+func CaptureWorkspace(desktop int, opts CaptureOptions) (CaptureResult, error) {
+	if _, err := waitForDelay(opts); err != nil {
+		return CaptureResult{}, err
+	}
+	windows, err := ListWindows()
+	if err != nil {
+		return CaptureResult{}, fmt.Errorf("capture desktop %d: %w", desktop, err)
+	}
+	monitors, _ := ListMonitors()
+	canvas := desktopBounds(monitors, image.Rectangle{})
+	var members []WindowInfo
+	own := 0
+	for _, win := range windows {
+		if win.Unviewable() {
+			continue
+		}
+		if win.Desktop == desktop || win.Desktop == AllDesktops {
+			members = append(members, win)
+		}
+		if win.Desktop == desktop {
+			own++
+		}
+	}
+	if own == 0 {
+		return CaptureResult{}, fmt.Errorf("capture desktop %d: no windows on it", desktop)
+	}
+	if canvas.Empty() {
+		for _, win := range members {
+			canvas = canvas.Union(win.Rect)
+		}
+	}
+	if canvas.Empty() {
+		return CaptureResult{}, fmt.Errorf("capture desktop %d: desktop has empty geometry", desktop)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, canvas.Dx(), canvas.Dy()))
+	var errs []error
+	drawn := 0
+	// Window lists are ordered top-most first.
+	for idx := len(members) - 1; idx >= 0; idx-- {
+		win := members[idx]
+		img, err := captureWindowImage(win.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("window %q: %w", win.Title, err))
+			continue
+		}
+		rect := img.Bounds().Add(win.Rect.Min.Sub(canvas.Min))
+		draw.Draw(dst, rect, img, img.Bounds().Min, draw.Over)
+		drawn++
+	}
+	if drawn == 0 {
+		return CaptureResult{}, fmt.Errorf("capture desktop %d: %w", desktop, errors.Join(errs...))
+	}
+	res := newResult(dst, canvas, monitors)
+	res.Token = recaptureSpec{Kind: recaptureWorkspace, Desktop: desktop}.token()
+	return res, nil
 }
 
 // CaptureRegion uses the portal to allow the user to select a region interactively.
-func CaptureRegion(opts CaptureOptions) (*image.RGBA, error) {
+// The result has no Region because the dialog does not report where the
+// selection was.
+func CaptureRegion(opts CaptureOptions) (CaptureResult, error) {
+	opts, err := waitForDelay(opts)
+	if err != nil {
+		return CaptureResult{}, err
+	}
 	img, err := screenshot(true, opts)
 	if err != nil {
-		return nil, fmt.Errorf("capture region: %w", err)
+		return CaptureResult{}, fmt.Errorf("capture region: %w", err)
 	}
-	return img, nil
+	return newResult(img, image.Rectangle{}, nil), nil
 }
 
-// CaptureRegionRect captures a specific rectangle in global screen coordinates.
-func CaptureRegionRect(rect image.Rectangle, opts CaptureOptions) (*image.RGBA, error) {
+// CaptureRegionRect captures a specific rectangle in global screen
+// coordinates, read in the units opts.Units names.
+func CaptureRegionRect(rect image.Rectangle, opts CaptureOptions) (CaptureResult, error) {
 	if rect.Empty() {
-		return nil, fmt.Errorf("region is empty")
+		return CaptureResult{}, fmt.Errorf("region is empty")
+	}
+	opts, err := waitForDelay(opts)
+	if err != nil {
+		return CaptureResult{}, err
+	}
+	if strings.EqualFold(strings.TrimSpace(opts.Units), UnitsLogical) {
+		monitors, err := ListMonitors()
+		if err != nil {
+			return CaptureResult{}, fmt.Errorf("convert logical region: %w", err)
+		}
+		rect = logicalToPixels(rect, monitors)
 	}
 	shot, err := screenshot(false, opts)
 	if err != nil {
-		return nil, fmt.Errorf("capture screenshot: %w", err)
+		return CaptureResult{}, fmt.Errorf("capture screenshot: %w", err)
+	}
+	monitors, err := ListMonitors()
+	if err != nil || len(monitors) == 0 {
+		img, err := cropToRect(shot, rect)
+		if err != nil {
+			return CaptureResult{}, fmt.Errorf("crop region: %w", err)
+		}
+		res := newResult(img, rect.Intersect(shot.Bounds()), nil)
+		res.Token = recaptureSpec{Kind: recaptureRegion, Region: rect}.token()
+		return res, nil
 	}
-	img, err := cropToRect(shot, rect)
+	img, visible, err := stitchRegion(shot, rect, monitors)
 	if err != nil {
-		return nil, fmt.Errorf("crop region: %w", err)
+		return CaptureResult{}, fmt.Errorf("crop region: %w", err)
+	}
+	res := newResult(img, visible, monitors)
+	res.Token = recaptureSpec{Kind: recaptureRegion, Region: rect}.token()
+	return res, nil
+}
+
+// stitchRegion copies the parts of rect, in desktop coordinates, that lie on
+// a monitor out of shot, which shows the desktop from its top-left corner.
+// The result is clamped to the monitors the region touches, returned as the
+// visible rectangle, and gaps between monitors in a non-rectangular layout
+// stay transparent.
+func stitchRegion(shot *image.RGBA, rect image.Rectangle, monitors []MonitorInfo) (*image.RGBA, image.Rectangle, error) {
+	var desktop, visible image.Rectangle
+	for _, mon := range monitors {
+		area := mon.pixelRect()
+		desktop = desktop.Union(area)
+		visible = visible.Union(rect.Intersect(area))
+	}
+	if visible.Empty() {
+		return nil, image.Rectangle{}, fmt.Errorf("region %v is not on any monitor (desktop %v)", rect, desktop)
+	}
+	shift := shot.Bounds().Min.Sub(desktop.Min)
+	dst := image.NewRGBA(image.Rect(0, 0, visible.Dx(), visible.Dy()))
+	drawn := false
+	for _, mon := range monitors {
+		part := visible.Intersect(mon.pixelRect()).Intersect(shot.Bounds().Sub(shift))
+		if part.Empty() {
+			continue
+		}
+		draw.Draw(dst, part.Sub(visible.Min), shot, part.Min.Add(shift), draw.Src)
+		drawn = true
+	}
+	if !drawn {
+		return nil, image.Rectangle{}, fmt.Errorf("region %v is outside the captured image", rect)
+	}
+	return dst, visible, nil
+}
+
+// pixelRect is the area a monitor covers in a desktop capture: captures keep
+// each monitor at its layout origin with its native resolution.
+func (m MonitorInfo) pixelRect() image.Rectangle {
+	scale := m.ScaleFactor()
+	size := image.Pt(int(math.Round(float64(m.Rect.Dx())*scale)), int(math.Round(float64(m.Rect.Dy())*scale)))
+	return image.Rectangle{Min: m.Rect.Min, Max: m.Rect.Min.Add(size)}
+}
+
+// logicalToPixels scales a rectangle in layout coordinates by the monitor
+// it starts on. Offsets within the monitor grow by its scale while the
+// origin stays put, matching pixelRect. Regions outside every monitor are
+// left unchanged.
+func logicalToPixels(rect image.Rectangle, monitors []MonitorInfo) image.Rectangle {
+	for _, mon := range monitors {
+		if !rect.Min.In(mon.Rect) {
+			continue
+		}
+		scale := mon.ScaleFactor()
+		at := func(p image.Point) image.Point {
+			off := p.Sub(mon.Rect.Min)
+			return mon.Rect.Min.Add(image.Pt(int(math.Round(float64(off.X)*scale)), int(math.Round(float64(off.Y)*scale))))
+		}
+		return image.Rectangle{Min: at(rect.Min), Max: at(rect.Max)}
+	}
+	return rect
+}
+
+// drawCursor composites the pointer over img, which shows the desktop from
+// origin in root coordinates. The capture is kept without a cursor when the
+// platform cannot report one.
+func drawCursor(img *image.RGBA, origin image.Point) {
+	sprite, pos, err := cursorImageFn()
+	if err != nil || sprite == nil {
+		return
 	}
-	return img, nil
+	rect := sprite.Bounds().Add(pos.Sub(origin).Add(img.Bounds().Min))
+	draw.Draw(img, rect, sprite, sprite.Bounds().Min, draw.Over)
 }
 
 func cropToRect(src *image.RGBA, rect image.Rectangle) (*image.RGBA, error) {
@@ -1,21 +1,40 @@
 package capture
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
 
+func TestMain(m *testing.M) {
+	// Never unmap windows on the desktop the tests happen to run on.
+	hideOwnWindowsFn = func() func() { return func() {} }
+	os.Exit(m.Run())
+}
+
 type fakeBackend struct {
 	monitors    []MonitorInfo
 	windows     []WindowInfo
 	monitorsErr error
 	windowsErr  error
 	captureErr  error
+	frameID     uint32
+	frame       image.Rectangle
+	frameErr    error
+	cursor      image.Point
+	cursorErr   error
+}
+
+func (f fakeBackend) CursorPosition() (image.Point, error) {
+	return f.cursor, f.cursorErr
 }
 
 func (f fakeBackend) ListMonitors() ([]MonitorInfo, error) {
@@ -32,6 +51,13 @@ func (f fakeBackend) ListWindows() ([]WindowInfo, error) {
 	return f.windows, nil
 }
 
+func (f fakeBackend) WindowFrame(id uint32) (uint32, image.Rectangle, error) {
+	if f.frameErr != nil {
+		return 0, image.Rectangle{}, f.frameErr
+	}
+	return f.frameID, f.frame, nil
+}
+
 func (f fakeBackend) CaptureWindowImage(uint32) (*image.RGBA, error) {
 	if f.captureErr != nil {
 		return nil, f.captureErr
@@ -39,7 +65,7 @@ func (f fakeBackend) CaptureWindowImage(uint32) (*image.RGBA, error) {
 	return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
 }
 
-func TestCaptureWindowDetailedListWindowsError(t *testing.T) {
+func TestCaptureWindowListWindowsError(t *testing.T) {
 	t.Helper()
 
 	originalBackend := backend
@@ -47,7 +73,7 @@ func TestCaptureWindowDetailedListWindowsError(t *testing.T) {
 	backend = fakeBackend{windowsErr: windowsErr}
 	t.Cleanup(func() { backend = originalBackend })
 
-	if _, _, err := CaptureWindowDetailed("foo", CaptureOptions{}); err == nil {
+	if _, err := CaptureWindow("foo", CaptureOptions{}); err == nil {
 		t.Fatalf("expected error")
 	} else {
 		if !errors.Is(err, windowsErr) {
@@ -62,6 +88,7 @@ func TestCaptureWindowDetailedListWindowsError(t *testing.T) {
 func TestScreenshotFallsBackToPipewire(t *testing.T) {
 	t.Helper()
 
+	stubUnavailableDirectBackends(t)
 	prevPortal := portalScreenshotFn
 	prevPipewire := pipewireScreenshotFn
 	t.Cleanup(func() {
@@ -87,14 +114,15 @@ func TestScreenshotFallsBackToPipewire(t *testing.T) {
 	if !called {
 		t.Fatalf("expected pipewire fallback to be used")
 	}
-	if got != want {
-		t.Fatalf("expected pipewire result, got %#v", got)
+	if got.Image != want {
+		t.Fatalf("expected pipewire result, got %#v", got.Image)
 	}
 }
 
 func TestScreenshotFallsBackWhenPortalDisconnects(t *testing.T) {
 	t.Helper()
 
+	stubUnavailableDirectBackends(t)
 	prevPortal := portalScreenshotFn
 	prevPipewire := pipewireScreenshotFn
 	t.Cleanup(func() {
@@ -120,14 +148,15 @@ func TestScreenshotFallsBackWhenPortalDisconnects(t *testing.T) {
 	if !called {
 		t.Fatalf("expected pipewire fallback to be used")
 	}
-	if got != want {
-		t.Fatalf("expected pipewire result, got %#v", got)
+	if got.Image != want {
+		t.Fatalf("expected pipewire result, got %#v", got.Image)
 	}
 }
 
 func TestScreenshotFallbackPipewireFailure(t *testing.T) {
 	t.Helper()
 
+	stubUnavailableDirectBackends(t)
 	prevPortal := portalScreenshotFn
 	prevPipewire := pipewireScreenshotFn
 	t.Cleanup(func() {
@@ -160,6 +189,7 @@ func TestScreenshotFallbackPipewireFailure(t *testing.T) {
 func TestInteractiveScreenshotDoesNotFallbackToPipewire(t *testing.T) {
 	t.Helper()
 
+	stubUnavailableDirectBackends(t)
 	prevPortal := portalScreenshotFn
 	prevPipewire := pipewireScreenshotFn
 	t.Cleanup(func() {
@@ -190,3 +220,862 @@ func TestInteractiveScreenshotDoesNotFallbackToPipewire(t *testing.T) {
 		t.Fatalf("expected wrapped portal error, got %v", err)
 	}
 }
+
+// stubUnavailableDirectBackends keeps tests on the portal path even when they
+// run inside a wlroots, KDE or GNOME session or with screenshot tools
+// installed.
+func stubUnavailableDirectBackends(t *testing.T) {
+	t.Helper()
+	prevScreencopy, prevKWin, prevGNOME := wlrScreencopyFn, kwinScreenshotFn, gnomeScreenshotFn
+	prevExternal := externalScreenshotFn
+	unavailable := func(CaptureOptions) (*image.RGBA, error) { return nil, errBackendUnavailable }
+	wlrScreencopyFn, kwinScreenshotFn, gnomeScreenshotFn = unavailable, unavailable, unavailable
+	externalScreenshotFn = func(bool, CaptureOptions, string) (*image.RGBA, error) { return nil, errBackendUnavailable }
+	t.Cleanup(func() {
+		wlrScreencopyFn, kwinScreenshotFn, gnomeScreenshotFn = prevScreencopy, prevKWin, prevGNOME
+		externalScreenshotFn = prevExternal
+	})
+}
+
+func TestScreenshotPrefersScreencopy(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() { portalScreenshotFn = prevPortal })
+
+	want := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	wlrScreencopyFn = func(CaptureOptions) (*image.RGBA, error) { return want, nil }
+	portalCalls := 0
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		portalCalls++
+		return nil, errors.New("portal failed")
+	}
+	if got, err := screenshot(false, CaptureOptions{}); err != nil || got != want {
+		t.Fatalf("expected screencopy image, got %v %v", got, err)
+	}
+	if portalCalls != 0 {
+		t.Fatalf("portal should not be used when screencopy succeeds")
+	}
+
+	// Interactive selection always goes through the portal.
+	if _, err := screenshot(true, CaptureOptions{}); err == nil || portalCalls != 1 {
+		t.Fatalf("expected interactive capture to use the portal, calls=%d err=%v", portalCalls, err)
+	}
+
+	// A screencopy failure is reported alongside the portal error.
+	wlrScreencopyFn = func(CaptureOptions) (*image.RGBA, error) { return nil, errors.New("copy failed") }
+	_, err := screenshot(false, CaptureOptions{})
+	if err == nil || !strings.Contains(err.Error(), "wlr-screencopy: copy failed") || !strings.Contains(err.Error(), "portal failed") {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+}
+
+func TestScreenshotProbesCompositorBackends(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal, prevPipewire := portalScreenshotFn, pipewireScreenshotFn
+	t.Cleanup(func() {
+		portalScreenshotFn, pipewireScreenshotFn = prevPortal, prevPipewire
+	})
+
+	var calls []string
+	record := func(name string, img *image.RGBA, err error) func(CaptureOptions) (*image.RGBA, error) {
+		return func(CaptureOptions) (*image.RGBA, error) {
+			calls = append(calls, name)
+			return img, err
+		}
+	}
+	want := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	kwinScreenshotFn = record("kwin", nil, errors.New("not authorized"))
+	gnomeScreenshotFn = record("gnome", want, nil)
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		calls = append(calls, "portal")
+		return want, nil
+	}
+	pipewireScreenshotFn = record("x11", want, nil)
+
+	if got, err := screenshot(false, CaptureOptions{}); err != nil || got != want {
+		t.Fatalf("expected gnome image, got %v %v", got, err)
+	}
+	if strings.Join(calls, ",") != "kwin,gnome" {
+		t.Fatalf("unexpected probe order %v", calls)
+	}
+
+	// A forced backend skips the others and reports its own failure.
+	calls = nil
+	_, err := screenshot(false, CaptureOptions{Backend: "KWin"})
+	if err == nil || !strings.Contains(err.Error(), "kwin: not authorized") || strings.Join(calls, ",") != "kwin" {
+		t.Fatalf("forced kwin: calls=%v err=%v", calls, err)
+	}
+	calls = nil
+	if _, err := screenshot(false, CaptureOptions{Backend: BackendX11}); err != nil || strings.Join(calls, ",") != "x11" {
+		t.Fatalf("forced x11: calls=%v err=%v", calls, err)
+	}
+	if _, err := screenshot(true, CaptureOptions{Backend: BackendGNOME}); err == nil {
+		t.Fatalf("expected interactive gnome capture to be rejected")
+	}
+	if _, err := screenshot(false, CaptureOptions{Backend: "mutter"}); err == nil || !strings.Contains(err.Error(), "unknown capture backend") {
+		t.Fatalf("expected unknown backend error, got %v", err)
+	}
+}
+
+func TestCaptureWindowDrawsCursorAtWindowOffset(t *testing.T) {
+	originalBackend := backend
+	prevCursor := cursorImageFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		cursorImageFn = prevCursor
+	})
+	backend = fakeBackend{windows: []WindowInfo{{ID: 1, Title: "editor", Rect: image.Rect(100, 50, 101, 51), Active: true}}}
+	sprite := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	sprite.SetRGBA(1, 1, red)
+	calls := 0
+	cursorImageFn = func() (*image.RGBA, image.Point, error) {
+		calls++
+		// The sprite's hotspot pixel lands on the window's top-left corner.
+		return sprite, image.Pt(99, 49), nil
+	}
+
+	_, err := CaptureWindow("editor", CaptureOptions{})
+	if err != nil || calls != 0 {
+		t.Fatalf("cursor should only be read on request, calls=%d err=%v", calls, err)
+	}
+	res, err := CaptureWindow("editor", CaptureOptions{IncludeCursor: true})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if got := res.Image.RGBAAt(0, 0); got != red {
+		t.Fatalf("pixel 0,0 = %v, want cursor %v", got, red)
+	}
+
+	// Capture still succeeds when the cursor cannot be read.
+	cursorImageFn = func() (*image.RGBA, image.Point, error) { return nil, image.Point{}, errors.New("no xfixes") }
+	if _, err := CaptureWindow("editor", CaptureOptions{IncludeCursor: true}); err != nil {
+		t.Fatalf("capture without cursor: %v", err)
+	}
+}
+
+type recordingBackend struct {
+	fakeBackend
+	captured []uint32
+}
+
+func (b *recordingBackend) CaptureWindowImage(id uint32) (*image.RGBA, error) {
+	b.captured = append(b.captured, id)
+	return b.fakeBackend.CaptureWindowImage(id)
+}
+
+func TestCaptureWindowIncludesDecorations(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	client := WindowInfo{ID: 7, Title: "editor", Rect: image.Rect(10, 30, 110, 130), Active: true}
+	rec := &recordingBackend{fakeBackend: fakeBackend{
+		windows: []WindowInfo{client},
+		frameID: 70,
+		frame:   image.Rect(8, 8, 112, 132),
+	}}
+	backend = rec
+
+	res, err := CaptureWindow("editor", CaptureOptions{})
+	if err != nil || len(rec.captured) != 1 || rec.captured[0] != 7 || res.Window.Rect != client.Rect {
+		t.Fatalf("client capture: captured=%v res=%+v err=%v", rec.captured, res, err)
+	}
+	res, err = CaptureWindow("editor", CaptureOptions{IncludeDecorations: true})
+	if err != nil || rec.captured[1] != 70 || res.Window.Rect != image.Rect(8, 8, 112, 132) || res.Region != res.Window.Rect {
+		t.Fatalf("frame capture: captured=%v res=%+v err=%v", rec.captured, res, err)
+	}
+
+	// Frames without a drawable are cropped from a screenshot.
+	rec.frameID = 0
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return image.NewRGBA(image.Rect(0, 0, 200, 200)), nil
+	}
+	res, err = CaptureWindow("editor", CaptureOptions{IncludeDecorations: true})
+	if err != nil || len(rec.captured) != 2 || res.Image.Bounds() != image.Rect(0, 0, 104, 124) {
+		t.Fatalf("frame crop: captured=%v res=%+v err=%v", rec.captured, res, err)
+	}
+
+	// Unknown frames fall back to the client window.
+	rec.frameErr = errors.New("no frame")
+	if res, err := CaptureWindow("editor", CaptureOptions{IncludeDecorations: true}); err != nil || rec.captured[2] != 7 || res.Window.Rect != client.Rect {
+		t.Fatalf("frame error: captured=%v res=%+v err=%v", rec.captured, res, err)
+	}
+}
+
+func TestCaptureAllMonitorsReportsLayout(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return image.NewRGBA(image.Rect(0, 0, 300, 100)), nil
+	}
+	// A monitor left of the primary gives the desktop a negative origin.
+	backend = fakeBackend{monitors: []MonitorInfo{
+		{Index: 0, Name: "DP-1", Rect: image.Rect(0, 0, 200, 100), Primary: true},
+		{Index: 1, Name: "HDMI-1", Rect: image.Rect(-100, 0, 0, 100)},
+	}}
+	shot, err := CaptureAllMonitors(CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if len(shot.Monitors) != 2 || shot.Monitors[0].Rect != image.Rect(100, 0, 300, 100) || shot.Monitors[1].Rect != image.Rect(0, 0, 100, 100) {
+		t.Fatalf("unexpected monitors %+v", shot.Monitors)
+	}
+
+	backend = fakeBackend{monitorsErr: errors.New("no randr")}
+	shot, err = CaptureAllMonitors(CaptureOptions{})
+	if err != nil || len(shot.Monitors) != 1 || shot.Monitors[0].Rect != shot.Image.Bounds() {
+		t.Fatalf("expected the whole image as one monitor, got %+v %v", shot.Monitors, err)
+	}
+}
+
+func TestSplitMonitorsCutsDesktopCapture(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+		img.Pix[img.PixOffset(150, 50)] = 0xff
+		return img, nil
+	}
+	backend = fakeBackend{monitors: []MonitorInfo{
+		{Index: 0, Name: "DP-1", Rect: image.Rect(0, 0, 200, 100), Primary: true},
+		{Index: 1, Name: "HDMI-1", Rect: image.Rect(-100, 0, 0, 100)},
+	}}
+	res, err := CaptureScreenshot(AllMonitorsSelector, CaptureOptions{})
+	if err != nil || res.Region != image.Rect(-100, 0, 200, 100) {
+		t.Fatalf("capture all = %+v, %v", res.Region, err)
+	}
+	parts := SplitMonitors(res)
+	if len(parts) != 2 {
+		t.Fatalf("expected a part per monitor, got %d", len(parts))
+	}
+	dp, hdmi := parts[0], parts[1]
+	if dp.Image.Bounds() != image.Rect(0, 0, 200, 100) || dp.Monitor == nil || dp.Monitor.Name != "DP-1" || dp.Region != image.Rect(0, 0, 200, 100) {
+		t.Fatalf("unexpected DP-1 part %+v", dp)
+	}
+	if dp.Image.Pix[dp.Image.PixOffset(50, 50)] != 0xff || !dp.Time.Equal(res.Time) {
+		t.Fatal("DP-1 part is not the right side of the desktop")
+	}
+	if hdmi.Image.Bounds().Dx() != 100 || hdmi.Monitor == nil || hdmi.Monitor.Name != "HDMI-1" {
+		t.Fatalf("unexpected HDMI-1 part %+v", hdmi)
+	}
+
+	one, err := CaptureScreenshot("DP-1", CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture DP-1: %v", err)
+	}
+	if one.Image.Pix[one.Image.PixOffset(50, 50)] != 0xff {
+		t.Fatal("DP-1 was cropped from the wrong side of the desktop")
+	}
+	if parts := SplitMonitors(one); len(parts) != 1 || parts[0].Image != one.Image {
+		t.Fatalf("a single monitor capture was split into %d", len(parts))
+	}
+}
+
+func TestSelectWindowByDesktop(t *testing.T) {
+	windows := []WindowInfo{
+		{ID: 1, Title: "sticky", Desktop: AllDesktops},
+		{ID: 2, Title: "mail", Desktop: 2},
+		{ID: 3, Title: "editor", Desktop: 2, Active: true},
+		{ID: 4, Title: "music", Desktop: 3},
+	}
+	if win, err := SelectWindow("desktop:2", windows); err != nil || win.ID != 3 {
+		t.Fatalf("desktop:2 = %+v, %v; want the active window", win, err)
+	}
+	if win, err := SelectWindow("Desktop: 3", windows); err != nil || win.ID != 4 {
+		t.Fatalf("desktop:3 = %+v, %v", win, err)
+	}
+	if _, err := SelectWindow("desktop:5", windows); err == nil {
+		t.Fatalf("expected no window on desktop 5")
+	}
+	if _, err := SelectWindow("desktop:x", windows); err == nil {
+		t.Fatalf("expected invalid desktop error")
+	}
+}
+
+func TestCaptureWorkspaceCompositesItsWindows(t *testing.T) {
+	originalBackend := backend
+	t.Cleanup(func() { backend = originalBackend })
+	rec := &recordingBackend{fakeBackend: fakeBackend{
+		monitors: []MonitorInfo{{Rect: image.Rect(0, 0, 40, 30)}},
+		windows: []WindowInfo{
+			{ID: 1, Title: "top", Rect: image.Rect(5, 5, 6, 6), Desktop: 1},
+			{ID: 2, Title: "other", Rect: image.Rect(0, 0, 1, 1), Desktop: 0},
+			{ID: 3, Title: "panel", Rect: image.Rect(0, 0, 1, 1), Desktop: AllDesktops},
+		},
+	}}
+	backend = rec
+	res, err := CaptureWorkspace(1, CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if res.Image.Bounds() != image.Rect(0, 0, 40, 30) {
+		t.Fatalf("unexpected bounds %v", res.Image.Bounds())
+	}
+	// Bottom-most first: the sticky panel, then the desktop's own window.
+	if fmt.Sprint(rec.captured) != "[3 1]" {
+		t.Fatalf("captured %v, want [3 1]", rec.captured)
+	}
+
+	rec.captureErr = errors.New("unmapped")
+	if _, err := CaptureWorkspace(1, CaptureOptions{}); err == nil || !strings.Contains(err.Error(), "unmapped") {
+		t.Fatalf("expected capture errors, got %v", err)
+	}
+	if _, err := CaptureWorkspace(7, CaptureOptions{}); err == nil || !strings.Contains(err.Error(), "no windows") {
+		t.Fatalf("expected empty desktop error, got %v", err)
+	}
+}
+
+func TestScreenshotHidesOwnWindows(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal, prevHide := portalScreenshotFn, hideOwnWindowsFn
+	t.Cleanup(func() {
+		portalScreenshotFn, hideOwnWindowsFn = prevPortal, prevHide
+	})
+	var events []string
+	hideOwnWindowsFn = func() func() {
+		events = append(events, "hide")
+		return func() { events = append(events, "restore") }
+	}
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		events = append(events, "capture")
+		return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+	}
+	if _, err := screenshot(false, CaptureOptions{}); err != nil {
+		t.Fatalf("screenshot: %v", err)
+	}
+	if got := strings.Join(events, ","); got != "hide,capture,restore" {
+		t.Fatalf("events = %s", got)
+	}
+	events = nil
+	if _, err := screenshot(false, CaptureOptions{IncludeOwnWindows: true}); err != nil {
+		t.Fatalf("screenshot: %v", err)
+	}
+	if got := strings.Join(events, ","); got != "capture" {
+		t.Fatalf("events with own windows = %s", got)
+	}
+}
+
+func TestIsOwnWindow(t *testing.T) {
+	if !isOwnWindow(WindowInfo{Instance: "shineyshot", Class: "ShineyShot"}) {
+		t.Fatalf("tagged window not recognised")
+	}
+	if !isOwnWindow(WindowInfo{PID: uint32(os.Getpid())}) {
+		t.Fatalf("window owned by this process not recognised")
+	}
+	if isOwnWindow(WindowInfo{Instance: "firefox"}) {
+		t.Fatalf("foreign window recognised as own")
+	}
+}
+
+func TestCaptureRegionRectLogicalUnits(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	shot := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	marker := color.RGBA{R: 255, A: 255}
+	shot.SetRGBA(120, 20, marker)
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return shot, nil
+	}
+	backend = fakeBackend{monitors: []MonitorInfo{
+		{Index: 0, Name: "eDP-1", Rect: image.Rect(0, 0, 100, 100), Scale: 1},
+		{Index: 1, Name: "DP-1", Rect: image.Rect(100, 0, 200, 100), Scale: 2},
+	}}
+
+	res, err := CaptureRegionRect(image.Rect(110, 10, 120, 20), CaptureOptions{Units: UnitsLogical})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	img := res.Image
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 20 || img.RGBAAt(0, 0) != marker {
+		t.Fatalf("expected the 2x monitor region scaled to 20x20 from (120,20), got %v", img.Bounds())
+	}
+
+	res, err = CaptureRegionRect(image.Rect(110, 10, 120, 20), CaptureOptions{Units: UnitsPixel})
+	if err != nil || res.Image.Bounds().Dx() != 10 {
+		t.Fatalf("pixel units should crop as given, got %+v %v", res, err)
+	}
+}
+
+func TestCaptureScreenshotCountsDownDelay(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal, prevSleep := portalScreenshotFn, sleepFn
+	t.Cleanup(func() {
+		portalScreenshotFn, sleepFn = prevPortal, prevSleep
+	})
+	var events []string
+	sleepFn = func(d time.Duration) { events = append(events, "sleep "+d.String()) }
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		events = append(events, "capture")
+		return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+	}
+	opts := CaptureOptions{
+		Delay:    2500 * time.Millisecond,
+		Progress: func(left time.Duration) { events = append(events, "left "+left.String()) },
+	}
+	if _, err := CaptureScreenshot("", opts); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	want := "left 2.5s,sleep 1s,left 1.5s,sleep 1s,left 500ms,sleep 500ms,left 0s,capture"
+	if got := strings.Join(events, ","); got != want {
+		t.Fatalf("events = %s, want %s", got, want)
+	}
+}
+
+func TestCaptureDelayStopsWhenCancelled(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal, prevSleep := portalScreenshotFn, sleepFn
+	t.Cleanup(func() {
+		portalScreenshotFn, sleepFn = prevPortal, prevSleep
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	sleeps := 0
+	sleepFn = func(time.Duration) {
+		sleeps++
+		cancel()
+	}
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		t.Fatal("capture ran after the delay was cancelled")
+		return nil, nil
+	}
+	_, err := CaptureScreenshot("", CaptureOptions{Delay: 5 * time.Second, Context: ctx})
+	if !errors.Is(err, context.Canceled) || sleeps != 1 {
+		t.Fatalf("got %v after %d sleeps, want cancellation after 1", err, sleeps)
+	}
+}
+
+func TestCaptureRegionRectStitchesAcrossMonitors(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	// The left monitor sits at a negative origin and the primary is shorter,
+	// leaving a gap below it.
+	shot := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			switch {
+			case x < 100:
+				shot.SetRGBA(x, y, red)
+			case y < 50:
+				shot.SetRGBA(x, y, blue)
+			}
+		}
+	}
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return shot, nil
+	}
+	backend = fakeBackend{monitors: []MonitorInfo{
+		{Index: 0, Name: "DP-1", Rect: image.Rect(0, 0, 200, 50), Primary: true},
+		{Index: 1, Name: "HDMI-1", Rect: image.Rect(-100, 0, 0, 100)},
+	}}
+
+	res, err := CaptureRegionRect(image.Rect(-50, 25, 250, 150), CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if res.Region != image.Rect(-50, 25, 200, 100) || res.Monitor != nil {
+		t.Fatalf("expected the clamped region across both monitors, got %v %v", res.Region, res.Monitor)
+	}
+	img := res.Image
+	if img.Bounds() != image.Rect(0, 0, 250, 75) {
+		t.Fatalf("expected the region clamped to the desktop, got %v", img.Bounds())
+	}
+	if img.RGBAAt(0, 0) != red || img.RGBAAt(100, 0) != blue || img.RGBAAt(100, 40).A != 0 {
+		t.Fatalf("unexpected stitched pixels %v %v %v", img.RGBAAt(0, 0), img.RGBAAt(100, 0), img.RGBAAt(100, 40))
+	}
+
+	if _, err := CaptureRegionRect(image.Rect(500, 500, 600, 600), CaptureOptions{}); err == nil || !strings.Contains(err.Error(), "not on any monitor") {
+		t.Fatalf("expected an off-screen region to fail, got %v", err)
+	}
+}
+
+func TestScreenshotFallsBackToExternalTool(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	prevPortal, prevPipewire := portalScreenshotFn, pipewireScreenshotFn
+	t.Cleanup(func() {
+		portalScreenshotFn, pipewireScreenshotFn = prevPortal, prevPipewire
+	})
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return nil, &dbus.Error{Name: "org.freedesktop.portal.Error.NotSupported"}
+	}
+	pipewireScreenshotFn = func(CaptureOptions) (*image.RGBA, error) {
+		return nil, errors.New("no x11")
+	}
+	want := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var calls []string
+	externalScreenshotFn = func(interactive bool, _ CaptureOptions, name string) (*image.RGBA, error) {
+		calls = append(calls, fmt.Sprintf("%s interactive=%v", name, interactive))
+		return want, nil
+	}
+
+	if img, err := screenshot(false, CaptureOptions{}); err != nil || img != want {
+		t.Fatalf("expected the external tool's image, got %v", err)
+	}
+	if img, err := screenshot(true, CaptureOptions{}); err != nil || img != want {
+		t.Fatalf("expected interactive selection through the external tool, got %v", err)
+	}
+	if _, err := screenshot(false, CaptureOptions{Backend: BackendMaim}); err != nil {
+		t.Fatalf("forced tool: %v", err)
+	}
+	want2 := "external interactive=false,external interactive=true,maim interactive=false"
+	if got := strings.Join(calls, ","); got != want2 {
+		t.Fatalf("calls = %s, want %s", got, want2)
+	}
+
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return nil, errors.New("user cancelled")
+	}
+	calls = nil
+	if _, err := screenshot(true, CaptureOptions{}); err == nil || len(calls) != 0 {
+		t.Fatalf("a refused portal request must not start a tool, got %v %v", calls, err)
+	}
+}
+
+func TestCaptureResultSummaryAndText(t *testing.T) {
+	monitors := []MonitorInfo{
+		{Name: "DP-1", Rect: image.Rect(0, 0, 100, 100), Scale: 2},
+		{Name: "HDMI-1", Rect: image.Rect(100, 0, 200, 100)},
+	}
+	res := newResult(image.NewRGBA(image.Rect(0, 0, 40, 20)), image.Rect(10, 10, 50, 30), monitors)
+	if res.Monitor == nil || res.Monitor.Name != "DP-1" || res.Scale != 2 {
+		t.Fatalf("expected DP-1 at 2x, got %v %v", res.Monitor, res.Scale)
+	}
+	if got, want := res.Summary(), "40x20 on DP-1 at 2x"; got != want {
+		t.Fatalf("summary: got %q want %q", got, want)
+	}
+	res.Window = &WindowInfo{Title: "notes", Class: "gedit"}
+	text := res.Text()
+	if text["Title"] != "notes" || text["shineyshot:window-class"] != "gedit" || text["shineyshot:region"] != "10,10,50,30" || text["Creation Time"] == "" {
+		t.Fatalf("unexpected text %v", text)
+	}
+
+	spanning := newResult(image.NewRGBA(image.Rect(0, 0, 100, 10)), image.Rect(50, 0, 150, 10), monitors)
+	if spanning.Monitor != nil || spanning.Scale != 2 {
+		t.Fatalf("expected no single monitor at 2x, got %v %v", spanning.Monitor, spanning.Scale)
+	}
+}
+
+type fakeFrameSource struct {
+	next   int
+	failAt int
+	closed bool
+}
+
+func (s *fakeFrameSource) Next() (*image.RGBA, error) {
+	s.next++
+	if s.next == s.failAt {
+		return nil, errors.New("source gone")
+	}
+	return image.NewRGBA(image.Rect(0, 0, s.next, 1)), nil
+}
+
+func (s *fakeFrameSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestStreamSendsFramesUntilCancelled(t *testing.T) {
+	prev := openStreamFn
+	t.Cleanup(func() { openStreamFn = prev })
+	src := &fakeFrameSource{}
+	var gotTarget StreamTarget
+	openStreamFn = func(_ context.Context, target StreamTarget, fps int) (frameSource, error) {
+		gotTarget = target
+		return src, nil
+	}
+
+	if _, err := Stream(context.Background(), StreamTarget{}, 0); err == nil {
+		t.Fatalf("expected an error for a zero frame rate")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	frames, err := Stream(ctx, StreamTarget{Window: "editor"}, 200)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if gotTarget.Window != "editor" {
+		t.Fatalf("expected the target to reach the source, got %+v", gotTarget)
+	}
+	var last time.Time
+	for want := 1; want <= 3; want++ {
+		frame := <-frames
+		if frame.Err != nil || frame.Image.Bounds().Dx() != want {
+			t.Fatalf("frame %d: got %v %v", want, frame.Image.Bounds(), frame.Err)
+		}
+		if frame.Time.Before(last) {
+			t.Fatalf("frame times went backwards")
+		}
+		last = frame.Time
+	}
+	cancel()
+	for range frames {
+	}
+	if !src.closed {
+		t.Fatalf("expected the source to be closed")
+	}
+}
+
+func TestStreamReportsSourceFailure(t *testing.T) {
+	prev := openStreamFn
+	t.Cleanup(func() { openStreamFn = prev })
+	openStreamFn = func(context.Context, StreamTarget, int) (frameSource, error) {
+		return &fakeFrameSource{failAt: 2}, nil
+	}
+	frames, err := Stream(context.Background(), StreamTarget{}, 500)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	var got []Frame
+	for frame := range frames {
+		got = append(got, frame)
+	}
+	if len(got) != 2 || got[0].Err != nil || got[1].Err == nil || got[1].Image != nil {
+		t.Fatalf("expected one frame then the error, got %+v", got)
+	}
+}
+
+func TestFindMonitorCurrentFollowsPointer(t *testing.T) {
+	originalBackend := backend
+	t.Cleanup(func() { backend = originalBackend })
+	monitors := []MonitorInfo{
+		{Index: 0, Name: "DP-1", Rect: image.Rect(0, 0, 100, 100)},
+		{Index: 1, Name: "HDMI-1", Rect: image.Rect(100, 0, 200, 100)},
+	}
+
+	backend = fakeBackend{monitors: monitors, cursor: image.Pt(150, 40)}
+	if mon, err := MonitorAtCursor(); err != nil || mon.Name != "HDMI-1" {
+		t.Fatalf("expected the monitor under the pointer, got %v %v", mon.Name, err)
+	}
+
+	// Without a pointer position the focused output wins, then the one
+	// holding the active window.
+	noPointer := errors.New("no pointer")
+	focused := append([]MonitorInfo(nil), monitors...)
+	focused[1].Focused = true
+	backend = fakeBackend{cursorErr: noPointer}
+	if mon, err := FindMonitor(focused, "current"); err != nil || mon.Name != "HDMI-1" {
+		t.Fatalf("expected the focused monitor, got %v %v", mon.Name, err)
+	}
+	backend = fakeBackend{cursorErr: noPointer, windows: []WindowInfo{{Monitor: 1, Active: true}}}
+	if mon, err := FindMonitor(monitors, "CURRENT"); err != nil || mon.Name != "HDMI-1" {
+		t.Fatalf("expected the active window's monitor, got %v %v", mon.Name, err)
+	}
+	backend = fakeBackend{cursorErr: noPointer}
+	if _, err := FindMonitor(monitors, "current"); !errors.Is(err, noPointer) {
+		t.Fatalf("expected the pointer error, got %v", err)
+	}
+}
+
+func TestCaptureWindowPickSelector(t *testing.T) {
+	originalBackend := backend
+	prevPick := pickWindowFn
+	prevScreencast := screencastWindowFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		pickWindowFn = prevPick
+		screencastWindowFn = prevScreencast
+	})
+	rec := &recordingBackend{fakeBackend: fakeBackend{windows: []WindowInfo{
+		{Index: 0, ID: 10, Title: "editor", Rect: image.Rect(0, 0, 10, 10)},
+		{Index: 1, ID: 20, Title: "terminal", Rect: image.Rect(10, 0, 20, 10), Active: true},
+	}}}
+	backend = rec
+	pickWindowFn = func() (uint32, error) { return 10, nil }
+	res, err := CaptureWindow(PickWindowSelector, CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if res.Window == nil || res.Window.ID != 10 || len(rec.captured) != 1 || rec.captured[0] != 10 {
+		t.Fatalf("expected the picked window, got %+v %v", res.Window, rec.captured)
+	}
+
+	pickWindowFn = func() (uint32, error) { return 0, errors.New("cancelled") }
+	if _, err := CaptureWindow("pick", CaptureOptions{}); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected the cancellation, got %v", err)
+	}
+
+	// Sessions that cannot grab the pointer use the portal's picker.
+	pickWindowFn = func() (uint32, error) { return 0, ErrPickUnsupported }
+	screencastWindowFn = func(CaptureOptions, string) (*image.RGBA, string, error) {
+		return image.NewRGBA(image.Rect(0, 0, 3, 3)), "", nil
+	}
+	res, err = CaptureWindow("pick", CaptureOptions{})
+	if err != nil || res.Window == nil || res.Window.Title != "portal selection" {
+		t.Fatalf("expected the portal picker, got %+v %v", res.Window, err)
+	}
+}
+
+func TestCaptureWindowRefusesMinimizedWindows(t *testing.T) {
+	originalBackend := backend
+	t.Cleanup(func() { backend = originalBackend })
+	rec := &recordingBackend{fakeBackend: fakeBackend{windows: []WindowInfo{
+		{Index: 0, ID: 10, Title: "editor", Rect: image.Rect(0, 0, 10, 10), State: WindowMaximized},
+		{Index: 1, ID: 20, Title: "mail", Rect: image.Rect(0, 0, 10, 10), State: WindowMinimized | WindowShaded},
+	}}}
+	backend = rec
+
+	if got := rec.windows[1].State.String(); got != "minimized,shaded" {
+		t.Fatalf("State.String() = %q", got)
+	}
+	if _, err := CaptureWindow("mail", CaptureOptions{}); !errors.Is(err, ErrWindowMinimized) || len(rec.captured) != 0 {
+		t.Fatalf("expected ErrWindowMinimized without capturing, got %v %v", err, rec.captured)
+	}
+	// Without an active window the default skips the minimized one.
+	res, err := CaptureWindow("", CaptureOptions{})
+	if err != nil || res.Window == nil || res.Window.ID != 10 {
+		t.Fatalf("expected the visible window, got %+v %v", res.Window, err)
+	}
+}
+
+func TestListWindowsFiltered(t *testing.T) {
+	originalBackend := backend
+	t.Cleanup(func() { backend = originalBackend })
+	backend = fakeBackend{
+		monitors: []MonitorInfo{
+			{Index: 0, Name: "left", Rect: image.Rect(0, 0, 100, 100)},
+			{Index: 1, Name: "right", Rect: image.Rect(100, 0, 200, 100)},
+		},
+		windows: []WindowInfo{
+			{Index: 0, Title: "panel", Type: "dock", Rect: image.Rect(0, 0, 200, 10), Monitor: 0},
+			{Index: 1, Title: "editor", Type: "normal", Rect: image.Rect(0, 10, 100, 100), Monitor: 0},
+			{Index: 2, Title: "tip", Type: "tooltip", Rect: image.Rect(10, 10, 30, 20), Monitor: 0},
+			{Index: 3, Title: "mail", Rect: image.Rect(100, 0, 200, 100), Monitor: 1, State: WindowMinimized},
+			{Index: 4, Title: "clock", Rect: image.Rect(150, 0, 170, 20), Monitor: 1},
+		},
+	}
+	titles := func(windows []WindowInfo) string {
+		var names []string
+		for _, win := range windows {
+			names = append(names, fmt.Sprintf("%d:%s", win.Index, win.Title))
+		}
+		return strings.Join(names, " ")
+	}
+	tests := []struct {
+		filter WindowFilter
+		want   string
+	}{
+		{WindowFilter{}, "0:panel 1:editor 2:tip 3:mail 4:clock"},
+		{WindowFilter{VisibleOnly: true}, "0:panel 1:editor 2:tip 4:clock"},
+		{WindowFilter{ExcludeTypes: PanelWindowTypes}, "1:editor 3:mail 4:clock"},
+		{WindowFilter{Monitor: "right"}, "3:mail 4:clock"},
+		{WindowFilter{MinWidth: 50, MinHeight: 50}, "1:editor 3:mail"},
+		{WindowFilter{Monitor: "1", VisibleOnly: true}, "4:clock"},
+	}
+	for _, tt := range tests {
+		windows, err := ListWindowsFiltered(tt.filter)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.filter, err)
+		}
+		if got := titles(windows); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+	if _, err := ListWindowsFiltered(WindowFilter{Monitor: "missing"}); err == nil {
+		t.Fatal("expected an unknown monitor to be an error")
+	}
+}
+
+func TestRecaptureRepeatsCaptures(t *testing.T) {
+	stubUnavailableDirectBackends(t)
+	originalBackend := backend
+	prevPortal := portalScreenshotFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		portalScreenshotFn = prevPortal
+	})
+	portalScreenshotFn = func(bool, CaptureOptions) (*image.RGBA, error) {
+		return image.NewRGBA(image.Rect(0, 0, 300, 200)), nil
+	}
+	rec := &recordingBackend{fakeBackend: fakeBackend{
+		monitors: []MonitorInfo{
+			{Index: 0, Name: "DP-10", Rect: image.Rect(0, 0, 100, 100), Scale: 1},
+			{Index: 1, Name: "DP-1", Rect: image.Rect(100, 0, 200, 100), Scale: 2},
+		},
+		windows: []WindowInfo{{Index: 0, ID: 42, Title: "editor", Rect: image.Rect(0, 0, 10, 10)}},
+	}}
+	backend = rec
+
+	// Logical regions are stored in pixels, so a recapture reads the same
+	// pixels whatever units it is given.
+	first, err := CaptureRegionRect(image.Rect(110, 10, 120, 20), CaptureOptions{Units: UnitsLogical})
+	if err != nil {
+		t.Fatalf("capture region: %v", err)
+	}
+	again, err := Recapture(first.Token, CaptureOptions{Units: UnitsLogical})
+	if err != nil || again.Region != first.Region || again.Token != first.Token {
+		t.Fatalf("recapture region = %v %v, want %v", again.Region, err, first.Region)
+	}
+
+	first, err = CaptureScreenshot("1", CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture screen: %v", err)
+	}
+	again, err = Recapture(first.Token, CaptureOptions{})
+	if err != nil || again.Monitor == nil || again.Monitor.Name != "DP-1" {
+		t.Fatalf("recapture screen = %+v %v, want DP-1", again.Monitor, err)
+	}
+
+	first, err = CaptureWindow("editor", CaptureOptions{})
+	if err != nil {
+		t.Fatalf("capture window: %v", err)
+	}
+	rec.windows = nil
+	if _, err := Recapture(first.Token, CaptureOptions{}); err == nil {
+		t.Fatalf("expected a closed window not to be recaptured")
+	}
+
+	if _, err := Recapture("", CaptureOptions{}); !errors.Is(err, ErrNotRecapturable) {
+		t.Fatalf("expected ErrNotRecapturable for an empty token, got %v", err)
+	}
+	if _, err := Recapture("not a token!", CaptureOptions{}); err == nil {
+		t.Fatalf("expected a malformed token to be refused")
+	}
+}
+
+func TestEDIDModelNamesMonitor(t *testing.T) {
+	edid := make([]byte, 128)
+	copy(edid, []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00})
+	// "DEL" packed as three 5-bit letters.
+	edid[8], edid[9] = 0x10, 0xac
+	copy(edid[72:], []byte{0, 0, 0, 0xfc, 0})
+	copy(edid[77:], "DELL U2720Q\n ")
+	if got := edidModel(edid); got != "Dell U2720Q" {
+		t.Fatalf("edidModel = %q, want Dell U2720Q", got)
+	}
+	if got := edidModel(edid[:64]); got != "" {
+		t.Fatalf("expected a truncated EDID to have no model, got %q", got)
+	}
+
+	monitors := []MonitorInfo{
+		{Index: 0, Name: "eDP-1"},
+		{Index: 1, Name: "DP-2", Model: "Dell U2720Q"},
+	}
+	for _, selector := range []string{"dell u2720q", "U2720"} {
+		if mon, err := FindMonitor(monitors, selector); err != nil || mon.Name != "DP-2" {
+			t.Fatalf("FindMonitor(%q) = %v %v, want DP-2", selector, mon.Name, err)
+		}
+	}
+}
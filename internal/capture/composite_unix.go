@@ -0,0 +1,53 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jezek/xgb/composite"
+	"github.com/jezek/xgb/xproto"
+)
+
+// compositeWindowImage reads the offscreen pixmap the Composite extension
+// keeps for a window, which holds its full contents even where other windows
+// cover it. Under a compositing manager every mapped window already has one;
+// otherwise the window is redirected for the duration of the read, and only
+// the parts the application has painted since are guaranteed to be current.
+func compositeWindowImage(reader *xImageReader, setup *xproto.SetupInfo, win xproto.Window) (*image.RGBA, error) {
+	conn := reader.conn
+	if err := composite.Init(conn); err != nil {
+		return nil, fmt.Errorf("init composite: %w", err)
+	}
+	// NameWindowPixmap arrived in version 0.2.
+	version, err := composite.QueryVersion(conn, 0, 2).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("composite version: %w", err)
+	}
+	if version.MajorVersion == 0 && version.MinorVersion < 2 {
+		return nil, fmt.Errorf("composite %d.%d lacks NameWindowPixmap", version.MajorVersion, version.MinorVersion)
+	}
+
+	if err := composite.RedirectWindowChecked(conn, win, composite.RedirectAutomatic).Check(); err == nil {
+		defer composite.UnredirectWindow(conn, win, composite.RedirectAutomatic)
+	}
+	pixmap, err := xproto.NewPixmapId(conn)
+	if err != nil {
+		return nil, fmt.Errorf("allocate pixmap id: %w", err)
+	}
+	if err := composite.NameWindowPixmapChecked(conn, win, pixmap).Check(); err != nil {
+		return nil, fmt.Errorf("name window pixmap: %w", err)
+	}
+	defer xproto.FreePixmap(conn, pixmap)
+
+	geom, err := xproto.GetGeometry(conn, xproto.Drawable(pixmap)).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("pixmap geometry: %w", err)
+	}
+	reply, err := reader.getImage(xproto.Drawable(pixmap), image.Rect(0, 0, int(geom.Width), int(geom.Height)))
+	if err != nil {
+		return nil, fmt.Errorf("pixmap pixels: %w", err)
+	}
+	return xImageToRGBA(setup, reply, int(geom.Width), int(geom.Height), "window")
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import "image"
+
+func kwinScreenshot(CaptureOptions) (*image.RGBA, error) {
+	return nil, errBackendUnavailable
+}
+
+func gnomeScreenshot(CaptureOptions) (*image.RGBA, error) {
+	return nil, errBackendUnavailable
+}
@@ -0,0 +1,36 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestKWinImageConvertsQImageFormats(t *testing.T) {
+	meta := func(format uint32) map[string]dbus.Variant {
+		return map[string]dbus.Variant{
+			"width":  dbus.MakeVariant(uint32(1)),
+			"height": dbus.MakeVariant(uint32(1)),
+			"stride": dbus.MakeVariant(uint32(4)),
+			"format": dbus.MakeVariant(format),
+		}
+	}
+	// ARGB32 is stored B, G, R, A on little-endian hosts.
+	img, err := kwinImage(meta(qimageFormatARGB32Premultiplied), []byte{0, 0, 0xff, 0xff})
+	if err != nil || img.RGBAAt(0, 0) != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("ARGB32 pixel = %v, %v; want red", img.RGBAAt(0, 0), err)
+	}
+	img, err = kwinImage(meta(qimageFormatRGBA8888), []byte{0xff, 0, 0, 0xff})
+	if err != nil || img.RGBAAt(0, 0) != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("RGBA8888 pixel = %v, %v; want red", img.RGBAAt(0, 0), err)
+	}
+	if _, err := kwinImage(meta(qimageFormatRGB32), nil); err == nil {
+		t.Fatalf("expected truncated image error")
+	}
+	if _, err := kwinImage(meta(3), []byte{0, 0, 0, 0}); err == nil {
+		t.Fatalf("expected unsupported format error")
+	}
+}
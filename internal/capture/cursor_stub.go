@@ -0,0 +1,12 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import (
+	"fmt"
+	"image"
+)
+
+func cursorImage() (*image.RGBA, image.Point, error) {
+	return nil, image.Point{}, fmt.Errorf("cursor capture is not supported on this platform")
+}
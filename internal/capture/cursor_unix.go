@@ -0,0 +1,54 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xfixes"
+)
+
+// cursorImage reads the current pointer sprite through XFixes and returns it
+// with the root coordinates of its top-left corner.
+func cursorImage() (*image.RGBA, image.Point, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, image.Point{}, fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := xfixes.Init(conn); err != nil {
+		return nil, image.Point{}, fmt.Errorf("init xfixes: %w", err)
+	}
+	// The server only answers cursor requests once the client has announced
+	// the version it speaks.
+	if _, err := xfixes.QueryVersion(conn, 4, 0).Reply(); err != nil {
+		return nil, image.Point{}, fmt.Errorf("xfixes version: %w", err)
+	}
+	reply, err := xfixes.GetCursorImage(conn).Reply()
+	if err != nil {
+		return nil, image.Point{}, fmt.Errorf("cursor image: %w", err)
+	}
+	sprite := cursorSpriteToRGBA(int(reply.Width), int(reply.Height), reply.CursorImage)
+	pos := image.Pt(int(reply.X)-int(reply.Xhot), int(reply.Y)-int(reply.Yhot))
+	return sprite, pos, nil
+}
+
+// cursorSpriteToRGBA converts XFixes cursor pixels, premultiplied ARGB
+// words, into an image.
+func cursorSpriteToRGBA(width, height int, pixels []uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for idx, p := range pixels {
+		if idx >= width*height {
+			break
+		}
+		off := idx * 4
+		img.Pix[off] = byte(p >> 16)
+		img.Pix[off+1] = byte(p >> 8)
+		img.Pix[off+2] = byte(p)
+		img.Pix[off+3] = byte(p >> 24)
+	}
+	return img
+}
@@ -0,0 +1,19 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCursorSpriteToRGBA(t *testing.T) {
+	img := cursorSpriteToRGBA(2, 1, []uint32{0xff102030, 0x80400000})
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}) {
+		t.Fatalf("opaque pixel = %v", got)
+	}
+	// Pixels stay premultiplied, matching image.RGBA.
+	if got := img.RGBAAt(1, 0); got != (color.RGBA{R: 0x40, A: 0x80}) {
+		t.Fatalf("translucent pixel = %v", got)
+	}
+}
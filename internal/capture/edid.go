@@ -0,0 +1,63 @@
+package capture
+
+import "strings"
+
+// edidVendors spells out the PNP manufacturer IDs of common monitor makers.
+var edidVendors = map[string]string{
+	"AAC": "Acer", "ACR": "Acer", "AOC": "AOC", "APP": "Apple", "AUS": "ASUS",
+	"BNQ": "BenQ", "CMN": "Innolux", "DEL": "Dell", "EIZ": "EIZO",
+	"GSM": "LG", "HPN": "HP", "HWP": "HP", "IVM": "Iiyama", "LEN": "Lenovo",
+	"LGD": "LG", "MSI": "MSI", "NEC": "NEC", "PHL": "Philips",
+	"SAM": "Samsung", "SDC": "Samsung", "SEC": "Samsung", "SHP": "Sharp",
+	"SNY": "Sony", "VSC": "ViewSonic", "AUO": "AUO", "BOE": "BOE",
+}
+
+// edidModel reads a monitor's make and model, such as "Dell U2720Q", from
+// its EDID: the display product name descriptor, led by the manufacturer
+// when the name does not already start with it. It returns "" for data
+// that is not an EDID block.
+func edidModel(edid []byte) string {
+	header := []byte{0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x00}
+	if len(edid) < 128 || string(edid[:8]) != string(header) {
+		return ""
+	}
+	// Three 5-bit letters, 'A' being 1.
+	id := uint16(edid[8])<<8 | uint16(edid[9])
+	pnp := string([]byte{
+		byte(id>>10&0x1F) + 'A' - 1,
+		byte(id>>5&0x1F) + 'A' - 1,
+		byte(id&0x1F) + 'A' - 1,
+	})
+	vendor := edidVendors[pnp]
+	if vendor == "" {
+		vendor = pnp
+	}
+	// Four 18-byte descriptors follow the timing data; tag 0xFC holds
+	// the product name, ended by a newline.
+	var name string
+	for off := 54; off+18 <= 126; off += 18 {
+		d := edid[off : off+18]
+		if d[0] == 0 && d[1] == 0 && d[3] == 0xFC {
+			name, _, _ = strings.Cut(string(d[5:]), "\n")
+			name = strings.TrimSpace(name)
+			break
+		}
+	}
+	return joinMakeModel(vendor, name)
+}
+
+// joinMakeModel puts the first word of the manufacturer in front of the
+// model name unless the model already starts with it, in which case that
+// word takes the manufacturer's spelling: "Dell Inc." and "DELL U2720Q" give
+// "Dell U2720Q". An empty model gives "".
+func joinMakeModel(vendor, model string) string {
+	model = strings.TrimSpace(model)
+	brand, _, _ := strings.Cut(strings.TrimSpace(vendor), " ")
+	if model == "" || brand == "" {
+		return model
+	}
+	if word, tail, _ := strings.Cut(model, " "); strings.EqualFold(word, brand) {
+		model = tail
+	}
+	return strings.TrimSpace(brand + " " + model)
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import (
+	"fmt"
+	"image"
+)
+
+func externalScreenshot(bool, CaptureOptions, string) (*image.RGBA, error) {
+	return nil, fmt.Errorf("%w: external screenshot tools are not supported on this platform", errBackendUnavailable)
+}
@@ -0,0 +1,141 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// externalTool is a screenshot program shineyshot runs when none of its own
+// backends work. command returns the argument list that writes a PNG of the
+// desktop, or of a region the user drags out when interactive, to path.
+type externalTool struct {
+	name         string
+	x11, wayland bool
+	command      func(interactive bool, opts CaptureOptions, path string) ([]string, error)
+}
+
+// externalTools lists the supported programs in auto-detection order.
+var externalTools = []externalTool{
+	{BackendGrim, false, true, grimCommand},
+	{BackendSpectacle, true, true, spectacleCommand},
+	{BackendMaim, true, false, maimCommand},
+	{BackendScrot, true, false, scrotCommand},
+}
+
+// externalScreenshot captures the desktop with the tool called name, or with
+// the first installed tool suited to the session when name is
+// BackendExternal.
+func externalScreenshot(interactive bool, opts CaptureOptions, name string) (*image.RGBA, error) {
+	tool, err := findExternalTool(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "shineyshot-"+tool.name+"-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("create capture file: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	args, err := tool.command(interactive, opts, path)
+	if err != nil {
+		return nil, err
+	}
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		if msg := bytes.TrimSpace(out); len(msg) > 0 {
+			return nil, fmt.Errorf("%s: %w: %s", tool.name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", tool.name, err)
+	}
+	return loadPNG(path)
+}
+
+func findExternalTool(name string) (externalTool, error) {
+	wayland := runningOnWayland()
+	var names []string
+	for _, tool := range externalTools {
+		if name != BackendExternal {
+			if tool.name != name {
+				continue
+			}
+			if _, err := exec.LookPath(tool.name); err != nil {
+				return externalTool{}, fmt.Errorf("%s is not installed: %w", tool.name, err)
+			}
+			return tool, nil
+		}
+		if (wayland && !tool.wayland) || (!wayland && !tool.x11) {
+			continue
+		}
+		names = append(names, tool.name)
+		if _, err := exec.LookPath(tool.name); err == nil {
+			return tool, nil
+		}
+	}
+	if name != BackendExternal {
+		return externalTool{}, fmt.Errorf("unknown screenshot tool %q", name)
+	}
+	return externalTool{}, fmt.Errorf("%w: none of %s is installed", errBackendUnavailable, strings.Join(names, ", "))
+}
+
+// grimCommand captures with grim, asking slurp for the region first when
+// interactive.
+func grimCommand(interactive bool, opts CaptureOptions, path string) ([]string, error) {
+	args := []string{"grim"}
+	if opts.IncludeCursor {
+		args = append(args, "-c")
+	}
+	if interactive {
+		out, err := exec.Command("slurp").Output()
+		if err != nil {
+			return nil, fmt.Errorf("slurp region selection: %w", err)
+		}
+		args = append(args, "-g", strings.TrimSpace(string(out)))
+	}
+	return append(args, path), nil
+}
+
+func spectacleCommand(interactive bool, opts CaptureOptions, path string) ([]string, error) {
+	args := []string{"spectacle", "-b", "-n", "-o", path}
+	if interactive {
+		args = append(args, "-r")
+	} else {
+		args = append(args, "-f")
+	}
+	if opts.IncludeCursor {
+		args = append(args, "-p")
+	}
+	return args, nil
+}
+
+// maimCommand captures with maim, which draws the pointer unless told not
+// to.
+func maimCommand(interactive bool, opts CaptureOptions, path string) ([]string, error) {
+	args := []string{"maim"}
+	if interactive {
+		args = append(args, "-s")
+	}
+	if !opts.IncludeCursor {
+		args = append(args, "-u")
+	}
+	return append(args, path), nil
+}
+
+// scrotCommand captures with scrot; -o stops it renaming the output because
+// the temporary file already exists.
+func scrotCommand(interactive bool, opts CaptureOptions, path string) ([]string, error) {
+	args := []string{"scrot", "-o"}
+	if interactive {
+		args = append(args, "-s")
+	}
+	if opts.IncludeCursor {
+		args = append(args, "-p")
+	}
+	return append(args, path), nil
+}
@@ -0,0 +1,88 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTools empties PATH but for a temporary directory and returns a
+// function installing stand-in screenshot programs there. Each records its
+// arguments to NAME.args, returned by the installer, and copies a 6x4 PNG to
+// its last argument.
+func fakeTools(t *testing.T) (string, func(name string) string) {
+	t.Helper()
+	cp, err := exec.LookPath("cp")
+	if err != nil {
+		t.Skipf("cp not available: %v", err)
+	}
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	src := filepath.Join(dir, "src.png")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 6, 4))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	f.Close()
+	return dir, func(name string) string {
+		argsFile := filepath.Join(dir, name+".args")
+		body := "#!/bin/sh\necho \"$@\" > '" + argsFile + "'\nfor last; do :; done\n" + cp + " '" + src + "' \"$last\"\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o755); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return argsFile
+	}
+}
+
+func TestExternalScreenshotDetectsTools(t *testing.T) {
+	_, install := fakeTools(t)
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	if _, err := externalScreenshot(false, CaptureOptions{}, BackendExternal); !errors.Is(err, errBackendUnavailable) {
+		t.Fatalf("expected no tool to be found, got %v", err)
+	}
+
+	// grim only serves Wayland sessions, so an X11 session picks scrot.
+	install("grim")
+	scrotArgs := install("scrot")
+	img, err := externalScreenshot(false, CaptureOptions{IncludeCursor: true}, BackendExternal)
+	if err != nil {
+		t.Fatalf("external screenshot: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 6, 4) {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+	if args, _ := os.ReadFile(scrotArgs); !strings.HasPrefix(string(args), "-o -p ") {
+		t.Fatalf("unexpected scrot arguments %q", args)
+	}
+
+	if _, err := externalScreenshot(false, CaptureOptions{}, BackendMaim); err == nil || !strings.Contains(err.Error(), "maim is not installed") {
+		t.Fatalf("expected a missing maim to be reported, got %v", err)
+	}
+}
+
+func TestExternalScreenshotSelectsRegionWithSlurp(t *testing.T) {
+	dir, install := fakeTools(t)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-1")
+	grimArgs := install("grim")
+	if err := os.WriteFile(filepath.Join(dir, "slurp"), []byte("#!/bin/sh\necho '10,20 30x40'\n"), 0o755); err != nil {
+		t.Fatalf("write slurp: %v", err)
+	}
+	if _, err := externalScreenshot(true, CaptureOptions{}, BackendExternal); err != nil {
+		t.Fatalf("external screenshot: %v", err)
+	}
+	if args, _ := os.ReadFile(grimArgs); !strings.HasPrefix(string(args), "-g 10,20 30x40 ") {
+		t.Fatalf("unexpected grim arguments %q", args)
+	}
+}
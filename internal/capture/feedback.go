@@ -0,0 +1,46 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// shutterEvent is the sound theme event played after a capture.
+const shutterEvent = "camera-shutter"
+
+// shutterSoundFiles are played with paplay when canberra-gtk-play is
+// missing, from the freedesktop sound theme most desktops install.
+var shutterSoundFiles = []string{
+	"/usr/share/sounds/freedesktop/stereo/camera-shutter.oga",
+	"/usr/share/sounds/freedesktop/stereo/screen-capture.oga",
+}
+
+// PlayShutter starts the shutter sound without waiting for it to finish. It
+// prefers canberra-gtk-play, which follows the desktop's sound theme and
+// volume settings, and falls back to paplay with the freedesktop theme file.
+func PlayShutter() error {
+	if path, err := exec.LookPath("canberra-gtk-play"); err == nil {
+		return startDetached(exec.Command(path, "-i", shutterEvent, "-d", "shineyshot"))
+	}
+	path, err := exec.LookPath("paplay")
+	if err != nil {
+		return errors.New("the shutter sound needs canberra-gtk-play or paplay")
+	}
+	for _, file := range shutterSoundFiles {
+		if _, err := os.Stat(file); err == nil {
+			return startDetached(exec.Command(path, file))
+		}
+	}
+	return fmt.Errorf("no shutter sound file found for paplay; install the freedesktop sound theme")
+}
+
+// startDetached runs cmd in the background and reaps it when it exits.
+func startDetached(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
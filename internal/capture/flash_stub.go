@@ -0,0 +1,8 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import "image"
+
+// Flash is a no-op on platforms without X11.
+func Flash(image.Rectangle) error { return nil }
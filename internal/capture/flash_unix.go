@@ -0,0 +1,61 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// flashDuration is how long the capture flash stays on screen.
+var flashDuration = 120 * time.Millisecond
+
+// flashOpacity is the _NET_WM_WINDOW_OPACITY of the flash, about 60%, so
+// compositing window managers show the captured area through it.
+const flashOpacity = 0x99999999
+
+// Flash briefly covers rect, or the whole screen when rect is empty, with a
+// white override-redirect window so a capture taken in the background is
+// visibly confirmed. It returns once the window is gone again.
+func Flash(rect image.Rectangle) error {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		return fmt.Errorf("xproto setup unavailable")
+	}
+	screen := setup.DefaultScreen(conn)
+	if rect.Empty() {
+		rect = image.Rect(0, 0, int(screen.WidthInPixels), int(screen.HeightInPixels))
+	}
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		return err
+	}
+	if err := xproto.CreateWindowChecked(conn, screen.RootDepth, win, screen.Root,
+		int16(rect.Min.X), int16(rect.Min.Y), uint16(rect.Dx()), uint16(rect.Dy()), 0,
+		xproto.WindowClassInputOutput, screen.RootVisual,
+		xproto.CwBackPixel|xproto.CwOverrideRedirect, []uint32{screen.WhitePixel, 1},
+	).Check(); err != nil {
+		return fmt.Errorf("create flash window: %w", err)
+	}
+	if opacity, err := internAtom(conn, "_NET_WM_WINDOW_OPACITY"); err == nil && opacity != 0 {
+		value := make([]byte, 4)
+		xgb.Put32(value, flashOpacity)
+		xproto.ChangeProperty(conn, xproto.PropModeReplace, win, opacity, xproto.AtomCardinal, 32, 1, value)
+	}
+	xproto.MapWindow(conn, win)
+	_, _ = xproto.GetInputFocus(conn).Reply()
+	time.Sleep(flashDuration)
+	xproto.DestroyWindow(conn, win)
+	_, _ = xproto.GetInputFocus(conn).Reply()
+	return nil
+}
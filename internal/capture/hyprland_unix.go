@@ -0,0 +1,343 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// hyprlandBackend answers window and monitor queries through Hyprland's
+// request socket when HYPRLAND_INSTANCE_SIGNATURE names a running instance,
+// and defers to the next backend otherwise. Hyprland windows are captured by
+// cropping a wlr-screencopy screenshot, so no portal dialog is needed.
+type hyprlandBackend struct {
+	fallback platformBackend
+}
+
+// hyprlandSocketPath returns the request socket of the Hyprland instance in
+// the environment: under $XDG_RUNTIME_DIR/hypr since Hyprland 0.40, and under
+// /tmp/hypr before.
+func hyprlandSocketPath() string {
+	sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if sig == "" {
+		return ""
+	}
+	var dirs []string
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		dirs = append(dirs, filepath.Join(runtime, "hypr"))
+	}
+	dirs = append(dirs, filepath.Join(os.TempDir(), "hypr"))
+	for _, dir := range dirs {
+		path := filepath.Join(dir, sig, ".socket.sock")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func (b hyprlandBackend) ListMonitors() ([]MonitorInfo, error) {
+	path := hyprlandSocketPath()
+	if path == "" {
+		return b.fallback.ListMonitors()
+	}
+	monitors, err := hyprlandMonitors(path)
+	if err == nil && len(monitors) > 0 {
+		return monitors, nil
+	}
+	if err == nil {
+		err = errNoMonitors
+	}
+	fallback, fallbackErr := b.fallback.ListMonitors()
+	if fallbackErr != nil {
+		return nil, errors.Join(fmt.Errorf("hyprland ipc: %w", err), fallbackErr)
+	}
+	return fallback, nil
+}
+
+func (b hyprlandBackend) ListWindows() ([]WindowInfo, error) {
+	path := hyprlandSocketPath()
+	if path == "" {
+		return b.fallback.ListWindows()
+	}
+	windows, err := hyprlandWindows(path)
+	if err == nil && len(windows) > 0 {
+		return windows, nil
+	}
+	if err == nil {
+		err = errNoWindows
+	}
+	fallback, fallbackErr := b.fallback.ListWindows()
+	if fallbackErr != nil {
+		return nil, errors.Join(fmt.Errorf("hyprland ipc: %w", err), fallbackErr)
+	}
+	return fallback, nil
+}
+
+// CaptureWindowImage refuses Hyprland windows: the client list does not say
+// which X11 window an XWayland client is, and native clients have none, so
+// callers crop a screenshot instead.
+func (b hyprlandBackend) CaptureWindowImage(id uint32) (*image.RGBA, error) {
+	if path := hyprlandSocketPath(); path != "" {
+		if client, err := hyprlandClient(path, id); err == nil && client != nil {
+			return nil, fmt.Errorf("window %d is a Hyprland client without an X11 drawable", id)
+		}
+	}
+	return b.fallback.CaptureWindowImage(id)
+}
+
+// WindowFrame widens the client area by the border Hyprland draws around
+// every window.
+func (b hyprlandBackend) WindowFrame(id uint32) (uint32, image.Rectangle, error) {
+	if path := hyprlandSocketPath(); path != "" {
+		if client, err := hyprlandClient(path, id); err == nil && client != nil {
+			var border struct {
+				Int int `json:"int"`
+			}
+			_ = hyprlandQuery(path, "getoption general:border_size", &border)
+			return 0, client.rect().Inset(-max(border.Int, 0)), nil
+		}
+	}
+	return b.fallback.WindowFrame(id)
+}
+
+// CursorPosition asks Hyprland for the pointer, which unlike sway it
+// reports in layout coordinates. Other Wayland compositors report none.
+func (b hyprlandBackend) CursorPosition() (image.Point, error) {
+	if path := hyprlandSocketPath(); path != "" {
+		var pos struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		}
+		if err := hyprlandQuery(path, "cursorpos", &pos); err != nil {
+			return image.Point{}, fmt.Errorf("hyprland ipc: %w", err)
+		}
+		return image.Pt(pos.X, pos.Y), nil
+	}
+	if runningOnWayland() {
+		return image.Point{}, fmt.Errorf("%w: the compositor does not report the pointer position", errBackendUnavailable)
+	}
+	return b.fallback.CursorPosition()
+}
+
+type hyprlandMonitor struct {
+	Name      string  `json:"name"`
+	Make      string  `json:"make"`
+	Model     string  `json:"model"`
+	X         int     `json:"x"`
+	Y         int     `json:"y"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Scale     float64 `json:"scale"`
+	Transform int     `json:"transform"`
+	Focused   bool    `json:"focused"`
+	Disabled  bool    `json:"disabled"`
+}
+
+// rect returns the monitor's layout rectangle. Hyprland reports the mode in
+// pixels before rotation and scaling, while the layout, as under sway, is in
+// logical pixels.
+func (m hyprlandMonitor) rect() image.Rectangle {
+	w, h := m.Width, m.Height
+	if m.Transform%2 == 1 {
+		w, h = h, w
+	}
+	if m.Scale > 0 {
+		w = int(math.Round(float64(w) / m.Scale))
+		h = int(math.Round(float64(h) / m.Scale))
+	}
+	return image.Rect(m.X, m.Y, m.X+w, m.Y+h)
+}
+
+type hyprlandWorkspace struct {
+	ID int `json:"id"`
+}
+
+type hyprlandWindow struct {
+	Address        string             `json:"address"`
+	Mapped         bool               `json:"mapped"`
+	Hidden         bool               `json:"hidden"`
+	At             [2]int             `json:"at"`
+	Size           [2]int             `json:"size"`
+	Workspace      hyprlandWorkspace  `json:"workspace"`
+	Class          string             `json:"class"`
+	InitialClass   string             `json:"initialClass"`
+	Title          string             `json:"title"`
+	PID            int64              `json:"pid"`
+	Fullscreen     hyprlandFullscreen `json:"fullscreen"`
+	FocusHistoryID int                `json:"focusHistoryID"`
+}
+
+// hyprlandFullscreen is a client's fullscreen state: a boolean before
+// Hyprland 0.42, then 1 for maximized and 2 for fullscreen.
+type hyprlandFullscreen int
+
+func (f *hyprlandFullscreen) UnmarshalJSON(data []byte) error {
+	var on bool
+	if err := json.Unmarshal(data, &on); err == nil {
+		*f = 0
+		if on {
+			*f = 2
+		}
+		return nil
+	}
+	var mode int
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return err
+	}
+	*f = hyprlandFullscreen(mode)
+	return nil
+}
+
+// id folds the client's address into a window id. Addresses are heap
+// pointers, so the low 32 bits tell the clients of one session apart.
+func (c *hyprlandWindow) id() uint32 {
+	addr, _ := strconv.ParseUint(c.Address, 0, 64)
+	return uint32(addr)
+}
+
+func (c *hyprlandWindow) rect() image.Rectangle {
+	return image.Rect(c.At[0], c.At[1], c.At[0]+c.Size[0], c.At[1]+c.Size[1])
+}
+
+func hyprlandMonitors(path string) ([]MonitorInfo, error) {
+	var outputs []hyprlandMonitor
+	if err := hyprlandQuery(path, "monitors", &outputs); err != nil {
+		return nil, err
+	}
+	return monitorsFromHyprland(outputs), nil
+}
+
+func monitorsFromHyprland(outputs []hyprlandMonitor) []MonitorInfo {
+	monitors := make([]MonitorInfo, 0, len(outputs))
+	for _, out := range outputs {
+		if out.Disabled || out.Width == 0 || out.Height == 0 {
+			continue
+		}
+		monitors = append(monitors, MonitorInfo{
+			Index: len(monitors),
+			Name:  out.Name,
+			Model: joinMakeModel(out.Make, out.Model),
+			Rect:  out.rect(),
+			Scale: out.Scale,
+			// wl_output transforms 0 to 3 turn a quarter each; 4 to 7 flip
+			// first.
+			Rotation: 90 * (out.Transform % 4),
+			Flipped:  out.Transform >= 4,
+			Focused:  out.Focused,
+		})
+	}
+	return monitors
+}
+
+func hyprlandClients(path string) ([]hyprlandWindow, error) {
+	var clients []hyprlandWindow
+	if err := hyprlandQuery(path, "clients", &clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// hyprlandClient returns the mapped client with the window id, or nil.
+func hyprlandClient(path string, id uint32) (*hyprlandWindow, error) {
+	clients, err := hyprlandClients(path)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range clients {
+		if clients[idx].Mapped && clients[idx].id() == id {
+			return &clients[idx], nil
+		}
+	}
+	return nil, nil
+}
+
+func hyprlandWindows(path string) ([]WindowInfo, error) {
+	clients, err := hyprlandClients(path)
+	if err != nil {
+		return nil, err
+	}
+	monitors, _ := hyprlandMonitors(path)
+	return windowsFromHyprland(clients, monitors), nil
+}
+
+func windowsFromHyprland(clients []hyprlandWindow, monitors []MonitorInfo) []WindowInfo {
+	windows := make([]WindowInfo, 0, len(clients))
+	for _, client := range clients {
+		if !client.Mapped {
+			continue
+		}
+		info := WindowInfo{
+			Index:    len(windows),
+			ID:       client.id(),
+			Title:    client.Title,
+			Class:    client.Class,
+			Instance: client.InitialClass,
+			Rect:     client.rect(),
+			Active:   client.FocusHistoryID == 0,
+			Desktop:  -1,
+		}
+		if client.PID > 0 {
+			info.PID = uint32(client.PID)
+			info.Executable = readExecutable(info.PID)
+		}
+		if info.Instance == "" {
+			info.Instance = client.Class
+		}
+		// Special workspaces, Hyprland's scratchpads, have negative ids and
+		// are only shown on demand.
+		switch {
+		case client.Workspace.ID > 0:
+			info.Desktop = client.Workspace.ID
+		case client.Workspace.ID < 0:
+			info.State |= WindowHidden
+		}
+		if client.Hidden {
+			info.State |= WindowHidden
+		}
+		switch client.Fullscreen {
+		case 0:
+		case 1:
+			info.State |= WindowMaximized
+		default:
+			info.State |= WindowFullscreen
+		}
+		info.Monitor = monitorForRect(info.Rect, monitors)
+		windows = append(windows, info)
+	}
+	return windows
+}
+
+// hyprlandQuery sends a request for JSON output and decodes the reply, which
+// Hyprland ends by closing the connection.
+func hyprlandQuery(path, request string, v any) error {
+	conn, err := net.DialTimeout("unix", path, ipcTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(ipcTimeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write([]byte("j/" + request)); err != nil {
+		return err
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("read hyprland reply: %w", err)
+	}
+	if err := json.Unmarshal(reply, v); err != nil {
+		return fmt.Errorf("hyprland %s: %w", request, err)
+	}
+	return nil
+}
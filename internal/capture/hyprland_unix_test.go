@@ -0,0 +1,130 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"image"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHyprlandMonitors = `[
+ {"id":0,"name":"eDP-1","make":"BOE","model":"0x0BCA","x":0,"y":0,"width":2880,"height":1800,"scale":2.0,"transform":0,"focused":false,"disabled":false},
+ {"id":1,"name":"DP-2","make":"Dell Inc.","model":"DELL U2720Q","x":1440,"y":0,"width":3840,"height":2160,"scale":1.5,"transform":5,"focused":true,"disabled":false}
+]`
+
+const testHyprlandClients = `[
+ {"address":"0x55d1c0a3b2f0","mapped":true,"hidden":false,"at":[10,40],"size":[700,500],"workspace":{"id":2,"name":"2"},"class":"foot","initialClass":"foot","title":"Terminal","pid":0,"xwayland":false,"fullscreen":2,"focusHistoryID":0},
+ {"address":"0x55d1c0a3c100","mapped":true,"hidden":false,"at":[1500,20],"size":[800,600],"workspace":{"id":3,"name":"3"},"class":"firefox","initialClass":"firefox","title":"Firefox","pid":0,"xwayland":true,"fullscreen":false,"focusHistoryID":1},
+ {"address":"0x55d1c0a3d000","mapped":true,"hidden":false,"at":[100,100],"size":[300,200],"workspace":{"id":-98,"name":"special:magic"},"class":"pavucontrol","title":"Volume","pid":0,"fullscreen":0,"focusHistoryID":2},
+ {"address":"0x55d1c0a3e000","mapped":false,"at":[0,0],"size":[0,0],"workspace":{"id":-1,"name":""},"class":"","title":"","pid":0,"fullscreen":0,"focusHistoryID":3}
+]`
+
+// serveFakeHyprland answers Hyprland's JSON requests with canned replies
+// from a socket laid out as HYPRLAND_INSTANCE_SIGNATURE names it.
+func serveFakeHyprland(t *testing.T) {
+	t.Helper()
+	runtime := t.TempDir()
+	dir := filepath.Join(runtime, "hypr", "test_sig")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, ".socket.sock"))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test_sig")
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 256)
+				n, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+				reply := "unknown request"
+				switch string(buf[:n]) {
+				case "j/monitors":
+					reply = testHyprlandMonitors
+				case "j/clients":
+					reply = testHyprlandClients
+				case "j/cursorpos":
+					reply = `{"x":2000,"y":300}`
+				case "j/getoption general:border_size":
+					reply = `{"option":"general:border_size","int":2,"set":true}`
+				}
+				_, _ = io.WriteString(conn, reply)
+			}(conn)
+		}
+	}()
+}
+
+func TestHyprlandBackendListsMonitorsAndWindows(t *testing.T) {
+	serveFakeHyprland(t)
+	b := hyprlandBackend{fallback: failingBackend{}}
+
+	monitors, err := b.ListMonitors()
+	if err != nil {
+		t.Fatalf("list monitors: %v", err)
+	}
+	if len(monitors) != 2 {
+		t.Fatalf("unexpected monitors: %+v", monitors)
+	}
+	if m := monitors[0]; m.Rect != image.Rect(0, 0, 1440, 900) || m.Scale != 2 || m.Rotation != 0 || m.Flipped {
+		t.Fatalf("expected eDP-1 to be 1440x900 logical pixels, got %+v", m)
+	}
+	// Flipped and turned a quarter, 3840x2160 at 1.5x lays out as 1440x2560.
+	if m := monitors[1]; m.Rect != image.Rect(1440, 0, 2880, 2560) || m.Model != "Dell U2720Q" || m.Rotation != 90 || !m.Flipped || !m.Focused {
+		t.Fatalf("expected DP-2 to be a focused, flipped-90 Dell U2720Q, got %+v", m)
+	}
+
+	windows, err := b.ListWindows()
+	if err != nil {
+		t.Fatalf("list windows: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("expected the 3 mapped windows, got %+v", windows)
+	}
+	term, fox, scratch := windows[0], windows[1], windows[2]
+	if term.ID != 0xc0a3b2f0 || term.Class != "foot" || !term.Active || term.Desktop != 2 || term.State != WindowFullscreen || term.Rect != image.Rect(10, 40, 710, 540) || term.Monitor != 0 {
+		t.Fatalf("unexpected terminal: %+v", term)
+	}
+	if fox.Active || fox.State != 0 || fox.Monitor != 1 || fox.Desktop != 3 {
+		t.Fatalf("unexpected firefox window: %+v", fox)
+	}
+	if !scratch.Unviewable() || scratch.Desktop != -1 || scratch.Instance != "pavucontrol" {
+		t.Fatalf("expected the special workspace window to be hidden, got %+v", scratch)
+	}
+
+	if _, err := b.CaptureWindowImage(term.ID); err == nil || !strings.Contains(err.Error(), "Hyprland client") {
+		t.Fatalf("expected window capture to be refused, got %v", err)
+	}
+	if _, err := b.CaptureWindowImage(1); err == nil || !strings.Contains(err.Error(), "no x11") {
+		t.Fatalf("expected unknown windows to use the fallback, got %v", err)
+	}
+	if id, frame, err := b.WindowFrame(fox.ID); err != nil || id != 0 || frame != image.Rect(1498, 18, 2302, 622) {
+		t.Fatalf("WindowFrame = %d, %v, %v; want the client with its border", id, frame, err)
+	}
+	if pos, err := b.CursorPosition(); err != nil || pos != image.Pt(2000, 300) {
+		t.Fatalf("CursorPosition = %v, %v", pos, err)
+	}
+}
+
+func TestHyprlandBackendDefersWithoutInstance(t *testing.T) {
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "")
+	b := hyprlandBackend{fallback: failingBackend{}}
+	if _, err := b.ListWindows(); err == nil || err.Error() != "no x11" {
+		t.Fatalf("expected the fallback's error, got %v", err)
+	}
+}
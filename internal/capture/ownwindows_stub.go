@@ -0,0 +1,12 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+// TagOwnWindow is a no-op on platforms without X11.
+func TagOwnWindow(string) error { return nil }
+
+// CanHideOwnWindows reports false: without X11 shineyshot's windows cannot be
+// hidden from captures.
+func CanHideOwnWindows() bool { return false }
+
+func hideOwnWindows() func() { return func() {} }
@@ -0,0 +1,134 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// ownWindowSettle gives the window manager and compositor time to repaint
+// the desktop after our windows are unmapped.
+var ownWindowSettle = 150 * time.Millisecond
+
+// TagOwnWindow marks the untagged X11 windows titled title as shineyshot's by
+// setting WM_CLASS and _NET_WM_PID, which the GUI toolkit leaves unset, so
+// captures can find and hide them. It reports an error when no window
+// matched, for example because the window is not mapped yet.
+func TagOwnWindow(title string) error {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		return fmt.Errorf("xproto setup unavailable")
+	}
+	root := setup.DefaultScreen(conn).Root
+	class := OwnWindowClass + "\x00" + ownWindowClassName + "\x00"
+	pidAtom, _ := internAtom(conn, "_NET_WM_PID")
+	pid := make([]byte, 4)
+	binary.NativeEndian.PutUint32(pid, uint32(os.Getpid()))
+
+	tagged := 0
+	var walk func(win xproto.Window, depth int)
+	walk = func(win xproto.Window, depth int) {
+		tree, err := xproto.QueryTree(conn, win).Reply()
+		if err != nil {
+			return
+		}
+		for _, child := range tree.Children {
+			name := readUTF8Property(conn, child, "_NET_WM_NAME")
+			if name == "" {
+				name = readStringProperty(conn, child, "WM_NAME")
+			}
+			if existing, _ := readClass(conn, child); name == title && existing == "" {
+				xproto.ChangeProperty(conn, xproto.PropModeReplace, child, xproto.AtomWmClass, xproto.AtomString, 8, uint32(len(class)), []byte(class))
+				if pidAtom != 0 {
+					xproto.ChangeProperty(conn, xproto.PropModeReplace, child, pidAtom, xproto.AtomCardinal, 32, 1, pid)
+				}
+				tagged++
+				continue
+			}
+			// Reparenting window managers nest clients inside frames.
+			if depth < 2 {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+	// Round trip so the property changes reach the server before closing.
+	if _, err := xproto.GetInputFocus(conn).Reply(); err != nil {
+		return fmt.Errorf("tag window: %w", err)
+	}
+	if tagged == 0 {
+		return fmt.Errorf("no window titled %q", title)
+	}
+	return nil
+}
+
+// CanHideOwnWindows reports whether captures can hide shineyshot's windows,
+// which takes an X server.
+func CanHideOwnWindows() bool {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// hideOwnWindows unmaps shineyshot's visible windows and returns a function
+// that maps them again. It does nothing without an X server, which also
+// covers native Wayland, where other clients' surfaces cannot be hidden.
+func hideOwnWindows() func() {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return func() {}
+	}
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		conn.Close()
+		return func() {}
+	}
+	root := setup.DefaultScreen(conn).Root
+	windows, err := fetchWindows(conn, root, nil, 0)
+	if err != nil {
+		conn.Close()
+		return func() {}
+	}
+	var hidden []xproto.Window
+	for _, win := range windows {
+		if !isOwnWindow(win) {
+			continue
+		}
+		// Windows on other desktops are already unmapped; mapping them
+		// afterwards would drag them onto this one.
+		attrs, err := xproto.GetWindowAttributes(conn, xproto.Window(win.ID)).Reply()
+		if err != nil || attrs.MapState != xproto.MapStateViewable {
+			continue
+		}
+		xproto.UnmapWindow(conn, xproto.Window(win.ID))
+		hidden = append(hidden, xproto.Window(win.ID))
+	}
+	if len(hidden) == 0 {
+		conn.Close()
+		return func() {}
+	}
+	_, _ = xproto.GetInputFocus(conn).Reply()
+	time.Sleep(ownWindowSettle)
+	return func() {
+		for _, win := range hidden {
+			xproto.MapWindow(conn, win)
+		}
+		_, _ = xproto.GetInputFocus(conn).Reply()
+		conn.Close()
+	}
+}
@@ -0,0 +1,35 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PickWindowSelector asks the user to click the window to capture.
+const PickWindowSelector = "pick"
+
+// ErrPickUnsupported reports a session where PickWindow cannot grab the
+// pointer, such as Wayland. CaptureWindow then uses the ScreenCast portal's
+// window picker instead.
+var ErrPickUnsupported = errors.New("window picking needs an X11 session")
+
+var pickWindowFn = pickWindow
+
+// PickWindow shows a crosshair pointer and returns the window the user clicks.
+// Any key or the right button cancels.
+func PickWindow() (WindowInfo, error) {
+	id, err := pickWindowFn()
+	if err != nil {
+		return WindowInfo{}, fmt.Errorf("pick window: %w", err)
+	}
+	windows, err := ListWindows()
+	if err != nil {
+		return WindowInfo{}, fmt.Errorf("pick window: %w", err)
+	}
+	for _, win := range windows {
+		if win.ID == id {
+			return win, nil
+		}
+	}
+	return WindowInfo{}, fmt.Errorf("pick window: window 0x%x is not in the window list", id)
+}
@@ -0,0 +1,7 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+func pickWindow() (uint32, error) {
+	return 0, ErrPickUnsupported
+}
@@ -0,0 +1,125 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// xcCrosshair is the crosshair glyph in the X cursor font; the glyph after
+// it is its mask.
+const xcCrosshair = 34
+
+// pickWindow grabs the pointer and keyboard until the user clicks a window,
+// and returns the client window under the click.
+func pickWindow() (uint32, error) {
+	if runningOnWayland() {
+		return 0, ErrPickUnsupported
+	}
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return 0, fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		return 0, fmt.Errorf("xproto setup unavailable")
+	}
+	screen := setup.DefaultScreen(conn)
+	if screen == nil {
+		return 0, fmt.Errorf("xproto screen unavailable")
+	}
+	root := screen.Root
+
+	cursor, err := crosshairCursor(conn)
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreeCursor(conn, cursor)
+
+	grab, err := xproto.GrabPointer(conn, false, root,
+		xproto.EventMaskButtonPress|xproto.EventMaskButtonRelease,
+		xproto.GrabModeAsync, xproto.GrabModeAsync, xproto.WindowNone, cursor, xproto.TimeCurrentTime).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("grab pointer: %w", err)
+	}
+	if grab.Status != xproto.GrabStatusSuccess {
+		return 0, fmt.Errorf("grab pointer: another client holds the pointer (status %d)", grab.Status)
+	}
+	defer xproto.UngrabPointer(conn, xproto.TimeCurrentTime)
+	// The keyboard grab only serves to cancel, so a failure is not fatal.
+	if kb, err := xproto.GrabKeyboard(conn, false, root, xproto.TimeCurrentTime, xproto.GrabModeAsync, xproto.GrabModeAsync).Reply(); err == nil && kb.Status == xproto.GrabStatusSuccess {
+		defer xproto.UngrabKeyboard(conn, xproto.TimeCurrentTime)
+	}
+
+	for {
+		ev, err := conn.WaitForEvent()
+		if err != nil {
+			return 0, fmt.Errorf("pick window: %w", err)
+		}
+		if ev == nil {
+			return 0, errors.New("X connection closed while picking")
+		}
+		switch e := ev.(type) {
+		case xproto.KeyPressEvent:
+			return 0, errors.New("cancelled")
+		case xproto.ButtonPressEvent:
+			if e.Detail != xproto.ButtonIndex1 {
+				return 0, errors.New("cancelled")
+			}
+			if e.Child == xproto.WindowNone {
+				return 0, errors.New("no window under the pointer")
+			}
+			return clientUnderPointer(conn, e.Child)
+		}
+	}
+}
+
+// crosshairCursor creates the crosshair cursor from the X cursor font.
+func crosshairCursor(conn *xgb.Conn) (xproto.Cursor, error) {
+	font, err := xproto.NewFontId(conn)
+	if err != nil {
+		return 0, fmt.Errorf("allocate font id: %w", err)
+	}
+	if err := xproto.OpenFontChecked(conn, font, uint16(len("cursor")), "cursor").Check(); err != nil {
+		return 0, fmt.Errorf("open cursor font: %w", err)
+	}
+	defer xproto.CloseFont(conn, font)
+	cursor, err := xproto.NewCursorId(conn)
+	if err != nil {
+		return 0, fmt.Errorf("allocate cursor id: %w", err)
+	}
+	if err := xproto.CreateGlyphCursorChecked(conn, cursor, font, font, xcCrosshair, xcCrosshair+1,
+		0, 0, 0, 0xffff, 0xffff, 0xffff).Check(); err != nil {
+		return 0, fmt.Errorf("create crosshair cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// clientUnderPointer walks from the top-level window the click landed on
+// down through the windows under the pointer until it reaches one with
+// WM_STATE, which marks the client inside a window manager's frame.
+func clientUnderPointer(conn *xgb.Conn, top xproto.Window) (uint32, error) {
+	wmState, err := internAtom(conn, "WM_STATE")
+	if err != nil {
+		return uint32(top), nil
+	}
+	for win := top; win != xproto.WindowNone; {
+		prop, err := xproto.GetProperty(conn, false, win, wmState, xproto.GetPropertyTypeAny, 0, 0).Reply()
+		if err == nil && prop.Type != xproto.AtomNone {
+			return uint32(win), nil
+		}
+		ptr, err := xproto.QueryPointer(conn, win).Reply()
+		if err != nil {
+			break
+		}
+		win = ptr.Child
+	}
+	// Without a WM_STATE window the top-level window is the client.
+	return uint32(top), nil
+}
@@ -11,7 +11,6 @@ import (
 )
 
 func pipewireScreenshot(opts CaptureOptions) (*image.RGBA, error) {
-	_ = opts
 	conn, err := xgb.NewConn()
 	if err != nil {
 		return nil, fmt.Errorf("connect X server: %w", err)
@@ -37,7 +36,9 @@ func pipewireScreenshot(opts CaptureOptions) (*image.RGBA, error) {
 		return nil, fmt.Errorf("screen has empty geometry")
 	}
 
-	reply, err := xproto.GetImage(conn, xproto.ImageFormatZPixmap, xproto.Drawable(screen.Root), 0, 0, geom.Width, geom.Height, ^uint32(0)).Reply()
+	reader := newXImageReader(conn)
+	defer reader.Close()
+	reply, err := reader.getImage(xproto.Drawable(screen.Root), image.Rect(0, 0, width, height))
 	if err != nil {
 		return nil, fmt.Errorf("screen pixels: %w", err)
 	}
@@ -46,5 +47,9 @@ func pipewireScreenshot(opts CaptureOptions) (*image.RGBA, error) {
 	if err != nil {
 		return nil, err
 	}
+	// GetImage never includes the pointer, so draw the XFixes sprite.
+	if opts.IncludeCursor {
+		drawCursor(img, image.Point{})
+	}
 	return img, nil
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -12,6 +14,12 @@ type platformBackend interface {
 	ListMonitors() ([]MonitorInfo, error)
 	ListWindows() ([]WindowInfo, error)
 	CaptureWindowImage(uint32) (*image.RGBA, error)
+	// WindowFrame returns the window to read, and its root rectangle, when
+	// decorations are wanted. A zero id means the frame has no drawable of
+	// its own and the rectangle should be cropped from a screenshot.
+	WindowFrame(uint32) (uint32, image.Rectangle, error)
+	// CursorPosition returns the pointer's position in desktop coordinates.
+	CursorPosition() (image.Point, error)
 }
 
 var backend = newBackend()
@@ -23,10 +31,34 @@ var (
 
 // MonitorInfo describes an individual monitor in the display layout.
 type MonitorInfo struct {
-	Index   int
-	Name    string
+	Index int
+	// Name is the output's connector name, such as "DP-2".
+	Name string
+	// Model is the monitor's make and model, such as "Dell U2720Q", read
+	// from its EDID or reported by the compositor. It is empty when unknown.
+	Model   string
 	Rect    image.Rectangle
 	Primary bool
+	// Scale is the number of physical pixels per logical pixel, from the
+	// compositor's output scale or a RandR scaling transform. Zero means
+	// unknown; see ScaleFactor.
+	Scale float64
+	// Rotation is the clockwise output rotation in degrees: 0, 90, 180 or
+	// 270.
+	Rotation int
+	// Flipped reports a mirrored output.
+	Flipped bool
+	// Focused marks the output holding keyboard focus, where the compositor
+	// reports one.
+	Focused bool
+}
+
+// ScaleFactor returns the monitor's scale, treating an unknown scale as 1.
+func (m MonitorInfo) ScaleFactor() float64 {
+	if m.Scale <= 0 {
+		return 1
+	}
+	return m.Scale
 }
 
 // WindowInfo describes a top-level window available for capture.
@@ -41,6 +73,85 @@ type WindowInfo struct {
 	Rect       image.Rectangle
 	Monitor    int
 	Active     bool
+	// Desktop is the virtual desktop the window is on: _NET_WM_DESKTOP on
+	// X11, counted from 0, or the workspace number under sway and i3. It is
+	// -1 when unknown and AllDesktops for windows shown on every desktop.
+	Desktop int
+	State   WindowState
+	// Type is the window type from _NET_WM_WINDOW_TYPE in lower case without
+	// its prefix, such as "normal", "dock" or "tooltip". It is empty when the
+	// window does not say.
+	Type string
+	// Icon is the window's icon at about WindowIconSize pixels, or nil when
+	// the window has none or the platform cannot read it.
+	Icon *image.RGBA
+}
+
+// WindowIconSize is the width WindowInfo.Icon is chosen for: the smallest
+// icon at least this wide, or the largest one when all are smaller.
+const WindowIconSize = 32
+
+// WindowState holds the window manager's state flags for a window.
+type WindowState uint8
+
+const (
+	// WindowMinimized marks an iconified window (ICCCM IconicState).
+	WindowMinimized WindowState = 1 << iota
+	// WindowMaximized marks a window maximized in both directions.
+	WindowMaximized
+	// WindowFullscreen marks a fullscreen window.
+	WindowFullscreen
+	// WindowHidden marks a window the window manager does not show, such as
+	// _NET_WM_STATE_HIDDEN on X11 or the sway scratchpad.
+	WindowHidden
+	// WindowShaded marks a window rolled up to its title bar.
+	WindowShaded
+)
+
+var windowStateNames = []string{"minimized", "maximized", "fullscreen", "hidden", "shaded"}
+
+// Has reports whether any of flags is set.
+func (s WindowState) Has(flags WindowState) bool {
+	return s&flags != 0
+}
+
+// String lists the set flags separated by commas, such as
+// "maximized,fullscreen".
+func (s WindowState) String() string {
+	var names []string
+	for idx, name := range windowStateNames {
+		if s.Has(1 << idx) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Unviewable reports windows whose pixels are not on screen, so a capture
+// would return stale or black content.
+func (w WindowInfo) Unviewable() bool {
+	return w.State.Has(WindowMinimized | WindowHidden)
+}
+
+// ErrWindowMinimized is returned when asked to capture a minimized or hidden
+// window.
+var ErrWindowMinimized = errors.New("window is minimized or hidden; restore it before capturing")
+
+// AllDesktops is the WindowInfo.Desktop of sticky windows.
+const AllDesktops = -2
+
+// OwnWindowClass is the WM_CLASS instance shineyshot tags its windows with.
+const OwnWindowClass = "shineyshot"
+
+const ownWindowClassName = "ShineyShot"
+
+// isOwnWindow reports whether win belongs to shineyshot: tagged with
+// OwnWindowClass or owned by this process.
+func isOwnWindow(win WindowInfo) bool {
+	if strings.EqualFold(win.Instance, OwnWindowClass) {
+		return true
+	}
+	return win.PID != 0 && int(win.PID) == os.Getpid()
 }
 
 // ListMonitors retrieves all monitors using the platform backend.
@@ -61,6 +172,68 @@ func ListWindows() ([]WindowInfo, error) {
 	return windows, nil
 }
 
+// PanelWindowTypes are the window types of desktop furniture rather than
+// application windows: backgrounds, panels, menus, tooltips and the like.
+var PanelWindowTypes = []string{
+	"desktop", "dock", "toolbar", "menu", "dropdown_menu", "popup_menu",
+	"tooltip", "notification", "splash", "combo", "dnd",
+}
+
+// WindowFilter narrows the result of ListWindowsFiltered. The zero value
+// keeps every window.
+type WindowFilter struct {
+	// VisibleOnly drops minimized and hidden windows.
+	VisibleOnly bool
+	// Monitor keeps windows on one monitor, given as a selector accepted by
+	// FindMonitor.
+	Monitor string
+	// ExcludeTypes drops windows whose Type is listed; see PanelWindowTypes.
+	ExcludeTypes []string
+	// MinWidth and MinHeight drop windows smaller than this in either
+	// direction.
+	MinWidth  int
+	MinHeight int
+}
+
+// ListWindowsFiltered lists the windows that pass filter. Windows keep the
+// Index they have in ListWindows so index selectors still refer to them.
+func ListWindowsFiltered(filter WindowFilter) ([]WindowInfo, error) {
+	windows, err := ListWindows()
+	if err != nil {
+		return nil, err
+	}
+	monitor := -1
+	if filter.Monitor != "" {
+		monitors, err := ListMonitors()
+		if err != nil {
+			return nil, err
+		}
+		mon, err := FindMonitor(monitors, filter.Monitor)
+		if err != nil {
+			return nil, err
+		}
+		monitor = mon.Index
+	}
+	return filterWindows(windows, filter, monitor), nil
+}
+
+// filterWindows applies filter, reading the monitor index from monitor
+// rather than filter.Monitor; -1 keeps every monitor.
+func filterWindows(windows []WindowInfo, filter WindowFilter, monitor int) []WindowInfo {
+	kept := make([]WindowInfo, 0, len(windows))
+	for _, win := range windows {
+		switch {
+		case filter.VisibleOnly && win.Unviewable():
+		case monitor >= 0 && win.Monitor != monitor:
+		case win.Rect.Dx() < filter.MinWidth || win.Rect.Dy() < filter.MinHeight:
+		case slices.Contains(filter.ExcludeTypes, win.Type):
+		default:
+			kept = append(kept, win)
+		}
+	}
+	return kept
+}
+
 func captureWindowImage(id uint32) (*image.RGBA, error) {
 	img, err := backend.CaptureWindowImage(id)
 	if err != nil {
@@ -69,6 +242,56 @@ func captureWindowImage(id uint32) (*image.RGBA, error) {
 	return img, nil
 }
 
+// CurrentMonitorSelector selects the monitor under the pointer; see
+// MonitorAtCursor.
+const CurrentMonitorSelector = "current"
+
+// MonitorAtCursor returns the monitor under the mouse pointer. Where the
+// pointer cannot be read, as on Wayland, it returns the monitor the
+// compositor reports as focused, or the one holding the active window.
+func MonitorAtCursor() (MonitorInfo, error) {
+	monitors, err := ListMonitors()
+	if err != nil {
+		return MonitorInfo{}, err
+	}
+	return monitorAtCursor(monitors)
+}
+
+func monitorAtCursor(monitors []MonitorInfo) (MonitorInfo, error) {
+	if len(monitors) == 0 {
+		return MonitorInfo{}, errNoMonitors
+	}
+	pos, cursorErr := backend.CursorPosition()
+	if cursorErr == nil {
+		for _, mon := range monitors {
+			if pos.In(mon.Rect) {
+				return mon, nil
+			}
+		}
+	}
+	for _, mon := range monitors {
+		if mon.Focused {
+			return mon, nil
+		}
+	}
+	if windows, err := backend.ListWindows(); err == nil {
+		for _, win := range windows {
+			if !win.Active {
+				continue
+			}
+			for _, mon := range monitors {
+				if mon.Index == win.Monitor {
+					return mon, nil
+				}
+			}
+		}
+	}
+	if cursorErr != nil {
+		return MonitorInfo{}, fmt.Errorf("find monitor under the pointer: %w", cursorErr)
+	}
+	return MonitorInfo{}, fmt.Errorf("pointer at %v is not on any monitor", pos)
+}
+
 // FindMonitor resolves a monitor selector against the provided list.
 func FindMonitor(monitors []MonitorInfo, selector string) (MonitorInfo, error) {
 	if len(monitors) == 0 {
@@ -87,6 +310,9 @@ func FindMonitor(monitors []MonitorInfo, selector string) (MonitorInfo, error) {
 		}
 		return monitors[0], nil
 	}
+	if lower == CurrentMonitorSelector {
+		return monitorAtCursor(monitors)
+	}
 	lower = strings.TrimPrefix(lower, "#")
 	if idx, err := strconv.Atoi(lower); err == nil {
 		if idx < 0 || idx >= len(monitors) {
@@ -94,11 +320,28 @@ func FindMonitor(monitors []MonitorInfo, selector string) (MonitorInfo, error) {
 		}
 		return monitors[idx], nil
 	}
+	// An exact name wins over a longer one containing it, as DP-1 over
+	// DP-10, and connector names over models.
+	for _, mon := range monitors {
+		if strings.ToLower(mon.Name) == lower {
+			return mon, nil
+		}
+	}
+	for _, mon := range monitors {
+		if mon.Model != "" && strings.ToLower(mon.Model) == lower {
+			return mon, nil
+		}
+	}
 	for _, mon := range monitors {
 		if strings.Contains(strings.ToLower(mon.Name), lower) {
 			return mon, nil
 		}
 	}
+	for _, mon := range monitors {
+		if strings.Contains(strings.ToLower(mon.Model), lower) {
+			return mon, nil
+		}
+	}
 	return MonitorInfo{}, fmt.Errorf("monitor %q not found", selector)
 }
 
@@ -114,6 +357,11 @@ func SelectWindow(selector string, windows []WindowInfo) (WindowInfo, error) {
 				return win, nil
 			}
 		}
+		for idx := len(windows) - 1; idx >= 0; idx-- {
+			if !windows[idx].Unviewable() {
+				return windows[idx], nil
+			}
+		}
 		return windows[len(windows)-1], nil
 	}
 	lower := strings.ToLower(sel)
@@ -163,6 +411,29 @@ func SelectWindow(selector string, windows []WindowInfo) (WindowInfo, error) {
 		}
 		return WindowInfo{}, fmt.Errorf("window with pid %d not found", pid)
 	}
+	if strings.HasPrefix(lower, "desktop:") {
+		val := strings.TrimSpace(lower[8:])
+		desktop, err := strconv.Atoi(val)
+		if err != nil || desktop < 0 {
+			return WindowInfo{}, fmt.Errorf("invalid desktop %q", val)
+		}
+		var first *WindowInfo
+		for idx, win := range windows {
+			if win.Desktop != desktop {
+				continue
+			}
+			if win.Active {
+				return win, nil
+			}
+			if first == nil {
+				first = &windows[idx]
+			}
+		}
+		if first == nil {
+			return WindowInfo{}, fmt.Errorf("no window on desktop %d", desktop)
+		}
+		return *first, nil
+	}
 	if strings.HasPrefix(lower, "exec:") {
 		needle := strings.TrimSpace(lower[5:])
 		for _, win := range windows {
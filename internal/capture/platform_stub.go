@@ -25,4 +25,12 @@ func (unsupportedBackend) CaptureWindowImage(uint32) (*image.RGBA, error) {
 	return nil, fmt.Errorf("window capture is not supported on this platform")
 }
 
+func (unsupportedBackend) WindowFrame(uint32) (uint32, image.Rectangle, error) {
+	return 0, image.Rectangle{}, fmt.Errorf("window frames are not supported on this platform")
+}
+
+func (unsupportedBackend) CursorPosition() (image.Point, error) {
+	return image.Point{}, fmt.Errorf("pointer position is not supported on this platform")
+}
+
 func runningOnWayland() bool { return false }
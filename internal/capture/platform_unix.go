@@ -4,6 +4,7 @@ package capture
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"os"
@@ -18,7 +19,7 @@ import (
 type x11Backend struct{}
 
 func newBackend() platformBackend {
-	return x11Backend{}
+	return ipcBackend{fallback: hyprlandBackend{fallback: x11Backend{}}}
 }
 
 func runningOnWayland() bool {
@@ -113,18 +114,124 @@ func (x11Backend) CaptureWindowImage(id uint32) (*image.RGBA, error) {
 		return nil, fmt.Errorf("xproto setup unavailable")
 	}
 
-	reply, err := xproto.GetImage(conn, xproto.ImageFormatZPixmap, xproto.Drawable(id), 0, 0, geom.Width, geom.Height, ^uint32(0)).Reply()
+	// The composited pixmap is unaffected by overlapping windows; reading the
+	// window itself returns whatever is on screen in its rectangle.
+	reader := newXImageReader(conn)
+	defer reader.Close()
+	img, compositeErr := compositeWindowImage(reader, setup, xproto.Window(id))
+	if compositeErr == nil {
+		return img, nil
+	}
+	reply, err := reader.getImage(xproto.Drawable(id), image.Rect(0, 0, width, height))
 	if err != nil {
-		return nil, fmt.Errorf("window pixels: %w", err)
+		return nil, errors.Join(compositeErr, fmt.Errorf("window pixels: %w", err))
 	}
 
-	img, err := xImageToRGBA(setup, reply, width, height, "window")
+	img, err = xImageToRGBA(setup, reply, width, height, "window")
 	if err != nil {
 		return nil, err
 	}
 	return img, nil
 }
 
+func (x11Backend) CursorPosition() (image.Point, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return image.Point{}, fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		return image.Point{}, fmt.Errorf("xproto setup unavailable")
+	}
+	screen := setup.DefaultScreen(conn)
+	if screen == nil {
+		return image.Point{}, fmt.Errorf("xproto screen unavailable")
+	}
+	reply, err := xproto.QueryPointer(conn, screen.Root).Reply()
+	if err != nil {
+		return image.Point{}, fmt.Errorf("query pointer: %w", err)
+	}
+	return image.Pt(int(reply.RootX), int(reply.RootY)), nil
+}
+
+// WindowFrame finds the frame a reparenting window manager wraps the client
+// in: the ancestor that is a direct child of the root. Window managers that
+// draw decorations without reparenting advertise them in _NET_FRAME_EXTENTS,
+// so the client rectangle is grown to match and cropped from a screenshot.
+func (x11Backend) WindowFrame(id uint32) (uint32, image.Rectangle, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return 0, image.Rectangle{}, fmt.Errorf("connect X server: %w", err)
+	}
+	defer conn.Close()
+
+	setup := xproto.Setup(conn)
+	if setup == nil {
+		return 0, image.Rectangle{}, fmt.Errorf("xproto setup unavailable")
+	}
+	root := setup.DefaultScreen(conn).Root
+	frame := xproto.Window(id)
+	for {
+		tree, err := xproto.QueryTree(conn, frame).Reply()
+		if err != nil {
+			return 0, image.Rectangle{}, fmt.Errorf("query tree: %w", err)
+		}
+		if tree.Parent == root || tree.Parent == 0 {
+			break
+		}
+		frame = tree.Parent
+	}
+	if frame != xproto.Window(id) {
+		rect, err := rootRect(conn, root, frame)
+		if err != nil {
+			return 0, image.Rectangle{}, fmt.Errorf("frame geometry: %w", err)
+		}
+		return uint32(frame), rect, nil
+	}
+	rect, err := rootRect(conn, root, frame)
+	if err != nil {
+		return 0, image.Rectangle{}, fmt.Errorf("window geometry: %w", err)
+	}
+	left, right, top, bottom, ok := readFrameExtents(conn, frame)
+	if !ok {
+		return id, rect, nil
+	}
+	return 0, image.Rect(rect.Min.X-left, rect.Min.Y-top, rect.Max.X+right, rect.Max.Y+bottom), nil
+}
+
+// rootRect returns the outer rectangle of win, borders included, in root
+// coordinates.
+func rootRect(conn *xgb.Conn, root xproto.Window, win xproto.Window) (image.Rectangle, error) {
+	geo, err := xproto.GetGeometry(conn, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	trans, err := xproto.TranslateCoordinates(conn, win, root, 0, 0).Reply()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	border := int(geo.BorderWidth)
+	x := int(trans.DstX) - border
+	y := int(trans.DstY) - border
+	return image.Rect(x, y, x+int(geo.Width)+border*2, y+int(geo.Height)+border*2), nil
+}
+
+// readFrameExtents reads _NET_FRAME_EXTENTS as left, right, top and bottom.
+func readFrameExtents(conn *xgb.Conn, win xproto.Window) (left, right, top, bottom int, ok bool) {
+	atom, err := internAtom(conn, "_NET_FRAME_EXTENTS")
+	if err != nil || atom == 0 {
+		return 0, 0, 0, 0, false
+	}
+	reply, err := xproto.GetProperty(conn, false, win, atom, xproto.AtomCardinal, 0, 4).Reply()
+	if err != nil || reply.Format != 32 || len(reply.Value) < 16 {
+		return 0, 0, 0, 0, false
+	}
+	v := func(idx int) int { return int(xgb.Get32(reply.Value[idx*4:])) }
+	return v(0), v(1), v(2), v(3), true
+}
+
 func fetchMonitors(conn *xgb.Conn, root xproto.Window) ([]MonitorInfo, error) {
 	if err := randr.Init(conn); err != nil {
 		return nil, fmt.Errorf("init randr: %w", err)
@@ -158,17 +265,65 @@ func fetchMonitors(conn *xgb.Conn, root xproto.Window) ([]MonitorInfo, error) {
 			int(crtc.X)+int(crtc.Width),
 			int(crtc.Y)+int(crtc.Height),
 		)
+		rotation, flipped := randrRotation(crtc.Rotation)
+		scale := 1.0
+		if transform, err := randr.GetCrtcTransform(conn, info.Crtc).Reply(); err == nil && transform.HasTransforms {
+			scale = transformScale(int32(transform.CurrentTransform.Matrix11))
+		}
 		monitors = append(monitors, MonitorInfo{
-			Index:   idx,
-			Name:    name,
-			Rect:    rect,
-			Primary: output == primaryOutput,
+			Index:    idx,
+			Name:     name,
+			Model:    outputModel(conn, output),
+			Rect:     rect,
+			Primary:  output == primaryOutput,
+			Scale:    scale,
+			Rotation: rotation,
+			Flipped:  flipped,
 		})
 		idx++
 	}
 	return monitors, nil
 }
 
+// outputModel reads the make and model from the EDID RandR output property.
+func outputModel(conn *xgb.Conn, output randr.Output) string {
+	atom, err := internAtom(conn, "EDID")
+	if err != nil || atom == xproto.AtomNone {
+		return ""
+	}
+	// Base EDID blocks are 128 bytes, 32 in 4-byte units.
+	prop, err := randr.GetOutputProperty(conn, output, atom, xproto.AtomAny, 0, 32, false, false).Reply()
+	if err != nil || prop.Format != 8 {
+		return ""
+	}
+	return edidModel(prop.Data)
+}
+
+// randrRotation decodes a RandR rotation mask into degrees and whether the
+// output is reflected.
+func randrRotation(mask uint16) (int, bool) {
+	degrees := 0
+	switch {
+	case mask&randr.RotationRotate90 != 0:
+		degrees = 90
+	case mask&randr.RotationRotate180 != 0:
+		degrees = 180
+	case mask&randr.RotationRotate270 != 0:
+		degrees = 270
+	}
+	return degrees, mask&(randr.RotationReflectX|randr.RotationReflectY) != 0
+}
+
+// transformScale converts the horizontal factor of a RandR transform, a
+// 16.16 fixed-point value mapping output pixels to screen coordinates, into
+// physical pixels per logical pixel. "xrandr --scale 0.5x0.5" yields 2.
+func transformScale(m11 int32) float64 {
+	if m11 <= 0 {
+		return 1
+	}
+	return 65536 / float64(m11)
+}
+
 func fetchActiveWindow(conn *xgb.Conn, root xproto.Window) (uint32, error) {
 	atom, err := internAtom(conn, "_NET_ACTIVE_WINDOW")
 	if err != nil {
@@ -245,9 +400,160 @@ func describeWindow(conn *xgb.Conn, root xproto.Window, win xproto.Window) (Wind
 		Executable: exec,
 		Rect:       rect,
 		Monitor:    -1,
+		Desktop:    readDesktop(conn, win),
+		State:      readWindowState(conn, win),
+		Type:       readWindowType(conn, win),
+		Icon:       readWindowIcon(conn, win),
 	}, nil
 }
 
+// readWindowIcon reads the icon closest to WindowIconSize from _NET_WM_ICON.
+func readWindowIcon(conn *xgb.Conn, win xproto.Window) *image.RGBA {
+	atom, err := internAtom(conn, "_NET_WM_ICON")
+	if err != nil || atom == xproto.AtomNone {
+		return nil
+	}
+	// Icon sets run to a few hundred kilobytes with the larger sizes.
+	reply, err := xproto.GetProperty(conn, false, win, atom, xproto.AtomCardinal, 0, 1<<18).Reply()
+	if err != nil || reply.Format != 32 || reply.ValueLen == 0 {
+		return nil
+	}
+	values := make([]uint32, reply.ValueLen)
+	for idx := range values {
+		values[idx] = xgb.Get32(reply.Value[idx*4:])
+	}
+	return decodeNetWMIcon(values, WindowIconSize)
+}
+
+// decodeNetWMIcon picks an icon from _NET_WM_ICON data, a run of width,
+// height and width*height ARGB pixels per icon, preferring the smallest at
+// least size wide. The pixels are not premultiplied.
+func decodeNetWMIcon(data []uint32, size int) *image.RGBA {
+	bestOff, bestW, bestH := -1, 0, 0
+	for off := 0; off+2 <= len(data); {
+		w, h := int(data[off]), int(data[off+1])
+		if w <= 0 || h <= 0 || w*h > len(data)-off-2 {
+			break
+		}
+		better := bestOff < 0 ||
+			(w >= size && (bestW < size || w < bestW)) ||
+			(w < size && bestW < size && w > bestW)
+		if better {
+			bestOff, bestW, bestH = off+2, w, h
+		}
+		off += 2 + w*h
+	}
+	if bestOff < 0 {
+		return nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, bestW, bestH))
+	for idx, argb := range data[bestOff : bestOff+bestW*bestH] {
+		a := argb >> 24
+		p := img.Pix[idx*4 : idx*4+4]
+		p[0] = uint8((argb >> 16 & 0xFF) * a / 0xFF)
+		p[1] = uint8((argb >> 8 & 0xFF) * a / 0xFF)
+		p[2] = uint8((argb & 0xFF) * a / 0xFF)
+		p[3] = uint8(a)
+	}
+	return img
+}
+
+// readWindowType returns the first, most preferred, _NET_WM_WINDOW_TYPE
+// atom with its _NET_WM_WINDOW_TYPE_ prefix removed.
+func readWindowType(conn *xgb.Conn, win xproto.Window) string {
+	atom, err := internAtom(conn, "_NET_WM_WINDOW_TYPE")
+	if err != nil || atom == xproto.AtomNone {
+		return ""
+	}
+	reply, err := xproto.GetProperty(conn, false, win, atom, xproto.AtomAtom, 0, 1).Reply()
+	if err != nil || reply.Format != 32 || reply.ValueLen == 0 {
+		return ""
+	}
+	name, err := xproto.GetAtomName(conn, xproto.Atom(xgb.Get32(reply.Value))).Reply()
+	if err != nil {
+		return ""
+	}
+	return windowTypeName(name.Name)
+}
+
+// windowTypeName turns a _NET_WM_WINDOW_TYPE atom name, or the window_type
+// sway and i3 report for X clients, into a WindowInfo.Type.
+func windowTypeName(name string) string {
+	name = strings.ToLower(strings.TrimPrefix(name, "_NET_WM_WINDOW_TYPE_"))
+	if name == "unknown" {
+		return ""
+	}
+	return name
+}
+
+// iconicState is the ICCCM WM_STATE value of an iconified window.
+const iconicState = 3
+
+// readWindowState combines the ICCCM WM_STATE with the EWMH _NET_WM_STATE
+// atoms.
+func readWindowState(conn *xgb.Conn, win xproto.Window) WindowState {
+	var state WindowState
+	if atom, err := internAtom(conn, "WM_STATE"); err == nil && atom != xproto.AtomNone {
+		reply, err := xproto.GetProperty(conn, false, win, atom, xproto.GetPropertyTypeAny, 0, 1).Reply()
+		if err == nil && reply.Format == 32 && reply.ValueLen > 0 && xgb.Get32(reply.Value) == iconicState {
+			state |= WindowMinimized
+		}
+	}
+	atom, err := internAtom(conn, "_NET_WM_STATE")
+	if err != nil || atom == xproto.AtomNone {
+		return state
+	}
+	reply, err := xproto.GetProperty(conn, false, win, atom, xproto.AtomAtom, 0, 64).Reply()
+	if err != nil || reply.Format != 32 {
+		return state
+	}
+	names := make(map[xproto.Atom]string)
+	for _, name := range []string{
+		"_NET_WM_STATE_HIDDEN", "_NET_WM_STATE_MAXIMIZED_VERT", "_NET_WM_STATE_MAXIMIZED_HORZ",
+		"_NET_WM_STATE_FULLSCREEN", "_NET_WM_STATE_SHADED",
+	} {
+		if a, err := internAtom(conn, name); err == nil && a != xproto.AtomNone {
+			names[a] = name
+		}
+	}
+	var vert, horz bool
+	for idx := 0; idx < int(reply.ValueLen); idx++ {
+		switch names[xproto.Atom(xgb.Get32(reply.Value[idx*4:]))] {
+		case "_NET_WM_STATE_HIDDEN":
+			state |= WindowHidden
+		case "_NET_WM_STATE_MAXIMIZED_VERT":
+			vert = true
+		case "_NET_WM_STATE_MAXIMIZED_HORZ":
+			horz = true
+		case "_NET_WM_STATE_FULLSCREEN":
+			state |= WindowFullscreen
+		case "_NET_WM_STATE_SHADED":
+			state |= WindowShaded
+		}
+	}
+	if vert && horz {
+		state |= WindowMaximized
+	}
+	return state
+}
+
+// readDesktop reads _NET_WM_DESKTOP, where 0xFFFFFFFF marks a sticky window.
+func readDesktop(conn *xgb.Conn, win xproto.Window) int {
+	atom, err := internAtom(conn, "_NET_WM_DESKTOP")
+	if err != nil || atom == 0 {
+		return -1
+	}
+	reply, err := xproto.GetProperty(conn, false, win, atom, xproto.AtomCardinal, 0, 1).Reply()
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return -1
+	}
+	desktop := xgb.Get32(reply.Value)
+	if desktop == 0xFFFFFFFF {
+		return AllDesktops
+	}
+	return int(desktop)
+}
+
 func windowRect(conn *xgb.Conn, root xproto.Window, win xproto.Window) (image.Rectangle, error) {
 	geo, err := xproto.GetGeometry(conn, xproto.Drawable(win)).Reply()
 	if err != nil {
@@ -2,7 +2,10 @@
 
 package capture
 
-import "testing"
+import (
+	"image/color"
+	"testing"
+)
 
 func TestRunningOnWayland(t *testing.T) {
 	t.Setenv("XDG_SESSION_TYPE", "wayland")
@@ -23,3 +26,59 @@ func TestRunningOnWayland(t *testing.T) {
 		t.Fatalf("did not expect wayland session when indicators are absent")
 	}
 }
+
+func TestRandrRotationAndScale(t *testing.T) {
+	cases := []struct {
+		mask    uint16
+		degrees int
+		flipped bool
+	}{
+		{1, 0, false},
+		{2, 90, false},
+		{4, 180, false},
+		{8 | 16, 270, true},
+		{1 | 32, 0, true},
+	}
+	for _, c := range cases {
+		if degrees, flipped := randrRotation(c.mask); degrees != c.degrees || flipped != c.flipped {
+			t.Errorf("randrRotation(%#x) = %d, %v; want %d, %v", c.mask, degrees, flipped, c.degrees, c.flipped)
+		}
+	}
+	if got := transformScale(1 << 15); got != 2 {
+		t.Errorf("transformScale(0.5) = %v, want 2", got)
+	}
+	if got := transformScale(0); got != 1 {
+		t.Errorf("transformScale(0) = %v, want 1", got)
+	}
+}
+
+func TestDecodeNetWMIconPicksSize(t *testing.T) {
+	icon := func(size int, argb uint32) []uint32 {
+		out := []uint32{uint32(size), uint32(size)}
+		for range size * size {
+			out = append(out, argb)
+		}
+		return out
+	}
+	var data []uint32
+	data = append(data, icon(16, 0xFFFF0000)...)
+	data = append(data, icon(48, 0x80FFFFFF)...)
+	data = append(data, icon(32, 0xFF00FF00)...)
+
+	img := decodeNetWMIcon(data, 32)
+	if img == nil || img.Bounds().Dx() != 32 || img.RGBAAt(0, 0) != (color.RGBA{G: 255, A: 255}) {
+		t.Fatalf("expected the 32px green icon, got %v", img)
+	}
+	img = decodeNetWMIcon(data, 40)
+	if img == nil || img.Bounds().Dx() != 48 || img.RGBAAt(0, 0) != (color.RGBA{R: 128, G: 128, B: 128, A: 128}) {
+		t.Fatalf("expected the premultiplied 48px icon, got %v", img)
+	}
+	img = decodeNetWMIcon(data, 64)
+	if img == nil || img.Bounds().Dx() != 48 {
+		t.Fatalf("expected the largest icon when all are smaller, got %v", img)
+	}
+	// A truncated entry ends the list.
+	if img := decodeNetWMIcon([]uint32{64, 64, 1, 2}, 32); img != nil {
+		t.Fatalf("expected no icon from truncated data, got %v", img.Bounds())
+	}
+}
@@ -0,0 +1,57 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Errors reported by captures that go through the desktop portal. They are
+// wrapped, so test for them with errors.Is.
+var (
+	// ErrPortalCancelled means the user dismissed the portal's dialog.
+	ErrPortalCancelled = errors.New("portal request cancelled by the user")
+	// ErrPortalDenied means the portal refused the request, for example
+	// because screenshots are not permitted for this application.
+	ErrPortalDenied = errors.New("portal request denied")
+	// ErrPortalUnavailable means no portal answered on the session bus or it
+	// does not offer the interface.
+	ErrPortalUnavailable = errors.New("desktop portal unavailable")
+	// ErrPortalTimeout means the portal did not answer within the timeout.
+	ErrPortalTimeout = errors.New("timed out waiting for the portal")
+)
+
+// portalTimeout bounds each wait on a portal response. The dialogs wait on
+// the user, so it is generous.
+const portalTimeout = 2 * time.Minute
+
+// portalContext returns the context portal requests wait under: the
+// caller's, or the background one. Each wait adds portalTimeout to it.
+func portalContext(opts CaptureOptions) context.Context {
+	if opts.Context != nil {
+		return opts.Context
+	}
+	return context.Background()
+}
+
+// portalWaitError turns the end of ctx into the error for an abandoned
+// portal request; caller cancellation is passed through as ctx.Err.
+func portalWaitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrPortalTimeout
+	}
+	return ctx.Err()
+}
+
+// portalResponseError maps the code of an org.freedesktop.portal.Request
+// Response signal to an error: 0 is success, 1 a cancelled dialog and
+// anything else a refusal.
+func portalResponseError(code uint32) error {
+	switch code {
+	case 0:
+		return nil
+	case 1:
+		return ErrPortalCancelled
+	}
+	return ErrPortalDenied
+}
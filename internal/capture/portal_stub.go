@@ -3,12 +3,13 @@
 package capture
 
 import (
+	"errors"
 	"fmt"
 	"image"
 )
 
 func portalScreenshot(interactive bool, _ CaptureOptions) (*image.RGBA, error) {
-	return nil, fmt.Errorf("portal screenshot is not supported on this platform")
+	return nil, fmt.Errorf("portal screenshot is not supported on this platform: %w", ErrPortalUnavailable)
 }
 
-func isPortalUnsupportedError(error) bool { return false }
+func isPortalUnsupportedError(err error) bool { return errors.Is(err, ErrPortalUnavailable) }
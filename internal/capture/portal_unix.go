@@ -3,11 +3,13 @@
 package capture
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	"image/png"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -20,7 +22,7 @@ var portalHandleToken = newPortalHandleToken
 func portalScreenshot(interactive bool, captureOpts CaptureOptions) (*image.RGBA, error) {
 	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
-		return nil, fmt.Errorf("dbus connect: %w", err)
+		return nil, fmt.Errorf("dbus connect: %w: %w", ErrPortalUnavailable, err)
 	}
 	defer func() {
 		if cerr := conn.Close(); cerr != nil {
@@ -28,49 +30,97 @@ func portalScreenshot(interactive bool, captureOpts CaptureOptions) (*image.RGBA
 		}
 	}()
 
+	// Subscribe before calling so a quick response is not missed.
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	rule := "type='signal',interface='org.freedesktop.portal.Request',member='Response'"
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err != nil {
+		return nil, fmt.Errorf("portal screenshot subscribe: %w", err)
+	}
+	defer conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, rule)
+
 	obj := conn.Object("org.freedesktop.portal.Desktop", "/org/freedesktop/portal/desktop")
 	opts := portalScreenshotOptions(interactive, captureOpts)
 	var handle dbus.ObjectPath
-	call := obj.Call("org.freedesktop.portal.Screenshot.Screenshot", 0, "", opts)
-	if call.Err != nil {
-		return nil, fmt.Errorf("portal screenshot call: %w", call.Err)
+	if err := obj.Call("org.freedesktop.portal.Screenshot.Screenshot", 0, "", opts).Store(&handle); err != nil {
+		return nil, fmt.Errorf("portal screenshot call: %w", portalCallError(err))
 	}
-	if err := call.Store(&handle); err != nil {
-		return nil, fmt.Errorf("portal screenshot response: %w", err)
+	results, err := waitPortalResponse(portalContext(captureOpts), conn, signals, handle)
+	if err != nil {
+		return nil, fmt.Errorf("portal screenshot: %w", err)
 	}
-
-	sigc := make(chan *dbus.Signal, 1)
-	conn.Signal(sigc)
-	rule := fmt.Sprintf("type='signal',interface='org.freedesktop.portal.Request',member='Response',path='%s'", handle)
-	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err != nil {
-		return nil, fmt.Errorf("portal screenshot subscribe: %w", err)
+	uri, _ := results["uri"].Value().(string)
+	if uri == "" {
+		return nil, fmt.Errorf("portal screenshot: response missing image data")
 	}
-	defer conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, rule)
+	path, err := portalFilePath(uri)
+	if err != nil {
+		return nil, fmt.Errorf("portal screenshot: %w", err)
+	}
+	img, err := loadPNG(path)
+	if err != nil {
+		return nil, fmt.Errorf("portal screenshot image: %w", err)
+	}
+	return img, nil
+}
 
-	for sig := range sigc {
-		if sig.Path == handle && sig.Name == "org.freedesktop.portal.Request.Response" {
-			if len(sig.Body) >= 2 {
-				res := sig.Body[1].(map[string]dbus.Variant)
-				if uriVar, ok := res["uri"]; ok {
-					uri := uriVar.Value().(string)
-					path := strings.TrimPrefix(uri, "file://")
-					img, err := loadPNG(path)
-					if err != nil {
-						return nil, fmt.Errorf("portal screenshot image: %w", err)
-					}
-					return img, nil
-				}
+// waitPortalResponse waits for the Response signal of the portal request at
+// handle, for at most portalTimeout. A request abandoned because ctx ended or
+// the wait timed out is closed so its dialog goes away.
+func waitPortalResponse(ctx context.Context, conn *dbus.Conn, signals <-chan *dbus.Signal, handle dbus.ObjectPath) (map[string]dbus.Variant, error) {
+	ctx, cancel := context.WithTimeout(ctx, portalTimeout)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Object("org.freedesktop.portal.Desktop", handle).Call("org.freedesktop.portal.Request.Close", 0)
+			return nil, portalWaitError(ctx)
+		case sig, ok := <-signals:
+			if !ok {
+				return nil, fmt.Errorf("%w: dbus connection closed", ErrPortalUnavailable)
+			}
+			if sig.Path != handle || sig.Name != "org.freedesktop.portal.Request.Response" || len(sig.Body) < 2 {
+				continue
 			}
-			break
+			code, _ := sig.Body[0].(uint32)
+			if err := portalResponseError(code); err != nil {
+				return nil, err
+			}
+			results, _ := sig.Body[1].(map[string]dbus.Variant)
+			return results, nil
 		}
 	}
-	return nil, fmt.Errorf("portal screenshot: response missing image data")
+}
+
+// portalCallError marks a failed portal method call with
+// ErrPortalUnavailable when it means no usable portal is running.
+func portalCallError(err error) error {
+	if isPortalUnsupportedError(err) && !errors.Is(err, ErrPortalUnavailable) {
+		return fmt.Errorf("%w: %w", ErrPortalUnavailable, err)
+	}
+	return err
+}
+
+// portalFilePath returns the local path of the file:// URI the Screenshot
+// portal reports; the path is percent-encoded in the URI.
+func portalFilePath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("image uri %q: %w", uri, err)
+	}
+	if u.Scheme != "file" || u.Path == "" {
+		return "", fmt.Errorf("image uri %q is not a local file", uri)
+	}
+	return u.Path, nil
 }
 
 func isPortalUnsupportedError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if errors.Is(err, ErrPortalUnavailable) {
+		return true
+	}
 	var dbusErr *dbus.Error
 	if errors.As(err, &dbusErr) {
 		switch dbusErr.Name {
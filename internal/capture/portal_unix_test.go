@@ -3,6 +3,8 @@
 package capture
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/godbus/dbus/v5"
@@ -65,6 +67,47 @@ func TestPortalScreenshotOptions(t *testing.T) {
 	}
 }
 
+func TestWaitPortalResponseReportsTypedErrors(t *testing.T) {
+	const handle = dbus.ObjectPath("/org/freedesktop/portal/desktop/request/1_1/t")
+	response := func(path dbus.ObjectPath, code uint32) *dbus.Signal {
+		return &dbus.Signal{Path: path, Name: "org.freedesktop.portal.Request.Response",
+			Body: []any{code, map[string]dbus.Variant{"uri": dbus.MakeVariant("file:///tmp/a%20b.png")}}}
+	}
+	for code, want := range map[uint32]error{1: ErrPortalCancelled, 2: ErrPortalDenied} {
+		signals := make(chan *dbus.Signal, 2)
+		// Responses to other requests are skipped.
+		signals <- response("/org/freedesktop/portal/desktop/request/1_1/other", 0)
+		signals <- response(handle, code)
+		if _, err := waitPortalResponse(context.Background(), nil, signals, handle); !errors.Is(err, want) {
+			t.Fatalf("response %d: got %v, want %v", code, err, want)
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	signals <- response(handle, 0)
+	results, err := waitPortalResponse(context.Background(), nil, signals, handle)
+	if err != nil {
+		t.Fatalf("success: %v", err)
+	}
+	uri, _ := results["uri"].Value().(string)
+	if path, err := portalFilePath(uri); err != nil || path != "/tmp/a b.png" {
+		t.Fatalf("portalFilePath(%q) = %q, %v; want the unescaped path", uri, path, err)
+	}
+	if _, err := portalFilePath("https://example.com/a.png"); err == nil {
+		t.Fatalf("expected a non-file uri to be refused")
+	}
+
+	closed := make(chan *dbus.Signal)
+	close(closed)
+	_, err = waitPortalResponse(context.Background(), nil, closed, handle)
+	if !errors.Is(err, ErrPortalUnavailable) || !isPortalUnsupportedError(err) {
+		t.Fatalf("expected a closed connection to mark the portal unavailable, got %v", err)
+	}
+	if err := portalCallError(&dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}); !errors.Is(err, ErrPortalUnavailable) {
+		t.Fatalf("expected a missing portal service to be ErrPortalUnavailable, got %v", err)
+	}
+}
+
 func boolVariant(t *testing.T, values map[string]dbus.Variant, key string) bool {
 	t.Helper()
 	variant, ok := values[key]
@@ -0,0 +1,94 @@
+package capture
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// RecaptureToken records what a capture read so Recapture can read the same
+// monitor, area or window again. It is an opaque string that may be stored
+// between runs. Captures that cannot be repeated, such as regions picked in
+// the portal's dialog, carry an empty token.
+type RecaptureToken string
+
+// Recapture kinds.
+const (
+	recaptureScreen    = "screen"
+	recaptureRegion    = "region"
+	recaptureWindow    = "window"
+	recaptureWorkspace = "workspace"
+)
+
+// recaptureSpec is the decoded form of a RecaptureToken.
+type recaptureSpec struct {
+	Kind string `json:"kind"`
+	// Monitor is the monitor name for screen captures; empty means the
+	// whole desktop.
+	Monitor string          `json:"monitor,omitempty"`
+	Region  image.Rectangle `json:"region"`
+	Window  uint32          `json:"window,omitempty"`
+	Desktop int             `json:"desktop,omitempty"`
+	// Restore is the ScreenCast portal's restore token for windows picked in
+	// its dialog, which lets the portal share the same window without asking.
+	Restore string `json:"restore,omitempty"`
+}
+
+func (s recaptureSpec) token() RecaptureToken {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return RecaptureToken(base64.RawURLEncoding.EncodeToString(data))
+}
+
+// ErrNotRecapturable is returned by Recapture for an empty token.
+var ErrNotRecapturable = errors.New("capture cannot be repeated")
+
+func (t RecaptureToken) spec() (recaptureSpec, error) {
+	if t == "" {
+		return recaptureSpec{}, ErrNotRecapturable
+	}
+	data, err := base64.RawURLEncoding.DecodeString(string(t))
+	if err != nil {
+		return recaptureSpec{}, fmt.Errorf("invalid recapture token: %w", err)
+	}
+	var spec recaptureSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return recaptureSpec{}, fmt.Errorf("invalid recapture token: %w", err)
+	}
+	return spec, nil
+}
+
+// Recapture repeats the capture token came from: the same monitor or whole
+// desktop, the same region in pixels, the same window or the same virtual
+// desktop. A window that has since closed is reported as an error rather
+// than replaced by another.
+func Recapture(token RecaptureToken, opts CaptureOptions) (CaptureResult, error) {
+	spec, err := token.spec()
+	if err != nil {
+		return CaptureResult{}, fmt.Errorf("recapture: %w", err)
+	}
+	switch spec.Kind {
+	case recaptureScreen:
+		return CaptureScreenshot(spec.Monitor, opts)
+	case recaptureRegion:
+		opts.Units = UnitsPixel
+		return CaptureRegionRect(spec.Region, opts)
+	case recaptureWindow:
+		if spec.Window == 0 {
+			opts, err := waitForDelay(opts)
+			if err != nil {
+				return CaptureResult{}, err
+			}
+			return captureWindowViaPortal(PortalWindowSelector, opts, spec.Restore, nil)
+		}
+		return CaptureWindow("id:"+strconv.FormatUint(uint64(spec.Window), 10), opts)
+	case recaptureWorkspace:
+		return CaptureWorkspace(spec.Desktop, opts)
+	}
+	return CaptureResult{}, fmt.Errorf("recapture: unknown capture kind %q", spec.Kind)
+}
@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CaptureResult is a captured image together with where and when it was
+// taken.
+type CaptureResult struct {
+	Image *image.RGBA
+	// Time is when the pixels were read, after any delay.
+	Time time.Time
+	// Monitor is the monitor holding the whole capture, or nil when it spans
+	// several monitors or the layout is unknown.
+	Monitor *MonitorInfo
+	// Window is the captured window for window captures.
+	Window *WindowInfo
+	// Region is the area the image covers in desktop coordinates. It is empty
+	// when the position is unknown, as for regions picked in a portal dialog.
+	Region image.Rectangle
+	// Scale is the largest scale factor of the monitors under Region, 1 when
+	// unknown.
+	Scale float64
+	// Token repeats this capture through Recapture; it is empty when the
+	// capture cannot be repeated.
+	Token RecaptureToken
+}
+
+// newResult stamps img with the current time and locates region on the
+// monitors, which may be nil when the layout cannot be read.
+func newResult(img *image.RGBA, region image.Rectangle, monitors []MonitorInfo) CaptureResult {
+	res := CaptureResult{Image: img, Time: time.Now(), Region: region, Scale: 1}
+	if region.Empty() {
+		return res
+	}
+	for _, mon := range monitors {
+		if !region.Overlaps(mon.Rect) {
+			continue
+		}
+		res.Scale = max(res.Scale, mon.ScaleFactor())
+		if region.In(mon.Rect) {
+			m := mon
+			res.Monitor = &m
+		}
+	}
+	return res
+}
+
+// desktopBounds is the union of the monitors, or fallback when there are
+// none.
+func desktopBounds(monitors []MonitorInfo, fallback image.Rectangle) image.Rectangle {
+	var desktop image.Rectangle
+	for _, mon := range monitors {
+		desktop = desktop.Union(mon.Rect)
+	}
+	if desktop.Empty() {
+		return fallback
+	}
+	return desktop
+}
+
+// Summary describes the capture in a few words for notifications, such as
+// "1280x720 on DP-1 at 2x".
+func (r CaptureResult) Summary() string {
+	var parts []string
+	if r.Image != nil {
+		parts = append(parts, fmt.Sprintf("%dx%d", r.Image.Bounds().Dx(), r.Image.Bounds().Dy()))
+	}
+	if r.Monitor != nil && r.Monitor.Name != "" {
+		parts = append(parts, "on "+r.Monitor.Name)
+	}
+	if r.Scale > 1 {
+		parts = append(parts, "at "+strconv.FormatFloat(r.Scale, 'g', -1, 64)+"x")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Text returns the metadata as PNG text keywords. Title and Creation Time
+// are the PNG specification's predefined keywords; the rest are prefixed
+// with "shineyshot:". Unknown values are left out.
+func (r CaptureResult) Text() map[string]string {
+	text := map[string]string{"Software": "shineyshot"}
+	if !r.Time.IsZero() {
+		text["Creation Time"] = r.Time.Format(time.RFC3339)
+	}
+	if r.Window != nil {
+		if r.Window.Title != "" {
+			text["Title"] = r.Window.Title
+		}
+		if r.Window.Class != "" {
+			text["shineyshot:window-class"] = r.Window.Class
+		}
+	}
+	if r.Monitor != nil && r.Monitor.Name != "" {
+		text["shineyshot:monitor"] = r.Monitor.Name
+	}
+	if !r.Region.Empty() {
+		text["shineyshot:region"] = fmt.Sprintf("%d,%d,%d,%d", r.Region.Min.X, r.Region.Min.Y, r.Region.Max.X, r.Region.Max.Y)
+	}
+	if r.Scale > 0 {
+		text["shineyshot:scale"] = strconv.FormatFloat(r.Scale, 'g', -1, 64)
+	}
+	return text
+}
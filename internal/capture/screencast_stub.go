@@ -0,0 +1,12 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import (
+	"fmt"
+	"image"
+)
+
+func screencastWindow(CaptureOptions, string) (*image.RGBA, string, error) {
+	return nil, "", fmt.Errorf("screencast window capture is not supported on this platform")
+}
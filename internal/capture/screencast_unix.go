@@ -0,0 +1,235 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	screencastIface = "org.freedesktop.portal.ScreenCast"
+
+	screencastSourceMonitor = 1
+	screencastSourceWindow  = 2
+	screencastCursorHidden  = 1
+	screencastCursorEmbed   = 2
+)
+
+// gstLaunch is the GStreamer tool used to pull one frame from the PipeWire
+// stream the portal hands out.
+var gstLaunch = "gst-launch-1.0"
+
+// screencastWindow asks the ScreenCast portal for a window, letting the user
+// pick it in the desktop's dialog, and returns a single frame of it with the
+// restore token that shares the same window next time.
+func screencastWindow(opts CaptureOptions, restore string) (*image.RGBA, string, error) {
+	sc, err := openScreencast(portalContext(opts), screencastSourceWindow, opts.IncludeCursor, screencastPersist{restore: restore, enabled: true})
+	if err != nil {
+		return nil, "", err
+	}
+	defer sc.Close()
+	img, err := pipewireFrame(sc.remote, sc.node)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, sc.restoreToken, nil
+}
+
+// screencastPersist asks the portal to remember the shared source. The
+// restore token from an earlier session selects it again without a dialog
+// on portals that support it (ScreenCast version 4).
+type screencastPersist struct {
+	enabled bool
+	restore string
+}
+
+// screencastPersistUntilRevoked keeps the permission until the user revokes
+// it in the desktop's settings.
+const screencastPersistUntilRevoked = 2
+
+// screencast is a running ScreenCast portal session and the PipeWire node
+// it shares.
+type screencast struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+	remote  *os.File
+	node    uint32
+	// restoreToken is set when the portal agreed to persist the selection.
+	restoreToken string
+}
+
+// openScreencast starts a ScreenCast session for one source of the given
+// types, which the user picks in the desktop's dialog.
+func openScreencast(ctx context.Context, types uint32, includeCursor bool, persist screencastPersist) (sc *screencast, err error) {
+	if _, err := exec.LookPath(gstLaunch); err != nil {
+		return nil, fmt.Errorf("screencast needs %s with the PipeWire plugin: %w", gstLaunch, err)
+	}
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("dbus connect: %w: %w", ErrPortalUnavailable, err)
+	}
+	sc = &screencast{conn: conn}
+	defer func() {
+		if err != nil {
+			sc.Close()
+		}
+	}()
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	rule := "type='signal',interface='org.freedesktop.portal.Request',member='Response'"
+	if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err != nil {
+		return nil, fmt.Errorf("screencast subscribe: %w", err)
+	}
+	defer conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, rule)
+
+	obj := conn.Object("org.freedesktop.portal.Desktop", "/org/freedesktop/portal/desktop")
+	results, err := screencastRequest(ctx, conn, obj, signals, "CreateSession", map[string]dbus.Variant{
+		"handle_token":         dbus.MakeVariant(portalHandleToken()),
+		"session_handle_token": dbus.MakeVariant(portalHandleToken()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("screencast session: %w", err)
+	}
+	sc.session, err = variantObjectPath(results["session_handle"])
+	if err != nil {
+		return nil, fmt.Errorf("screencast session: %w", err)
+	}
+
+	cursor := uint32(screencastCursorHidden)
+	if includeCursor {
+		cursor = screencastCursorEmbed
+	}
+	sources := map[string]dbus.Variant{
+		"handle_token": dbus.MakeVariant(portalHandleToken()),
+		"types":        dbus.MakeVariant(types),
+		"multiple":     dbus.MakeVariant(false),
+		"cursor_mode":  dbus.MakeVariant(cursor),
+	}
+	if persist.enabled {
+		sources["persist_mode"] = dbus.MakeVariant(uint32(screencastPersistUntilRevoked))
+		if persist.restore != "" {
+			sources["restore_token"] = dbus.MakeVariant(persist.restore)
+		}
+	}
+	if _, err := screencastRequest(ctx, conn, obj, signals, "SelectSources", sc.session, sources); err != nil {
+		return nil, fmt.Errorf("screencast select source: %w", err)
+	}
+	results, err = screencastRequest(ctx, conn, obj, signals, "Start", sc.session, "", map[string]dbus.Variant{
+		"handle_token": dbus.MakeVariant(portalHandleToken()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("screencast start: %w", err)
+	}
+	if sc.node, err = screencastNode(results); err != nil {
+		return nil, err
+	}
+	sc.restoreToken, _ = results["restore_token"].Value().(string)
+
+	var fd dbus.UnixFD
+	if err := obj.Call(screencastIface+".OpenPipeWireRemote", 0, sc.session, map[string]dbus.Variant{}).Store(&fd); err != nil {
+		return nil, fmt.Errorf("screencast pipewire remote: %w", err)
+	}
+	sc.remote = os.NewFile(uintptr(fd), "pipewire-remote")
+	return sc, nil
+}
+
+// Close ends the portal session and releases the PipeWire remote.
+func (sc *screencast) Close() error {
+	if sc.remote != nil {
+		sc.remote.Close()
+	}
+	if sc.session != "" {
+		sc.conn.Object("org.freedesktop.portal.Desktop", sc.session).Call("org.freedesktop.portal.Session.Close", 0)
+	}
+	return sc.conn.Close()
+}
+
+// screencastRequest calls a ScreenCast method and waits for the Response
+// signal on the request object it returns.
+func screencastRequest(ctx context.Context, conn *dbus.Conn, obj dbus.BusObject, signals <-chan *dbus.Signal, method string, args ...any) (map[string]dbus.Variant, error) {
+	var handle dbus.ObjectPath
+	if err := obj.Call(screencastIface+"."+method, 0, args...).Store(&handle); err != nil {
+		return nil, portalCallError(err)
+	}
+	return waitPortalResponse(ctx, conn, signals, handle)
+}
+
+func variantObjectPath(v dbus.Variant) (dbus.ObjectPath, error) {
+	switch val := v.Value().(type) {
+	case dbus.ObjectPath:
+		return val, nil
+	case string:
+		return dbus.ObjectPath(val), nil
+	}
+	return "", fmt.Errorf("response missing session handle")
+}
+
+// screencastNode returns the PipeWire node of the first stream in a Start
+// response; streams are a(ua{sv}).
+func screencastNode(results map[string]dbus.Variant) (uint32, error) {
+	raw, ok := results["streams"]
+	if !ok {
+		return 0, fmt.Errorf("screencast start: response has no streams")
+	}
+	// godbus decodes the array as [][]any; accept []any too.
+	var streams [][]any
+	switch v := raw.Value().(type) {
+	case [][]any:
+		streams = v
+	case []any:
+		for _, item := range v {
+			if fields, ok := item.([]any); ok {
+				streams = append(streams, fields)
+			}
+		}
+	}
+	for _, fields := range streams {
+		if len(fields) > 0 {
+			if node, ok := fields[0].(uint32); ok {
+				return node, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("screencast start: nothing was shared")
+}
+
+// pipewireFrame reads one frame of node from the PipeWire remote with
+// GStreamer and decodes it.
+func pipewireFrame(remote *os.File, node uint32) (*image.RGBA, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gstLaunch, "-q",
+		"pipewiresrc", "fd=3", "path="+strconv.FormatUint(uint64(node), 10), "num-buffers=1", "do-timestamp=true",
+		"!", "videoconvert", "!", "pngenc", "snapshot=true", "!", "fdsink", "fd=1")
+	cmd.ExtraFiles = []*os.File{remote}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := bytes.TrimSpace(stderr.Bytes())
+		if len(msg) > 0 {
+			return nil, fmt.Errorf("read pipewire frame: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("read pipewire frame: %w", err)
+	}
+	img, err := png.Decode(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("decode pipewire frame: %w", err)
+	}
+	if img.Bounds().Empty() {
+		return nil, errors.New("pipewire frame is empty")
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
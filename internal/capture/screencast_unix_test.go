@@ -0,0 +1,107 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestScreencastNodeDecodesStreams(t *testing.T) {
+	streams := []any{
+		[]any{uint32(57), map[string]dbus.Variant{"size": dbus.MakeVariant([]any{int32(800), int32(600)})}},
+	}
+	node, err := screencastNode(map[string]dbus.Variant{"streams": dbus.MakeVariant(streams)})
+	if err != nil || node != 57 {
+		t.Fatalf("screencastNode = %d, %v; want 57", node, err)
+	}
+	decoded := [][]any{{uint32(9), map[string]dbus.Variant{}}}
+	if node, err := screencastNode(map[string]dbus.Variant{"streams": dbus.MakeVariant(decoded)}); err != nil || node != 9 {
+		t.Fatalf("screencastNode = %d, %v; want 9", node, err)
+	}
+	if _, err := screencastNode(map[string]dbus.Variant{}); err == nil {
+		t.Fatalf("expected error without streams")
+	}
+}
+
+func TestPipewireFrameDecodesGStreamerOutput(t *testing.T) {
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "frame.png")
+	f, err := os.Create(pngPath)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 7, 5))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// The stand-in checks it was handed the remote on fd 3 and that the node
+	// reached the pipeline.
+	script := filepath.Join(dir, "gst-launch")
+	body := "#!/bin/sh\n[ -e /dev/fd/3 ] || exit 3\ncase \"$*\" in *path=42*) ;; *) exit 4 ;; esac\ncat '" + pngPath + "'\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	prev := gstLaunch
+	gstLaunch = script
+	t.Cleanup(func() { gstLaunch = prev })
+
+	remote, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("open remote: %v", err)
+	}
+	defer remote.Close()
+	img, err := pipewireFrame(remote, 42)
+	if err != nil {
+		t.Fatalf("pipewireFrame: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 7, 5) {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+}
+
+func TestCaptureWindowFallsBackToScreencastOnWayland(t *testing.T) {
+	originalBackend := backend
+	prev := screencastWindowFn
+	t.Cleanup(func() {
+		backend = originalBackend
+		screencastWindowFn = prev
+	})
+	backend = fakeBackend{windowsErr: errors.New("no x11")}
+	want := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	calls := 0
+	screencastWindowFn = func(CaptureOptions, string) (*image.RGBA, string, error) {
+		calls++
+		return want, "", nil
+	}
+
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if _, err := CaptureWindow("firefox", CaptureOptions{}); err == nil || calls != 0 {
+		t.Fatalf("X11 sessions should not use the portal, calls=%d err=%v", calls, err)
+	}
+	if res, err := CaptureWindow("portal", CaptureOptions{}); err != nil || res.Image != want || res.Window.Title != "portal selection" {
+		t.Fatalf("explicit portal selector: %+v %v", res, err)
+	}
+
+	t.Setenv("XDG_SESSION_TYPE", "wayland")
+	res, err := CaptureWindow("firefox", CaptureOptions{})
+	if err != nil || res.Image != want || calls != 2 {
+		t.Fatalf("expected portal fallback, calls=%d err=%v", calls, err)
+	}
+
+	screencastWindowFn = func(CaptureOptions, string) (*image.RGBA, string, error) { return nil, "", errors.New("cancelled") }
+	_, err = CaptureWindow("firefox", CaptureOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no x11") || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import "image"
+
+func wlrScreencopy(CaptureOptions) (*image.RGBA, error) {
+	return nil, errBackendUnavailable
+}
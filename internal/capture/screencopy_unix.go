@@ -0,0 +1,270 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/example/shineyshot/internal/wayland"
+)
+
+// wl_shm pixel formats screencopy frames are commonly offered in. ARGB and
+// XRGB use the wl_shm enum values; the others are DRM fourcc codes.
+const (
+	shmFormatARGB8888 = 0
+	shmFormatXRGB8888 = 1
+	shmFormatABGR8888 = 0x34324241
+	shmFormatXBGR8888 = 0x34324258
+)
+
+const screencopyFlagYInvert = 1
+
+// screencopyOutput is a wl_output and the layout details needed to place its
+// frame in a desktop-sized image.
+type screencopyOutput struct {
+	id       uint32
+	name     string
+	position image.Point
+}
+
+type screencopyBuffer struct {
+	format, width, height, stride uint32
+}
+
+// wlrScreencopy captures every output through zwlr_screencopy_manager_v1 and
+// arranges the frames by output position. It needs no dialog, but only
+// wlroots-based compositors such as sway and Hyprland offer the protocol.
+func wlrScreencopy(opts CaptureOptions) (*image.RGBA, error) {
+	if !runningOnWayland() {
+		return nil, errBackendUnavailable
+	}
+	c, err := wayland.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBackendUnavailable, err)
+	}
+	defer c.Close()
+
+	registry, globals, err := c.Registry()
+	if err != nil {
+		return nil, err
+	}
+	var manager, shm uint32
+	var managerVersion uint32
+	var outputs []*screencopyOutput
+	for _, g := range globals {
+		switch g.Iface {
+		case "zwlr_screencopy_manager_v1":
+			managerVersion = min(g.Version, 3)
+			manager, err = c.Bind(registry, g, managerVersion)
+		case "wl_shm":
+			shm, err = c.Bind(registry, g, 1)
+		case "wl_output":
+			out := &screencopyOutput{}
+			out.id, err = c.Bind(registry, g, 4)
+			c.Handle(out.id, out.handle)
+			outputs = append(outputs, out)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manager == 0 {
+		return nil, fmt.Errorf("%w: compositor does not offer zwlr_screencopy_manager_v1", errBackendUnavailable)
+	}
+	if shm == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("%w: compositor offers no shm or outputs", errBackendUnavailable)
+	}
+	// Let the outputs report their geometry and names.
+	if err := c.Roundtrip(); err != nil {
+		return nil, err
+	}
+
+	frames := make([]*image.RGBA, len(outputs))
+	bounds := image.Rectangle{}
+	for idx, out := range outputs {
+		img, err := copyOutput(c, manager, managerVersion, shm, out, opts.IncludeCursor)
+		if err != nil {
+			return nil, fmt.Errorf("screencopy output %s: %w", out.label(), err)
+		}
+		frames[idx] = img
+		bounds = bounds.Union(img.Bounds().Add(out.position))
+	}
+	if len(frames) == 1 {
+		return frames[0], nil
+	}
+	// Place each output at its layout position, shifted so the top-left
+	// output lands at the origin.
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for idx := range outputs {
+		offset := outputs[idx].position.Sub(bounds.Min)
+		src := frames[idx]
+		for y := 0; y < src.Rect.Dy(); y++ {
+			row := src.Pix[y*src.Stride : y*src.Stride+src.Rect.Dx()*4]
+			copy(dst.Pix[dst.PixOffset(offset.X, offset.Y+y):], row)
+		}
+	}
+	return dst, nil
+}
+
+func (o *screencopyOutput) label() string {
+	if o.name != "" {
+		return o.name
+	}
+	return fmt.Sprintf("#%d", o.id)
+}
+
+func (o *screencopyOutput) handle(opcode uint16, ev *wayland.Event) {
+	switch opcode {
+	case 0: // geometry: x, y, physical size, subpixel, make, model, transform
+		o.position = image.Pt(int(ev.Int()), int(ev.Int()))
+	case 4: // name
+		o.name = ev.String()
+	}
+}
+
+// copyOutput captures one output into a shm buffer and converts it to RGBA.
+func copyOutput(c *wayland.Conn, manager, managerVersion, shm uint32, out *screencopyOutput, cursor bool) (*image.RGBA, error) {
+	frame := c.NewID()
+	var (
+		buf       screencopyBuffer
+		haveBuf   bool
+		described bool
+		flags     uint32
+		ready     bool
+		failed    bool
+	)
+	c.Handle(frame, func(opcode uint16, ev *wayland.Event) {
+		switch opcode {
+		case 0: // buffer
+			b := screencopyBuffer{format: ev.Uint(), width: ev.Uint(), height: ev.Uint(), stride: ev.Uint()}
+			// Prefer a format we can convert when several are offered.
+			if !haveBuf || shmFormatSupported(b.format) && !shmFormatSupported(buf.format) {
+				buf, haveBuf = b, true
+			}
+			if managerVersion < 3 {
+				described = true
+			}
+		case 1: // flags
+			flags = ev.Uint()
+		case 2: // ready
+			ready = true
+		case 3: // failed
+			failed = true
+		case 6: // buffer_done
+			described = true
+		}
+	})
+	defer c.Forget(frame)
+
+	overlay := int32(0)
+	if cursor {
+		overlay = 1
+	}
+	req := (&wayland.Request{}).Uint(frame).Int(overlay).Uint(out.id)
+	if err := c.Send(manager, 0, req); err != nil { // capture_output
+		return nil, err
+	}
+	if err := c.DispatchUntil(func() bool { return described || failed }); err != nil {
+		return nil, err
+	}
+	if failed || !haveBuf {
+		return nil, fmt.Errorf("compositor refused the capture")
+	}
+	if !shmFormatSupported(buf.format) {
+		return nil, fmt.Errorf("unsupported shm format 0x%08x", buf.format)
+	}
+	if buf.width == 0 || buf.height == 0 || buf.stride < buf.width*4 {
+		return nil, fmt.Errorf("invalid buffer %dx%d stride %d", buf.width, buf.height, buf.stride)
+	}
+
+	size := int(buf.stride) * int(buf.height)
+	f, err := shmFile(size)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pool, buffer := c.NewID(), c.NewID()
+	if err := c.Send(shm, 0, (&wayland.Request{}).Uint(pool).FD(int(f.Fd())).Int(int32(size))); err != nil { // create_pool
+		return nil, err
+	}
+	req = (&wayland.Request{}).Uint(buffer).Int(0).Int(int32(buf.width)).Int(int32(buf.height)).Int(int32(buf.stride)).Uint(buf.format)
+	if err := c.Send(pool, 0, req); err != nil { // create_buffer
+		return nil, err
+	}
+	if err := c.Send(frame, 0, (&wayland.Request{}).Uint(buffer)); err != nil { // copy
+		return nil, err
+	}
+	if err := c.DispatchUntil(func() bool { return ready || failed }); err != nil {
+		return nil, err
+	}
+	// Destroy the frame, buffer and pool; the connection closes soon anyway.
+	_ = c.Send(frame, 1, nil)
+	_ = c.Send(buffer, 0, nil)
+	_ = c.Send(pool, 1, nil)
+	if failed {
+		return nil, fmt.Errorf("copy failed")
+	}
+
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, 0); err != nil {
+		return nil, fmt.Errorf("read shm buffer: %w", err)
+	}
+	return shmToRGBA(data, buf, flags&screencopyFlagYInvert != 0), nil
+}
+
+// shmFile creates an unlinked file to back a wl_shm pool.
+func shmFile(size int) (*os.File, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "shineyshot-shm-*")
+	if err != nil {
+		return nil, fmt.Errorf("create shm file: %w", err)
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unlink shm file: %w", err)
+	}
+	if err := f.Truncate(int64(size)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("size shm file: %w", err)
+	}
+	return f, nil
+}
+
+func shmFormatSupported(format uint32) bool {
+	switch format {
+	case shmFormatARGB8888, shmFormatXRGB8888, shmFormatABGR8888, shmFormatXBGR8888:
+		return true
+	}
+	return false
+}
+
+// shmToRGBA converts a little-endian 32-bit shm buffer to opaque RGBA.
+func shmToRGBA(data []byte, buf screencopyBuffer, yInvert bool) *image.RGBA {
+	width, height := int(buf.width), int(buf.height)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	swap := buf.format == shmFormatARGB8888 || buf.format == shmFormatXRGB8888
+	for y := 0; y < height; y++ {
+		srcY := y
+		if yInvert {
+			srcY = height - 1 - y
+		}
+		src := data[srcY*int(buf.stride):]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			p := src[x*4 : x*4+4]
+			if swap {
+				dst[x*4], dst[x*4+1], dst[x*4+2] = p[2], p[1], p[0]
+			} else {
+				dst[x*4], dst[x*4+1], dst[x*4+2] = p[0], p[1], p[2]
+			}
+			dst[x*4+3] = 0xff
+		}
+	}
+	return img
+}
@@ -0,0 +1,217 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/example/shineyshot/internal/wayland"
+)
+
+// fakeCompositor speaks just enough Wayland to serve screencopy frames for two
+// outputs laid out side by side: a red 4x2 output at 0,0 and a blue 2x2
+// output at 4,0.
+type fakeCompositor struct {
+	t       *testing.T
+	conn    *net.UnixConn
+	buf     []byte
+	fds     []int
+	ifaces  map[uint32]string
+	outputs map[uint32]uint32 // wl_output or frame object -> output global
+	pools   map[uint32]*os.File
+	bufs    map[uint32]*os.File
+}
+
+func startFakeCompositor(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wayland-test")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	t.Setenv("WAYLAND_DISPLAY", path)
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("XDG_SESSION_TYPE", "wayland")
+	go func() {
+		conn, err := ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fc := &fakeCompositor{
+			t: t, conn: conn,
+			ifaces:  map[uint32]string{wayland.DisplayID: "wl_display"},
+			outputs: map[uint32]uint32{},
+			pools:   map[uint32]*os.File{},
+			bufs:    map[uint32]*os.File{},
+		}
+		fc.serve()
+	}()
+}
+
+type fakeOutput struct {
+	pos   image.Point
+	size  image.Point
+	pixel [4]byte // B, G, R, X as stored for XRGB8888
+	name  string
+}
+
+var fakeCompositorOutputs = map[uint32]fakeOutput{
+	2: {pos: image.Pt(0, 0), size: image.Pt(4, 2), pixel: [4]byte{0, 0, 0xff, 0}, name: "DP-1"},
+	3: {pos: image.Pt(4, 0), size: image.Pt(2, 2), pixel: [4]byte{0xff, 0, 0, 0}, name: "DP-2"},
+}
+
+func (fc *fakeCompositor) event(object uint32, opcode uint16, args *wayland.Request) {
+	if args == nil {
+		args = &wayland.Request{}
+	}
+	msg := make([]byte, 8)
+	binary.NativeEndian.PutUint32(msg, object)
+	binary.NativeEndian.PutUint32(msg[4:], uint32(8+len(args.Bytes()))<<16|uint32(opcode))
+	if _, err := fc.conn.Write(append(msg, args.Bytes()...)); err != nil {
+		fc.t.Logf("fake compositor write: %v", err)
+	}
+}
+
+// next returns the next complete request, reading more data as needed.
+func (fc *fakeCompositor) next() (uint32, uint16, *wayland.Event, bool) {
+	for {
+		if len(fc.buf) >= 8 {
+			size := int(binary.NativeEndian.Uint32(fc.buf[4:]) >> 16)
+			if len(fc.buf) >= size {
+				object := binary.NativeEndian.Uint32(fc.buf)
+				opcode := uint16(binary.NativeEndian.Uint32(fc.buf[4:]))
+				ev := wayland.NewEvent(append([]byte(nil), fc.buf[8:size]...))
+				fc.buf = fc.buf[size:]
+				return object, opcode, ev, true
+			}
+		}
+		data := make([]byte, 4096)
+		oob := make([]byte, syscall.CmsgSpace(4*4))
+		n, oobn, _, _, err := fc.conn.ReadMsgUnix(data, oob)
+		if err != nil {
+			return 0, 0, nil, false
+		}
+		fc.buf = append(fc.buf, data[:n]...)
+		if msgs, err := syscall.ParseSocketControlMessage(oob[:oobn]); err == nil {
+			for _, m := range msgs {
+				if fds, err := syscall.ParseUnixRights(&m); err == nil {
+					fc.fds = append(fc.fds, fds...)
+				}
+			}
+		}
+	}
+}
+
+func (fc *fakeCompositor) serve() {
+	for {
+		object, opcode, req, ok := fc.next()
+		if !ok {
+			return
+		}
+		switch fc.ifaces[object] {
+		case "wl_display":
+			id := req.Uint()
+			if opcode == 0 { // sync
+				fc.event(id, 0, (&wayland.Request{}).Uint(0))
+				continue
+			}
+			fc.ifaces[id] = "wl_registry"
+			fc.event(id, 0, (&wayland.Request{}).Uint(1).String("wl_shm").Uint(1))
+			fc.event(id, 0, (&wayland.Request{}).Uint(2).String("wl_output").Uint(4))
+			fc.event(id, 0, (&wayland.Request{}).Uint(3).String("wl_output").Uint(4))
+			fc.event(id, 0, (&wayland.Request{}).Uint(4).String("zwlr_screencopy_manager_v1").Uint(3))
+		case "wl_registry":
+			name, iface, _, id := req.Uint(), req.String(), req.Uint(), req.Uint()
+			fc.ifaces[id] = iface
+			if iface == "wl_output" {
+				out := fakeCompositorOutputs[name]
+				fc.outputs[id] = name
+				fc.event(id, 0, (&wayland.Request{}).Int(int32(out.pos.X)).Int(int32(out.pos.Y)).Int(0).Int(0).Int(0).String("make").String("model").Int(0))
+				fc.event(id, 4, (&wayland.Request{}).String(out.name))
+				fc.event(id, 2, nil)
+			}
+		case "zwlr_screencopy_manager_v1":
+			frame, _, output := req.Uint(), req.Int(), req.Uint()
+			fc.ifaces[frame] = "frame"
+			fc.outputs[frame] = fc.outputs[output]
+			out := fakeCompositorOutputs[fc.outputs[frame]]
+			fc.event(frame, 0, (&wayland.Request{}).Uint(shmFormatXRGB8888).Uint(uint32(out.size.X)).Uint(uint32(out.size.Y)).Uint(uint32(out.size.X*4)))
+			fc.event(frame, 6, nil)
+		case "wl_shm":
+			pool := req.Uint()
+			fc.ifaces[pool] = "wl_shm_pool"
+			fc.pools[pool] = os.NewFile(uintptr(fc.fds[0]), "pool")
+			fc.fds = fc.fds[1:]
+		case "wl_shm_pool":
+			if opcode == 0 {
+				buffer := req.Uint()
+				fc.ifaces[buffer] = "wl_buffer"
+				fc.bufs[buffer] = fc.pools[object]
+			}
+		case "frame":
+			if opcode != 0 {
+				continue
+			}
+			out := fakeCompositorOutputs[fc.outputs[object]]
+			f := fc.bufs[req.Uint()]
+			data := make([]byte, out.size.X*out.size.Y*4)
+			for idx := 0; idx < len(data); idx += 4 {
+				copy(data[idx:], out.pixel[:])
+			}
+			if _, err := f.WriteAt(data, 0); err != nil {
+				fc.t.Logf("fake compositor write buffer: %v", err)
+			}
+			fc.event(object, 2, (&wayland.Request{}).Uint(0).Uint(0).Uint(0))
+		}
+	}
+}
+
+func TestWlrScreencopyArrangesOutputs(t *testing.T) {
+	startFakeCompositor(t)
+	img, err := wlrScreencopy(CaptureOptions{})
+	if err != nil {
+		t.Fatalf("screencopy: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 6, 2) {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	if got := img.RGBAAt(0, 0); got != red {
+		t.Fatalf("pixel 0,0 = %v, want %v", got, red)
+	}
+	if got := img.RGBAAt(5, 1); got != blue {
+		t.Fatalf("pixel 5,1 = %v, want %v", got, blue)
+	}
+}
+
+func TestWlrScreencopyUnavailableWithoutWayland(t *testing.T) {
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if _, err := wlrScreencopy(CaptureOptions{}); err != errBackendUnavailable {
+		t.Fatalf("expected errBackendUnavailable, got %v", err)
+	}
+}
+
+func TestShmToRGBAHandlesFormatsAndInversion(t *testing.T) {
+	// Two XBGR rows, red then green, with stride padding; inverted on read.
+	buf := screencopyBuffer{format: shmFormatXBGR8888, width: 1, height: 2, stride: 8}
+	data := []byte{0xff, 0, 0, 0, 9, 9, 9, 9, 0, 0xff, 0, 0, 9, 9, 9, 9}
+	img := shmToRGBA(data, buf, true)
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{G: 0xff, A: 0xff}) {
+		t.Fatalf("inverted top row = %v, want green", got)
+	}
+	if got := img.RGBAAt(0, 1); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("inverted bottom row = %v, want red", got)
+	}
+}
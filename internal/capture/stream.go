@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"image"
+	"time"
+)
+
+// Frame is one image read from a Stream.
+type Frame struct {
+	Image *image.RGBA
+	// Time is when the frame was read.
+	Time time.Time
+	// Err is set on the last frame when the stream failed; Image is nil then.
+	Err error
+}
+
+// StreamTarget selects what Stream reads.
+type StreamTarget struct {
+	// Window is a window selector as accepted by CaptureWindow. It takes
+	// precedence over Display.
+	Window string
+	// Display is a monitor selector as accepted by CaptureScreenshot. Both
+	// empty streams the whole desktop.
+	Display string
+	// Region limits the stream to this area, in desktop pixels, and takes
+	// precedence over Display. Wayland streams one monitor chosen in the
+	// portal's picker, and the area is taken relative to that monitor.
+	Region image.Rectangle
+	// IncludeCursor draws the pointer into each frame when supported.
+	IncludeCursor bool
+}
+
+// frameSource reads successive frames of a stream target.
+type frameSource interface {
+	Next() (*image.RGBA, error)
+	Close() error
+}
+
+var openStreamFn = openStream
+
+// Stream reads target fps times a second until ctx is cancelled, sending
+// each frame on the returned channel. X11 sessions read the screen directly;
+// Wayland sessions ask the ScreenCast portal for a PipeWire stream, which
+// shows the desktop's source picker once. A failure after the stream starts
+// is sent as a final Frame with Err set. The channel is closed when the
+// stream ends.
+func Stream(ctx context.Context, target StreamTarget, fps int) (<-chan Frame, error) {
+	if fps <= 0 {
+		return nil, errors.New("stream needs a positive frame rate")
+	}
+	src, err := openStreamFn(ctx, target, fps)
+	if err != nil {
+		return nil, err
+	}
+	frames := make(chan Frame, 1)
+	go streamFrames(ctx, src, time.Second/time.Duration(fps), frames)
+	return frames, nil
+}
+
+// streamFrames reads src once per interval, skipping ticks while the
+// receiver is busy so frames never queue up behind a slow consumer.
+func streamFrames(ctx context.Context, src frameSource, interval time.Duration, frames chan<- Frame) {
+	defer close(frames)
+	defer src.Close()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		img, err := src.Next()
+		frame := Frame{Image: img, Time: time.Now(), Err: err}
+		select {
+		case frames <- frame:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package capture
+
+import (
+	"context"
+	"fmt"
+)
+
+func openStream(context.Context, StreamTarget, int) (frameSource, error) {
+	return nil, fmt.Errorf("streaming is not supported on this platform")
+}
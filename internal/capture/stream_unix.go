@@ -0,0 +1,199 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+func openStream(ctx context.Context, target StreamTarget, fps int) (frameSource, error) {
+	if runningOnWayland() {
+		return openPipewireStream(ctx, target, fps)
+	}
+	return openX11Stream(target)
+}
+
+// x11Stream reads frames over one X connection. Windows are read from their
+// composited pixmap when possible so covered windows still stream whole.
+type x11Stream struct {
+	conn        *xgb.Conn
+	reader      *xImageReader
+	setup       *xproto.SetupInfo
+	root        xproto.Window
+	win         xproto.Window
+	rect        image.Rectangle
+	cursor      bool
+	noComposite bool
+}
+
+func openX11Stream(target StreamTarget) (frameSource, error) {
+	s := &x11Stream{cursor: target.IncludeCursor}
+	switch {
+	case target.Window != "":
+		windows, err := ListWindows()
+		if err != nil {
+			return nil, fmt.Errorf("stream window %q: %w", target.Window, err)
+		}
+		info, err := SelectWindow(target.Window, windows)
+		if err != nil {
+			return nil, fmt.Errorf("stream window %q: %w", target.Window, err)
+		}
+		s.win = xproto.Window(info.ID)
+	case !target.Region.Empty():
+		s.rect = target.Region
+	case target.Display != "":
+		monitors, err := ListMonitors()
+		if err != nil {
+			return nil, fmt.Errorf("stream display %q: %w", target.Display, err)
+		}
+		mon, err := FindMonitor(monitors, target.Display)
+		if err != nil {
+			return nil, fmt.Errorf("stream display %q: %w", target.Display, err)
+		}
+		s.rect = mon.Rect
+	}
+
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("connect X server: %w", err)
+	}
+	s.conn = conn
+	s.reader = newXImageReader(conn)
+	s.setup = xproto.Setup(conn)
+	if s.setup == nil {
+		conn.Close()
+		return nil, fmt.Errorf("xproto setup unavailable")
+	}
+	screen := s.setup.DefaultScreen(conn)
+	if screen == nil {
+		conn.Close()
+		return nil, fmt.Errorf("xproto screen unavailable")
+	}
+	s.root = screen.Root
+	if s.win == 0 && s.rect.Empty() {
+		s.rect = image.Rect(0, 0, int(screen.WidthInPixels), int(screen.HeightInPixels))
+	}
+	return s, nil
+}
+
+func (s *x11Stream) Next() (*image.RGBA, error) {
+	rect := s.rect
+	if s.win != 0 {
+		// Follow the window as it moves or resizes.
+		var err error
+		if rect, err = windowRect(s.conn, s.root, s.win); err != nil {
+			return nil, fmt.Errorf("window geometry: %w", err)
+		}
+		if !s.noComposite {
+			img, err := compositeWindowImage(s.reader, s.setup, s.win)
+			if err == nil {
+				if s.cursor {
+					drawCursor(img, rect.Min)
+				}
+				return img, nil
+			}
+			// Without Composite, read the window's area of the screen.
+			s.noComposite = true
+		}
+	}
+	if rect.Empty() {
+		return nil, fmt.Errorf("stream area is empty")
+	}
+	reply, err := s.reader.getImage(xproto.Drawable(s.root), rect)
+	if err != nil {
+		return nil, fmt.Errorf("screen pixels: %w", err)
+	}
+	img, err := xImageToRGBA(s.setup, reply, rect.Dx(), rect.Dy(), "screen")
+	if err != nil {
+		return nil, err
+	}
+	if s.cursor {
+		drawCursor(img, rect.Min)
+	}
+	return img, nil
+}
+
+func (s *x11Stream) Close() error {
+	s.reader.Close()
+	s.conn.Close()
+	return nil
+}
+
+// pipewireStream decodes the PNG frames a GStreamer pipeline writes for a
+// ScreenCast portal stream; videorate holds the pipeline to the requested
+// frame rate.
+type pipewireStream struct {
+	sc  *screencast
+	cmd *exec.Cmd
+	out *bufio.Reader
+	// region crops each frame when set.
+	region image.Rectangle
+}
+
+func openPipewireStream(ctx context.Context, target StreamTarget, fps int) (frameSource, error) {
+	types := uint32(screencastSourceMonitor)
+	if target.Window != "" {
+		types = screencastSourceWindow
+	}
+	sc, err := openScreencast(ctx, types, target.IncludeCursor, screencastPersist{})
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(gstLaunch, "-q",
+		"pipewiresrc", "fd=3", "path="+strconv.FormatUint(uint64(sc.node), 10), "do-timestamp=true",
+		"!", "videorate", "!", "video/x-raw,framerate="+strconv.Itoa(fps)+"/1",
+		"!", "videoconvert", "!", "pngenc", "!", "fdsink", "fd=1")
+	cmd.ExtraFiles = []*os.File{sc.remote}
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		sc.Close()
+		return nil, fmt.Errorf("pipewire stream: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		sc.Close()
+		return nil, fmt.Errorf("pipewire stream: %w", err)
+	}
+	return &pipewireStream{sc: sc, cmd: cmd, out: bufio.NewReader(stdout), region: target.Region}, nil
+}
+
+func (s *pipewireStream) Next() (*image.RGBA, error) {
+	// The PNG decoder stops at IEND, so the frames can be read back to back.
+	img, err := png.Decode(s.out)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("pipewire stream ended")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode pipewire frame: %w", err)
+	}
+	area := img.Bounds()
+	if !s.region.Empty() {
+		area = s.region.Add(area.Min).Intersect(area)
+		if area.Empty() {
+			return nil, fmt.Errorf("stream region %v is outside the shared monitor", s.region)
+		}
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, area.Min, draw.Src)
+	return rgba, nil
+}
+
+func (s *pipewireStream) Close() error {
+	if s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+	s.cmd.Wait()
+	return s.sc.Close()
+}
@@ -0,0 +1,399 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// The sway and i3 IPC protocol frames every message as the magic string,
+// a native-endian payload length and message type, then a JSON payload.
+const (
+	ipcMagic       = "i3-ipc"
+	ipcGetOutputs  = 3
+	ipcGetTree     = 4
+	ipcTimeout     = 2 * time.Second
+	ipcHeaderBytes = len(ipcMagic) + 8
+)
+
+// ipcBackend answers window and monitor queries through the sway/i3 IPC socket
+// when one is advertised, and defers to the next backend otherwise or when
+// the compositor cannot be reached.
+type ipcBackend struct {
+	fallback platformBackend
+}
+
+// ipcSocketPath returns the compositor IPC socket from the environment.
+func ipcSocketPath() string {
+	if path := os.Getenv("SWAYSOCK"); path != "" {
+		return path
+	}
+	return os.Getenv("I3SOCK")
+}
+
+func (b ipcBackend) ListMonitors() ([]MonitorInfo, error) {
+	path := ipcSocketPath()
+	if path == "" {
+		return b.fallback.ListMonitors()
+	}
+	monitors, err := ipcMonitors(path)
+	if err == nil && len(monitors) > 0 {
+		return monitors, nil
+	}
+	if err == nil {
+		err = errNoMonitors
+	}
+	fallback, fallbackErr := b.fallback.ListMonitors()
+	if fallbackErr != nil {
+		return nil, errors.Join(fmt.Errorf("compositor ipc: %w", err), fallbackErr)
+	}
+	return fallback, nil
+}
+
+func (b ipcBackend) ListWindows() ([]WindowInfo, error) {
+	path := ipcSocketPath()
+	if path == "" {
+		return b.fallback.ListWindows()
+	}
+	windows, err := ipcWindows(path)
+	if err == nil && len(windows) > 0 {
+		return windows, nil
+	}
+	if err == nil {
+		err = errNoWindows
+	}
+	fallback, fallbackErr := b.fallback.ListWindows()
+	if fallbackErr != nil {
+		return nil, errors.Join(fmt.Errorf("compositor ipc: %w", err), fallbackErr)
+	}
+	return fallback, nil
+}
+
+// CaptureWindowImage grabs X11 and XWayland windows directly. Native Wayland
+// clients have no drawable to read, so an error is returned and callers crop
+// a screenshot instead.
+func (b ipcBackend) CaptureWindowImage(id uint32) (*image.RGBA, error) {
+	if path := ipcSocketPath(); path != "" {
+		if tree, err := ipcTree(path); err == nil {
+			if node := tree.find(id); node != nil && node.Window == nil {
+				return nil, fmt.Errorf("window %d is a Wayland client without an X11 drawable", id)
+			}
+		}
+	}
+	return b.fallback.CaptureWindowImage(id)
+}
+
+// WindowFrame returns the container rectangle, which includes the title bar
+// and borders the compositor draws, for windows in the layout tree.
+func (b ipcBackend) WindowFrame(id uint32) (uint32, image.Rectangle, error) {
+	if path := ipcSocketPath(); path != "" {
+		if tree, err := ipcTree(path); err == nil {
+			if node := tree.find(id); node != nil {
+				return 0, node.Rect.rectangle(), nil
+			}
+		}
+	}
+	return b.fallback.WindowFrame(id)
+}
+
+// CursorPosition defers to the next backend outside sway. Sway has no
+// request for the pointer position, and XWayland only sees it over X11
+// windows, so sway sessions report none and callers use the focused output
+// instead.
+func (b ipcBackend) CursorPosition() (image.Point, error) {
+	if ipcSocketPath() != "" && runningOnWayland() {
+		return image.Point{}, fmt.Errorf("%w: the compositor does not report the pointer position", errBackendUnavailable)
+	}
+	return b.fallback.CursorPosition()
+}
+
+type ipcRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (r ipcRect) rectangle() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+}
+
+type ipcOutput struct {
+	Name      string  `json:"name"`
+	Active    bool    `json:"active"`
+	Primary   bool    `json:"primary"`
+	Rect      ipcRect `json:"rect"`
+	Scale     float64 `json:"scale"`
+	Transform string  `json:"transform"`
+	Focused   bool    `json:"focused"`
+	Make      string  `json:"make"`
+	Model     string  `json:"model"`
+}
+
+type ipcNode struct {
+	ID               int64     `json:"id"`
+	Type             string    `json:"type"`
+	Name             string    `json:"name"`
+	Rect             ipcRect   `json:"rect"`
+	WindowRect       ipcRect   `json:"window_rect"`
+	Focused          bool      `json:"focused"`
+	PID              uint32    `json:"pid"`
+	AppID            *string   `json:"app_id"`
+	Window           *uint32   `json:"window"`
+	WindowProperties *ipcProps `json:"window_properties"`
+	Num              *int      `json:"num"`
+	FullscreenMode   int       `json:"fullscreen_mode"`
+	WindowType       string    `json:"window_type"`
+	Nodes            []ipcNode `json:"nodes"`
+	FloatingNodes    []ipcNode `json:"floating_nodes"`
+
+	// workspace is the number of the enclosing workspace, set by
+	// markWorkspaces.
+	workspace int
+	// scratchpad marks nodes in the hidden scratchpad workspace.
+	scratchpad bool
+}
+
+// scratchpadWorkspace is the workspace sway and i3 keep scratchpad windows
+// in.
+const scratchpadWorkspace = "__i3_scratch"
+
+type ipcProps struct {
+	Class    string `json:"class"`
+	Instance string `json:"instance"`
+}
+
+// windowID is the identifier shineyshot uses for the node: the X11 window for
+// X clients so direct capture keeps working, otherwise the container id.
+func (n *ipcNode) windowID() uint32 {
+	if n.Window != nil && *n.Window != 0 {
+		return *n.Window
+	}
+	return uint32(n.ID)
+}
+
+// isClient reports whether the node holds an application window rather than
+// a split, workspace or output container.
+func (n *ipcNode) isClient() bool {
+	if n.Type != "con" && n.Type != "floating_con" {
+		return false
+	}
+	if len(n.Nodes) > 0 || len(n.FloatingNodes) > 0 {
+		return false
+	}
+	return (n.Window != nil && *n.Window != 0) || n.AppID != nil || n.PID != 0
+}
+
+// contentRect returns the client area in global coordinates, excluding the
+// title bar and borders the compositor draws around it.
+func (n *ipcNode) contentRect() image.Rectangle {
+	if n.WindowRect.Width == 0 || n.WindowRect.Height == 0 {
+		return n.Rect.rectangle()
+	}
+	x := n.Rect.X + n.WindowRect.X
+	y := n.Rect.Y + n.WindowRect.Y
+	return image.Rect(x, y, x+n.WindowRect.Width, y+n.WindowRect.Height)
+}
+
+func (n *ipcNode) find(id uint32) *ipcNode {
+	if n.isClient() && n.windowID() == id {
+		return n
+	}
+	for _, group := range [][]ipcNode{n.Nodes, n.FloatingNodes} {
+		for idx := range group {
+			if found := group[idx].find(id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// markWorkspaces records the enclosing workspace number on every node; -1
+// outside workspaces and for workspaces without a number.
+func (n *ipcNode) markWorkspaces(num int, scratchpad bool) {
+	if n.Type == "workspace" {
+		num = -1
+		if n.Num != nil && *n.Num >= 0 {
+			num = *n.Num
+		}
+		scratchpad = n.Name == scratchpadWorkspace
+	}
+	n.workspace = num
+	n.scratchpad = scratchpad
+	for idx := range n.Nodes {
+		n.Nodes[idx].markWorkspaces(num, scratchpad)
+	}
+	for idx := range n.FloatingNodes {
+		n.FloatingNodes[idx].markWorkspaces(num, scratchpad)
+	}
+}
+
+// clients walks the tree in layout order, skipping the i3 scratchpad.
+func (n *ipcNode) clients(out []*ipcNode) []*ipcNode {
+	if n.isClient() {
+		return append(out, n)
+	}
+	for idx := range n.Nodes {
+		out = n.Nodes[idx].clients(out)
+	}
+	for idx := range n.FloatingNodes {
+		out = n.FloatingNodes[idx].clients(out)
+	}
+	return out
+}
+
+func ipcMonitors(path string) ([]MonitorInfo, error) {
+	var outputs []ipcOutput
+	if err := ipcQuery(path, ipcGetOutputs, &outputs); err != nil {
+		return nil, err
+	}
+	return monitorsFromOutputs(outputs), nil
+}
+
+func monitorsFromOutputs(outputs []ipcOutput) []MonitorInfo {
+	monitors := make([]MonitorInfo, 0, len(outputs))
+	for _, out := range outputs {
+		if !out.Active || out.Rect.Width == 0 || out.Rect.Height == 0 {
+			continue
+		}
+		rotation, flipped := outputTransform(out.Transform)
+		monitors = append(monitors, MonitorInfo{
+			Index:    len(monitors),
+			Name:     out.Name,
+			Model:    out.model(),
+			Rect:     out.Rect.rectangle(),
+			Primary:  out.Primary,
+			Scale:    out.Scale,
+			Rotation: rotation,
+			Flipped:  flipped,
+			Focused:  out.Focused,
+		})
+	}
+	return monitors
+}
+
+// model joins the make and model sway reads from the EDID; virtual outputs
+// report "Unknown" for both.
+func (o ipcOutput) model() string {
+	if strings.EqualFold(o.Model, "Unknown") {
+		return ""
+	}
+	return joinMakeModel(o.Make, o.Model)
+}
+
+// outputTransform decodes sway's transform names: normal, 90, 180, 270 and
+// their flipped variants such as flipped-90. i3 reports none.
+func outputTransform(name string) (int, bool) {
+	flipped := strings.HasPrefix(name, "flipped")
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "flipped"), "-")
+	switch name {
+	case "90":
+		return 90, flipped
+	case "180":
+		return 180, flipped
+	case "270":
+		return 270, flipped
+	}
+	return 0, flipped
+}
+
+func ipcTree(path string) (*ipcNode, error) {
+	var tree ipcNode
+	if err := ipcQuery(path, ipcGetTree, &tree); err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
+func ipcWindows(path string) ([]WindowInfo, error) {
+	tree, err := ipcTree(path)
+	if err != nil {
+		return nil, err
+	}
+	monitors, _ := ipcMonitors(path)
+	return windowsFromTree(tree, monitors), nil
+}
+
+func windowsFromTree(tree *ipcNode, monitors []MonitorInfo) []WindowInfo {
+	tree.markWorkspaces(-1, false)
+	nodes := tree.clients(nil)
+	windows := make([]WindowInfo, 0, len(nodes))
+	for _, node := range nodes {
+		info := WindowInfo{
+			Index:      len(windows),
+			ID:         node.windowID(),
+			Title:      node.Name,
+			PID:        node.PID,
+			Executable: readExecutable(node.PID),
+			Rect:       node.contentRect(),
+			Active:     node.Focused,
+			Desktop:    node.workspace,
+		}
+		if node.WindowProperties != nil {
+			info.Class = node.WindowProperties.Class
+			info.Instance = node.WindowProperties.Instance
+		}
+		if node.AppID != nil && *node.AppID != "" {
+			info.Class = *node.AppID
+			if info.Instance == "" {
+				info.Instance = *node.AppID
+			}
+		}
+		if node.scratchpad {
+			info.State |= WindowHidden
+		}
+		if node.FullscreenMode != 0 {
+			info.State |= WindowFullscreen
+		}
+		info.Type = windowTypeName(node.WindowType)
+		info.Monitor = monitorForRect(info.Rect, monitors)
+		windows = append(windows, info)
+	}
+	return windows
+}
+
+// ipcQuery sends a payload-less request and decodes the JSON reply into v.
+func ipcQuery(path string, msgType uint32, v any) error {
+	conn, err := net.DialTimeout("unix", path, ipcTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(ipcTimeout)); err != nil {
+		return err
+	}
+
+	header := make([]byte, ipcHeaderBytes)
+	copy(header, ipcMagic)
+	binary.NativeEndian.PutUint32(header[len(ipcMagic):], 0)
+	binary.NativeEndian.PutUint32(header[len(ipcMagic)+4:], msgType)
+	if _, err := conn.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("read ipc reply: %w", err)
+	}
+	if string(header[:len(ipcMagic)]) != ipcMagic {
+		return fmt.Errorf("unexpected ipc reply header")
+	}
+	size := binary.NativeEndian.Uint32(header[len(ipcMagic):])
+	if got := binary.NativeEndian.Uint32(header[len(ipcMagic)+4:]); got != msgType {
+		return fmt.Errorf("ipc reply type %d, want %d", got, msgType)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return fmt.Errorf("read ipc reply: %w", err)
+	}
+	return json.Unmarshal(payload, v)
+}
@@ -0,0 +1,157 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSwayOutputs = `[
+ {"name":"eDP-1","active":true,"rect":{"x":0,"y":0,"width":1920,"height":1080}},
+ {"name":"HDMI-A-1","make":"Dell Inc.","model":"DELL U2720Q","active":true,"focused":true,"scale":2,"transform":"flipped-90","rect":{"x":1920,"y":0,"width":2560,"height":1440}},
+ {"name":"DP-2","active":false,"rect":{"x":0,"y":0,"width":0,"height":0}}
+]`
+
+const testSwayTree = `{"id":1,"type":"root","nodes":[
+ {"id":2,"type":"output","name":"eDP-1","nodes":[
+  {"id":3,"type":"workspace","name":"1","num":1,"nodes":[
+   {"id":10,"type":"con","name":"Terminal","focused":true,"pid":0,"app_id":"foot","fullscreen_mode":1,
+    "rect":{"x":0,"y":0,"width":960,"height":1080},
+    "window_rect":{"x":2,"y":2,"width":956,"height":1076},"nodes":[]}
+  ],"floating_nodes":[
+   {"id":11,"type":"floating_con","name":"Firefox","pid":0,"app_id":null,"window":4194307,"window_type":"normal",
+    "window_properties":{"class":"firefox","instance":"Navigator"},
+    "rect":{"x":2000,"y":100,"width":800,"height":600},
+    "window_rect":{"x":0,"y":0,"width":800,"height":600},"nodes":[]}
+  ]}
+ ]},
+ {"id":4,"type":"output","name":"__i3","nodes":[
+  {"id":5,"type":"workspace","name":"__i3_scratch","nodes":[
+   {"id":12,"type":"con","name":"Hidden","app_id":"scratch","nodes":[]}
+  ]}
+ ]}
+]}`
+
+// serveFakeIPC answers GET_OUTPUTS and GET_TREE with canned JSON.
+func serveFakeIPC(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ipc.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				header := make([]byte, ipcHeaderBytes)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				msgType := binary.NativeEndian.Uint32(header[len(ipcMagic)+4:])
+				payload := "[]"
+				switch msgType {
+				case ipcGetOutputs:
+					payload = testSwayOutputs
+				case ipcGetTree:
+					payload = testSwayTree
+				}
+				binary.NativeEndian.PutUint32(header[len(ipcMagic):], uint32(len(payload)))
+				_, _ = conn.Write(append(header, payload...))
+			}(conn)
+		}
+	}()
+	return path
+}
+
+type failingBackend struct{}
+
+func (failingBackend) ListMonitors() ([]MonitorInfo, error) { return nil, errors.New("no x11") }
+func (failingBackend) ListWindows() ([]WindowInfo, error)   { return nil, errors.New("no x11") }
+func (failingBackend) CaptureWindowImage(uint32) (*image.RGBA, error) {
+	return nil, errors.New("no x11")
+}
+func (failingBackend) WindowFrame(uint32) (uint32, image.Rectangle, error) {
+	return 0, image.Rectangle{}, errors.New("no x11")
+}
+func (failingBackend) CursorPosition() (image.Point, error) {
+	return image.Point{}, errors.New("no x11")
+}
+
+func TestIPCBackendListsSwayOutputsAndWindows(t *testing.T) {
+	t.Setenv("SWAYSOCK", serveFakeIPC(t))
+	t.Setenv("I3SOCK", "")
+	b := ipcBackend{fallback: failingBackend{}}
+
+	monitors, err := b.ListMonitors()
+	if err != nil {
+		t.Fatalf("list monitors: %v", err)
+	}
+	if len(monitors) != 2 || monitors[1].Name != "HDMI-A-1" || monitors[1].Rect != image.Rect(1920, 0, 4480, 1440) {
+		t.Fatalf("unexpected monitors: %+v", monitors)
+	}
+	if m := monitors[1]; m.Model != "Dell U2720Q" || m.Scale != 2 || m.Rotation != 90 || !m.Flipped || !m.Focused {
+		t.Fatalf("expected HDMI-A-1 to be a focused Dell U2720Q, scaled 2x and flipped-90, got %+v", m)
+	}
+	if m := monitors[0]; m.ScaleFactor() != 1 || m.Rotation != 0 || m.Flipped {
+		t.Fatalf("expected eDP-1 untransformed, got %+v", m)
+	}
+
+	windows, err := b.ListWindows()
+	if err != nil {
+		t.Fatalf("list windows: %v", err)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows including the scratchpad, got %+v", windows)
+	}
+	term, fox, hidden := windows[0], windows[1], windows[2]
+	if term.ID != 10 || term.Class != "foot" || !term.Active || term.Rect != image.Rect(2, 2, 958, 1078) || term.Monitor != 0 {
+		t.Fatalf("unexpected native window: %+v", term)
+	}
+	if term.State != WindowFullscreen {
+		t.Fatalf("expected the terminal to be fullscreen, got %q", term.State)
+	}
+	if fox.ID != 4194307 || fox.Class != "firefox" || fox.Instance != "Navigator" || fox.Monitor != 1 || fox.Desktop != 1 || fox.State != 0 || fox.Type != "normal" {
+		t.Fatalf("unexpected xwayland window: %+v", fox)
+	}
+	if hidden.ID != 12 || !hidden.Unviewable() {
+		t.Fatalf("expected the scratchpad window to be hidden, got %+v", hidden)
+	}
+
+	if _, err := b.CaptureWindowImage(10); err == nil || !strings.Contains(err.Error(), "Wayland client") {
+		t.Fatalf("expected native window capture to be refused, got %v", err)
+	}
+	if _, err := b.CaptureWindowImage(4194307); err == nil || !strings.Contains(err.Error(), "no x11") {
+		t.Fatalf("expected xwayland window capture to use the fallback, got %v", err)
+	}
+	if id, frame, err := b.WindowFrame(10); err != nil || id != 0 || frame != image.Rect(0, 0, 960, 1080) {
+		t.Fatalf("WindowFrame(10) = %d, %v, %v; want the container rect", id, frame, err)
+	}
+
+	sel, err := SelectWindow("foot", windows)
+	if err != nil || sel.ID != 10 {
+		t.Fatalf("select by app_id: %+v %v", sel, err)
+	}
+}
+
+func TestIPCBackendFallsBackWithoutSocket(t *testing.T) {
+	t.Setenv("SWAYSOCK", "")
+	t.Setenv("I3SOCK", filepath.Join(t.TempDir(), "missing.sock"))
+	b := ipcBackend{fallback: failingBackend{}}
+	_, err := b.ListWindows()
+	if err == nil || !strings.Contains(err.Error(), "compositor ipc") || !strings.Contains(err.Error(), "no x11") {
+		t.Fatalf("expected joined ipc and fallback errors, got %v", err)
+	}
+}
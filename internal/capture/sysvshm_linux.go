@@ -0,0 +1,30 @@
+package capture
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// createSharedMemory creates and maps a private System V shared memory
+// segment the X server can attach to by id.
+func createSharedMemory(size int) (int, []byte, error) {
+	id, err := unix.SysvShmGet(unix.IPC_PRIVATE, size, unix.IPC_CREAT|0o600)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create shared memory: %w", err)
+	}
+	data, err := unix.SysvShmAttach(id, 0, 0)
+	if err != nil {
+		removeSharedMemory(id)
+		return 0, nil, fmt.Errorf("map shared memory: %w", err)
+	}
+	return id, data, nil
+}
+
+func removeSharedMemory(id int) {
+	_, _ = unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+}
+
+func detachSharedMemory(data []byte) {
+	_ = unix.SysvShmDetach(data)
+}
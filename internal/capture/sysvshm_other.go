@@ -0,0 +1,15 @@
+//go:build freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import "errors"
+
+// System V shared memory is not wrapped for these systems, so X reads always
+// go through the socket.
+func createSharedMemory(int) (int, []byte, error) {
+	return 0, nil, errors.New("shared memory unsupported on this platform")
+}
+
+func removeSharedMemory(int) {}
+
+func detachSharedMemory([]byte) {}
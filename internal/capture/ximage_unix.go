@@ -43,6 +43,10 @@ func xImageToRGBA(setup *xproto.SetupInfo, reply *xproto.GetImageReply, width, h
 		return nil, fmt.Errorf("%s pixels: unexpected stride", kind)
 	}
 
+	// Only 32-bit visuals carry alpha, stored premultiplied like image.RGBA.
+	// Depth 24 pixels pad to four bytes and the pad byte is undefined.
+	hasAlpha := reply.Depth == 32 && bytesPerPixel >= 4
+
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
 	for y := 0; y < height; y++ {
 		row := reply.Data[y*stride : (y+1)*stride]
@@ -55,7 +59,7 @@ func xImageToRGBA(setup *xproto.SetupInfo, reply *xproto.GetImageReply, width, h
 			g := row[off+1]
 			r := row[off+2]
 			a := byte(0xFF)
-			if bytesPerPixel >= 4 && off+3 < len(row) {
+			if hasAlpha && off+3 < len(row) {
 				a = row[off+3]
 			}
 			pix := img.PixOffset(x, y)
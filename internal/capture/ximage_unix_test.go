@@ -0,0 +1,35 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+func TestXImageToRGBAKeepsAlphaOnlyForARGBVisuals(t *testing.T) {
+	setup := &xproto.SetupInfo{PixmapFormats: []xproto.Format{
+		{Depth: 24, BitsPerPixel: 32},
+		{Depth: 32, BitsPerPixel: 32},
+	}}
+	// One half-transparent premultiplied red pixel stored B, G, R, A.
+	data := []byte{0, 0, 0x80, 0x80}
+
+	img, err := xImageToRGBA(setup, &xproto.GetImageReply{Depth: 32, Data: data}, 1, 1, "window")
+	if err != nil {
+		t.Fatalf("depth 32: %v", err)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{R: 0x80, A: 0x80}) {
+		t.Fatalf("depth 32 pixel = %v, want translucent red", got)
+	}
+
+	img, err = xImageToRGBA(setup, &xproto.GetImageReply{Depth: 24, Data: data}, 1, 1, "window")
+	if err != nil {
+		t.Fatalf("depth 24: %v", err)
+	}
+	if got := img.RGBAAt(0, 0); got.A != 0xff {
+		t.Fatalf("depth 24 pixel = %v, want opaque", got)
+	}
+}
@@ -0,0 +1,88 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package capture
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/shm"
+	"github.com/jezek/xgb/xproto"
+)
+
+// xImageReader reads ZPixmap images over an X connection. When the server
+// can share memory with this process the pixels arrive through a MIT-SHM
+// segment instead of being copied through the X socket, which matters for
+// full-screen reads on large or multi-head displays. Servers without the
+// extension, or on another host as with ssh -X, get a plain GetImage.
+type xImageReader struct {
+	conn *xgb.Conn
+	seg  shm.Seg
+	data []byte
+	// noShm is set once shared memory failed to set up on conn.
+	noShm bool
+}
+
+func newXImageReader(conn *xgb.Conn) *xImageReader {
+	return &xImageReader{conn: conn}
+}
+
+// getImage reads rect of drawable. The reply's Data may point into the shared
+// segment, so it is only valid until the next getImage or Close.
+func (r *xImageReader) getImage(drawable xproto.Drawable, rect image.Rectangle) (*xproto.GetImageReply, error) {
+	if !r.noShm {
+		if err := r.reserve(rect.Dx() * rect.Dy() * 4); err != nil {
+			r.noShm = true
+		} else if reply, err := shm.GetImage(r.conn, drawable, int16(rect.Min.X), int16(rect.Min.Y),
+			uint16(rect.Dx()), uint16(rect.Dy()), ^uint32(0), xproto.ImageFormatZPixmap, r.seg, 0).Reply(); err == nil && int(reply.Size) <= len(r.data) {
+			return &xproto.GetImageReply{Depth: reply.Depth, Visual: reply.Visual, Data: r.data[:reply.Size]}, nil
+		}
+	}
+	return xproto.GetImage(r.conn, xproto.ImageFormatZPixmap, drawable, int16(rect.Min.X), int16(rect.Min.Y),
+		uint16(rect.Dx()), uint16(rect.Dy()), ^uint32(0)).Reply()
+}
+
+// reserve makes sure the shared segment holds at least size bytes, replacing
+// a smaller one.
+func (r *xImageReader) reserve(size int) error {
+	if len(r.data) >= size {
+		return nil
+	}
+	r.release()
+	if err := shm.Init(r.conn); err != nil {
+		return fmt.Errorf("init shm: %w", err)
+	}
+	id, data, err := createSharedMemory(size)
+	if err != nil {
+		return err
+	}
+	// Once both sides have attached, mark the segment for removal so it
+	// goes away with the last detach even if this process dies.
+	defer removeSharedMemory(id)
+	seg, err := shm.NewSegId(r.conn)
+	if err != nil {
+		detachSharedMemory(data)
+		return fmt.Errorf("allocate shm segment id: %w", err)
+	}
+	if err := shm.AttachChecked(r.conn, seg, uint32(id), false).Check(); err != nil {
+		detachSharedMemory(data)
+		return fmt.Errorf("attach shm segment: %w", err)
+	}
+	r.seg, r.data = seg, data
+	return nil
+}
+
+func (r *xImageReader) release() {
+	if r.data == nil {
+		return
+	}
+	shm.Detach(r.conn, r.seg)
+	detachSharedMemory(r.data)
+	r.data = nil
+}
+
+// Close detaches the shared segment; the X connection is left open.
+func (r *xImageReader) Close() {
+	r.release()
+}
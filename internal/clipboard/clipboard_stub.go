@@ -3,6 +3,7 @@
 package clipboard
 
 import (
+	"context"
 	"fmt"
 	"image"
 )
@@ -11,14 +12,30 @@ func WriteImage(image.Image) error {
 	return fmt.Errorf("clipboard image operations are not supported on this platform")
 }
 
+func WriteContent(Content, ...Selection) error {
+	return fmt.Errorf("clipboard image operations are not supported on this platform")
+}
+
 func ReadImage() (image.Image, error) {
 	return nil, fmt.Errorf("clipboard image operations are not supported on this platform")
 }
 
-func WriteText(string) error {
+func ReadImageFrom(Selection) (image.Image, error) {
+	return nil, fmt.Errorf("clipboard image operations are not supported on this platform")
+}
+
+func WriteText(string, ...Selection) error {
 	return fmt.Errorf("clipboard text operations are not supported on this platform")
 }
 
 func ReadText() (string, error) {
 	return "", fmt.Errorf("clipboard text operations are not supported on this platform")
 }
+
+func ReadTextFrom(Selection) (string, error) {
+	return "", fmt.Errorf("clipboard text operations are not supported on this platform")
+}
+
+func Watch(context.Context, Selection) (<-chan Copied, error) {
+	return nil, fmt.Errorf("clipboard watching is not supported on this platform")
+}
@@ -4,14 +4,21 @@ package clipboard
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"image"
 	"image/png"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xfixes"
 	"github.com/jezek/xgb/xproto"
 )
 
@@ -19,15 +26,43 @@ var (
 	initOnce     sync.Once
 	initErr      error
 	errNoDisplay = errors.New("clipboard initialization requires DISPLAY or WAYLAND_DISPLAY")
-	backend      *x11Clipboard
+	backend      clipboardBackend
 )
 
+// clipboardBackend publishes and reads the clipboard of one display server.
+type clipboardBackend interface {
+	// write takes the selection, offering each target until another
+	// client replaces it.
+	write(sel Selection, targets []target) error
+	readText(sel Selection) ([]byte, error)
+	// readImage reads the first of imageMimeTypes sel offers and reports
+	// which it was.
+	readImage(sel Selection) (string, []byte, error)
+	// watch calls changed each time another client takes sel, until ctx
+	// is done or watching fails.
+	watch(ctx context.Context, sel Selection, changed func()) error
+}
+
+// ensureInit picks the Wayland clipboard when the compositor offers a data
+// device, and otherwise X11, which also reaches XWayland's clipboard.
 func ensureInit() error {
 	initOnce.Do(func() {
-		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		hasX11 := os.Getenv("DISPLAY") != ""
+		if !hasX11 && os.Getenv("WAYLAND_DISPLAY") == "" {
 			initErr = errNoDisplay
 			return
 		}
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			clip, err := newWaylandClipboard()
+			if err == nil {
+				backend = clip
+				return
+			}
+			if !hasX11 {
+				initErr = err
+				return
+			}
+		}
 		clip := &x11Clipboard{}
 		if err := clip.initialize(); err != nil {
 			initErr = err
@@ -38,83 +73,259 @@ func ensureInit() error {
 	return initErr
 }
 
+// target is clipboard data offered under one MIME type.
+type target struct {
+	mime string
+	data []byte
+}
+
 // WriteImage encodes the provided image as PNG and publishes it to the clipboard.
 func WriteImage(img image.Image) error {
+	return WriteContent(Content{Image: img})
+}
+
+// WriteContent publishes the image as PNG together with the other formats
+// Content describes, to the clipboard unless other selections are named.
+func WriteContent(content Content, selections ...Selection) error {
 	if err := ensureInit(); err != nil {
 		return err
 	}
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
+	targets, err := content.targets()
+	if err != nil {
+		return err
+	}
+	if err := writeSelections(targets, selections); err != nil {
 		return err
 	}
-	return backend.writeImage(buf.Bytes())
+	_ = recordHistory(false, ".png", targets[0].data)
+	return nil
+}
+
+func writeSelections(targets []target, selections []Selection) error {
+	if idx := slices.IndexFunc(targets, func(t target) bool { return t.mime == "image/png" }); idx >= 0 {
+		lastCopiedMu.Lock()
+		lastCopied = targets[idx].data
+		lastCopiedMu.Unlock()
+	}
+	for _, sel := range selectionsOrDefault(selections) {
+		if err := backend.write(sel, targets); err != nil {
+			return fmt.Errorf("write %s: %w", sel, err)
+		}
+	}
+	return nil
+}
+
+func (c Content) targets() ([]target, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image); err != nil {
+		return nil, err
+	}
+	targets := []target{{mime: "image/png", data: buf.Bytes()}}
+	if c.Path != "" {
+		path, err := filepath.Abs(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		uri := (&url.URL{Scheme: "file", Path: path}).String()
+		targets = append(targets,
+			target{mime: "text/uri-list", data: []byte(uri + "\r\n")},
+			// Nautilus and other GNOME file managers paste from this target.
+			target{mime: "x-special/gnome-copied-files", data: []byte("copy\n" + uri)},
+		)
+	}
+	html := `<img src="data:image/png;base64,` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `">`
+	targets = append(targets, target{mime: "text/html", data: []byte(html)})
+	return targets, nil
+}
+
+// textMimeTypes names text, most specific first. The X11 names keep
+// XWayland clients able to paste.
+var textMimeTypes = []string{"text/plain;charset=utf-8", "UTF8_STRING", "text/plain", "STRING", "TEXT"}
+
+// textTargets offers text under each of textMimeTypes.
+func textTargets(text string) []target {
+	targets := make([]target, len(textMimeTypes))
+	for i, mime := range textMimeTypes {
+		targets[i] = target{mime: mime, data: []byte(text)}
+	}
+	return targets
+}
+
+// Watch sends each image copied to sel from now on until ctx is done, so
+// screenshots taken in other applications can be collected as they are
+// copied. Copies of text and other data are skipped, as are images this
+// process copied itself. The channel is closed when watching stops; a
+// failure is sent first as a Copied with Err set.
+func Watch(ctx context.Context, sel Selection) (<-chan Copied, error) {
+	if err := ensureInit(); err != nil {
+		return nil, err
+	}
+	copies := make(chan Copied)
+	go func() {
+		defer close(copies)
+		err := backend.watch(ctx, sel, func() {
+			mime, data, err := backend.readImage(sel)
+			if err != nil || len(data) == 0 || copiedByUs(data) {
+				return
+			}
+			img, err := decodeImage(mime, data)
+			if err != nil {
+				return
+			}
+			select {
+			case copies <- Copied{Image: img, Time: time.Now()}:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			select {
+			case copies <- Copied{Time: time.Now(), Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return copies, nil
+}
+
+var (
+	lastCopiedMu sync.Mutex
+	// lastCopied is the PNG this process last copied, which watchers skip.
+	lastCopied []byte
+)
+
+func copiedByUs(data []byte) bool {
+	lastCopiedMu.Lock()
+	defer lastCopiedMu.Unlock()
+	return bytes.Equal(data, lastCopied)
 }
 
-// ReadImage retrieves PNG image data from the clipboard and decodes it.
+// ReadImage retrieves image data from the clipboard and decodes it.
 func ReadImage() (image.Image, error) {
+	return ReadImageFrom(Clipboard)
+}
+
+// ReadImageFrom retrieves image data from sel and decodes it. PNG, BMP,
+// JPEG and SVG targets are accepted, in that order of preference, as is a
+// copied image file named by text/uri-list. SVG is rasterized at 96 DPI with
+// rsvg-convert.
+func ReadImageFrom(sel Selection) (image.Image, error) {
 	if err := ensureInit(); err != nil {
 		return nil, err
 	}
-	data, err := backend.readSelection(backend.atoms.png)
+	mime, data, err := backend.readImage(sel)
 	if err != nil {
 		return nil, err
 	}
 	if len(data) == 0 {
-		return nil, fmt.Errorf("clipboard does not contain image data")
+		return nil, fmt.Errorf("%s does not contain image data", sel)
 	}
-	img, err := png.Decode(bytes.NewReader(data))
+	img, err := decodeImage(mime, data)
 	if err != nil {
 		return nil, err
 	}
+	// History keeps every image as PNG.
+	if mime != "image/png" {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return img, nil
+		}
+		data = buf.Bytes()
+	}
+	_ = recordHistory(true, ".png", data)
 	return img, nil
 }
 
-// WriteText writes text data to the clipboard.
-func WriteText(text string) error {
+// WriteText writes text data to the clipboard unless other selections are
+// named.
+func WriteText(text string, selections ...Selection) error {
 	if err := ensureInit(); err != nil {
 		return err
 	}
-	return backend.writeText([]byte(text))
+	if err := writeSelections(textTargets(text), selections); err != nil {
+		return err
+	}
+	_ = recordHistory(false, ".txt", []byte(text))
+	return nil
 }
 
 // ReadText returns UTF-8 text data from the clipboard.
 func ReadText() (string, error) {
+	return ReadTextFrom(Clipboard)
+}
+
+// ReadTextFrom returns UTF-8 text data from sel.
+func ReadTextFrom(sel Selection) (string, error) {
 	if err := ensureInit(); err != nil {
 		return "", err
 	}
-	data, err := backend.readSelection(backend.atoms.utf8)
+	data, err := backend.readText(sel)
 	if err != nil {
-		data, err = backend.readSelection(xproto.AtomString)
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 	if len(data) == 0 {
-		return "", fmt.Errorf("clipboard does not contain text data")
+		return "", fmt.Errorf("%s does not contain text data", sel)
 	}
 	// Trim trailing null byte some applications include in STRING responses.
 	if data[len(data)-1] == 0 {
 		data = data[:len(data)-1]
 	}
+	_ = recordHistory(true, ".txt", data)
 	return string(data), nil
 }
 
 type x11Clipboard struct {
-	conn      *xgb.Conn
-	window    xproto.Window
-	atoms     atomSet
-	mu        sync.RWMutex
-	textData  []byte
-	imageData []byte
+	conn   *xgb.Conn
+	window xproto.Window
+	atoms  atomSet
+	mu     sync.RWMutex
+	// targets holds what each owned selection atom offers.
+	targets map[xproto.Atom][]x11Target
+	// mimeAtoms caches the atoms of offered MIME types.
+	mimeAtoms map[string]xproto.Atom
+	// maxChunk is the most data one ChangeProperty request can carry;
+	// larger targets are sent with INCR.
+	maxChunk int
+	// transfers tracks INCR sends by requestor window and property. Only
+	// the event loop touches it.
+	transfers map[incrKey]*incrTransfer
+}
+
+type incrKey struct {
+	requestor xproto.Window
+	property  xproto.Atom
+}
+
+// incrTransfer is a target being sent in chunks: the requestor deletes the
+// property to ask for the next one, and an empty chunk ends the transfer.
+type incrTransfer struct {
+	target xproto.Atom
+	data   []byte
+	done   bool
+}
+
+// next returns up to size bytes of what is left. Once the data runs out it
+// returns the empty chunk that ends the transfer and marks it done.
+func (t *incrTransfer) next(size int) []byte {
+	n := min(size, len(t.data))
+	chunk := t.data[:n]
+	t.data = t.data[n:]
+	if n == 0 {
+		t.done = true
+	}
+	return chunk
+}
+
+type x11Target struct {
+	atom xproto.Atom
+	data []byte
 }
 
 type atomSet struct {
 	clipboard xproto.Atom
 	targets   xproto.Atom
 	utf8      xproto.Atom
-	textPlain xproto.Atom
-	png       xproto.Atom
 	property  xproto.Atom
+	incr      xproto.Atom
 }
 
 func (c *x11Clipboard) initialize() error {
@@ -143,13 +354,15 @@ func (c *x11Clipboard) initialize() error {
 	c.conn = conn
 	c.window = window
 	c.atoms = atoms
+	c.maxChunk = maxPropertyChunk(setup)
+	c.transfers = make(map[incrKey]*incrTransfer)
 	go c.eventLoop()
 	return nil
 }
 
 func internAtoms(conn *xgb.Conn) (atomSet, error) {
 	get := func(name string) (xproto.Atom, error) {
-		reply, err := xproto.InternAtom(conn, true, uint16(len(name)), name).Reply()
+		reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
 		if err != nil {
 			return 0, err
 		}
@@ -167,39 +380,71 @@ func internAtoms(conn *xgb.Conn) (atomSet, error) {
 	if err != nil {
 		return atomSet{}, err
 	}
-	textPlain, err := get("text/plain;charset=utf-8")
+	property, err := get("SHINEYSHOT_CLIPBOARD")
 	if err != nil {
 		return atomSet{}, err
 	}
-	png, err := get("image/png")
+	incr, err := get("INCR")
 	if err != nil {
 		return atomSet{}, err
 	}
-	property, err := get("SHINEYSHOT_CLIPBOARD")
-	if err != nil {
-		return atomSet{}, err
+	return atomSet{clipboard: clipboard, targets: targets, utf8: utf8, property: property, incr: incr}, nil
+}
+
+// maxPropertyChunk is the largest payload that fits one ChangeProperty
+// request, whose header takes 24 bytes, within the server's request limit.
+// Clients commonly mishandle properties near the limit, so a quarter of it
+// is used, as other toolkits do.
+func maxPropertyChunk(setup *xproto.SetupInfo) int {
+	limit := int(setup.MaximumRequestLength)*4 - 24
+	return max(limit/4, 4096) &^ 3
+}
+
+// selectionAtom maps sel to the X11 selection it names.
+func (c *x11Clipboard) selectionAtom(sel Selection) xproto.Atom {
+	if sel == Primary {
+		return xproto.AtomPrimary
 	}
-	return atomSet{clipboard: clipboard, targets: targets, utf8: utf8, textPlain: textPlain, png: png, property: property}, nil
+	return c.atoms.clipboard
 }
 
-func (c *x11Clipboard) writeText(data []byte) error {
+func (c *x11Clipboard) write(sel Selection, targets []target) error {
+	offered := make([]x11Target, 0, len(targets))
+	for _, t := range targets {
+		atom, err := c.mimeAtom(t.mime)
+		if err != nil {
+			return err
+		}
+		offered = append(offered, x11Target{atom: atom, data: append([]byte(nil), t.data...)})
+	}
+	selection := c.selectionAtom(sel)
 	c.mu.Lock()
-	c.textData = append([]byte(nil), data...)
-	c.imageData = nil
+	if c.targets == nil {
+		c.targets = make(map[xproto.Atom][]x11Target)
+	}
+	c.targets[selection] = offered
 	c.mu.Unlock()
-	return c.setSelectionOwner()
+	return xproto.SetSelectionOwnerChecked(c.conn, c.window, selection, xproto.TimeCurrentTime).Check()
 }
 
-func (c *x11Clipboard) writeImage(data []byte) error {
+func (c *x11Clipboard) mimeAtom(mime string) (xproto.Atom, error) {
+	c.mu.RLock()
+	atom, ok := c.mimeAtoms[mime]
+	c.mu.RUnlock()
+	if ok {
+		return atom, nil
+	}
+	reply, err := xproto.InternAtom(c.conn, false, uint16(len(mime)), mime).Reply()
+	if err != nil {
+		return 0, err
+	}
 	c.mu.Lock()
-	c.imageData = append([]byte(nil), data...)
-	c.textData = nil
+	if c.mimeAtoms == nil {
+		c.mimeAtoms = make(map[string]xproto.Atom)
+	}
+	c.mimeAtoms[mime] = reply.Atom
 	c.mu.Unlock()
-	return c.setSelectionOwner()
-}
-
-func (c *x11Clipboard) setSelectionOwner() error {
-	return xproto.SetSelectionOwnerChecked(c.conn, c.window, c.atoms.clipboard, xproto.TimeCurrentTime).Check()
+	return reply.Atom, nil
 }
 
 func (c *x11Clipboard) eventLoop() {
@@ -212,7 +457,13 @@ func (c *x11Clipboard) eventLoop() {
 		case xproto.SelectionRequestEvent:
 			c.handleSelectionRequest(e)
 		case xproto.SelectionClearEvent:
-			c.handleSelectionClear()
+			c.handleSelectionClear(e)
+		case xproto.PropertyNotifyEvent:
+			if e.State == xproto.PropertyDelete {
+				c.continueTransfer(incrKey{requestor: e.Window, property: e.Atom})
+			}
+		case xproto.DestroyNotifyEvent:
+			c.dropTransfers(e.Window)
 		}
 	}
 }
@@ -224,8 +475,7 @@ func (c *x11Clipboard) handleSelectionRequest(e xproto.SelectionRequestEvent) {
 	}
 
 	c.mu.RLock()
-	text := c.textData
-	image := c.imageData
+	targets := c.targets[e.Selection]
 	c.mu.RUnlock()
 
 	var (
@@ -234,39 +484,25 @@ func (c *x11Clipboard) handleSelectionRequest(e xproto.SelectionRequestEvent) {
 		payload    []byte
 	)
 
-	switch e.Target {
-	case c.atoms.targets:
-		targets := []xproto.Atom{c.atoms.targets}
-		if len(text) > 0 {
-			targets = append(targets, c.atoms.utf8, xproto.AtomString, c.atoms.textPlain)
-		}
-		if len(image) > 0 {
-			targets = append(targets, c.atoms.png)
+	if e.Target == c.atoms.targets {
+		atoms := []xproto.Atom{c.atoms.targets}
+		for _, t := range targets {
+			atoms = append(atoms, t.atom)
 		}
-		payload = atomsToBytes(targets)
+		payload = atomsToBytes(atoms)
 		targetType = xproto.AtomAtom
 		format = 32
-	case c.atoms.utf8, xproto.AtomString, c.atoms.textPlain:
-		if len(text) == 0 {
-			property = xproto.AtomNone
-			break
-		}
-		payload = text
-		targetType = c.atoms.utf8
-		format = 8
-	case c.atoms.png:
-		if len(image) == 0 {
-			property = xproto.AtomNone
-			break
-		}
-		payload = image
-		targetType = c.atoms.png
+	} else if idx := slices.IndexFunc(targets, func(t x11Target) bool { return t.atom == e.Target }); idx >= 0 {
+		payload = targets[idx].data
+		targetType = e.Target
 		format = 8
-	default:
+	} else {
 		property = xproto.AtomNone
 	}
 
-	if property != xproto.AtomNone {
+	if property != xproto.AtomNone && format == 8 && len(payload) > c.maxChunk {
+		c.startTransfer(e.Requestor, property, targetType, payload)
+	} else if property != xproto.AtomNone {
 		var length uint32
 		switch format {
 		case 8:
@@ -289,14 +525,143 @@ func (c *x11Clipboard) handleSelectionRequest(e xproto.SelectionRequestEvent) {
 	_ = xproto.SendEvent(c.conn, false, e.Requestor, 0, string(notify.Bytes()))
 }
 
-func (c *x11Clipboard) handleSelectionClear() {
+// startTransfer announces an INCR send of data. The size written with INCR
+// is only a lower bound, so data that does not fit 32 bits is not a problem.
+func (c *x11Clipboard) startTransfer(requestor xproto.Window, property, target xproto.Atom, data []byte) {
+	// Deletions of the requestor's property drive the transfer, and its
+	// destruction abandons it.
+	xproto.ChangeWindowAttributes(c.conn, requestor, xproto.CwEventMask, []uint32{xproto.EventMaskPropertyChange | xproto.EventMaskStructureNotify})
+	c.transfers[incrKey{requestor: requestor, property: property}] = &incrTransfer{target: target, data: data}
+	size := make([]byte, 4)
+	xgb.Put32(size, uint32(min(len(data), 1<<32-1)))
+	xproto.ChangeProperty(c.conn, xproto.PropModeReplace, requestor, property, c.atoms.incr, 32, 1, size)
+}
+
+// continueTransfer writes the next chunk once the requestor has deleted the
+// previous one.
+func (c *x11Clipboard) continueTransfer(key incrKey) {
+	t, ok := c.transfers[key]
+	if !ok {
+		return
+	}
+	chunk := t.next(c.maxChunk)
+	xproto.ChangeProperty(c.conn, xproto.PropModeReplace, key.requestor, key.property, t.target, 8, uint32(len(chunk)), chunk)
+	if t.done {
+		delete(c.transfers, key)
+		c.releaseRequestor(key.requestor)
+	}
+}
+
+// dropTransfers abandons the sends to a requestor window that is gone.
+func (c *x11Clipboard) dropTransfers(requestor xproto.Window) {
+	for key := range c.transfers {
+		if key.requestor == requestor {
+			delete(c.transfers, key)
+		}
+	}
+}
+
+// releaseRequestor stops following a requestor's events once no transfer
+// to it is left.
+func (c *x11Clipboard) releaseRequestor(requestor xproto.Window) {
+	for key := range c.transfers {
+		if key.requestor == requestor {
+			return
+		}
+	}
+	xproto.ChangeWindowAttributes(c.conn, requestor, xproto.CwEventMask, []uint32{xproto.EventMaskNoEvent})
+}
+
+func (c *x11Clipboard) handleSelectionClear(e xproto.SelectionClearEvent) {
 	c.mu.Lock()
-	c.textData = nil
-	c.imageData = nil
+	delete(c.targets, e.Selection)
 	c.mu.Unlock()
 }
 
-func (c *x11Clipboard) readSelection(target xproto.Atom) ([]byte, error) {
+// watch follows owner changes of sel through XFixes on a connection of its
+// own, which is closed to stop waiting when ctx is done.
+func (c *x11Clipboard) watch(ctx context.Context, sel Selection, changed func()) error {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := xfixes.Init(conn); err != nil {
+		return fmt.Errorf("watching the clipboard needs XFixes: %w", err)
+	}
+	if _, err := xfixes.QueryVersion(conn, 5, 0).Reply(); err != nil {
+		return err
+	}
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	window, err := xproto.NewWindowId(conn)
+	if err != nil {
+		return err
+	}
+	if err := xproto.CreateWindowChecked(conn, 0, window, screen.Root, 0, 0, 1, 1, 0, xproto.WindowClassInputOnly, 0, 0, nil).Check(); err != nil {
+		return err
+	}
+	if err := xfixes.SelectSelectionInputChecked(conn, window, c.selectionAtom(sel), xfixes.SelectionEventMaskSetSelectionOwner).Check(); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	for {
+		ev, err := conn.WaitForEvent()
+		if ev == nil && err == nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.New("X connection closed")
+		}
+		if e, ok := ev.(xfixes.SelectionNotifyEvent); ok && e.Owner != xproto.WindowNone && e.Owner != c.window {
+			changed()
+		}
+	}
+}
+
+// readImage asks the owner which targets it offers and reads the most
+// preferred image among them. Owners that cannot list their targets are
+// asked for PNG.
+func (c *x11Clipboard) readImage(sel Selection) (string, []byte, error) {
+	selection := c.selectionAtom(sel)
+	mime := "image/png"
+	if list, err := c.readSelection(selection, c.atoms.targets); err == nil {
+		offered := make(map[xproto.Atom]bool, len(list)/4)
+		for i := 0; i+4 <= len(list); i += 4 {
+			offered[xproto.Atom(xgb.Get32(list[i:]))] = true
+		}
+		mime = ""
+		for _, candidate := range imageMimeTypes {
+			atom, err := c.mimeAtom(candidate)
+			if err != nil {
+				return "", nil, err
+			}
+			if offered[atom] {
+				mime = candidate
+				break
+			}
+		}
+		if mime == "" {
+			return "", nil, fmt.Errorf("%s does not contain image data", sel)
+		}
+	}
+	atom, err := c.mimeAtom(mime)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := c.readSelection(selection, atom)
+	return mime, data, err
+}
+
+func (c *x11Clipboard) readText(sel Selection) ([]byte, error) {
+	data, err := c.readSelection(c.selectionAtom(sel), c.atoms.utf8)
+	if err != nil {
+		return c.readSelection(c.selectionAtom(sel), xproto.AtomString)
+	}
+	return data, nil
+}
+
+func (c *x11Clipboard) readSelection(selection, target xproto.Atom) ([]byte, error) {
 	conn, err := xgb.NewConn()
 	if err != nil {
 		return nil, err
@@ -318,7 +683,7 @@ func (c *x11Clipboard) readSelection(target xproto.Atom) ([]byte, error) {
 	if err := xproto.DeletePropertyChecked(conn, window, c.atoms.property).Check(); err != nil {
 		return nil, err
 	}
-	if err := xproto.ConvertSelectionChecked(conn, window, c.atoms.clipboard, target, c.atoms.property, xproto.TimeCurrentTime).Check(); err != nil {
+	if err := xproto.ConvertSelectionChecked(conn, window, selection, target, c.atoms.property, xproto.TimeCurrentTime).Check(); err != nil {
 		return nil, err
 	}
 
@@ -335,10 +700,15 @@ func (c *x11Clipboard) readSelection(target xproto.Atom) ([]byte, error) {
 			if e.Property != c.atoms.property {
 				continue
 			}
-			reply, err := xproto.GetProperty(conn, false, window, c.atoms.property, xproto.GetPropertyTypeAny, 0, (1<<31)-1).Reply()
+			reply, err := xproto.GetProperty(conn, true, window, c.atoms.property, xproto.GetPropertyTypeAny, 0, (1<<31)-1).Reply()
 			if err != nil {
 				return nil, err
 			}
+			if reply.Type == c.atoms.incr {
+				// Deleting the property, which GetProperty just did, asks
+				// the owner for the first chunk.
+				return c.readIncr(conn, window)
+			}
 			data := make([]byte, len(reply.Value))
 			copy(data, reply.Value)
 			return data, nil
@@ -346,6 +716,33 @@ func (c *x11Clipboard) readSelection(target xproto.Atom) ([]byte, error) {
 	}
 }
 
+// readIncr collects the chunks of an INCR transfer, deleting each to ask
+// for the next, until the owner writes an empty one.
+func (c *x11Clipboard) readIncr(conn *xgb.Conn, window xproto.Window) ([]byte, error) {
+	var data []byte
+	for {
+		ev, xerr := conn.WaitForEvent()
+		if xerr != nil {
+			return nil, xerr
+		}
+		if ev == nil {
+			return nil, errors.New("X connection closed during transfer")
+		}
+		e, ok := ev.(xproto.PropertyNotifyEvent)
+		if !ok || e.Atom != c.atoms.property || e.State != xproto.PropertyNewValue {
+			continue
+		}
+		reply, err := xproto.GetProperty(conn, true, window, c.atoms.property, xproto.GetPropertyTypeAny, 0, (1<<31)-1).Reply()
+		if err != nil {
+			return nil, err
+		}
+		if len(reply.Value) == 0 {
+			return data, nil
+		}
+		data = append(data, reply.Value...)
+	}
+}
+
 func atomsToBytes(atoms []xproto.Atom) []byte {
 	buf := make([]byte, len(atoms)*4)
 	for i, atom := range atoms {
@@ -3,9 +3,23 @@
 package clipboard
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/example/shineyshot/internal/wayland"
 )
 
 func TestEnsureInitWithoutDisplay(t *testing.T) {
@@ -20,3 +34,197 @@ func TestEnsureInitWithoutDisplay(t *testing.T) {
 		t.Fatalf("expected errNoDisplay, got %v", err)
 	}
 }
+
+// serveFakeDataControl answers one connection as a compositor whose
+// clipboard and primary selection hold data of one MIME type offered by
+// another client, which copies it again right after the device is bound.
+func serveFakeDataControl(t *testing.T, conn *net.UnixConn, mime, content string) {
+	defer conn.Close()
+	ifaces := map[uint32]string{wayland.DisplayID: "wl_display"}
+	event := func(object uint32, opcode uint16, args *wayland.Request) {
+		msg := binary.NativeEndian.AppendUint32(nil, object)
+		msg = binary.NativeEndian.AppendUint32(msg, uint32(8+len(args.Bytes()))<<16|uint32(opcode))
+		if _, err := conn.Write(append(msg, args.Bytes()...)); err != nil {
+			t.Logf("fake compositor write: %v", err)
+		}
+	}
+	var buf []byte
+	for {
+		data := make([]byte, 4096)
+		oob := make([]byte, syscall.CmsgSpace(4))
+		n, oobn, _, _, err := conn.ReadMsgUnix(data, oob)
+		if err != nil || n == 0 {
+			return
+		}
+		buf = append(buf, data[:n]...)
+		var fds []int
+		if msgs, err := syscall.ParseSocketControlMessage(oob[:oobn]); err == nil {
+			for _, m := range msgs {
+				if got, err := syscall.ParseUnixRights(&m); err == nil {
+					fds = append(fds, got...)
+				}
+			}
+		}
+		for len(buf) >= 8 && len(buf) >= int(binary.NativeEndian.Uint32(buf[4:])>>16) {
+			size := int(binary.NativeEndian.Uint32(buf[4:]) >> 16)
+			object, opcode := binary.NativeEndian.Uint32(buf), uint16(binary.NativeEndian.Uint32(buf[4:]))
+			req := wayland.NewEvent(append([]byte(nil), buf[8:size]...))
+			buf = buf[size:]
+			switch ifaces[object] {
+			case "wl_display":
+				id := req.Uint()
+				if opcode == 0 { // sync
+					event(id, 0, (&wayland.Request{}).Uint(0))
+					continue
+				}
+				ifaces[id] = "wl_registry"
+				event(id, 0, (&wayland.Request{}).Uint(1).String("wl_seat").Uint(7))
+				event(id, 0, (&wayland.Request{}).Uint(2).String("zwlr_data_control_manager_v1").Uint(2))
+			case "wl_registry":
+				_, iface, _, id := req.Uint(), req.String(), req.Uint(), req.Uint()
+				ifaces[id] = iface
+			case "zwlr_data_control_manager_v1":
+				if opcode != 1 {
+					continue
+				}
+				device := req.Uint()
+				for _, offer := range []uint32{0xff000000, 0xff000001} {
+					ifaces[offer] = "offer"
+					event(device, 0, (&wayland.Request{}).Uint(offer))
+					event(offer, 0, (&wayland.Request{}).String("text/html"))
+					event(offer, 0, (&wayland.Request{}).String(mime))
+					event(device, 1, (&wayland.Request{}).Uint(offer))
+					event(device, 3, (&wayland.Request{}).Uint(offer)) // primary_selection
+				}
+			case "offer":
+				if opcode != 0 || len(fds) == 0 {
+					continue
+				}
+				if got := req.String(); got != mime {
+					t.Errorf("paste asked for %q, want %q", got, mime)
+				}
+				f := os.NewFile(uintptr(fds[0]), "paste")
+				fds = fds[1:]
+				_, _ = f.WriteString(content)
+				f.Close()
+			}
+		}
+	}
+}
+
+func startFakeDataControl(t *testing.T, mime, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wayland-test")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	t.Setenv("WAYLAND_DISPLAY", path)
+	go func() {
+		for {
+			conn, err := ln.AcceptUnix()
+			if err != nil {
+				return
+			}
+			go serveFakeDataControl(t, conn, mime, content)
+		}
+	}()
+}
+
+func TestWaylandClipboardReadsOfferedText(t *testing.T) {
+	startFakeDataControl(t, "text/plain;charset=utf-8", "hello wayland")
+
+	for _, sel := range []Selection{Clipboard, Primary} {
+		data, err := (&wlClipboard{}).readText(sel)
+		if err != nil {
+			t.Fatalf("read text from %s: %v", sel, err)
+		}
+		if string(data) != "hello wayland" {
+			t.Fatalf("read %q from %s, want hello wayland", data, sel)
+		}
+	}
+}
+
+func TestContentTargetsOfferEveryFormat(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	targets, err := Content{Image: img, Path: "/tmp/shot one.png"}.targets()
+	if err != nil {
+		t.Fatalf("targets: %v", err)
+	}
+	got := map[string]string{}
+	for _, target := range targets {
+		got[target.mime] = string(target.data)
+	}
+	if targets[0].mime != "image/png" {
+		t.Fatalf("expected image/png first, got %s", targets[0].mime)
+	}
+	if uri := got["text/uri-list"]; uri != "file:///tmp/shot%20one.png\r\n" {
+		t.Fatalf("unexpected uri list %q", uri)
+	}
+	if html := got["text/html"]; !strings.HasPrefix(html, `<img src="data:image/png;base64,`) {
+		t.Fatalf("unexpected html %q", html)
+	}
+
+	targets, err = Content{Image: img}.targets()
+	if err != nil || slices.ContainsFunc(targets, func(t target) bool { return t.mime == "text/uri-list" }) {
+		t.Fatalf("expected no uri list without a path, got %v %v", targets, err)
+	}
+}
+
+func TestIncrTransferSendsChunksThenEnd(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	tr := &incrTransfer{data: data}
+	var got []byte
+	chunks := 0
+	for !tr.done {
+		chunk := tr.next(100)
+		if len(chunk) > 100 {
+			t.Fatalf("chunk of %d bytes exceeds the limit", len(chunk))
+		}
+		got = append(got, chunk...)
+		chunks++
+	}
+	// Three chunks of data and the empty one that ends the transfer.
+	if chunks != 4 || !bytes.Equal(got, data) {
+		t.Fatalf("got %d chunks and %d bytes, want 4 chunks and %d bytes", chunks, len(got), len(data))
+	}
+}
+
+func TestWatchSendsCopiedImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	startFakeDataControl(t, "image/png", buf.String())
+	initOnce = sync.Once{}
+	initOnce.Do(func() {})
+	initErr = nil
+	backend = &wlClipboard{}
+	t.Cleanup(func() {
+		initOnce = sync.Once{}
+		backend = nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	copies, err := Watch(ctx, Clipboard)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	select {
+	case copied := <-copies:
+		if copied.Err != nil || copied.Image.Bounds() != image.Rect(0, 0, 3, 2) {
+			t.Fatalf("unexpected copy %+v", copied)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no copy seen")
+	}
+	cancel()
+	for copied := range copies {
+		if copied.Err != nil {
+			t.Fatalf("expected cancelling to stop quietly, got %v", copied.Err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package clipboard
+
+import "image"
+
+// Content is an image offered on the clipboard in several formats at once,
+// so it pastes into image editors, file managers, chats and rich-text
+// editors alike. The image is always offered as image/png and as text/html
+// holding an <img> with a data URI.
+type Content struct {
+	Image image.Image
+	// Path is the file the image was saved to. When set it is also offered
+	// as text/uri-list, so file managers paste a copy of the file.
+	Path string
+}
@@ -0,0 +1,102 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/example/shineyshot/internal/imagefile"
+)
+
+// imageMimeTypes lists the image targets a paste accepts, in order of
+// preference: lossless formats first, then SVG, which needs an external
+// rasterizer, and last a file copied in a file manager.
+var imageMimeTypes = []string{"image/png", "image/bmp", "image/jpeg", "image/svg+xml", "text/uri-list"}
+
+// svgDPI is the resolution SVG is rasterized at, the CSS reference density,
+// so an SVG pastes at the pixel size it declares.
+const svgDPI = 96
+
+// rsvgConvert rasterizes SVG; it ships with librsvg, which most Linux
+// desktops already have installed.
+const rsvgConvert = "rsvg-convert"
+
+// decodeImage decodes clipboard data offered under mime.
+func decodeImage(mime string, data []byte) (image.Image, error) {
+	switch mime {
+	case "image/png":
+		return png.Decode(bytes.NewReader(data))
+	case "image/jpeg":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "image/bmp":
+		return bmp.Decode(bytes.NewReader(data))
+	case "image/svg+xml":
+		return rasterizeSVG(data)
+	case "text/uri-list":
+		path, err := firstFileURI(data)
+		if err != nil {
+			return nil, err
+		}
+		return decodeImageFile(path)
+	}
+	return nil, fmt.Errorf("unsupported clipboard image type %s", mime)
+}
+
+// firstFileURI returns the path of the first local file in a text/uri-list,
+// skipping the comment lines the format allows.
+func firstFileURI(data []byte) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+			continue
+		}
+		return u.Path, nil
+	}
+	return "", errors.New("clipboard uri list names no local file")
+}
+
+// decodeImageFile decodes an SVG file or any image imagefile.Open reads.
+func decodeImageFile(path string) (image.Image, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".svg") {
+		return imagefile.Open(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return rasterizeSVG(data)
+}
+
+// rasterizeSVG renders SVG to an image with rsvg-convert at svgDPI.
+func rasterizeSVG(data []byte) (image.Image, error) {
+	if _, err := exec.LookPath(rsvgConvert); err != nil {
+		return nil, fmt.Errorf("pasting SVG needs %s (librsvg): %w", rsvgConvert, err)
+	}
+	dpi := fmt.Sprint(svgDPI)
+	cmd := exec.Command(rsvgConvert, "--dpi-x", dpi, "--dpi-y", dpi, "--format", "png")
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", rsvgConvert, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", rsvgConvert, err)
+	}
+	return png.Decode(bytes.NewReader(out))
+}
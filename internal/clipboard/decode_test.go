@@ -0,0 +1,52 @@
+package clipboard
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+func TestDecodeImageAcceptsPastedFormats(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	encode := func(enc func(*bytes.Buffer) error) []byte {
+		var buf bytes.Buffer
+		if err := enc(&buf); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		return buf.Bytes()
+	}
+	pngData := encode(func(b *bytes.Buffer) error { return png.Encode(b, img) })
+	path := filepath.Join(t.TempDir(), "shot one.png")
+	if err := os.WriteFile(path, pngData, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	uri := (&url.URL{Scheme: "file", Path: path}).String()
+
+	for mime, data := range map[string][]byte{
+		"image/png":     pngData,
+		"image/jpeg":    encode(func(b *bytes.Buffer) error { return jpeg.Encode(b, img, nil) }),
+		"image/bmp":     encode(func(b *bytes.Buffer) error { return bmp.Encode(b, img) }),
+		"text/uri-list": []byte("# copied from a file manager\r\n" + uri + "\r\n"),
+	} {
+		got, err := decodeImage(mime, data)
+		if err != nil {
+			t.Fatalf("%s: %v", mime, err)
+		}
+		if got.Bounds() != img.Bounds() {
+			t.Fatalf("%s: bounds %v, want %v", mime, got.Bounds(), img.Bounds())
+		}
+	}
+
+	if _, err := decodeImage("text/uri-list", []byte("https://example.com/shot.png\r\n")); err == nil {
+		t.Fatalf("expected remote uri to be rejected")
+	}
+}
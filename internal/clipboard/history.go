@@ -0,0 +1,185 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryLimit is how many copies and pastes the history keeps.
+const HistoryLimit = 20
+
+// HistoryEntry is an image or text shineyshot copied or pasted. Entries are
+// kept in the user cache directory, so every shineyshot process shares them.
+type HistoryEntry struct {
+	Time time.Time
+	// Pasted is set for entries read from a selection rather than copied
+	// to one.
+	Pasted bool
+	// Text holds a text entry; it is empty for images.
+	Text string
+	// Size is the pixel size of an image entry.
+	Size image.Point
+
+	path string
+}
+
+// IsImage reports whether the entry holds an image.
+func (e HistoryEntry) IsImage() bool {
+	return filepath.Ext(e.path) == ".png"
+}
+
+// String summarizes the entry for listings.
+func (e HistoryEntry) String() string {
+	verb := "copied"
+	if e.Pasted {
+		verb = "pasted"
+	}
+	if e.IsImage() {
+		return fmt.Sprintf("%s %dx%d image", verb, e.Size.X, e.Size.Y)
+	}
+	text := strings.Join(strings.Fields(e.Text), " ")
+	if r := []rune(text); len(r) > 40 {
+		text = string(r[:39]) + "…"
+	}
+	return fmt.Sprintf("%s text %q", verb, text)
+}
+
+// Image decodes an image entry.
+func (e HistoryEntry) Image() (image.Image, error) {
+	if !e.IsImage() {
+		return nil, fmt.Errorf("history entry is text, not an image")
+	}
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// Recopy publishes the entry again, to the clipboard unless other
+// selections are named.
+func Recopy(e HistoryEntry, selections ...Selection) error {
+	if !e.IsImage() {
+		return WriteText(e.Text, selections...)
+	}
+	img, err := e.Image()
+	if err != nil {
+		return err
+	}
+	return WriteContent(Content{Image: img}, selections...)
+}
+
+// historyDir is where entries are stored, one file each; tests replace it.
+var historyDir = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shineyshot", "clipboard-history"), nil
+}
+
+// History returns the kept entries, newest first.
+func History() ([]HistoryEntry, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	for _, f := range files {
+		e, ok := parseHistoryName(f.Name())
+		if !ok {
+			continue
+		}
+		e.path = filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			continue
+		}
+		if e.IsImage() {
+			cfg, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			e.Size = image.Pt(cfg.Width, cfg.Height)
+		} else {
+			e.Text = string(data)
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b HistoryEntry) int { return b.Time.Compare(a.Time) })
+	return entries, nil
+}
+
+// parseHistoryName reads the time and action back from names written by
+// recordHistory, such as 1700000000000000000-copy.png.
+func parseHistoryName(name string) (HistoryEntry, bool) {
+	ext := filepath.Ext(name)
+	if ext != ".png" && ext != ".txt" {
+		return HistoryEntry{}, false
+	}
+	stamp, action, ok := strings.Cut(strings.TrimSuffix(name, ext), "-")
+	if !ok || (action != "copy" && action != "paste") {
+		return HistoryEntry{}, false
+	}
+	nanos, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return HistoryEntry{}, false
+	}
+	return HistoryEntry{Time: time.Unix(0, nanos), Pasted: action == "paste"}, true
+}
+
+// recordHistory keeps data, a PNG or text depending on ext, as the newest
+// entry. An identical older entry is dropped rather than kept twice, and
+// the oldest entries beyond HistoryLimit are removed. History is a
+// convenience, so callers ignore its errors.
+func recordHistory(pasted bool, ext string, data []byte) error {
+	dir, err := historyDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	entries, err := History()
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if filepath.Ext(e.path) != ext {
+			continue
+		}
+		if old, err := os.ReadFile(e.path); err == nil && bytes.Equal(old, data) {
+			_ = os.Remove(e.path)
+			entries = slices.Delete(entries, i, i+1)
+			break
+		}
+	}
+	action := "copy"
+	if pasted {
+		action = "paste"
+	}
+	name := fmt.Sprintf("%d-%s%s", time.Now().UnixNano(), action, ext)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		return err
+	}
+	for _, e := range entries[min(len(entries), HistoryLimit-1):] {
+		_ = os.Remove(e.path)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestHistoryKeepsNewestWithoutDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	orig := historyDir
+	historyDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { historyDir = orig })
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 5, 4))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := recordHistory(false, ".png", buf.Bytes()); err != nil {
+		t.Fatalf("record image: %v", err)
+	}
+	for i := range HistoryLimit {
+		if err := recordHistory(true, ".txt", []byte(fmt.Sprintf("text %d", i))); err != nil {
+			t.Fatalf("record text: %v", err)
+		}
+	}
+	if err := recordHistory(false, ".txt", []byte("text 3")); err != nil {
+		t.Fatalf("record duplicate: %v", err)
+	}
+
+	entries, err := History()
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != HistoryLimit {
+		t.Fatalf("got %d entries, want %d", len(entries), HistoryLimit)
+	}
+	if got := entries[0].String(); got != `copied text "text 3"` {
+		t.Fatalf("newest = %s, want the re-copied text", got)
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if e.IsImage() {
+			t.Fatalf("oldest image should have been dropped, got %s", e)
+		}
+		if seen[e.Text] {
+			t.Fatalf("duplicate entry %q", e.Text)
+		}
+		seen[e.Text] = true
+	}
+}
@@ -0,0 +1,28 @@
+package clipboard
+
+// Selection names one of the buffers copies are published to and pastes
+// are read from.
+type Selection int
+
+const (
+	// Clipboard is the buffer behind explicit copy and paste.
+	Clipboard Selection = iota
+	// Primary is the buffer X11 and Wayland desktops fill with selected text
+	// and paste with a middle click.
+	Primary
+)
+
+func (s Selection) String() string {
+	if s == Primary {
+		return "primary selection"
+	}
+	return "clipboard"
+}
+
+// selectionsOrDefault returns the clipboard when no selection was named.
+func selectionsOrDefault(selections []Selection) []Selection {
+	if len(selections) == 0 {
+		return []Selection{Clipboard}
+	}
+	return selections
+}
@@ -0,0 +1,15 @@
+package clipboard
+
+import (
+	"image"
+	"time"
+)
+
+// Copied is one image read while watching a selection.
+type Copied struct {
+	Image image.Image
+	// Time is when the image was read.
+	Time time.Time
+	// Err is set on the last value when watching failed; Image is nil then.
+	Err error
+}
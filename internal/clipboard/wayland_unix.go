@@ -0,0 +1,356 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/example/shineyshot/internal/wayland"
+)
+
+// wlReadTimeout bounds how long a paste waits for the owning client to
+// write its data.
+const wlReadTimeout = 5 * time.Second
+
+// dataProtocol holds the opcodes that differ between the data-control
+// protocols and the core wl_data_device. Every manager creates sources with
+// opcode 0 and devices with opcode 1, devices announce offers with event 0,
+// offers list their types with event 0 and sources offer types with
+// request 0 and are destroyed with request 1.
+type dataProtocol struct {
+	manager         string
+	version         uint32
+	setSelection    uint16
+	selection       uint16
+	finished        int // device event, or -1
+	sourceSend      uint16
+	sourceCancelled uint16
+	offerReceive    uint16
+	offerDestroy    uint16
+	// serial reports whether set_selection takes an input serial.
+	serial bool
+	// primarySince is the version that added the primary selection's
+	// request and event, or 0 when the protocol has none.
+	primarySince uint32
+	setPrimary   uint16
+	primary      uint16
+}
+
+// dataProtocols lists the protocols in order of preference. The
+// data-control protocols work without a focused window, which a command
+// line tool never has. Compositors ignore the core data device unless the
+// client has keyboard focus, so it is only a last resort.
+var dataProtocols = []dataProtocol{
+	{manager: "ext_data_control_manager_v1", version: 1, setSelection: 0, selection: 1, finished: 2, sourceSend: 0, sourceCancelled: 1, offerReceive: 0, offerDestroy: 1, primarySince: 1, setPrimary: 2, primary: 3},
+	{manager: "zwlr_data_control_manager_v1", version: 2, setSelection: 0, selection: 1, finished: 2, sourceSend: 0, sourceCancelled: 1, offerReceive: 0, offerDestroy: 1, primarySince: 2, setPrimary: 2, primary: 3},
+	{manager: "wl_data_device_manager", version: 3, setSelection: 1, selection: 5, finished: -1, sourceSend: 1, sourceCancelled: 2, offerReceive: 1, offerDestroy: 2, serial: true},
+}
+
+// wlDataSession is a connection with a data device bound for the first seat.
+type wlDataSession struct {
+	conn    *wayland.Conn
+	proto   dataProtocol
+	manager uint32
+	device  uint32
+	// version is the version the manager was bound at.
+	version uint32
+}
+
+// supports reports an error when the session cannot reach sel.
+func (s *wlDataSession) supports(sel Selection) error {
+	if sel == Primary && (s.proto.primarySince == 0 || s.version < s.proto.primarySince) {
+		return fmt.Errorf("%s does not support the primary selection", s.proto.manager)
+	}
+	return nil
+}
+
+// dialDataSession connects and creates a data device. Events for the device
+// are only read once the caller dispatches, so it can register a handler
+// first.
+func dialDataSession() (*wlDataSession, error) {
+	conn, err := wayland.Dial()
+	if err != nil {
+		return nil, err
+	}
+	registry, globals, err := conn.Registry()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	seat := slices.IndexFunc(globals, func(g wayland.Global) bool { return g.Iface == "wl_seat" })
+	if seat < 0 {
+		conn.Close()
+		return nil, errors.New("compositor offers no wl_seat")
+	}
+	for _, proto := range dataProtocols {
+		idx := slices.IndexFunc(globals, func(g wayland.Global) bool { return g.Iface == proto.manager })
+		if idx < 0 {
+			continue
+		}
+		s, err := bindDataDevice(conn, registry, proto, globals[idx], globals[seat])
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+	conn.Close()
+	return nil, errors.New("compositor offers no clipboard data device")
+}
+
+func bindDataDevice(conn *wayland.Conn, registry uint32, proto dataProtocol, manager, seat wayland.Global) (*wlDataSession, error) {
+	s := &wlDataSession{conn: conn, proto: proto, version: min(manager.Version, proto.version)}
+	var err error
+	if s.manager, err = conn.Bind(registry, manager, proto.version); err != nil {
+		return nil, err
+	}
+	seatID, err := conn.Bind(registry, seat, 1)
+	if err != nil {
+		return nil, err
+	}
+	s.device = conn.NewID()
+	if err := conn.Send(s.manager, 1, (&wayland.Request{}).Uint(s.device).Uint(seatID)); err != nil { // get_data_device
+		return nil, err
+	}
+	return s, nil
+}
+
+// wlClipboard serves the selection from a connection that stays open, and
+// so keeps answering paste requests, for as long as the process lives.
+type wlClipboard struct {
+	mu      sync.Mutex
+	serving *wlDataSession
+}
+
+func newWaylandClipboard() (*wlClipboard, error) {
+	s, err := dialDataSession()
+	if err != nil {
+		return nil, err
+	}
+	c := &wlClipboard{}
+	c.serve(s)
+	return c, nil
+}
+
+// serve dispatches the session's events in the background. Offers for other
+// clients' selections are destroyed unread. A session that fails or whose
+// device is finished is replaced on the next write.
+func (c *wlClipboard) serve(s *wlDataSession) {
+	c.serving = s
+	s.conn.Handle(s.device, func(opcode uint16, ev *wayland.Event) {
+		switch {
+		case opcode == 0: // data_offer
+			_ = s.conn.Send(ev.Uint(), s.proto.offerDestroy, nil)
+		case int(opcode) == s.proto.finished:
+			_ = s.conn.Close()
+		}
+	})
+	go func() {
+		for {
+			if err := s.conn.Dispatch(); err != nil {
+				break
+			}
+		}
+		c.mu.Lock()
+		if c.serving == s {
+			c.serving = nil
+		}
+		c.mu.Unlock()
+		s.conn.Close()
+	}()
+}
+
+// write offers each target from one data source and takes the selection.
+// The compositor cancels the previous source, which is then destroyed.
+func (c *wlClipboard) write(sel Selection, targets []target) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.serving == nil {
+		s, err := dialDataSession()
+		if err != nil {
+			return err
+		}
+		c.serve(s)
+	}
+	s := c.serving
+	if err := s.supports(sel); err != nil {
+		return err
+	}
+	offered := make(map[string][]byte, len(targets))
+	for _, t := range targets {
+		offered[t.mime] = append([]byte(nil), t.data...)
+	}
+	source := s.conn.NewID()
+	s.conn.Handle(source, func(opcode uint16, ev *wayland.Event) {
+		switch opcode {
+		case s.proto.sourceSend:
+			mime := ev.String()
+			if fd := ev.FD(); fd >= 0 {
+				go sendClipboardData(os.NewFile(uintptr(fd), "clipboard"), offered[mime])
+			}
+		case s.proto.sourceCancelled:
+			s.conn.Forget(source)
+			_ = s.conn.Send(source, 1, nil) // destroy
+		}
+	})
+	if err := s.conn.Send(s.manager, 0, (&wayland.Request{}).Uint(source)); err != nil { // create_data_source
+		return err
+	}
+	for _, t := range targets {
+		if err := s.conn.Send(source, 0, (&wayland.Request{}).String(t.mime)); err != nil { // offer
+			return err
+		}
+	}
+	if sel == Primary {
+		return s.conn.Send(s.device, s.proto.setPrimary, (&wayland.Request{}).Uint(source))
+	}
+	req := (&wayland.Request{}).Uint(source)
+	if s.proto.serial {
+		req.Uint(0)
+	}
+	return s.conn.Send(s.device, s.proto.setSelection, req)
+}
+
+// sendClipboardData writes one paste and closes the pipe to end it.
+func sendClipboardData(f *os.File, data []byte) {
+	defer f.Close()
+	_, _ = f.Write(data)
+}
+
+func (c *wlClipboard) readText(sel Selection) ([]byte, error) {
+	_, data, err := c.read(sel, textMimeTypes)
+	return data, err
+}
+
+func (c *wlClipboard) readImage(sel Selection) (string, []byte, error) {
+	return c.read(sel, imageMimeTypes)
+}
+
+// read asks the owner of sel for the first of mimes it offers, using a
+// fresh connection so the serving one can answer when the owner is us.
+func (c *wlClipboard) read(sel Selection, mimes []string) (string, []byte, error) {
+	s, err := dialDataSession()
+	if err != nil {
+		return "", nil, err
+	}
+	defer s.conn.Close()
+	if err := s.supports(sel); err != nil {
+		return "", nil, err
+	}
+	event := s.proto.selection
+	if sel == Primary {
+		event = s.proto.primary
+	}
+
+	offers := make(map[uint32][]string)
+	var selection uint32
+	s.conn.Handle(s.device, func(opcode uint16, ev *wayland.Event) {
+		switch opcode {
+		case 0: // data_offer
+			offer := ev.Uint()
+			offers[offer] = nil
+			s.conn.Handle(offer, func(opcode uint16, ev *wayland.Event) {
+				if opcode == 0 { // offer
+					offers[offer] = append(offers[offer], ev.String())
+				}
+			})
+		case event:
+			selection = ev.Uint()
+		}
+	})
+	if err := s.conn.Roundtrip(); err != nil {
+		return "", nil, err
+	}
+	if selection == 0 {
+		return "", nil, fmt.Errorf("%s is empty", sel)
+	}
+	idx := slices.IndexFunc(mimes, func(mime string) bool { return slices.Contains(offers[selection], mime) })
+	if idx < 0 {
+		return "", nil, fmt.Errorf("%s target unavailable", sel)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+	err = s.conn.Send(selection, s.proto.offerReceive, (&wayland.Request{}).String(mimes[idx]).FD(int(w.Fd())))
+	w.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	_ = r.SetReadDeadline(time.Now().Add(wlReadTimeout))
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("read clipboard: %w", err)
+	}
+	return mimes[idx], data, nil
+}
+
+// watch follows the device's selection events on a connection of its own.
+// The compositor announces the current selection as soon as the device is
+// bound, which is not a change and is skipped.
+func (c *wlClipboard) watch(ctx context.Context, sel Selection, changed func()) error {
+	s, err := dialDataSession()
+	if err != nil {
+		return err
+	}
+	defer s.conn.Close()
+	if err := s.supports(sel); err != nil {
+		return err
+	}
+	event := s.proto.selection
+	if sel == Primary {
+		event = s.proto.primary
+	}
+	other := -1
+	if s.supports(Primary) == nil {
+		other = int(s.proto.primary)
+		if sel == Primary {
+			other = int(s.proto.selection)
+		}
+	}
+	var current uint32
+	announced := false
+	s.conn.Handle(s.device, func(opcode uint16, ev *wayland.Event) {
+		switch {
+		case opcode == 0: // data_offer
+		case opcode == event:
+			// Offers are only needed to tell changes apart; reads use
+			// a fresh connection.
+			offer := ev.Uint()
+			if current != 0 {
+				_ = s.conn.Send(current, s.proto.offerDestroy, nil)
+			}
+			current = offer
+			if announced && offer != 0 {
+				changed()
+			}
+			announced = true
+		case int(opcode) == s.proto.finished:
+			_ = s.conn.Close()
+		case int(opcode) == other:
+			// The other selection's offer is not ours to keep.
+			if offer := ev.Uint(); offer != 0 {
+				_ = s.conn.Send(offer, s.proto.offerDestroy, nil)
+			}
+		}
+	})
+	stop := context.AfterFunc(ctx, func() { s.conn.Close() })
+	defer stop()
+	for {
+		if err := s.conn.Dispatch(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+}
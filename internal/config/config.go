@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image/color"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/example/shineyshot/internal/theme"
@@ -14,14 +15,81 @@ type Notify struct {
 	Capture bool
 	Save    bool
 	Copy    bool
+	Upload  bool
+}
+
+// Profile holds a named set of session defaults, selected with --profile.
+type Profile struct {
+	SaveDir string
+	Pattern string
+	Color   string
+	Width   int
+	// Sound and Flash confirm each capture with a shutter sound and a
+	// brief flash over the captured area.
+	Sound bool
+	Flash bool
+}
+
+// Upload is a named upload destination, selected with upload -to. Its
+// fields mirror upload.Destination.
+type Upload struct {
+	Type      string
+	URL       string
+	Method    string
+	Field     string
+	Headers   []string
+	Response  string
+	ClientID  string
+	PublicURL string
+}
+
+// PaletteColor is a named drawing color, in any form the -color flags take.
+type PaletteColor struct {
+	Name  string
+	Color string
 }
 
 // Config holds the application configuration.
 type Config struct {
-	Theme   string
-	SaveDir string
-	Notify  Notify
-	Themes  map[string]*theme.Theme
+	Theme    string
+	SaveDir  string
+	Notify   Notify
+	Themes   map[string]*theme.Theme
+	Profiles map[string]*Profile
+	// Uploads are the [upload.NAME] destinations and DefaultUpload the one
+	// used when none is named.
+	Uploads       map[string]*Upload
+	DefaultUpload string
+	// Palette, when set, replaces the built-in drawing colors, in order.
+	Palette []PaletteColor
+	// Widths, when set, replaces the built-in stroke widths.
+	Widths []int
+	// Color and Width are the stroke the editor and interactive mode start
+	// with, and Pattern names their automatic saves.
+	Color   string
+	Width   int
+	Pattern string
+	// Shortcuts maps editor action names to comma separated key
+	// combinations such as "Ctrl+Shift+C", replacing the built-in keys. An
+	// empty value leaves the action without a key.
+	Shortcuts map[string]string
+	// Hotkeys maps hotkey action names to accelerators such as "Shift+Print".
+	// An empty accelerator disables the action.
+	Hotkeys map[string]string
+	// Fonts lists fonts, as paths or file names, tried before the built-in
+	// fallbacks for characters Go Regular lacks.
+	Fonts []string
+	// TabStorage is how the editor keeps inactive tabs: memory, compress
+	// or disk.
+	TabStorage string
+	// WebPQuality is the WebP quality, 0 to 100 or lossless.
+	WebPQuality string
+	// AVIFQuality is the AVIF quality, 0 to 100, and AVIFSpeed the
+	// encoder speed, 0 to 10.
+	AVIFQuality string
+	AVIFSpeed   string
+	// JPEGQuality is the JPEG quality, 1 to 100.
+	JPEGQuality string
 }
 
 // New creates a new Config with defaults.
@@ -32,8 +100,13 @@ func New() *Config {
 			Capture: false,
 			Save:    false,
 			Copy:    false,
+			Upload:  false,
 		},
-		Themes: make(map[string]*theme.Theme),
+		Themes:    make(map[string]*theme.Theme),
+		Profiles:  make(map[string]*Profile),
+		Uploads:   make(map[string]*Upload),
+		Shortcuts: make(map[string]string),
+		Hotkeys:   make(map[string]string),
 	}
 }
 
@@ -48,6 +121,43 @@ func (c *Config) String() string {
 	if c.SaveDir != "" {
 		fmt.Fprintf(&sb, "save_dir = %s\n", c.SaveDir)
 	}
+	if len(c.Fonts) > 0 {
+		fmt.Fprintf(&sb, "fonts = %s\n", strings.Join(c.Fonts, ", "))
+	}
+	if c.TabStorage != "" {
+		fmt.Fprintf(&sb, "tab_storage = %s\n", c.TabStorage)
+	}
+	if c.WebPQuality != "" {
+		fmt.Fprintf(&sb, "webp_quality = %s\n", c.WebPQuality)
+	}
+	if c.AVIFQuality != "" {
+		fmt.Fprintf(&sb, "avif_quality = %s\n", c.AVIFQuality)
+	}
+	if c.AVIFSpeed != "" {
+		fmt.Fprintf(&sb, "avif_speed = %s\n", c.AVIFSpeed)
+	}
+	if c.JPEGQuality != "" {
+		fmt.Fprintf(&sb, "jpeg_quality = %s\n", c.JPEGQuality)
+	}
+	if len(c.Widths) > 0 {
+		var ws []string
+		for _, w := range c.Widths {
+			ws = append(ws, strconv.Itoa(w))
+		}
+		fmt.Fprintf(&sb, "widths = %s\n", strings.Join(ws, ", "))
+	}
+	if c.Color != "" {
+		fmt.Fprintf(&sb, "color = %s\n", c.Color)
+	}
+	if c.Width > 0 {
+		fmt.Fprintf(&sb, "width = %d\n", c.Width)
+	}
+	if c.Pattern != "" {
+		fmt.Fprintf(&sb, "pattern = %s\n", c.Pattern)
+	}
+	if c.DefaultUpload != "" {
+		fmt.Fprintf(&sb, "upload = %s\n", c.DefaultUpload)
+	}
 	sb.WriteString("\n")
 
 	// Notify section
@@ -55,8 +165,18 @@ func (c *Config) String() string {
 	fmt.Fprintf(&sb, "capture = %v\n", c.Notify.Capture)
 	fmt.Fprintf(&sb, "save = %v\n", c.Notify.Save)
 	fmt.Fprintf(&sb, "copy = %v\n", c.Notify.Copy)
+	fmt.Fprintf(&sb, "upload = %v\n", c.Notify.Upload)
 	sb.WriteString("\n")
 
+	// Palette section, in order
+	if len(c.Palette) > 0 {
+		sb.WriteString("[palette]\n")
+		for _, p := range c.Palette {
+			fmt.Fprintf(&sb, "%s = %s\n", p.Name, p.Color)
+		}
+		sb.WriteString("\n")
+	}
+
 	// Themes sections
 	// Sort keys for deterministic output
 	var themeNames []string
@@ -90,6 +210,94 @@ func (c *Config) String() string {
 		sb.WriteString("\n")
 	}
 
+	// Profile sections
+	var profileNames []string
+	for name := range c.Profiles {
+		profileNames = append(profileNames, name)
+	}
+	sort.Strings(profileNames)
+
+	for _, name := range profileNames {
+		p := c.Profiles[name]
+		fmt.Fprintf(&sb, "[profile.%s]\n", name)
+		if p.SaveDir != "" {
+			fmt.Fprintf(&sb, "save_dir = %s\n", p.SaveDir)
+		}
+		if p.Pattern != "" {
+			fmt.Fprintf(&sb, "pattern = %s\n", p.Pattern)
+		}
+		if p.Color != "" {
+			fmt.Fprintf(&sb, "color = %s\n", p.Color)
+		}
+		if p.Width > 0 {
+			fmt.Fprintf(&sb, "width = %d\n", p.Width)
+		}
+		if p.Sound {
+			sb.WriteString("sound = true\n")
+		}
+		if p.Flash {
+			sb.WriteString("flash = true\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// Upload sections
+	var uploadNames []string
+	for name := range c.Uploads {
+		uploadNames = append(uploadNames, name)
+	}
+	sort.Strings(uploadNames)
+
+	for _, name := range uploadNames {
+		u := c.Uploads[name]
+		fmt.Fprintf(&sb, "[upload.%s]\n", name)
+		for _, kv := range [][2]string{
+			{"type", u.Type},
+			{"url", u.URL},
+			{"method", u.Method},
+			{"field", u.Field},
+			{"response", u.Response},
+			{"client_id", u.ClientID},
+			{"public_url", u.PublicURL},
+		} {
+			if kv[1] != "" {
+				fmt.Fprintf(&sb, "%s = %s\n", kv[0], kv[1])
+			}
+		}
+		for _, h := range u.Headers {
+			fmt.Fprintf(&sb, "header = %s\n", h)
+		}
+		sb.WriteString("\n")
+	}
+
+	// Shortcuts section
+	if len(c.Shortcuts) > 0 {
+		var actions []string
+		for action := range c.Shortcuts {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		sb.WriteString("[shortcuts]\n")
+		for _, action := range actions {
+			fmt.Fprintf(&sb, "%s = %s\n", action, c.Shortcuts[action])
+		}
+		sb.WriteString("\n")
+	}
+
+	// Hotkeys section
+	if len(c.Hotkeys) > 0 {
+		var actions []string
+		for action := range c.Hotkeys {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		sb.WriteString("[hotkeys]\n")
+		for _, action := range actions {
+			fmt.Fprintf(&sb, "%s = %s\n", action, c.Hotkeys[action])
+		}
+		sb.WriteString("\n")
+	}
+
 	return sb.String()
 }
 
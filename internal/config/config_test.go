@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -56,6 +57,27 @@ Foreground = #FFFFFF
 func TestCircular(t *testing.T) {
 	input := `theme = dark
 save_dir = /home/user/shots
+fonts = NotoColorEmoji.ttf, /opt/fonts/Extra.otf
+tab_storage = disk
+webp_quality = lossless
+avif_quality = 45
+avif_speed = 4
+jpeg_quality = 85
+upload = share
+widths = 1, 3, 5, 9
+color = blue
+width = 5
+pattern = shot-{date}-{time}.png
+shortcut save = Ctrl+Alt+S
+
+[palette]
+Ink = #202020
+Signal Orange = #FF7F00
+Mint = #3EB489CC
+
+[shortcuts]
+copy = Ctrl+Shift+C, Ctrl+Insert
+debug =
 
 [notify]
 capture = true
@@ -66,6 +88,25 @@ copy = false
 Name = custom
 Background = #000000
 Foreground = #FFFFFF
+
+[profile.docs]
+save_dir = ~/docs/shots
+pattern = docs-{timestamp}.png
+color = red
+width = 6
+sound = true
+
+[upload.share]
+type = http
+url = https://img.example.com/api/upload?name={name}
+field = file
+header = Authorization: Bearer abc=
+header = X-Album: shots
+response = data.url
+
+[hotkeys]
+region = Ctrl+Shift+4
+copy =
 `
 	// 1. Parse initial input
 	cfg, err := Parse(strings.NewReader(input))
@@ -89,6 +130,21 @@ Foreground = #FFFFFF
 	if cfg.SaveDir != cfg2.SaveDir {
 		t.Errorf("SaveDir mismatch: %q vs %q", cfg.SaveDir, cfg2.SaveDir)
 	}
+	if !reflect.DeepEqual(cfg.Fonts, []string{"NotoColorEmoji.ttf", "/opt/fonts/Extra.otf"}) || !reflect.DeepEqual(cfg.Fonts, cfg2.Fonts) {
+		t.Errorf("Fonts mismatch: %q vs %q", cfg.Fonts, cfg2.Fonts)
+	}
+	if cfg.TabStorage != "disk" || cfg.TabStorage != cfg2.TabStorage {
+		t.Errorf("TabStorage mismatch: %q vs %q", cfg.TabStorage, cfg2.TabStorage)
+	}
+	if cfg.WebPQuality != "lossless" || cfg.WebPQuality != cfg2.WebPQuality {
+		t.Errorf("WebPQuality mismatch: %q vs %q", cfg.WebPQuality, cfg2.WebPQuality)
+	}
+	if cfg.AVIFQuality != "45" || cfg.AVIFSpeed != "4" || cfg.AVIFQuality != cfg2.AVIFQuality || cfg.AVIFSpeed != cfg2.AVIFSpeed {
+		t.Errorf("AVIF mismatch: %q/%q vs %q/%q", cfg.AVIFQuality, cfg.AVIFSpeed, cfg2.AVIFQuality, cfg2.AVIFSpeed)
+	}
+	if cfg.JPEGQuality != "85" || cfg.JPEGQuality != cfg2.JPEGQuality {
+		t.Errorf("JPEGQuality mismatch: %q vs %q", cfg.JPEGQuality, cfg2.JPEGQuality)
+	}
 	if cfg.Notify != cfg2.Notify {
 		t.Errorf("Notify mismatch: %+v vs %+v", cfg.Notify, cfg2.Notify)
 	}
@@ -102,4 +158,62 @@ Foreground = #FFFFFF
 	if t1.Background != t2.Background {
 		t.Errorf("Theme background mismatch: %v vs %v", t1.Background, t2.Background)
 	}
+
+	p1 := cfg.Profiles["docs"]
+	p2 := cfg2.Profiles["docs"]
+	if p1 == nil || p2 == nil {
+		t.Fatalf("Profile missing in one config")
+	}
+	if *p1 != *p2 {
+		t.Errorf("Profile mismatch: %+v vs %+v", *p1, *p2)
+	}
+	if p1.Width != 6 || p1.Pattern != "docs-{timestamp}.png" || !p1.Sound || p1.Flash {
+		t.Errorf("Unexpected profile values: %+v", *p1)
+	}
+
+	u1 := cfg.Uploads["share"]
+	u2 := cfg2.Uploads["share"]
+	if u1 == nil || u2 == nil {
+		t.Fatalf("Upload destination missing in one config")
+	}
+	if !reflect.DeepEqual(u1, u2) {
+		t.Errorf("Upload mismatch: %+v vs %+v", *u1, *u2)
+	}
+	if u1.URL != "https://img.example.com/api/upload?name={name}" || !reflect.DeepEqual(u1.Headers, []string{"Authorization: Bearer abc=", "X-Album: shots"}) {
+		t.Errorf("Unexpected upload values: %+v", *u1)
+	}
+	if cfg.DefaultUpload != "share" || cfg2.DefaultUpload != "share" {
+		t.Errorf("DefaultUpload mismatch: %q vs %q", cfg.DefaultUpload, cfg2.DefaultUpload)
+	}
+
+	if !reflect.DeepEqual(cfg.Widths, []int{1, 3, 5, 9}) || !reflect.DeepEqual(cfg.Widths, cfg2.Widths) {
+		t.Errorf("Widths mismatch: %v vs %v", cfg.Widths, cfg2.Widths)
+	}
+	if cfg.Color != "blue" || cfg.Width != 5 || cfg.Pattern != "shot-{date}-{time}.png" ||
+		cfg.Color != cfg2.Color || cfg.Width != cfg2.Width || cfg.Pattern != cfg2.Pattern {
+		t.Errorf("Stroke defaults mismatch: %q/%d/%q vs %q/%d/%q", cfg.Color, cfg.Width, cfg.Pattern, cfg2.Color, cfg2.Width, cfg2.Pattern)
+	}
+	wantPalette := []PaletteColor{{"Ink", "#202020"}, {"Signal Orange", "#FF7F00"}, {"Mint", "#3EB489CC"}}
+	if !reflect.DeepEqual(cfg.Palette, wantPalette) || !reflect.DeepEqual(cfg.Palette, cfg2.Palette) {
+		t.Errorf("Palette mismatch: %v vs %v", cfg.Palette, cfg2.Palette)
+	}
+	if !reflect.DeepEqual(cfg.Shortcuts, cfg2.Shortcuts) || cfg.Shortcuts["copy"] != "Ctrl+Shift+C, Ctrl+Insert" {
+		t.Errorf("Shortcuts mismatch: %v vs %v", cfg.Shortcuts, cfg2.Shortcuts)
+	}
+	if cfg.Shortcuts["save"] != "Ctrl+Alt+S" {
+		t.Errorf("Expected root shortcut line for save, got %q", cfg.Shortcuts["save"])
+	}
+	if accel, ok := cfg.Shortcuts["debug"]; !ok || accel != "" {
+		t.Errorf("Expected debug shortcut to be disabled, got %q (set %v)", accel, ok)
+	}
+
+	if !reflect.DeepEqual(cfg.Hotkeys, cfg2.Hotkeys) {
+		t.Errorf("Hotkeys mismatch: %v vs %v", cfg.Hotkeys, cfg2.Hotkeys)
+	}
+	if accel, ok := cfg.Hotkeys["copy"]; !ok || accel != "" {
+		t.Errorf("Expected copy hotkey to be disabled, got %q (set %v)", accel, ok)
+	}
+	if cfg.Hotkeys["region"] != "Ctrl+Shift+4" {
+		t.Errorf("Unexpected region hotkey %q", cfg.Hotkeys["region"])
+	}
 }
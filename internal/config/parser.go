@@ -20,6 +20,8 @@ func Parse(r io.Reader) (*Config, error) {
 	// Context for parsing
 	var currentSection string
 	var currentTheme *theme.Theme
+	var currentProfile *Profile
+	var currentUpload *Upload
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -31,6 +33,8 @@ func Parse(r io.Reader) (*Config, error) {
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
 			currentSection = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
 			currentTheme = nil
+			currentProfile = nil
+			currentUpload = nil
 
 			if strings.HasPrefix(currentSection, "theme.") {
 				themeName := strings.TrimPrefix(currentSection, "theme.")
@@ -39,6 +43,16 @@ func Parse(r io.Reader) (*Config, error) {
 				currentTheme.Name = themeName
 				cfg.Themes[themeName] = currentTheme
 			}
+			if strings.HasPrefix(currentSection, "profile.") {
+				profileName := strings.TrimPrefix(currentSection, "profile.")
+				currentProfile = &Profile{}
+				cfg.Profiles[profileName] = currentProfile
+			}
+			if strings.HasPrefix(currentSection, "upload.") {
+				uploadName := strings.TrimPrefix(currentSection, "upload.")
+				currentUpload = &Upload{}
+				cfg.Uploads[uploadName] = currentUpload
+			}
 			continue
 		}
 
@@ -64,6 +78,18 @@ func Parse(r io.Reader) (*Config, error) {
 			if err := setThemeField(currentTheme, key, value); err != nil {
 				return nil, fmt.Errorf("error in section [%s]: %w", currentSection, err)
 			}
+		} else if currentProfile != nil {
+			if err := setProfileField(currentProfile, key, value); err != nil {
+				return nil, fmt.Errorf("error in section [%s]: %w", currentSection, err)
+			}
+		} else if currentUpload != nil {
+			setUploadField(currentUpload, key, value)
+		} else if currentSection == "palette" {
+			cfg.Palette = append(cfg.Palette, PaletteColor{Name: key, Color: value})
+		} else if currentSection == "shortcuts" {
+			cfg.Shortcuts[strings.ToLower(key)] = value
+		} else if currentSection == "hotkeys" {
+			cfg.Hotkeys[strings.ToLower(key)] = value
 		} else if currentSection == "notify" {
 			if err := setNotifyField(&cfg.Notify, key, value); err != nil {
 				return nil, fmt.Errorf("error in section [notify]: %w", err)
@@ -80,11 +106,58 @@ func Parse(r io.Reader) (*Config, error) {
 }
 
 func setRootField(cfg *Config, key, value string) error {
+	// "shortcut save = Ctrl+Shift+S" is the one-line form of a [shortcuts]
+	// entry.
+	if action, ok := strings.CutPrefix(strings.ToLower(key), "shortcut "); ok {
+		cfg.Shortcuts[strings.TrimSpace(action)] = value
+		return nil
+	}
 	switch strings.ToLower(key) {
 	case "theme":
 		cfg.Theme = value
 	case "save_dir":
 		cfg.SaveDir = value
+	case "fonts":
+		cfg.Fonts = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Fonts = append(cfg.Fonts, name)
+			}
+		}
+	case "tab_storage":
+		cfg.TabStorage = value
+	case "webp_quality":
+		cfg.WebPQuality = value
+	case "avif_quality":
+		cfg.AVIFQuality = value
+	case "avif_speed":
+		cfg.AVIFSpeed = value
+	case "jpeg_quality":
+		cfg.JPEGQuality = value
+	case "widths":
+		cfg.Widths = nil
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			w, err := strconv.Atoi(field)
+			if err != nil || w < 1 {
+				return fmt.Errorf("invalid width %q in %s", field, key)
+			}
+			cfg.Widths = append(cfg.Widths, w)
+		}
+	case "color":
+		cfg.Color = value
+	case "width":
+		w, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer for key %s: %w", key, err)
+		}
+		cfg.Width = w
+	case "pattern":
+		cfg.Pattern = value
+	case "upload":
+		cfg.DefaultUpload = value
 	}
 	return nil
 }
@@ -101,10 +174,64 @@ func setNotifyField(n *Notify, key, value string) error {
 		n.Save = b
 	case "copy":
 		n.Copy = b
+	case "upload":
+		n.Upload = b
 	}
 	return nil
 }
 
+func setProfileField(p *Profile, key, value string) error {
+	switch strings.ToLower(key) {
+	case "save_dir":
+		p.SaveDir = value
+	case "pattern":
+		p.Pattern = value
+	case "color":
+		p.Color = value
+	case "width":
+		w, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer for key %s: %w", key, err)
+		}
+		p.Width = w
+	case "sound":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean for key %s: %w", key, err)
+		}
+		p.Sound = b
+	case "flash":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean for key %s: %w", key, err)
+		}
+		p.Flash = b
+	}
+	return nil
+}
+
+func setUploadField(u *Upload, key, value string) {
+	switch strings.ToLower(key) {
+	case "type":
+		u.Type = value
+	case "url":
+		u.URL = value
+	case "method":
+		u.Method = value
+	case "field":
+		u.Field = value
+	case "header":
+		// Repeated header keys each add a header.
+		u.Headers = append(u.Headers, value)
+	case "response":
+		u.Response = value
+	case "client_id":
+		u.ClientID = value
+	case "public_url":
+		u.PublicURL = value
+	}
+}
+
 func setThemeField(t *theme.Theme, key, value string) error {
 	if strings.EqualFold(key, "Name") {
 		t.Name = value
@@ -0,0 +1,191 @@
+package fonts
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+)
+
+// colorBitmaps reads PNG glyph images from a font's CBLC and CBDT tables,
+// the color bitmap format of Noto Color Emoji. Only the strike with the
+// largest ppem is used; glyphs are scaled down from it.
+type colorBitmaps struct {
+	cbdt   []byte
+	ppem   int
+	ranges []bitmapRange
+}
+
+// bitmapRange is one CBLC index subtable: the location of the images of a
+// run of consecutive glyphs.
+type bitmapRange struct {
+	first, last uint16
+	indexFormat uint16
+	imageFormat uint16
+	imageOffset int
+	// sub is the subtable after its 8 byte header.
+	sub []byte
+}
+
+// glyphMetrics places a bitmap relative to the pen: bearingX is the left
+// edge and bearingY the top edge above the baseline, in strike pixels.
+type glyphMetrics struct {
+	width, height      int
+	bearingX, bearingY int
+	advance            int
+}
+
+// parseColorBitmaps returns the largest strike of the tables, or nil if
+// they hold none.
+func parseColorBitmaps(cblc, cbdt []byte) *colorBitmaps {
+	numSizes := int(u32(cblc, 4))
+	best := -1
+	for i := 0; i < numSizes; i++ {
+		rec := 8 + i*48
+		if rec+48 > len(cblc) {
+			break
+		}
+		if best < 0 || cblc[rec+44] > cblc[8+best*48+44] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	rec := 8 + best*48
+	c := &colorBitmaps{
+		cbdt: cbdt,
+		ppem: int(cblc[rec+44]),
+	}
+	array := int(u32(cblc, rec))
+	for i := 0; i < int(u32(cblc, rec+8)); i++ {
+		entry := array + i*8
+		sub := array + int(u32(cblc, entry+4))
+		if entry+8 > len(cblc) || sub+8 > len(cblc) {
+			break
+		}
+		c.ranges = append(c.ranges, bitmapRange{
+			first:       u16(cblc, entry),
+			last:        u16(cblc, entry+2),
+			indexFormat: u16(cblc, sub),
+			imageFormat: u16(cblc, sub+2),
+			imageOffset: int(u32(cblc, sub+4)),
+			sub:         cblc[sub+8:],
+		})
+	}
+	if c.ppem == 0 || len(c.ranges) == 0 {
+		return nil
+	}
+	return c
+}
+
+// glyph returns the metrics and decoded image of glyph gid.
+func (c *colorBitmaps) glyph(gid uint16) (glyphMetrics, image.Image, bool) {
+	for _, r := range c.ranges {
+		if gid < r.first || gid > r.last {
+			continue
+		}
+		start, end, m, ok := r.locate(gid)
+		if !ok || start < 0 || end > len(c.cbdt) || start >= end {
+			return glyphMetrics{}, nil, false
+		}
+		return decodeBitmap(r.imageFormat, c.cbdt[start:end], m)
+	}
+	return glyphMetrics{}, nil, false
+}
+
+// locate returns where glyph gid's image data lies in CBDT, and the metrics
+// index formats 2 and 5 share between all their glyphs.
+func (r bitmapRange) locate(gid uint16) (start, end int, shared glyphMetrics, ok bool) {
+	i := int(gid - r.first)
+	switch r.indexFormat {
+	case 1:
+		start, end = int(u32(r.sub, i*4)), int(u32(r.sub, i*4+4))
+	case 3:
+		start, end = int(u16(r.sub, i*2)), int(u16(r.sub, i*2+2))
+	case 2:
+		size := int(u32(r.sub, 0))
+		shared = bigMetrics(r.sub[min(4, len(r.sub)):])
+		start, end = i*size, (i+1)*size
+	case 4:
+		n, found := int(u32(r.sub, 0)), false
+		for j := 0; j < n && !found; j++ {
+			if u16(r.sub, 4+j*4) == gid {
+				start, end = int(u16(r.sub, 4+j*4+2)), int(u16(r.sub, 4+j*4+6))
+				found = true
+			}
+		}
+		if !found {
+			return 0, 0, shared, false
+		}
+	case 5:
+		size := int(u32(r.sub, 0))
+		shared = bigMetrics(r.sub[min(4, len(r.sub)):])
+		n := int(u32(r.sub, 12))
+		i = -1
+		for j := 0; j < n; j++ {
+			if u16(r.sub, 16+j*2) == gid {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return 0, 0, shared, false
+		}
+		start, end = i*size, (i+1)*size
+	default:
+		return 0, 0, shared, false
+	}
+	return r.imageOffset + start, r.imageOffset + end, shared, true
+}
+
+// decodeBitmap decodes CBDT image formats 17, 18 and 19, which hold PNG
+// data with small, big or no embedded metrics.
+func decodeBitmap(format uint16, data []byte, m glyphMetrics) (glyphMetrics, image.Image, bool) {
+	var pngData []byte
+	switch format {
+	case 17:
+		if len(data) < 9 {
+			return m, nil, false
+		}
+		m = glyphMetrics{
+			height:   int(data[0]),
+			width:    int(data[1]),
+			bearingX: int(int8(data[2])),
+			bearingY: int(int8(data[3])),
+			advance:  int(data[4]),
+		}
+		pngData = data[9:]
+	case 18:
+		if len(data) < 12 {
+			return m, nil, false
+		}
+		m = bigMetrics(data)
+		pngData = data[12:]
+	case 19:
+		if len(data) < 4 {
+			return m, nil, false
+		}
+		pngData = data[4:]
+	default:
+		return m, nil, false
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		return m, nil, false
+	}
+	return m, img, true
+}
+
+// bigMetrics reads the horizontal half of a bigGlyphMetrics record.
+func bigMetrics(b []byte) glyphMetrics {
+	if len(b) < 5 {
+		return glyphMetrics{}
+	}
+	return glyphMetrics{
+		height:   int(b[0]),
+		width:    int(b[1]),
+		bearingX: int(int8(b[2])),
+		bearingY: int(int8(b[3])),
+		advance:  int(b[4]),
+	}
+}
@@ -0,0 +1,128 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// colorTables builds CBLC and CBDT tables with one 100 ppem strike holding
+// glyphs 3 and 4 as format 17 images, indexed by a format 1 subtable.
+func colorTables(t *testing.T, imgs ...image.Image) (cblc, cbdt []byte) {
+	t.Helper()
+	var data bytes.Buffer
+	data.Write([]byte{0, 3, 0, 0}) // version
+	offsets := []uint32{uint32(data.Len())}
+	for _, img := range imgs {
+		var p bytes.Buffer
+		if err := png.Encode(&p, img); err != nil {
+			t.Fatal(err)
+		}
+		b := img.Bounds()
+		data.Write([]byte{byte(b.Dy()), byte(b.Dx()), 2, 80, byte(b.Dx() + 4)})
+		_ = binary.Write(&data, binary.BigEndian, uint32(p.Len()))
+		data.Write(p.Bytes())
+		offsets = append(offsets, uint32(data.Len()))
+	}
+
+	var loc bytes.Buffer
+	be := func(v any) { _ = binary.Write(&loc, binary.BigEndian, v) }
+	be(uint16(3))
+	be(uint16(0))
+	be(uint32(1)) // numSizes
+	size := make([]byte, 48)
+	binary.BigEndian.PutUint32(size[0:], 8+48) // indexSubTableArrayOffset
+	binary.BigEndian.PutUint32(size[8:], 1)    // numberOfIndexSubTables
+	binary.BigEndian.PutUint16(size[40:], 3)
+	binary.BigEndian.PutUint16(size[42:], uint16(2+len(imgs)))
+	size[44], size[45], size[46] = 100, 100, 32
+	loc.Write(size)
+	be(uint16(3))
+	be(uint16(2 + len(imgs)))
+	be(uint32(8)) // subtable follows the one entry array
+	be(uint16(1)) // index format
+	be(uint16(17))
+	be(uint32(0))
+	for _, o := range offsets {
+		be(o)
+	}
+	return loc.Bytes(), data.Bytes()
+}
+
+func TestColorBitmaps(t *testing.T) {
+	red := image.NewRGBA(image.Rect(0, 0, 10, 8))
+	blue := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	for i := range red.Pix {
+		red.Pix[i] = []byte{0xff, 0, 0, 0xff}[i%4]
+	}
+	for i := range blue.Pix {
+		blue.Pix[i] = []byte{0, 0, 0xff, 0xff}[i%4]
+	}
+	c := parseColorBitmaps(colorTables(t, red, blue))
+	if c == nil || c.ppem != 100 {
+		t.Fatalf("parseColorBitmaps = %+v, want a 100 ppem strike", c)
+	}
+
+	m, img, ok := c.glyph(4)
+	if !ok {
+		t.Fatal("glyph 4 not found")
+	}
+	want := glyphMetrics{width: 6, height: 6, bearingX: 2, bearingY: 80, advance: 10}
+	if m != want {
+		t.Errorf("metrics = %+v, want %+v", m, want)
+	}
+	if got := color.RGBAModel.Convert(img.At(3, 3)); got != (color.RGBA{0, 0, 0xff, 0xff}) {
+		t.Errorf("glyph 4 pixel = %v, want blue", got)
+	}
+	if _, _, ok := c.glyph(5); ok {
+		t.Error("glyph 5 is outside the strike but was found")
+	}
+}
+
+func TestScaledColorGlyph(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for i := range img.Pix {
+		img.Pix[i] = []byte{0, 0x80, 0, 0xff}[i%4]
+	}
+	c := parseColorBitmaps(colorTables(t, img))
+	g := c.scaled(3, 20)
+	if g == nil {
+		t.Fatal("glyph 3 not found")
+	}
+	// 100 ppem down to 20: bearings 2,80 become 0,16 and the advance 104
+	// becomes 20.8 pixels.
+	if want := image.Rect(0, -16, 20, 4); g.img.Bounds() != want {
+		t.Errorf("bounds = %v, want %v", g.img.Bounds(), want)
+	}
+	if want := fixed.Int26_6(math.Round(20.8 * 64)); g.advance != want {
+		t.Errorf("advance = %v, want %v", g.advance, want)
+	}
+	if got := g.img.RGBAAt(10, -6); got != (color.RGBA{0, 0x80, 0, 0xff}) {
+		t.Errorf("scaled pixel = %v, want the glyph's green", got)
+	}
+}
+
+func TestIgnorableRunesHaveNoWidth(t *testing.T) {
+	f, err := NewFace(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []rune{0x200d, 0xfe0f} {
+		if adv, ok := f.GlyphAdvance(r); !ok || adv != 0 {
+			t.Errorf("GlyphAdvance(%U) = %v, %v; want 0, true", r, adv, ok)
+		}
+	}
+	plain, _ := f.GlyphAdvance('a')
+	if plain <= 0 {
+		t.Fatalf("GlyphAdvance('a') = %v", plain)
+	}
+	if got := f.Kern('a', 'b'); got > fixed.I(1) || got < -fixed.I(1) {
+		t.Errorf("Kern('a', 'b') = %v", got)
+	}
+}
@@ -0,0 +1,256 @@
+package fonts
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Face is a font.Face that takes each rune from the first font of the chain
+// that has it: Go Regular, then the fallbacks. It works with font.Drawer,
+// which draws color emoji as silhouettes in the drawer's color; Draw paints
+// them in their own colors.
+type Face struct {
+	size    float64
+	primary *source
+
+	mu         sync.Mutex
+	buf        sfnt.Buffer
+	outlines   map[*source]font.Face
+	picks      map[rune]*source
+	colors     map[rune]*colorGlyph
+	generation int
+}
+
+// colorGlyph is a color bitmap scaled to the face size, with bounds
+// relative to the pen position.
+type colorGlyph struct {
+	img     *image.RGBA
+	advance fixed.Int26_6
+}
+
+// NewFace returns a face of the given size in points at 72 DPI, so the size
+// is also the height in pixels.
+func NewFace(size float64) (*Face, error) {
+	p, err := loadPrimary()
+	if err != nil {
+		return nil, err
+	}
+	f := &Face{
+		size:     size,
+		primary:  p,
+		outlines: map[*source]font.Face{},
+		picks:    map[rune]*source{},
+		colors:   map[rune]*colorGlyph{},
+	}
+	if _, err := f.outline(p); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ignorable reports whether r only changes how its neighbors are shown:
+// variation selectors and joiners. Without shaping they are dropped rather
+// than drawn as boxes.
+func ignorable(r rune) bool {
+	return r == 0x200c || r == 0x200d || (r >= 0xfe00 && r <= 0xfe0f) || (r >= 0xe0100 && r <= 0xe01ef)
+}
+
+// pick returns the font to draw r with, or nil for ignorable runes. Runes
+// no font has come from Go Regular and show as its missing glyph box.
+// f.mu must be held.
+func (f *Face) pick(r rune) *source {
+	if ignorable(r) {
+		return nil
+	}
+	if has(f.primary, &f.buf, r) {
+		return f.primary
+	}
+	chain, gen := fallbackChain()
+	if gen != f.generation {
+		f.picks = map[rune]*source{}
+		f.colors = map[rune]*colorGlyph{}
+		f.generation = gen
+	}
+	if src, ok := f.picks[r]; ok {
+		return src
+	}
+	src := f.primary
+	for _, s := range chain {
+		if has(s, &f.buf, r) {
+			src = s
+			break
+		}
+	}
+	f.picks[r] = src
+	return src
+}
+
+func has(s *source, buf *sfnt.Buffer, r rune) bool {
+	gid, err := s.font.GlyphIndex(buf, r)
+	return err == nil && gid != 0
+}
+
+// outline returns the face drawing src's outlines at f's size.
+// f.mu must be held.
+func (f *Face) outline(src *source) (font.Face, error) {
+	if face, ok := f.outlines[src]; ok {
+		return face, nil
+	}
+	face, err := opentype.NewFace(src.font, &opentype.FaceOptions{Size: f.size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	f.outlines[src] = face
+	return face, nil
+}
+
+// color returns r from a color font scaled to f's size, or nil if the
+// font has no bitmap for it. f.mu must be held.
+func (f *Face) color(src *source, r rune) *colorGlyph {
+	if g, ok := f.colors[r]; ok {
+		return g
+	}
+	var g *colorGlyph
+	if gid, err := src.font.GlyphIndex(&f.buf, r); err == nil {
+		g = src.color.scaled(uint16(gid), f.size)
+	}
+	f.colors[r] = g
+	return g
+}
+
+// scaled returns glyph gid scaled from the strike to size pixels per em,
+// or nil if the strike has no bitmap for it.
+func (c *colorBitmaps) scaled(gid uint16, size float64) *colorGlyph {
+	m, img, ok := c.glyph(gid)
+	if !ok {
+		return nil
+	}
+	s := size / float64(c.ppem)
+	x0, y0 := int(math.Round(float64(m.bearingX)*s)), -int(math.Round(float64(m.bearingY)*s))
+	w, h := max(1, int(math.Round(float64(m.width)*s))), max(1, int(math.Round(float64(m.height)*s)))
+	dst := image.NewRGBA(image.Rect(x0, y0, x0+w, y0+h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return &colorGlyph{img: dst, advance: fixed.Int26_6(math.Round(float64(m.advance) * s * 64))}
+}
+
+// glyph is Glyph, also reporting whether mask is a color image to draw
+// as is rather than a mask for the drawer's source.
+func (f *Face) glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, isColor, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	src := f.pick(r)
+	if src == nil {
+		return image.Rectangle{}, image.Transparent, image.Point{}, 0, false, true
+	}
+	if src.color != nil {
+		if g := f.color(src, r); g != nil {
+			b := g.img.Bounds()
+			return b.Add(image.Pt(dot.X.Round(), dot.Y.Round())), g.img, b.Min, g.advance, true, true
+		}
+		src = f.primary
+	}
+	face, err := f.outline(src)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false, false
+	}
+	dr, mask, maskp, advance, ok = face.Glyph(dot, r)
+	return dr, mask, maskp, advance, false, ok
+}
+
+// Glyph implements font.Face.
+func (f *Face) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	dr, mask, maskp, advance, _, ok = f.glyph(dot, r)
+	return
+}
+
+// GlyphBounds implements font.Face.
+func (f *Face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	src := f.pick(r)
+	if src == nil {
+		return fixed.Rectangle26_6{}, 0, true
+	}
+	if src.color != nil {
+		if g := f.color(src, r); g != nil {
+			b := g.img.Bounds()
+			return fixed.R(b.Min.X, b.Min.Y, b.Max.X, b.Max.Y), g.advance, true
+		}
+		src = f.primary
+	}
+	face, err := f.outline(src)
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return face.GlyphBounds(r)
+}
+
+// GlyphAdvance implements font.Face.
+func (f *Face) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	_, advance, ok = f.GlyphBounds(r)
+	return advance, ok
+}
+
+// Kern implements font.Face. Runes from different fonts are not kerned.
+func (f *Face) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	src := f.pick(r0)
+	if src == nil || src.color != nil || f.pick(r1) != src {
+		return 0
+	}
+	face, err := f.outline(src)
+	if err != nil {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+
+// Metrics implements font.Face, returning Go Regular's metrics so line
+// height does not change with the runes drawn.
+func (f *Face) Metrics() font.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	face, err := f.outline(f.primary)
+	if err != nil {
+		return font.Metrics{}
+	}
+	return face.Metrics()
+}
+
+// Close implements font.Face.
+func (f *Face) Close() error { return nil }
+
+// Draw draws s like d.DrawString, advancing d.Dot, but paints color emoji
+// in their own colors when d.Face is a *Face.
+func Draw(d *font.Drawer, s string) {
+	f, ok := d.Face.(*Face)
+	if !ok {
+		d.DrawString(s)
+		return
+	}
+	prev := rune(-1)
+	for _, c := range s {
+		if prev >= 0 {
+			d.Dot.X += f.Kern(prev, c)
+		}
+		dr, mask, maskp, advance, isColor, ok := f.glyph(d.Dot, c)
+		if ok {
+			if isColor {
+				draw.Draw(d.Dst, dr, mask, maskp, draw.Over)
+			} else {
+				draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
+			}
+		}
+		d.Dot.X += advance
+		prev = c
+	}
+}
@@ -0,0 +1,229 @@
+// Package fonts provides the text faces shineyshot draws annotations with.
+// Go Regular is embedded and always used first; runes it lacks, such as
+// emoji and non-Latin scripts, are looked up in a chain of fallback fonts
+// found on the system, so they render instead of showing as boxes.
+package fonts
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// DefaultFallbacks lists the fonts tried, in order, for runes Go Regular
+// lacks. Names without a directory are searched for in the usual font
+// directories; fonts that are not installed are skipped.
+var DefaultFallbacks = []string{
+	"NotoSans-Regular.ttf",
+	"DejaVuSans.ttf",
+	"NotoSansCJK-Regular.ttc",
+	"DroidSansFallbackFull.ttf",
+	"NotoColorEmoji.ttf",
+	"NotoEmoji-Regular.ttf",
+	"Symbola.ttf",
+	"Arial Unicode.ttf",
+	"segoeui.ttf",
+	"seguisym.ttf",
+	"msyh.ttc",
+}
+
+// source is one font of the chain. Outline fonts are drawn through sfnt;
+// color is set for fonts whose glyphs are CBDT color bitmaps.
+type source struct {
+	name  string
+	font  *sfnt.Font
+	color *colorBitmaps
+}
+
+var (
+	primary    *source
+	primaryErr error
+	primaryOne sync.Once
+
+	mu         sync.Mutex
+	configured []string
+	fallbacks  []*source
+	loaded     bool
+	generation int
+)
+
+// loadPrimary parses the embedded Go Regular font.
+func loadPrimary() (*source, error) {
+	primaryOne.Do(func() {
+		var f *sfnt.Font
+		f, primaryErr = sfnt.Parse(goregular.TTF)
+		primary = &source{name: "Go Regular", font: f}
+	})
+	return primary, primaryErr
+}
+
+// SetFallbacks puts fonts ahead of DefaultFallbacks in the fallback chain.
+// Each entry is a font file path or a file name to search the font
+// directories for. It is usually called once with the configured fonts,
+// before any text is drawn.
+func SetFallbacks(names []string) {
+	mu.Lock()
+	defer mu.Unlock()
+	configured = append([]string(nil), names...)
+	fallbacks = nil
+	loaded = false
+	generation++
+}
+
+// ParseList splits a comma separated list of fonts, as read from the
+// configuration file or SHINEYSHOT_FONTS, dropping empty entries.
+func ParseList(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fallbackChain returns the fallback fonts that were found, loading them on
+// first use, and the chain's generation, which changes when SetFallbacks
+// is called.
+func fallbackChain() ([]*source, int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !loaded {
+		fallbacks = loadFallbacks(append(append([]string(nil), configured...), DefaultFallbacks...))
+		loaded = true
+	}
+	return fallbacks, generation
+}
+
+// loadFallbacks parses the named fonts, skipping ones that are missing,
+// unreadable or repeated.
+func loadFallbacks(names []string) []*source {
+	var index map[string]string
+	seen := map[string]bool{}
+	var out []*source
+	for _, name := range names {
+		path := name
+		if !strings.ContainsRune(name, filepath.Separator) && !strings.ContainsRune(name, '/') {
+			if index == nil {
+				index = indexFontDirs()
+			}
+			path = index[strings.ToLower(name)]
+		}
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		if src, err := loadSource(path); err == nil {
+			out = append(out, src)
+		}
+	}
+	return out
+}
+
+// loadSource parses the font file at path, the first font of a collection.
+func loadSource(path string) (*source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f *sfnt.Font
+	offset := 0
+	if strings.HasPrefix(string(data[:min(len(data), 4)]), "ttcf") {
+		c, err := sfnt.ParseCollection(data)
+		if err != nil {
+			return nil, err
+		}
+		if f, err = c.Font(0); err != nil {
+			return nil, err
+		}
+		offset = int(u32(data, 12))
+	} else if f, err = sfnt.Parse(data); err != nil {
+		return nil, err
+	}
+	src := &source{name: filepath.Base(path), font: f}
+	tables := tableDirectory(data, offset)
+	if cblc, cbdt := tables["CBLC"], tables["CBDT"]; cblc != nil && cbdt != nil {
+		src.color = parseColorBitmaps(cblc, cbdt)
+	}
+	return src, nil
+}
+
+// fontDirs returns the directories fonts are installed in on this system.
+func fontDirs() []string {
+	home, _ := os.UserHomeDir()
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = append(dirs, filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"))
+	case "darwin":
+		dirs = append(dirs, "/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts"))
+	default:
+		if data := os.Getenv("XDG_DATA_HOME"); data != "" {
+			dirs = append(dirs, filepath.Join(data, "fonts"))
+		}
+		dirs = append(dirs, filepath.Join(home, ".local", "share", "fonts"), filepath.Join(home, ".fonts"),
+			"/usr/local/share/fonts", "/usr/share/fonts")
+	}
+	return dirs
+}
+
+// indexFontDirs maps lower-cased font file names to their paths. Earlier
+// directories win, so fonts a user installed shadow system ones.
+func indexFontDirs() map[string]string {
+	index := map[string]string{}
+	for _, dir := range fontDirs() {
+		_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			name := strings.ToLower(d.Name())
+			if _, ok := index[name]; !ok {
+				index[name] = path
+			}
+			return nil
+		})
+	}
+	return index
+}
+
+// tableDirectory returns the tables of the font whose offset table starts
+// at offset in data, keyed by tag.
+func tableDirectory(data []byte, offset int) map[string][]byte {
+	if offset+12 > len(data) {
+		return nil
+	}
+	n := int(u16(data, offset+4))
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		rec := offset + 12 + i*16
+		if rec+16 > len(data) {
+			break
+		}
+		start, length := int(u32(data, rec+8)), int(u32(data, rec+12))
+		if start < 0 || length < 0 || start+length > len(data) {
+			continue
+		}
+		tables[string(data[rec:rec+4])] = data[start : start+length]
+	}
+	return tables
+}
+
+func u16(b []byte, i int) uint16 {
+	if i < 0 || i+2 > len(b) {
+		return 0
+	}
+	return uint16(b[i])<<8 | uint16(b[i+1])
+}
+
+func u32(b []byte, i int) uint32 {
+	if i < 0 || i+4 > len(b) {
+		return 0
+	}
+	return uint32(b[i])<<24 | uint32(b[i+1])<<16 | uint32(b[i+2])<<8 | uint32(b[i+3])
+}
@@ -0,0 +1,151 @@
+// Package hotkeys registers system-wide keyboard shortcuts using X11 key grabs
+// or the GlobalShortcuts desktop portal.
+package hotkeys
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Modifier is a set of modifier keys held with an accelerator.
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModControl
+	ModAlt
+	ModSuper
+)
+
+var modifierNames = []struct {
+	mod  Modifier
+	name string
+}{
+	{ModControl, "Ctrl"},
+	{ModAlt, "Alt"},
+	{ModShift, "Shift"},
+	{ModSuper, "Super"},
+}
+
+// Accelerator is a key together with the modifiers held while pressing it.
+type Accelerator struct {
+	Mods Modifier
+	// Key is the X keysym name, such as "Print", "a" or "F5".
+	Key string
+}
+
+// ParseAccelerator parses strings such as "Print", "Shift+Print" or
+// "ctrl+alt+s". Modifier and key names are case-insensitive.
+func ParseAccelerator(s string) (Accelerator, error) {
+	var acc Accelerator
+	parts := strings.Split(strings.TrimSpace(s), "+")
+	for idx, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return acc, fmt.Errorf("invalid accelerator %q", s)
+		}
+		if idx == len(parts)-1 {
+			name, ok := canonicalKey(part)
+			if !ok {
+				return acc, fmt.Errorf("unknown key %q in accelerator %q", part, s)
+			}
+			acc.Key = name
+			break
+		}
+		switch strings.ToLower(part) {
+		case "shift":
+			acc.Mods |= ModShift
+		case "ctrl", "control":
+			acc.Mods |= ModControl
+		case "alt", "mod1":
+			acc.Mods |= ModAlt
+		case "super", "win", "logo", "meta", "mod4":
+			acc.Mods |= ModSuper
+		default:
+			return acc, fmt.Errorf("unknown modifier %q in accelerator %q", part, s)
+		}
+	}
+	return acc, nil
+}
+
+// String formats the accelerator as ParseAccelerator accepts it.
+func (a Accelerator) String() string {
+	var parts []string
+	for _, m := range modifierNames {
+		if a.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, a.Key), "+")
+}
+
+// portalTrigger formats the accelerator in the XDG shortcuts syntax used for
+// the GlobalShortcuts preferred_trigger option.
+func (a Accelerator) portalTrigger() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{ModControl, "CTRL"}, {ModAlt, "ALT"}, {ModShift, "SHIFT"}, {ModSuper, "LOGO"}} {
+		if a.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, a.Key), "+")
+}
+
+// Binding ties a shortcut identifier to an accelerator.
+type Binding struct {
+	ID          string
+	Description string
+	Accelerator Accelerator
+}
+
+// Backend selects how shortcuts are registered.
+type Backend string
+
+const (
+	BackendAuto   Backend = "auto"
+	BackendX11    Backend = "x11"
+	BackendPortal Backend = "portal"
+)
+
+// ParseBackend validates a backend name; an empty name selects BackendAuto.
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(strings.ToLower(s)); b {
+	case "":
+		return BackendAuto, nil
+	case BackendAuto, BackendX11, BackendPortal:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown hotkey backend %q (want auto, x11 or portal)", s)
+}
+
+// resolve picks the portal on Wayland sessions and X11 grabs otherwise.
+func (b Backend) resolve() Backend {
+	if b != BackendAuto && b != "" {
+		return b
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") == "" {
+		return BackendPortal
+	}
+	return BackendX11
+}
+
+// Listen registers bindings and calls fn with a binding's ID each time it is
+// pressed, until ctx is cancelled. fn runs on the listener goroutine and
+// should return quickly.
+func Listen(ctx context.Context, backend Backend, bindings []Binding, fn func(id string)) error {
+	if len(bindings) == 0 {
+		return fmt.Errorf("no hotkeys to register")
+	}
+	switch backend.resolve() {
+	case BackendX11:
+		return listenX11(ctx, bindings, fn)
+	case BackendPortal:
+		return listenPortal(ctx, bindings, fn)
+	}
+	return fmt.Errorf("unknown hotkey backend %q", backend)
+}
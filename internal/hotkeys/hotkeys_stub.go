@@ -0,0 +1,16 @@
+//go:build !(linux || freebsd || openbsd || netbsd || dragonfly)
+
+package hotkeys
+
+import (
+	"context"
+	"fmt"
+)
+
+func listenX11(context.Context, []Binding, func(string)) error {
+	return fmt.Errorf("x11 hotkeys are not supported on this platform")
+}
+
+func listenPortal(context.Context, []Binding, func(string)) error {
+	return fmt.Errorf("portal hotkeys are not supported on this platform")
+}
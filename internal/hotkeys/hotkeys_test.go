@@ -0,0 +1,50 @@
+package hotkeys
+
+import "testing"
+
+func TestParseAccelerator(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Accelerator
+		str     string
+		trigger string
+	}{
+		{"Print", Accelerator{Key: "Print"}, "Print", "Print"},
+		{"shift+print", Accelerator{Mods: ModShift, Key: "Print"}, "Shift+Print", "SHIFT+Print"},
+		{"Super+Ctrl+S", Accelerator{Mods: ModSuper | ModControl, Key: "s"}, "Ctrl+Super+s", "CTRL+LOGO+s"},
+		{"Control+Alt+f12", Accelerator{Mods: ModControl | ModAlt, Key: "F12"}, "Ctrl+Alt+F12", "CTRL+ALT+F12"},
+		{"Alt+PrtSc", Accelerator{Mods: ModAlt, Key: "Print"}, "Alt+Print", "ALT+Print"},
+	} {
+		got, err := ParseAccelerator(tc.in)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q = %+v, want %+v", tc.in, got, tc.want)
+		}
+		if got.String() != tc.str {
+			t.Errorf("%q formats as %q, want %q", tc.in, got.String(), tc.str)
+		}
+		if got.portalTrigger() != tc.trigger {
+			t.Errorf("%q trigger %q, want %q", tc.in, got.portalTrigger(), tc.trigger)
+		}
+	}
+	for _, bad := range []string{"", "Ctrl+", "Hyper+a", "Ctrl+NoSuchKey", "+a"} {
+		if _, err := ParseAccelerator(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestKeysym(t *testing.T) {
+	if got := keysym("Print"); got != 0xff61 {
+		t.Errorf("Print = %#x", got)
+	}
+	if got := keysym("F1"); got != 0xffbe {
+		t.Errorf("F1 = %#x", got)
+	}
+	if got := keysym("a"); got != 'a' {
+		t.Errorf("a = %#x", got)
+	}
+}
@@ -0,0 +1,81 @@
+package hotkeys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// namedKeysyms lists the non-character keys accelerators can use.
+var namedKeysyms = map[string]uint32{
+	"BackSpace":   0xff08,
+	"Tab":         0xff09,
+	"Return":      0xff0d,
+	"Pause":       0xff13,
+	"Scroll_Lock": 0xff14,
+	"Escape":      0xff1b,
+	"Home":        0xff50,
+	"Left":        0xff51,
+	"Up":          0xff52,
+	"Right":       0xff53,
+	"Down":        0xff54,
+	"Page_Up":     0xff55,
+	"Page_Down":   0xff56,
+	"End":         0xff57,
+	"Print":       0xff61,
+	"Insert":      0xff63,
+	"Delete":      0xffff,
+	"space":       0x0020,
+}
+
+var keyAliases = map[string]string{
+	"prtsc":    "Print",
+	"prtscr":   "Print",
+	"printscr": "Print",
+	"esc":      "Escape",
+	"enter":    "Return",
+	"del":      "Delete",
+	"ins":      "Insert",
+	"pgup":     "Page_Up",
+	"pageup":   "Page_Up",
+	"pgdn":     "Page_Down",
+	"pagedown": "Page_Down",
+}
+
+func init() {
+	for i := 1; i <= 24; i++ {
+		namedKeysyms[fmt.Sprintf("F%d", i)] = 0xffbe + uint32(i-1)
+	}
+}
+
+// canonicalKey returns the keysym name for a key written in any case or
+// through a common alias.
+func canonicalKey(name string) (string, bool) {
+	if len(name) == 1 {
+		c := name[0]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			return name, true
+		case c >= 'A' && c <= 'Z':
+			return strings.ToLower(name), true
+		}
+		return "", false
+	}
+	lower := strings.ToLower(name)
+	if alias, ok := keyAliases[lower]; ok {
+		return alias, true
+	}
+	for known := range namedKeysyms {
+		if strings.ToLower(known) == lower {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// keysym returns the X keysym for a canonical key name.
+func keysym(name string) uint32 {
+	if len(name) == 1 {
+		return uint32(name[0])
+	}
+	return namedKeysyms[name]
+}
@@ -0,0 +1,134 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package hotkeys
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalDest      = "org.freedesktop.portal.Desktop"
+	portalPath      = dbus.ObjectPath("/org/freedesktop/portal/desktop")
+	shortcutsIface  = "org.freedesktop.portal.GlobalShortcuts"
+	requestResponse = "org.freedesktop.portal.Request.Response"
+)
+
+// portalShortcut is the (sa{sv}) pair BindShortcuts expects.
+type portalShortcut struct {
+	ID      string
+	Options map[string]dbus.Variant
+}
+
+func listenPortal(ctx context.Context, bindings []Binding, fn func(id string)) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("dbus connect: %w", err)
+	}
+	defer conn.Close()
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	for _, rule := range []string{
+		"type='signal',interface='org.freedesktop.portal.Request',member='Response'",
+		"type='signal',interface='" + shortcutsIface + "',member='Activated'",
+	} {
+		if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err != nil {
+			return fmt.Errorf("global shortcuts subscribe: %w", err)
+		}
+	}
+
+	obj := conn.Object(portalDest, portalPath)
+	results, err := portalRequest(ctx, obj, signals, "CreateSession", map[string]dbus.Variant{
+		"handle_token":         dbus.MakeVariant(portalToken()),
+		"session_handle_token": dbus.MakeVariant(portalToken()),
+	})
+	if err != nil {
+		return fmt.Errorf("global shortcuts session: %w", err)
+	}
+	var session dbus.ObjectPath
+	switch v := results["session_handle"].Value().(type) {
+	case string:
+		session = dbus.ObjectPath(v)
+	case dbus.ObjectPath:
+		session = v
+	}
+	if !session.IsValid() {
+		return fmt.Errorf("global shortcuts session: response missing session handle")
+	}
+	defer conn.Object(portalDest, session).Call("org.freedesktop.portal.Session.Close", 0)
+
+	shortcuts := make([]portalShortcut, 0, len(bindings))
+	for _, b := range bindings {
+		shortcuts = append(shortcuts, portalShortcut{
+			ID: b.ID,
+			Options: map[string]dbus.Variant{
+				"description":       dbus.MakeVariant(b.Description),
+				"preferred_trigger": dbus.MakeVariant(b.Accelerator.portalTrigger()),
+			},
+		})
+	}
+	if _, err := portalRequest(ctx, obj, signals, "BindShortcuts", session, shortcuts, "", map[string]dbus.Variant{
+		"handle_token": dbus.MakeVariant(portalToken()),
+	}); err != nil {
+		return fmt.Errorf("global shortcuts bind: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig, ok := <-signals:
+			if !ok {
+				return fmt.Errorf("dbus connection closed")
+			}
+			if sig.Name != shortcutsIface+".Activated" || len(sig.Body) < 2 {
+				continue
+			}
+			if path, _ := sig.Body[0].(dbus.ObjectPath); path != session {
+				continue
+			}
+			if id, ok := sig.Body[1].(string); ok {
+				fn(id)
+			}
+		}
+	}
+}
+
+// portalRequest calls a GlobalShortcuts method and waits for the Response
+// signal on the request object it returns. The options map must be the last
+// argument.
+func portalRequest(ctx context.Context, obj dbus.BusObject, signals <-chan *dbus.Signal, method string, args ...any) (map[string]dbus.Variant, error) {
+	var handle dbus.ObjectPath
+	if err := obj.CallWithContext(ctx, shortcutsIface+"."+method, 0, args...).Store(&handle); err != nil {
+		return nil, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case sig, ok := <-signals:
+			if !ok {
+				return nil, fmt.Errorf("dbus connection closed")
+			}
+			if sig.Path != handle || sig.Name != requestResponse || len(sig.Body) < 2 {
+				continue
+			}
+			if code, _ := sig.Body[0].(uint32); code != 0 {
+				return nil, fmt.Errorf("request denied or cancelled (response %d)", code)
+			}
+			results, _ := sig.Body[1].(map[string]dbus.Variant)
+			return results, nil
+		}
+	}
+}
+
+var portalTokenSeq atomic.Uint64
+
+func portalToken() string {
+	return fmt.Sprintf("shineyshot_%d_%d", time.Now().UnixNano(), portalTokenSeq.Add(1))
+}
@@ -0,0 +1,116 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package hotkeys
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// ignoredMods are lock modifiers that must not stop a shortcut from firing,
+// so each binding is grabbed once per combination of them.
+var ignoredMods = []uint16{
+	0,
+	xproto.ModMaskLock,
+	xproto.ModMask2, // Num Lock on most layouts
+	xproto.ModMaskLock | xproto.ModMask2,
+}
+
+func x11Mask(m Modifier) uint16 {
+	var mask uint16
+	if m&ModShift != 0 {
+		mask |= xproto.ModMaskShift
+	}
+	if m&ModControl != 0 {
+		mask |= xproto.ModMaskControl
+	}
+	if m&ModAlt != 0 {
+		mask |= xproto.ModMask1
+	}
+	if m&ModSuper != 0 {
+		mask |= xproto.ModMask4
+	}
+	return mask
+}
+
+type x11Grab struct {
+	keycode xproto.Keycode
+	mask    uint16
+}
+
+func listenX11(ctx context.Context, bindings []Binding, fn func(id string)) error {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return fmt.Errorf("x11 connect: %w", err)
+	}
+	defer conn.Close()
+	setup := xproto.Setup(conn)
+	root := setup.DefaultScreen(conn).Root
+
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+	mapping, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		return fmt.Errorf("x11 keyboard mapping: %w", err)
+	}
+	per := int(mapping.KeysymsPerKeycode)
+	keycodeFor := func(sym uint32) (xproto.Keycode, bool) {
+		for idx := 0; idx < int(count); idx++ {
+			for col := 0; col < per && col < 2; col++ {
+				if uint32(mapping.Keysyms[idx*per+col]) == sym {
+					return setup.MinKeycode + xproto.Keycode(idx), true
+				}
+			}
+		}
+		return 0, false
+	}
+
+	grabs := make(map[x11Grab]string)
+	for _, b := range bindings {
+		keycode, ok := keycodeFor(keysym(b.Accelerator.Key))
+		if !ok {
+			return fmt.Errorf("key %s is not on the current keyboard layout", b.Accelerator.Key)
+		}
+		mask := x11Mask(b.Accelerator.Mods)
+		for _, extra := range ignoredMods {
+			err := xproto.GrabKeyChecked(conn, true, root, mask|extra, keycode, xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+			if err != nil {
+				return fmt.Errorf("grab %s for %s: %w (is another program using it?)", b.Accelerator, b.ID, err)
+			}
+		}
+		grabs[x11Grab{keycode: keycode, mask: mask}] = b.ID
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		ev, err := conn.WaitForEvent()
+		if ev == nil && err == nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("x11 connection closed")
+		}
+		if err != nil {
+			continue
+		}
+		press, ok := ev.(xproto.KeyPressEvent)
+		if !ok {
+			continue
+		}
+		mask := press.State &^ (xproto.ModMaskLock | xproto.ModMask2)
+		if id, ok := grabs[x11Grab{keycode: press.Detail, mask: mask}]; ok {
+			fn(id)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package imagefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // register GIF
+	_ "image/jpeg" // register JPEG
+	_ "image/png"  // register PNG
+	"io"
+	"os"
+
+	_ "golang.org/x/image/bmp"  // register BMP
+	_ "golang.org/x/image/tiff" // register TIFF
+	_ "golang.org/x/image/webp" // register WebP
+)
+
+// Open decodes the image file at path: PNG, JPEG, GIF, BMP, TIFF or WebP,
+// turned upright as its EXIF orientation says.
+func Open(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Decode(f)
+	if cerr := f.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Decode decodes an image in any format Open accepts into a fresh RGBA
+// image, turned upright as its EXIF orientation says.
+func Decode(r io.Reader) (*image.RGBA, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return orient(src, exifOrientation(data)), nil
+}
+
+// orient copies src into an RGBA image at the origin, undoing EXIF
+// orientation o: 2 to 8 are the mirrorings and quarter turns the camera
+// recorded, anything else leaves the pixels as they are.
+func orient(src image.Image, o int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if o < 2 || o > 8 {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+		return dst
+	}
+	flat := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(flat, flat.Rect, src, b.Min, draw.Src)
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// (sx, sy) is the stored pixel shown at (x, y).
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], flat.Pix[flat.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation finds the EXIF orientation in a JPEG APP1 segment, a PNG
+// eXIf chunk, a WebP EXIF chunk or a TIFF file's first IFD. It returns 1,
+// upright, when there is none.
+func exifOrientation(data []byte) int {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+			marker := data[i+1]
+			if marker == 0xda { // start of scan: no more metadata
+				break
+			}
+			n := int(binary.BigEndian.Uint16(data[i+2:]))
+			end := min(i+2+n, len(data))
+			if seg := data[i+4 : end]; marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+				return tiffOrientation(seg[6:])
+			}
+			i = end
+		}
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		for i := 8; i+8 <= len(data); {
+			n := int(binary.BigEndian.Uint32(data[i:]))
+			end := min(i+8+n, len(data))
+			if string(data[i+4:i+8]) == "eXIf" {
+				return tiffOrientation(data[i+8 : end])
+			}
+			i = end + 4 // skip the CRC
+		}
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		for i := 12; i+8 <= len(data); {
+			n := int(binary.LittleEndian.Uint32(data[i+4:]))
+			end := min(i+8+n, len(data))
+			if string(data[i:i+4]) == "EXIF" {
+				return tiffOrientation(bytes.TrimPrefix(data[i+8:end], []byte("Exif\x00\x00")))
+			}
+			i = end + n%2
+		}
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		return tiffOrientation(data)
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of a TIFF
+// structure, the form EXIF data takes.
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(b[4:]))
+	if ifd < 8 || ifd+2 > len(b) {
+		return 1
+	}
+	count := int(order.Uint16(b[ifd:]))
+	for e := ifd + 2; count > 0 && e+12 <= len(b); e, count = e+12, count-1 {
+		// Tag 0x0112 holds one SHORT, stored in the value field.
+		if order.Uint16(b[e:]) == 0x0112 && order.Uint16(b[e+2:]) == 3 {
+			return int(order.Uint16(b[e+8:]))
+		}
+	}
+	return 1
+}
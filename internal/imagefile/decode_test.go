@@ -0,0 +1,118 @@
+package imagefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/example/shineyshot/internal/webp"
+)
+
+var (
+	red  = color.RGBA{0xff, 0, 0, 0xff}
+	blue = color.RGBA{0, 0, 0xff, 0xff}
+)
+
+// halves is 16x8, red on the left and blue on the right.
+func halves() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			c := red
+			if x >= 8 {
+				c = blue
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifWithOrientation is a little endian TIFF structure whose first IFD
+// holds only the orientation tag.
+func exifWithOrientation(o uint16) []byte {
+	b := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry, 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], o)
+	return append(append(b, entry...), 0, 0, 0, 0)
+}
+
+func near(a, b color.RGBA) bool {
+	d := func(x, y uint8) bool { return max(x, y)-min(x, y) < 16 }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B)
+}
+
+func TestDecodeJPEGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, halves(), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	app1 := append([]byte("Exif\x00\x00"), exifWithOrientation(6)...)
+	seg := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(2+len(app1)))
+	data := append(append(append([]byte{}, buf.Bytes()[:2]...), append(seg, app1...)...), buf.Bytes()[2:]...)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A quarter turn clockwise puts the left half on top.
+	if img.Rect != image.Rect(0, 0, 8, 16) {
+		t.Fatalf("bounds %v, want 8x16", img.Rect)
+	}
+	if top, bottom := img.RGBAAt(4, 2), img.RGBAAt(4, 13); !near(top, red) || !near(bottom, blue) {
+		t.Errorf("top %v and bottom %v, want red above blue", top, bottom)
+	}
+}
+
+func TestDecodePNGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halves()); err != nil {
+		t.Fatal(err)
+	}
+	body := append([]byte("eXIf"), exifWithOrientation(2)...)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(body)-4))
+	chunk = append(chunk, body...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(body))
+	// The chunk goes after IHDR, which ends 33 bytes in.
+	data := append(append(append([]byte{}, buf.Bytes()[:33]...), chunk...), buf.Bytes()[33:]...)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Rect != image.Rect(0, 0, 16, 8) || img.RGBAAt(0, 0) != blue || img.RGBAAt(15, 0) != red {
+		t.Errorf("got %v with %v and %v at the ends, want the halves mirrored", img.Rect, img.RGBAAt(0, 0), img.RGBAAt(15, 0))
+	}
+}
+
+func TestDecodeRegisteredFormats(t *testing.T) {
+	src := halves()
+	var bmpData, webpData bytes.Buffer
+	if err := bmp.Encode(&bmpData, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := webp.Encode(&webpData, src, &webp.Options{Lossless: true}); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"bmp": bmpData.Bytes(), "webp": webpData.Bytes()} {
+		img, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(img.Pix, src.Pix) {
+			t.Errorf("%s: decoded pixels differ from the source", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package imagefile reads the image files shineyshot opens and writes the
+// formats it saves and exports.
+package imagefile
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+
+	"github.com/example/shineyshot/internal/avif"
+	"github.com/example/shineyshot/internal/pdf"
+	"github.com/example/shineyshot/internal/pngtext"
+	"github.com/example/shineyshot/internal/webp"
+)
+
+// Format is an image file format.
+type Format int
+
+const (
+	PNG Format = iota
+	WebP
+	AVIF
+	// PDF is a single page document holding the image.
+	PDF
+	// JPEG has no alpha channel, so transparent pixels are laid on white.
+	JPEG
+	// BMP is laid on white too: its basic header, the one readers agree on,
+	// leaves alpha undefined.
+	BMP
+	// TIFF is written with Deflate compression.
+	TIFF
+)
+
+// DefaultJPEGQuality is the JPEG quality used when none is configured. It
+// is higher than image/jpeg's default because text and thin annotation
+// lines blur at lower qualities.
+const DefaultJPEGQuality = 90
+
+// String returns the format's name, which ParseFormat also reads.
+func (f Format) String() string {
+	switch f {
+	case WebP:
+		return "WebP"
+	case AVIF:
+		return "AVIF"
+	case PDF:
+		return "PDF"
+	case JPEG:
+		return "JPEG"
+	case BMP:
+		return "BMP"
+	case TIFF:
+		return "TIFF"
+	}
+	return "PNG"
+}
+
+// Ext returns the file extension for the format, including the dot.
+func (f Format) Ext() string {
+	if f == JPEG {
+		return ".jpg"
+	}
+	return "." + strings.ToLower(f.String())
+}
+
+// ParseFormat reads a format name: png, webp, avif, pdf, jpeg (or jpg), bmp
+// or tiff (or tif).
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "png":
+		return PNG, nil
+	case "webp":
+		return WebP, nil
+	case "avif":
+		return AVIF, nil
+	case "pdf":
+		return PDF, nil
+	case "jpeg", "jpg":
+		return JPEG, nil
+	case "bmp":
+		return BMP, nil
+	case "tiff", "tif":
+		return TIFF, nil
+	}
+	return PNG, fmt.Errorf("unknown image format %q (want png, webp, avif, pdf, jpeg, bmp or tiff)", s)
+}
+
+// FormatOf picks the format from a file name's extension: WebP for .webp,
+// AVIF for .avif, PDF for .pdf, JPEG for .jpg and .jpeg, BMP for .bmp, TIFF
+// for .tif and .tiff, and PNG for anything else.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".webp":
+		return WebP
+	case ".avif":
+		return AVIF
+	case ".pdf":
+		return PDF
+	case ".jpg", ".jpeg":
+		return JPEG
+	case ".bmp":
+		return BMP
+	case ".tif", ".tiff":
+		return TIFF
+	}
+	return PNG
+}
+
+// ParseJPEGQuality reads a JPEG quality from 1 to 100. Empty means
+// DefaultJPEGQuality.
+func ParseJPEGQuality(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultJPEGQuality, nil
+	}
+	q, err := strconv.Atoi(s)
+	if err != nil || q < 1 || q > 100 {
+		return 0, fmt.Errorf("invalid jpeg quality %q (want 1 to 100)", s)
+	}
+	return q, nil
+}
+
+// Options sets how Encode writes an image.
+type Options struct {
+	Format Format
+	// Text is stored as PNG text chunks. Other formats do not carry it.
+	Text map[string]string
+	// WebP sets the WebP encoding; the zero value is lossy at quality 0,
+	// so callers normally pass webp.ParseQuality's result.
+	WebP webp.Options
+	// AVIF sets the AVIF encoding.
+	AVIF avif.Options
+	// JPEG sets the JPEG encoding; callers normally pass
+	// DefaultJPEGQuality or ParseJPEGQuality's result as its quality.
+	JPEG jpeg.Options
+}
+
+// Encode writes img to w as o describes.
+func Encode(w io.Writer, img image.Image, o Options) error {
+	switch {
+	case o.Format == WebP:
+		return webp.Encode(w, img, &o.WebP)
+	case o.Format == AVIF:
+		return avif.Encode(w, img, &o.AVIF)
+	case o.Format == PDF:
+		return pdf.Encode(w, []image.Image{img})
+	case o.Format == JPEG:
+		return jpeg.Encode(w, onWhite(img), &o.JPEG)
+	case o.Format == BMP:
+		return bmp.Encode(w, onWhite(img))
+	case o.Format == TIFF:
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	case len(o.Text) > 0:
+		return pngtext.Encode(w, img, o.Text)
+	}
+	return png.Encode(w, img)
+}
+
+// onWhite lays img over white for formats without alpha, which would
+// otherwise show transparent pixels, such as a drop shadow's surroundings,
+// as black.
+func onWhite(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, b, img, b.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,87 @@
+package imagefile
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/example/shineyshot/internal/webp"
+)
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{
+		"shot.png":       PNG,
+		"shot.WebP":      WebP,
+		"dir.webp/shot":  PNG,
+		"/tmp/shot.webp": WebP,
+		"no-extension":   PNG,
+		"docs/shot.avif": AVIF,
+		"report.PDF":     PDF,
+		"photo.jpg":      JPEG,
+		"photo.JPEG":     JPEG,
+		"old.bmp":        BMP,
+		"scan.tif":       TIFF,
+		"scan.tiff":      TIFF,
+	} {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestEncodeWritesFormat(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for _, tc := range []struct {
+		o     Options
+		magic string
+	}{
+		{Options{}, "\x89PNG"},
+		{Options{Text: map[string]string{"Title": "x"}}, "\x89PNG"},
+		{Options{Format: WebP, WebP: webp.Options{Lossless: true}}, "RIFF"},
+		{Options{Format: PDF}, "%PDF"},
+		{Options{Format: JPEG, JPEG: jpeg.Options{Quality: DefaultJPEGQuality}}, "\xff\xd8\xff"},
+		{Options{Format: BMP}, "BM"},
+		{Options{Format: TIFF}, "II*\x00"},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, img, tc.o); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte(tc.magic)) {
+			t.Errorf("%v: output starts %q, want %q", tc.o.Format, buf.Bytes()[:4], tc.magic)
+		}
+	}
+}
+
+func TestEncodeRoundTrips(t *testing.T) {
+	// Half the image is transparent: TIFF keeps it, JPEG and BMP show it
+	// as white.
+	src := halves()
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			src.SetRGBA(x, y, color.RGBA{})
+		}
+	}
+	for _, f := range []Format{JPEG, BMP, TIFF} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, src, Options{Format: f, JPEG: jpeg.Options{Quality: DefaultJPEGQuality}}); err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		img, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("%v: %v", f, err)
+		}
+		want := color.RGBA{}
+		if f != TIFF {
+			want = color.RGBA{0xff, 0xff, 0xff, 0xff}
+		}
+		if got := img.RGBAAt(2, 2); !near(got, want) || got.A != want.A {
+			t.Errorf("%v: transparent half decoded as %v, want %v", f, got, want)
+		}
+		if got := img.RGBAAt(12, 4); !near(got, blue) {
+			t.Errorf("%v: blue half decoded as %v", f, got)
+		}
+	}
+}
@@ -3,12 +3,15 @@ package notify
 import (
 	"fmt"
 	"image"
+	_ "image/jpeg"
 	"image/png"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
+	xdraw "golang.org/x/image/draw"
+
 	"github.com/example/shineyshot/internal/platform"
 )
 
@@ -22,8 +25,13 @@ const (
 	EventSave Event = "save"
 	// EventCopy emits a notification when data is copied to the clipboard.
 	EventCopy Event = "copy"
+	// EventUpload emits a notification when an image is uploaded.
+	EventUpload Event = "upload"
 )
 
+// Events lists every notification trigger.
+var Events = []Event{EventCapture, EventSave, EventCopy, EventUpload}
+
 // EventPreference describes formatting for a notification event.
 type EventPreference struct {
 	Template string
@@ -43,6 +51,7 @@ func DefaultPreferences() Preferences {
 			EventCapture: {Template: "Captured %s"},
 			EventSave:    {Template: "Saved %s"},
 			EventCopy:    {Template: "Copied %s to clipboard"},
+			EventUpload:  {Template: "Uploaded %s"},
 		},
 	}
 }
@@ -63,6 +72,7 @@ func LoadPreferences() Preferences {
 	apply("SHINEYSHOT_NOTIFY_CAPTURE_TEXT", EventCapture)
 	apply("SHINEYSHOT_NOTIFY_SAVE_TEXT", EventSave)
 	apply("SHINEYSHOT_NOTIFY_COPY_TEXT", EventCopy)
+	apply("SHINEYSHOT_NOTIFY_UPLOAD_TEXT", EventUpload)
 	return prefs
 }
 
@@ -92,6 +102,10 @@ func (n *Notifier) Enable(event Event, enabled bool) {
 	n.enabled[event] = enabled
 }
 
+// thumbnailSize bounds the longer side of notification previews. Servers
+// scale images down further, so anything larger only costs bus traffic.
+const thumbnailSize = 256
+
 // Capture sends a capture notification with an optional image preview.
 func (n *Notifier) Capture(detail string, img image.Image) {
 	if !n.enabledFor(EventCapture) {
@@ -99,7 +113,9 @@ func (n *Notifier) Capture(detail string, img image.Image) {
 	}
 	opts := platform.Options{}
 	if img != nil {
-		if path, cleanup, err := createPreview(img); err != nil {
+		thumb := thumbnail(img)
+		opts.Image = thumb
+		if path, cleanup, err := createPreview(thumb); err != nil {
 			log.Printf("notification preview: %v", err)
 		} else {
 			defer cleanup()
@@ -120,6 +136,9 @@ func (n *Notifier) Save(path string) {
 		detail = abs
 		if _, statErr := os.Stat(abs); statErr == nil {
 			opts.IconPath = abs
+			if img, err := decodeFile(abs); err == nil {
+				opts.Image = thumbnail(img)
+			}
 		}
 	}
 	n.dispatch(EventSave, detail, opts)
@@ -136,6 +155,29 @@ func (n *Notifier) Copy(detail string) {
 	n.dispatch(EventCopy, detail, platform.Options{})
 }
 
+// Upload sends an upload notification; detail is usually the link.
+func (n *Notifier) Upload(detail string) {
+	if !n.enabledFor(EventUpload) {
+		return
+	}
+	n.dispatch(EventUpload, detail, platform.Options{})
+}
+
+// Test sends a notification whether or not any event is enabled, and
+// reports the failure dispatch only logs, so a setup can be checked.
+func (n *Notifier) Test() error {
+	title := "ShineyShot"
+	if n != nil && n.prefs.Title != "" {
+		title = n.prefs.Title
+	}
+	return platform.Notify(title, "Notifications are working", platform.Options{})
+}
+
+// Template returns the text template of event.
+func (n *Notifier) Template(event Event) string {
+	return n.template(event)
+}
+
 func (n *Notifier) enabledFor(event Event) bool {
 	if n == nil {
 		return false
@@ -173,6 +215,32 @@ func (n *Notifier) template(event Event) string {
 	return ""
 }
 
+// thumbnail scales img down to fit thumbnailSize, keeping its aspect ratio.
+// Smaller images are returned unchanged.
+func thumbnail(img image.Image) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= thumbnailSize && b.Dy() <= thumbnailSize {
+		return img
+	}
+	w, h := thumbnailSize, b.Dy()*thumbnailSize/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*thumbnailSize/b.Dy(), thumbnailSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+func decodeFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
 func createPreview(img image.Image) (string, func(), error) {
 	f, err := os.CreateTemp("", "shineyshot-preview-*.png")
 	if err != nil {
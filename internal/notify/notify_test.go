@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"image"
+	"testing"
+)
+
+func TestThumbnailFitsPreviewSize(t *testing.T) {
+	for _, tc := range []struct {
+		size, want image.Point
+	}{
+		{image.Pt(3840, 2160), image.Pt(256, 144)},
+		{image.Pt(1000, 4000), image.Pt(64, 256)},
+		{image.Pt(200, 100), image.Pt(200, 100)},
+	} {
+		got := thumbnail(image.NewRGBA(image.Rectangle{Max: tc.size})).Bounds().Size()
+		if got != tc.want {
+			t.Errorf("thumbnail of %v = %v, want %v", tc.size, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/example/shineyshot/internal/platform"
+)
+
+// ProgressUnknown is passed to Progress.Update by operations that cannot
+// tell how far along they are; the time elapsed is shown instead.
+const ProgressUnknown = platform.ProgressUnknown
+
+// Progress is a notification kept on screen and updated while a long
+// operation runs, then replaced by the event's usual notification when it
+// ends. A nil Progress, returned when the event is disabled, ignores every
+// call.
+type Progress struct {
+	n     *Notifier
+	event Event
+	start time.Time
+	p     *platform.Progress
+}
+
+// StartProgress shows body for an operation that will end in event. When
+// cancel is set the notification offers a Cancel button that calls it.
+func (n *Notifier) StartProgress(event Event, body string, cancel func()) *Progress {
+	if !n.enabledFor(event) {
+		return nil
+	}
+	p, err := platform.StartProgress(n.prefs.Title, body, cancel)
+	if err != nil {
+		// The final notification is still sent the usual way.
+		log.Printf("notification %s progress: %v", event, err)
+	}
+	return &Progress{n: n, event: event, start: time.Now(), p: p}
+}
+
+// Update replaces the progress text. percent is 0 to 100, or
+// ProgressUnknown.
+func (pr *Progress) Update(body string, percent int) {
+	if pr == nil || pr.p == nil {
+		return
+	}
+	if percent < 0 {
+		body = fmt.Sprintf("%s (%s)", body, time.Since(pr.start).Round(time.Second))
+	} else {
+		body = fmt.Sprintf("%s %d%%", body, percent)
+	}
+	if err := pr.p.Update(body, percent); err != nil {
+		log.Printf("notification %s progress: %v", pr.event, err)
+	}
+}
+
+// Done ends the operation with the event's notification for detail, which
+// replaces the progress one. img, when set, is shown as a thumbnail.
+func (pr *Progress) Done(detail string, img image.Image) {
+	if pr == nil {
+		return
+	}
+	template := strings.TrimSpace(pr.n.template(pr.event))
+	body := strings.TrimSpace(fmt.Sprintf(template, strings.TrimSpace(detail)))
+	pr.finish(body, img)
+}
+
+// Fail ends the operation with err in place of the progress notification.
+func (pr *Progress) Fail(err error) {
+	if pr == nil {
+		return
+	}
+	pr.finish(fmt.Sprintf("Failed: %v", err), nil)
+}
+
+func (pr *Progress) finish(body string, img image.Image) {
+	if body == "" {
+		return
+	}
+	opts := platform.Options{}
+	if img != nil {
+		thumb := thumbnail(img)
+		opts.Image = thumb
+		if path, cleanup, err := createPreview(thumb); err != nil {
+			log.Printf("notification preview: %v", err)
+		} else {
+			defer cleanup()
+			opts.IconPath = path
+		}
+	}
+	var err error
+	if pr.p == nil {
+		err = platform.Notify(pr.n.prefs.Title, body, opts)
+	} else {
+		err = pr.p.Done(body, opts)
+	}
+	if err != nil {
+		log.Printf("notification %s: %v", pr.event, err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package pdf writes images as the pages of a PDF document, one image
+// filling each page.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DPI is the resolution pages are laid out at. A pixel is 1/96 inch, as in
+// CSS, so a screenshot prints at the size it shows on a standard density
+// screen.
+const DPI = 96
+
+// Encode writes a PDF with one page per image, each page the size of its
+// image. Transparent pixels show the page through a soft mask.
+func Encode(w io.Writer, pages []image.Image) error {
+	if len(pages) == 0 {
+		return errors.New("pdf: no pages to write")
+	}
+	pw := &writer{w: bufio.NewWriter(w), offsets: []int{0}}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// The catalog and page tree come first in numbering; the tree is
+	// written last, once the pages it lists have numbers.
+	catalog, tree := pw.alloc(), pw.alloc()
+	pw.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", tree))
+	var kids []string
+	for i, img := range pages {
+		b := img.Bounds()
+		if b.Empty() {
+			return fmt.Errorf("pdf: page %d is empty", i+1)
+		}
+		nrgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+		rgb, alpha, opaque := planes(nrgba)
+
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", b.Dx(), b.Dy())
+		smask := ""
+		if !opaque {
+			id := pw.alloc()
+			pw.stream(id, dict+" /ColorSpace /DeviceGray "+flate(1, b.Dx()), deflate(alpha, 1, b.Dx()))
+			smask = fmt.Sprintf(" /SMask %d 0 R", id)
+		}
+		xobj := pw.alloc()
+		pw.stream(xobj, dict+" /ColorSpace /DeviceRGB "+flate(3, b.Dx())+smask, deflate(rgb, 3, b.Dx()))
+
+		wpt, hpt := points(b.Dx()), points(b.Dy())
+		contents := pw.alloc()
+		pw.stream(contents, "", []byte(fmt.Sprintf("q %s 0 0 %s 0 0 cm /Im0 Do Q", wpt, hpt)))
+		page := pw.alloc()
+		pw.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			tree, wpt, hpt, xobj, contents))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	pw.object(tree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets))
+	for _, off := range pw.offsets[1:] {
+		pw.printf("%010d 00000 n \n", off)
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets), catalog, xref)
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// writer tracks the byte offset of each object for the cross-reference
+// table and keeps the first write error.
+type writer struct {
+	w       *bufio.Writer
+	n       int
+	offsets []int
+	err     error
+}
+
+func (pw *writer) printf(format string, args ...any) {
+	pw.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (pw *writer) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.n += n
+	pw.err = err
+}
+
+// alloc numbers a new object.
+func (pw *writer) alloc() int {
+	pw.offsets = append(pw.offsets, 0)
+	return len(pw.offsets) - 1
+}
+
+func (pw *writer) begin(id int) {
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n", id)
+}
+
+func (pw *writer) object(id int, body string) {
+	pw.begin(id)
+	pw.printf("%s\nendobj\n", body)
+}
+
+// stream writes a stream object whose dictionary holds dict and the
+// length.
+func (pw *writer) stream(id int, dict string, data []byte) {
+	pw.begin(id)
+	if dict != "" {
+		dict += " "
+	}
+	pw.printf("<< %s/Length %d >>\nstream\n", dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
+
+// points converts pixels to PDF points at DPI.
+func points(px int) string {
+	return strconv.FormatFloat(float64(px)*72/DPI, 'f', -1, 64)
+}
+
+// flate names the filter deflate applies for samples of colors channels.
+func flate(colors, width int) string {
+	return fmt.Sprintf("/Filter /FlateDecode /DecodeParms << /Predictor 15 /Colors %d /Columns %d >>", colors, width)
+}
+
+// planes splits img into packed RGB and alpha samples.
+func planes(img *image.NRGBA) (rgb, alpha []byte, opaque bool) {
+	n := img.Rect.Dx() * img.Rect.Dy()
+	rgb, alpha = make([]byte, 0, 3*n), make([]byte, 0, n)
+	opaque = true
+	for i := 0; i < len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4]
+		rgb = append(rgb, p[0], p[1], p[2])
+		alpha = append(alpha, p[3])
+		opaque = opaque && p[3] == 0xff
+	}
+	return rgb, alpha, opaque
+}
+
+// deflate compresses samples rows of width pixels of bpp bytes, filtering
+// each row with whichever of the PNG None, Sub and Up filters leaves the
+// smallest residuals, as /Predictor 15 allows.
+func deflate(samples []byte, bpp, width int) []byte {
+	stride := bpp * width
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestSpeed)
+	prev := make([]byte, stride)
+	cand := [3][]byte{make([]byte, stride), make([]byte, stride), make([]byte, stride)}
+	for y := 0; y*stride < len(samples); y++ {
+		row := samples[y*stride : (y+1)*stride]
+		best, bestCost := 0, -1
+		for f := range cand {
+			c := cand[f]
+			cost := 0
+			for x := range row {
+				var pred byte
+				switch f {
+				case 1:
+					if x >= bpp {
+						pred = row[x-bpp]
+					}
+				case 2:
+					pred = prev[x]
+				}
+				c[x] = row[x] - pred
+				cost += abs(int(int8(c[x])))
+			}
+			if bestCost < 0 || cost < bestCost {
+				best, bestCost = f, cost
+			}
+		}
+		zw.Write([]byte{byte(best)})
+		zw.Write(cand[best])
+		prev = row
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func testImage(w, h int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := uint8(0xff)
+			if alpha && x < w/2 {
+				a = uint8(y * 255 / h)
+			}
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 7), uint8(y * 5), uint8(x ^ y), a})
+		}
+	}
+	return img
+}
+
+// unpredict inflates a /Predictor 15 stream of width pixels of bpp bytes.
+func unpredict(t *testing.T, data []byte, bpp, width int) []byte {
+	t.Helper()
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stride := bpp * width
+	var out []byte
+	prev := make([]byte, stride)
+	for len(raw) > 0 {
+		f, row := raw[0], append([]byte(nil), raw[1:1+stride]...)
+		raw = raw[1+stride:]
+		for x := range row {
+			switch f {
+			case 1:
+				if x >= bpp {
+					row[x] += row[x-bpp]
+				}
+			case 2:
+				row[x] += prev[x]
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out
+}
+
+func TestEncodePages(t *testing.T) {
+	pages := []image.Image{testImage(40, 30, false), testImage(17, 9, true)}
+	var buf bytes.Buffer
+	if err := Encode(&buf, pages); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Every cross-reference entry points at its object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, data[off:off+10])
+		}
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("page tree does not count two pages")
+	}
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 30 22.5]")) {
+		t.Error("first page is not 40x30 pixels at 96 DPI")
+	}
+
+	// The RGB and alpha samples of each image decode to the source.
+	streams := regexp.MustCompile(`(?s)/Width (\d+) .*?/ColorSpace /Device(RGB|Gray) .*?/Length (\d+) >>\nstream\n`).FindAllSubmatchIndex(data, -1)
+	var got [][]byte
+	for _, s := range streams {
+		width, _ := strconv.Atoi(string(data[s[2]:s[3]]))
+		n, _ := strconv.Atoi(string(data[s[6]:s[7]]))
+		bpp := 3
+		if string(data[s[4]:s[5]]) == "Gray" {
+			bpp = 1
+		}
+		got = append(got, unpredict(t, data[s[1]:s[1]+n], bpp, width))
+	}
+	if len(got) != 3 {
+		t.Fatalf("found %d image streams, want RGB, then alpha and RGB", len(got))
+	}
+	rgb, alpha, _ := planes(pages[0].(*image.NRGBA))
+	if !bytes.Equal(got[0], rgb) {
+		t.Error("first page's RGB samples differ")
+	}
+	rgb, alpha, _ = planes(pages[1].(*image.NRGBA))
+	if !bytes.Equal(got[1], alpha) || !bytes.Equal(got[2], rgb) {
+		t.Error("second page's alpha or RGB samples differ")
+	}
+}
+
+func TestEncodeNoPages(t *testing.T) {
+	if err := Encode(io.Discard, nil); err == nil {
+		t.Error("encoding no pages succeeded")
+	}
+}
@@ -3,6 +3,9 @@
 package platform
 
 import (
+	"image"
+	"image/draw"
+
 	"github.com/godbus/dbus/v5"
 )
 
@@ -14,8 +17,42 @@ func Notify(title, body string, opts Options) error {
 	}
 	defer conn.Close()
 
+	icon := opts.IconPath
+	hints := map[string]dbus.Variant{}
+	if opts.Image != nil {
+		// The preview replaces the icon; servers show image-data in
+		// the body of the notification.
+		icon = ""
+		hints["image-data"] = dbus.MakeVariant(newImageData(opts.Image))
+	}
 	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
 	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
-		"ShineyShot", uint32(0), opts.IconPath, title, body, []string{}, map[string]dbus.Variant{}, int32(5000))
+		"ShineyShot", uint32(0), icon, title, body, []string{}, hints, int32(5000))
 	return call.Err
 }
+
+// imageData is the (iiibiiay) structure of the image-data hint: raw,
+// non-premultiplied RGBA rows.
+type imageData struct {
+	Width, Height int32
+	Rowstride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+func newImageData(img image.Image) imageData {
+	b := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	return imageData{
+		Width:         int32(b.Dx()),
+		Height:        int32(b.Dy()),
+		Rowstride:     int32(nrgba.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          nrgba.Pix,
+	}
+}
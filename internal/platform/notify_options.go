@@ -1,8 +1,14 @@
 package platform
 
+import "image"
+
 // Options configures how a notification is displayed on the host platform.
 type Options struct {
 	// IconPath, when non-empty, points to an image file the notification center
 	// should display with the notification if supported by the platform.
 	IconPath string
+	// Image, when set, is a small preview shown in the notification. Linux
+	// sends it inline as the image-data hint, so no file has to outlive the
+	// call; other platforms show IconPath instead.
+	Image image.Image
 }
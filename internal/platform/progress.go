@@ -0,0 +1,5 @@
+package platform
+
+// ProgressUnknown marks a progress update without a percentage, for
+// operations that can only report how long they have been running.
+const ProgressUnknown = -1
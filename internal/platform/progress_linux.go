@@ -0,0 +1,147 @@
+//go:build linux
+
+package platform
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// cancelAction is the action key of the progress notification's button.
+const cancelAction = "cancel"
+
+// Progress is one notification updated in place while an operation runs,
+// using the replaces_id argument of the Freedesktop.org notification spec.
+type Progress struct {
+	title    string
+	conn     *dbus.Conn
+	signals  chan *dbus.Signal
+	onCancel func()
+
+	// send serializes notifications so each replaces the last one; mu
+	// guards id and closed, which the action watcher also reads.
+	send   sync.Mutex
+	mu     sync.Mutex
+	id     uint32
+	closed bool
+}
+
+// StartProgress shows a progress notification with body. When onCancel is
+// set the notification offers a Cancel button that calls it once.
+func StartProgress(title, body string, onCancel func()) (*Progress, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	p := &Progress{title: title, conn: conn, onCancel: onCancel}
+	if onCancel != nil {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchInterface("org.freedesktop.Notifications"),
+			dbus.WithMatchMember("ActionInvoked"),
+		); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		p.signals = make(chan *dbus.Signal, 4)
+		conn.Signal(p.signals)
+		go p.watchActions()
+	}
+	if err := p.Update(body, ProgressUnknown); err != nil {
+		p.close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// watchActions calls onCancel when the Cancel button of this notification
+// is pressed. The signal channel is closed with the connection.
+func (p *Progress) watchActions() {
+	for sig := range p.signals {
+		if len(sig.Body) < 2 {
+			continue
+		}
+		id, _ := sig.Body[0].(uint32)
+		action, _ := sig.Body[1].(string)
+		p.mu.Lock()
+		ours := id == p.id && !p.closed
+		p.mu.Unlock()
+		if ours && action == cancelAction {
+			p.onCancel()
+			return
+		}
+	}
+}
+
+// Update replaces the notification's body. percent between 0 and 100 is
+// sent as the value hint, which servers draw as a progress bar.
+func (p *Progress) Update(body string, percent int) error {
+	if p == nil {
+		return nil
+	}
+	hints := map[string]dbus.Variant{
+		// Keep the notification on screen and out of the history while
+		// it is only a progress report.
+		"transient": dbus.MakeVariant(true),
+		"urgency":   dbus.MakeVariant(byte(0)),
+	}
+	if percent >= 0 {
+		hints["value"] = dbus.MakeVariant(int32(min(percent, 100)))
+	}
+	var actions []string
+	if p.onCancel != nil {
+		actions = []string{cancelAction, "Cancel"}
+	}
+	return p.notify(body, actions, hints, 0)
+}
+
+// Done replaces the progress notification with a final one that expires
+// like any other, and releases the connection.
+func (p *Progress) Done(body string, opts Options) error {
+	if p == nil {
+		return nil
+	}
+	defer p.close()
+	hints := map[string]dbus.Variant{}
+	if opts.Image != nil {
+		hints["image-data"] = dbus.MakeVariant(newImageData(opts.Image))
+	}
+	return p.notify(body, nil, hints, 5000)
+}
+
+func (p *Progress) notify(body string, actions []string, hints map[string]dbus.Variant, timeout int32) error {
+	p.send.Lock()
+	defer p.send.Unlock()
+	p.mu.Lock()
+	id, closed := p.id, p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil
+	}
+	if actions == nil {
+		actions = []string{}
+	}
+	obj := p.conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"ShineyShot", id, "", p.title, body, actions, hints, timeout)
+	if call.Err != nil {
+		return call.Err
+	}
+	if err := call.Store(&id); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.id = id
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Progress) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.conn.Close()
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package platform
+
+// Progress reports a long operation. Notification centers outside Linux
+// cannot update a notification in place, so only the final one is shown.
+type Progress struct {
+	title string
+}
+
+// StartProgress begins reporting an operation. onCancel is never called
+// because there is no notification to offer a Cancel button on.
+func StartProgress(title, body string, onCancel func()) (*Progress, error) {
+	return &Progress{title: title}, nil
+}
+
+// Update does nothing outside Linux.
+func (p *Progress) Update(body string, percent int) error {
+	return nil
+}
+
+// Done shows the final notification.
+func (p *Progress) Done(body string, opts Options) error {
+	if p == nil {
+		return nil
+	}
+	return Notify(p.title, body, opts)
+}
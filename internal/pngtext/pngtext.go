@@ -0,0 +1,72 @@
+// Package pngtext writes PNG files carrying textual metadata.
+package pngtext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"sort"
+)
+
+// pngHeaderLen covers the signature and the IHDR chunk that must lead every
+// PNG: 8 signature bytes plus a 13-byte IHDR with its length, type and CRC.
+const pngHeaderLen = 8 + 4 + 4 + 13 + 4
+
+// Encode writes img as a PNG with an iTXt chunk for each entry of text,
+// in keyword order. Keywords must be 1 to 79 bytes without NUL; values are
+// UTF-8.
+func Encode(w io.Writer, img image.Image, text map[string]string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if len(data) < pngHeaderLen {
+		return errors.New("png encoder produced a truncated header")
+	}
+	keys := make([]string, 0, len(text))
+	for k := range text {
+		if len(k) == 0 || len(k) > 79 || bytes.IndexByte([]byte(k), 0) >= 0 {
+			return errors.New("invalid png text keyword " + k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if _, err := w.Write(data[:pngHeaderLen]); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := writeITXt(w, k, text[k]); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(data[pngHeaderLen:])
+	return err
+}
+
+// writeITXt writes an uncompressed iTXt chunk with no language tag.
+func writeITXt(w io.Writer, keyword, value string) error {
+	var body bytes.Buffer
+	body.WriteString("iTXt")
+	body.WriteString(keyword)
+	// NUL separator, compression flag and method, then empty language tag
+	// and translated keyword, each NUL terminated.
+	body.Write([]byte{0, 0, 0, 0, 0})
+	body.WriteString(value)
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(body.Len()-4))
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(body.Bytes()))
+	for _, part := range [][]byte{length[:], body.Bytes(), crc[:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
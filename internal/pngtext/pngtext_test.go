@@ -0,0 +1,38 @@
+package pngtext
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestEncodeAddsTextChunks(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, map[string]string{"Title": "héllo", "Software": "shineyshot"}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()
+	software := bytes.Index(data, []byte("iTXtSoftware"))
+	title := bytes.Index(data, []byte("iTXtTitle\x00\x00\x00\x00\x00héllo"))
+	if software < 0 || title < 0 || software > title {
+		t.Fatalf("expected sorted iTXt chunks, got Software at %d and Title at %d", software, title)
+	}
+	if idat := bytes.Index(data, []byte("IDAT")); idat < title {
+		t.Fatalf("expected text chunks before image data")
+	}
+	dec, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if dec.Bounds() != img.Bounds() {
+		t.Fatalf("bounds mismatch: got %v want %v", dec.Bounds(), img.Bounds())
+	}
+}
+
+func TestEncodeRejectsBadKeyword(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewRGBA(image.Rect(0, 0, 1, 1)), map[string]string{"": "x"}); err == nil {
+		t.Fatalf("expected error for empty keyword")
+	}
+}
@@ -0,0 +1,362 @@
+// Package project reads and writes .shineyshot project files, which keep
+// an editor session's tabs so it can be reopened and annotated further
+// instead of being flattened into one exported image.
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ext is the extension of project files.
+const Ext = ".shineyshot"
+
+// Version is the project format written by Write. Read accepts this
+// version and older ones. Version 1 kept every text, callout and numbered
+// marker drawn into the tab's pixels; version 2 keeps them beside the
+// pixels so they can still be edited.
+const Version = 2
+
+// format tags the JSON document so other JSON files are not mistaken for
+// projects.
+const format = "shineyshot-project"
+
+// Tab is one editor tab: its pixels with every annotation drawn into them
+// so far, the texts, callouts and numbered markers drawn over them, and how
+// it was being viewed and annotated.
+type Tab struct {
+	Title string
+	Image *image.RGBA
+	// Texts and Callouts are the tab's items, each with its place in the
+	// stack drawn over the pixels, and Numbers its numbered markers, drawn
+	// beneath them.
+	Texts    []Text
+	Callouts []Callout
+	Numbers  []Number
+	// Offset and Zoom are the view, Offset in image coordinates.
+	Offset image.Point
+	Zoom   float64
+	// NextNumber is the number the next numbered marker gets.
+	NextNumber int
+	// WidthIdx is the selected stroke width.
+	WidthIdx      int
+	ShadowApplied bool
+	// SavedPath is the file the tab was last saved to.
+	SavedPath string
+}
+
+// Text is text placed on a tab. Colours are kept as they were drawn
+// rather than as places in the palette, which the configuration sets and
+// the editor adds to.
+type Text struct {
+	Text string
+	// Pos is the start of the baseline.
+	Pos     image.Point
+	SizeIdx int
+	Color   color.RGBA
+	Item
+}
+
+// Callout is a speech bubble placed on a tab, its tail pointing at Tip.
+type Callout struct {
+	Box      image.Rectangle
+	Tip      image.Point
+	Text     string
+	SizeIdx  int
+	Color    color.RGBA
+	WidthIdx int
+	Fill     color.NRGBA
+	Item
+}
+
+// Item is where a text or callout sits among the tab's items, and how it
+// is shown in the layers panel.
+type Item struct {
+	// Z is its place in the stack, 0 at the bottom.
+	Z      int
+	Hidden bool
+	Locked bool
+}
+
+// Number is a numbered marker. Link is how it is joined to the marker
+// before it: none, a line or an arrow.
+type Number struct {
+	Center image.Point
+	Value  int
+	Size   int
+	Color  color.RGBA
+	Link   int
+}
+
+// Project is a saved editor session.
+type Project struct {
+	Tabs []Tab
+	// Current is the index of the tab that was shown.
+	Current int
+}
+
+// fileTab is a tab as stored: a JSON object holding the image as a PNG.
+type fileTab struct {
+	Title         string        `json:"title"`
+	OffsetX       int           `json:"offset_x"`
+	OffsetY       int           `json:"offset_y"`
+	Zoom          float64       `json:"zoom"`
+	NextNumber    int           `json:"next_number"`
+	WidthIdx      int           `json:"width_index"`
+	ShadowApplied bool          `json:"shadow_applied,omitempty"`
+	SavedPath     string        `json:"saved_path,omitempty"`
+	PNG           []byte        `json:"png"`
+	Texts         []fileText    `json:"texts,omitempty"`
+	Callouts      []fileCallout `json:"callouts,omitempty"`
+	Numbers       []fileNumber  `json:"numbers,omitempty"`
+}
+
+// fileItem is an Item as stored.
+type fileItem struct {
+	Z      int  `json:"z"`
+	Hidden bool `json:"hidden,omitempty"`
+	Locked bool `json:"locked,omitempty"`
+}
+
+type fileText struct {
+	Text    string   `json:"text"`
+	X       int      `json:"x"`
+	Y       int      `json:"y"`
+	SizeIdx int      `json:"size_index"`
+	Color   [4]uint8 `json:"color"`
+	fileItem
+}
+
+type fileCallout struct {
+	Text     string   `json:"text"`
+	MinX     int      `json:"min_x"`
+	MinY     int      `json:"min_y"`
+	MaxX     int      `json:"max_x"`
+	MaxY     int      `json:"max_y"`
+	TipX     int      `json:"tip_x"`
+	TipY     int      `json:"tip_y"`
+	SizeIdx  int      `json:"size_index"`
+	Color    [4]uint8 `json:"color"`
+	WidthIdx int      `json:"width_index"`
+	Fill     [4]uint8 `json:"fill"`
+	fileItem
+}
+
+type fileNumber struct {
+	X     int      `json:"x"`
+	Y     int      `json:"y"`
+	Value int      `json:"value"`
+	Size  int      `json:"size"`
+	Color [4]uint8 `json:"color"`
+	Link  int      `json:"link,omitempty"`
+}
+
+type file struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Current int       `json:"current"`
+	Tabs    []fileTab `json:"tabs"`
+}
+
+// IsProject reports whether path names a project file by its extension.
+func IsProject(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), Ext)
+}
+
+// Write encodes p as JSON, each tab's image a base64 PNG.
+func Write(w io.Writer, p *Project) error {
+	if len(p.Tabs) == 0 {
+		return errors.New("project has no tabs")
+	}
+	f := file{Format: format, Version: Version, Current: p.Current}
+	for i, t := range p.Tabs {
+		if t.Image == nil {
+			return fmt.Errorf("tab %d has no image", i+1)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, t.Image); err != nil {
+			return fmt.Errorf("encode tab %d: %w", i+1, err)
+		}
+		ft := fileTab{
+			Title:         t.Title,
+			OffsetX:       t.Offset.X,
+			OffsetY:       t.Offset.Y,
+			Zoom:          t.Zoom,
+			NextNumber:    t.NextNumber,
+			WidthIdx:      t.WidthIdx,
+			ShadowApplied: t.ShadowApplied,
+			SavedPath:     t.SavedPath,
+			PNG:           buf.Bytes(),
+		}
+		for _, x := range t.Texts {
+			ft.Texts = append(ft.Texts, fileText{
+				Text:     x.Text,
+				X:        x.Pos.X,
+				Y:        x.Pos.Y,
+				SizeIdx:  x.SizeIdx,
+				Color:    rgba(x.Color),
+				fileItem: fileItem(x.Item),
+			})
+		}
+		for _, c := range t.Callouts {
+			ft.Callouts = append(ft.Callouts, fileCallout{
+				Text:     c.Text,
+				MinX:     c.Box.Min.X,
+				MinY:     c.Box.Min.Y,
+				MaxX:     c.Box.Max.X,
+				MaxY:     c.Box.Max.Y,
+				TipX:     c.Tip.X,
+				TipY:     c.Tip.Y,
+				SizeIdx:  c.SizeIdx,
+				Color:    rgba(c.Color),
+				WidthIdx: c.WidthIdx,
+				Fill:     [4]uint8{c.Fill.R, c.Fill.G, c.Fill.B, c.Fill.A},
+				fileItem: fileItem(c.Item),
+			})
+		}
+		for _, n := range t.Numbers {
+			ft.Numbers = append(ft.Numbers, fileNumber{
+				X:     n.Center.X,
+				Y:     n.Center.Y,
+				Value: n.Value,
+				Size:  n.Size,
+				Color: rgba(n.Color),
+				Link:  n.Link,
+			})
+		}
+		f.Tabs = append(f.Tabs, ft)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// Read decodes a project written by Write, of this version or an older one.
+// A version 1 tab has its annotations in its pixels, and so reads with no
+// texts, callouts or numbered markers of its own.
+func Read(r io.Reader) (*Project, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("read project: %w", err)
+	}
+	if f.Format != format {
+		return nil, errors.New("not a shineyshot project")
+	}
+	if f.Version > Version {
+		return nil, fmt.Errorf("project version %d is newer than this shineyshot reads (%d)", f.Version, Version)
+	}
+	if len(f.Tabs) == 0 {
+		return nil, errors.New("project has no tabs")
+	}
+	p := &Project{Current: f.Current}
+	if p.Current < 0 || p.Current >= len(f.Tabs) {
+		p.Current = 0
+	}
+	for i, ft := range f.Tabs {
+		src, err := png.Decode(bytes.NewReader(ft.PNG))
+		if err != nil {
+			return nil, fmt.Errorf("decode tab %d: %w", i+1, err)
+		}
+		img, ok := src.(*image.RGBA)
+		if !ok {
+			img = image.NewRGBA(src.Bounds())
+			draw.Draw(img, img.Rect, src, src.Bounds().Min, draw.Src)
+		}
+		t := Tab{
+			Title:         ft.Title,
+			Image:         img,
+			Offset:        image.Pt(ft.OffsetX, ft.OffsetY),
+			Zoom:          ft.Zoom,
+			NextNumber:    ft.NextNumber,
+			WidthIdx:      ft.WidthIdx,
+			ShadowApplied: ft.ShadowApplied,
+			SavedPath:     ft.SavedPath,
+		}
+		if f.Version >= 2 {
+			readItems(&t, ft)
+		}
+		p.Tabs = append(p.Tabs, t)
+	}
+	return p, nil
+}
+
+// readItems fills in t's texts, callouts and numbered markers from ft.
+func readItems(t *Tab, ft fileTab) {
+	for _, x := range ft.Texts {
+		t.Texts = append(t.Texts, Text{
+			Text:    x.Text,
+			Pos:     image.Pt(x.X, x.Y),
+			SizeIdx: x.SizeIdx,
+			Color:   fromRGBA(x.Color),
+			Item:    Item(x.fileItem),
+		})
+	}
+	for _, c := range ft.Callouts {
+		t.Callouts = append(t.Callouts, Callout{
+			Box:      image.Rect(c.MinX, c.MinY, c.MaxX, c.MaxY),
+			Tip:      image.Pt(c.TipX, c.TipY),
+			Text:     c.Text,
+			SizeIdx:  c.SizeIdx,
+			Color:    fromRGBA(c.Color),
+			WidthIdx: c.WidthIdx,
+			Fill:     color.NRGBA{c.Fill[0], c.Fill[1], c.Fill[2], c.Fill[3]},
+			Item:     Item(c.fileItem),
+		})
+	}
+	for _, n := range ft.Numbers {
+		t.Numbers = append(t.Numbers, Number{
+			Center: image.Pt(n.X, n.Y),
+			Value:  n.Value,
+			Size:   n.Size,
+			Color:  fromRGBA(n.Color),
+			Link:   n.Link,
+		})
+	}
+}
+
+// rgba stores c as its red, green, blue and alpha bytes.
+func rgba(c color.RGBA) [4]uint8 {
+	return [4]uint8{c.R, c.G, c.B, c.A}
+}
+
+// fromRGBA is the colour rgba stored.
+func fromRGBA(b [4]uint8) color.RGBA {
+	return color.RGBA{b[0], b[1], b[2], b[3]}
+}
+
+// Save writes p to path.
+func Save(path string, p *Project) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Open reads the project at path.
+func Open(path string) (*Project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
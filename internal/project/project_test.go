@@ -0,0 +1,87 @@
+package project
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveOpen(t *testing.T) {
+	first := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	first.SetRGBA(3, 4, color.RGBA{0xff, 0, 0, 0xff})
+	second := image.NewRGBA(image.Rect(0, 0, 8, 9))
+	second.SetRGBA(7, 8, color.RGBA{0, 0x80, 0, 0x80})
+	p := &Project{
+		Current: 1,
+		Tabs: []Tab{
+			{
+				Title: "1", Image: first, Offset: image.Pt(-12, 5), Zoom: 1.5, NextNumber: 4, WidthIdx: 3, ShadowApplied: true, SavedPath: "/tmp/a.png",
+				Texts: []Text{{Text: "note", Pos: image.Pt(2, 12), SizeIdx: 1, Color: color.RGBA{0, 0, 0xff, 0xff}, Item: Item{Z: 1, Locked: true}}},
+				Callouts: []Callout{{
+					Box: image.Rect(1, 1, 20, 8), Tip: image.Pt(4, 16), Text: "look", SizeIdx: 0, Color: color.RGBA{0xff, 0, 0, 0xff}, WidthIdx: 2,
+					Fill: color.NRGBA{0xff, 0xff, 0xee, 0xf0}, Item: Item{Z: 0, Hidden: true},
+				}},
+				Numbers: []Number{{Center: image.Pt(5, 5), Value: 1, Size: 8, Color: color.RGBA{0x10, 0x20, 0x30, 0xff}}, {Center: image.Pt(25, 15), Value: 2, Size: 8, Color: color.RGBA{0x10, 0x20, 0x30, 0xff}, Link: 2}},
+			},
+			{Title: "crop", Image: second, Zoom: 1, NextNumber: 1},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "session"+Ext)
+	if err := Save(path, p); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Current != 1 || len(got.Tabs) != 2 {
+		t.Fatalf("got current %d and %d tabs", got.Current, len(got.Tabs))
+	}
+	for i, want := range p.Tabs {
+		tab := got.Tabs[i]
+		if !bytes.Equal(tab.Image.Pix, want.Image.Pix) || tab.Image.Rect != want.Image.Rect {
+			t.Errorf("tab %d: pixels differ", i)
+		}
+		tab.Image, want.Image = nil, nil
+		if !reflect.DeepEqual(tab, want) {
+			t.Errorf("tab %d: got %+v, want %+v", i, tab, want)
+		}
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"other json": `{"name": "package.json"}`,
+		"newer":      `{"format": "shineyshot-project", "version": 99, "tabs": [{}]}`,
+		"empty":      `{"format": "shineyshot-project", "version": 1, "tabs": []}`,
+	} {
+		if _, err := Read(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: read succeeded", name)
+		}
+	}
+	if !IsProject("a/b.ShineyShot") || IsProject("b.png") {
+		t.Error("IsProject does not go by the extension")
+	}
+}
+
+func TestReadVersion1(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Project{Tabs: []Tab{{Title: "1", Image: image.NewRGBA(image.Rect(0, 0, 2, 2)), Zoom: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	// A version 1 project has its markup in its pixels, so any items the
+	// document carries are not drawn a second time.
+	doc := strings.Replace(buf.String(), `"version": 2`, `"version": 1`, 1)
+	doc = strings.Replace(doc, `"png":`, `"texts": [{"text": "drawn", "z": 0}], "png":`, 1)
+	p, err := Read(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tabs) != 1 || p.Tabs[0].Title != "1" || p.Tabs[0].Texts != nil {
+		t.Fatalf("read %+v", p.Tabs)
+	}
+}
@@ -0,0 +1,74 @@
+package record
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// quantize reduces img to the 256 most used colors, counted at 5 bits a
+// channel. Screens are mostly flat colors, which this keeps exactly where
+// a fixed palette or dithering would band or speckle them.
+func quantize(img *image.RGBA) *image.Paletted {
+	const bins = 1 << 15
+	bin := func(r, g, b uint8) int {
+		return int(r>>3)<<10 | int(g>>3)<<5 | int(b>>3)
+	}
+	var count [bins]uint32
+	var sum [bins][3]uint64
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[4*x:]
+			i := bin(p[0], p[1], p[2])
+			count[i]++
+			sum[i][0] += uint64(p[0])
+			sum[i][1] += uint64(p[1])
+			sum[i][2] += uint64(p[2])
+		}
+	}
+	var used []int
+	for i, n := range count {
+		if n > 0 {
+			used = append(used, i)
+		}
+	}
+	sort.Slice(used, func(i, j int) bool { return count[used[i]] > count[used[j]] })
+
+	// Each bin's color is the average of its pixels.
+	avg := func(i int) color.RGBA {
+		n := uint64(count[i])
+		return color.RGBA{uint8(sum[i][0] / n), uint8(sum[i][1] / n), uint8(sum[i][2] / n), 0xff}
+	}
+	pal := make(color.Palette, 0, 256)
+	var index [bins]uint8
+	for _, i := range used[:min(len(used), 256)] {
+		index[i] = uint8(len(pal))
+		pal = append(pal, avg(i))
+	}
+	// Rarer bins take the nearest palette entry.
+	for _, i := range used[len(pal):] {
+		c := avg(i)
+		best, bestDist := 0, -1
+		for j, pc := range pal {
+			p := pc.(color.RGBA)
+			dr, dg, db := int(c.R)-int(p.R), int(c.G)-int(p.G), int(c.B)-int(p.B)
+			if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+				best, bestDist = j, d
+			}
+		}
+		index[i] = uint8(best)
+	}
+
+	out := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), pal)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		dst := out.Pix[out.PixOffset(0, y-b.Min.Y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[4*x:]
+			dst[x] = index[bin(p[0], p[1], p[2])]
+		}
+	}
+	return out
+}
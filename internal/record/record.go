@@ -0,0 +1,149 @@
+// Package record assembles captured frames into animated GIF or WebP
+// files, both encoded in Go, or into MP4 or WebM videos by piping them to
+// ffmpeg.
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/example/shineyshot/internal/webp"
+)
+
+// Format is an animated image format.
+type Format string
+
+const (
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+	FormatMP4  Format = "mp4"
+	FormatWebM Format = "webm"
+)
+
+// ParseFormat validates a format name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatGIF, FormatWebP, FormatMP4, FormatWebM:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown recording format %q (want gif, webp, mp4 or webm)", s)
+}
+
+// FormatOf picks the format from path's extension, GIF when it is not
+// .webp, .mp4 or .webm.
+func FormatOf(path string) Format {
+	switch f := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))); f {
+	case FormatWebP, FormatMP4, FormatWebM:
+		return f
+	}
+	return FormatGIF
+}
+
+// IsVideo reports whether f is encoded by ffmpeg.
+func (f Format) IsVideo() bool {
+	return f == FormatMP4 || f == FormatWebM
+}
+
+// Recorder collects frames for one animation. A frame identical to the one
+// before it lengthens that frame rather than adding another, which keeps
+// recordings of a mostly still screen small. GIF frames are reduced to a
+// palette of their own most used colors as they arrive and WebP frames are
+// compressed, so no full color frames are kept beyond the latest.
+type Recorder struct {
+	format   Format
+	interval time.Duration
+	anim     webp.Animation
+	gif      gif.GIF
+	canvas   image.Rectangle
+
+	// pending is the latest distinct frame, added once its duration is
+	// known; last stays set once Encode has added it.
+	pending   *image.RGBA
+	pendingAt time.Time
+	last      *image.RGBA
+}
+
+// New returns a Recorder for format. interval is how long the last frame
+// is shown. o sets how WebP frames are encoded and may be nil.
+func New(format Format, interval time.Duration, o *webp.Options) *Recorder {
+	return &Recorder{format: format, interval: interval, anim: webp.Animation{Options: o}}
+}
+
+// Add appends a frame captured at t. Frames of another size than the first
+// are cropped or padded to it.
+func (r *Recorder) Add(img *image.RGBA, t time.Time) error {
+	if r.last == nil {
+		if img.Bounds().Empty() {
+			return errors.New("record: empty frame")
+		}
+		r.canvas = image.Rectangle{Max: img.Bounds().Size()}
+	}
+	if img.Bounds() != r.canvas {
+		fit := image.NewRGBA(r.canvas)
+		draw.Draw(fit, fit.Rect, img, img.Bounds().Min, draw.Src)
+		img = fit
+	}
+	if r.pending != nil {
+		if bytes.Equal(r.pending.Pix, img.Pix) {
+			return nil
+		}
+		if err := r.flush(t.Sub(r.pendingAt)); err != nil {
+			return err
+		}
+	}
+	r.pending, r.pendingAt, r.last = img, t, img
+	return nil
+}
+
+// flush adds the pending frame, shown for d.
+func (r *Recorder) flush(d time.Duration) error {
+	if r.format == FormatWebP {
+		return r.anim.Add(r.pending, d)
+	}
+	r.gif.Image = append(r.gif.Image, quantize(r.pending))
+	// GIF delays are in hundredths of a second, and players treat anything
+	// under two as a default of ten.
+	r.gif.Delay = append(r.gif.Delay, max(int((d+5*time.Millisecond)/(10*time.Millisecond)), 2))
+	return nil
+}
+
+// Frames returns the number of distinct frames added.
+func (r *Recorder) Frames() int {
+	n := len(r.gif.Image) + r.anim.Frames()
+	if r.pending != nil {
+		n++
+	}
+	return n
+}
+
+// Last returns the latest frame, or nil before the first.
+func (r *Recorder) Last() *image.RGBA {
+	return r.last
+}
+
+// Encode writes the animation, looping forever. Call it once, after the
+// last frame.
+func (r *Recorder) Encode(w io.Writer) error {
+	if r.pending != nil {
+		if err := r.flush(r.interval); err != nil {
+			return err
+		}
+		r.pending = nil
+	}
+	if r.Frames() == 0 {
+		return errors.New("record: no frames recorded")
+	}
+	if r.format == FormatWebP {
+		return r.anim.Encode(w)
+	}
+	r.gif.Config = image.Config{Width: r.canvas.Dx(), Height: r.canvas.Dy()}
+	return gif.EncodeAll(w, &r.gif)
+}
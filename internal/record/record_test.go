@@ -0,0 +1,82 @@
+package record
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Rect, image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestRecorderGIF(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0x20, 0x40, 0xc0, 0xff}
+	start := time.Now()
+	r := New(FormatGIF, 100*time.Millisecond, nil)
+	for i, c := range []color.RGBA{red, red, red, blue} {
+		if err := r.Add(solid(8, 6, c), start.Add(time.Duration(i)*100*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A frame of another size is fitted to the first.
+	bigger := solid(12, 12, red)
+	if err := r.Add(bigger, start.Add(500*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames() != 3 {
+		t.Errorf("Frames() = %d, want 3 with the repeats merged", r.Frames())
+	}
+	if r.Last().Bounds() != image.Rect(0, 0, 8, 6) {
+		t.Errorf("Last() bounds = %v, want the first frame's", r.Last().Bounds())
+	}
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{30, 20, 10}; len(g.Delay) != len(want) || g.Delay[0] != want[0] || g.Delay[1] != want[1] || g.Delay[2] != want[2] {
+		t.Errorf("delays = %v, want %v", g.Delay, want)
+	}
+	if got := color.RGBAModel.Convert(g.Image[1].At(3, 3)); got != blue {
+		t.Errorf("second frame is %v, want %v", got, blue)
+	}
+}
+
+func TestRecorderWebP(t *testing.T) {
+	r := New(FormatWebP, time.Second/10, nil)
+	start := time.Now()
+	for i := range 3 {
+		if err := r.Add(solid(4, 4, color.RGBA{uint8(i * 80), 0, 0, 0xff}), start.Add(time.Duration(i)*time.Second/10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("ANMF")); n != 3 {
+		t.Errorf("got %d ANMF chunks, want 3", n)
+	}
+}
+
+func TestQuantizeKeepsFlatColors(t *testing.T) {
+	img := solid(16, 16, color.RGBA{0x12, 0x34, 0x56, 0xff})
+	draw.Draw(img, image.Rect(4, 4, 8, 8), image.NewUniform(color.RGBA{0xfe, 0xfd, 0xfc, 0xff}), image.Point{}, draw.Src)
+	p := quantize(img)
+	for _, pt := range []image.Point{{0, 0}, {5, 5}} {
+		if got, want := color.RGBAModel.Convert(p.At(pt.X, pt.Y)), img.At(pt.X, pt.Y); got != want {
+			t.Errorf("pixel %v = %v, want %v", pt, got, want)
+		}
+	}
+}
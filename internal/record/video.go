@@ -0,0 +1,129 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ffmpegCommand is the encoder video recordings are piped to.
+var ffmpegCommand = "ffmpeg"
+
+// video pipes raw RGBA frames to ffmpeg at a constant frame rate, repeating
+// frames to cover ticks the capture missed so the video keeps real time.
+type video struct {
+	path   string
+	format Format
+	fps    int
+
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	stderr bytes.Buffer
+	canvas image.Rectangle
+	start  time.Time
+	frames int
+	last   *image.RGBA
+	// err is set once ffmpeg has failed and been waited for.
+	err error
+}
+
+func newVideo(path string, format Format, fps int) (*video, error) {
+	if _, err := exec.LookPath(ffmpegCommand); err != nil {
+		return nil, fmt.Errorf("%s recordings need ffmpeg: %w", format, err)
+	}
+	return &video{path: path, format: format, fps: fps}, nil
+}
+
+// ffmpegArgs reads raw frames of size from stdin. yuv420p, which players
+// expect, needs even dimensions, so odd ones are padded.
+func ffmpegArgs(path string, format Format, size image.Point, fps int) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y",
+		"-f", "rawvideo", "-pix_fmt", "rgba", "-s", fmt.Sprintf("%dx%d", size.X, size.Y),
+		"-framerate", strconv.Itoa(fps), "-i", "-",
+		"-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2", "-pix_fmt", "yuv420p"}
+	switch format {
+	case FormatWebM:
+		args = append(args, "-c:v", "libvpx-vp9", "-b:v", "0", "-crf", "32", "-deadline", "realtime", "-cpu-used", "8")
+	default:
+		args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-movflags", "+faststart")
+	}
+	return append(args, path)
+}
+
+func (v *video) Add(img *image.RGBA, t time.Time) error {
+	if v.err != nil {
+		return v.err
+	}
+	if v.cmd == nil {
+		if img.Bounds().Empty() {
+			return errors.New("record: empty frame")
+		}
+		v.canvas = image.Rectangle{Max: img.Bounds().Size()}
+		v.cmd = exec.Command(ffmpegCommand, ffmpegArgs(v.path, v.format, v.canvas.Size(), v.fps)...)
+		v.cmd.Stderr = &v.stderr
+		in, err := v.cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := v.cmd.Start(); err != nil {
+			return fmt.Errorf("start ffmpeg: %w", err)
+		}
+		v.in, v.start = in, t
+	}
+	if img.Bounds() != v.canvas || img.Stride != 4*v.canvas.Dx() {
+		fit := image.NewRGBA(v.canvas)
+		draw.Draw(fit, fit.Rect, img, img.Bounds().Min, draw.Src)
+		img = fit
+	}
+	v.last = img
+	// The frame covers every tick up to its capture time.
+	due := int(t.Sub(v.start)*time.Duration(v.fps)/time.Second) + 1
+	for v.frames < due {
+		if _, err := v.in.Write(img.Pix); err != nil {
+			// ffmpeg has exited; wait for it to have its message.
+			v.in.Close()
+			v.cmd.Wait()
+			v.err = v.failed(err)
+			return v.err
+		}
+		v.frames++
+	}
+	return nil
+}
+
+func (v *video) Frames() int {
+	return v.frames
+}
+
+func (v *video) Last() *image.RGBA {
+	return v.last
+}
+
+func (v *video) Close() error {
+	if v.err != nil {
+		return v.err
+	}
+	if v.cmd == nil {
+		return errors.New("record: no frames recorded")
+	}
+	v.in.Close()
+	if err := v.cmd.Wait(); err != nil {
+		return v.failed(err)
+	}
+	return nil
+}
+
+// failed adds what ffmpeg reported to err.
+func (v *video) failed(err error) error {
+	if msg := strings.TrimSpace(v.stderr.String()); msg != "" {
+		return fmt.Errorf("ffmpeg: %w: %s", err, msg)
+	}
+	return fmt.Errorf("ffmpeg: %w", err)
+}
@@ -0,0 +1,71 @@
+package record
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestVideoPipesFramesToFFmpeg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script in place of ffmpeg")
+	}
+	// The stand-in ffmpeg copies the raw frames to the output file, the
+	// last argument.
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\nfor a; do out=$a; done\ncat > \"$out\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := ffmpegCommand
+	ffmpegCommand = fake
+	defer func() { ffmpegCommand = old }()
+
+	out := filepath.Join(dir, "clip.mp4")
+	w, err := Create(out, FormatOf(out), 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	// The third frame arrives late, so the second one fills the gap.
+	for _, at := range []time.Duration{0, 100 * time.Millisecond, 400 * time.Millisecond} {
+		if err := w.Add(image.NewRGBA(image.Rect(0, 0, 3, 2)), start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Frames() != 5 {
+		t.Errorf("Frames() = %d, want 5", w.Frames())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 5*3*2*4 {
+		t.Errorf("ffmpeg got %d bytes, want 5 frames of 3x2", len(data))
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	args := ffmpegArgs("out.webm", FormatWebM, image.Pt(641, 480), 15)
+	want := map[string]string{"-s": "641x480", "-framerate": "15", "-c:v": "libvpx-vp9"}
+	for idx := 0; idx+1 < len(args); idx++ {
+		if v, ok := want[args[idx]]; ok {
+			if args[idx+1] != v {
+				t.Errorf("%s %s, want %s", args[idx], args[idx+1], v)
+			}
+			delete(want, args[idx])
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing arguments %v in %q", want, args)
+	}
+	if args[len(args)-1] != "out.webm" {
+		t.Errorf("output %q, want out.webm last", args[len(args)-1])
+	}
+}
@@ -0,0 +1,54 @@
+package record
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/example/shineyshot/internal/webp"
+)
+
+// Writer receives the frames of a recording and writes them to its file.
+type Writer interface {
+	// Add appends a frame captured at t.
+	Add(img *image.RGBA, t time.Time) error
+	// Frames returns the number of frames kept so far.
+	Frames() int
+	// Last returns the latest frame, or nil before the first.
+	Last() *image.RGBA
+	// Close finishes the file.
+	Close() error
+}
+
+// Create starts a recording at path in format, captured fps times a second.
+// GIF and WebP files are written when the Writer is closed; video frames go
+// to ffmpeg as they arrive, which must be on the PATH. o sets how WebP
+// frames are encoded and may be nil.
+func Create(path string, format Format, fps int, o *webp.Options) (Writer, error) {
+	if fps <= 0 {
+		return nil, fmt.Errorf("record: frame rate must be positive")
+	}
+	if format.IsVideo() {
+		return newVideo(path, format, fps)
+	}
+	return &fileRecorder{Recorder: New(format, time.Second/time.Duration(fps), o), path: path}, nil
+}
+
+// fileRecorder writes a Recorder's animation to path when closed.
+type fileRecorder struct {
+	*Recorder
+	path string
+}
+
+func (r *fileRecorder) Close() error {
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	if err := r.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,188 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"github.com/example/shineyshot/internal/fonts"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Chrome is the window decoration ApplyFrame draws around a screenshot to
+// make it look like an application window.
+type Chrome string
+
+const (
+	// ChromeNone leaves the screenshot undecorated.
+	ChromeNone Chrome = ""
+	// ChromeMacOS adds a title bar with red, yellow and green buttons.
+	ChromeMacOS Chrome = "macos"
+	// ChromeGNOME adds a GNOME header bar with a close button.
+	ChromeGNOME Chrome = "gnome"
+	// ChromeBrowser adds a browser toolbar whose address bar shows
+	// FrameOptions.URL.
+	ChromeBrowser Chrome = "browser"
+)
+
+// urlPlaceholder fills the address bar when no URL is set.
+const urlPlaceholder = "Search or enter address"
+
+var (
+	chromeText     = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chromeFaint    = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	chromeDivider  = color.RGBA{0xd4, 0xd4, 0xd4, 0xff}
+	trafficLights  = []color.RGBA{{0xff, 0x5f, 0x57, 0xff}, {0xfe, 0xbc, 0x2e, 0xff}, {0x28, 0xc8, 0x40, 0xff}}
+	chromeFaceOnce sync.Once
+	chromeFace     font.Face
+)
+
+// chromeFont returns the face used for titles and URLs, or nil if the
+// embedded font fails to load, in which case text is left out.
+func chromeFont() font.Face {
+	chromeFaceOnce.Do(func() {
+		if f, err := fonts.NewFace(13); err == nil {
+			chromeFace = f
+		}
+	})
+	return chromeFace
+}
+
+// addChrome returns img below a title bar or browser toolbar in the style of
+// opts.Chrome.
+func addChrome(img *image.RGBA, opts FrameOptions) *image.RGBA {
+	barHeight, barColor := 28, color.RGBA{0xec, 0xec, 0xec, 0xff}
+	switch opts.Chrome {
+	case ChromeGNOME:
+		barHeight, barColor = 40, color.RGBA{0xeb, 0xeb, 0xeb, 0xff}
+	case ChromeBrowser:
+		barHeight, barColor = 44, color.RGBA{0xf1, 0xf1, 0xf4, 0xff}
+	}
+	b := img.Bounds()
+	w := b.Dx()
+	out := image.NewRGBA(image.Rect(0, 0, w, b.Dy()+barHeight))
+	bar := image.Rect(0, 0, w, barHeight)
+	draw.Draw(out, bar, image.NewUniform(barColor), image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(0, barHeight-1, w, barHeight), image.NewUniform(chromeDivider), image.Point{}, draw.Src)
+	draw.Draw(out, image.Rect(0, barHeight, w, out.Bounds().Dy()), img, b.Min, draw.Src)
+
+	mid := barHeight / 2
+	switch opts.Chrome {
+	case ChromeMacOS:
+		drawTrafficLights(out, mid)
+		drawText(out, opts.Title, image.Rect(76, 0, w-76, barHeight), chromeText, true)
+	case ChromeGNOME:
+		button := image.Rect(w-34, mid-12, w-10, mid+12)
+		fillRounded(out, button, 12, color.RGBA{0xdc, 0xdc, 0xdc, 0xff})
+		c := button.Min.Add(image.Pt(12, 12))
+		strokeLine(out, float64(c.X-4), float64(c.Y-4), float64(c.X+4), float64(c.Y+4), 1.5, chromeText)
+		strokeLine(out, float64(c.X-4), float64(c.Y+4), float64(c.X+4), float64(c.Y-4), 1.5, chromeText)
+		drawText(out, opts.Title, image.Rect(44, 0, w-44, barHeight), chromeText, true)
+	case ChromeBrowser:
+		drawTrafficLights(out, mid)
+		field := image.Rect(80, mid-14, max(w-12, 120), mid+14)
+		fillRounded(out, field, 8, chromeDivider)
+		fillRounded(out, field.Inset(1), 7, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		text, col := opts.URL, chromeText
+		if text == "" {
+			text, col = urlPlaceholder, chromeFaint
+		}
+		drawText(out, text, field.Inset(12), col, false)
+	}
+	return out
+}
+
+// drawTrafficLights draws the macOS close, minimize and zoom buttons
+// centered on row y.
+func drawTrafficLights(dst *image.RGBA, y int) {
+	for i, c := range trafficLights {
+		x := 14 + i*20
+		fillRounded(dst, image.Rect(x-6, y-6, x+6, y+6), 6, c)
+	}
+}
+
+// drawText writes s in the chrome font, vertically centered in r and either
+// horizontally centered or left aligned. Text too wide for r is shortened
+// with an ellipsis.
+func drawText(dst *image.RGBA, s string, r image.Rectangle, c color.RGBA, center bool) {
+	face := chromeFont()
+	if face == nil || s == "" || r.Dx() <= 0 {
+		return
+	}
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	limit := fixed.I(r.Dx())
+	if d.MeasureString(s) > limit {
+		runes := []rune(s)
+		for len(runes) > 0 && d.MeasureString(string(runes)+"…") > limit {
+			runes = runes[:len(runes)-1]
+		}
+		s = string(runes) + "…"
+	}
+	m := face.Metrics()
+	y := r.Min.Y + (r.Dy()+m.Ascent.Ceil()-m.Descent.Ceil())/2
+	x := fixed.I(r.Min.X)
+	if center {
+		x += (limit - d.MeasureString(s)) / 2
+	}
+	d.Dot = fixed.Point26_6{X: x, Y: fixed.I(y)}
+	fonts.Draw(d, s)
+}
+
+// fillRounded paints r in c with corners of the given radius, antialiasing
+// the curved edges. A radius of half r's size gives a circle.
+func fillRounded(dst *image.RGBA, r image.Rectangle, radius float64, c color.RGBA) {
+	left, top := float64(r.Min.X)+radius, float64(r.Min.Y)+radius
+	right, bottom := float64(r.Max.X)-radius, float64(r.Max.Y)-radius
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			dx := max(left-px, px-right, 0)
+			dy := max(top-py, py-bottom, 0)
+			if dx == 0 && dy == 0 {
+				blend(dst, x, y, c, 1)
+				continue
+			}
+			if cover := radius - math.Hypot(dx, dy) + 0.5; cover > 0 {
+				blend(dst, x, y, c, min(cover, 1))
+			}
+		}
+	}
+}
+
+// strokeLine draws an antialiased line of the given width.
+func strokeLine(dst *image.RGBA, x0, y0, x1, y1, width float64, c color.RGBA) {
+	half := width / 2
+	minX, maxX := int(math.Floor(min(x0, x1)-half)), int(math.Ceil(max(x0, x1)+half))
+	minY, maxY := int(math.Floor(min(y0, y1)-half)), int(math.Ceil(max(y0, y1)+half))
+	dx, dy := x1-x0, y1-y0
+	length2 := dx*dx + dy*dy
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if length2 > 0 {
+				t = min(max(((px-x0)*dx+(py-y0)*dy)/length2, 0), 1)
+			}
+			dist := math.Hypot(px-(x0+t*dx), py-(y0+t*dy))
+			if cover := half - dist + 0.5; cover > 0 {
+				blend(dst, x, y, c, min(cover, 1))
+			}
+		}
+	}
+}
+
+// blend draws c at (x, y) with the given coverage, over what is there.
+func blend(dst *image.RGBA, x, y int, c color.RGBA, cover float64) {
+	if !(image.Point{x, y}.In(dst.Bounds())) {
+		return
+	}
+	i := dst.PixOffset(x, y)
+	sa := float64(c.A) * cover
+	k := 1 - sa/255
+	for ch, v := range [4]uint8{c.R, c.G, c.B, c.A} {
+		dst.Pix[i+ch] = uint8(float64(v)*cover + float64(dst.Pix[i+ch])*k + 0.5)
+	}
+}
@@ -0,0 +1,227 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Insets are distances from each side of an image, in pixels.
+type Insets struct {
+	Top, Right, Bottom, Left int
+}
+
+// UniformInsets returns n on every side.
+func UniformInsets(n int) Insets {
+	return Insets{Top: n, Right: n, Bottom: n, Left: n}
+}
+
+// ParseInsets reads insets written as in CSS: "N" for every side, "V,H"
+// for top and bottom then left and right, or "T,R,B,L".
+func ParseInsets(spec string) (Insets, error) {
+	parts := strings.Split(spec, ",")
+	vals := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 0 {
+			return Insets{}, fmt.Errorf("invalid padding %q: sides must be non-negative integers", spec)
+		}
+		vals[i] = v
+	}
+	switch len(vals) {
+	case 1:
+		return UniformInsets(vals[0]), nil
+	case 2:
+		return Insets{Top: vals[0], Right: vals[1], Bottom: vals[0], Left: vals[1]}, nil
+	case 4:
+		return Insets{Top: vals[0], Right: vals[1], Bottom: vals[2], Left: vals[3]}, nil
+	}
+	return Insets{}, fmt.Errorf("invalid padding %q: give 1, 2 or 4 comma separated values", spec)
+}
+
+// FrameOptions configures the backdrop ApplyFrame places a screenshot on.
+type FrameOptions struct {
+	// Padding is the backdrop visible on each side of the screenshot.
+	Padding Insets
+	// CornerRadius rounds the screenshot's corners.
+	CornerRadius int
+	// Background is the backdrop color, or the top-left end of the gradient
+	// when GradientEnd is set.
+	Background color.RGBA
+	// GradientEnd, unless fully transparent, turns the backdrop into a
+	// diagonal gradient from Background to this color at the bottom right.
+	GradientEnd color.RGBA
+	// Shadow is cast by the screenshot onto the backdrop; a zero Opacity
+	// disables it.
+	Shadow ShadowOptions
+	// Chrome wraps the screenshot in fake window decoration, with Title in
+	// its title bar or, for ChromeBrowser, URL in its address bar.
+	Chrome Chrome
+	Title  string
+	URL    string
+}
+
+// FramePreset is a named FrameOptions combination.
+type FramePreset struct {
+	Name        string
+	Description string
+	Options     FrameOptions
+}
+
+// DefaultFramePreset names the preset used when none is chosen.
+const DefaultFramePreset = "ocean"
+
+// FramePresets returns the built-in presets in display order.
+func FramePresets() []FramePreset {
+	softShadow := ShadowOptions{Radius: 16, Offset: image.Pt(0, 8), Opacity: 0.35}
+	deepShadow := ShadowOptions{Radius: 24, Offset: image.Pt(0, 12), Opacity: 0.45}
+	return []FramePreset{
+		{
+			Name:        "ocean",
+			Description: "blue to violet gradient, rounded corners and a deep shadow",
+			Options: FrameOptions{Padding: UniformInsets(64), CornerRadius: 12, Shadow: deepShadow,
+				Background: color.RGBA{0x4f, 0xac, 0xfe, 0xff}, GradientEnd: color.RGBA{0x8e, 0x54, 0xe9, 0xff}},
+		},
+		{
+			Name:        "sunset",
+			Description: "orange to pink gradient, rounded corners and a deep shadow",
+			Options: FrameOptions{Padding: UniformInsets(64), CornerRadius: 12, Shadow: deepShadow,
+				Background: color.RGBA{0xff, 0x9a, 0x56, 0xff}, GradientEnd: color.RGBA{0xff, 0x4f, 0x8b, 0xff}},
+		},
+		{
+			Name:        "midnight",
+			Description: "charcoal gradient, rounded corners and a deep shadow",
+			Options: FrameOptions{Padding: UniformInsets(48), CornerRadius: 10, Shadow: ShadowOptions{Radius: 24, Offset: image.Pt(0, 12), Opacity: 0.6},
+				Background: color.RGBA{0x23, 0x25, 0x26, 0xff}, GradientEnd: color.RGBA{0x41, 0x43, 0x45, 0xff}},
+		},
+		{
+			Name:        "macos",
+			Description: "macOS-style window with traffic light buttons on a blue gradient",
+			Options: FrameOptions{Padding: UniformInsets(64), CornerRadius: 10, Shadow: deepShadow, Chrome: ChromeMacOS,
+				Background: color.RGBA{0x4f, 0xac, 0xfe, 0xff}, GradientEnd: color.RGBA{0x8e, 0x54, 0xe9, 0xff}},
+		},
+		{
+			Name:        "gnome",
+			Description: "GNOME-style window with a header bar on a blue gradient",
+			Options: FrameOptions{Padding: UniformInsets(64), CornerRadius: 12, Shadow: deepShadow, Chrome: ChromeGNOME,
+				Background: color.RGBA{0x62, 0xa0, 0xea, 0xff}, GradientEnd: color.RGBA{0x1c, 0x71, 0xd8, 0xff}},
+		},
+		{
+			Name:        "browser",
+			Description: "browser window whose address bar shows the frame URL, on a pastel gradient",
+			Options: FrameOptions{Padding: UniformInsets(64), CornerRadius: 10, Shadow: deepShadow, Chrome: ChromeBrowser,
+				Background: color.RGBA{0xe0, 0xc3, 0xfc, 0xff}, GradientEnd: color.RGBA{0x8e, 0xc5, 0xfc, 0xff}},
+		},
+		{
+			Name:        "subtle",
+			Description: "light grey backdrop, small corners and a soft shadow",
+			Options: FrameOptions{Padding: UniformInsets(32), CornerRadius: 8, Shadow: softShadow,
+				Background: color.RGBA{0xf2, 0xf2, 0xf5, 0xff}},
+		},
+		{
+			Name:        "plain",
+			Description: "white border only",
+			Options:     FrameOptions{Padding: UniformInsets(24), Background: color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		},
+	}
+}
+
+// LookupFramePreset returns the options of the named preset.
+func LookupFramePreset(name string) (FrameOptions, error) {
+	var names []string
+	for _, p := range FramePresets() {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p.Options, nil
+		}
+		names = append(names, p.Name)
+	}
+	return FrameOptions{}, fmt.Errorf("unknown frame preset %q (choose %s)", name, strings.Join(names, ", "))
+}
+
+// ApplyFrame returns img with any window chrome, rounded corners and a
+// shadow, on a solid or gradient backdrop extending opts.Padding beyond each
+// side. The result has
+// a zero origin; img itself is left unchanged.
+func ApplyFrame(img *image.RGBA, opts FrameOptions) *image.RGBA {
+	if img == nil || img.Bounds().Empty() {
+		return img
+	}
+	pad := opts.Padding
+	pad = Insets{Top: max(pad.Top, 0), Right: max(pad.Right, 0), Bottom: max(pad.Bottom, 0), Left: max(pad.Left, 0)}
+	if opts.Chrome != ChromeNone {
+		img = addChrome(img, opts)
+	}
+	content := roundCorners(img, opts.CornerRadius)
+	size := content.Bounds().Size().Add(image.Pt(pad.Left+pad.Right, pad.Top+pad.Bottom))
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	fillBackdrop(dst, opts.Background, opts.GradientEnd)
+	// The shadow spreads past the screenshot; ApplyShadow reports where the
+	// screenshot sits in its result, so that point is aligned with the
+	// padding and the shadow falls around it.
+	shadowed := ApplyShadow(content, opts.Shadow)
+	at := image.Pt(pad.Left, pad.Top).Sub(shadowed.Offset)
+	draw.Draw(dst, shadowed.Image.Bounds().Add(at), shadowed.Image, image.Point{}, draw.Over)
+	return dst
+}
+
+// roundCorners copies img to a zero-origin image whose corners outside a
+// circle of radius are transparent, with antialiased edges.
+func roundCorners(img *image.RGBA, radius int) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(b.Sub(b.Min))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	radius = min(radius, b.Dx()/2, b.Dy()/2)
+	if radius <= 0 {
+		return out
+	}
+	w, h := b.Dx(), b.Dy()
+	r := float64(radius)
+	for y := 0; y < radius; y++ {
+		for x := 0; x < radius; x++ {
+			// Coverage is how far the pixel center lies inside the circle,
+			// clamped to one pixel either side of the edge.
+			dist := math.Hypot(r-(float64(x)+0.5), r-(float64(y)+0.5))
+			cover := r - dist + 0.5
+			if cover >= 1 {
+				continue
+			}
+			cover = max(cover, 0)
+			for _, p := range [4]image.Point{{x, y}, {w - 1 - x, y}, {x, h - 1 - y}, {w - 1 - x, h - 1 - y}} {
+				i := out.PixOffset(p.X, p.Y)
+				for c := 0; c < 4; c++ {
+					out.Pix[i+c] = uint8(float64(out.Pix[i+c])*cover + 0.5)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// fillBackdrop paints dst with from, or with a diagonal gradient from from
+// to to when to is not fully transparent.
+func fillBackdrop(dst *image.RGBA, from, to color.RGBA) {
+	b := dst.Bounds()
+	if to.A == 0 {
+		draw.Draw(dst, b, image.NewUniform(from), image.Point{}, draw.Src)
+		return
+	}
+	span := float64(max(b.Dx()+b.Dy()-2, 1))
+	lerp := func(a, b uint8, t float64) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			t := float64(x-b.Min.X+y-b.Min.Y) / span
+			dst.SetRGBA(x, y, color.RGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: lerp(from.A, to.A, t),
+			})
+		}
+	}
+}
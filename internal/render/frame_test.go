@@ -0,0 +1,107 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyFramePadsAndRoundsCorners(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	red := color.RGBA{255, 0, 0, 255}
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.SetRGBA(x, y, red)
+		}
+	}
+	backdrop := color.RGBA{0, 0, 255, 255}
+	out := ApplyFrame(img, FrameOptions{Padding: UniformInsets(10), CornerRadius: 8, Background: backdrop})
+	if want := image.Rect(0, 0, 60, 50); out.Bounds() != want {
+		t.Fatalf("bounds mismatch: got %v want %v", out.Bounds(), want)
+	}
+	if got := out.RGBAAt(0, 0); got != backdrop {
+		t.Fatalf("padding should show the backdrop, got %v", got)
+	}
+	if got := out.RGBAAt(10, 10); got != backdrop {
+		t.Fatalf("rounded corner should show the backdrop, got %v", got)
+	}
+	if got := out.RGBAAt(30, 25); got != red {
+		t.Fatalf("screenshot should be centered, got %v", got)
+	}
+	if img.RGBAAt(0, 0) != red {
+		t.Fatalf("source image was modified")
+	}
+}
+
+func TestApplyFrameGradientEnds(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	from, to := color.RGBA{0, 0, 0, 255}, color.RGBA{200, 100, 50, 255}
+	out := ApplyFrame(img, FrameOptions{Padding: UniformInsets(8), Background: from, GradientEnd: to})
+	b := out.Bounds()
+	if got := out.RGBAAt(0, 0); got != from {
+		t.Fatalf("top-left: got %v want %v", got, from)
+	}
+	if got := out.RGBAAt(b.Max.X-1, b.Max.Y-1); got != to {
+		t.Fatalf("bottom-right: got %v want %v", got, to)
+	}
+}
+
+func TestLookupFramePreset(t *testing.T) {
+	if _, err := LookupFramePreset(DefaultFramePreset); err != nil {
+		t.Fatalf("default preset: %v", err)
+	}
+	if _, err := LookupFramePreset("Sunset"); err != nil {
+		t.Fatalf("lookup should ignore case: %v", err)
+	}
+	if _, err := LookupFramePreset("nope"); err == nil {
+		t.Fatalf("expected an error for an unknown preset")
+	}
+}
+
+func TestApplyFrameChromeAddsTitleBar(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	plain := ApplyFrame(img, FrameOptions{})
+	for _, chrome := range []Chrome{ChromeMacOS, ChromeGNOME, ChromeBrowser} {
+		out := ApplyFrame(img, FrameOptions{Chrome: chrome, Title: "Settings", URL: "https://example.com"})
+		if out.Bounds().Dx() != plain.Bounds().Dx() || out.Bounds().Dy() <= plain.Bounds().Dy() {
+			t.Fatalf("%s: chrome should only add height, got %v from %v", chrome, out.Bounds(), plain.Bounds())
+		}
+		// The title bar sits above the screenshot and is not transparent.
+		if out.RGBAAt(150, 2).A == 0 {
+			t.Fatalf("%s: title bar missing", chrome)
+		}
+	}
+}
+
+func TestApplyFramePerSidePadding(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	red := color.RGBA{255, 0, 0, 255}
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{red.R, red.G, red.B, red.A})
+	}
+	out := ApplyFrame(img, FrameOptions{Padding: Insets{Top: 1, Right: 2, Bottom: 3, Left: 4}, Background: color.RGBA{A: 255}})
+	if want := image.Rect(0, 0, 16, 14); out.Bounds() != want {
+		t.Fatalf("bounds mismatch: got %v want %v", out.Bounds(), want)
+	}
+	if out.RGBAAt(4, 1) != red || out.RGBAAt(3, 1) == red || out.RGBAAt(4, 0) == red {
+		t.Fatalf("screenshot should start at (4,1)")
+	}
+}
+
+func TestParseInsets(t *testing.T) {
+	for spec, want := range map[string]Insets{
+		"8":       UniformInsets(8),
+		"8, 16":   {Top: 8, Right: 16, Bottom: 8, Left: 16},
+		"1,2,3,4": {Top: 1, Right: 2, Bottom: 3, Left: 4},
+	} {
+		got, err := ParseInsets(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseInsets(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "1,2,3", "-1", "a"} {
+		if _, err := ParseInsets(spec); err == nil {
+			t.Errorf("ParseInsets(%q) should fail", spec)
+		}
+	}
+}
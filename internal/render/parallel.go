@@ -0,0 +1,49 @@
+package render
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// minBandPixels is the smallest area Bands splits up; below it the
+// goroutines cost more than they save.
+const minBandPixels = 1 << 16
+
+// Parallel splits [0, n) into one contiguous range per CPU and calls fn for
+// each range concurrently.
+func Parallel(n int, fn func(lo, hi int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for lo := 0; lo < n; lo += chunk {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(lo, min(lo+chunk, n))
+		}()
+	}
+	wg.Wait()
+}
+
+// Bands calls fn concurrently for horizontal bands of r within dst, each
+// given as a sub-image of dst, so that drawing calls which clip to their
+// destination, such as draw.Draw and the x/image/draw scalers, can split
+// their work across CPUs. Small areas are passed to fn whole.
+func Bands(dst *image.RGBA, r image.Rectangle, fn func(band *image.RGBA)) {
+	r = r.Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	if r.Dx()*r.Dy() < minBandPixels {
+		fn(dst.SubImage(r).(*image.RGBA))
+		return
+	}
+	Parallel(r.Dy(), func(lo, hi int) {
+		fn(dst.SubImage(image.Rect(r.Min.X, r.Min.Y+lo, r.Max.X, r.Min.Y+hi)).(*image.RGBA))
+	})
+}
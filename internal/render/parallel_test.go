@@ -0,0 +1,73 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"sync"
+	"testing"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+func TestBandsCoverEachRowOnce(t *testing.T) {
+	dst := image.NewRGBA(image.Rect(0, 0, 600, 500))
+	r := image.Rect(-20, 30, 650, 480)
+	var mu sync.Mutex
+	rows := map[int]int{}
+	Bands(dst, r, func(band *image.RGBA) {
+		b := band.Bounds()
+		if b.Min.X != 0 || b.Max.X != 600 {
+			t.Errorf("band %v not clipped to the destination", b)
+		}
+		mu.Lock()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			rows[y]++
+		}
+		mu.Unlock()
+	})
+	for y := 30; y < 480; y++ {
+		if rows[y] != 1 {
+			t.Fatalf("row %d drawn %d times", y, rows[y])
+		}
+	}
+	if len(rows) != 450 {
+		t.Fatalf("drew %d rows, want 450", len(rows))
+	}
+}
+
+func TestBandsScaleMatchesSingleCall(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 317, 211))
+	for i := range src.Pix {
+		src.Pix[i] = byte(i * 31)
+	}
+	dr := image.Rect(-40, 13, 900, 720)
+	want := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	xdraw.NearestNeighbor.Scale(want, dr, src, src.Bounds(), draw.Over, nil)
+	got := image.NewRGBA(want.Bounds())
+	Bands(got, dr, func(band *image.RGBA) {
+		xdraw.NearestNeighbor.Scale(band, dr, src, src.Bounds(), draw.Over, nil)
+	})
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Fatal("banded scale differs from a single Scale call")
+	}
+}
+
+func benchmarkScale(b *testing.B, banded bool) {
+	src := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	dst := image.NewRGBA(image.Rect(0, 0, 2560, 1440))
+	dr := dst.Bounds()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if banded {
+			Bands(dst, dr, func(band *image.RGBA) {
+				xdraw.NearestNeighbor.Scale(band, dr, src, src.Bounds(), draw.Over, nil)
+			})
+		} else {
+			xdraw.NearestNeighbor.Scale(dst, dr, src, src.Bounds(), draw.Over, nil)
+		}
+	}
+}
+
+func BenchmarkScale4K(b *testing.B)       { benchmarkScale(b, false) }
+func BenchmarkScale4KBanded(b *testing.B) { benchmarkScale(b, true) }
@@ -0,0 +1,90 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ScaleOptions resizes an image as it is exported.
+type ScaleOptions struct {
+	// Percent scales both sides; zero leaves the size alone.
+	Percent float64
+	// MaxWidth shrinks images wider than it, keeping their aspect ratio;
+	// zero sets no limit.
+	MaxWidth int
+}
+
+// Requested reports whether the options change an image's size.
+func (o ScaleOptions) Requested() bool {
+	return o.Percent != 0 || o.MaxWidth != 0
+}
+
+// ParseScalePercent reads a scale written as a percentage, such as "50%";
+// the percent sign is optional.
+func ParseScalePercent(spec string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(spec), "%"), 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid scale %q: give a positive percentage such as 50%%", spec)
+	}
+	return v, nil
+}
+
+// ScaledSize returns the size an image of size is exported at.
+func (o ScaleOptions) ScaledSize(size image.Point) image.Point {
+	w, h := float64(size.X), float64(size.Y)
+	if o.Percent > 0 {
+		w, h = w*o.Percent/100, h*o.Percent/100
+	}
+	if o.MaxWidth > 0 && w > float64(o.MaxWidth) {
+		w, h = float64(o.MaxWidth), h*float64(o.MaxWidth)/w
+	}
+	return image.Pt(max(int(math.Round(w)), 1), max(int(math.Round(h)), 1))
+}
+
+// Scale returns img resized by opts with Catmull-Rom resampling, which keeps
+// text in shrunk screenshots legible. The result has a zero origin; img is
+// returned as it is when its size does not change.
+func Scale(img *image.RGBA, opts ScaleOptions) *image.RGBA {
+	if img == nil || img.Bounds().Empty() || !opts.Requested() {
+		return img
+	}
+	size := opts.ScaledSize(img.Bounds().Size())
+	if size == img.Bounds().Size() {
+		return img
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// Halve returns img shrunk to half its size, rounding up, with each pixel
+// the average of the 2x2 block it covers. Blocks on an odd edge repeat the
+// edge pixel.
+func Halve(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, (b.Dx()+1)/2, (b.Dy()+1)/2))
+	last := (b.Dx() - 1) * 4
+	Parallel(dst.Rect.Dy(), func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			y0 := b.Min.Y + 2*y
+			r0 := img.Pix[img.PixOffset(b.Min.X, y0):]
+			r1 := img.Pix[img.PixOffset(b.Min.X, min(y0+1, b.Max.Y-1)):]
+			out := dst.Pix[y*dst.Stride:]
+			for x := 0; x < dst.Rect.Dx(); x++ {
+				i0 := 8 * x
+				i1 := min(i0+4, last)
+				for c := 0; c < 4; c++ {
+					sum := uint(r0[i0+c]) + uint(r0[i1+c]) + uint(r1[i0+c]) + uint(r1[i1+c])
+					out[4*x+c] = uint8((sum + 2) / 4)
+				}
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	red := color.RGBA{255, 0, 0, 255}
+	draw.Draw(img, img.Bounds(), &image.Uniform{red}, image.Point{}, draw.Src)
+	tests := []struct {
+		name string
+		opts ScaleOptions
+		want image.Rectangle
+	}{
+		{"percent", ScaleOptions{Percent: 50}, image.Rect(0, 0, 200, 150)},
+		{"max width", ScaleOptions{MaxWidth: 100}, image.Rect(0, 0, 100, 75)},
+		{"narrow enough", ScaleOptions{MaxWidth: 1200}, image.Rect(0, 0, 400, 300)},
+		{"both", ScaleOptions{Percent: 200, MaxWidth: 600}, image.Rect(0, 0, 600, 450)},
+	}
+	for _, tt := range tests {
+		out := Scale(img, tt.opts)
+		if out.Bounds() != tt.want {
+			t.Errorf("%s: bounds %v, want %v", tt.name, out.Bounds(), tt.want)
+			continue
+		}
+		if got := out.RGBAAt(tt.want.Dx()/2, tt.want.Dy()/2); got != red {
+			t.Errorf("%s: centre %v, want %v", tt.name, got, red)
+		}
+	}
+}
+
+func TestParseScalePercent(t *testing.T) {
+	for spec, want := range map[string]float64{"50%": 50, " 25 ": 25, "12.5%": 12.5} {
+		if got, err := ParseScalePercent(spec); err != nil || got != want {
+			t.Errorf("ParseScalePercent(%q) = %v, %v, want %v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "0", "-10%", "half"} {
+		if _, err := ParseScalePercent(spec); err == nil {
+			t.Errorf("ParseScalePercent(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestHalve(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 10, 15, 13))
+	for y := 10; y < 13; y++ {
+		for x := 10; x < 15; x++ {
+			if (x+y)%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{200, 100, 0, 255})
+			}
+		}
+	}
+	out := Halve(img)
+	if out.Bounds() != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("bounds %v, want (0,0)-(3,2)", out.Bounds())
+	}
+	// A checkerboard block averages to half its colour.
+	if got, want := out.RGBAAt(0, 0), (color.RGBA{100, 50, 0, 128}); got != want {
+		t.Errorf("block %v, want %v", got, want)
+	}
+	// The odd corner pixel is averaged with itself.
+	if got, want := out.RGBAAt(2, 1), (color.RGBA{200, 100, 0, 255}); got != want {
+		t.Errorf("corner %v, want %v", got, want)
+	}
+}
@@ -2,8 +2,7 @@ package render
 
 import (
 	"image"
-	"image/color"
-	"image/draw"
+	"math"
 )
 
 // ShadowOptions configures the drop shadow effect applied to an image.
@@ -75,85 +74,239 @@ func ApplyShadow(img *image.RGBA, opts ShadowOptions) ShadowResult {
 	shift := srcBounds.Min.Sub(compositeBounds.Min)
 	shadowOrigin := shadowBounds.Min.Sub(compositeBounds.Min)
 
-	mask := image.NewGray(paddedBounds.Sub(paddedBounds.Min))
-	for y := srcBounds.Min.Y; y < srcBounds.Max.Y; y++ {
-		for x := srcBounds.Min.X; x < srcBounds.Max.X; x++ {
-			a := img.RGBAAt(x, y).A
-			if a == 0 {
-				continue
-			}
-			mx := x - paddedBounds.Min.X
-			my := y - paddedBounds.Min.Y
-			mask.SetGray(mx, my, color.Gray{Y: a})
+	// The mask holds the image's alpha inside a border of radius, leaving
+	// room for the blur to spread.
+	mask := image.NewAlpha(paddedBounds.Sub(paddedBounds.Min))
+	inset := srcBounds.Min.Sub(paddedBounds.Min)
+	for y := 0; y < srcBounds.Dy(); y++ {
+		row := img.Pix[img.PixOffset(srcBounds.Min.X, srcBounds.Min.Y+y):]
+		out := mask.Pix[mask.PixOffset(inset.X, inset.Y+y):]
+		for x := 0; x < srcBounds.Dx(); x++ {
+			out[x] = row[x*4+3]
 		}
 	}
 
-	blurred := blurGray(mask, radius)
+	blurred := blurAlpha(mask, radius)
 
 	dst := image.NewRGBA(dstRect)
-	draw.Draw(dst, dst.Bounds(), image.Transparent, image.Point{}, draw.Src)
-	shadowAlpha := uint8(opacity*255 + 0.5)
-	if shadowAlpha > 0 {
-		draw.DrawMask(dst, blurred.Bounds().Add(shadowOrigin), image.NewUniform(color.RGBA{0, 0, 0, shadowAlpha}), image.Point{}, blurred, blurred.Bounds().Min, draw.Over)
+	if shadowAlpha := uint32(opacity*255 + 0.5); shadowAlpha > 0 {
+		paintShadow(dst, blurred, shadowOrigin, shadowAlpha)
 	}
-	draw.Draw(dst, srcBounds.Sub(compositeBounds.Min), img, srcBounds.Min, draw.Over)
+	compositeOver(dst, img, shift)
 
 	return ShadowResult{Image: dst, Offset: shift}
 }
 
-func blurGray(src *image.Gray, radius int) *image.Gray {
-	if radius <= 0 {
-		out := image.NewGray(src.Bounds())
+// paintShadow fills the empty dst with black at mask's alpha scaled by
+// alpha/255, with mask's origin placed at at.
+func paintShadow(dst *image.RGBA, mask *image.Alpha, at image.Point, alpha uint32) {
+	w := mask.Bounds().Dx()
+	Parallel(mask.Bounds().Dy(), func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			out := dst.Pix[dst.PixOffset(at.X, at.Y+y):]
+			for x, m := range mask.Pix[y*mask.Stride : y*mask.Stride+w] {
+				out[x*4+3] = uint8((uint32(m)*alpha + 127) / 255)
+			}
+		}
+	})
+}
+
+// compositeOver draws src over dst with src's top-left corner at at. It
+// does what draw.Draw does with draw.Over, copying opaque pixels outright,
+// but spreads the rows over all CPUs.
+func compositeOver(dst, src *image.RGBA, at image.Point) {
+	b := src.Bounds()
+	w := b.Dx()
+	Parallel(b.Dy(), func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			in := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):][:w*4]
+			out := dst.Pix[dst.PixOffset(at.X, at.Y+y):][:w*4]
+			for i := 0; i < len(in); i += 4 {
+				switch a := uint32(in[i+3]); a {
+				case 0xff:
+					copy(out[i:i+4], in[i:i+4])
+				case 0:
+				default:
+					k := 0xff - a
+					for c := i; c < i+4; c++ {
+						out[c] = uint8(uint32(in[c]) + (uint32(out[c])*k+127)/255)
+					}
+				}
+			}
+		}
+	})
+}
+
+// exactBlurRadius is the largest radius blurred with a true gaussian
+// kernel. Beyond it three stacked box blurs approximate the gaussian at a
+// cost that does not grow with the radius.
+const exactBlurRadius = 12
+
+// blurAlpha applies a gaussian blur with a standard deviation of half the
+// radius. The blur is separable: rows and columns are blurred in turn, each
+// pass spread over all CPUs. Pixels outside src count as empty and the
+// result is clipped to src, so callers leave a border of radius around the
+// shape; the little that would spread further is too faint to see.
+func blurAlpha(src *image.Alpha, radius int) *image.Alpha {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewAlpha(b)
+	if radius <= 0 || w == 0 || h == 0 {
 		copy(out.Pix, src.Pix)
 		return out
 	}
-	bounds := src.Bounds()
-	w := bounds.Dx()
-	h := bounds.Dy()
-	tmp := image.NewGray(bounds)
-	dst := image.NewGray(bounds)
-
+	buf := make([]float32, w*h)
 	for y := 0; y < h; y++ {
-		rowStart := y * src.Stride
-		tmpStart := y * tmp.Stride
-		prefix := make([]int, w+1)
-		for x := 0; x < w; x++ {
-			prefix[x+1] = prefix[x] + int(src.Pix[rowStart+x])
+		for x, v := range src.Pix[y*src.Stride : y*src.Stride+w] {
+			buf[y*w+x] = float32(v)
 		}
-		for x := 0; x < w; x++ {
-			x0 := x - radius
-			if x0 < 0 {
-				x0 = 0
+	}
+	tmp := make([]float32, w*h)
+	if radius <= exactBlurRadius {
+		kernel := gaussianKernel(radius)
+		convolveRows(buf, tmp, w, h, kernel)
+		convolveColumns(tmp, buf, w, h, kernel)
+	} else {
+		// Box blurs commute, so each box is applied to rows then columns.
+		for _, r := range gaussianBoxes(float64(radius)/2, 3) {
+			boxRows(buf, tmp, w, h, r)
+			boxColumns(tmp, buf, w, h, r)
+		}
+	}
+	for y := 0; y < h; y++ {
+		row := out.Pix[y*out.Stride:]
+		for x, v := range buf[y*w : (y+1)*w] {
+			row[x] = uint8(min(max(v+0.5, 0), 255))
+		}
+	}
+	return out
+}
+
+// gaussianKernel returns the normalized weights of a gaussian with a
+// standard deviation of radius/2, cut off three standard deviations out.
+func gaussianKernel(radius int) []float32 {
+	sigma := float64(radius) / 2
+	reach := int(math.Ceil(3 * sigma))
+	kernel := make([]float32, 2*reach+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - reach)
+		v := math.Exp(-d * d / (2 * sigma * sigma))
+		kernel[i] = float32(v)
+		sum += v
+	}
+	for i := range kernel {
+		kernel[i] /= float32(sum)
+	}
+	return kernel
+}
+
+// convolveRows convolves each w-wide row of src with kernel into dst.
+func convolveRows(src, dst []float32, w, h int, kernel []float32) {
+	radius := len(kernel) / 2
+	Parallel(h, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			in, out := src[y*w:(y+1)*w], dst[y*w:(y+1)*w]
+			for x := range out {
+				var acc float32
+				for i := max(x-radius, 0); i <= min(x+radius, w-1); i++ {
+					acc += in[i] * kernel[i-x+radius]
+				}
+				out[x] = acc
 			}
-			x1 := x + radius
-			if x1 >= w {
-				x1 = w - 1
+		}
+	})
+}
+
+// convolveColumns convolves each column of src with kernel into dst. It
+// accumulates whole rows at a time so memory is read in order.
+func convolveColumns(src, dst []float32, w, h int, kernel []float32) {
+	radius := len(kernel) / 2
+	Parallel(h, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			out := dst[y*w : (y+1)*w]
+			clear(out)
+			for i := max(y-radius, 0); i <= min(y+radius, h-1); i++ {
+				k := kernel[i-y+radius]
+				for x, v := range src[i*w : (i+1)*w] {
+					out[x] += v * k
+				}
 			}
-			sum := prefix[x1+1] - prefix[x0]
-			count := x1 - x0 + 1
-			tmp.Pix[tmpStart+x] = uint8(sum / count)
 		}
+	})
+}
+
+// gaussianBoxes returns the radii of n box blurs that, applied in turn,
+// approximate a gaussian with standard deviation sigma.
+func gaussianBoxes(sigma float64, n int) []int {
+	ideal := math.Sqrt(12*sigma*sigma/float64(n) + 1)
+	lower := int(ideal)
+	if lower%2 == 0 {
+		lower--
 	}
+	upper := lower + 2
+	m := int(math.Round((12*sigma*sigma - float64(n*lower*lower+4*n*lower+3*n)) / float64(-4*lower-4)))
+	radii := make([]int, n)
+	for i := range radii {
+		size := upper
+		if i < m {
+			size = lower
+		}
+		radii[i] = (size - 1) / 2
+	}
+	return radii
+}
 
-	for x := 0; x < w; x++ {
-		prefix := make([]int, h+1)
-		for y := 0; y < h; y++ {
-			prefix[y+1] = prefix[y] + int(tmp.Pix[y*tmp.Stride+x])
+// boxRows writes the mean of each 2r+1 wide window along the rows of src to
+// dst, keeping a running sum so the cost does not depend on r.
+func boxRows(src, dst []float32, w, h, r int) {
+	scale := 1 / float32(2*r+1)
+	Parallel(h, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			in, out := src[y*w:(y+1)*w], dst[y*w:(y+1)*w]
+			var sum float32
+			for x := 0; x < min(r, w); x++ {
+				sum += in[x]
+			}
+			for x := range out {
+				if next := x + r; next < w {
+					sum += in[next]
+				}
+				if prev := x - r - 1; prev >= 0 {
+					sum -= in[prev]
+				}
+				out[x] = sum * scale
+			}
+		}
+	})
+}
+
+// boxColumns is boxRows down the columns. Each worker keeps running sums for
+// a strip of columns and walks it row by row, so memory is read in order.
+func boxColumns(src, dst []float32, w, h, r int) {
+	scale := 1 / float32(2*r+1)
+	Parallel(w, func(lo, hi int) {
+		sum := make([]float32, hi-lo)
+		for y := 0; y < min(r, h); y++ {
+			for x, v := range src[y*w+lo : y*w+hi] {
+				sum[x] += v
+			}
 		}
 		for y := 0; y < h; y++ {
-			y0 := y - radius
-			if y0 < 0 {
-				y0 = 0
+			if next := y + r; next < h {
+				for x, v := range src[next*w+lo : next*w+hi] {
+					sum[x] += v
+				}
+			}
+			if prev := y - r - 1; prev >= 0 {
+				for x, v := range src[prev*w+lo : prev*w+hi] {
+					sum[x] -= v
+				}
 			}
-			y1 := y + radius
-			if y1 >= h {
-				y1 = h - 1
+			out := dst[y*w+lo : y*w+hi]
+			for x, v := range sum {
+				out[x] = v * scale
 			}
-			sum := prefix[y1+1] - prefix[y0]
-			count := y1 - y0 + 1
-			dst.Pix[y*dst.Stride+x] = uint8(sum / count)
 		}
-	}
-
-	return dst
+	})
 }
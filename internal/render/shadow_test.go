@@ -71,3 +71,39 @@ func TestApplyShadowUsesConfiguredOffset(t *testing.T) {
 		t.Fatalf("expected translated shadow alpha at (2,1)")
 	}
 }
+
+func TestBoxStackApproximatesGaussian(t *testing.T) {
+	const w, h, radius = 96, 96, 20
+	src := make([]float32, w*h)
+	for y := 30; y < 66; y++ {
+		for x := 30; x < 66; x++ {
+			src[y*w+x] = 255
+		}
+	}
+	exact, tmp := make([]float32, w*h), make([]float32, w*h)
+	kernel := gaussianKernel(radius)
+	convolveRows(src, tmp, w, h, kernel)
+	convolveColumns(tmp, exact, w, h, kernel)
+	approx := append([]float32(nil), src...)
+	for _, r := range gaussianBoxes(radius/2, 3) {
+		boxRows(approx, tmp, w, h, r)
+		boxColumns(tmp, approx, w, h, r)
+	}
+	for i := range exact {
+		// Three boxes track the gaussian to within 5% of full opacity.
+		if d := exact[i] - approx[i]; d > 0.05*255 || d < -0.05*255 {
+			t.Fatalf("box blur differs from gaussian by %.1f at %d", d, i)
+		}
+	}
+}
+
+func BenchmarkApplyShadow4K(b *testing.B) {
+	img := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	opts := ShadowOptions{Radius: 64, Offset: image.Pt(24, 24), Opacity: 0.5}
+	for b.Loop() {
+		ApplyShadow(img, opts)
+	}
+}
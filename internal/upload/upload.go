@@ -0,0 +1,250 @@
+// Package upload sends images to hosting services and returns the link they
+// can be shared by: a custom HTTP endpoint, imgur, or an S3 presigned URL.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Kinds of destination.
+const (
+	// KindHTTP posts to a configured endpoint.
+	KindHTTP = "http"
+	// KindImgur posts to the imgur API as an anonymous upload.
+	KindImgur = "imgur"
+	// KindS3 PUTs to an S3 (or compatible) presigned URL.
+	KindS3 = "s3"
+)
+
+// ImgurEndpoint is the imgur API that KindImgur uses unless URL is set.
+const ImgurEndpoint = "https://api.imgur.com/3/image"
+
+// Timeout bounds each upload.
+const Timeout = 2 * time.Minute
+
+// Destination describes where and how an image is uploaded. The text fields
+// are templates in which {name} expands to the uploaded file name.
+type Destination struct {
+	// Kind is KindHTTP, KindImgur or KindS3; empty means KindHTTP.
+	Kind string
+	// URL is the endpoint, or for KindS3 the presigned PUT URL.
+	URL string
+	// Method is the HTTP method, POST by default, PUT for KindS3.
+	Method string
+	// Field is the multipart form field holding the image. When empty a
+	// KindHTTP upload sends the image as the raw request body.
+	Field string
+	// Headers are extra request headers as "Name: value".
+	Headers []string
+	// Response is the dotted path, such as data.link, of the link in a JSON
+	// response. When empty the response body itself is the link.
+	Response string
+	// ClientID is the imgur application client id.
+	ClientID string
+	// PublicURL is the link returned for KindS3; by default the presigned
+	// URL without its query.
+	PublicURL string
+}
+
+// Image is the file being uploaded.
+type Image struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Upload sends img to d and returns the link to it.
+func Upload(ctx context.Context, client *http.Client, d Destination, img Image) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+	switch strings.ToLower(d.Kind) {
+	case "", KindHTTP:
+		return uploadHTTP(ctx, client, d, img)
+	case KindImgur:
+		return uploadImgur(ctx, client, d, img)
+	case KindS3:
+		return uploadS3(ctx, client, d, img)
+	default:
+		return "", fmt.Errorf("unknown upload type %q: want http, imgur or s3", d.Kind)
+	}
+}
+
+func uploadHTTP(ctx context.Context, client *http.Client, d Destination, img Image) (string, error) {
+	if d.URL == "" {
+		return "", errors.New("upload destination has no url")
+	}
+	body, contentType, err := requestBody(d.Field, img)
+	if err != nil {
+		return "", err
+	}
+	resp, err := send(ctx, client, method(d.Method, http.MethodPost), expand(d.URL, img), body, contentType, d.Headers, img)
+	if err != nil {
+		return "", err
+	}
+	return linkFrom(resp, d.Response)
+}
+
+func uploadImgur(ctx context.Context, client *http.Client, d Destination, img Image) (string, error) {
+	if d.ClientID == "" {
+		return "", errors.New("imgur uploads need a client_id")
+	}
+	endpoint := d.URL
+	if endpoint == "" {
+		endpoint = ImgurEndpoint
+	}
+	body, contentType, err := requestBody("image", img)
+	if err != nil {
+		return "", err
+	}
+	headers := append([]string{"Authorization: Client-ID " + d.ClientID}, d.Headers...)
+	resp, err := send(ctx, client, method(d.Method, http.MethodPost), expand(endpoint, img), body, contentType, headers, img)
+	if err != nil {
+		return "", err
+	}
+	path := d.Response
+	if path == "" {
+		path = "data.link"
+	}
+	return linkFrom(resp, path)
+}
+
+func uploadS3(ctx context.Context, client *http.Client, d Destination, img Image) (string, error) {
+	if d.URL == "" {
+		return "", errors.New("s3 uploads need a presigned url")
+	}
+	target := expand(d.URL, img)
+	if _, err := send(ctx, client, method(d.Method, http.MethodPut), target, img.Data, img.ContentType, d.Headers, img); err != nil {
+		return "", err
+	}
+	if d.PublicURL != "" {
+		return expand(d.PublicURL, img), nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("parse presigned url: %w", err)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// requestBody returns img as the request body: a multipart form with img in
+// field, or the bare image when field is empty.
+func requestBody(field string, img Image) ([]byte, string, error) {
+	if field == "" {
+		return img.Data, img.ContentType, nil
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, img.Name))
+	h.Set("Content-Type", img.ContentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(img.Data); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// send makes the request and returns the response body of a 2xx reply.
+func send(ctx context.Context, client *http.Client, method, target string, body []byte, contentType string, headers []string, img Image) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("header %q is not Name: value", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.ReplaceAll(strings.TrimSpace(value), "{name}", img.Name))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 200 {
+			msg = msg[:200] + "..."
+		}
+		if msg == "" {
+			return nil, fmt.Errorf("upload failed: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("upload failed: %s: %s", resp.Status, msg)
+	}
+	return data, nil
+}
+
+// linkFrom finds the link in a response: the value at the dotted path of a
+// JSON document, or the whole body when path is empty.
+func linkFrom(body []byte, path string) (string, error) {
+	if path == "" {
+		link := strings.TrimSpace(string(body))
+		if link == "" {
+			return "", errors.New("upload response is empty")
+		}
+		return link, nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", fmt.Errorf("upload response is not JSON: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("upload response has no %s", path)
+		}
+		if v, ok = obj[key]; !ok {
+			return "", fmt.Errorf("upload response has no %s", path)
+		}
+	}
+	link, ok := v.(string)
+	if !ok || link == "" {
+		return "", fmt.Errorf("upload response %s is not a link", path)
+	}
+	return link, nil
+}
+
+func method(m, def string) string {
+	if m == "" {
+		return def
+	}
+	return strings.ToUpper(m)
+}
+
+// expand fills the {name} placeholder of a URL template.
+func expand(tmpl string, img Image) string {
+	return strings.ReplaceAll(tmpl, "{name}", url.PathEscape(img.Name))
+}
@@ -0,0 +1,112 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadDestinations(t *testing.T) {
+	img := Image{Name: "shot.png", ContentType: "image/png", Data: []byte("png data")}
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		switch r.URL.Path {
+		case "/form", "/3/image":
+			f, _, err := r.FormFile(map[string]string{"/form": "file", "/3/image": "image"}[r.URL.Path])
+			if err != nil {
+				t.Errorf("form file: %v", err)
+				return
+			}
+			body, _ = io.ReadAll(f)
+			io.WriteString(w, `{"data":{"link":"https://img.example/abc.png"}}`)
+		default:
+			body, _ = io.ReadAll(r.Body)
+			io.WriteString(w, "https://plain.example/abc.png\n")
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		dest       Destination
+		wantLink   string
+		wantMethod string
+		wantHeader [2]string
+	}{
+		{
+			name:       "http form",
+			dest:       Destination{URL: srv.URL + "/form", Field: "file", Response: "data.link", Headers: []string{"X-Name: {name}"}},
+			wantLink:   "https://img.example/abc.png",
+			wantMethod: http.MethodPost,
+			wantHeader: [2]string{"X-Name", "shot.png"},
+		},
+		{
+			name:       "http raw body",
+			dest:       Destination{Kind: KindHTTP, URL: srv.URL + "/raw/{name}", Method: "put"},
+			wantLink:   "https://plain.example/abc.png",
+			wantMethod: http.MethodPut,
+			wantHeader: [2]string{"Content-Type", "image/png"},
+		},
+		{
+			name:       "imgur",
+			dest:       Destination{Kind: KindImgur, URL: srv.URL + "/3/image", ClientID: "id123"},
+			wantLink:   "https://img.example/abc.png",
+			wantMethod: http.MethodPost,
+			wantHeader: [2]string{"Authorization", "Client-ID id123"},
+		},
+		{
+			name:       "s3",
+			dest:       Destination{Kind: KindS3, URL: srv.URL + "/bucket/shot.png?X-Amz-Signature=abc"},
+			wantLink:   srv.URL + "/bucket/shot.png",
+			wantMethod: http.MethodPut,
+			wantHeader: [2]string{"Content-Type", "image/png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body = nil, nil
+			link, err := Upload(context.Background(), srv.Client(), tt.dest, img)
+			if err != nil {
+				t.Fatalf("Upload: %v", err)
+			}
+			if link != tt.wantLink {
+				t.Errorf("link = %q, want %q", link, tt.wantLink)
+			}
+			if got.Method != tt.wantMethod {
+				t.Errorf("method = %s, want %s", got.Method, tt.wantMethod)
+			}
+			if v := got.Header.Get(tt.wantHeader[0]); v != tt.wantHeader[1] {
+				t.Errorf("header %s = %q, want %q", tt.wantHeader[0], v, tt.wantHeader[1])
+			}
+			if string(body) != string(img.Data) {
+				t.Errorf("server got %q, want the image", body)
+			}
+		})
+	}
+}
+
+func TestUploadReportsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/denied" {
+			http.Error(w, "bad token", http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, `{"data":{}}`)
+	}))
+	defer srv.Close()
+	img := Image{Name: "shot.png", ContentType: "image/png", Data: []byte("x")}
+	for _, d := range []Destination{
+		{URL: srv.URL + "/denied"},
+		{URL: srv.URL + "/ok", Response: "data.link"},
+		{Kind: KindImgur},
+		{Kind: "ftp", URL: srv.URL},
+	} {
+		if link, err := Upload(context.Background(), srv.Client(), d, img); err == nil {
+			t.Errorf("Upload(%+v) = %q, want an error", d, link)
+		}
+	}
+}
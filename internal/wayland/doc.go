@@ -0,0 +1,5 @@
+// Package wayland is a minimal Wayland client: enough of the wire protocol
+// to bind globals, pass file descriptors both ways and dispatch events to
+// per-object handlers. Protocol objects are plain ids; callers encode their
+// own requests and decode their own events.
+package wayland
@@ -0,0 +1,351 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package wayland
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// DisplayID is the object id of wl_display, the only object that exists
+// when a Wayland connection is opened.
+const DisplayID = 1
+
+// maxFDs bounds the descriptors accepted with one read; the compositor
+// sends at most a few per message.
+const maxFDs = 28
+
+// Handler receives the events sent to one object.
+type Handler func(opcode uint16, ev *Event)
+
+// Conn is a connection to the compositor. Requests may be sent and handlers
+// changed from any goroutine, but only one goroutine may dispatch events.
+type Conn struct {
+	conn *net.UnixConn
+	buf  []byte
+
+	mu       sync.Mutex
+	fds      []int
+	nextID   uint32
+	handlers map[uint32]Handler
+	err      error
+}
+
+// SocketPath resolves the compositor socket from WAYLAND_DISPLAY, which may
+// be absolute or relative to XDG_RUNTIME_DIR.
+func SocketPath() (string, error) {
+	name := os.Getenv("WAYLAND_DISPLAY")
+	if name == "" {
+		return "", errors.New("WAYLAND_DISPLAY is not set")
+	}
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", errors.New("XDG_RUNTIME_DIR is not set")
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// Dial connects to the compositor named by SocketPath.
+func Dial() (*Conn, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("connect wayland: %w", err)
+	}
+	c := &Conn{
+		conn:     conn,
+		nextID:   DisplayID + 1,
+		handlers: make(map[uint32]Handler),
+	}
+	c.handlers[DisplayID] = func(opcode uint16, ev *Event) {
+		if opcode == 0 { // wl_display.error
+			object, code, msg := ev.Uint(), ev.Uint(), ev.String()
+			c.mu.Lock()
+			c.err = fmt.Errorf("wayland error on object %d (code %d): %s", object, code, msg)
+			c.mu.Unlock()
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection and any received descriptors nobody claimed.
+// It may be called from any goroutine to stop one blocked in Dispatch.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	for _, fd := range c.fds {
+		syscall.Close(fd)
+	}
+	c.fds = nil
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+// NewID allocates a client object id.
+func (c *Conn) NewID() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+// Handle routes the events of object to h, replacing any earlier handler.
+// Objects the compositor creates, such as data offers, need a handler
+// registered from the event that announces them.
+func (c *Conn) Handle(object uint32, h Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[object] = h
+}
+
+// Forget drops the handler of object; its events are ignored afterwards.
+func (c *Conn) Forget(object uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.handlers, object)
+}
+
+// Request builds the argument payload of a request.
+type Request struct {
+	data []byte
+	fds  []int
+}
+
+// Uint appends an unsigned, object or new_id argument.
+func (r *Request) Uint(v uint32) *Request {
+	r.data = binary.NativeEndian.AppendUint32(r.data, v)
+	return r
+}
+
+// Int appends a signed argument.
+func (r *Request) Int(v int32) *Request {
+	return r.Uint(uint32(v))
+}
+
+// String appends a NUL-terminated, padded string argument.
+func (r *Request) String(s string) *Request {
+	r.Uint(uint32(len(s) + 1))
+	r.data = append(r.data, s...)
+	r.data = append(r.data, 0)
+	for len(r.data)%4 != 0 {
+		r.data = append(r.data, 0)
+	}
+	return r
+}
+
+// FD attaches a file descriptor. It is duplicated into the compositor, so
+// the caller still owns fd.
+func (r *Request) FD(fd int) *Request {
+	r.fds = append(r.fds, fd)
+	return r
+}
+
+// Bytes returns the encoded arguments, for fake compositors in tests.
+func (r *Request) Bytes() []byte {
+	return r.data
+}
+
+// Send writes one request to object.
+func (c *Conn) Send(object uint32, opcode uint16, req *Request) error {
+	if req == nil {
+		req = &Request{}
+	}
+	size := 8 + len(req.data)
+	msg := make([]byte, 8, size)
+	binary.NativeEndian.PutUint32(msg, object)
+	binary.NativeEndian.PutUint32(msg[4:], uint32(size)<<16|uint32(opcode))
+	msg = append(msg, req.data...)
+	var oob []byte
+	if len(req.fds) > 0 {
+		oob = syscall.UnixRights(req.fds...)
+	}
+	if _, _, err := c.conn.WriteMsgUnix(msg, oob, nil); err != nil {
+		return fmt.Errorf("wayland write: %w", err)
+	}
+	return nil
+}
+
+// Event decodes event arguments in order. Reads past the end yield zero
+// values so a short event cannot panic the client.
+type Event struct {
+	data []byte
+	conn *Conn
+}
+
+// NewEvent decodes data as arguments, for fake compositors in tests. It
+// carries no file descriptors.
+func NewEvent(data []byte) *Event {
+	return &Event{data: data}
+}
+
+// Uint reads an unsigned, object or new_id argument.
+func (e *Event) Uint() uint32 {
+	if len(e.data) < 4 {
+		e.data = nil
+		return 0
+	}
+	v := binary.NativeEndian.Uint32(e.data)
+	e.data = e.data[4:]
+	return v
+}
+
+// Int reads a signed argument.
+func (e *Event) Int() int32 {
+	return int32(e.Uint())
+}
+
+// String reads a string argument.
+func (e *Event) String() string {
+	n := int(e.Uint())
+	padded := (n + 3) &^ 3
+	if n == 0 || padded > len(e.data) {
+		e.data = nil
+		return ""
+	}
+	s := string(e.data[:n-1])
+	e.data = e.data[padded:]
+	return s
+}
+
+// FD takes the next file descriptor sent with the event, or -1 when none
+// arrived. The caller owns and must close it.
+func (e *Event) FD() int {
+	if e.conn == nil {
+		return -1
+	}
+	e.conn.mu.Lock()
+	defer e.conn.mu.Unlock()
+	if len(e.conn.fds) == 0 {
+		return -1
+	}
+	fd := e.conn.fds[0]
+	e.conn.fds = e.conn.fds[1:]
+	return fd
+}
+
+// fill reads more bytes and any descriptors sent alongside them.
+func (c *Conn) fill() error {
+	data := make([]byte, 4096)
+	oob := make([]byte, syscall.CmsgSpace(maxFDs*4))
+	n, oobn, _, _, err := c.conn.ReadMsgUnix(data, oob)
+	if n == 0 && err == nil {
+		err = errors.New("connection closed")
+	}
+	if err != nil {
+		return fmt.Errorf("wayland read: %w", err)
+	}
+	c.buf = append(c.buf, data[:n]...)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return fmt.Errorf("wayland read: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range msgs {
+		if fds, err := syscall.ParseUnixRights(&m); err == nil {
+			c.fds = append(c.fds, fds...)
+		}
+	}
+	return nil
+}
+
+// Dispatch reads and handles one event.
+func (c *Conn) Dispatch() error {
+	for len(c.buf) < 8 {
+		if err := c.fill(); err != nil {
+			return err
+		}
+	}
+	object := binary.NativeEndian.Uint32(c.buf)
+	word := binary.NativeEndian.Uint32(c.buf[4:])
+	size, opcode := int(word>>16), uint16(word)
+	if size < 8 {
+		return fmt.Errorf("wayland read: malformed event size %d", size)
+	}
+	for len(c.buf) < size {
+		if err := c.fill(); err != nil {
+			return err
+		}
+	}
+	ev := &Event{data: append([]byte(nil), c.buf[8:size]...), conn: c}
+	c.buf = c.buf[size:]
+	c.mu.Lock()
+	h := c.handlers[object]
+	c.mu.Unlock()
+	if h != nil {
+		h(opcode, ev)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// DispatchUntil handles events until done reports true.
+func (c *Conn) DispatchUntil(done func() bool) error {
+	for !done() {
+		if err := c.Dispatch(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Roundtrip waits until the compositor has processed every request sent so
+// far, and so has delivered every event they caused.
+func (c *Conn) Roundtrip() error {
+	callback := c.NewID()
+	finished := false
+	c.Handle(callback, func(uint16, *Event) { finished = true })
+	defer c.Forget(callback)
+	if err := c.Send(DisplayID, 0, (&Request{}).Uint(callback)); err != nil { // wl_display.sync
+		return err
+	}
+	return c.DispatchUntil(func() bool { return finished })
+}
+
+// Global is an interface the compositor announced through wl_registry.
+type Global struct {
+	Name    uint32
+	Iface   string
+	Version uint32
+}
+
+// Registry binds wl_registry and returns the globals the compositor announced.
+func (c *Conn) Registry() (uint32, []Global, error) {
+	registry := c.NewID()
+	var globals []Global
+	c.Handle(registry, func(opcode uint16, ev *Event) {
+		if opcode == 0 { // wl_registry.global
+			globals = append(globals, Global{Name: ev.Uint(), Iface: ev.String(), Version: ev.Uint()})
+		}
+	})
+	if err := c.Send(DisplayID, 1, (&Request{}).Uint(registry)); err != nil { // wl_display.get_registry
+		return 0, nil, err
+	}
+	if err := c.Roundtrip(); err != nil {
+		return 0, nil, err
+	}
+	return registry, globals, nil
+}
+
+// Bind creates a client object for a global at no more than version.
+func (c *Conn) Bind(registry uint32, g Global, version uint32) (uint32, error) {
+	id := c.NewID()
+	req := (&Request{}).Uint(g.Name).String(g.Iface).Uint(min(g.Version, version)).Uint(id)
+	if err := c.Send(registry, 0, req); err != nil { // wl_registry.bind
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,110 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// maxDuration is the longest a frame can be shown, in milliseconds.
+const maxDuration = 1<<24 - 1
+
+// Animation builds an animated WebP file a frame at a time. Frames are
+// compressed as they are added, so a long recording keeps only the encoded
+// data. The canvas is the size of the first frame and every frame covers
+// all of it.
+type Animation struct {
+	// Options sets how frames are encoded; nil encodes lossy at
+	// DefaultQuality.
+	Options *Options
+	// LoopCount is how many times the animation plays; 0 loops forever.
+	LoopCount int
+
+	size   image.Point
+	alpha  bool
+	count  int
+	frames bytes.Buffer
+}
+
+// Add appends img, shown for d. Images of another size than the first are
+// cropped or padded to the canvas.
+func (a *Animation) Add(img image.Image, d time.Duration) error {
+	b := img.Bounds()
+	if a.count == 0 {
+		if b.Empty() {
+			return errors.New("webp: cannot encode an empty image")
+		}
+		if b.Dx() > maxSize || b.Dy() > maxSize {
+			return fmt.Errorf("webp: %dx%d is larger than the %d pixel limit", b.Dx(), b.Dy(), maxSize)
+		}
+		a.size = b.Size()
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || b.Size() != a.size {
+		nrgba = image.NewNRGBA(image.Rectangle{Max: a.size})
+		draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+	}
+	o := a.Options
+	if o == nil {
+		o = &Options{Quality: DefaultQuality}
+	}
+	ms := min(max(int(d/time.Millisecond), 0), maxDuration)
+
+	var data bytes.Buffer
+	header := make([]byte, 16)
+	// The frame sits at the canvas origin, left by 3 zero bytes each
+	// for X and Y.
+	putUint24(header[6:], a.size.X-1)
+	putUint24(header[9:], a.size.Y-1)
+	putUint24(header[12:], ms)
+	header[15] = 0x02 // replace the canvas rather than blend with it
+	data.Write(header)
+	if writeImage(&data, nrgba, o) {
+		a.alpha = true
+	}
+	writeChunk(&a.frames, "ANMF", data.Bytes())
+	a.count++
+	return nil
+}
+
+// Frames returns the number of frames added.
+func (a *Animation) Frames() int {
+	return a.count
+}
+
+// Encode writes the animation as a WebP file.
+func (a *Animation) Encode(w io.Writer) error {
+	if a.count == 0 {
+		return errors.New("webp: animation has no frames")
+	}
+	var chunks bytes.Buffer
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x02 // animation
+	if a.alpha {
+		vp8x[0] |= 0x10
+	}
+	putUint24(vp8x[4:], a.size.X-1)
+	putUint24(vp8x[7:], a.size.Y-1)
+	writeChunk(&chunks, "VP8X", vp8x)
+	anim := make([]byte, 6)
+	// The background color, left transparent black, is only a hint since
+	// every frame covers the canvas.
+	binary.LittleEndian.PutUint16(anim[4:], uint16(min(max(a.LoopCount, 0), 0xffff)))
+	writeChunk(&chunks, "ANIM", anim)
+
+	header := make([]byte, 12)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+chunks.Len()+a.frames.Len()))
+	copy(header[8:], "WEBP")
+	for _, b := range [][]byte{header, chunks.Bytes(), a.frames.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
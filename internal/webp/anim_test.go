@@ -0,0 +1,59 @@
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"testing"
+	"time"
+)
+
+func TestAnimation(t *testing.T) {
+	a := Animation{Options: &Options{Lossless: true}}
+	frames := []*image.NRGBA{testImage(40, 30, false), testImage(40, 30, true), testImage(50, 20, false)}
+	for i, f := range frames {
+		if err := a.Add(f, time.Duration(i+1)*100*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := a.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" || int(binary.LittleEndian.Uint32(data[4:])) != len(data)-8 {
+		t.Fatalf("bad RIFF header % x", data[:12])
+	}
+	var anmf [][]byte
+	for rest := data[12:]; len(rest) >= 8; {
+		n := int(binary.LittleEndian.Uint32(rest[4:]))
+		switch string(rest[:4]) {
+		case "VP8X":
+			if rest[8] != 0x12 {
+				t.Errorf("VP8X flags = %#x, want animation and alpha", rest[8])
+			}
+		case "ANMF":
+			anmf = append(anmf, rest[8:8+n])
+		}
+		rest = rest[8+n+n%2:]
+	}
+	if len(anmf) != len(frames) {
+		t.Fatalf("got %d frames, want %d", len(anmf), len(frames))
+	}
+	for i, f := range anmf {
+		if ms := int(f[12]) | int(f[13])<<8 | int(f[14])<<16; ms != (i+1)*100 {
+			t.Errorf("frame %d lasts %dms, want %d", i, ms, (i+1)*100)
+		}
+		// The frame's own chunks make a still WebP file.
+		still := append([]byte("RIFF\x00\x00\x00\x00WEBP"), f[16:]...)
+		binary.LittleEndian.PutUint32(still[4:], uint32(len(still)-8))
+		want := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+		draw.Draw(want, want.Rect, frames[i], image.Point{}, draw.Src)
+		got := image.NewNRGBA(want.Rect)
+		draw.Draw(got, got.Rect, decode(t, still), image.Point{}, draw.Src)
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("frame %d: decoded pixels differ from the source", i)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package webp
+
+import "sort"
+
+// bitWriter packs bits least significant first, as VP8L reads them.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// write appends the low n bits of v, n at most 32.
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.n
+	b.n += n
+	for b.n >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+// bytes returns the bits written, the last byte padded with zeros.
+func (b *bitWriter) bytes() []byte {
+	if b.n > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.n = 0, 0
+	}
+	return b.buf
+}
+
+// prefixCode is a canonical Huffman code. A code with a single symbol
+// takes no bits at all.
+type prefixCode struct {
+	lengths []uint8
+	// codes are bit reversed, ready for the least significant first writer.
+	codes []uint32
+	used  int
+}
+
+// newPrefixCode builds a code of at most maxLen bits for the symbol counts.
+func newPrefixCode(counts []uint32, maxLen int) *prefixCode {
+	pc := &prefixCode{lengths: huffmanLengths(counts, maxLen), codes: make([]uint32, len(counts))}
+	for _, l := range pc.lengths {
+		if l > 0 {
+			pc.used++
+		}
+	}
+	var count [16]uint32
+	for _, l := range pc.lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range pc.lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		var r uint32
+		for i := uint8(0); i < l; i++ {
+			r = r<<1 | (c>>i)&1
+		}
+		pc.codes[s] = r
+	}
+	return pc
+}
+
+// put writes symbol s.
+func (pc *prefixCode) put(b *bitWriter, s int) {
+	if pc.used > 1 {
+		b.write(pc.codes[s], uint(pc.lengths[s]))
+	}
+}
+
+// huffmanLengths returns Huffman code lengths for counts, halving the counts
+// until no code is longer than maxLen. Unused symbols get length zero and a
+// lone used symbol gets length one.
+func huffmanLengths(counts []uint32, maxLen int) []uint8 {
+	lengths := make([]uint8, len(counts))
+	type node struct {
+		count       uint64
+		left, right int
+	}
+	c := append([]uint32(nil), counts...)
+	for {
+		var nodes []node
+		var leaves []int
+		for s, n := range c {
+			if n > 0 {
+				leaves = append(leaves, s)
+				nodes = append(nodes, node{count: uint64(n), left: -1, right: s})
+			}
+		}
+		switch len(leaves) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[leaves[0]] = 1
+			return lengths
+		}
+		order := make([]int, len(nodes))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return nodes[order[i]].count < nodes[order[j]].count })
+		// Two queue construction: the sorted leaves and the merged nodes,
+		// which are made in increasing count order.
+		var merged []int
+		pop := func() int {
+			if len(merged) == 0 || (len(order) > 0 && nodes[order[0]].count <= nodes[merged[0]].count) {
+				n := order[0]
+				order = order[1:]
+				return n
+			}
+			n := merged[0]
+			merged = merged[1:]
+			return n
+		}
+		for len(order)+len(merged) > 1 {
+			a, b := pop(), pop()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, left: a, right: b})
+			merged = append(merged, len(nodes)-1)
+		}
+		depth := make([]int, len(nodes))
+		longest := 0
+		for i := len(nodes) - 1; i >= 0; i-- {
+			if nodes[i].left < 0 {
+				lengths[nodes[i].right] = uint8(depth[i])
+				longest = max(longest, depth[i])
+				continue
+			}
+			depth[nodes[i].left] = depth[i] + 1
+			depth[nodes[i].right] = depth[i] + 1
+		}
+		if longest <= maxLen {
+			return lengths
+		}
+		for s, n := range c {
+			if n > 0 {
+				c[s] = max(1, n>>1)
+			}
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package webp
+
+import "math/bits"
+
+// This file implements the VP8L lossless format: the subtract green and
+// predictor transforms, then LZ77 backward references, a color cache and
+// one group of prefix codes for the whole image.
+
+const (
+	nLiteral        = 256
+	nLength         = 24
+	nDistance       = 40
+	maxLength       = 4096
+	maxDistance     = 1<<20 - 120
+	cacheMultiplier = 0x1e35a7bd
+	predictorBits   = 4
+	// Code length codes are limited to 7 bits, the others to 15.
+	codeLengthLimit = 7
+	codeLimit       = 15
+)
+
+// codeLengthOrder is the order code length code lengths are stored in.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// distancePlane lists the (dx, dy) neighbours the first 120 distance codes
+// stand for, packed as dy<<4 | (8-dx).
+var distancePlane = [120]uint8{
+	0x18, 0x07, 0x17, 0x19, 0x28, 0x06, 0x27, 0x29, 0x16, 0x1a,
+	0x26, 0x2a, 0x38, 0x05, 0x37, 0x39, 0x15, 0x1b, 0x36, 0x3a,
+	0x25, 0x2b, 0x48, 0x04, 0x47, 0x49, 0x14, 0x1c, 0x35, 0x3b,
+	0x46, 0x4a, 0x24, 0x2c, 0x58, 0x45, 0x4b, 0x34, 0x3c, 0x03,
+	0x57, 0x59, 0x13, 0x1d, 0x56, 0x5a, 0x23, 0x2d, 0x44, 0x4c,
+	0x55, 0x5b, 0x33, 0x3d, 0x68, 0x02, 0x67, 0x69, 0x12, 0x1e,
+	0x66, 0x6a, 0x22, 0x2e, 0x54, 0x5c, 0x43, 0x4d, 0x65, 0x6b,
+	0x32, 0x3e, 0x78, 0x01, 0x77, 0x79, 0x53, 0x5d, 0x11, 0x1f,
+	0x64, 0x6c, 0x42, 0x4e, 0x76, 0x7a, 0x21, 0x2f, 0x75, 0x7b,
+	0x31, 0x3f, 0x63, 0x6d, 0x52, 0x5e, 0x00, 0x74, 0x7c, 0x41,
+	0x4f, 0x10, 0x20, 0x62, 0x6e, 0x30, 0x73, 0x7d, 0x51, 0x5f,
+	0x40, 0x72, 0x7e, 0x61, 0x6f, 0x50, 0x71, 0x7f, 0x60, 0x70,
+}
+
+// encodeLossless returns the VP8L bitstream for argb, w by h pixels in
+// 0xAARRGGBB form.
+func encodeLossless(argb []uint32, w, h int) []byte {
+	var b bitWriter
+	b.write(0x2f, 8)
+	b.write(uint32(w-1), 14)
+	b.write(uint32(h-1), 14)
+	opaque := uint32(1)
+	for _, p := range argb {
+		if p>>24 != 0xff {
+			opaque = 0
+			break
+		}
+	}
+	b.write(1-opaque, 1)
+	b.write(0, 3)
+	writeLosslessImage(&b, argb, w, h)
+	return b.bytes()
+}
+
+// writeLosslessImage writes the transforms and pixels of a VP8L image,
+// everything after the header. Alpha planes are stored this way too.
+func writeLosslessImage(b *bitWriter, argb []uint32, w, h int) {
+	pix := append([]uint32(nil), argb...)
+	subtractGreen(pix)
+	b.write(1, 1)
+	b.write(2, 2)
+
+	modes, residuals := predict(pix, w, h)
+	b.write(1, 1)
+	b.write(0, 2)
+	b.write(predictorBits-2, 3)
+	tw, th := tiles(w), tiles(h)
+	writeEntropyImage(b, modes, tw, th, false)
+	b.write(0, 1)
+
+	writeEntropyImage(b, residuals, w, h, true)
+}
+
+func tiles(n int) int {
+	return (n + 1<<predictorBits - 1) >> predictorBits
+}
+
+func subtractGreen(pix []uint32) {
+	for i, p := range pix {
+		g := (p >> 8) & 0xff
+		r := (p>>16 - g) & 0xff
+		bl := (p - g) & 0xff
+		pix[i] = p&0xff00ff00 | r<<16 | bl
+	}
+}
+
+// Per channel arithmetic on 0xAARRGGBB pixels.
+
+func subPixels(a, b uint32) uint32 {
+	ag := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	rb := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return ag&0xff00ff00 | rb&0x00ff00ff
+}
+
+func average2(a, b uint32) uint32 {
+	return ((a^b)&0xfefefefe)>>1 + a&b
+}
+
+func channel(p uint32, shift uint) int32 {
+	return int32(p>>shift) & 0xff
+}
+
+func abs32(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func clamp255(x int32) uint32 {
+	if x < 0 {
+		return 0
+	}
+	if x > 255 {
+		return 255
+	}
+	return uint32(x)
+}
+
+func selectPixel(l, t, tl uint32) uint32 {
+	var pl, pt int32
+	for s := uint(0); s < 32; s += 8 {
+		pl += abs32(channel(tl, s) - channel(t, s))
+		pt += abs32(channel(tl, s) - channel(l, s))
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for s := uint(0); s < 32; s += 8 {
+		out |= clamp255(channel(a, s)+channel(b, s)-channel(c, s)) << s
+	}
+	return out
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for s := uint(0); s < 32; s += 8 {
+		x := channel(a, s)
+		out |= clamp255(x+(x-channel(b, s))/2) << s
+	}
+	return out
+}
+
+// predictMode returns what predictor mode m makes of pixel i, which is not
+// in the first row or column.
+func predictMode(pix []uint32, i, w int, m uint32) uint32 {
+	l, t := pix[i-1], pix[i-w]
+	switch m {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return pix[i-w+1]
+	case 4:
+		return pix[i-w-1]
+	case 5:
+		return average2(average2(l, pix[i-w+1]), t)
+	case 6:
+		return average2(l, pix[i-w-1])
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(pix[i-w-1], t)
+	case 9:
+		return average2(t, pix[i-w+1])
+	case 10:
+		return average2(average2(l, pix[i-w-1]), average2(t, pix[i-w+1]))
+	case 11:
+		return selectPixel(l, t, pix[i-w-1])
+	case 12:
+		return clampAddSubtractFull(l, t, pix[i-w-1])
+	default:
+		return clampAddSubtractHalf(average2(l, t), pix[i-w-1])
+	}
+}
+
+// residualCost estimates the bits a residual takes: small differences
+// either way are cheap.
+func residualCost(r uint32) int32 {
+	var c int32
+	for s := uint(0); s < 32; s += 8 {
+		v := int32(int8(r >> s))
+		c += abs32(v)
+	}
+	return c
+}
+
+// predict picks the predictor mode of each tile with the smallest
+// residuals and returns the modes as a tile image and the residuals.
+func predict(pix []uint32, w, h int) (modes, residuals []uint32) {
+	tw, th := tiles(w), tiles(h)
+	modes = make([]uint32, tw*th)
+	residuals = make([]uint32, len(pix))
+	residuals[0] = subPixels(pix[0], 0xff000000)
+	for x := 1; x < w; x++ {
+		residuals[x] = subPixels(pix[x], pix[x-1])
+	}
+	for y := 1; y < h; y++ {
+		residuals[y*w] = subPixels(pix[y*w], pix[(y-1)*w])
+	}
+	const size = 1 << predictorBits
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := max(1, ty*size), min(h, (ty+1)*size)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := max(1, tx*size), min(w, (tx+1)*size)
+			best, bestCost := uint32(1), int32(-1)
+			for m := uint32(0); m < 14 && y0 < y1 && x0 < x1; m++ {
+				var cost int32
+				for y := y0; y < y1 && (bestCost < 0 || cost < bestCost); y++ {
+					for x := x0; x < x1; x++ {
+						i := y*w + x
+						cost += residualCost(subPixels(pix[i], predictMode(pix, i, w, m)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = m, cost
+				}
+			}
+			modes[ty*tw+tx] = 0xff000000 | best<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*w + x
+					residuals[i] = subPixels(pix[i], predictMode(pix, i, w, best))
+				}
+			}
+		}
+	}
+	return modes, residuals
+}
+
+// symbol is one entry of the coded pixel stream: a literal pixel, a color
+// cache index or a backward reference of length pixels.
+type symbol struct {
+	argb     uint32
+	cache    int
+	length   int
+	distance int
+}
+
+// prefixSplit splits a length or distance code v into its prefix symbol
+// and the extra bits that follow it.
+func prefixSplit(v int) (prefix int, extra uint32, nExtra uint) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := bits.Len(uint(d)) - 1
+	second := (d >> (h - 1)) & 1
+	nExtra = uint(h - 1)
+	return 2*h + second, uint32(d) & (1<<nExtra - 1), nExtra
+}
+
+// distanceCode returns the code for a backward reference of d pixels,
+// preferring the short codes for nearby pixels.
+func distanceCode(d int, plane map[int]int) int {
+	if c, ok := plane[d]; ok {
+		return c
+	}
+	return d + len(distancePlane)
+}
+
+// planeCodes maps distances to the nearby pixel codes that reach them.
+func planeCodes(w int) map[int]int {
+	m := make(map[int]int, len(distancePlane))
+	for i, v := range distancePlane {
+		dy, dx := int(v>>4), 8-int(v&0xf)
+		if d := dy*w + dx; d >= 1 {
+			if _, ok := m[d]; !ok {
+				m[d] = i + 1
+			}
+		}
+	}
+	return m
+}
+
+// backwardRefs turns pix into literals and backward references, using a
+// hash chain over pairs of pixels to find earlier matches.
+func backwardRefs(pix []uint32, w int) []symbol {
+	const (
+		hashBits = 16
+		chain    = 24
+		minMatch = 3
+	)
+	n := len(pix)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+	hash := func(i int) uint32 {
+		return ((pix[i] * cacheMultiplier) ^ (pix[i+1] * 0x9e3779b1)) >> (32 - hashBits) & (1<<hashBits - 1)
+	}
+	insert := func(i int) {
+		if i+1 < n {
+			hv := hash(i)
+			prev[i] = head[hv]
+			head[hv] = int32(i)
+		}
+	}
+	matchLen := func(i, j, limit int) int {
+		l := 0
+		for l < limit && pix[i+l] == pix[j+l] {
+			l++
+		}
+		return l
+	}
+	var out []symbol
+	for i := 0; i < n; {
+		limit := min(maxLength, n-i)
+		bestLen, bestDist := 0, 0
+		if limit >= minMatch {
+			// Runs and the row above first: their codes are short.
+			for _, d := range [...]int{1, w} {
+				if d <= i {
+					if l := matchLen(i, i-d, limit); l > bestLen {
+						bestLen, bestDist = l, d
+					}
+				}
+			}
+			if i+1 < n {
+				for j, steps := head[hash(i)], 0; j >= 0 && steps < chain && bestLen < limit; j, steps = prev[j], steps+1 {
+					d := i - int(j)
+					if d > maxDistance {
+						break
+					}
+					if l := matchLen(i, int(j), limit); l > bestLen+1 {
+						bestLen, bestDist = l, d
+					}
+				}
+			}
+		}
+		if bestLen >= minMatch {
+			out = append(out, symbol{length: bestLen, distance: bestDist})
+			for k := 0; k < bestLen; k++ {
+				insert(i + k)
+			}
+			i += bestLen
+			continue
+		}
+		out = append(out, symbol{argb: pix[i], cache: -1})
+		insert(i)
+		i++
+	}
+	return out
+}
+
+// writeEntropyImage writes pix as prefix coded pixels: the color cache
+// parameters, for the main image the meta prefix code flag, the five
+// prefix codes and then the pixels.
+func writeEntropyImage(b *bitWriter, pix []uint32, w, h int, main bool) {
+	syms := backwardRefs(pix, w)
+	cacheBits := 0
+	if len(pix) >= 1<<12 {
+		cacheBits = 10
+	}
+	if cacheBits > 0 {
+		cache := make([]uint32, 1<<cacheBits)
+		// Start with a value no pixel can match at index zero.
+		cache[0] = 1
+		add := func(p uint32) { cache[(p*cacheMultiplier)>>(32-cacheBits)] = p }
+		pos := 0
+		for k := range syms {
+			s := &syms[k]
+			if s.length > 0 {
+				for j := 0; j < s.length; j++ {
+					add(pix[pos+j])
+				}
+				pos += s.length
+				continue
+			}
+			idx := int((s.argb * cacheMultiplier) >> (32 - cacheBits))
+			if cache[idx] == s.argb {
+				s.cache = idx
+			}
+			add(s.argb)
+			pos++
+		}
+		b.write(1, 1)
+		b.write(uint32(cacheBits), 4)
+	} else {
+		b.write(0, 1)
+	}
+	if main {
+		b.write(0, 1)
+	}
+
+	plane := planeCodes(w)
+	cacheSize := 0
+	if cacheBits > 0 {
+		cacheSize = 1 << cacheBits
+	}
+	green := make([]uint32, nLiteral+nLength+cacheSize)
+	red := make([]uint32, nLiteral)
+	blue := make([]uint32, nLiteral)
+	alpha := make([]uint32, nLiteral)
+	dist := make([]uint32, nDistance)
+	for k := range syms {
+		s := &syms[k]
+		switch {
+		case s.length > 0:
+			p, _, _ := prefixSplit(s.length)
+			green[nLiteral+p]++
+			s.distance = distanceCode(s.distance, plane)
+			p, _, _ = prefixSplit(s.distance)
+			dist[p]++
+		case s.cache >= 0:
+			green[nLiteral+nLength+s.cache]++
+		default:
+			green[(s.argb>>8)&0xff]++
+			red[(s.argb>>16)&0xff]++
+			blue[s.argb&0xff]++
+			alpha[s.argb>>24]++
+		}
+	}
+	codes := [5]*prefixCode{}
+	for i, counts := range [5][]uint32{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(counts, codeLimit)
+		writePrefixCode(b, codes[i])
+	}
+	for _, s := range syms {
+		switch {
+		case s.length > 0:
+			p, extra, n := prefixSplit(s.length)
+			codes[0].put(b, nLiteral+p)
+			b.write(extra, n)
+			p, extra, n = prefixSplit(s.distance)
+			codes[4].put(b, p)
+			b.write(extra, n)
+		case s.cache >= 0:
+			codes[0].put(b, nLiteral+nLength+s.cache)
+		default:
+			codes[0].put(b, int((s.argb>>8)&0xff))
+			codes[1].put(b, int((s.argb>>16)&0xff))
+			codes[2].put(b, int(s.argb&0xff))
+			codes[3].put(b, int(s.argb>>24))
+		}
+	}
+}
+
+// writePrefixCode stores a code's lengths: as a simple code when it has at
+// most two symbols below 256, otherwise run length coded with a code length
+// code.
+func writePrefixCode(b *bitWriter, pc *prefixCode) {
+	var used []int
+	for s, l := range pc.lengths {
+		if l > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		b.write(1, 1)
+		b.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			b.write(0, 1)
+			b.write(uint32(used[0]), 1)
+		} else {
+			b.write(1, 1)
+			b.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			b.write(uint32(used[1]), 8)
+		}
+		return
+	}
+
+	type token struct {
+		code  int
+		extra uint32
+	}
+	var tokens []token
+	prevLen := uint8(8)
+	for i := 0; i < len(pc.lengths); {
+		v := pc.lengths[i]
+		run := 1
+		for i+run < len(pc.lengths) && pc.lengths[i+run] == v {
+			run++
+		}
+		i += run
+		if v == 0 {
+			for run >= 3 {
+				if run >= 11 {
+					r := min(run, 138)
+					tokens = append(tokens, token{18, uint32(r - 11)})
+					run -= r
+				} else {
+					r := min(run, 10)
+					tokens = append(tokens, token{17, uint32(r - 3)})
+					run -= r
+				}
+			}
+		} else {
+			if v != prevLen {
+				tokens = append(tokens, token{int(v), 0})
+				prevLen = v
+				run--
+			}
+			for run >= 3 {
+				r := min(run, 6)
+				tokens = append(tokens, token{16, uint32(r - 3)})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, token{int(v), 0})
+		}
+	}
+	counts := make([]uint32, 19)
+	for _, t := range tokens {
+		counts[t.code]++
+	}
+	lc := newPrefixCode(counts, codeLengthLimit)
+	n := 4
+	for i, c := range codeLengthOrder {
+		if lc.lengths[c] > 0 {
+			n = max(n, i+1)
+		}
+	}
+	b.write(0, 1)
+	b.write(uint32(n-4), 4)
+	for _, c := range codeLengthOrder[:n] {
+		b.write(uint32(lc.lengths[c]), 3)
+	}
+	b.write(0, 1)
+	for _, t := range tokens {
+		lc.put(b, t.code)
+		switch t.code {
+		case 16:
+			b.write(t.extra, 2)
+		case 17:
+			b.write(t.extra, 3)
+		case 18:
+			b.write(t.extra, 7)
+		}
+	}
+}
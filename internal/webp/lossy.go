@@ -0,0 +1,671 @@
+package webp
+
+import (
+	"image"
+	"math"
+)
+
+// This file implements a VP8 key frame encoder for lossy WebP. Every
+// macroblock is predicted as a whole with one of the four 16x16 luma and
+// 8x8 chroma modes, and the residuals are coded with the default token
+// probabilities. The reconstruction mirrors the decoder's exactly so
+// later macroblocks predict from what the decoder will see.
+
+// Prediction modes, numbered as the mode trees order them.
+const (
+	modeDC = iota
+	modeV
+	modeH
+	modeTM
+)
+
+// boolEncoder is the boolean entropy encoder of RFC 6386 section 7.
+type boolEncoder struct {
+	buf    []byte
+	rng    uint32
+	bottom uint32
+	count  int
+}
+
+func newBoolEncoder() *boolEncoder {
+	return &boolEncoder{rng: 255, count: 24}
+}
+
+// put codes bit, which is false with probability prob/256.
+func (e *boolEncoder) put(prob uint8, bit bool) {
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			// Carry into the bytes already written.
+			i := len(e.buf) - 1
+			for ; i >= 0 && e.buf[i] == 0xff; i-- {
+				e.buf[i] = 0
+			}
+			e.buf[i]++
+		}
+		e.bottom <<= 1
+		e.count--
+		if e.count == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.count = 8
+		}
+	}
+}
+
+// putUint codes the low n bits of v, most significant first, at even odds.
+func (e *boolEncoder) putUint(v uint32, n int) {
+	for n > 0 {
+		n--
+		e.put(128, v>>n&1 != 0)
+	}
+}
+
+// bytes flushes the encoder and returns its output.
+func (e *boolEncoder) bytes() []byte {
+	for i := 0; i < 32; i++ {
+		e.put(128, false)
+	}
+	return e.buf
+}
+
+// macroblock holds the modes and quantized coefficients chosen for one
+// 16x16 macroblock. Coefficients are in raster order within each block.
+type macroblock struct {
+	yMode, uvMode int
+	skip          bool
+	y2            [16]int32
+	y             [16][16]int32
+	// uv holds the four U blocks and then the four V blocks.
+	uv [8][16]int32
+}
+
+// lossyEncoder holds the padded source planes and their reconstruction.
+type lossyEncoder struct {
+	mbw, mbh         int
+	yStride, cStride int
+	src, rec         [3][]uint8
+	// Quantizer steps: DC then AC, for luma, the luma DC plane and chroma.
+	qy1, qy2, quv [2]int32
+}
+
+// qualityIndex maps a 0 to 100 quality to a quantizer index, 127 being the
+// coarsest, spending more of the index range on the higher qualities.
+func qualityIndex(quality int) int {
+	q := float64(min(max(quality, 0), 100)) / 100
+	var c float64
+	if q < 0.75 {
+		c = q * 2 / 3
+	} else {
+		c = 2*q - 1
+	}
+	return min(127, max(0, int(127*(1-math.Cbrt(c))+0.5)))
+}
+
+// encodeLossy returns the VP8 bitstream for img at quality.
+func encodeLossy(img *image.NRGBA, quality int) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	e := &lossyEncoder{mbw: (w + 15) / 16, mbh: (h + 15) / 16}
+	e.yStride, e.cStride = 16*e.mbw, 8*e.mbw
+	e.toYUV(img)
+	qi := qualityIndex(quality)
+	e.qy1 = [2]int32{int32(dequantTableDC[qi]), int32(dequantTableAC[qi])}
+	e.qy2 = [2]int32{int32(dequantTableDC[qi]) * 2, max(8, int32(dequantTableAC[qi])*155/100)}
+	e.quv = [2]int32{int32(dequantTableDC[min(qi, 117)]), int32(dequantTableAC[qi])}
+
+	mbs := make([]macroblock, e.mbw*e.mbh)
+	skipped := 0
+	for mby := 0; mby < e.mbh; mby++ {
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &mbs[mby*e.mbw+mbx]
+			e.encodeMacroblock(mb, mbx, mby)
+			if mb.skip {
+				skipped++
+			}
+		}
+	}
+	skipProb := uint8(min(254, max(1, 256*(len(mbs)-skipped)/len(mbs))))
+
+	first := newBoolEncoder()
+	first.put(128, false) // color space
+	first.put(128, false) // clamping type
+	first.put(128, false) // no segmentation
+	first.put(128, false) // normal loop filter
+	first.putUint(uint32(filterLevel(qi)), 6)
+	first.putUint(0, 3)   // sharpness
+	first.put(128, false) // no loop filter adjustments
+	first.putUint(0, 2)   // one token partition
+	first.putUint(uint32(qi), 7)
+	for i := 0; i < 5; i++ {
+		first.put(128, false) // no quantizer deltas
+	}
+	first.put(128, false) // refresh entropy probs
+	for i := range tokenUpdateProbs {
+		for j := range tokenUpdateProbs[i] {
+			for k := range tokenUpdateProbs[i][j] {
+				for _, p := range tokenUpdateProbs[i][j][k] {
+					first.put(p, false)
+				}
+			}
+		}
+	}
+	first.put(128, true)
+	first.putUint(uint32(skipProb), 8)
+
+	tokens := newBoolEncoder()
+	upNz := make([][9]uint8, e.mbw)
+	for mby := 0; mby < e.mbh; mby++ {
+		var leftNz [9]uint8
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := &mbs[mby*e.mbw+mbx]
+			first.put(skipProb, mb.skip)
+			first.put(145, true) // 16x16 luma prediction
+			switch mb.yMode {
+			case modeDC:
+				first.put(156, false)
+				first.put(163, false)
+			case modeV:
+				first.put(156, false)
+				first.put(163, true)
+			case modeH:
+				first.put(156, true)
+				first.put(128, false)
+			default:
+				first.put(156, true)
+				first.put(128, true)
+			}
+			switch mb.uvMode {
+			case modeDC:
+				first.put(142, false)
+			case modeV:
+				first.put(142, true)
+				first.put(114, false)
+			case modeH:
+				first.put(142, true)
+				first.put(114, true)
+				first.put(183, false)
+			default:
+				first.put(142, true)
+				first.put(114, true)
+				first.put(183, true)
+			}
+			if mb.skip {
+				leftNz, upNz[mbx] = [9]uint8{}, [9]uint8{}
+				continue
+			}
+			tokens.putMacroblock(mb, &leftNz, &upNz[mbx])
+		}
+	}
+
+	part1, part2 := first.bytes(), tokens.bytes()
+	out := make([]byte, 0, 10+len(part1)+len(part2))
+	tag := uint32(len(part1))<<5 | 1<<4 // key frame, version 0, shown
+	out = append(out, byte(tag), byte(tag>>8), byte(tag>>16))
+	out = append(out, 0x9d, 0x01, 0x2a, byte(w), byte(w>>8), byte(h), byte(h>>8))
+	out = append(out, part1...)
+	return append(out, part2...)
+}
+
+// filterLevel picks a loop filter strength for quantizer index qi:
+// coarser quantizing leaves stronger block edges to smooth.
+func filterLevel(qi int) int {
+	return min(63, qi*5/16)
+}
+
+// toYUV converts img to padded BT.601 Y'CbCr planes, repeating the last
+// row and column into the padding, with chroma averaged over 2x2 pixels.
+func (e *lossyEncoder) toYUV(img *image.NRGBA) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pw, ph := 16*e.mbw, 16*e.mbh
+	for i := range e.src {
+		n := pw * ph
+		if i > 0 {
+			n /= 4
+		}
+		e.src[i] = make([]uint8, n)
+		e.rec[i] = make([]uint8, n)
+	}
+	rgb := func(x, y int) (r, g, bl int32) {
+		x, y = min(x, w-1), min(y, h-1)
+		p := img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y):]
+		return int32(p[0]), int32(p[1]), int32(p[2])
+	}
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			r, g, bl := rgb(x, y)
+			e.src[0][y*e.yStride+x] = uint8((16839*r + 33059*g + 6420*bl + 1<<15 + 16<<16) >> 16)
+		}
+	}
+	for y := 0; y < ph/2; y++ {
+		for x := 0; x < pw/2; x++ {
+			var r, g, bl int32
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				r1, g1, b1 := rgb(2*x+d[0], 2*y+d[1])
+				r, g, bl = r+r1, g+g1, bl+b1
+			}
+			const round = 1<<17 + 128<<18
+			e.src[1][y*e.cStride+x] = uint8(clamp255((-9719*r - 19081*g + 28800*bl + round) >> 18))
+			e.src[2][y*e.cStride+x] = uint8(clamp255((28800*r - 24116*g - 4684*bl + round) >> 18))
+		}
+	}
+}
+
+// edges returns the reconstructed pixels above and left of the n by n
+// block at (x, y) of plane p, and the one above and left of it, with the
+// values the decoder substitutes outside the image.
+func (e *lossyEncoder) edges(p, x, y, n int) (above, left []uint8, corner uint8) {
+	stride := e.yStride
+	if p > 0 {
+		stride = e.cStride
+	}
+	rec := e.rec[p]
+	above, left = make([]uint8, n), make([]uint8, n)
+	for i := 0; i < n; i++ {
+		above[i], left[i] = 127, 129
+		if y > 0 {
+			above[i] = rec[(y-1)*stride+x+i]
+		}
+		if x > 0 {
+			left[i] = rec[(y+i)*stride+x-1]
+		}
+	}
+	switch {
+	case y == 0:
+		corner = 127
+	case x == 0:
+		corner = 129
+	default:
+		corner = rec[(y-1)*stride+x-1]
+	}
+	return above, left, corner
+}
+
+// predictBlock fills the n by n dst with mode's prediction.
+func predictBlock(dst []uint8, n, mode int, above, left []uint8, corner uint8, hasAbove, hasLeft bool) {
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			var v uint8
+			switch mode {
+			case modeV:
+				v = above[i]
+			case modeH:
+				v = left[j]
+			case modeTM:
+				v = uint8(clamp255(int32(left[j]) + int32(above[i]) - int32(corner)))
+			}
+			dst[j*n+i] = v
+		}
+	}
+	if mode != modeDC {
+		return
+	}
+	sum, shift := 0, 0
+	if hasAbove {
+		for _, v := range above {
+			sum += int(v)
+		}
+		shift++
+	}
+	if hasLeft {
+		for _, v := range left {
+			sum += int(v)
+		}
+		shift++
+	}
+	dc := uint8(128)
+	if shift > 0 {
+		count := n * shift
+		dc = uint8((sum + count/2) / count)
+	}
+	for i := range dst[:n*n] {
+		dst[i] = dc
+	}
+}
+
+// choosePrediction returns the mode whose prediction of the n by n blocks
+// of planes is closest to the source, and the predictions.
+func (e *lossyEncoder) choosePrediction(planes []int, x, y, n int) (int, [][]uint8) {
+	best, bestErr := 0, -1
+	var bestPred [][]uint8
+	for mode := modeDC; mode <= modeTM; mode++ {
+		preds := make([][]uint8, len(planes))
+		sse := 0
+		for k, p := range planes {
+			stride := e.yStride
+			if p > 0 {
+				stride = e.cStride
+			}
+			above, left, corner := e.edges(p, x, y, n)
+			preds[k] = make([]uint8, n*n)
+			predictBlock(preds[k], n, mode, above, left, corner, y > 0, x > 0)
+			for j := 0; j < n; j++ {
+				for i := 0; i < n; i++ {
+					d := int(e.src[p][(y+j)*stride+x+i]) - int(preds[k][j*n+i])
+					sse += d * d
+				}
+			}
+		}
+		if bestErr < 0 || sse < bestErr {
+			best, bestErr, bestPred = mode, sse, preds
+		}
+	}
+	return best, bestPred
+}
+
+// encodeMacroblock chooses mb's modes, quantizes its residuals and writes
+// the decoder's reconstruction of it.
+func (e *lossyEncoder) encodeMacroblock(mb *macroblock, mbx, mby int) {
+	x, y := 16*mbx, 16*mby
+	var pred [][]uint8
+	mb.yMode, pred = e.choosePrediction([]int{0}, x, y, 16)
+	ypred := pred[0]
+
+	var dcs [16]int32
+	var coeffs [16][16]int32
+	for b := 0; b < 16; b++ {
+		bx, by := 4*(b%4), 4*(b/4)
+		var res [16]int32
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				res[j*4+i] = int32(e.src[0][(y+by+j)*e.yStride+x+bx+i]) - int32(ypred[(by+j)*16+bx+i])
+			}
+		}
+		coeffs[b] = forwardDCT(res)
+		dcs[b] = coeffs[b][0]
+	}
+	wht := forwardWHT(dcs)
+	nonzero := false
+	for i := range wht {
+		mb.y2[i] = quantize(wht[i], e.qy2[min(i, 1)], i == 0)
+		nonzero = nonzero || mb.y2[i] != 0
+	}
+	var deq [16]int32
+	for i := range deq {
+		deq[i] = mb.y2[i] * e.qy2[min(i, 1)]
+	}
+	dcs = inverseWHT(deq)
+	for b := 0; b < 16; b++ {
+		var block [16]int32
+		block[0] = dcs[b]
+		for i := 1; i < 16; i++ {
+			mb.y[b][i] = quantize(coeffs[b][i], e.qy1[1], false)
+			block[i] = mb.y[b][i] * e.qy1[1]
+			nonzero = nonzero || mb.y[b][i] != 0
+		}
+		bx, by := 4*(b%4), 4*(b/4)
+		inverseDCT(block, ypred[by*16+bx:], 16)
+		for j := 0; j < 4; j++ {
+			copy(e.rec[0][(y+by+j)*e.yStride+x+bx:][:4], ypred[(by+j)*16+bx:][:4])
+		}
+	}
+
+	cx, cy := 8*mbx, 8*mby
+	mb.uvMode, pred = e.choosePrediction([]int{1, 2}, cx, cy, 8)
+	for k, p := range []int{1, 2} {
+		for b := 0; b < 4; b++ {
+			bx, by := 4*(b%2), 4*(b/2)
+			var res [16]int32
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					res[j*4+i] = int32(e.src[p][(cy+by+j)*e.cStride+cx+bx+i]) - int32(pred[k][(by+j)*8+bx+i])
+				}
+			}
+			c := forwardDCT(res)
+			levels := &mb.uv[4*k+b]
+			var block [16]int32
+			for i := range c {
+				levels[i] = quantize(c[i], e.quv[min(i, 1)], i == 0)
+				block[i] = levels[i] * e.quv[min(i, 1)]
+				nonzero = nonzero || levels[i] != 0
+			}
+			inverseDCT(block, pred[k][by*8+bx:], 8)
+		}
+		for j := 0; j < 8; j++ {
+			copy(e.rec[p][(cy+j)*e.cStride+cx:][:8], pred[k][j*8:][:8])
+		}
+	}
+	mb.skip = !nonzero
+}
+
+// quantize divides c by step, rounding DC coefficients to nearest and
+// rounding AC ones toward zero a little, which saves bits on noise.
+func quantize(c, step int32, dc bool) int32 {
+	bias := step * 3 / 8
+	if dc {
+		bias = step / 2
+	}
+	neg := c < 0
+	if neg {
+		c = -c
+	}
+	v := min((c+bias)/step, 2048)
+	if neg {
+		return -v
+	}
+	return v
+}
+
+// forwardDCT transforms a 4x4 block of residuals, as libwebp does.
+func forwardDCT(in [16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		d0, d1, d2, d3 := in[i*4], in[i*4+1], in[i*4+2], in[i*4+3]
+		a0, a1, a2, a3 := d0+d3, d1+d2, d1-d2, d0-d3
+		tmp[i*4+0] = (a0 + a1) * 8
+		tmp[i*4+1] = (a2*2217 + a3*5352 + 1812) >> 9
+		tmp[i*4+2] = (a0 - a1) * 8
+		tmp[i*4+3] = (a3*2217 - a2*5352 + 937) >> 9
+	}
+	for i := 0; i < 4; i++ {
+		a0, a1 := tmp[i]+tmp[12+i], tmp[4+i]+tmp[8+i]
+		a2, a3 := tmp[4+i]-tmp[8+i], tmp[i]-tmp[12+i]
+		out[i] = (a0 + a1 + 7) >> 4
+		out[4+i] = (a2*2217+a3*5352+12000)>>16 + boolInt32(a3 != 0)
+		out[8+i] = (a0 - a1 + 7) >> 4
+		out[12+i] = (a3*2217 - a2*5352 + 51000) >> 16
+	}
+	return out
+}
+
+func boolInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// forwardWHT transforms the DC coefficients of the 16 luma blocks.
+func forwardWHT(in [16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[i*4]+in[i*4+2], in[i*4+1]+in[i*4+3]
+		a2, a3 := in[i*4+1]-in[i*4+3], in[i*4]-in[i*4+2]
+		tmp[i*4+0] = a0 + a1
+		tmp[i*4+1] = a3 + a2
+		tmp[i*4+2] = a3 - a2
+		tmp[i*4+3] = a0 - a1
+	}
+	for i := 0; i < 4; i++ {
+		a0, a1 := tmp[i]+tmp[8+i], tmp[4+i]+tmp[12+i]
+		a2, a3 := tmp[4+i]-tmp[12+i], tmp[i]-tmp[8+i]
+		out[i] = (a0 + a1) >> 1
+		out[4+i] = (a3 + a2) >> 1
+		out[8+i] = (a3 - a2) >> 1
+		out[12+i] = (a0 - a1) >> 1
+	}
+	return out
+}
+
+// inverseWHT is the decoder's inverse of forwardWHT.
+func inverseWHT(in [16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0, a1 := in[i]+in[12+i], in[4+i]+in[8+i]
+		a2, a3 := in[4+i]-in[8+i], in[i]-in[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0, a1 := dc+m[i*4+3], m[i*4+1]+m[i*4+2]
+		a2, a3 := m[i*4+1]-m[i*4+2], dc-m[i*4+3]
+		out[i*4+0] = (a0 + a1) >> 3
+		out[i*4+1] = (a3 + a2) >> 3
+		out[i*4+2] = (a0 - a1) >> 3
+		out[i*4+3] = (a3 - a2) >> 3
+	}
+	return out
+}
+
+// inverseDCT adds the decoder's inverse transform of in to the 4x4 block
+// at the start of dst, whose rows are stride apart.
+func inverseDCT(in [16]int32, dst []uint8, stride int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := in[i] + in[8+i]
+		b := in[i] - in[8+i]
+		c := (in[4+i]*c2)>>16 - (in[12+i]*c1)>>16
+		d := (in[4+i]*c1)>>16 + (in[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a, b := dc+m[2][j], dc-m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := dst[j*stride:]
+		for i, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			row[i] = uint8(clamp255(int32(row[i]) + v>>3))
+		}
+	}
+}
+
+// putMacroblock codes mb's coefficients. left and up hold whether the
+// neighbouring blocks had coefficients: four luma, two U and two V
+// entries, then the luma DC plane.
+func (e *boolEncoder) putMacroblock(mb *macroblock, left, up *[9]uint8) {
+	nz := e.putCoefficients(planeY2, left[8]+up[8], &mb.y2, 0)
+	left[8], up[8] = nz, nz
+	for by := 0; by < 4; by++ {
+		for bx := 0; bx < 4; bx++ {
+			nz := e.putCoefficients(planeY1AfterY2, left[by]+up[bx], &mb.y[by*4+bx], 1)
+			left[by], up[bx] = nz, nz
+		}
+	}
+	for c := 0; c < 2; c++ {
+		for by := 0; by < 2; by++ {
+			for bx := 0; bx < 2; bx++ {
+				l, u := &left[4+2*c+by], &up[4+2*c+bx]
+				nz := e.putCoefficients(planeUV, *l+*u, &mb.uv[4*c+2*by+bx], 0)
+				*l, *u = nz, nz
+			}
+		}
+	}
+}
+
+// putCoefficients codes a block's coefficients from position first, in
+// zigzag order, and returns 1 if any was non-zero.
+func (e *boolEncoder) putCoefficients(plane int, ctx uint8, levels *[16]int32, first int) uint8 {
+	probs := &defaultTokenProbs[plane]
+	last := -1
+	for n := 15; n >= first; n-- {
+		if levels[zigzag[n]] != 0 {
+			last = n
+			break
+		}
+	}
+	p := &probs[bands[first]][ctx]
+	if last < 0 {
+		e.put(p[0], false)
+		return 0
+	}
+	e.put(p[0], true)
+	for n := first; n < 16; {
+		v := levels[zigzag[n]]
+		n++
+		if v == 0 {
+			e.put(p[1], false)
+			p = &probs[bands[n]][0]
+			continue
+		}
+		e.put(p[1], true)
+		a := v
+		if a < 0 {
+			a = -a
+		}
+		if a == 1 {
+			e.put(p[2], false)
+			p = &probs[bands[n]][1]
+		} else {
+			e.put(p[2], true)
+			e.putLarge(p, a)
+			p = &probs[bands[n]][2]
+		}
+		e.put(128, v < 0)
+		if n == 16 {
+			break
+		}
+		e.put(p[0], n <= last)
+		if n > last {
+			break
+		}
+	}
+	return 1
+}
+
+// putLarge codes a coefficient magnitude of two or more.
+func (e *boolEncoder) putLarge(p *[nProb]uint8, a int32) {
+	switch {
+	case a <= 4:
+		e.put(p[3], false)
+		e.put(p[4], a != 2)
+		if a != 2 {
+			e.put(p[5], a == 4)
+		}
+	case a <= 10:
+		e.put(p[3], true)
+		e.put(p[6], false)
+		if a <= 6 {
+			e.put(p[7], false)
+			e.put(159, a == 6)
+		} else {
+			e.put(p[7], true)
+			e.put(165, (a-7)&2 != 0)
+			e.put(145, (a-7)&1 != 0)
+		}
+	default:
+		e.put(p[3], true)
+		e.put(p[6], true)
+		cat := 3
+		switch {
+		case a <= 18:
+			cat = 0
+		case a <= 34:
+			cat = 1
+		case a <= 66:
+			cat = 2
+		}
+		e.put(p[8], cat >= 2)
+		e.put(p[9+cat/2], cat&1 != 0)
+		extra := a - 3 - 8<<cat
+		tab := categoryProbs[cat]
+		for k, prob := range tab {
+			e.put(prob, extra>>(len(tab)-1-k)&1 != 0)
+		}
+	}
+}
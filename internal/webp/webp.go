@@ -0,0 +1,175 @@
+// Package webp encodes images as WebP files, either lossless or lossy with
+// a quality setting. It is written in Go so builds need no C toolchain;
+// golang.org/x/image/webp reads the files back.
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultQuality is the lossy quality used when none is configured.
+const DefaultQuality = 90
+
+// maxSize is the largest width or height either format can store.
+const maxSize = 16383
+
+// Options sets how an image is encoded.
+type Options struct {
+	// Lossless stores the pixels exactly. Screenshots of text and flat
+	// colors often compress better this way than lossy.
+	Lossless bool
+	// Quality, from 0 to 100, trades size for fidelity when lossy.
+	Quality int
+}
+
+// ParseQuality reads a quality setting as given on the command line or in
+// the configuration file: a number from 0 to 100 for lossy encoding, or
+// lossless. Empty means DefaultQuality.
+func ParseQuality(s string) (Options, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return Options{Quality: DefaultQuality}, nil
+	case "lossless":
+		return Options{Lossless: true}, nil
+	}
+	q, err := strconv.Atoi(s)
+	if err != nil || q < 0 || q > 100 {
+		return Options{}, fmt.Errorf("invalid webp quality %q (want 0 to 100 or lossless)", s)
+	}
+	return Options{Quality: q}, nil
+}
+
+// String formats o as ParseQuality reads it.
+func (o Options) String() string {
+	if o.Lossless {
+		return "lossless"
+	}
+	return strconv.Itoa(o.Quality)
+}
+
+// Encode writes img as a WebP file. A nil o encodes lossy at
+// DefaultQuality. Lossy images with transparency keep their alpha channel
+// losslessly alongside.
+func Encode(w io.Writer, img image.Image, o *Options) error {
+	if o == nil {
+		o = &Options{Quality: DefaultQuality}
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return errors.New("webp: cannot encode an empty image")
+	}
+	if b.Dx() > maxSize || b.Dy() > maxSize {
+		return fmt.Errorf("webp: %dx%d is larger than the %d pixel limit", b.Dx(), b.Dy(), maxSize)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(nrgba, nrgba.Rect, img, b.Min, draw.Src)
+	}
+
+	var data, chunks bytes.Buffer
+	if writeImage(&data, nrgba, o) && !o.Lossless {
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x10 // alpha
+		putUint24(vp8x[4:], b.Dx()-1)
+		putUint24(vp8x[7:], b.Dy()-1)
+		writeChunk(&chunks, "VP8X", vp8x)
+	}
+	chunks.Write(data.Bytes())
+
+	header := make([]byte, 12)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+chunks.Len()))
+	copy(header[8:], "WEBP")
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(chunks.Bytes())
+	return err
+}
+
+// writeImage appends the chunks holding img's pixels: VP8L when lossless,
+// otherwise VP8 after an ALPH chunk if img has transparency. It reports
+// whether img has transparency.
+func writeImage(buf *bytes.Buffer, img *image.NRGBA, o *Options) bool {
+	b := img.Bounds()
+	if o.Lossless {
+		argb := argbPixels(img)
+		writeChunk(buf, "VP8L", encodeLossless(argb, b.Dx(), b.Dy()))
+		for _, p := range argb {
+			if p>>24 != 0xff {
+				return true
+			}
+		}
+		return false
+	}
+	alpha := alphaPlane(img)
+	if alpha != nil {
+		var bw bitWriter
+		bw.write(1, 8) // lossless compression, no filter or preprocessing
+		writeLosslessImage(&bw, alpha, b.Dx(), b.Dy())
+		writeChunk(buf, "ALPH", bw.bytes())
+	}
+	writeChunk(buf, "VP8 ", encodeLossy(img, o.Quality))
+	return alpha != nil
+}
+
+// writeChunk appends a RIFF chunk, padded to an even length.
+func writeChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	buf.WriteString(fourCC)
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(data)))
+	buf.Write(n[:])
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// argbPixels returns img's pixels as 0xAARRGGBB values.
+func argbPixels(img *image.NRGBA) []uint32 {
+	b := img.Bounds()
+	out := make([]uint32, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[4*x:]
+			out = append(out, uint32(p[3])<<24|uint32(p[0])<<16|uint32(p[1])<<8|uint32(p[2]))
+		}
+	}
+	return out
+}
+
+// alphaPlane returns img's alpha values in the green channel of otherwise
+// empty pixels, the form the ALPH chunk codes them in, or nil if img is
+// opaque.
+func alphaPlane(img *image.NRGBA) []uint32 {
+	b := img.Bounds()
+	out := make([]uint32, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			a := row[4*x+3]
+			opaque = opaque && a == 0xff
+			out = append(out, uint32(a)<<8)
+		}
+	}
+	if opaque {
+		return nil
+	}
+	return out
+}
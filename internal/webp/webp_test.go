@@ -0,0 +1,181 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// testImage draws something like a screenshot: flat panels, text-like
+// strokes, a gradient, noise and, when alpha is set, a transparent margin
+// with a soft edge.
+func testImage(w, h int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{0xf0, 0xf0, 0xf0, 0xff}
+			switch {
+			case y < h/5:
+				c = color.NRGBA{0x30, 0x50, 0x90, 0xff}
+			case x < w/4:
+				c = color.NRGBA{uint8(x * 255 / w), uint8(y * 255 / h), 0x80, 0xff}
+			case y > h*4/5:
+				c = color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 0xff}
+			case (x/3+y/7)%5 == 0 && y%7 < 5:
+				c = color.NRGBA{0x10, 0x10, 0x10, 0xff}
+			}
+			if alpha {
+				edge := min(x, y, w-1-x, h-1-y)
+				if edge < 8 {
+					c.A = uint8(edge * 32)
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := xwebp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding the encoded image: %v", err)
+	}
+	return img
+}
+
+func TestLosslessRoundTrip(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {3, 2}, {17, 33}, {200, 120}} {
+		for _, alpha := range []bool{false, true} {
+			src := testImage(size.X, size.Y, alpha)
+			var buf bytes.Buffer
+			if err := Encode(&buf, src, &Options{Lossless: true}); err != nil {
+				t.Fatal(err)
+			}
+			got := image.NewNRGBA(src.Rect)
+			draw.Draw(got, got.Rect, decode(t, buf.Bytes()), image.Point{}, draw.Src)
+			if !bytes.Equal(got.Pix, src.Pix) {
+				t.Errorf("%v alpha=%v: decoded pixels differ from the source", size, alpha)
+			}
+		}
+	}
+}
+
+// psnr compares the luma the decoder produced with the source's.
+func psnr(src *image.NRGBA, got *image.YCbCr) float64 {
+	var sse float64
+	b := src.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			p := src.Pix[src.PixOffset(x, y):]
+			want := (16839*int(p[0]) + 33059*int(p[1]) + 6420*int(p[2]) + 1<<15 + 16<<16) >> 16
+			d := float64(want) - float64(got.Y[got.YOffset(x, y)])
+			sse += d * d
+		}
+	}
+	mse := sse / float64(b.Dx()*b.Dy())
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
+
+func TestLossyQuality(t *testing.T) {
+	src := testImage(203, 117, false)
+	prevSize, prevPSNR := 0, 0.0
+	for _, q := range []int{10, 50, 90, 100} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, src, &Options{Quality: q}); err != nil {
+			t.Fatal(err)
+		}
+		m, ok := decode(t, buf.Bytes()).(*image.YCbCr)
+		if !ok {
+			t.Fatalf("quality %d: decoded a %T, want *image.YCbCr", q, m)
+		}
+		if m.Rect != src.Rect {
+			t.Fatalf("quality %d: decoded bounds %v, want %v", q, m.Rect, src.Rect)
+		}
+		p := psnr(src, m)
+		t.Logf("quality %d: %d bytes, %.1f dB", q, buf.Len(), p)
+		if q >= 90 && p < 35 {
+			t.Errorf("quality %d: PSNR %.1f dB, want at least 35", q, p)
+		}
+		if p < prevPSNR || buf.Len() < prevSize {
+			t.Errorf("quality %d: %d bytes at %.1f dB after %d bytes at %.1f dB", q, buf.Len(), p, prevSize, prevPSNR)
+		}
+		prevSize, prevPSNR = buf.Len(), p
+	}
+}
+
+func TestLossyKeepsAlpha(t *testing.T) {
+	src := testImage(64, 40, true)
+	var buf bytes.Buffer
+	if err := Encode(&buf, src, &Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	m, ok := decode(t, buf.Bytes()).(*image.NYCbCrA)
+	if !ok {
+		t.Fatalf("decoded a %T, want *image.NYCbCrA", m)
+	}
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 64; x++ {
+			if got, want := m.A[m.AOffset(x, y)], src.NRGBAAt(x, y).A; got != want {
+				t.Fatalf("alpha at %d,%d = %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestTransformsInvert(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for n := 0; n < 100; n++ {
+		var dcs [16]int32
+		for i := range dcs {
+			dcs[i] = int32(rng.Intn(4000) - 2000)
+		}
+		back := inverseWHT(forwardWHT(dcs))
+		for i := range dcs {
+			if d := back[i] - dcs[i]; d < -1 || d > 1 {
+				t.Fatalf("WHT round trip of %v gave %v", dcs, back)
+			}
+		}
+		var res [16]int32
+		src, rec := make([]uint8, 16), make([]uint8, 16)
+		for i := range res {
+			src[i], rec[i] = uint8(rng.Intn(256)), uint8(rng.Intn(256))
+			res[i] = int32(src[i]) - int32(rec[i])
+		}
+		inverseDCT(forwardDCT(res), rec, 4)
+		for i := range src {
+			if d := int(rec[i]) - int(src[i]); d < -1 || d > 1 {
+				t.Fatalf("DCT round trip gave %v, want %v", rec, src)
+			}
+		}
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	for s, want := range map[string]Options{
+		"":         {Quality: DefaultQuality},
+		"75":       {Quality: 75},
+		"Lossless": {Lossless: true},
+	} {
+		got, err := ParseQuality(s)
+		if err != nil || got != want {
+			t.Errorf("ParseQuality(%q) = %+v, %v; want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"101", "-1", "best"} {
+		if _, err := ParseQuality(s); err == nil {
+			t.Errorf("ParseQuality(%q) succeeded", s)
+		}
+	}
+}
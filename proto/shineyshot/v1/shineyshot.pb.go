@@ -0,0 +1,899 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: shineyshot/v1/shineyshot.proto
+
+// Package shineyshot.v1 drives a ShineyShot background session, started with
+// `shineyshot background grpc`, as a screenshot microservice.
+
+package shineyshotv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CaptureRequest_Mode int32
+
+const (
+	// MODE_UNSPECIFIED captures a screen.
+	CaptureRequest_MODE_UNSPECIFIED CaptureRequest_Mode = 0
+	CaptureRequest_MODE_SCREEN      CaptureRequest_Mode = 1
+	CaptureRequest_MODE_WINDOW      CaptureRequest_Mode = 2
+	CaptureRequest_MODE_REGION      CaptureRequest_Mode = 3
+)
+
+// Enum value maps for CaptureRequest_Mode.
+var (
+	CaptureRequest_Mode_name = map[int32]string{
+		0: "MODE_UNSPECIFIED",
+		1: "MODE_SCREEN",
+		2: "MODE_WINDOW",
+		3: "MODE_REGION",
+	}
+	CaptureRequest_Mode_value = map[string]int32{
+		"MODE_UNSPECIFIED": 0,
+		"MODE_SCREEN":      1,
+		"MODE_WINDOW":      2,
+		"MODE_REGION":      3,
+	}
+)
+
+func (x CaptureRequest_Mode) Enum() *CaptureRequest_Mode {
+	p := new(CaptureRequest_Mode)
+	*p = x
+	return p
+}
+
+func (x CaptureRequest_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CaptureRequest_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_shineyshot_v1_shineyshot_proto_enumTypes[0].Descriptor()
+}
+
+func (CaptureRequest_Mode) Type() protoreflect.EnumType {
+	return &file_shineyshot_v1_shineyshot_proto_enumTypes[0]
+}
+
+func (x CaptureRequest_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CaptureRequest_Mode.Descriptor instead.
+func (CaptureRequest_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{1, 0}
+}
+
+type Shape_Kind int32
+
+const (
+	Shape_KIND_UNSPECIFIED Shape_Kind = 0
+	Shape_KIND_ARROW       Shape_Kind = 1
+	Shape_KIND_LINE        Shape_Kind = 2
+	Shape_KIND_RECT        Shape_Kind = 3
+	Shape_KIND_CIRCLE      Shape_Kind = 4
+)
+
+// Enum value maps for Shape_Kind.
+var (
+	Shape_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_ARROW",
+		2: "KIND_LINE",
+		3: "KIND_RECT",
+		4: "KIND_CIRCLE",
+	}
+	Shape_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_ARROW":       1,
+		"KIND_LINE":        2,
+		"KIND_RECT":        3,
+		"KIND_CIRCLE":      4,
+	}
+)
+
+func (x Shape_Kind) Enum() *Shape_Kind {
+	p := new(Shape_Kind)
+	*p = x
+	return p
+}
+
+func (x Shape_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Shape_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shineyshot_v1_shineyshot_proto_enumTypes[1].Descriptor()
+}
+
+func (Shape_Kind) Type() protoreflect.EnumType {
+	return &file_shineyshot_v1_shineyshot_proto_enumTypes[1]
+}
+
+func (x Shape_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Shape_Kind.Descriptor instead.
+func (Shape_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{2, 0}
+}
+
+// Rect is a rectangle in image or screen pixels.
+type Rect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             int32                  `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             int32                  `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Width         int32                  `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rect) Reset() {
+	*x = Rect{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rect) ProtoMessage() {}
+
+func (x *Rect) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rect.ProtoReflect.Descriptor instead.
+func (*Rect) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rect) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Rect) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Rect) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Rect) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type CaptureRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Mode  CaptureRequest_Mode    `protobuf:"varint,1,opt,name=mode,proto3,enum=shineyshot.v1.CaptureRequest_Mode" json:"mode,omitempty"`
+	// target is the display for screen and region captures or the window
+	// selector for window captures. Empty uses the current display or the
+	// active window.
+	Target string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	// rect is required for region captures and relative to the target screen.
+	Rect *Rect `protobuf:"bytes,3,opt,name=rect,proto3" json:"rect,omitempty"`
+	// include_png returns the captured image in the response.
+	IncludePng    bool `protobuf:"varint,4,opt,name=include_png,json=includePng,proto3" json:"include_png,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CaptureRequest) Reset() {
+	*x = CaptureRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CaptureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureRequest) ProtoMessage() {}
+
+func (x *CaptureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureRequest.ProtoReflect.Descriptor instead.
+func (*CaptureRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CaptureRequest) GetMode() CaptureRequest_Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return CaptureRequest_MODE_UNSPECIFIED
+}
+
+func (x *CaptureRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *CaptureRequest) GetRect() *Rect {
+	if x != nil {
+		return x.Rect
+	}
+	return nil
+}
+
+func (x *CaptureRequest) GetIncludePng() bool {
+	if x != nil {
+		return x.IncludePng
+	}
+	return false
+}
+
+type Shape struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  Shape_Kind             `protobuf:"varint,1,opt,name=kind,proto3,enum=shineyshot.v1.Shape_Kind" json:"kind,omitempty"`
+	// points holds x0, y0, x1, y1, or x, y, radius for circles.
+	Points []int32 `protobuf:"varint,2,rep,packed,name=points,proto3" json:"points,omitempty"`
+	// color and width override the stroke for this shape and later ones.
+	Color         string `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`
+	Width         int32  `protobuf:"varint,4,opt,name=width,proto3" json:"width,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shape) Reset() {
+	*x = Shape{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shape) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shape) ProtoMessage() {}
+
+func (x *Shape) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shape.ProtoReflect.Descriptor instead.
+func (*Shape) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Shape) GetKind() Shape_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return Shape_KIND_UNSPECIFIED
+}
+
+func (x *Shape) GetPoints() []int32 {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *Shape) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *Shape) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+type DrawRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// color and width set the stroke before any shape is drawn. Colors accept
+	// a palette index, name or hex value.
+	Color         string   `protobuf:"bytes,1,opt,name=color,proto3" json:"color,omitempty"`
+	Width         int32    `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Shapes        []*Shape `protobuf:"bytes,3,rep,name=shapes,proto3" json:"shapes,omitempty"`
+	IncludePng    bool     `protobuf:"varint,4,opt,name=include_png,json=includePng,proto3" json:"include_png,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrawRequest) Reset() {
+	*x = DrawRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrawRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrawRequest) ProtoMessage() {}
+
+func (x *DrawRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrawRequest.ProtoReflect.Descriptor instead.
+func (*DrawRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DrawRequest) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *DrawRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *DrawRequest) GetShapes() []*Shape {
+	if x != nil {
+		return x.Shapes
+	}
+	return nil
+}
+
+func (x *DrawRequest) GetIncludePng() bool {
+	if x != nil {
+		return x.IncludePng
+	}
+	return false
+}
+
+type CropRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rect          *Rect                  `protobuf:"bytes,1,opt,name=rect,proto3" json:"rect,omitempty"`
+	IncludePng    bool                   `protobuf:"varint,2,opt,name=include_png,json=includePng,proto3" json:"include_png,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CropRequest) Reset() {
+	*x = CropRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CropRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CropRequest) ProtoMessage() {}
+
+func (x *CropRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CropRequest.ProtoReflect.Descriptor instead.
+func (*CropRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CropRequest) GetRect() *Rect {
+	if x != nil {
+		return x.Rect
+	}
+	return nil
+}
+
+func (x *CropRequest) GetIncludePng() bool {
+	if x != nil {
+		return x.IncludePng
+	}
+	return false
+}
+
+type SaveRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// path is the file to write. Empty uses the session outdir and pattern, or
+	// the Pictures directory when the session has no outdir.
+	Path          string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveRequest) Reset() {
+	*x = SaveRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveRequest) ProtoMessage() {}
+
+func (x *SaveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveRequest.ProtoReflect.Descriptor instead.
+func (*SaveRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SaveRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type SaveResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// path is the absolute path written.
+	Path          string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveResponse) Reset() {
+	*x = SaveResponse{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveResponse) ProtoMessage() {}
+
+func (x *SaveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveResponse.ProtoReflect.Descriptor instead.
+func (*SaveResponse) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SaveResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type GetImageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetImageRequest) Reset() {
+	*x = GetImageRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetImageRequest) ProtoMessage() {}
+
+func (x *GetImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetImageRequest.ProtoReflect.Descriptor instead.
+func (*GetImageRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{7}
+}
+
+// Image describes the session image after a call.
+type Image struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Width  int32                  `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height int32                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	// revision increases whenever the image changes.
+	Revision uint64 `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	// png holds the encoded image when requested.
+	Png           []byte `protobuf:"bytes,4,opt,name=png,proto3" json:"png,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Image) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Image) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Image) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Image) GetPng() []byte {
+	if x != nil {
+		return x.Png
+	}
+	return nil
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{9}
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// kind is capture, save, copy or tab.
+	Kind          string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Detail        string `protobuf:"bytes,2,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_shineyshot_v1_shineyshot_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_shineyshot_v1_shineyshot_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Event) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Event) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+var File_shineyshot_v1_shineyshot_proto protoreflect.FileDescriptor
+
+const file_shineyshot_v1_shineyshot_proto_rawDesc = "" +
+	"\n" +
+	"\x1eshineyshot/v1/shineyshot.proto\x12\rshineyshot.v1\"P\n" +
+	"\x04Rect\x12\f\n" +
+	"\x01x\x18\x01 \x01(\x05R\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\x05R\x01y\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\x05R\x06height\"\xfb\x01\n" +
+	"\x0eCaptureRequest\x126\n" +
+	"\x04mode\x18\x01 \x01(\x0e2\".shineyshot.v1.CaptureRequest.ModeR\x04mode\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12'\n" +
+	"\x04rect\x18\x03 \x01(\v2\x13.shineyshot.v1.RectR\x04rect\x12\x1f\n" +
+	"\vinclude_png\x18\x04 \x01(\bR\n" +
+	"includePng\"O\n" +
+	"\x04Mode\x12\x14\n" +
+	"\x10MODE_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vMODE_SCREEN\x10\x01\x12\x0f\n" +
+	"\vMODE_WINDOW\x10\x02\x12\x0f\n" +
+	"\vMODE_REGION\x10\x03\"\xd7\x01\n" +
+	"\x05Shape\x12-\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x19.shineyshot.v1.Shape.KindR\x04kind\x12\x16\n" +
+	"\x06points\x18\x02 \x03(\x05R\x06points\x12\x14\n" +
+	"\x05color\x18\x03 \x01(\tR\x05color\x12\x14\n" +
+	"\x05width\x18\x04 \x01(\x05R\x05width\"[\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"KIND_ARROW\x10\x01\x12\r\n" +
+	"\tKIND_LINE\x10\x02\x12\r\n" +
+	"\tKIND_RECT\x10\x03\x12\x0f\n" +
+	"\vKIND_CIRCLE\x10\x04\"\x88\x01\n" +
+	"\vDrawRequest\x12\x14\n" +
+	"\x05color\x18\x01 \x01(\tR\x05color\x12\x14\n" +
+	"\x05width\x18\x02 \x01(\x05R\x05width\x12,\n" +
+	"\x06shapes\x18\x03 \x03(\v2\x14.shineyshot.v1.ShapeR\x06shapes\x12\x1f\n" +
+	"\vinclude_png\x18\x04 \x01(\bR\n" +
+	"includePng\"W\n" +
+	"\vCropRequest\x12'\n" +
+	"\x04rect\x18\x01 \x01(\v2\x13.shineyshot.v1.RectR\x04rect\x12\x1f\n" +
+	"\vinclude_png\x18\x02 \x01(\bR\n" +
+	"includePng\"!\n" +
+	"\vSaveRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\"\n" +
+	"\fSaveResponse\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x11\n" +
+	"\x0fGetImageRequest\"c\n" +
+	"\x05Image\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\x12\x1a\n" +
+	"\brevision\x18\x03 \x01(\x04R\brevision\x12\x10\n" +
+	"\x03png\x18\x04 \x01(\fR\x03png\"\x15\n" +
+	"\x13StreamEventsRequest\"3\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06detail\x18\x02 \x01(\tR\x06detail2\x8f\x03\n" +
+	"\n" +
+	"Shineyshot\x12>\n" +
+	"\aCapture\x12\x1d.shineyshot.v1.CaptureRequest\x1a\x14.shineyshot.v1.Image\x128\n" +
+	"\x04Draw\x12\x1a.shineyshot.v1.DrawRequest\x1a\x14.shineyshot.v1.Image\x128\n" +
+	"\x04Crop\x12\x1a.shineyshot.v1.CropRequest\x1a\x14.shineyshot.v1.Image\x12?\n" +
+	"\x04Save\x12\x1a.shineyshot.v1.SaveRequest\x1a\x1b.shineyshot.v1.SaveResponse\x12@\n" +
+	"\bGetImage\x12\x1e.shineyshot.v1.GetImageRequest\x1a\x14.shineyshot.v1.Image\x12J\n" +
+	"\fStreamEvents\x12\".shineyshot.v1.StreamEventsRequest\x1a\x14.shineyshot.v1.Event0\x01B@Z>github.com/example/shineyshot/proto/shineyshot/v1;shineyshotv1b\x06proto3"
+
+var (
+	file_shineyshot_v1_shineyshot_proto_rawDescOnce sync.Once
+	file_shineyshot_v1_shineyshot_proto_rawDescData []byte
+)
+
+func file_shineyshot_v1_shineyshot_proto_rawDescGZIP() []byte {
+	file_shineyshot_v1_shineyshot_proto_rawDescOnce.Do(func() {
+		file_shineyshot_v1_shineyshot_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_shineyshot_v1_shineyshot_proto_rawDesc), len(file_shineyshot_v1_shineyshot_proto_rawDesc)))
+	})
+	return file_shineyshot_v1_shineyshot_proto_rawDescData
+}
+
+var file_shineyshot_v1_shineyshot_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_shineyshot_v1_shineyshot_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_shineyshot_v1_shineyshot_proto_goTypes = []any{
+	(CaptureRequest_Mode)(0),    // 0: shineyshot.v1.CaptureRequest.Mode
+	(Shape_Kind)(0),             // 1: shineyshot.v1.Shape.Kind
+	(*Rect)(nil),                // 2: shineyshot.v1.Rect
+	(*CaptureRequest)(nil),      // 3: shineyshot.v1.CaptureRequest
+	(*Shape)(nil),               // 4: shineyshot.v1.Shape
+	(*DrawRequest)(nil),         // 5: shineyshot.v1.DrawRequest
+	(*CropRequest)(nil),         // 6: shineyshot.v1.CropRequest
+	(*SaveRequest)(nil),         // 7: shineyshot.v1.SaveRequest
+	(*SaveResponse)(nil),        // 8: shineyshot.v1.SaveResponse
+	(*GetImageRequest)(nil),     // 9: shineyshot.v1.GetImageRequest
+	(*Image)(nil),               // 10: shineyshot.v1.Image
+	(*StreamEventsRequest)(nil), // 11: shineyshot.v1.StreamEventsRequest
+	(*Event)(nil),               // 12: shineyshot.v1.Event
+}
+var file_shineyshot_v1_shineyshot_proto_depIdxs = []int32{
+	0,  // 0: shineyshot.v1.CaptureRequest.mode:type_name -> shineyshot.v1.CaptureRequest.Mode
+	2,  // 1: shineyshot.v1.CaptureRequest.rect:type_name -> shineyshot.v1.Rect
+	1,  // 2: shineyshot.v1.Shape.kind:type_name -> shineyshot.v1.Shape.Kind
+	4,  // 3: shineyshot.v1.DrawRequest.shapes:type_name -> shineyshot.v1.Shape
+	2,  // 4: shineyshot.v1.CropRequest.rect:type_name -> shineyshot.v1.Rect
+	3,  // 5: shineyshot.v1.Shineyshot.Capture:input_type -> shineyshot.v1.CaptureRequest
+	5,  // 6: shineyshot.v1.Shineyshot.Draw:input_type -> shineyshot.v1.DrawRequest
+	6,  // 7: shineyshot.v1.Shineyshot.Crop:input_type -> shineyshot.v1.CropRequest
+	7,  // 8: shineyshot.v1.Shineyshot.Save:input_type -> shineyshot.v1.SaveRequest
+	9,  // 9: shineyshot.v1.Shineyshot.GetImage:input_type -> shineyshot.v1.GetImageRequest
+	11, // 10: shineyshot.v1.Shineyshot.StreamEvents:input_type -> shineyshot.v1.StreamEventsRequest
+	10, // 11: shineyshot.v1.Shineyshot.Capture:output_type -> shineyshot.v1.Image
+	10, // 12: shineyshot.v1.Shineyshot.Draw:output_type -> shineyshot.v1.Image
+	10, // 13: shineyshot.v1.Shineyshot.Crop:output_type -> shineyshot.v1.Image
+	8,  // 14: shineyshot.v1.Shineyshot.Save:output_type -> shineyshot.v1.SaveResponse
+	10, // 15: shineyshot.v1.Shineyshot.GetImage:output_type -> shineyshot.v1.Image
+	12, // 16: shineyshot.v1.Shineyshot.StreamEvents:output_type -> shineyshot.v1.Event
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_shineyshot_v1_shineyshot_proto_init() }
+func file_shineyshot_v1_shineyshot_proto_init() {
+	if File_shineyshot_v1_shineyshot_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shineyshot_v1_shineyshot_proto_rawDesc), len(file_shineyshot_v1_shineyshot_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_shineyshot_v1_shineyshot_proto_goTypes,
+		DependencyIndexes: file_shineyshot_v1_shineyshot_proto_depIdxs,
+		EnumInfos:         file_shineyshot_v1_shineyshot_proto_enumTypes,
+		MessageInfos:      file_shineyshot_v1_shineyshot_proto_msgTypes,
+	}.Build()
+	File_shineyshot_v1_shineyshot_proto = out.File
+	file_shineyshot_v1_shineyshot_proto_goTypes = nil
+	file_shineyshot_v1_shineyshot_proto_depIdxs = nil
+}
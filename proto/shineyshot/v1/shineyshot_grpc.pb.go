@@ -0,0 +1,340 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: shineyshot/v1/shineyshot.proto
+
+// Package shineyshot.v1 drives a ShineyShot background session, started with
+// `shineyshot background grpc`, as a screenshot microservice.
+
+package shineyshotv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Shineyshot_Capture_FullMethodName      = "/shineyshot.v1.Shineyshot/Capture"
+	Shineyshot_Draw_FullMethodName         = "/shineyshot.v1.Shineyshot/Draw"
+	Shineyshot_Crop_FullMethodName         = "/shineyshot.v1.Shineyshot/Crop"
+	Shineyshot_Save_FullMethodName         = "/shineyshot.v1.Shineyshot/Save"
+	Shineyshot_GetImage_FullMethodName     = "/shineyshot.v1.Shineyshot/GetImage"
+	Shineyshot_StreamEvents_FullMethodName = "/shineyshot.v1.Shineyshot/StreamEvents"
+)
+
+// ShineyshotClient is the client API for Shineyshot service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Shineyshot captures, annotates and saves images in a single session. Calls
+// run one at a time on the session's job queue, shared with socket, D-Bus and
+// HTTP clients.
+type ShineyshotClient interface {
+	// Capture replaces the session image with a new screenshot.
+	Capture(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (*Image, error)
+	// Draw applies shapes to the session image in order.
+	Draw(ctx context.Context, in *DrawRequest, opts ...grpc.CallOption) (*Image, error)
+	// Crop trims the session image to a rectangle.
+	Crop(ctx context.Context, in *CropRequest, opts ...grpc.CallOption) (*Image, error)
+	// Save writes the session image to disk on the session's host.
+	Save(ctx context.Context, in *SaveRequest, opts ...grpc.CallOption) (*SaveResponse, error)
+	// GetImage returns the session image as PNG.
+	GetImage(ctx context.Context, in *GetImageRequest, opts ...grpc.CallOption) (*Image, error)
+	// StreamEvents streams capture, save, copy and tab events until the client
+	// cancels or the session stops.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type shineyshotClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShineyshotClient(cc grpc.ClientConnInterface) ShineyshotClient {
+	return &shineyshotClient{cc}
+}
+
+func (c *shineyshotClient) Capture(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (*Image, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Image)
+	err := c.cc.Invoke(ctx, Shineyshot_Capture_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shineyshotClient) Draw(ctx context.Context, in *DrawRequest, opts ...grpc.CallOption) (*Image, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Image)
+	err := c.cc.Invoke(ctx, Shineyshot_Draw_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shineyshotClient) Crop(ctx context.Context, in *CropRequest, opts ...grpc.CallOption) (*Image, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Image)
+	err := c.cc.Invoke(ctx, Shineyshot_Crop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shineyshotClient) Save(ctx context.Context, in *SaveRequest, opts ...grpc.CallOption) (*SaveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveResponse)
+	err := c.cc.Invoke(ctx, Shineyshot_Save_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shineyshotClient) GetImage(ctx context.Context, in *GetImageRequest, opts ...grpc.CallOption) (*Image, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Image)
+	err := c.cc.Invoke(ctx, Shineyshot_GetImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shineyshotClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Shineyshot_ServiceDesc.Streams[0], Shineyshot_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shineyshot_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// ShineyshotServer is the server API for Shineyshot service.
+// All implementations must embed UnimplementedShineyshotServer
+// for forward compatibility.
+//
+// Shineyshot captures, annotates and saves images in a single session. Calls
+// run one at a time on the session's job queue, shared with socket, D-Bus and
+// HTTP clients.
+type ShineyshotServer interface {
+	// Capture replaces the session image with a new screenshot.
+	Capture(context.Context, *CaptureRequest) (*Image, error)
+	// Draw applies shapes to the session image in order.
+	Draw(context.Context, *DrawRequest) (*Image, error)
+	// Crop trims the session image to a rectangle.
+	Crop(context.Context, *CropRequest) (*Image, error)
+	// Save writes the session image to disk on the session's host.
+	Save(context.Context, *SaveRequest) (*SaveResponse, error)
+	// GetImage returns the session image as PNG.
+	GetImage(context.Context, *GetImageRequest) (*Image, error)
+	// StreamEvents streams capture, save, copy and tab events until the client
+	// cancels or the session stops.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedShineyshotServer()
+}
+
+// UnimplementedShineyshotServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShineyshotServer struct{}
+
+func (UnimplementedShineyshotServer) Capture(context.Context, *CaptureRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capture not implemented")
+}
+func (UnimplementedShineyshotServer) Draw(context.Context, *DrawRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Draw not implemented")
+}
+func (UnimplementedShineyshotServer) Crop(context.Context, *CropRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Crop not implemented")
+}
+func (UnimplementedShineyshotServer) Save(context.Context, *SaveRequest) (*SaveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Save not implemented")
+}
+func (UnimplementedShineyshotServer) GetImage(context.Context, *GetImageRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetImage not implemented")
+}
+func (UnimplementedShineyshotServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedShineyshotServer) mustEmbedUnimplementedShineyshotServer() {}
+func (UnimplementedShineyshotServer) testEmbeddedByValue()                    {}
+
+// UnsafeShineyshotServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShineyshotServer will
+// result in compilation errors.
+type UnsafeShineyshotServer interface {
+	mustEmbedUnimplementedShineyshotServer()
+}
+
+func RegisterShineyshotServer(s grpc.ServiceRegistrar, srv ShineyshotServer) {
+	// If the following call pancis, it indicates UnimplementedShineyshotServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Shineyshot_ServiceDesc, srv)
+}
+
+func _Shineyshot_Capture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShineyshotServer).Capture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shineyshot_Capture_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShineyshotServer).Capture(ctx, req.(*CaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shineyshot_Draw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShineyshotServer).Draw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shineyshot_Draw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShineyshotServer).Draw(ctx, req.(*DrawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shineyshot_Crop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CropRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShineyshotServer).Crop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shineyshot_Crop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShineyshotServer).Crop(ctx, req.(*CropRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shineyshot_Save_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShineyshotServer).Save(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shineyshot_Save_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShineyshotServer).Save(ctx, req.(*SaveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shineyshot_GetImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShineyshotServer).GetImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shineyshot_GetImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShineyshotServer).GetImage(ctx, req.(*GetImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shineyshot_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShineyshotServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shineyshot_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// Shineyshot_ServiceDesc is the grpc.ServiceDesc for Shineyshot service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Shineyshot_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shineyshot.v1.Shineyshot",
+	HandlerType: (*ShineyshotServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Capture",
+			Handler:    _Shineyshot_Capture_Handler,
+		},
+		{
+			MethodName: "Draw",
+			Handler:    _Shineyshot_Draw_Handler,
+		},
+		{
+			MethodName: "Crop",
+			Handler:    _Shineyshot_Crop_Handler,
+		},
+		{
+			MethodName: "Save",
+			Handler:    _Shineyshot_Save_Handler,
+		},
+		{
+			MethodName: "GetImage",
+			Handler:    _Shineyshot_GetImage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Shineyshot_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "shineyshot/v1/shineyshot.proto",
+}